@@ -112,7 +112,7 @@ class AllResultsSyncWorker(
             // Create output data
             val outputData = Data.Builder()
                 .putString("sync_status", "completed")
-                .putLong("sync_time", System.currentTimeMillis())
+                .putLong("sync_time", SessionSources.now())
                 .build()
 
             Log.d(TAG, "Background sync completed successfully")
@@ -0,0 +1,142 @@
+package com.polyfieldandroid
+
+import java.util.Locale
+
+enum class AnnouncementType {
+    MARK,
+    FOUL,
+    PASS
+}
+
+/**
+ * One announcer-ready result, with the structured fields it was built from
+ */
+data class Announcement(
+    val type: AnnouncementType,
+    val athleteBib: String,
+    val athleteName: String,
+    val round: Int,
+    val mark: Double?,          // Metres, for MARK
+    val place: Int?,            // Placing after this attempt
+    val isNewLeader: Boolean,
+    val isPersonalBestInEvent: Boolean, // Improved on the athlete's earlier marks in this event
+    val text: String,
+    val languageTag: String,
+    val timestamp: Long = SessionSources.now()
+) {
+    fun toMap(): Map<String, Any?> = mapOf(
+        "type" to type.name,
+        "athleteBib" to athleteBib,
+        "athleteName" to athleteName,
+        "round" to round,
+        "mark" to mark,
+        "place" to place,
+        "isNewLeader" to isNewLeader,
+        "isPersonalBestInEvent" to isPersonalBestInEvent,
+        "text" to text,
+        "language" to languageTag,
+        "timestamp" to timestamp
+    )
+}
+
+/**
+ * Wording for announcements in one language. Placeholders: {bib}, {name}, {round},
+ * {mark} (formatted for the locale) and {place}.
+ */
+data class AnnouncementFormat(
+    val languageTag: String,
+    val mark: String,
+    val foul: String,
+    val pass: String,
+    val newLeader: String,
+    val place: String,
+    val includeName: Boolean = false,
+    val nameTemplate: String = " ({name})"
+) {
+    val locale: Locale
+        get() = Locale.forLanguageTag(languageTag)
+
+    companion object {
+        val ENGLISH = AnnouncementFormat("en", "Bib {bib}, Round {round}: {mark} m", "Bib {bib}, Round {round}: foul", "Bib {bib}, Round {round}: pass", ", new leader", ", now in position {place}")
+        val FRENCH = AnnouncementFormat("fr", "Dossard {bib}, essai {round} : {mark} m", "Dossard {bib}, essai {round} : essai nul", "Dossard {bib}, essai {round} : passe", ", nouveau leader", ", désormais {place}e")
+        val GERMAN = AnnouncementFormat("de", "Startnummer {bib}, Versuch {round}: {mark} m", "Startnummer {bib}, Versuch {round}: ungültig", "Startnummer {bib}, Versuch {round}: verzichtet", ", neue Führung", ", jetzt auf Platz {place}")
+        val SPANISH = AnnouncementFormat("es", "Dorsal {bib}, intento {round}: {mark} m", "Dorsal {bib}, intento {round}: nulo", "Dorsal {bib}, intento {round}: pasa", ", nuevo líder", ", ahora en la posición {place}")
+        val WELSH = AnnouncementFormat("cy", "Rhif {bib}, Rownd {round}: {mark} m", "Rhif {bib}, Rownd {round}: ffowl", "Rhif {bib}, Rownd {round}: pasio", ", arweinydd newydd", ", nawr yn safle {place}")
+
+        private val builtIn = listOf(ENGLISH, FRENCH, GERMAN, SPANISH, WELSH)
+
+        /**
+         * Built-in format for a language, falling back to English
+         */
+        fun forLanguage(languageTag: String): AnnouncementFormat {
+            val language = Locale.forLanguageTag(languageTag).language
+            return builtIn.find { it.languageTag == language } ?: ENGLISH
+        }
+    }
+}
+
+/**
+ * Announcement Formatter
+ * Turns a recorded attempt and the standings into text an announcer or TTS engine can
+ * read out directly
+ */
+object AnnouncementFormatter {
+
+    fun format(
+        result: MeasurementResult,
+        athleteName: String,
+        place: Int?,
+        isNewLeader: Boolean,
+        isPersonalBestInEvent: Boolean,
+        format: AnnouncementFormat
+    ): Announcement {
+        val type = when {
+            !result.isValid -> AnnouncementType.FOUL
+            result.isPass || result.distance == null -> AnnouncementType.PASS
+            else -> AnnouncementType.MARK
+        }
+        val markText = result.distance?.let { String.format(format.locale, "%.2f", it) } ?: ""
+        fun fill(template: String) = template
+            .replace("{bib}", result.athleteBib)
+            .replace("{name}", athleteName)
+            .replace("{round}", result.round.toString())
+            .replace("{mark}", markText)
+            .replace("{place}", place?.toString() ?: "")
+
+        val text = buildString {
+            append(
+                fill(
+                    when (type) {
+                        AnnouncementType.MARK -> format.mark
+                        AnnouncementType.FOUL -> format.foul
+                        AnnouncementType.PASS -> format.pass
+                    }
+                )
+            )
+            if (format.includeName && athleteName.isNotBlank()) {
+                // Name goes straight after the bib number
+                val bibEnd = indexOf(result.athleteBib).takeIf { it >= 0 }?.plus(result.athleteBib.length)
+                if (bibEnd != null) insert(bibEnd, fill(format.nameTemplate)) else append(fill(format.nameTemplate))
+            }
+            if (type == AnnouncementType.MARK) {
+                when {
+                    isNewLeader -> append(fill(format.newLeader))
+                    place != null -> append(fill(format.place))
+                }
+            }
+        }
+
+        return Announcement(
+            type = type,
+            athleteBib = result.athleteBib,
+            athleteName = athleteName,
+            round = result.round,
+            mark = result.distance.takeIf { type == AnnouncementType.MARK },
+            place = place,
+            isNewLeader = isNewLeader,
+            isPersonalBestInEvent = isPersonalBestInEvent,
+            text = text,
+            languageTag = format.languageTag
+        )
+    }
+}
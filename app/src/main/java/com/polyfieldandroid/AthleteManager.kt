@@ -7,6 +7,7 @@ import androidx.lifecycle.ViewModel
 import androidx.lifecycle.viewModelScope
 import com.google.gson.Gson
 import com.google.gson.reflect.TypeToken
+import kotlinx.coroutines.Job
 import kotlinx.coroutines.flow.MutableStateFlow
 import kotlinx.coroutines.flow.StateFlow
 import kotlinx.coroutines.flow.asStateFlow
@@ -32,8 +33,11 @@ data class CompetitionAthlete(
         return attempts.filter { it.round == round }
     }
     
+    /**
+     * Marks that count in the rankings; attempts under protest only when the referee allows it
+     */
     fun getValidMeasurements(): List<AthleteAttempt> {
-        return attempts.filter { it.isValid && it.distance != null }
+        return attempts.filter { it.countsForRanking() }
     }
     
     fun getBestMark(): Double? {
@@ -49,16 +53,34 @@ data class CompetitionAthlete(
  * Individual measurement data for a round
  */
 data class AthleteAttempt(
-    val id: String = java.util.UUID.randomUUID().toString(),
+    val id: String = SessionSources.newId(),
     val round: Int,
     val attemptNumber: Int = 1, // Always 1 for throws/horizontal jumps
+    val throwNumber: Int? = null, // Order in the event, from 1; kept when the attempt is remeasured
     val distance: Double? = null, // Distance in meters
     val windSpeed: Double? = null, // Wind speed in m/s
     val isValid: Boolean = true,
     val isPass: Boolean = false, // Whether this is a pass (valid but no distance)
-    val timestamp: Long = System.currentTimeMillis(),
-    val coordinates: ThrowCoordinate? = null // Landing coordinates for heatmap
+    val timestamp: Long = SessionSources.now(),
+    val measuredAt: Long? = null, // When the EDM was triggered, if measured by EDM
+    val coordinates: ThrowCoordinate? = null, // Landing coordinates for heatmap
+    val implementId: String? = null, // Implement thrown, from the implement registry
+    val review: AttemptReview? = null, // Protest or referee review, if one was raised
+    val foulMarginMm: Double? = null // Jumps: how far over the take-off line a foul was
 ) {
+    val isUnderReview: Boolean
+        get() = review != null && review.resolution == null
+
+    fun countsForRanking(): Boolean {
+        if (!isValid || distance == null) return false
+        val review = review ?: return true
+        return when (review.resolution) {
+            null -> review.includeInRankings
+            ReviewResolution.STANDS -> true
+            ReviewResolution.DISALLOWED -> false
+        }
+    }
+
     fun getDisplayMark(): String = when {
         !isValid -> "X"
         isPass -> "P"
@@ -67,6 +89,25 @@ data class AthleteAttempt(
     }
 }
 
+enum class ReviewResolution {
+    STANDS,     // Attempt counts as recorded
+    DISALLOWED  // Attempt kept on record but excluded from the rankings
+}
+
+/**
+ * A protest or referee review of one attempt, and its outcome once decided
+ */
+data class AttemptReview(
+    val reason: String,
+    val raisedBy: String,
+    val includeInRankings: Boolean = false, // Whether the mark counts while the review is open
+    val raisedAt: Long = SessionSources.now(),
+    val resolution: ReviewResolution? = null,
+    val resolvedBy: String? = null,
+    val resolutionNote: String? = null,
+    val resolvedAt: Long? = null
+)
+
 // ThrowCoordinate moved to shared data classes
 
 /**
@@ -101,6 +142,9 @@ class AthleteManagerViewModel(private val context: Context) : ViewModel() {
     // State management
     private val _athleteState = MutableStateFlow(AthleteManagementState())
     val athleteState: StateFlow<AthleteManagementState> = _athleteState.asStateFlow()
+
+    // Results rebuilt from the write-ahead log after a crash, merged into athletes as they load
+    private var recoveredResults: WalRecovery? = null
     
     init {
         loadSavedAthletes()
@@ -122,12 +166,9 @@ class AthleteManagerViewModel(private val context: Context) : ViewModel() {
                         club = serverAthlete.club,
                         isSelected = true // All athletes selected by default
                     )
-                }?.sortedBy { it.order } ?: emptyList()
+                }?.sortedBy { it.order }?.let { mergeRecoveredResults(it) } ?: emptyList()
                 
-                _athleteState.value = _athleteState.value.copy(
-                    athletes = athletes,
-                    selectedAthletes = athletes.filter { it.isSelected },
-                    rotationOrder = athletes.filter { it.isSelected },
+                _athleteState.value = withRecomputedStandings(athletes).copy(
                     isLoading = false,
                     errorMessage = null
                 )
@@ -152,13 +193,9 @@ class AthleteManagerViewModel(private val context: Context) : ViewModel() {
             val savedAthletesJson = preferences.getString(PREF_MANUAL_ATHLETES, null)
             if (savedAthletesJson != null) {
                 val listType = object : TypeToken<List<CompetitionAthlete>>() {}.type
-                val athletes = gson.fromJson<List<CompetitionAthlete>>(savedAthletesJson, listType)
+                val athletes = mergeRecoveredResults(gson.fromJson<List<CompetitionAthlete>>(savedAthletesJson, listType))
                 
-                _athleteState.value = _athleteState.value.copy(
-                    athletes = athletes,
-                    selectedAthletes = athletes.filter { it.isSelected },
-                    rotationOrder = athletes.filter { it.isSelected }.sortedBy { it.order }
-                )
+                _athleteState.value = withRecomputedStandings(athletes)
                 
                 Log.d(TAG, "Loaded ${athletes.size} manual athletes")
             }
@@ -184,13 +221,8 @@ class AthleteManagerViewModel(private val context: Context) : ViewModel() {
             )
             
             currentAthletes.add(newAthlete)
-            val selectedAthletes = currentAthletes.filter { it.isSelected }
             
-            _athleteState.value = _athleteState.value.copy(
-                athletes = currentAthletes,
-                selectedAthletes = selectedAthletes,
-                rotationOrder = selectedAthletes.sortedBy { it.order }
-            )
+            _athleteState.value = withRecomputedStandings(currentAthletes)
             
             saveManualAthletes(currentAthletes)
             Log.d(TAG, "Added manual athlete: $bib - $name")
@@ -205,13 +237,7 @@ class AthleteManagerViewModel(private val context: Context) : ViewModel() {
             val currentAthletes = _athleteState.value.athletes.toMutableList()
             currentAthletes.removeAll { it.bib == bib }
             
-            val selectedAthletes = currentAthletes.filter { it.isSelected }
-            
-            _athleteState.value = _athleteState.value.copy(
-                athletes = currentAthletes,
-                selectedAthletes = selectedAthletes,
-                rotationOrder = selectedAthletes.sortedBy { it.order }
-            )
+            _athleteState.value = withRecomputedStandings(currentAthletes)
             
             saveManualAthletes(currentAthletes)
             Log.d(TAG, "Removed athlete: $bib")
@@ -230,13 +256,7 @@ class AthleteManagerViewModel(private val context: Context) : ViewModel() {
                 val athlete = currentAthletes[athleteIndex]
                 currentAthletes[athleteIndex] = athlete.copy(isSelected = !athlete.isSelected)
                 
-                val selectedAthletes = currentAthletes.filter { it.isSelected }
-                
-                _athleteState.value = _athleteState.value.copy(
-                    athletes = currentAthletes,
-                    selectedAthletes = selectedAthletes,
-                    rotationOrder = selectedAthletes.sortedBy { it.order }
-                )
+                _athleteState.value = withRecomputedStandings(currentAthletes)
                 
                 Log.d(TAG, "Toggled selection for athlete $bib: ${athlete.isSelected}")
             }
@@ -254,7 +274,12 @@ class AthleteManagerViewModel(private val context: Context) : ViewModel() {
         isValid: Boolean,
         isPass: Boolean = false,
         windSpeed: Double? = null,
-        coordinates: ThrowCoordinate? = null
+        coordinates: ThrowCoordinate? = null,
+        implementId: String? = null,
+        foulMarginMm: Double? = null,
+        measuredAt: Long? = null,
+        attemptId: String? = null,
+        throwNumber: Int? = null
     ) {
         viewModelScope.launch {
             val currentAthletes = _athleteState.value.athletes.toMutableList()
@@ -264,13 +289,18 @@ class AthleteManagerViewModel(private val context: Context) : ViewModel() {
                 val athlete = currentAthletes[athleteIndex]
                 
                 val measurement = AthleteAttempt(
+                    id = attemptId ?: SessionSources.newId(),
                     round = round,
                     attemptNumber = 1, // Always 1 for throws/horizontal jumps
+                    throwNumber = throwNumber,
                     distance = distance,
                     windSpeed = windSpeed,
                     isValid = isValid,
                     isPass = isPass,
-                    coordinates = coordinates
+                    coordinates = coordinates,
+                    implementId = implementId,
+                    foulMarginMm = foulMarginMm.takeIf { !isValid },
+                    measuredAt = measuredAt
                 )
                 
                 // Replace existing round measurement or add new one
@@ -294,19 +324,12 @@ class AthleteManagerViewModel(private val context: Context) : ViewModel() {
                 }
                 
                 // Create updated athlete with new data
-                val updatedAthlete = athlete.copy(
+                currentAthletes[athleteIndex] = athlete.copy(
                     attempts = updatedMeasurements,
-                    heatmapData = updatedHeatmapData,
-                    currentBestMark = updatedMeasurements.filter { it.isValid && it.distance != null }
-                        .maxByOrNull { it.distance ?: 0.0 }?.distance
+                    heatmapData = updatedHeatmapData
                 )
-                currentAthletes[athleteIndex] = updatedAthlete
                 
-                _athleteState.value = _athleteState.value.copy(
-                    athletes = currentAthletes,
-                    selectedAthletes = currentAthletes.filter { it.isSelected },
-                    rotationOrder = currentAthletes.filter { it.isSelected }.sortedBy { it.order }
-                )
+                _athleteState.value = withRecomputedStandings(currentAthletes)
                 
                 saveAthleteResults()
                 Log.d(TAG, "Recorded measurement for athlete $athleteBib: ${measurement.getDisplayMark()}")
@@ -314,6 +337,140 @@ class AthleteManagerViewModel(private val context: Context) : ViewModel() {
         }
     }
     
+    /**
+     * Re-express stored throw coordinates, e.g. into a new station setup's frame.
+     * Nothing is saved unless some coordinate actually changed.
+     */
+    fun realignHeatmapData(transform: (ThrowCoordinate) -> ThrowCoordinate) {
+        viewModelScope.launch {
+            var changed = false
+            val currentAthletes = _athleteState.value.athletes.map { athlete ->
+                val realigned = athlete.heatmapData.map(transform)
+                if (realigned == athlete.heatmapData) {
+                    athlete
+                } else {
+                    changed = true
+                    athlete.copy(heatmapData = realigned.toMutableList())
+                }
+            }
+            if (!changed) return@launch
+
+            _athleteState.value = withRecomputedStandings(currentAthletes)
+
+            saveAthleteResults()
+            Log.d(TAG, "Realigned throw coordinates to the current station setup")
+        }
+    }
+
+    /**
+     * Change the implement recorded for an athlete's attempt in a round
+     */
+    fun setAttemptImplement(athleteBib: String, round: Int, implementId: String?) {
+        updateAttempt(athleteBib, round) { it.copy(implementId = implementId) }
+        Log.d(TAG, "Attempt for athlete $athleteBib, round $round tagged with implement ${implementId ?: "none"}")
+    }
+
+    fun setAttemptFoulMargin(athleteBib: String, round: Int, foulMarginMm: Double?): Job {
+        Log.d(TAG, "Foul margin for athlete $athleteBib, round $round: ${foulMarginMm ?: "cleared"} mm")
+        return updateAttempt(athleteBib, round) { if (it.isValid) it else it.copy(foulMarginMm = foulMarginMm) }
+    }
+
+    /**
+     * Raise, resolve or clear the review of an athlete's attempt in a round
+     */
+    fun setAttemptReview(athleteBib: String, round: Int, review: AttemptReview?): Job {
+        Log.d(TAG, "Attempt for athlete $athleteBib, round $round review: ${review?.resolution ?: if (review != null) "open" else "none"}")
+        return updateAttempt(athleteBib, round) { it.copy(review = review) }
+    }
+
+    /**
+     * Change one attempt; the returned job completes once the change is in the athlete state
+     */
+    private fun updateAttempt(athleteBib: String, round: Int, transform: (AthleteAttempt) -> AthleteAttempt): Job =
+        viewModelScope.launch {
+            val currentAthletes = _athleteState.value.athletes.toMutableList()
+            val athleteIndex = currentAthletes.indexOfFirst { it.bib == athleteBib }
+            if (athleteIndex == -1) return@launch
+
+            val athlete = currentAthletes[athleteIndex]
+            if (athlete.attempts.none { it.round == round }) return@launch
+            val updatedMeasurements = athlete.attempts.map { if (it.round == round) transform(it) else it }.toMutableList()
+            currentAthletes[athleteIndex] = athlete.copy(attempts = updatedMeasurements)
+
+            _athleteState.value = withRecomputedStandings(currentAthletes)
+
+            saveAthleteResults()
+        }
+
+    /**
+     * Clear an athlete's attempt for a round so it can be measured again
+     */
+    fun voidMeasurement(athleteBib: String, round: Int): Job =
+        viewModelScope.launch {
+            val currentAthletes = _athleteState.value.athletes.toMutableList()
+            val athleteIndex = currentAthletes.indexOfFirst { it.bib == athleteBib }
+            if (athleteIndex == -1) return@launch
+
+            val athlete = currentAthletes[athleteIndex]
+            val updatedMeasurements = athlete.attempts.filter { it.round != round }.toMutableList()
+            currentAthletes[athleteIndex] = athlete.copy(
+                attempts = updatedMeasurements,
+                heatmapData = athlete.heatmapData.filter { it.round != round }.toMutableList()
+            )
+
+            _athleteState.value = withRecomputedStandings(currentAthletes)
+
+            saveAthleteResults()
+            Log.d(TAG, "Voided measurement for athlete $athleteBib, round $round")
+        }
+
+    /**
+     * Apply results rebuilt from the write-ahead log to the loaded athletes, and to
+     * any loaded later in this session
+     */
+    fun applyRecoveredResults(recovery: WalRecovery) {
+        recoveredResults = recovery
+        _athleteState.value = withRecomputedStandings(mergeRecoveredResults(_athleteState.value.athletes))
+        Log.d(TAG, "Applied recovered results for ${recovery.attempts.size} athletes")
+    }
+
+    /**
+     * Forget recovered results once a competition has ended
+     */
+    fun clearRecoveredResults() {
+        recoveredResults = null
+    }
+
+    private fun mergeRecoveredResults(athletes: List<CompetitionAthlete>): List<CompetitionAthlete> {
+        val recovery = recoveredResults ?: return athletes
+        return athletes.map { athlete ->
+            val attempts = recovery.attempts[athlete.bib] ?: return@map athlete
+            athlete.copy(
+                attempts = attempts.toMutableList(),
+                heatmapData = (recovery.heatmapData[athlete.bib] ?: emptyList()).toMutableList()
+            )
+        }
+    }
+
+    /**
+     * State holding these athletes, each with their best mark recomputed from their attempts,
+     * and the selection and rotation order that follow from them
+     */
+    private fun withRecomputedStandings(athletes: List<CompetitionAthlete>): AthleteManagementState {
+        val standings = athletes.map { athlete ->
+            athlete.copy(
+                currentBestMark = athlete.attempts.filter { it.countsForRanking() }
+                    .maxByOrNull { it.distance ?: 0.0 }?.distance
+            )
+        }
+        val selected = standings.filter { it.isSelected }
+        return _athleteState.value.copy(
+            athletes = standings,
+            selectedAthletes = selected,
+            rotationOrder = selected.sortedBy { it.order }
+        )
+    }
+
     /**
      * Navigate to next athlete in rotation
      */
@@ -580,12 +737,9 @@ class AthleteManagerViewModel(private val context: Context) : ViewModel() {
         // Find the athlete's index in the rotation order
         val rotationIndex = _athleteState.value.rotationOrder.indexOfFirst { it.bib == athlete.bib }
 
-        _athleteState.value = _athleteState.value.copy(
-            athletes = updatedAthletes,
+        _athleteState.value = withRecomputedStandings(updatedAthletes).copy(
             currentAthlete = competitionAthlete,
-            currentAthleteIndex = if (rotationIndex >= 0) rotationIndex else 0,
-            selectedAthletes = updatedAthletes.filter { it.isSelected },
-            rotationOrder = updatedAthletes.filter { it.isSelected }.sortedBy { it.order }
+            currentAthleteIndex = if (rotationIndex >= 0) rotationIndex else 0
         )
 
         Log.d(TAG, "Selected athlete: ${athlete.name} (${athlete.bib}) at rotation index $rotationIndex. Total athletes: ${updatedAthletes.size}")
@@ -0,0 +1,81 @@
+package com.polyfieldandroid
+
+import kotlin.math.pow
+
+/**
+ * Atmosphere used for one correction, with where each value came from
+ */
+data class AtmosphericConditions(
+    val altitudeM: Double,
+    val pressureHpa: Double,
+    val pressureMeasured: Boolean,  // False when derived from the venue altitude
+    val temperatureC: Double,
+    val temperatureMeasured: Boolean, // False when the reference temperature was assumed
+    val ppm: Double,
+    val humidityPct: Double? = null  // Relative humidity; null treats the air as dry
+) {
+    fun toMap(): Map<String, Any> = listOfNotNull(
+        humidityPct?.let { "humidityPct" to it }
+    ).toMap() + mapOf(
+        "altitudeM" to altitudeM,
+        "pressureHpa" to pressureHpa,
+        "pressureMeasured" to pressureMeasured,
+        "temperatureC" to temperatureC,
+        "temperatureMeasured" to temperatureMeasured,
+        "ppm" to ppm
+    )
+}
+
+/**
+ * Atmospheric correction for EDM slope distances
+ * The EDM's modulation wavelength depends on air density, so distances are scaled by a
+ * parts-per-million factor from pressure and temperature. Zero at 15°C and 1013.25 hPa.
+ */
+object AtmosphericCorrection {
+
+    const val REFERENCE_TEMPERATURE_C = 15.0
+    const val SEA_LEVEL_PRESSURE_HPA = 1013.25
+
+    // Marks set at or above this altitude are annotated "A" under World Athletics rules
+    const val ALTITUDE_ANNOTATION_M = 1000.0
+
+    /**
+     * Standard-atmosphere pressure at an altitude, for venues without a barometer
+     */
+    fun standardPressureAt(altitudeM: Double): Double =
+        SEA_LEVEL_PRESSURE_HPA * (1 - 2.25577e-5 * altitudeM).pow(5.25588)
+
+    /**
+     * Correction in ppm at the given pressure and temperature. Water vapour lowers the
+     * refractive index slightly, so humid air adds a little under a ppm in temperate weather.
+     */
+    fun ppm(pressureHpa: Double, temperatureC: Double, humidityPct: Double? = null): Double {
+        val kelvin = 273.15 + temperatureC
+        val vapourHpa = humidityPct?.let { it / 100 * saturationVapourPressure(temperatureC) } ?: 0.0
+        return 279.85 - 79.585 * pressureHpa / kelvin + 11.27 * vapourHpa / kelvin
+    }
+
+    /**
+     * Saturation vapour pressure over water in hPa (Magnus formula)
+     */
+    fun saturationVapourPressure(temperatureC: Double): Double =
+        6.1078 * 10.0.pow(7.5 * temperatureC / (237.3 + temperatureC))
+
+    fun conditions(altitudeM: Double, pressureHpa: Double?, temperatureC: Double?, humidityPct: Double? = null): AtmosphericConditions {
+        val pressure = pressureHpa ?: standardPressureAt(altitudeM)
+        val temperature = temperatureC ?: REFERENCE_TEMPERATURE_C
+        return AtmosphericConditions(
+            altitudeM = altitudeM,
+            pressureHpa = pressure,
+            pressureMeasured = pressureHpa != null,
+            temperatureC = temperature,
+            temperatureMeasured = temperatureC != null,
+            ppm = ppm(pressure, temperature, humidityPct),
+            humidityPct = humidityPct
+        )
+    }
+
+    fun apply(slopeDistanceMm: Double, ppm: Double): Double = slopeDistanceMm * (1 + ppm * 1e-6)
+
+    fun isAltitudeAnnotated(altitudeM: Double?): Boolean = altitudeM != null && altitudeM >= ALTITUDE_ANNOTATION_M
+}
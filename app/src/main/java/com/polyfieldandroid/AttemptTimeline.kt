@@ -0,0 +1,105 @@
+package com.polyfieldandroid
+
+import android.content.Context
+import android.util.Log
+import com.google.gson.Gson
+import java.io.File
+
+/**
+ * Where the wind samples paired with an attempt were taken from
+ */
+enum class AttemptWindowKind {
+    RULE_WINDOW,            // The jump's rule wind window, started when the athlete was on the runway
+    SINCE_PREVIOUS_ATTEMPT  // No window taken: from the previous result to this measurement, which contains the flight
+}
+
+/**
+ * One attempt on the tablet's clock with the wind gauge samples that fell in its window.
+ * EDM and wind times are both tablet time; gauges that stamp their own output have had
+ * their clock offset removed before sampling.
+ */
+data class AttemptTimeline(
+    val attemptId: String,
+    val athleteBib: String,
+    val round: Int,
+    val measuredAt: Long?,          // EDM trigger
+    val windowFrom: Long,
+    val windowTo: Long,
+    val windowKind: AttemptWindowKind,
+    val windSamples: List<WindSample>,
+    val windClockOffsetMs: Long?,   // Gauge clock minus tablet clock, when the gauge stamps its output
+    val recordedAt: Long = SessionSources.now()
+) {
+    fun toMap(): Map<String, Any?> = mapOf(
+        "attemptId" to attemptId,
+        "athleteBib" to athleteBib,
+        "round" to round,
+        "measuredAt" to measuredAt,
+        "windowFrom" to windowFrom,
+        "windowTo" to windowTo,
+        "windowKind" to windowKind.name,
+        "windSamples" to windSamples.map {
+            mapOf("timestamp" to it.timestamp, "windSpeed" to it.windSpeed, "windDirection" to it.windDirection, "durationMs" to it.durationMs)
+        },
+        "windClockOffsetMs" to windClockOffsetMs,
+        "recordedAt" to recordedAt
+    )
+}
+
+/**
+ * Attempt Timeline Log
+ * Pairs each attempt with the gauge's samples as they were buffered when it was recorded,
+ * since the gauge buffer only reaches back a few minutes and the conditions log keeps one
+ * sample a minute. Appended to a file until the next competition starts.
+ */
+class AttemptTimelineLog(context: Context) {
+
+    companion object {
+        private const val TAG = "AttemptTimelineLog"
+        private const val FILE_NAME = "attempt_timeline.jsonl"
+        private val lock = Any()
+    }
+
+    private val file = File(context.applicationContext.filesDir, FILE_NAME)
+    private val gson = Gson()
+
+    fun append(entry: AttemptTimeline) {
+        synchronized(lock) {
+            try {
+                file.appendText(gson.toJson(entry) + "\n", Charsets.UTF_8)
+            } catch (e: Exception) {
+                Log.e(TAG, "Failed to record attempt timeline: ${e.message}")
+            }
+        }
+    }
+
+    /**
+     * Latest entry per attempt; a remeasured attempt replaces its earlier entry
+     */
+    fun getEntries(): List<AttemptTimeline> {
+        val lines = synchronized(lock) {
+            if (file.exists()) file.readLines(Charsets.UTF_8) else emptyList()
+        }
+
+        val latest = linkedMapOf<String, AttemptTimeline>()
+        lines.forEach { line ->
+            if (line.isBlank()) return@forEach
+            try {
+                gson.fromJson(line, AttemptTimeline::class.java)?.let { latest[it.attemptId] = it }
+            } catch (e: Exception) {
+                Log.w(TAG, "Skipping unreadable entry: ${e.message}")
+            }
+        }
+        return latest.values.sortedBy { it.windowFrom }
+    }
+
+    fun find(attemptId: String): AttemptTimeline? = getEntries().find { it.attemptId == attemptId }
+
+    fun reset() {
+        synchronized(lock) {
+            if (file.exists() && !file.delete()) {
+                Log.e(TAG, "Could not delete ${file.name}")
+            }
+        }
+    }
+}
@@ -0,0 +1,293 @@
+package com.polyfieldandroid
+
+import android.annotation.SuppressLint
+import android.bluetooth.BluetoothAdapter
+import android.bluetooth.BluetoothDevice
+import android.bluetooth.BluetoothGatt
+import android.bluetooth.BluetoothGattCallback
+import android.bluetooth.BluetoothGattCharacteristic
+import android.bluetooth.BluetoothGattDescriptor
+import android.bluetooth.BluetoothManager
+import android.bluetooth.BluetoothProfile
+import android.bluetooth.BluetoothStatusCodes
+import android.content.Context
+import android.os.Build
+import android.util.Log
+import java.io.ByteArrayOutputStream
+import java.io.IOException
+import java.io.InputStream
+import java.io.OutputStream
+import java.util.UUID
+import java.util.concurrent.CountDownLatch
+import java.util.concurrent.LinkedBlockingQueue
+import java.util.concurrent.TimeUnit
+
+/**
+ * BLE GATT Link
+ * A notifying characteristic presented as a byte stream: notifications are queued for
+ * reading, and writes go to the write characteristic (the notifying one if it is
+ * writable and no other is given), split to fit the default ATT MTU.
+ */
+@SuppressLint("MissingPermission")
+class BleGattLink(
+    private val context: Context,
+    private val device: BluetoothDevice,
+    private val serviceUuid: UUID,
+    private val notifyUuid: UUID,
+    private val writeUuid: UUID? = null
+) : StreamLink() {
+
+    companion object {
+        private const val TAG = "BleGattLink"
+        private const val CONNECT_TIMEOUT_MS = 15000L
+        private const val WRITE_TIMEOUT_MS = 2000L
+        private const val CHUNK_SIZE = 20               // Default ATT MTU (23) less the 3-byte header
+        private const val MAX_BUFFERED_BYTES = 64 * 1024 // Oldest notifications dropped beyond this
+
+        private val CCCD_UUID: UUID = UUID.fromString("00002902-0000-1000-8000-00805f9b34fb")
+    }
+
+    override val address: String = device.address
+
+    private val notifications = ReceiveBuffer(MAX_BUFFERED_BYTES) { !closed && gattConnected }
+    override val source: InputStream = notifications
+    override val sink: OutputStream = CharacteristicOutputStream()
+
+    @Volatile private var gatt: BluetoothGatt? = null
+    @Volatile private var writeCharacteristic: BluetoothGattCharacteristic? = null
+    @Volatile private var gattConnected = false
+
+    private val ready = CountDownLatch(1)
+    @Volatile private var setupError: String? = null
+    private val writeResults = LinkedBlockingQueue<Int>()
+
+    private val callback = object : BluetoothGattCallback() {
+
+        override fun onConnectionStateChange(gatt: BluetoothGatt, status: Int, newState: Int) {
+            if (newState == BluetoothProfile.STATE_CONNECTED && status == BluetoothGatt.GATT_SUCCESS) {
+                gattConnected = true
+                if (!gatt.discoverServices()) fail("Service discovery could not start")
+            } else if (newState == BluetoothProfile.STATE_DISCONNECTED) {
+                gattConnected = false
+                Log.w(TAG, "$address disconnected (GATT status $status)")
+                fail("Connection to $address failed (GATT status $status)")
+            }
+        }
+
+        override fun onServicesDiscovered(gatt: BluetoothGatt, status: Int) {
+            if (status != BluetoothGatt.GATT_SUCCESS) {
+                fail("Service discovery on $address failed (GATT status $status)")
+                return
+            }
+            val service = gatt.getService(serviceUuid)
+            if (service == null) {
+                fail("Service $serviceUuid not found on $address")
+                return
+            }
+            val notify = service.getCharacteristic(notifyUuid)
+            if (notify == null) {
+                fail("Characteristic $notifyUuid not found on $address")
+                return
+            }
+            writeCharacteristic = writeUuid?.let { service.getCharacteristic(it) }
+                ?: notify.takeIf { it.isWritable() }
+            enableNotifications(gatt, notify)
+        }
+
+        override fun onDescriptorWrite(gatt: BluetoothGatt, descriptor: BluetoothGattDescriptor, status: Int) {
+            if (descriptor.uuid != CCCD_UUID) return
+            if (status == BluetoothGatt.GATT_SUCCESS) {
+                Log.d(TAG, "Subscribed to $notifyUuid on $address")
+                ready.countDown()
+            } else {
+                fail("Subscribing to $notifyUuid failed (GATT status $status)")
+            }
+        }
+
+        // Android 13+ delivers the value directly; older versions only through the characteristic
+        override fun onCharacteristicChanged(gatt: BluetoothGatt, characteristic: BluetoothGattCharacteristic, value: ByteArray) {
+            if (characteristic.uuid == notifyUuid) notifications.append(value)
+        }
+
+        @Deprecated("Used before Android 13")
+        @Suppress("DEPRECATION")
+        override fun onCharacteristicChanged(gatt: BluetoothGatt, characteristic: BluetoothGattCharacteristic) {
+            if (characteristic.uuid == notifyUuid) notifications.append(characteristic.value)
+        }
+
+        override fun onCharacteristicWrite(gatt: BluetoothGatt, characteristic: BluetoothGattCharacteristic, status: Int) {
+            writeResults.offer(status)
+        }
+    }
+
+    /**
+     * Connect, discover the service and subscribe, blocking until notifications are on
+     */
+    fun open() {
+        gatt = if (Build.VERSION.SDK_INT >= Build.VERSION_CODES.M) {
+            device.connectGatt(context, false, callback, BluetoothDevice.TRANSPORT_LE)
+        } else {
+            device.connectGatt(context, false, callback)
+        }
+        if (!ready.await(CONNECT_TIMEOUT_MS, TimeUnit.MILLISECONDS)) {
+            close()
+            throw IOException("Timed out connecting to $address")
+        }
+        setupError?.let { error ->
+            close()
+            throw IOException(error)
+        }
+    }
+
+    override fun closeChannel() {
+        gattConnected = false
+        gatt?.let {
+            it.disconnect()
+            it.close()
+        }
+        gatt = null
+    }
+
+    override fun isChannelConnected(): Boolean = gattConnected
+
+    private fun fail(message: String) {
+        if (ready.count > 0) {
+            setupError = message
+            ready.countDown()
+        }
+    }
+
+    private fun BluetoothGattCharacteristic.isWritable(): Boolean =
+        properties and (BluetoothGattCharacteristic.PROPERTY_WRITE or BluetoothGattCharacteristic.PROPERTY_WRITE_NO_RESPONSE) != 0
+
+    @Suppress("DEPRECATION")
+    private fun enableNotifications(gatt: BluetoothGatt, characteristic: BluetoothGattCharacteristic) {
+        val value = when {
+            characteristic.properties and BluetoothGattCharacteristic.PROPERTY_NOTIFY != 0 ->
+                BluetoothGattDescriptor.ENABLE_NOTIFICATION_VALUE
+            characteristic.properties and BluetoothGattCharacteristic.PROPERTY_INDICATE != 0 ->
+                BluetoothGattDescriptor.ENABLE_INDICATION_VALUE
+            else -> {
+                fail("Characteristic $notifyUuid does not notify")
+                return
+            }
+        }
+        if (!gatt.setCharacteristicNotification(characteristic, true)) {
+            fail("Notifications for $notifyUuid could not be enabled")
+            return
+        }
+        val descriptor = characteristic.getDescriptor(CCCD_UUID)
+        if (descriptor == null) {
+            // Some devices notify without a configuration descriptor
+            ready.countDown()
+            return
+        }
+        val started = if (Build.VERSION.SDK_INT >= Build.VERSION_CODES.TIRAMISU) {
+            gatt.writeDescriptor(descriptor, value) == BluetoothStatusCodes.SUCCESS
+        } else {
+            descriptor.value = value
+            gatt.writeDescriptor(descriptor)
+        }
+        if (!started) fail("Subscribing to $notifyUuid could not start")
+    }
+
+    @Suppress("DEPRECATION")
+    private fun writeChunks(data: ByteArray) {
+        val characteristic = writeCharacteristic ?: throw IOException("$address has no writable characteristic")
+        val gatt = gatt ?: throw IOException("Link to $address closed")
+        val writeType = if (characteristic.properties and BluetoothGattCharacteristic.PROPERTY_WRITE != 0) {
+            BluetoothGattCharacteristic.WRITE_TYPE_DEFAULT
+        } else {
+            BluetoothGattCharacteristic.WRITE_TYPE_NO_RESPONSE
+        }
+
+        for (offset in data.indices step CHUNK_SIZE) {
+            val chunk = data.copyOfRange(offset, minOf(offset + CHUNK_SIZE, data.size))
+            writeResults.clear()
+            val started = if (Build.VERSION.SDK_INT >= Build.VERSION_CODES.TIRAMISU) {
+                gatt.writeCharacteristic(characteristic, chunk, writeType) == BluetoothStatusCodes.SUCCESS
+            } else {
+                characteristic.writeType = writeType
+                characteristic.value = chunk
+                gatt.writeCharacteristic(characteristic)
+            }
+            if (!started) throw IOException("Write to $address could not start")
+
+            // Writes without response are still confirmed once the stack has taken the packet
+            val status = writeResults.poll(WRITE_TIMEOUT_MS, TimeUnit.MILLISECONDS)
+                ?: throw IOException("Write to $address timed out")
+            if (status != BluetoothGatt.GATT_SUCCESS) throw IOException("Write to $address failed (GATT status $status)")
+        }
+    }
+
+    private inner class CharacteristicOutputStream : OutputStream() {
+        private val pending = ByteArrayOutputStream()
+
+        override fun write(b: Int) = synchronized(pending) { pending.write(b) }
+
+        override fun write(b: ByteArray, off: Int, len: Int) = synchronized(pending) { pending.write(b, off, len) }
+
+        override fun flush() {
+            val data = synchronized(pending) { pending.toByteArray().also { pending.reset() } }
+            if (data.isNotEmpty()) writeChunks(data)
+        }
+    }
+}
+
+/**
+ * BLE GATT Module
+ * Connects to Bluetooth Low Energy devices - modern wind gauges in particular - by
+ * subscribing to a notifying characteristic. UUIDs may be given in full or as 16-bit
+ * short forms such as "2A72".
+ */
+class BleGattModule(private val context: Context) {
+
+    companion object {
+        private const val TAG = "BleGatt"
+        private const val BASE_UUID_SUFFIX = "-0000-1000-8000-00805f9b34fb"
+
+        fun parseUuid(uuid: String): UUID {
+            val trimmed = uuid.trim()
+            return when (trimmed.length) {
+                4 -> UUID.fromString("0000$trimmed$BASE_UUID_SUFFIX")
+                8 -> UUID.fromString("$trimmed$BASE_UUID_SUFFIX")
+                else -> UUID.fromString(trimmed)
+            }
+        }
+    }
+
+    private val adapter: BluetoothAdapter?
+        get() = (context.getSystemService(Context.BLUETOOTH_SERVICE) as? BluetoothManager)?.adapter
+
+    /**
+     * Connect and subscribe, blocking until notifications are on. Failures are IOExceptions
+     * with a message for the operator.
+     */
+    @SuppressLint("MissingPermission")
+    fun connectLink(
+        deviceAddress: String,
+        serviceUuid: String,
+        characteristicUuid: String,
+        writeCharacteristicUuid: String? = null
+    ): BleGattLink {
+        val adapter = adapter ?: throw IOException("Bluetooth is not available on this device")
+        if (!adapter.isEnabled) throw IOException("Bluetooth is turned off")
+        if (!BluetoothAdapter.checkBluetoothAddress(deviceAddress)) {
+            throw IOException("Invalid Bluetooth address: $deviceAddress")
+        }
+
+        val uuids = try {
+            Triple(parseUuid(serviceUuid), parseUuid(characteristicUuid), writeCharacteristicUuid?.let { parseUuid(it) })
+        } catch (e: IllegalArgumentException) {
+            throw IOException("Invalid UUID: ${e.message}")
+        }
+
+        return try {
+            val device = adapter.getRemoteDevice(deviceAddress)
+            BleGattLink(context, device, uuids.first, uuids.second, uuids.third).apply { open() }
+        } catch (e: SecurityException) {
+            Log.e(TAG, "Bluetooth permission not granted: ${e.message}")
+            throw IOException("Bluetooth permission not granted")
+        }
+    }
+}
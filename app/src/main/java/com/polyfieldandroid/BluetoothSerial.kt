@@ -0,0 +1,130 @@
+package com.polyfieldandroid
+
+import android.annotation.SuppressLint
+import android.bluetooth.BluetoothAdapter
+import android.bluetooth.BluetoothManager
+import android.bluetooth.BluetoothSocket
+import android.content.Context
+import android.util.Log
+import kotlinx.coroutines.Dispatchers
+import kotlinx.coroutines.withContext
+import java.io.IOException
+import java.io.InputStream
+import java.io.OutputStream
+import java.util.UUID
+
+/**
+ * A device paired with this tablet, as offered for connection
+ */
+data class PairedBluetoothDevice(
+    val name: String,
+    val address: String,        // MAC address, e.g. "00:11:22:33:44:55"
+    val majorDeviceClass: Int?
+)
+
+data class BluetoothOpenResult(
+    val link: BluetoothSerialLink? = null,
+    val error: String? = null
+)
+
+/**
+ * Bluetooth Serial Link
+ * An open RFCOMM (SPP) channel
+ */
+class BluetoothSerialLink(private val bluetoothSocket: BluetoothSocket) : StreamLink() {
+
+    override val address: String = bluetoothSocket.remoteDevice.address
+
+    override val source: InputStream = bluetoothSocket.inputStream
+
+    override val sink: OutputStream = bluetoothSocket.outputStream
+
+    override fun closeChannel() = bluetoothSocket.close()
+
+    override fun isChannelConnected(): Boolean = bluetoothSocket.isConnected
+}
+
+/**
+ * Bluetooth Serial Module
+ * Opens Bluetooth Classic serial port profile (SPP) channels to paired devices - the
+ * adapters many EDMs and wind gauges ship with. Pairing itself is left to system settings.
+ */
+class BluetoothSerialModule(private val context: Context) {
+
+    companion object {
+        private const val TAG = "BluetoothSerial"
+
+        // Well-known serial port profile service UUID
+        private val SPP_UUID: UUID = UUID.fromString("00001101-0000-1000-8000-00805F9B34FB")
+    }
+
+    private val adapter: BluetoothAdapter?
+        get() = (context.getSystemService(Context.BLUETOOTH_SERVICE) as? BluetoothManager)?.adapter
+
+    fun isAvailable(): Boolean = adapter?.isEnabled == true
+
+    /**
+     * Devices paired with this tablet; empty if Bluetooth is off or permission is missing
+     */
+    @SuppressLint("MissingPermission")
+    fun getPairedDevices(): List<PairedBluetoothDevice> {
+        val adapter = adapter ?: return emptyList()
+        return try {
+            adapter.bondedDevices.orEmpty().map { device ->
+                PairedBluetoothDevice(
+                    name = device.name ?: device.address,
+                    address = device.address,
+                    majorDeviceClass = device.bluetoothClass?.majorDeviceClass
+                )
+            }.sortedBy { it.name }
+        } catch (e: SecurityException) {
+            Log.e(TAG, "Bluetooth permission not granted: ${e.message}")
+            emptyList()
+        }
+    }
+
+    /**
+     * Open an SPP channel to a paired device
+     */
+    suspend fun open(macAddress: String): BluetoothOpenResult = withContext(Dispatchers.IO) {
+        try {
+            BluetoothOpenResult(link = connectLink(macAddress))
+        } catch (e: IOException) {
+            BluetoothOpenResult(error = e.message)
+        }
+    }
+
+    /**
+     * Open an SPP channel, blocking for the RFCOMM connect - several seconds for a device
+     * that is off or out of range. Failures are IOExceptions with a message for the operator.
+     */
+    @SuppressLint("MissingPermission")
+    fun connectLink(macAddress: String): BluetoothSerialLink {
+        val adapter = adapter ?: throw IOException("Bluetooth is not available on this device")
+        if (!adapter.isEnabled) throw IOException("Bluetooth is turned off")
+        if (!BluetoothAdapter.checkBluetoothAddress(macAddress)) {
+            throw IOException("Invalid Bluetooth address: $macAddress")
+        }
+
+        var socket: BluetoothSocket? = null
+        try {
+            val device = adapter.getRemoteDevice(macAddress)
+            // Discovery slows RFCOMM connects down considerably
+            adapter.cancelDiscovery()
+            socket = device.createRfcommSocketToServiceRecord(SPP_UUID)
+            socket.connect()
+            Log.d(TAG, "SPP channel open to ${device.name ?: macAddress}")
+            return BluetoothSerialLink(socket)
+        } catch (e: SecurityException) {
+            Log.e(TAG, "Bluetooth permission not granted: ${e.message}")
+            throw IOException("Bluetooth permission not granted")
+        } catch (e: IOException) {
+            Log.e(TAG, "SPP connect to $macAddress failed: ${e.message}")
+            try {
+                socket?.close()
+            } catch (ignored: IOException) {
+            }
+            throw IOException("Could not connect to $macAddress. Check it is switched on, in range and paired")
+        }
+    }
+}
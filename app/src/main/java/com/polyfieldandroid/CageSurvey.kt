@@ -0,0 +1,187 @@
+package com.polyfieldandroid
+
+import android.content.Context
+import android.util.Log
+import com.google.gson.Gson
+import com.google.gson.reflect.TypeToken
+import kotlin.math.abs
+import kotlin.math.atan2
+import kotlin.math.hypot
+
+/**
+ * What the cage survey expects to be measured next
+ */
+enum class CageSurveyStep {
+    GATE_RIGHT,  // Inner edge of the netting at the right-hand end of the front opening
+    GATE_LEFT,
+    COMPLETE
+}
+
+/**
+ * One end of the cage's front opening, in the sector frame: x across the bisector
+ * (right positive), y along it
+ */
+data class CageGatePoint(
+    val x: Double,
+    val y: Double,
+    val angleFromBisectorDeg: Double,
+    val clearOfSector: Boolean  // Netting outside the sector line
+)
+
+data class CageSurveyResult(
+    val circleType: String,
+    val right: CageGatePoint,
+    val left: CageGatePoint,
+    val openingWidthM: Double,
+    val widthPassed: Boolean,
+    val setbackM: Double,          // Midpoint of the opening in front of the circle centre
+    val setbackPassed: Boolean,
+    val centreOffsetMm: Double,    // Midpoint off the sector bisector
+    val centrePassed: Boolean,
+    val completedAt: Long = SessionSources.now()
+) {
+    val passed: Boolean
+        get() = widthPassed && setbackPassed && centrePassed && right.clearOfSector && left.clearOfSector
+}
+
+/**
+ * Discus / hammer cage gate survey
+ * The front opening is 6 m wide between the inner edges of the netting (the gate panels
+ * in their open position for hammer), centred on the sector 7 m in front of the circle
+ * centre, and must not intrude into the sector. Points are taken in the circle frame of
+ * the current calibration, so the centre and a sector line must be measured first.
+ */
+class CageSurveyProcedure(
+    val circleType: String,
+    private val rightSectorLine: Pair<Double, Double>
+) {
+
+    companion object {
+        const val OPENING_WIDTH_M = 6.0
+        const val OPENING_SETBACK_M = 7.0
+
+        // Rules give no tolerance; this allows for the netting hanging off its frame
+        const val POSITION_TOLERANCE_MM = 50.0
+
+        fun supports(circleType: String): Boolean =
+            circleType == EDMCalculations.CIRCLE_DISCUS || circleType == EDMCalculations.CIRCLE_HAMMER
+    }
+
+    private val bisectorRad = CoordinateTransforms.sectorBisector(rightSectorLine, circleType)
+    private var right: Pair<Double, Double>? = null
+    private var left: Pair<Double, Double>? = null
+
+    @Synchronized
+    fun currentStep(): CageSurveyStep = when {
+        right == null -> CageSurveyStep.GATE_RIGHT
+        left == null -> CageSurveyStep.GATE_LEFT
+        else -> CageSurveyStep.COMPLETE
+    }
+
+    /**
+     * Record a measured point (circle frame) for the current step. Returns the step now expected.
+     */
+    @Synchronized
+    fun addPoint(point: Pair<Double, Double>): CageSurveyStep {
+        val sectorPoint = CoordinateTransforms.circleToSector(point, bisectorRad)
+        when (currentStep()) {
+            CageSurveyStep.GATE_RIGHT -> right = sectorPoint
+            CageSurveyStep.GATE_LEFT -> left = sectorPoint
+            CageSurveyStep.COMPLETE -> {}
+        }
+        return currentStep()
+    }
+
+    /**
+     * Checks once both ends of the opening are measured, otherwise null
+     */
+    @Synchronized
+    fun result(): CageSurveyResult? {
+        val right = right ?: return null
+        val left = left ?: return null
+
+        val width = hypot(right.first - left.first, right.second - left.second)
+        val midX = (right.first + left.first) / 2
+        val midY = (right.second + left.second) / 2
+
+        val widthDeviationMm = (width - OPENING_WIDTH_M) * 1000
+        val setbackDeviationMm = (midY - OPENING_SETBACK_M) * 1000
+        return CageSurveyResult(
+            circleType = circleType,
+            right = gatePoint(right),
+            left = gatePoint(left),
+            openingWidthM = width,
+            widthPassed = abs(widthDeviationMm) <= POSITION_TOLERANCE_MM,
+            setbackM = midY,
+            setbackPassed = abs(setbackDeviationMm) <= POSITION_TOLERANCE_MM,
+            centreOffsetMm = midX * 1000,
+            centrePassed = abs(midX * 1000) <= POSITION_TOLERANCE_MM
+        )
+    }
+
+    private fun gatePoint(point: Pair<Double, Double>): CageGatePoint {
+        val angle = Math.toDegrees(atan2(point.first, point.second))
+        return CageGatePoint(
+            x = point.first,
+            y = point.second,
+            angleFromBisectorDeg = angle,
+            clearOfSector = abs(angle) > CoordinateTransforms.halfSectorDeg(circleType)
+        )
+    }
+}
+
+/**
+ * What is known about one physical circle at the venue, beyond its calibrations
+ */
+data class VenueProfile(
+    val venueCircleId: String,
+    val cageSurvey: CageSurveyResult? = null,
+    val updatedAt: Long = SessionSources.now()
+)
+
+/**
+ * Venue Profile Store
+ * Keeps venue circle profiles between sessions, keyed by the venue circle name
+ */
+class VenueProfileStore(context: Context) {
+
+    companion object {
+        private const val TAG = "VenueProfileStore"
+        private const val PREFS_NAME = "polyfield_venue_profiles"
+        private const val PREF_PROFILES = "profiles"
+    }
+
+    private val preferences = context.getSharedPreferences(PREFS_NAME, Context.MODE_PRIVATE)
+    private val gson = Gson()
+    private val profiles = load().toMutableList()
+
+    fun getProfile(venueCircleId: String): VenueProfile? =
+        synchronized(profiles) { profiles.find { it.venueCircleId == venueCircleId } }
+
+    fun saveCageSurvey(venueCircleId: String, survey: CageSurveyResult) {
+        synchronized(profiles) {
+            val existing = profiles.find { it.venueCircleId == venueCircleId } ?: VenueProfile(venueCircleId)
+            profiles.removeAll { it.venueCircleId == venueCircleId }
+            profiles.add(existing.copy(cageSurvey = survey, updatedAt = SessionSources.now()))
+            save()
+        }
+        Log.d(TAG, "Saved ${survey.circleType} cage survey for $venueCircleId: ${if (survey.passed) "pass" else "fail"}")
+    }
+
+    private fun load(): List<VenueProfile> {
+        return try {
+            val json = preferences.getString(PREF_PROFILES, null) ?: return emptyList()
+            val type = object : TypeToken<List<VenueProfile>>() {}.type
+            gson.fromJson<List<VenueProfile>>(json, type) ?: emptyList()
+        } catch (e: Exception) {
+            Log.e(TAG, "Error loading venue profiles: ${e.message}")
+            emptyList()
+        }
+    }
+
+    private fun save() {
+        preferences.edit()
+            .putString(PREF_PROFILES, gson.toJson(profiles))
+            .apply()
+    }
+}
@@ -0,0 +1,196 @@
+package com.polyfieldandroid
+
+import android.content.Context
+import android.util.Log
+import com.google.gson.Gson
+import java.io.File
+import kotlin.math.abs
+import kotlin.math.atan2
+import kotlin.math.hypot
+
+/**
+ * A completed calibration kept for comparison with later sessions at the same circle.
+ * Station position is stored both as measured and in the sector frame; the latter does not
+ * depend on how the instrument was pointed, so it can be compared between sessions.
+ */
+data class ArchivedCalibration(
+    val venueCircleId: String,
+    val circleType: String,
+    val timestamp: Long,
+    val stationX: Double,
+    val stationY: Double,
+    val measuredRadius: Double?,
+    val stationDistanceM: Double,          // Station to circle centre
+    val stationBearingDeg: Double?,        // Station direction from the sector bisector; null without a sector line
+    val stopboard: StopboardVerification? = null
+)
+
+enum class CalibrationAnomalyType {
+    RADIUS_CHANGED,   // Edge no longer where it was: ring distorted or centre plate moved
+    CENTRE_SHIFTED,   // Station-to-centre distance changed although the station is on its mark
+    SECTOR_ROTATED    // Sector lines no longer at the same bearing from the station mark
+}
+
+data class CalibrationAnomaly(
+    val type: CalibrationAnomalyType,
+    val previous: Double,
+    val current: Double,
+    val difference: Double,
+    val unit: String,
+    val previousTimestamp: Long
+)
+
+data class CalibrationComparison(
+    val venueCircleId: String,
+    val current: ArchivedCalibration,
+    val previousSessions: List<ArchivedCalibration>,
+    val anomalies: List<CalibrationAnomaly>
+) {
+    val hasAnomalies: Boolean
+        get() = anomalies.isNotEmpty()
+}
+
+/**
+ * Calibration Archive
+ * Keeps every completed calibration per venue circle, across sessions, and compares a new
+ * calibration with the most recent earlier session. The comparison assumes the EDM is set
+ * up on the venue's fixed station mark each time; with the station elsewhere, only the
+ * radius check is meaningful.
+ */
+class CalibrationArchive(context: Context) {
+
+    companion object {
+        private const val TAG = "CalibrationArchive"
+        private const val FILE_NAME = "calibration_archive.jsonl"
+        private val lock = Any()
+
+        const val RADIUS_THRESHOLD_MM = 3.0
+        const val CENTRE_THRESHOLD_MM = 10.0
+        const val SECTOR_THRESHOLD_DEG = 0.05
+
+        /**
+         * Archive entry for a calibration, or null if the centre is not set
+         */
+        fun entryFor(venueCircleId: String, calibration: CalibrationState, timestamp: Long = SessionSources.now()): ArchivedCalibration? {
+            val station = calibration.stationCoordinates ?: return null
+            val bearing = calibration.sectorLineCoordinates
+                ?.takeIf { calibration.sectorLineSet }
+                ?.let { sectorLine ->
+                    val (x, y) = CoordinateTransforms.circleToSector(
+                        station, CoordinateTransforms.sectorBisector(sectorLine, calibration.circleType)
+                    )
+                    Math.toDegrees(atan2(x, y))
+                }
+            return ArchivedCalibration(
+                venueCircleId = venueCircleId,
+                circleType = calibration.circleType,
+                timestamp = timestamp,
+                stationX = station.first,
+                stationY = station.second,
+                measuredRadius = calibration.edgeResult?.averageRadius,
+                stationDistanceM = hypot(station.first, station.second),
+                stationBearingDeg = bearing,
+                stopboard = calibration.stopboardVerification
+            )
+        }
+    }
+
+    private val file = File(context.applicationContext.filesDir, FILE_NAME)
+    private val gson = Gson()
+
+    fun archive(entry: ArchivedCalibration) {
+        synchronized(lock) {
+            try {
+                file.appendText(gson.toJson(entry) + "\n", Charsets.UTF_8)
+                Log.d(TAG, "Archived ${entry.circleType} calibration for ${entry.venueCircleId}")
+            } catch (e: Exception) {
+                Log.e(TAG, "Failed to archive calibration: ${e.message}")
+            }
+        }
+    }
+
+    /**
+     * Record a stopboard check against the circle's most recent calibration, which is
+     * normally archived before the stopboard is measured
+     */
+    fun attachStopboard(venueCircleId: String, verification: StopboardVerification) {
+        synchronized(lock) {
+            if (!file.exists()) return
+            try {
+                val lines = file.readLines(Charsets.UTF_8).filter { it.isNotBlank() }
+                val latest = lines.indices.lastOrNull { index ->
+                    runCatching { gson.fromJson(lines[index], ArchivedCalibration::class.java) }
+                        .getOrNull()?.venueCircleId == venueCircleId
+                } ?: return
+                val entry = gson.fromJson(lines[latest], ArchivedCalibration::class.java)
+                val updated = lines.toMutableList().apply { set(latest, gson.toJson(entry.copy(stopboard = verification))) }
+                file.writeText(updated.joinToString("\n", postfix = "\n"), Charsets.UTF_8)
+                Log.d(TAG, "Stopboard check recorded for $venueCircleId")
+            } catch (e: Exception) {
+                Log.e(TAG, "Failed to record stopboard check: ${e.message}")
+            }
+        }
+    }
+
+    fun getHistory(venueCircleId: String): List<ArchivedCalibration> = synchronized(lock) {
+        if (!file.exists()) return emptyList()
+
+        val entries = mutableListOf<ArchivedCalibration>()
+        file.forEachLine { line ->
+            if (line.isBlank()) return@forEachLine
+            try {
+                gson.fromJson(line, ArchivedCalibration::class.java)
+                    ?.takeIf { it.venueCircleId == venueCircleId }
+                    ?.let { entries.add(it) }
+            } catch (e: Exception) {
+                Log.w(TAG, "Skipping unreadable entry: ${e.message}")
+            }
+        }
+        entries.sortedByDescending { it.timestamp }
+    }
+
+    /**
+     * Compare with earlier sessions, newest first; anomalies are against the most recent
+     * earlier session (a different day) of the same circle type
+     */
+    fun compare(current: ArchivedCalibration): CalibrationComparison {
+        val previous = getHistory(current.venueCircleId).filter {
+            it.circleType == current.circleType && !sameDay(it.timestamp, current.timestamp) && it.timestamp < current.timestamp
+        }
+        val anomalies = mutableListOf<CalibrationAnomaly>()
+        previous.firstOrNull()?.let { last ->
+            val currentRadius = current.measuredRadius
+            val lastRadius = last.measuredRadius
+            if (currentRadius != null && lastRadius != null) {
+                val differenceMm = (currentRadius - lastRadius) * 1000
+                if (abs(differenceMm) > RADIUS_THRESHOLD_MM) {
+                    anomalies.add(CalibrationAnomaly(CalibrationAnomalyType.RADIUS_CHANGED, lastRadius, currentRadius, differenceMm, "mm", last.timestamp))
+                }
+            }
+
+            val centreDifferenceMm = (current.stationDistanceM - last.stationDistanceM) * 1000
+            if (abs(centreDifferenceMm) > CENTRE_THRESHOLD_MM) {
+                anomalies.add(
+                    CalibrationAnomaly(CalibrationAnomalyType.CENTRE_SHIFTED, last.stationDistanceM, current.stationDistanceM, centreDifferenceMm, "mm", last.timestamp)
+                )
+            }
+
+            val currentBearing = current.stationBearingDeg
+            val lastBearing = last.stationBearingDeg
+            if (currentBearing != null && lastBearing != null) {
+                val differenceDeg = currentBearing - lastBearing
+                if (abs(differenceDeg) > SECTOR_THRESHOLD_DEG) {
+                    anomalies.add(CalibrationAnomaly(CalibrationAnomalyType.SECTOR_ROTATED, lastBearing, currentBearing, differenceDeg, "°", last.timestamp))
+                }
+            }
+        }
+
+        anomalies.forEach { Log.w(TAG, "${current.venueCircleId}: ${it.type} by ${String.format(java.util.Locale.US, "%+.2f", it.difference)} ${it.unit}") }
+        return CalibrationComparison(current.venueCircleId, current, previous, anomalies)
+    }
+
+    private fun sameDay(a: Long, b: Long): Boolean {
+        val format = java.text.SimpleDateFormat("yyyy-MM-dd", java.util.Locale.getDefault())
+        return format.format(java.util.Date(a)) == format.format(java.util.Date(b))
+    }
+}
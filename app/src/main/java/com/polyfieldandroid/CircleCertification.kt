@@ -0,0 +1,320 @@
+package com.polyfieldandroid
+
+import kotlin.math.abs
+import kotlin.math.atan2
+import kotlin.math.hypot
+
+/**
+ * What the certification procedure expects to be measured next
+ */
+enum class CertificationStep {
+    DIAMETER_POINTS,  // Pairs of diametrically opposite points on the inner edge
+    SECTOR_RIGHT,     // A point on the right-hand sector line, well out from the circle
+    SECTOR_LEFT,
+    STOPBOARD_RIGHT,  // Inner corners of the stopboard, shot put only
+    STOPBOARD_LEFT,
+    COMPLETE
+}
+
+/**
+ * One measured diameter: a pair of opposite edge points
+ */
+data class ChordCheck(
+    val index: Int,
+    val diameterM: Double,
+    val deviationMm: Double,
+    val passed: Boolean
+)
+
+data class SectorCheck(
+    val angleDeg: Double,
+    val nominalDeg: Double,
+    val deviationDeg: Double,
+    val passed: Boolean
+)
+
+data class StopboardCheck(
+    val widthM: Double,
+    val widthPassed: Boolean,
+    val centreOffsetMm: Double,   // Midpoint off the sector bisector
+    val centrePassed: Boolean,
+    val edgeDeviationMm: Double,  // Worst corner off the circle's inner edge
+    val edgePassed: Boolean
+) {
+    val passed: Boolean
+        get() = widthPassed && centrePassed && edgePassed
+}
+
+data class CertificationResult(
+    val circleType: String,
+    val nominalRadius: Double,
+    val diameters: List<ChordCheck>,
+    val sector: SectorCheck?,
+    val stopboard: StopboardCheck?,
+    val completedAt: Long = SessionSources.now()
+) {
+    val meanDiameterM: Double?
+        get() = if (diameters.isEmpty()) null else diameters.map { it.diameterM }.average()
+
+    val passed: Boolean
+        get() = diameters.isNotEmpty() && diameters.all { it.passed } &&
+            sector?.passed == true && (stopboard?.passed ?: true)
+}
+
+/**
+ * UKA / World Athletics circle certification
+ * A full survey of the circle and sector, distinct from the quick pre-event calibration:
+ * inner diameter at several chords, the sector angle from both sector lines, and for shot
+ * the stopboard width, centring and position on the inner edge. Points are taken in the
+ * circle frame of the current calibration, so the centre must be set first.
+ */
+class CircleCertificationProcedure(
+    val circleType: String,
+    val nominalRadius: Double,
+    private val requiredDiameters: Int = DEFAULT_DIAMETERS
+) {
+
+    companion object {
+        const val DEFAULT_DIAMETERS = 4
+
+        // Inner diameter tolerance for throwing circles; the javelin arc is checked to 10 mm
+        const val DIAMETER_TOLERANCE_MM = 5.0
+        const val JAVELIN_TOLERANCE_MM = 10.0
+
+        const val SECTOR_TOLERANCE_DEG = 0.05
+
+        // Stopboard: 1.21 m ± 10 mm inside width, centred between the sector lines
+        const val STOPBOARD_WIDTH_M = 1.21
+        const val STOPBOARD_WIDTH_TOLERANCE_MM = 10.0
+        const val STOPBOARD_CENTRE_TOLERANCE_MM = 10.0
+        const val STOPBOARD_EDGE_TOLERANCE_MM = 5.0
+    }
+
+    private val diameterPoints = mutableListOf<Pair<Double, Double>>()
+    private var sectorRight: Pair<Double, Double>? = null
+    private var sectorLeft: Pair<Double, Double>? = null
+    private var stopboardRight: Pair<Double, Double>? = null
+    private var stopboardLeft: Pair<Double, Double>? = null
+
+    private val hasStopboard: Boolean
+        get() = circleType == EDMCalculations.CIRCLE_SHOT
+
+    private val diameterToleranceMm: Double
+        get() = if (circleType == EDMCalculations.CIRCLE_JAVELIN) JAVELIN_TOLERANCE_MM else DIAMETER_TOLERANCE_MM
+
+    /**
+     * The javelin arc has no opposite edge, so its "diameters" are twice the radius to each point
+     */
+    private val pairsPoints: Boolean
+        get() = circleType != EDMCalculations.CIRCLE_JAVELIN
+
+    @Synchronized
+    fun currentStep(): CertificationStep = when {
+        diameterPoints.size < requiredDiameters * (if (pairsPoints) 2 else 1) -> CertificationStep.DIAMETER_POINTS
+        sectorRight == null -> CertificationStep.SECTOR_RIGHT
+        sectorLeft == null -> CertificationStep.SECTOR_LEFT
+        hasStopboard && stopboardRight == null -> CertificationStep.STOPBOARD_RIGHT
+        hasStopboard && stopboardLeft == null -> CertificationStep.STOPBOARD_LEFT
+        else -> CertificationStep.COMPLETE
+    }
+
+    /**
+     * Record a measured point for the current step. Returns the step now expected.
+     */
+    @Synchronized
+    fun addPoint(point: Pair<Double, Double>): CertificationStep {
+        when (currentStep()) {
+            CertificationStep.DIAMETER_POINTS -> diameterPoints.add(point)
+            CertificationStep.SECTOR_RIGHT -> sectorRight = point
+            CertificationStep.SECTOR_LEFT -> sectorLeft = point
+            CertificationStep.STOPBOARD_RIGHT -> stopboardRight = point
+            CertificationStep.STOPBOARD_LEFT -> stopboardLeft = point
+            CertificationStep.COMPLETE -> {}
+        }
+        return currentStep()
+    }
+
+    /**
+     * Checks so far; sections not yet measured are left out
+     */
+    @Synchronized
+    fun result(): CertificationResult = CertificationResult(
+        circleType = circleType,
+        nominalRadius = nominalRadius,
+        diameters = diameterChecks(),
+        sector = sectorCheck(),
+        stopboard = stopboardCheck()
+    )
+
+    private fun diameterChecks(): List<ChordCheck> {
+        val diameters = if (pairsPoints) {
+            diameterPoints.chunked(2).filter { it.size == 2 }.map { (a, b) ->
+                hypot(a.first - b.first, a.second - b.second)
+            }
+        } else {
+            diameterPoints.map { 2 * hypot(it.first, it.second) }
+        }
+        val nominal = 2 * nominalRadius
+        return diameters.mapIndexed { index, diameter ->
+            val deviationMm = (diameter - nominal) * 1000
+            ChordCheck(index + 1, diameter, deviationMm, abs(deviationMm) <= diameterToleranceMm)
+        }
+    }
+
+    private fun sectorCheck(): SectorCheck? {
+        val right = sectorRight ?: return null
+        val left = sectorLeft ?: return null
+        var angle = Math.toDegrees(atan2(left.second, left.first) - atan2(right.second, right.first))
+        if (angle < 0) angle += 360.0
+        val nominal = 2 * CoordinateTransforms.halfSectorDeg(circleType)
+        val deviation = angle - nominal
+        return SectorCheck(angle, nominal, deviation, abs(deviation) <= SECTOR_TOLERANCE_DEG)
+    }
+
+    private fun stopboardCheck(): StopboardCheck? {
+        val right = stopboardRight ?: return null
+        val left = stopboardLeft ?: return null
+        val width = hypot(left.first - right.first, left.second - right.second)
+
+        // Midpoint should sit on the sector bisector
+        val midpoint = Pair((left.first + right.first) / 2, (left.second + right.second) / 2)
+        val centreOffsetMm = sectorRight?.let { sectorLine ->
+            CoordinateTransforms.circleToSector(midpoint, CoordinateTransforms.sectorBisector(sectorLine, circleType)).first * 1000
+        } ?: 0.0
+
+        val edgeDeviationMm = listOf(right, left)
+            .map { (hypot(it.first, it.second) - nominalRadius) * 1000 }
+            .maxByOrNull { abs(it) } ?: 0.0
+
+        val widthDeviationMm = (width - STOPBOARD_WIDTH_M) * 1000
+        return StopboardCheck(
+            widthM = width,
+            widthPassed = abs(widthDeviationMm) <= STOPBOARD_WIDTH_TOLERANCE_MM,
+            centreOffsetMm = centreOffsetMm,
+            centrePassed = abs(centreOffsetMm) <= STOPBOARD_CENTRE_TOLERANCE_MM,
+            edgeDeviationMm = edgeDeviationMm,
+            edgePassed = abs(edgeDeviationMm) <= STOPBOARD_EDGE_TOLERANCE_MM
+        )
+    }
+}
+
+/**
+ * A point measured on the top of the stopboard at its inner edge, in the circle frame
+ */
+data class StopboardPoint(
+    val x: Double,
+    val y: Double,
+    val heightM: Double  // Prism above the instrument; only differences from the circle level matter
+)
+
+/**
+ * Stopboard position check recorded with a shot put calibration
+ */
+data class StopboardVerification(
+    val points: List<StopboardPoint>,
+    val circleLevelM: Double,        // Prism above the instrument standing on the circle
+    val edgeDeviationMm: Double,     // Worst point off the circle's inner edge
+    val edgePassed: Boolean,
+    val centreOffsetMm: Double?,     // Midpoint of the end points off the sector bisector; null without a sector line
+    val centrePassed: Boolean?,
+    val spanM: Double,               // Between the end points
+    val heightMm: Double,            // Mean height above the circle
+    val heightDeviationMm: Double,   // Worst point off the nominal height
+    val heightPassed: Boolean,
+    val completedAt: Long = SessionSources.now()
+) {
+    val passed: Boolean
+        get() = edgePassed && heightPassed && (centrePassed ?: true)
+}
+
+/**
+ * What the stopboard check expects to be measured next
+ */
+enum class StopboardStep {
+    CIRCLE_LEVEL,  // Prism pole on the circle surface just inside the board
+    INNER_EDGE     // Prism pole on top of the board at its inner edge
+}
+
+/**
+ * Stopboard position check, part of the pre-event shot put calibration
+ * One reading on the circle surface gives the level, then several points along the top of
+ * the board's inner edge are checked against the circle's inner edge and the board height.
+ * The prism pole is kept at the same height throughout so it cancels out. The first and
+ * last edge points should be at the ends of the board: their midpoint is checked against
+ * the sector bisector when a sector line has been measured.
+ */
+class StopboardVerificationProcedure(
+    val nominalRadius: Double,
+    private val rightSectorLine: Pair<Double, Double>?
+) {
+
+    companion object {
+        const val MIN_EDGE_POINTS = 3
+
+        // Top of the board 100 mm ± 2 mm above the inside of the circle
+        const val STOPBOARD_HEIGHT_M = 0.100
+        const val STOPBOARD_HEIGHT_TOLERANCE_MM = 2.0
+    }
+
+    private var circleLevel: Double? = null
+    private val edgePoints = mutableListOf<StopboardPoint>()
+
+    @Synchronized
+    fun currentStep(): StopboardStep =
+        if (circleLevel == null) StopboardStep.CIRCLE_LEVEL else StopboardStep.INNER_EDGE
+
+    @Synchronized
+    fun pointCount(): Int = edgePoints.size
+
+    /**
+     * Record a measured point for the current step. Returns the step now expected.
+     */
+    @Synchronized
+    fun addPoint(point: StopboardPoint): StopboardStep {
+        when (currentStep()) {
+            StopboardStep.CIRCLE_LEVEL -> circleLevel = point.heightM
+            StopboardStep.INNER_EDGE -> edgePoints.add(point)
+        }
+        return currentStep()
+    }
+
+    /**
+     * Checks from the points so far, or null until the level and enough edge points are in
+     */
+    @Synchronized
+    fun result(): StopboardVerification? {
+        val level = circleLevel ?: return null
+        if (edgePoints.size < MIN_EDGE_POINTS) return null
+
+        val edgeDeviationMm = edgePoints
+            .map { (hypot(it.x, it.y) - nominalRadius) * 1000 }
+            .maxByOrNull { abs(it) } ?: 0.0
+
+        val heights = edgePoints.map { (it.heightM - level) * 1000 }
+        val heightDeviationMm = heights
+            .map { it - STOPBOARD_HEIGHT_M * 1000 }
+            .maxByOrNull { abs(it) } ?: 0.0
+
+        val first = edgePoints.first()
+        val last = edgePoints.last()
+        val centreOffsetMm = rightSectorLine?.let { sectorLine ->
+            val midpoint = Pair((first.x + last.x) / 2, (first.y + last.y) / 2)
+            CoordinateTransforms.circleToSector(
+                midpoint, CoordinateTransforms.sectorBisector(sectorLine, EDMCalculations.CIRCLE_SHOT)
+            ).first * 1000
+        }
+
+        return StopboardVerification(
+            points = edgePoints.toList(),
+            circleLevelM = level,
+            edgeDeviationMm = edgeDeviationMm,
+            edgePassed = abs(edgeDeviationMm) <= CircleCertificationProcedure.STOPBOARD_EDGE_TOLERANCE_MM,
+            centreOffsetMm = centreOffsetMm,
+            centrePassed = centreOffsetMm?.let { abs(it) <= CircleCertificationProcedure.STOPBOARD_CENTRE_TOLERANCE_MM },
+            spanM = hypot(last.x - first.x, last.y - first.y),
+            heightMm = heights.average(),
+            heightDeviationMm = heightDeviationMm,
+            heightPassed = abs(heightDeviationMm) <= STOPBOARD_HEIGHT_TOLERANCE_MM
+        )
+    }
+}
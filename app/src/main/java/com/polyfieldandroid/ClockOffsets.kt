@@ -0,0 +1,75 @@
+package com.polyfieldandroid
+
+import java.util.concurrent.ConcurrentHashMap
+
+/**
+ * How far a device's own clock is from the tablet's
+ */
+data class ClockOffset(
+    val deviceId: String,
+    val offsetMs: Long,   // Device clock minus tablet clock
+    val samples: Int,
+    val spreadMs: Long,   // Range of the samples behind the estimate
+    val updatedAt: Long
+) {
+    fun toMap(): Map<String, Any> = mapOf(
+        "deviceId" to deviceId,
+        "offsetMs" to offsetMs,
+        "samples" to samples,
+        "spreadMs" to spreadMs,
+        "updatedAt" to updatedAt
+    )
+}
+
+/**
+ * Device Clock Offsets
+ * Instruments that stamp their own output (e.g. wind sensors sending NMEA ZDA time) run on
+ * clocks that drift from the tablet's. Each stamped message gives one offset sample - its
+ * device time against when it arrived, less any known transit time - and the median of the
+ * recent samples is taken, so one message held up in a buffer does not shift the timeline.
+ * EDM times need no offset: the tablet triggers every measurement and stamps it itself.
+ */
+class DeviceClockOffsets {
+
+    companion object {
+        private const val SAMPLE_COUNT = 25
+    }
+
+    private val samples = ConcurrentHashMap<String, ArrayDeque<Long>>()
+    private val updatedAt = ConcurrentHashMap<String, Long>()
+
+    fun record(deviceId: String, deviceTime: Long, receivedAt: Long, transitMs: Long = 0) {
+        val history = samples.getOrPut(deviceId) { ArrayDeque() }
+        synchronized(history) {
+            history.addLast(deviceTime - (receivedAt - transitMs))
+            while (history.size > SAMPLE_COUNT) history.removeFirst()
+        }
+        updatedAt[deviceId] = receivedAt
+    }
+
+    fun getOffset(deviceId: String): ClockOffset? {
+        val history = samples[deviceId] ?: return null
+        val sorted = synchronized(history) { history.sorted() }
+        if (sorted.isEmpty()) return null
+        return ClockOffset(
+            deviceId = deviceId,
+            offsetMs = sorted[sorted.size / 2],
+            samples = sorted.size,
+            spreadMs = sorted.last() - sorted.first(),
+            updatedAt = updatedAt[deviceId] ?: 0L
+        )
+    }
+
+    fun getOffsets(): List<ClockOffset> = samples.keys.sorted().mapNotNull { getOffset(it) }
+
+    /**
+     * A device timestamp on the tablet's clock; unchanged for a device with no offset yet
+     */
+    fun toLocalTime(deviceId: String, deviceTime: Long): Long =
+        deviceTime - (getOffset(deviceId)?.offsetMs ?: 0L)
+
+    fun reset(deviceId: String) {
+        samples.remove(deviceId)
+        updatedAt.remove(deviceId)
+    }
+}
@@ -0,0 +1,319 @@
+package com.polyfieldandroid
+
+import android.content.Context
+import android.util.Base64
+import android.util.Log
+import com.google.gson.Gson
+import kotlinx.coroutines.Dispatchers
+import kotlinx.coroutines.withContext
+import java.io.IOException
+import java.net.HttpURLConnection
+import java.net.URL
+import java.security.MessageDigest
+import java.text.SimpleDateFormat
+import java.util.Date
+import java.util.Locale
+import java.util.TimeZone
+import javax.crypto.Mac
+import javax.crypto.spec.SecretKeySpec
+
+/**
+ * Log entries recorded since the last successful push for a session
+ */
+data class SessionDelta(
+    val sessionId: String,
+    val eventName: String,
+    val fromSeq: Long,
+    val toSeq: Long,
+    val entries: List<WalEntry>,
+    val generatedAt: Long = SessionSources.now()
+)
+
+data class SyncResult(
+    val success: Boolean,
+    val message: String = ""
+)
+
+/**
+ * Destination for session backups. Implementations push competition deltas and
+ * pull startlists from a club's own storage.
+ */
+interface SyncAdapter {
+    val name: String
+
+    suspend fun pushSessionDelta(delta: SessionDelta): SyncResult
+
+    /**
+     * Startlist for an event, or null if the store has none
+     */
+    suspend fun pullStartlist(eventId: String): PolyFieldApiClient.Event?
+}
+
+enum class SyncTargetType {
+    HTTP,   // Any server accepting PUT/GET, optional basic auth or bearer token
+    S3,     // S3-compatible bucket, signed with AWS Signature Version 4
+    WEBDAV  // WebDAV collection, basic auth
+}
+
+data class SyncTargetConfig(
+    val type: SyncTargetType = SyncTargetType.HTTP,
+    val baseUrl: String = "",        // Collection or bucket URL, e.g. https://bucket.s3.eu-west-2.amazonaws.com/polyfield
+    val username: String? = null,    // Basic auth user, or S3 access key id
+    val password: String? = null,    // Basic auth password, or S3 secret key
+    val bearerToken: String? = null, // HTTP only
+    val region: String = "us-east-1" // S3 only
+)
+
+/**
+ * Reference sync adapter for plain HTTP, S3 and WebDAV stores.
+ * Objects are stored flat in the configured location so no collections need creating:
+ *   <sessionId>-delta-<fromSeq>-<toSeq>.json
+ *   startlist-<eventId>.json (read only, in the same shape as a server event)
+ */
+class ObjectStoreSyncAdapter(private val config: SyncTargetConfig) : SyncAdapter {
+
+    companion object {
+        private const val TAG = "ObjectStoreSync"
+        private const val CONNECTION_TIMEOUT = 10000
+        private const val READ_TIMEOUT = 30000
+    }
+
+    private val gson = Gson()
+
+    override val name: String
+        get() = "${config.type} ${config.baseUrl}"
+
+    override suspend fun pushSessionDelta(delta: SessionDelta): SyncResult = withContext(Dispatchers.IO) {
+        val key = "${safeKey(delta.sessionId)}-delta-${delta.fromSeq}-${delta.toSeq}.json"
+        try {
+            request("PUT", key, gson.toJson(delta).toByteArray(Charsets.UTF_8))
+            Log.d(TAG, "Pushed ${delta.entries.size} entries as $key")
+            SyncResult(true, "Uploaded $key")
+        } catch (e: Exception) {
+            Log.e(TAG, "Push of $key failed: ${e.message}")
+            SyncResult(false, e.message ?: "Upload failed")
+        }
+    }
+
+    override suspend fun pullStartlist(eventId: String): PolyFieldApiClient.Event? = withContext(Dispatchers.IO) {
+        val key = "startlist-${safeKey(eventId)}.json"
+        try {
+            val body = request("GET", key, null) ?: return@withContext null
+            gson.fromJson(String(body, Charsets.UTF_8), PolyFieldApiClient.Event::class.java)
+        } catch (e: Exception) {
+            Log.e(TAG, "Pull of $key failed: ${e.message}")
+            null
+        }
+    }
+
+    /**
+     * Returns the response body, or null for a GET of a missing object
+     */
+    private fun request(method: String, key: String, body: ByteArray?): ByteArray? {
+        val url = URL(config.baseUrl.trimEnd('/') + "/" + key)
+        val connection = url.openConnection() as HttpURLConnection
+        try {
+            connection.requestMethod = method
+            connection.connectTimeout = CONNECTION_TIMEOUT
+            connection.readTimeout = READ_TIMEOUT
+            if (body != null) connection.setRequestProperty("Content-Type", "application/json")
+            authorize(connection, method, url, body ?: ByteArray(0))
+
+            if (body != null) {
+                connection.doOutput = true
+                connection.setFixedLengthStreamingMode(body.size)
+                connection.outputStream.use { it.write(body) }
+            }
+
+            val responseCode = connection.responseCode
+            if (method == "GET" && responseCode == HttpURLConnection.HTTP_NOT_FOUND) return null
+            if (responseCode !in 200..299) {
+                val error = connection.errorStream?.bufferedReader()?.readText()
+                throw IOException("HTTP $responseCode: $error")
+            }
+            return connection.inputStream.use { it.readBytes() }
+        } finally {
+            connection.disconnect()
+        }
+    }
+
+    private fun authorize(connection: HttpURLConnection, method: String, url: URL, body: ByteArray) {
+        when (config.type) {
+            SyncTargetType.S3 -> signS3(connection, method, url, body)
+            SyncTargetType.HTTP, SyncTargetType.WEBDAV -> {
+                config.bearerToken?.takeIf { config.type == SyncTargetType.HTTP && it.isNotBlank() }?.let {
+                    connection.setRequestProperty("Authorization", "Bearer $it")
+                    return
+                }
+                val user = config.username ?: return
+                val credentials = "$user:${config.password.orEmpty()}".toByteArray(Charsets.UTF_8)
+                connection.setRequestProperty("Authorization", "Basic " + Base64.encodeToString(credentials, Base64.NO_WRAP))
+            }
+        }
+    }
+
+    /**
+     * AWS Signature Version 4 for a single-chunk request
+     */
+    private fun signS3(connection: HttpURLConnection, method: String, url: URL, body: ByteArray) {
+        val accessKey = config.username ?: throw IOException("S3 access key not configured")
+        val secretKey = config.password ?: throw IOException("S3 secret key not configured")
+
+        val now = Date(SessionSources.now())
+        val amzDate = utcFormat("yyyyMMdd'T'HHmmss'Z'").format(now)
+        val dateStamp = utcFormat("yyyyMMdd").format(now)
+        val host = if (url.port == -1) url.host else "${url.host}:${url.port}"
+        val payloadHash = sha256Hex(body)
+
+        val signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+        val canonicalRequest = listOf(
+            method,
+            url.path.ifEmpty { "/" },
+            url.query.orEmpty(),
+            "host:$host\nx-amz-content-sha256:$payloadHash\nx-amz-date:$amzDate\n",
+            signedHeaders,
+            payloadHash
+        ).joinToString("\n")
+
+        val scope = "$dateStamp/${config.region}/s3/aws4_request"
+        val stringToSign = "AWS4-HMAC-SHA256\n$amzDate\n$scope\n${sha256Hex(canonicalRequest.toByteArray(Charsets.UTF_8))}"
+
+        var signingKey = hmac("AWS4$secretKey".toByteArray(Charsets.UTF_8), dateStamp)
+        signingKey = hmac(signingKey, config.region)
+        signingKey = hmac(signingKey, "s3")
+        signingKey = hmac(signingKey, "aws4_request")
+        val signature = hmac(signingKey, stringToSign).joinToString("") { "%02x".format(it) }
+
+        connection.setRequestProperty("x-amz-date", amzDate)
+        connection.setRequestProperty("x-amz-content-sha256", payloadHash)
+        connection.setRequestProperty(
+            "Authorization",
+            "AWS4-HMAC-SHA256 Credential=$accessKey/$scope, SignedHeaders=$signedHeaders, Signature=$signature"
+        )
+    }
+
+    private fun utcFormat(pattern: String) = SimpleDateFormat(pattern, Locale.US).apply {
+        timeZone = TimeZone.getTimeZone("UTC")
+    }
+
+    private fun sha256Hex(data: ByteArray): String =
+        MessageDigest.getInstance("SHA-256").digest(data).joinToString("") { "%02x".format(it) }
+
+    private fun hmac(key: ByteArray, data: String): ByteArray {
+        val mac = Mac.getInstance("HmacSHA256")
+        mac.init(SecretKeySpec(key, "HmacSHA256"))
+        return mac.doFinal(data.toByteArray(Charsets.UTF_8))
+    }
+
+    // Keys are kept to characters that need no URL encoding, so the S3 canonical path is the raw path
+    private fun safeKey(value: String): String = value.replace(Regex("[^A-Za-z0-9._-]"), "_")
+}
+
+/**
+ * Cloud Sync Manager
+ * Tracks which write-ahead log entries each session has already backed up and pushes
+ * the rest through the configured adapter
+ */
+class CloudSyncManager(context: Context) {
+
+    companion object {
+        private const val TAG = "CloudSyncManager"
+        private const val PREFS_NAME = "polyfield_cloud_sync_prefs"
+        private const val PREF_CONFIG = "sync_target_config"
+        private const val PREF_SESSION_ID = "session_id"
+        private const val PREF_PUSHED_SEQ_PREFIX = "pushed_seq_"
+    }
+
+    private val preferences = context.getSharedPreferences(PREFS_NAME, Context.MODE_PRIVATE)
+    private val gson = Gson()
+
+    @Volatile private var adapter: SyncAdapter? = loadConfig()?.let { ObjectStoreSyncAdapter(it) }
+
+    val isEnabled: Boolean
+        get() = adapter != null
+
+    /**
+     * Use a custom adapter instead of the configured object store. Pass null to turn sync off.
+     */
+    fun setAdapter(syncAdapter: SyncAdapter?) {
+        adapter = syncAdapter
+        Log.d(TAG, "Sync adapter set to ${syncAdapter?.name ?: "none"}")
+    }
+
+    fun getConfig(): SyncTargetConfig? = loadConfig()
+
+    /**
+     * Store the object store target and switch to the reference adapter. Null clears it.
+     */
+    fun setConfig(config: SyncTargetConfig?) {
+        preferences.edit().apply {
+            if (config == null) remove(PREF_CONFIG) else putString(PREF_CONFIG, gson.toJson(config))
+        }.apply()
+        setAdapter(config?.let { ObjectStoreSyncAdapter(it) })
+    }
+
+    fun currentSessionId(): String {
+        preferences.getString(PREF_SESSION_ID, null)?.let { return it }
+        return startNewSession()
+    }
+
+    /**
+     * Begin a new backup session, e.g. once the log has been reset for the next competition
+     */
+    fun startNewSession(): String {
+        val sessionId = SimpleDateFormat("yyyyMMdd-HHmmss", Locale.US).format(Date(SessionSources.now())) +
+            "-" + SessionSources.newId().take(8)
+        preferences.edit().putString(PREF_SESSION_ID, sessionId).apply()
+        Log.d(TAG, "Started sync session $sessionId")
+        return sessionId
+    }
+
+    /**
+     * Push the entries this session has not yet backed up
+     */
+    suspend fun pushPending(sessionId: String, eventName: String, entries: List<WalEntry>): SyncResult {
+        val syncAdapter = adapter ?: return SyncResult(false, "Cloud sync not configured")
+        val pushedSeq = preferences.getLong(PREF_PUSHED_SEQ_PREFIX + sessionId, -1)
+        val pending = entries.filter { it.seq > pushedSeq }.sortedBy { it.seq }
+        if (pending.isEmpty()) return SyncResult(true, "Up to date")
+
+        val delta = SessionDelta(
+            sessionId = sessionId,
+            eventName = eventName,
+            fromSeq = pending.first().seq,
+            toSeq = pending.last().seq,
+            entries = pending
+        )
+        val result = syncAdapter.pushSessionDelta(delta)
+        if (result.success) {
+            preferences.edit().apply {
+                // Finished sessions no longer need a cursor
+                if (sessionId == currentSessionId()) {
+                    putLong(PREF_PUSHED_SEQ_PREFIX + sessionId, delta.toSeq)
+                } else {
+                    remove(PREF_PUSHED_SEQ_PREFIX + sessionId)
+                }
+            }.apply()
+        }
+        return result
+    }
+
+    suspend fun pullStartlist(eventId: String): PolyFieldApiClient.Event? {
+        val syncAdapter = adapter ?: return null
+        return syncAdapter.pullStartlist(eventId)
+    }
+
+    private fun loadConfig(): SyncTargetConfig? {
+        val json = preferences.getString(PREF_CONFIG, null) ?: return null
+        return try {
+            val config = gson.fromJson(json, SyncTargetConfig::class.java)
+            config.copy(
+                type = GsonFields.orDefault(config.type, SyncTargetType.HTTP),
+                region = GsonFields.orDefault(config.region, "us-east-1")
+            )
+        } catch (e: Exception) {
+            Log.e(TAG, "Invalid sync config: ${e.message}")
+            null
+        }
+    }
+}
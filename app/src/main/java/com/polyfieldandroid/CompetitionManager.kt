@@ -23,9 +23,14 @@ data class CompetitionSettings(
     val cutoffEnabled: Boolean = true,
     val reorderAfterRound3: Boolean = true,
     val eventType: String = "SHOT", // SHOT, DISCUS, HAMMER, JAVELIN_ARC
-    val allowAllAthletes: Boolean = false
+    val allowAllAthletes: Boolean = false,
+    val qualifyingStandardM: Double? = null, // Automatic qualifying mark (Q) for a qualification round
+    val qualifierTarget: Int = 0 // Athletes to go through to the final; 0 when not a qualification round
 ) {
     fun getCutoffDisplay(): String = if (allowAllAthletes) "ALL" else athleteCutoff.toString()
+
+    val isQualificationRound: Boolean
+        get() = qualifierTarget > 0
 }
 
 data class CompetitionState(
@@ -43,6 +48,7 @@ data class CompetitionState(
     val showRoundTransitionPopup: Boolean = false, // Show end-of-round popup
     val finalRoundSettings: FinalRoundSettings? = null, // Settings from Round 3 dialog
     val progressingAthletes: List<String> = emptyList(), // Bib numbers of athletes progressing to rounds 4-6
+    val qualificationComplete: Boolean = false, // Qualification round over; remaining places filled as q
     val athleteCutState: AthleteCutState = AthleteCutState() // State for athlete cut and reordering
 )
 
@@ -172,6 +178,26 @@ class CompetitionManagerViewModel(private val context: Context) : ViewModel() {
         updateSettings(newSettings)
     }
     
+    /**
+     * Make the event a qualification round with an automatic standard and a target number of
+     * qualifiers, or an ordinary competition when the target is 0. Qualification rounds
+     * have no cut to final rounds.
+     */
+    fun setQualification(standardM: Double?, target: Int) {
+        val currentSettings = _competitionState.value.settings
+        val newSettings = currentSettings.copy(
+            qualifyingStandardM = standardM?.takeIf { it > 0.0 && target > 0 },
+            qualifierTarget = target.coerceAtLeast(0),
+            cutoffEnabled = if (target > 0) false else currentSettings.cutoffEnabled
+        )
+        updateSettings(newSettings)
+    }
+
+    fun setQualificationComplete(complete: Boolean) {
+        _competitionState.value = _competitionState.value.copy(qualificationComplete = complete)
+        Log.d(TAG, "Qualification round ${if (complete) "complete" else "reopened"}")
+    }
+
     /**
      * Set event type (circle type for calibration)
      */
@@ -211,6 +237,7 @@ class CompetitionManagerViewModel(private val context: Context) : ViewModel() {
                 completedAttempts = emptyMap(),
                 roundComplete = false,
                 competitionComplete = false,
+                qualificationComplete = false,
                 settings = settings.copy(
                     athleteCutoff = effectiveCutoff,
                     allowAllAthletes = effectiveCutoff == totalAthletes
@@ -4,10 +4,18 @@ import android.content.Context
 import android.util.Log
 import androidx.lifecycle.ViewModel
 import androidx.lifecycle.viewModelScope
+import kotlinx.coroutines.Dispatchers
+import kotlinx.coroutines.Job
+import kotlinx.coroutines.delay
+import kotlinx.coroutines.isActive
+import kotlinx.coroutines.flow.MutableSharedFlow
 import kotlinx.coroutines.flow.MutableStateFlow
+import kotlinx.coroutines.flow.SharedFlow
 import kotlinx.coroutines.flow.StateFlow
+import kotlinx.coroutines.flow.asSharedFlow
 import kotlinx.coroutines.flow.asStateFlow
 import kotlinx.coroutines.launch
+import kotlinx.coroutines.withContext
 import kotlin.math.*
 
 /**
@@ -19,7 +27,9 @@ data class ThrowCoordinate(
     val distance: Double, // Calculated throw distance
     val round: Int,
     val attemptNumber: Int,
-    val isValid: Boolean = true
+    val isValid: Boolean = true,
+    val setupId: Int? = null, // Station setup whose frame x/y are in; see StationSetupLog
+    val atmosphere: AtmosphericConditions? = null // Conditions the slope distance was corrected for; null if uncorrected
 )
 
 /**
@@ -27,16 +37,22 @@ data class ThrowCoordinate(
  * Integrates existing EDM functionality with athlete tracking and results submission
  */
 data class MeasurementResult(
+    val id: String = SessionSources.newId(), // Stable attempt ID; a remeasure keeps the original's
     val athleteBib: String,
     val round: Int,
     val attemptNumber: Int,
+    val throwNumber: Int? = null, // Order of the attempt in the event, from 1; set when recorded
     val distance: Double?, // Distance in meters
     val windSpeed: Double? = null, // Wind speed in m/s
     val isValid: Boolean = true,
     val isPass: Boolean = false, // Whether this is a pass (valid but no distance)
     val coordinates: ThrowCoordinate? = null,
-    val timestamp: Long = System.currentTimeMillis(),
-    val rawEDMReading: Any? = null // Can store any EDM reading format
+    val timestamp: Long = SessionSources.now(), // When the result was recorded
+    val measuredAt: Long? = null, // When the EDM was triggered; for syncing with video and the attempt clock
+    val deviceLatencyMs: Long? = null, // EDM trigger to response
+    val implementId: String? = null, // Implement thrown, from the implement registry
+    val foulMarginMm: Double? = null, // Jumps: how far over the take-off line the foot was
+    val rawEDMReading: Any? = null // Can store any EDM reading format; EDMInterface.MeasurementComponents when verbose
 )
 
 data class MeasurementState(
@@ -48,9 +64,72 @@ data class MeasurementState(
     val lastEDMReading: Any? = null,
     val lastWindReading: Double? = null, // m/s
     val measurementHistory: List<MeasurementResult> = emptyList(),
-    val errorMessage: String? = null
+    val errorMessage: String? = null,
+    val verbose: Boolean = false // Keep the intermediate reduction values with each measurement
+)
+
+/**
+ * Rule wind window for a horizontal jump, started when the athlete is on the runway and
+ * bound to their next recorded jump
+ */
+data class JumpWindWindow(
+    val athleteBib: String,
+    val startedAt: Long,
+    val endsAt: Long,
+    val windSpeed: Double? = null, // m/s, once the window has closed
+    val error: String? = null,
+    val complete: Boolean = false
 )
 
+/**
+ * Placings changed after an attempt was voided, corrected or its review decided
+ */
+data class RankingChange(
+    val reason: String, // "void", "correction" or "review"
+    val athleteBib: String,
+    val round: Int,
+    val previousPlaces: Map<String, Int?>, // Bib to place; null without a valid mark
+    val places: Map<String, Int?>,
+    val advancingChanged: Boolean, // Athletes through to the final rounds changed
+    val timestamp: Long = SessionSources.now()
+) {
+    val movedAthletes: List<String>
+        get() = places.keys.filter { places[it] != previousPlaces[it] }
+}
+
+enum class BestMarkEventType {
+    NEW_LEADER,     // Took the lead from another athlete, or set the first mark of the event
+    SESSION_BEST    // Beat the athlete's own best earlier in this session
+}
+
+/**
+ * A new attempt beat a standing best. previousMark is the mark it beat: the leading mark
+ * for NEW_LEADER, the athlete's own best for SESSION_BEST; null when there was none.
+ */
+data class BestMarkEvent(
+    val type: BestMarkEventType,
+    val athleteBib: String,
+    val athleteName: String,
+    val round: Int,
+    val attemptId: String,
+    val previousMark: Double?,
+    val newMark: Double,
+    val previousLeaderBib: String? = null, // NEW_LEADER only
+    val timestamp: Long = SessionSources.now()
+) {
+    fun toMap(): Map<String, Any?> = mapOf(
+        "type" to type.name,
+        "athleteBib" to athleteBib,
+        "athleteName" to athleteName,
+        "round" to round,
+        "attemptId" to attemptId,
+        "previousMark" to previousMark,
+        "newMark" to newMark,
+        "previousLeaderBib" to previousLeaderBib,
+        "timestamp" to timestamp
+    )
+}
+
 // Alias for compatibility with CompetitionFlowScreens.kt
 typealias CompetitionMeasurementState = MeasurementState
 
@@ -66,11 +145,19 @@ class CompetitionMeasurementManager(
     private val getCalibrationState: () -> com.polyfieldandroid.CalibrationState
 ) : ViewModel() {
     
-    // Clean EDM Interface for new measurements
-    private val edmInterface = EDMInterface(context)
+    // Clean EDM Interface for new measurements; the module's, so each station's calibration applies
+    private val edmInterface get() = edmModule.edmInterface
     
     companion object {
         private const val TAG = "CompetitionMeasurement"
+        private const val CONDITIONS_SAMPLE_INTERVAL_MS = 60_000L
+        private const val FLIGHT_LOOKBACK_MS = 120_000L
+
+        // Plasticine indicator board is 100 mm wide; allow for a foot well past it
+        private const val MAX_FOUL_MARGIN_MM = 500.0
+
+        // Long and triple jump wind is measured over 5 s
+        const val JUMP_WIND_WINDOW_SECONDS = 5
     }
     
     private val edmCalculations = EDMCalculations()
@@ -78,6 +165,120 @@ class CompetitionMeasurementManager(
     // State management
     private val _measurementState = MutableStateFlow(MeasurementState())
     val measurementState: StateFlow<MeasurementState> = _measurementState.asStateFlow()
+
+    // Read-only public feed for venue big screens and spectators
+    private val spectatorFeed = SpectatorFeedServer()
+
+    // Every result change is logged here before it is applied
+    private val wal = CompetitionWal(context)
+
+    // Signs result payloads for QR scan-verification
+    private val resultsVerification = ResultsVerification(context)
+
+    // Backs up logged operations to the club's own storage when configured
+    val cloudSync = CloudSyncManager(context)
+
+    // Scoreboards, meet software and live feeds sent each result alongside the server
+    val uploadTargets = UploadTargetManager(context)
+
+    // Wind, temperature and officials' notes for the event report
+    private val conditionsLog = ConditionsLog(context)
+    private var conditionsSamplingJob: Job? = null
+
+    // Marks with wind across sessions, for coaches' wind analysis
+    private val windPerformanceLog = WindPerformanceLog(context)
+
+    // Station positions used this event, for bringing earlier throws into the current frame
+    private val stationSetups = StationSetupLog(context)
+
+    // Decimal places for numbers in responses and exports
+    private val outputPrecisionStore = OutputPrecisionStore(context)
+    @Volatile private var outputPrecision: OutputPrecision = outputPrecisionStore.load()
+
+    // Venue implements, and the one currently being thrown
+    private val implementRegistry = ImplementRegistry(context)
+    @Volatile private var selectedImplementId: String? = null
+    private val implementInspections = ImplementInspectionLog(context)
+
+    // Reduction of every measured throw, for the correction breakdown
+    private val throwAudit = ThrowAuditLog(context)
+
+    // Each attempt with the wind samples around it, for pairing throws with wind afterwards
+    private val attemptTimelines = AttemptTimelineLog(context)
+
+    // Full circle certification survey in progress, if any
+    private var certification: CircleCertificationProcedure? = null
+
+    // Discus/hammer cage gate survey in progress, and the venue circles it is saved against
+    private var cageSurvey: Pair<String, CageSurveyProcedure>? = null
+    private val venueProfiles = VenueProfileStore(context)
+
+    // EDM station this event measures with; a named station when one tablet runs two circles
+    @Volatile private var edmDeviceId: String = "edm"
+
+    // Wind gauge height and position for the event report
+    private val windGaugePlacementStore = WindGaugePlacementStore(context)
+    @Volatile private var windGaugePlacement: WindGaugePlacement? = windGaugePlacementStore.load()
+
+    // Results and statistics already worked out for recent session revisions
+    private val snapshotCache = SessionSnapshotCache()
+
+    // Steadies the live distance shown to the prism carrier
+    private val guidanceSmoother = TrackingSmoother()
+
+    // Officials' confirmations of the results set; all roles confirming locks the results
+    private val _signOffs = MutableStateFlow<List<OfficialSignOff>>(emptyList())
+    val signOffs: StateFlow<List<OfficialSignOff>> = _signOffs.asStateFlow()
+
+    // Placings as last seen, and the correction awaiting a re-rank
+    @Volatile private var lastPlaces: Map<String, Int?> = emptyMap()
+    @Volatile private var pendingCorrection: Triple<String, String, Int>? = null
+    private val _rankingChanges = MutableSharedFlow<RankingChange>(extraBufferCapacity = 16)
+    val rankingChanges: SharedFlow<RankingChange> = _rankingChanges.asSharedFlow()
+
+    // Live placings for announcers and big screens, rebuilt whenever results change
+    private val _leaderboard = MutableStateFlow<Leaderboard?>(null)
+    val leaderboard: StateFlow<Leaderboard?> = _leaderboard.asStateFlow()
+
+    // Announcer-ready text for each new result
+    @Volatile private var announcementFormat: AnnouncementFormat = AnnouncementFormat.ENGLISH
+    private val _announcements = MutableSharedFlow<Announcement>(extraBufferCapacity = 16)
+    val announcements: SharedFlow<Announcement> = _announcements.asSharedFlow()
+
+    // New leaders and session bests, so displays need not re-rank to spot them
+    private val _bestMarkEvents = MutableSharedFlow<BestMarkEvent>(extraBufferCapacity = 16)
+    val bestMarkEvents: SharedFlow<BestMarkEvent> = _bestMarkEvents.asSharedFlow()
+
+    // Wind window for the athlete on the runway
+    private val _jumpWind = MutableStateFlow<JumpWindWindow?>(null)
+    val jumpWind: StateFlow<JumpWindWindow?> = _jumpWind.asStateFlow()
+    private var jumpWindJob: Job? = null
+
+    // Highest throw number handed out this competition
+    private val throwNumberLock = Any()
+    private var lastThrowNumber = 0
+
+    // Operations that were logged but not completed when the app last stopped
+    private val _inFlightOperations = MutableStateFlow<List<WalEntry>>(emptyList())
+    val inFlightOperations: StateFlow<List<WalEntry>> = _inFlightOperations.asStateFlow()
+
+    init {
+        recoverFromWal()
+
+        // Keep the spectator feed in step with athlete results
+        viewModelScope.launch {
+            athleteManager.athleteState.collect { state ->
+                val competitionState = competitionManager.competitionState.value
+                spectatorFeed.publish(
+                    eventName = competitionState.selectedEvent?.name,
+                    round = competitionState.currentRound,
+                    athletes = state.selectedAthletes
+                )
+                rerankAfterCorrection()
+                _leaderboard.value = buildLeaderboard()
+            }
+        }
+    }
     
     /**
      * Start measurement session
@@ -93,6 +294,67 @@ class CompetitionMeasurementManager(
         }
     }
     
+    /**
+     * Keep raw and reduced components (slope distance, corrections, station offsets) with each
+     * measurement so technical officials can audit how a distance was derived
+     */
+    fun setVerboseMeasurements(enabled: Boolean) {
+        _measurementState.value = _measurementState.value.copy(verbose = enabled)
+        Log.d(TAG, "Verbose measurements ${if (enabled) "enabled" else "disabled"}")
+    }
+
+    /**
+     * Components behind the most recent measurement, if it was taken in verbose mode
+     */
+    fun getLastMeasurementComponents(): Map<String, Any>? =
+        (_measurementState.value.currentMeasurement?.rawEDMReading as? EDMInterface.MeasurementComponents)
+            ?.let { outputPrecision.apply(it.toMap()) }
+
+    fun getOutputPrecision(): OutputPrecision = outputPrecision
+
+    /**
+     * Set decimal places and rounding for numeric fields in responses and exports
+     */
+    fun setOutputPrecision(precision: OutputPrecision): OutputPrecision {
+        outputPrecision = outputPrecisionStore.save(precision)
+        Log.d(TAG, "Output precision updated: $outputPrecision")
+        return outputPrecision
+    }
+
+    fun getEdmStation(): String = edmDeviceId
+
+    /**
+     * Measure this event with a named EDM station (see EDMModule.connectSerialDeviceNamed)
+     */
+    fun setEdmStation(deviceId: String): Boolean {
+        if (!EDMModule.isEdmDeviceType(deviceId)) {
+            Log.w(TAG, "Not an EDM station: $deviceId")
+            return false
+        }
+        edmDeviceId = deviceId
+        Log.d(TAG, "Measuring with EDM station $deviceId")
+        return true
+    }
+
+    fun getWindGaugePlacement(): WindGaugePlacement? = windGaugePlacement
+
+    /**
+     * Record where the wind gauge stands (null clears it). A placement outside the rules
+     * is still kept, since it is what was used, and the report flags it; the problems
+     * are returned so the official can move the gauge first.
+     */
+    fun setWindGaugePlacement(placement: WindGaugePlacement?): List<String> {
+        windGaugePlacement = placement
+        windGaugePlacementStore.save(placement)
+        val problems = placement?.problems() ?: emptyList()
+        if (problems.isEmpty()) {
+            Log.d(TAG, "Wind gauge placement recorded: ${placement?.describe() ?: "cleared"}")
+        } else {
+            Log.w(TAG, "Wind gauge placement outside rules: ${problems.joinToString("; ")}")
+        }
+        return problems
+    }
+
     /**
      * Stop measurement session
      */
@@ -107,7 +369,7 @@ class CompetitionMeasurementManager(
     /**
      * Take throw measurement for current athlete
      */
-    suspend fun measureThrow(): MeasurementResult? {
+    suspend fun measureThrow(expectedCircle: String? = null): MeasurementResult? {
         return try {
             val currentAthlete = athleteManager.getCurrentAthlete()
             Log.d(TAG, "measureThrow called - currentAthlete: ${currentAthlete?.bib}")
@@ -140,15 +402,11 @@ class CompetitionMeasurementManager(
                 takeDemoMeasurement(currentAthlete.bib, currentRound, attemptNumber)
             } else {
                 Log.d(TAG, "Taking real measurement")
-                takeRealMeasurement(currentAthlete.bib, currentRound, attemptNumber)
+                takeRealMeasurement(currentAthlete.bib, currentRound, attemptNumber, expectedCircle)
             }
             
-            // Record the measurement
-            if (measurementResult != null) {
-                recordMeasurement(measurementResult)
-            }
-            
-            measurementResult
+            // Record the measurement; the recorded result carries the attempt's ID and number
+            measurementResult?.let { recordMeasurement(it) ?: it }
             
         } catch (e: Exception) {
             Log.e(TAG, "Error taking measurement: ${e.message}")
@@ -160,16 +418,54 @@ class CompetitionMeasurementManager(
     }
     
     /**
-     * Take real measurement using EDM device
+     * Take real measurement using EDM device. When the EDM is shared between circles the
+     * measurement is refused unless expectedCircle and the event match the active circle.
      */
     private suspend fun takeRealMeasurement(
+        athleteBib: String, 
+        round: Int, 
+        attemptNumber: Int,
+        expectedCircle: String? = null
+    ): MeasurementResult? {
+        val selectedEventId = competitionManager.competitionState.value.selectedEvent?.id
+        val claim = edmModule.sharedEdm.beginMeasurement(expectedCircle, selectedEventId)
+        if (claim.isFailure) {
+            _measurementState.value = _measurementState.value.copy(
+                errorMessage = claim.exceptionOrNull()?.message
+            )
+            return null
+        }
+        if (edmModule.sharedEdm.isInUse()) {
+            edmInterface.applyCalibration(getCalibrationState(), edmDeviceId)
+        }
+
+        var stillActive = true
+        val result = try {
+            takeClaimedMeasurement(athleteBib, round, attemptNumber)
+        } finally {
+            stillActive = edmModule.sharedEdm.endMeasurement(claim.getOrThrow())
+        }
+        if (!stillActive) {
+            _measurementState.value = _measurementState.value.copy(
+                errorMessage = "Active circle changed during the measurement; not recorded"
+            )
+            return null
+        }
+        return result
+    }
+
+    /**
+     * Measure and display a throw once the EDM has been claimed for it
+     */
+    private suspend fun takeClaimedMeasurement(
         athleteBib: String, 
         round: Int, 
         attemptNumber: Int
     ): MeasurementResult? {
         return try {
             // Use clean EDM interface for measurement
-            val throwResult = edmInterface.measure("edm")
+            // Components are always taken for the audit log; they are only attached to the result when verbose
+            val throwResult = edmInterface.measure(edmDeviceId, verbose = true)
             
             if (throwResult.isFailure) {
                 val error = throwResult.exceptionOrNull()?.message ?: "Unknown measurement error"
@@ -183,9 +479,11 @@ class CompetitionMeasurementManager(
             val data = throwResult.getOrThrow()
             val distance = data["throwDistance"] as? Double
             val isValid = distance != null && distance > 0.0
+            val components = data["components"] as? EDMInterface.MeasurementComponents
+            components?.let { throwAudit.append(ThrowAuditEntry(athleteBib, round, attemptNumber, it)) }
             
-            // Get wind reading if available
-            val windReading = try {
+            // Get wind reading if available, unless a rule window was taken for this athlete
+            val windReading = if (_jumpWind.value?.athleteBib == athleteBib) null else try {
                 val windResult = edmModule.measureWind()
                 // Extract wind speed from wind reading
                 windResult.windSpeed
@@ -194,6 +492,8 @@ class CompetitionMeasurementManager(
                 null
             }
             
+            val setup = trackStationSetup()
+
             // Generate coordinates for heatmap using clean interface data
             val coordinates = if (distance != null) {
                 val throwCoords = data["throwCoordinates"] as? Map<String, Double>
@@ -204,7 +504,9 @@ class CompetitionMeasurementManager(
                         distance = distance,
                         round = round,
                         attemptNumber = attemptNumber,
-                        isValid = isValid
+                        isValid = isValid,
+                        setupId = setup?.id,
+                        atmosphere = components?.atmosphere
                     )
                 } else {
                     generateThrowCoordinates(distance, round, attemptNumber)
@@ -219,7 +521,9 @@ class CompetitionMeasurementManager(
                 windSpeed = windReading,
                 isValid = isValid,
                 coordinates = coordinates,
-                rawEDMReading = null // Store raw data if needed
+                measuredAt = data["measuredAt"] as? Long,
+                deviceLatencyMs = data["deviceLatencyMs"] as? Long,
+                rawEDMReading = components?.takeIf { _measurementState.value.verbose }
             )
             
             _measurementState.value = _measurementState.value.copy(
@@ -249,13 +553,16 @@ class CompetitionMeasurementManager(
         attemptNumber: Int
     ): MeasurementResult {
         println("TAKING DEMO MEASUREMENT for athlete $athleteBib, round $round")
+        if (isJumpEvent()) {
+            return takeDemoJump(athleteBib, round, attemptNumber)
+        }
         // Generate realistic demo values
-        val baseDistance = 12.0 + (kotlin.random.Random.nextDouble() * 8.0) // 12-20m range
-        val isValidThrow = kotlin.random.Random.nextDouble() > 0.15 // 85% valid throws
+        val baseDistance = 12.0 + (SessionSources.random.nextDouble() * 8.0) // 12-20m range
+        val isValidThrow = SessionSources.random.nextDouble() > 0.15 // 85% valid throws
         val distance = if (isValidThrow) baseDistance else null
         println("GENERATED DISTANCE: $distance")
         
-        val windSpeed = -2.0 + (kotlin.random.Random.nextDouble() * 4.0) // -2 to +2 m/s
+        val windSpeed = -2.0 + (SessionSources.random.nextDouble() * 4.0) // -2 to +2 m/s
         
         val coordinates = if (distance != null) {
             generateDemoCoordinates(distance, round, attemptNumber)
@@ -280,6 +587,233 @@ class CompetitionMeasurementManager(
         return result
     }
     
+    /**
+     * Simulated horizontal jump. Wind comes from the athlete's rule window if one was
+     * started, otherwise from a simulated gauge that sometimes drops out.
+     */
+    private fun takeDemoJump(athleteBib: String, round: Int, attemptNumber: Int): MeasurementResult {
+        val eventType = competitionManager.competitionState.value.selectedEvent?.type ?: competitionManager.getSettings().eventType
+        val isValidJump = SessionSources.random.nextDouble() > 0.25
+        val windReading = if (_jumpWind.value?.athleteBib == athleteBib) null else DemoJumps.windWindow()
+        windReading?.error?.let { Log.w(TAG, "Demo wind: $it") }
+
+        val result = MeasurementResult(
+            athleteBib = athleteBib,
+            round = round,
+            attemptNumber = attemptNumber,
+            distance = if (isValidJump) DemoJumps.distance(DemoSkillLevel.values().random(SessionSources.random), eventType) else null,
+            windSpeed = windReading?.windSpeed,
+            isValid = isValidJump,
+            foulMarginMm = if (isValidJump) null else DemoJumps.foulMarginMm()
+        )
+
+        _measurementState.value = _measurementState.value.copy(
+            currentMeasurement = result,
+            lastWindReading = windReading?.windSpeed
+        )
+
+        Log.d(TAG, "Demo jump taken: ${result.distance?.let { "%.2f m".format(it) } ?: "FOUL"}, wind ${result.windSpeed ?: "none"}")
+        return result
+    }
+
+    private fun isJumpEvent(): Boolean {
+        val competitionState = competitionManager.competitionState.value
+        return DemoJumps.isJumpEvent(competitionState.selectedEvent?.type ?: competitionManager.getSettings().eventType)
+    }
+
+    /**
+     * Note the station setup in use and, once it can be aligned, rotate throws measured from
+     * earlier setups into its frame so the field plot and statistics cover the whole event
+     */
+    private fun trackStationSetup(): StationSetup? {
+        val setup = stationSetups.track(getCalibrationState()) ?: return null
+        if (setup.bisectorRad() != null) {
+            athleteManager.realignHeatmapData { stationSetups.toCurrentFrame(it) }
+        }
+        return setup
+    }
+
+    fun getStationSetups(): List<StationSetup> = stationSetups.getSetups()
+
+    /**
+     * Standard distance lines for field plots, in the same frame as the throw coordinates.
+     * Until the sector line is measured for this circle the arcs are returned in the sector
+     * frame instead, +y along the bisector.
+     */
+    fun getDistanceArcs(
+        circleType: String = getCalibrationState().circleType,
+        intervalM: Double = 5.0,
+        maxDistanceM: Double
+    ): List<DistanceArc> {
+        if (intervalM <= 0 || maxDistanceM <= 0) {
+            Log.w(TAG, "Invalid distance arc spacing: every $intervalM m to $maxDistanceM m")
+            return emptyList()
+        }
+        val calibration = getCalibrationState()
+        val calibrated = calibration.circleType == circleType
+        val bisectorRad = calibration.sectorLineCoordinates
+            ?.takeIf { calibrated && calibration.sectorLineSet }
+            ?.let { CoordinateTransforms.sectorBisector(it, circleType) }
+            ?: (Math.PI / 2)
+        val circleRadius = if (calibrated) calibration.targetRadius else edmModule.getCircleRadius(circleType)
+
+        return CoordinateTransforms.distanceArcs(circleType, circleRadius, intervalM, maxDistanceM, bisectorRad)
+    }
+
+    fun getImplements(): List<Implement> = implementRegistry.getImplements()
+
+    fun registerImplement(implement: Implement): Boolean = implementRegistry.register(implement)
+
+    fun removeImplement(implementId: String) {
+        implementRegistry.remove(implementId)
+        if (selectedImplementId == implementId) selectedImplementId = null
+    }
+
+    /**
+     * Set the implement being thrown; following attempts are tagged with it until changed.
+     * Null stops tagging.
+     */
+    fun selectImplement(implementId: String?): Boolean {
+        if (implementId != null && implementRegistry.get(implementId) == null) {
+            Log.w(TAG, "Unknown implement $implementId")
+            return false
+        }
+        if (implementId != null && implementInspections.latest(implementId)?.passed == false) {
+            Log.w(TAG, "Implement $implementId failed inspection")
+            return false
+        }
+        selectedImplementId = implementId
+        Log.d(TAG, "Selected implement: ${implementId ?: "none"}")
+        return true
+    }
+
+    /**
+     * Record the check-in inspection of an implement. Implements not yet in the registry are
+     * added with the inspected weight so they can be tagged on attempts.
+     */
+    fun recordImplementInspection(
+        implementId: String,
+        weightKg: Double,
+        dimensions: Map<String, Double> = emptyMap(),
+        passed: Boolean,
+        inspector: String,
+        notes: String? = null
+    ): Map<String, Any> {
+        if (implementId.isBlank()) return mapOf("success" to false, "error" to "Implement ID is required")
+        if (inspector.isBlank()) return mapOf("success" to false, "error" to "Inspector name is required")
+        if (weightKg <= 0.0 || weightKg.isNaN()) return mapOf("success" to false, "error" to "Invalid weight $weightKg kg")
+
+        val id = implementId.trim()
+        if (implementRegistry.get(id) == null) {
+            implementRegistry.register(Implement(id, getCalibrationState().circleType, weightKg))
+        }
+        val inspection = ImplementInspection(
+            implementId = id,
+            weightKg = weightKg,
+            dimensions = dimensions.filterValues { !it.isNaN() && !it.isInfinite() },
+            passed = passed,
+            inspector = inspector.trim(),
+            notes = notes?.takeIf { it.isNotBlank() }?.trim()
+        )
+        implementInspections.record(inspection)
+        if (!passed && selectedImplementId == inspection.implementId) selectedImplementId = null
+        return mapOf("success" to true, "implementId" to inspection.implementId, "passed" to passed)
+    }
+
+    fun getImplementInspections(): List<ImplementInspection> = implementInspections.getInspections()
+
+    /**
+     * Implements whose latest inspection this session passed
+     */
+    fun getApprovedImplements(): List<Implement> =
+        implementInspections.getInspections().filter { it.passed }.mapNotNull { implementRegistry.get(it.implementId) }
+
+    fun getSelectedImplement(): Implement? = selectedImplementId?.let { implementRegistry.get(it) }
+
+    /**
+     * Correct the implement recorded for an attempt already taken
+     */
+    fun tagAttemptImplement(athleteBib: String, round: Int, implementId: String?) {
+        if (isResultsLocked()) return
+        athleteManager.setAttemptImplement(athleteBib, round, implementId)
+    }
+
+    /**
+     * Attempts grouped by the implement they were thrown with, for reconciling against the
+     * implement check. Attempts with no implement recorded are left out.
+     */
+    fun getImplementUsage(): List<ImplementUsage> {
+        return athleteManager.athleteState.value.athletes
+            .flatMap { athlete -> athlete.attempts.mapNotNull { attempt -> attempt.implementId?.let { Triple(it, athlete.bib, attempt.round) } } }
+            .groupBy({ it.first }, { Pair(it.second, it.third) })
+            .map { (implementId, attempts) ->
+                ImplementUsage(implementRegistry.get(implementId), implementId, attempts.sortedBy { it.second })
+            }
+            .sortedBy { it.implementId }
+    }
+
+    /**
+     * Would-be distance from one quick read, for checking marker placement or giving
+     * feedback between attempts. Nothing is recorded, displayed or submitted.
+     */
+    suspend fun previewDistance(deviceType: String = edmDeviceId): Map<String, Any> {
+        val reading = edmInterface.measure(deviceType, quickRead = true)
+        if (reading.isFailure) {
+            return EDMInterface.failureMap(reading.exceptionOrNull(), "Preview reading failed")
+        }
+
+        val data = reading.getOrThrow()
+        val distance = data["throwDistance"] as? Double
+            ?: return mapOf("success" to false, "error" to "No distance in preview reading")
+        Log.d(TAG, "Preview distance: ${String.format(java.util.Locale.US, "%.2f", distance)} m")
+        return outputPrecision.apply(
+            mapOf(
+                "success" to true,
+                "preview" to true,
+                "distance" to distance,
+                "measurement" to String.format(java.util.Locale.US, "%.2f m", distance),
+                "throwCoordinates" to (data["throwCoordinates"] ?: emptyMap<String, Double>())
+            )
+        )
+    }
+
+    /**
+     * Read the prism without recording anything and report where it is relative to the circle
+     * edge and to the mark being placed (the last throw unless a target is given)
+     */
+    suspend fun readPrismGuidance(target: ThrowCoordinate? = null): Map<String, Any> {
+        val reading = edmInterface.measure(edmDeviceId, quickRead = true)
+        if (reading.isFailure) {
+            return EDMInterface.failureMap(reading.exceptionOrNull(), "Prism reading failed")
+        }
+
+        val data = reading.getOrThrow()
+        val throwCoords = data["throwCoordinates"] as? Map<*, *>
+        val x = throwCoords?.get("x") as? Double
+        val y = throwCoords?.get("y") as? Double
+        val circleRadius = data["circleRadius"] as? Double
+        if (x == null || y == null || circleRadius == null) {
+            return mapOf("success" to false, "error" to "No prism position in reading")
+        }
+
+        val rawDistance = kotlin.math.hypot(x, y) - circleRadius
+        val smoothed = guidanceSmoother.add(rawDistance)
+        val guidance = PrismGuidance.compute(
+            Pair(x, y),
+            circleRadius,
+            smoothed.smoothed,
+            target ?: _measurementState.value.currentMeasurement?.coordinates
+        )
+        return outputPrecision.apply(mapOf("success" to true) + guidance.toMap())
+    }
+
+    /**
+     * Forget the smoothed distance, e.g. when the carrier starts walking to a new mark
+     */
+    fun resetPrismGuidance() {
+        guidanceSmoother.reset()
+    }
+
     /**
      * Generate simplified throw coordinates for heatmap visualization
      */
@@ -290,7 +824,7 @@ class CompetitionMeasurementManager(
     ): ThrowCoordinate {
         // Generate realistic coordinates based on distance
         // In a real implementation, this would come from the EDM calculation results
-        val angle = kotlin.random.Random.nextDouble() * 2 * PI
+        val angle = SessionSources.random.nextDouble() * 2 * PI
         val x = distance * cos(angle)
         val y = distance * sin(angle)
         
@@ -312,7 +846,7 @@ class CompetitionMeasurementManager(
         round: Int, 
         attemptNumber: Int
     ): ThrowCoordinate {
-        val angle = kotlin.random.Random.nextDouble() * 2 * PI
+        val angle = SessionSources.random.nextDouble() * 2 * PI
         val x = distance * cos(angle)
         val y = distance * sin(angle)
         
@@ -327,12 +861,58 @@ class CompetitionMeasurementManager(
     }
     
     /**
-     * Record measurement result
+     * Record measurement result. Returns the result as recorded, or null if it was refused.
      */
-    private fun recordMeasurement(result: MeasurementResult) {
+    private fun recordMeasurement(measured: MeasurementResult, existingTxId: String? = null): MeasurementResult? {
+        if (existingTxId == null && isResultsLocked()) {
+            Log.w(TAG, "Results are signed off; not recording attempt for ${measured.athleteBib}")
+            _measurementState.value = _measurementState.value.copy(errorMessage = "Results have been signed off and can no longer be changed")
+            return null
+        }
+        val previous = athleteManager.getAthleteByBib(measured.athleteBib)?.attempts?.find { it.round == measured.round }
+        if (existingTxId == null && previous != null) {
+            pendingCorrection = Triple("correction", measured.athleteBib, measured.round)
+        }
+
+        // New attempts are tagged with the implement in use and any wind window taken for
+        // the athlete, and numbered unless they replace an earlier measurement of the same
+        // attempt; replayed ones keep what they had
+        val jumpWindow = if (existingTxId == null && measured.windSpeed == null) takeJumpWind(measured.athleteBib) else null
+        val result = if (existingTxId == null) {
+            measured.copy(
+                id = previous?.id ?: measured.id,
+                throwNumber = previous?.throwNumber ?: nextThrowNumber(),
+                implementId = measured.implementId ?: selectedImplementId,
+                windSpeed = measured.windSpeed ?: jumpWindow?.windSpeed.takeIf { !measured.isPass }
+            )
+        } else {
+            measured
+        }
         println("RECORD MEASUREMENT CALLED for ${result.athleteBib}, round ${result.round}, distance ${result.distance}, foul: ${!result.isValid}, pass: ${result.isPass}")
+        val txId = existingTxId ?: wal.begin(
+            operation = when {
+                !result.isValid -> WalOperation.FOUL
+                result.isPass -> WalOperation.PASS
+                else -> WalOperation.ATTEMPT
+            },
+            athleteBib = result.athleteBib,
+            round = result.round,
+            attemptNumber = result.attemptNumber,
+            distance = result.distance,
+            windSpeed = result.windSpeed,
+            coordinates = result.coordinates,
+            foulMarginMm = result.foulMarginMm,
+            measuredAt = result.measuredAt,
+            attemptId = result.id,
+            throwNumber = result.throwNumber,
+            implementId = result.implementId
+        )
         viewModelScope.launch {
             try {
+                if (existingTxId == null) {
+                    recordAttemptTimeline(result, jumpWindow, _measurementState.value.measurementHistory.lastOrNull()?.timestamp)
+                }
+
                 // Add to measurement history
                 val updatedHistory = _measurementState.value.measurementHistory.toMutableList()
                 updatedHistory.add(result)
@@ -342,7 +922,13 @@ class CompetitionMeasurementManager(
                     currentMeasurement = result, // Set as current measurement for display
                     measurementHistory = updatedHistory
                 )
+                result.windSpeed?.let { conditionsLog.record(ConditionKind.WIND, it, source = "measurement", timestamp = result.timestamp) }
+                recordWindMark(result)
                 
+                // Standings before this attempt are applied, for leader/place wording
+                val announcement = if (existingTxId == null) buildAnnouncement(result) else null
+                val bestMarkEvents = if (existingTxId == null) buildBestMarkEvents(result) else emptyList()
+
                 // Record measurement in athlete manager
                 println("CALLING athleteManager.recordMeasurement")
                 athleteManager.recordMeasurement(
@@ -353,12 +939,22 @@ class CompetitionMeasurementManager(
                     isValid = result.isValid,
                     isPass = result.isPass,
                     windSpeed = result.windSpeed,
-                    coordinates = result.coordinates
+                    coordinates = result.coordinates,
+                    implementId = result.implementId,
+                    foulMarginMm = result.foulMarginMm,
+                    measuredAt = result.measuredAt,
+                    attemptId = result.id,
+                    throwNumber = result.throwNumber
                 )
                 println("ATHLETE MANAGER RECORD COMPLETE")
                 
                 // Record measurement in competition manager
                 competitionManager.recordAttempt(result.athleteBib, result.attemptNumber)
+                wal.commit(txId)
+                announcement?.let { _announcements.tryEmit(it) }
+                bestMarkEvents.forEach { _bestMarkEvents.tryEmit(it) }
+                syncToCloud()
+                sendToUploadTargets(result.athleteBib, result.round)
                 
                 // Submit to server if in connected mode
                 if (modeManager.isConnectedMode() && competitionManager.competitionState.value.selectedEvent != null) {
@@ -378,11 +974,25 @@ class CompetitionMeasurementManager(
                 
             } catch (e: Exception) {
                 Log.e(TAG, "Error recording measurement: ${e.message}")
+                wal.abort(txId)
                 _measurementState.value = _measurementState.value.copy(
                     errorMessage = "Failed to record measurement: ${e.message}"
                 )
             }
         }
+        return result
+    }
+
+    /**
+     * Next throw number for the event. Counts on from the highest handed out this session as
+     * well as those recorded, since a just-recorded attempt may not have reached the athlete
+     * state yet.
+     */
+    private fun nextThrowNumber(): Int = synchronized(throwNumberLock) {
+        val attempts = athleteManager.athleteState.value.athletes.flatMap { it.attempts }
+        val recorded = maxOf(attempts.maxOfOrNull { it.throwNumber ?: 0 } ?: 0, attempts.size)
+        lastThrowNumber = maxOf(lastThrowNumber, recorded) + 1
+        lastThrowNumber
     }
     
     /**
@@ -413,15 +1023,9 @@ class CompetitionMeasurementManager(
                     y = calibration.sectorLineCoordinates!!.second
                 )
 
-                // Calculate left sector line (34.92° counterclockwise from right)
-                val rightAngle = kotlin.math.atan2(rightLine.y, rightLine.x)
-                val leftAngle = rightAngle + Math.toRadians(34.92)
-                val distance = kotlin.math.sqrt(rightLine.x * rightLine.x + rightLine.y * rightLine.y)
-
-                val leftLine = PolyFieldApiClient.Coordinate(
-                    x = distance * kotlin.math.cos(leftAngle),
-                    y = distance * kotlin.math.sin(leftAngle)
-                )
+                // Left sector line is the right line rotated through the sector angle
+                val (leftX, leftY) = CoordinateTransforms.leftSectorLine(calibration.sectorLineCoordinates!!, calibration.circleType)
+                val leftLine = PolyFieldApiClient.Coordinate(x = leftX, y = leftY)
 
                 PolyFieldApiClient.SectorLines(
                     rightLine = rightLine,
@@ -434,15 +1038,17 @@ class CompetitionMeasurementManager(
 
             // Create unique calibration ID from timestamp
             val calibrationId = calibration.centreTimestamp?.replace(":", "")?.replace(" ", "-")
-                ?: System.currentTimeMillis().toString()
+                ?: SessionSources.now().toString()
 
             return PolyFieldApiClient.CalibrationMetadata(
                 circleType = calibration.circleType,
                 circleRadius = calibration.targetRadius,
                 edmPosition = edmPosition,
                 sectorLines = sectorLines,
-                timestamp = java.time.Instant.now().toString(), // ISO 8601 format
-                calibrationId = calibrationId
+                timestamp = java.time.Instant.ofEpochMilli(SessionSources.now()).toString(), // ISO 8601 format
+                calibrationId = calibrationId,
+                venueAltitudeM = edmModule.getRuntimeConfig().venueAltitudeM,
+                prismConstantMm = calibration.prismConstantMm
             )
 
         } catch (e: Exception) {
@@ -456,54 +1062,7 @@ class CompetitionMeasurementManager(
      */
     private suspend fun submitResultToServer(result: MeasurementResult) {
         try {
-            val event = competitionManager.competitionState.value.selectedEvent ?: return
-            val athlete = athleteManager.getAthleteByBib(result.athleteBib) ?: return
-            
-            // Get all measurements for this athlete to build series
-            val allMeasurements = athlete.getCurrentRoundMeasurements(result.round)
-            val series = allMeasurements.map { measurement ->
-                PolyFieldApiClient.Performance(
-                    attempt = measurement.attemptNumber,
-                    mark = measurement.distance?.let { "%.2f".format(it) } ?: if (measurement.isPass) "PASS" else "FOUL",
-                    unit = "m",
-                    wind = measurement.windSpeed?.let { "%.1f".format(it) },
-                    valid = measurement.isValid,
-                    coordinates = measurement.coordinates?.let { coord ->
-                        PolyFieldApiClient.HeatmapCoordinate(
-                            x = coord.x,
-                            y = coord.y,
-                            distance = coord.distance,
-                            round = coord.round,
-                            attempt = coord.attemptNumber,
-                            valid = coord.isValid
-                        )
-                    }
-                )
-            }
-
-            // Build complete heatmap coordinates list for the athlete
-            val heatmapCoordinates = athlete.heatmapData.map { coord ->
-                PolyFieldApiClient.HeatmapCoordinate(
-                    x = coord.x,
-                    y = coord.y,
-                    distance = coord.distance,
-                    round = coord.round,
-                    attempt = coord.attemptNumber,
-                    valid = coord.isValid
-                )
-            }
-
-            // Build calibration metadata to provide complete field geometry
-            val calibrationMetadata = buildCalibrationMetadata()
-
-            val payload = PolyFieldApiClient.ResultPayload(
-                eventId = event.id,
-                athleteBib = result.athleteBib,
-                series = series,
-                heatmapCoordinates = heatmapCoordinates,
-                calibrationMetadata = calibrationMetadata
-            )
-            
+            val payload = buildResultPayload(result.athleteBib, result.round) ?: return
             val success = modeManager.submitResult(payload)
             if (success) {
                 Log.d(TAG, "Result submitted to server for athlete ${result.athleteBib}")
@@ -515,6 +1074,74 @@ class CompetitionMeasurementManager(
             Log.e(TAG, "Error submitting result to server: ${e.message}")
         }
     }
+
+    /**
+     * Send the athlete's series to the configured upload targets, in either mode. Called
+     * after voids, reviews and foul margin edits too, so targets never keep a stale series.
+     */
+    private fun sendToUploadTargets(athleteBib: String, round: Int) {
+        if (!uploadTargets.hasTargets) return
+        val payload = buildResultPayload(athleteBib, round) ?: return
+        viewModelScope.launch(Dispatchers.IO) {
+            val failed = uploadTargets.fanOut(payload).filterValues { !it.success }
+            if (failed.isNotEmpty()) Log.w(TAG, "Upload deferred for ${failed.size} target(s)")
+        }
+    }
+
+    /**
+     * The athlete's series up to the round, as sent to the server and upload targets
+     */
+    private fun buildResultPayload(athleteBib: String, round: Int): PolyFieldApiClient.ResultPayload? {
+        val event = competitionManager.competitionState.value.selectedEvent ?: return null
+        val athlete = athleteManager.getAthleteByBib(athleteBib) ?: return null
+
+        // Get all measurements for this athlete to build series
+        val allMeasurements = athlete.getCurrentRoundMeasurements(round)
+        val series = allMeasurements.map { measurement ->
+            PolyFieldApiClient.Performance(
+                attempt = measurement.attemptNumber,
+                mark = measurement.distance?.let { "%.2f".format(it) } ?: if (measurement.isPass) "PASS" else "FOUL",
+                unit = "m",
+                wind = measurement.windSpeed?.let { "%.1f".format(it) },
+                valid = measurement.isValid,
+                coordinates = measurement.coordinates?.let { coord ->
+                    PolyFieldApiClient.HeatmapCoordinate(
+                        x = coord.x,
+                        y = coord.y,
+                        distance = coord.distance,
+                        round = coord.round,
+                        attempt = coord.attemptNumber,
+                        valid = coord.isValid
+                    )
+                },
+                implementId = measurement.implementId,
+                measuredAt = measurement.measuredAt
+            )
+        }
+
+        // Build complete heatmap coordinates list for the athlete
+        val heatmapCoordinates = athlete.heatmapData.map { coord ->
+            PolyFieldApiClient.HeatmapCoordinate(
+                x = coord.x,
+                y = coord.y,
+                distance = coord.distance,
+                round = coord.round,
+                attempt = coord.attemptNumber,
+                valid = coord.isValid
+            )
+        }
+
+        // Build calibration metadata to provide complete field geometry
+        val calibrationMetadata = buildCalibrationMetadata()
+
+        return PolyFieldApiClient.ResultPayload(
+            eventId = event.id,
+            athleteBib = athleteBib,
+            series = series,
+            heatmapCoordinates = heatmapCoordinates,
+            calibrationMetadata = calibrationMetadata
+        )
+    }
     
     /**
      * Mark current measurement as foul
@@ -592,6 +1219,11 @@ class CompetitionMeasurementManager(
             val selectedEvent = competitionManager.competitionState.value.selectedEvent
             val totalAthletes = athleteManager.getSelectedAthleteCount()
             competitionManager.startCompetition(selectedEvent, totalAthletes)
+            startConditionsLog()
+            stationSetups.reset()
+            throwAudit.reset()
+            attemptTimelines.reset()
+            selectedImplementId = null
 
             // Start measurement session
             startMeasurement()
@@ -607,6 +1239,11 @@ class CompetitionMeasurementManager(
             // Start the actual competition in competition manager with specific count
             val selectedEvent = competitionManager.competitionState.value.selectedEvent
             competitionManager.startCompetition(selectedEvent, checkedInAthleteCount)
+            startConditionsLog()
+            stationSetups.reset()
+            throwAudit.reset()
+            attemptTimelines.reset()
+            selectedImplementId = null
 
             // Start measurement session
             startMeasurement()
@@ -620,6 +1257,28 @@ class CompetitionMeasurementManager(
     fun endCompetition() {
         stopMeasurement()
         competitionManager.endCompetition()
+        conditionsSamplingJob?.cancel()
+        conditionsSamplingJob = null
+
+        // Back up whatever the session has not pushed yet before the log is cleared
+        if (cloudSync.isEnabled) {
+            val finalEntries = wal.readEntries()
+            val sessionId = cloudSync.currentSessionId()
+            val eventName = competitionManager.competitionState.value.selectedEvent?.name ?: ""
+            viewModelScope.launch {
+                val result = cloudSync.pushPending(sessionId, eventName, finalEntries)
+                if (!result.success) Log.w(TAG, "Final cloud backup failed: ${result.message}")
+            }
+            cloudSync.startNewSession()
+        }
+
+        // Results are final; start the next competition with a clean log that keeps the calibration
+        wal.reset()
+        _signOffs.value = emptyList()
+        wal.record(WalOperation.CALIBRATION, calibration = getCalibrationState())
+        athleteManager.clearRecoveredResults()
+        _inFlightOperations.value = emptyList()
+        synchronized(throwNumberLock) { lastThrowNumber = 0 }
         Log.d(TAG, "Competition ended")
     }
     
@@ -646,11 +1305,92 @@ class CompetitionMeasurementManager(
     
     
     /**
-     * Get best mark for specific athlete
+     * Start the rule wind window for the athlete now on the runway, e.g. when the clerk taps
+     * "athlete on runway". The wind over exactly the next 5 s is attached to the athlete's
+     * next recorded jump. Returns false if a window is already running.
      */
-    fun getBestMarkForAthlete(athleteBib: String): Double? {
-        val athlete = athleteManager.getAthleteByBib(athleteBib)
-        return athlete?.getBestMark()
+    fun startJumpWindWindow(athleteBib: String): Boolean {
+        if (jumpWindJob?.isActive == true) {
+            Log.w(TAG, "Wind window already running for ${_jumpWind.value?.athleteBib}")
+            return false
+        }
+        val startedAt = SessionSources.now()
+        val window = JumpWindWindow(athleteBib, startedAt, startedAt + JUMP_WIND_WINDOW_SECONDS * 1000L)
+        _jumpWind.value = window
+        Log.d(TAG, "Wind window started for $athleteBib")
+
+        jumpWindJob = viewModelScope.launch {
+            val reading = if (_measurementState.value.isDemoMode) {
+                delay(JUMP_WIND_WINDOW_SECONDS * 1000L)
+                DemoJumps.windWindow()
+            } else {
+                edmModule.measureWindWindow(JUMP_WIND_WINDOW_SECONDS)
+            }
+            // Only fill in the window this job started; it may have been cancelled meanwhile
+            if (_jumpWind.value?.startedAt == startedAt) {
+                _jumpWind.value = window.copy(windSpeed = reading.windSpeed, error = reading.error, complete = true)
+                _measurementState.value = _measurementState.value.copy(lastWindReading = reading.windSpeed)
+            }
+        }
+        return true
+    }
+
+    fun cancelJumpWindWindow() {
+        jumpWindJob?.cancel()
+        jumpWindJob = null
+        _jumpWind.value = null
+    }
+
+    /**
+     * Closed wind window for this athlete, used once
+     */
+    private fun takeJumpWind(athleteBib: String): JumpWindWindow? {
+        val window = _jumpWind.value?.takeIf { it.athleteBib == athleteBib } ?: return null
+        if (!window.complete) {
+            Log.w(TAG, "Wind window for $athleteBib still open; jump recorded without wind")
+            return null
+        }
+        _jumpWind.value = null
+        return window
+    }
+
+    /**
+     * Keep the gauge samples over the attempt's window: the jump's rule window when one was
+     * taken, otherwise from the previous result (at most FLIGHT_LOOKBACK_MS back) to this one
+     */
+    private fun recordAttemptTimeline(result: MeasurementResult, jumpWindow: JumpWindWindow?, previousRecordedAt: Long?) {
+        val windowTo = jumpWindow?.endsAt ?: result.measuredAt ?: result.timestamp
+        val windowFrom = jumpWindow?.startedAt
+            ?: maxOf(previousRecordedAt ?: 0L, windowTo - FLIGHT_LOOKBACK_MS)
+        val samples = edmModule.getWindSamples().filter { it.timestamp - it.durationMs < windowTo && it.timestamp > windowFrom }
+        attemptTimelines.append(
+            AttemptTimeline(
+                attemptId = result.id,
+                athleteBib = result.athleteBib,
+                round = result.round,
+                measuredAt = result.measuredAt,
+                windowFrom = windowFrom,
+                windowTo = windowTo,
+                windowKind = if (jumpWindow != null) AttemptWindowKind.RULE_WINDOW else AttemptWindowKind.SINCE_PREVIOUS_ATTEMPT,
+                windSamples = samples,
+                windClockOffsetMs = edmModule.getClockOffsets().find { it.deviceId == "wind_network" }?.offsetMs
+            )
+        )
+    }
+
+    /**
+     * An attempt's window and the wind samples within it, or null if none was recorded
+     */
+    fun getAttemptTimeline(attemptId: String): AttemptTimeline? = attemptTimelines.find(attemptId)
+
+    fun getAttemptTimelines(): List<AttemptTimeline> = attemptTimelines.getEntries()
+
+    /**
+     * Get best mark for specific athlete
+     */
+    fun getBestMarkForAthlete(athleteBib: String): Double? {
+        val athlete = athleteManager.getAthleteByBib(athleteBib)
+        return athlete?.getBestMark()
     }
     
     /**
@@ -786,7 +1526,7 @@ class CompetitionMeasurementManager(
     /**
      * Take measurement for specific athlete (doesn't rely on AthleteManager current athlete)
      */
-    suspend fun measureThrowForAthlete(athlete: PolyFieldApiClient.Athlete): MeasurementResult? {
+    suspend fun measureThrowForAthlete(athlete: PolyFieldApiClient.Athlete, expectedCircle: String? = null): MeasurementResult? {
         return try {
             Log.d(TAG, "measureThrowForAthlete called - athlete: ${athlete.bib}")
 
@@ -811,17 +1551,16 @@ class CompetitionMeasurementManager(
                 takeDemoMeasurement(athlete.bib, currentRound, attemptNumber)
             } else {
                 Log.d(TAG, "Taking real measurement for athlete ${athlete.bib}")
-                takeRealMeasurement(athlete.bib, currentRound, attemptNumber)
+                takeRealMeasurement(athlete.bib, currentRound, attemptNumber, expectedCircle)
             }
 
             // Record the measurement
-            if (measurementResult != null) {
-                recordMeasurement(measurementResult)
-                Log.d(TAG, "Recorded measurement: ${measurementResult.distance}m for athlete ${athlete.bib}")
+            measurementResult?.let { measured ->
+                val recorded = recordMeasurement(measured) ?: measured
+                Log.d(TAG, "Recorded measurement #${recorded.throwNumber}: ${recorded.distance}m for athlete ${athlete.bib}")
+                recorded
             }
 
-            measurementResult
-
         } catch (e: Exception) {
             Log.e(TAG, "Error taking measurement for athlete ${athlete.bib}: ${e.message}")
             _measurementState.value = _measurementState.value.copy(
@@ -846,14 +1585,15 @@ class CompetitionMeasurementManager(
     /**
      * Record foul for athlete
      */
-    fun recordFoul(athleteBib: String, round: Int, attemptNumber: Int = 1) {
+    fun recordFoul(athleteBib: String, round: Int, attemptNumber: Int = 1, foulMarginMm: Double? = null) {
         println("RECORDING FOUL for athlete $athleteBib, round $round")
         val foulResult = MeasurementResult(
             athleteBib = athleteBib,
             round = round,
             attemptNumber = 1, // Always 1 since only one attempt per round
             distance = null,
-            isValid = false
+            isValid = false,
+            foulMarginMm = validFoulMargin(foulMarginMm)
         )
         
         recordMeasurement(foulResult)
@@ -861,6 +1601,34 @@ class CompetitionMeasurementManager(
         Log.d(TAG, "Recorded foul for athlete $athleteBib, round $round")
     }
     
+    /**
+     * Record how far over the take-off line a jump foul was, read from the plasticine
+     * after the foul was called. Only fouls carry a margin.
+     */
+    fun setFoulMargin(athleteBib: String, round: Int, foulMarginMm: Double?): Map<String, Any> {
+        if (isResultsLocked()) return mapOf("success" to false, "error" to "Results have been signed off")
+        val attempt = athleteManager.getAthleteByBib(athleteBib)?.attempts?.find { it.round == round }
+            ?: return mapOf("success" to false, "error" to "No attempt for $athleteBib in round $round")
+        if (attempt.isValid) return mapOf("success" to false, "error" to "Attempt is not a foul")
+        if (foulMarginMm != null && validFoulMargin(foulMarginMm) == null) {
+            return mapOf("success" to false, "error" to "Foul margin must be between 0 and $MAX_FOUL_MARGIN_MM mm")
+        }
+        val txId = wal.begin(WalOperation.FOUL_MARGIN, athleteBib = athleteBib, round = round, foulMarginMm = foulMarginMm)
+        applyLoggedEdit(txId, athleteBib, round) { athleteManager.setAttemptFoulMargin(athleteBib, round, foulMarginMm) }
+        return mapOf("success" to true)
+    }
+
+    /**
+     * Foul margins recorded for an athlete, by round, for coach feedback
+     */
+    fun getFoulMargins(athleteBib: String): Map<Int, Double> =
+        athleteManager.getAthleteByBib(athleteBib)?.attempts
+            ?.mapNotNull { attempt -> attempt.foulMarginMm?.let { attempt.round to it } }
+            ?.toMap() ?: emptyMap()
+
+    private fun validFoulMargin(foulMarginMm: Double?): Double? =
+        foulMarginMm?.takeIf { !it.isNaN() && it in 0.0..MAX_FOUL_MARGIN_MM }
+
     /**
      * Record pass for athlete
      */
@@ -884,22 +1652,342 @@ class CompetitionMeasurementManager(
      * Edit/delete specific attempt
      */
     fun editMeasurement(athleteBib: String, round: Int, measurementNumber: Int) {
-        // For now, just clear the measurement for the round - allows re-measurement
-        val currentAthletes = athleteManager.athleteState.value.athletes.toMutableList()
-        val athleteIndex = currentAthletes.indexOfFirst { it.bib == athleteBib }
+        // Clear the measurement for the round - allows re-measurement
+        val athlete = athleteManager.getAthleteByBib(athleteBib) ?: return
+        if (athlete.attempts.none { it.round == round }) return
+        if (isResultsLocked()) {
+            Log.w(TAG, "Results are signed off; not clearing round $round for $athleteBib")
+            return
+        }
+
+        // Committed only once the athlete state no longer has the attempt
+        val txId = wal.begin(WalOperation.VOID, athleteBib = athleteBib, round = round)
+        pendingCorrection = Triple("void", athleteBib, round)
+        applyLoggedEdit(txId, athleteBib, round) { athleteManager.voidMeasurement(athleteBib, round) }
+        Log.d(TAG, "Clearing measurement for athlete $athleteBib, round $round")
+    }
+
+    /**
+     * Complete or discard an operation that was interrupted by a crash
+     */
+    fun resolveInFlightOperation(txId: String, apply: Boolean) {
+        val entry = _inFlightOperations.value.find { it.txId == txId } ?: return
+        _inFlightOperations.value = _inFlightOperations.value.filter { it.txId != txId }
+
+        if (!apply) {
+            wal.abort(txId)
+            Log.d(TAG, "Discarded interrupted ${entry.operation} for ${entry.athleteBib}")
+            return
+        }
+
+        val bib = entry.athleteBib
+        val round = entry.round
+        when (entry.operation) {
+            WalOperation.ATTEMPT, WalOperation.FOUL, WalOperation.PASS -> if (bib != null && round != null) {
+                val attempt = wal.attemptFrom(entry)
+                recordMeasurement(
+                    MeasurementResult(
+                        id = attempt.id,
+                        athleteBib = bib,
+                        round = round,
+                        attemptNumber = attempt.attemptNumber,
+                        throwNumber = attempt.throwNumber,
+                        distance = attempt.distance,
+                        windSpeed = attempt.windSpeed,
+                        isValid = attempt.isValid,
+                        isPass = attempt.isPass,
+                        coordinates = attempt.coordinates,
+                        timestamp = attempt.timestamp,
+                        measuredAt = attempt.measuredAt,
+                        foulMarginMm = attempt.foulMarginMm,
+                        implementId = attempt.implementId
+                    ),
+                    existingTxId = txId
+                )
+            } else {
+                wal.abort(txId)
+            }
+            WalOperation.VOID -> if (bib != null && round != null) {
+                applyLoggedEdit(txId, bib, round) { athleteManager.voidMeasurement(bib, round) }
+            } else {
+                wal.abort(txId)
+            }
+            WalOperation.REVIEW -> if (bib != null && round != null) {
+                applyLoggedEdit(txId, bib, round) { athleteManager.setAttemptReview(bib, round, entry.review) }
+            } else {
+                wal.abort(txId)
+            }
+            WalOperation.FOUL_MARGIN -> if (bib != null && round != null) {
+                applyLoggedEdit(txId, bib, round) { athleteManager.setAttemptFoulMargin(bib, round, entry.foulMarginMm) }
+            } else {
+                wal.abort(txId)
+            }
+            // Calibration is owned by the app view model and restored there; replaying it here could overwrite a newer one
+            else -> wal.abort(txId)
+        }
+        Log.d(TAG, "Completed interrupted ${entry.operation} for $bib")
+    }
+
+    /**
+     * Push operations logged since the last backup. Failures are retried with the next push.
+     */
+    private fun syncToCloud() {
+        if (!cloudSync.isEnabled) return
+        viewModelScope.launch(Dispatchers.IO) {
+            val result = cloudSync.pushPending(
+                cloudSync.currentSessionId(),
+                competitionManager.competitionState.value.selectedEvent?.name ?: "",
+                wal.readEntries()
+            )
+            if (!result.success) Log.w(TAG, "Cloud backup deferred: ${result.message}")
+        }
+    }
+
+    /**
+     * Load an event's startlist from the configured cloud store
+     */
+    suspend fun pullStartlistFromCloud(eventId: String): Map<String, Any> {
+        if (!cloudSync.isEnabled) return mapOf("success" to false, "error" to "Cloud sync not configured")
+        val event = cloudSync.pullStartlist(eventId)
+            ?: return mapOf("success" to false, "error" to "No startlist found for $eventId")
+
+        competitionManager.selectEvent(event)
+        athleteManager.loadAthletesFromEvent(event)
+        return mapOf("success" to true, "athletes" to (event.athletes?.size ?: 0))
+    }
+
+    /**
+     * Record an official's confirmation of the results as they stand. Once every role has
+     * confirmed the same results the session is locked against further changes.
+     */
+    fun signOffResults(name: String, role: OfficialRole): Map<String, Any> {
+        if (name.isBlank()) return mapOf("success" to false, "error" to "Official's name is required")
+        val sheet = buildResultsSheet()
+        if (sheet.entries.isEmpty()) return mapOf("success" to false, "error" to "No results to sign off")
+
+        val resultsHash = ResultsVerification.sessionHash(sheet)
+        if (ResultsSignOff.isLocked(_signOffs.value, resultsHash)) {
+            return mapOf("success" to false, "error" to "Results are already signed off")
+        }
+        val openReviews = getAttemptsUnderReview()
+        if (openReviews.isNotEmpty()) {
+            return mapOf("success" to false, "error" to "${openReviews.size} attempt(s) are still under review")
+        }
+
+        val signOff = OfficialSignOff(name.trim(), role, resultsHash)
+        wal.record(WalOperation.SIGN_OFF, signOff = signOff)
+        _signOffs.value = _signOffs.value + signOff
+        syncToCloud()
+
+        val outstanding = ResultsSignOff.outstandingRoles(_signOffs.value, resultsHash)
+        Log.d(TAG, "Results $resultsHash confirmed by ${role.title} ${signOff.name}; outstanding: ${outstanding.map { it.title }}")
+        return mapOf(
+            "success" to true,
+            "resultsHash" to resultsHash,
+            "locked" to outstanding.isEmpty(),
+            "outstanding" to outstanding.map { it.title }
+        )
+    }
+
+    fun getAnnouncementFormat(): AnnouncementFormat = announcementFormat
+
+    /**
+     * Use a built-in language ("en", "fr", "de", "es", "cy") or custom wording for announcements
+     */
+    fun setAnnouncementFormat(format: AnnouncementFormat) {
+        announcementFormat = format
+        Log.d(TAG, "Announcement language: ${format.languageTag}")
+    }
+
+    fun setAnnouncementLanguage(languageTag: String) = setAnnouncementFormat(AnnouncementFormat.forLanguage(languageTag))
+
+    /**
+     * Announcement for a result about to be applied, placed against everyone else's best marks
+     */
+    private fun buildAnnouncement(result: MeasurementResult): Announcement {
+        val athlete = athleteManager.getAthleteByBib(result.athleteBib)
+        val previousBest = athlete?.getValidMeasurements()?.filter { it.round != result.round }?.mapNotNull { it.distance }?.maxOrNull()
+        val othersBest = athleteManager.getSelectedAthletes().filter { it.bib != result.athleteBib }.mapNotNull { it.getBestMark() }
+        val leaderBest = othersBest.maxOrNull()
+
+        val mark = result.distance?.takeIf { result.isValid && !result.isPass }
+        val best = listOfNotNull(previousBest, mark).maxOrNull()
+        val wasLeading = previousBest != null && (leaderBest == null || previousBest > leaderBest)
+        return AnnouncementFormatter.format(
+            result = result,
+            athleteName = athlete?.name ?: "",
+            place = best?.let { value -> othersBest.count { it > value } + 1 },
+            isNewLeader = mark != null && !wasLeading && (leaderBest == null || mark > leaderBest),
+            isPersonalBestInEvent = mark != null && previousBest != null && mark > previousBest,
+            format = announcementFormat
+        )
+    }
+
+    /**
+     * Bests this attempt beats, against the standings before it is applied. A remeasure of
+     * the same attempt is compared with the athlete's other rounds only.
+     */
+    private fun buildBestMarkEvents(result: MeasurementResult): List<BestMarkEvent> {
+        val mark = result.distance?.takeIf { result.isValid && !result.isPass } ?: return emptyList()
+        val athlete = athleteManager.getAthleteByBib(result.athleteBib)
+        val previousBest = athlete?.getValidMeasurements()?.filter { it.round != result.round }?.mapNotNull { it.distance }?.maxOrNull()
+        val leader = athleteManager.getSelectedAthletes()
+            .filter { it.bib != result.athleteBib }
+            .mapNotNull { other -> other.getBestMark()?.let { other.bib to it } }
+            .maxByOrNull { it.second }
+
+        fun event(type: BestMarkEventType, previousMark: Double?, previousLeaderBib: String? = null) = BestMarkEvent(
+            type = type,
+            athleteBib = result.athleteBib,
+            athleteName = athlete?.name ?: "",
+            round = result.round,
+            attemptId = result.id,
+            previousMark = previousMark,
+            newMark = mark,
+            previousLeaderBib = previousLeaderBib
+        )
+
+        val events = mutableListOf<BestMarkEvent>()
+        val wasLeading = previousBest != null && (leader == null || previousBest > leader.second)
+        if (!wasLeading && (leader == null || mark > leader.second)) {
+            events.add(event(BestMarkEventType.NEW_LEADER, leader?.second, leader?.first))
+        }
+        if (previousBest != null && mark > previousBest) {
+            events.add(event(BestMarkEventType.SESSION_BEST, previousBest))
+        }
+        return events
+    }
+
+    private fun recordWindMark(result: MeasurementResult) {
+        val distance = result.distance ?: return
+        val windSpeed = result.windSpeed ?: return
+        if (!result.isValid || result.isPass) return
+        windPerformanceLog.append(
+            WindMarkSample(
+                athleteBib = result.athleteBib,
+                athleteName = athleteManager.getAthleteByBib(result.athleteBib)?.name ?: "",
+                distance = distance,
+                windSpeed = windSpeed,
+                eventName = competitionManager.competitionState.value.selectedEvent?.name ?: competitionManager.getSettings().eventType,
+                timestamp = result.timestamp
+            )
+        )
+    }
+
+    /**
+     * Correlate wind component with distance per athlete over a training block, e.g. the
+     * last six weeks. Null bounds are open; a null bib analyses every athlete with marks.
+     */
+    fun getWindPerformanceAnalysis(athleteBib: String? = null, from: Long? = null, to: Long? = null): Map<String, Any> {
+        val samples = windPerformanceLog.getSamples(from, to).filter { athleteBib == null || it.athleteBib == athleteBib }
+        if (samples.isEmpty()) {
+            return mapOf("success" to false, "error" to "No marks with wind readings in this period")
+        }
+        val analyses = samples.groupBy { it.athleteBib }
+            .mapNotNull { (_, athleteSamples) -> WindPerformanceAnalysis.analyse(athleteSamples) }
+            .sortedBy { it.athleteBib }
+        return mapOf(
+            "success" to true,
+            "minSamples" to WindPerformanceAnalysis.MIN_SAMPLES,
+            "athletes" to analyses.map { outputPrecision.apply(it.toMap().filterValues { value -> value != null }.mapValues { (_, value) -> value!! }) }
+        )
+    }
+
+    /**
+     * Mark an attempt as under protest or review. The attempt stays on record; whether its
+     * mark counts in the rankings meanwhile is the referee's call.
+     */
+    fun flagAttemptForReview(athleteBib: String, round: Int, reason: String, raisedBy: String, includeInRankings: Boolean = false): Map<String, Any> {
+        if (isResultsLocked()) return mapOf("success" to false, "error" to "Results have been signed off")
+        val attempt = athleteManager.getAthleteByBib(athleteBib)?.attempts?.find { it.round == round }
+            ?: return mapOf("success" to false, "error" to "No attempt for $athleteBib in round $round")
+        if (attempt.isUnderReview) return mapOf("success" to false, "error" to "Attempt is already under review")
+        if (reason.isBlank() || raisedBy.isBlank()) return mapOf("success" to false, "error" to "Reason and who raised it are required")
+
+        pendingCorrection = Triple("review", athleteBib, round)
+        val review = AttemptReview(reason.trim(), raisedBy.trim(), includeInRankings)
+        val txId = wal.begin(WalOperation.REVIEW, athleteBib = athleteBib, round = round, review = review)
+        applyLoggedEdit(txId, athleteBib, round) { athleteManager.setAttemptReview(athleteBib, round, review) }
+        Log.d(TAG, "Attempt for $athleteBib round $round under review: $reason")
+        return mapOf("success" to true)
+    }
+
+    /**
+     * Record the referee's decision on an attempt under review
+     */
+    fun resolveAttemptReview(athleteBib: String, round: Int, resolution: ReviewResolution, resolvedBy: String, note: String? = null): Map<String, Any> {
+        val review = athleteManager.getAthleteByBib(athleteBib)?.attempts?.find { it.round == round }?.review
+            ?.takeIf { it.resolution == null }
+            ?: return mapOf("success" to false, "error" to "No open review for $athleteBib in round $round")
+        if (resolvedBy.isBlank()) return mapOf("success" to false, "error" to "Name of the official deciding is required")
+
+        pendingCorrection = Triple("review", athleteBib, round)
+        val resolved = review.copy(
+            resolution = resolution,
+            resolvedBy = resolvedBy.trim(),
+            resolutionNote = note?.takeIf { it.isNotBlank() }?.trim(),
+            resolvedAt = SessionSources.now()
+        )
+        val txId = wal.begin(WalOperation.REVIEW, athleteBib = athleteBib, round = round, review = resolved)
+        applyLoggedEdit(txId, athleteBib, round) { athleteManager.setAttemptReview(athleteBib, round, resolved) }
+        Log.d(TAG, "Review of $athleteBib round $round resolved: $resolution by $resolvedBy")
+        return mapOf("success" to true)
+    }
 
-        if (athleteIndex != -1) {
-            val athlete = currentAthletes[athleteIndex]
-            val updatedMeasurements = athlete.attempts.toMutableList()
-            updatedMeasurements.removeAll { it.round == round }
+    /**
+     * Apply an edit to a recorded attempt whose BEGIN has been logged, committing it once the
+     * athlete state holds the change and aborting it if the change fails. A committed edit is
+     * backed up and the athlete's new series sent to the upload targets.
+     */
+    private fun applyLoggedEdit(txId: String, athleteBib: String, round: Int, apply: () -> Job) {
+        viewModelScope.launch {
+            try {
+                apply().join()
+                wal.commit(txId)
+                syncToCloud()
+                sendToUploadTargets(athleteBib, round)
+            } catch (e: Exception) {
+                Log.e(TAG, "Error applying attempt edit: ${e.message}")
+                wal.abort(txId)
+            }
+        }
+    }
 
-            val updatedAthlete = athlete.copy(attempts = updatedMeasurements)
-            currentAthletes[athleteIndex] = updatedAthlete
+    /**
+     * Athlete bib and attempt for every review still open
+     */
+    fun getAttemptsUnderReview(): List<Pair<String, AthleteAttempt>> =
+        athleteManager.athleteState.value.athletes.flatMap { athlete ->
+            athlete.attempts.filter { it.isUnderReview }.map { athlete.bib to it }
+        }
 
-            // Update athlete state (this needs to be done through AthleteManager)
-            // For now just log the action
-            Log.d(TAG, "Cleared measurement for athlete $athleteBib, round $round")
+    /**
+     * Whether every official has confirmed the current results
+     */
+    fun isResultsLocked(): Boolean {
+        // Skip building the results sheet until someone has signed off
+        if (_signOffs.value.isEmpty()) return false
+        return takeSessionSnapshot().isResultsLocked()
+    }
+
+    /**
+     * Rebuild results from the write-ahead log and hold back interrupted operations for the official
+     */
+    private fun recoverFromWal() {
+        val recovery = wal.recover()
+        if (recovery.isEmpty) return
+
+        athleteManager.applyRecoveredResults(recovery)
+        _signOffs.value = recovery.signOffs
+        _inFlightOperations.value = recovery.inFlight.filter { it.operation != WalOperation.CALIBRATION }
+        recovery.inFlight.filter { it.operation == WalOperation.CALIBRATION }.forEach { wal.abort(it.txId) }
+
+        if (_inFlightOperations.value.isNotEmpty()) {
+            _measurementState.value = _measurementState.value.copy(
+                errorMessage = "${_inFlightOperations.value.size} result(s) were interrupted before being saved. Review them before continuing."
+            )
         }
+        Log.d(TAG, "Recovered ${recovery.operationsReplayed} logged operations, ${recovery.inFlight.size} interrupted")
     }
 
     /**
@@ -996,43 +2084,67 @@ class CompetitionMeasurementManager(
     }
 
     /**
-     * Get real athlete rankings from athlete manager for competition popups
+     * After a void, correction or review decision, recompute placings and, once the cut has
+     * been made, who is through to the final rounds and their throwing order. Displays and
+     * exports are told through rankingChanges when any placing moved.
      */
-    fun getRealAthleteRankings(): List<AthleteRanking> {
-        return try {
-            val selectedAthletes = athleteManager.getSelectedAthletes()
-            val athleteState = athleteManager.athleteState.value
-            val checkedInBibs = athleteState.checkedInAthletes
+    private fun rerankAfterCorrection() {
+        val places = buildResultsSheet().entries.associate { it.athlete.bib to it.place }
+        val previous = lastPlaces
+        lastPlaces = places
+
+        val (reason, athleteBib, round) = pendingCorrection ?: return
+        pendingCorrection = null
+        if (places == previous) return
+
+        competitionManager.setAthleteRankings(getRealAthleteRankings())
+        val advancingChanged = reapplyAdvancement()
+        val change = RankingChange(reason, athleteBib, round, previous, places, advancingChanged)
+        _rankingChanges.tryEmit(change)
+        Log.d(TAG, "Re-ranked after $reason of $athleteBib round $round: ${change.movedAthletes.size} athlete(s) moved")
+    }
 
-            if (selectedAthletes.isEmpty()) {
-                Log.d(TAG, "No selected athletes for rankings")
-                return emptyList()
-            }
+    /**
+     * Re-apply the cut to the current placings. Athletes tied at the last qualifying place all
+     * go through. Returns whether the advancing athletes changed.
+     */
+    private fun reapplyAdvancement(): Boolean {
+        val state = competitionManager.competitionState.value
+        val advanced = state.progressingAthletes
+        if (advanced.isEmpty()) return false
+
+        val finalRound = state.finalRoundSettings
+        val count = finalRound?.athleteCount?.takeIf { it > 0 } ?: return false
+        val ranked = buildResultsSheet().entries.filter { it.place != null }
+        val cutPlace = ranked.getOrNull(count - 1)?.place
+        val advancing = if (cutPlace == null) {
+            ranked.map { it.athlete.bib }
+        } else {
+            ranked.filter { (it.place ?: Int.MAX_VALUE) <= cutPlace }.map { it.athlete.bib }
+        }
 
-            // Filter to only checked-in athletes with valid measurements
-            val checkedInAthletes = selectedAthletes.filter { athlete ->
-                checkedInBibs.contains(athlete.bib) && athlete.getBestMark() != null
-            }
+        val changed = advancing.toSet() != advanced.toSet()
+        if (changed) {
+            competitionManager.setFinalRoundSettings(count, finalRound.reorderEnabled, advancing)
+            Log.d(TAG, "Advancing athletes changed after re-rank: $advancing")
+        }
+        if (finalRound.reorderEnabled && state.currentRound >= 4) {
+            athleteManager.reorderAthletesByPerformance(reverseOrder = true)
+        }
+        return changed
+    }
 
-            if (checkedInAthletes.isEmpty()) {
+    /**
+     * Get real athlete rankings from athlete manager for competition popups
+     */
+    fun getRealAthleteRankings(): List<AthleteRanking> {
+        return try {
+            val rankings = takeSessionSnapshot().athleteRankings
+            if (rankings.isEmpty()) {
                 Log.d(TAG, "No checked-in athletes with valid marks for rankings")
-                return emptyList()
+            } else {
+                Log.d(TAG, "Generated ${rankings.size} real athlete rankings from checked-in athletes with valid marks")
             }
-
-            // Create rankings using real athlete data, similar to VisualizationManager logic
-            val rankings = checkedInAthletes
-                .sortedByDescending { it.getBestMark() ?: 0.0 }
-                .mapIndexed { index, athlete ->
-                    AthleteRanking(
-                        position = index + 1,
-                        athlete = athlete,
-                        bestMark = athlete.getBestMark(),
-                        isAdvancing = true, // Will be set properly during cut calculation
-                        recentChange = 0
-                    )
-                }
-
-            Log.d(TAG, "Generated ${rankings.size} real athlete rankings from checked-in athletes with valid marks")
             rankings
         } catch (e: Exception) {
             Log.e(TAG, "Error getting real athlete rankings: ${e.message}")
@@ -1062,6 +2174,464 @@ class CompetitionMeasurementManager(
         Log.d(TAG, "Apply athlete cut and advance")
     }
 
+    /**
+     * Capture the session as it stands for statistics and exports. Cheap enough to take on
+     * the main thread; the work derived from it can then run anywhere without reading live
+     * state again.
+     */
+    fun takeSessionSnapshot(): SessionSnapshot {
+        val config = edmModule.getRuntimeConfig()
+        return snapshotCache.share(SessionSnapshot(
+            takenAt = SessionSources.now(),
+            athleteState = athleteManager.athleteState.value,
+            competitionState = competitionManager.competitionState.value,
+            signOffs = _signOffs.value,
+            calibration = getCalibrationState(),
+            outputPrecision = outputPrecision,
+            venueAltitudeM = config.venueAltitudeM,
+            atmosphericPpm = if (edmModule.isAtmosphericCorrectionOn(edmDeviceId)) edmModule.getAtmosphericConditions(edmDeviceId).ppm else null,
+            windGaugePlacement = windGaugePlacement
+        ))
+    }
+
+    /**
+     * Results for the current event, ranked, from checked-in athletes (or all selected
+     * athletes when nobody has been checked in)
+     */
+    fun buildResultsSheet(): ResultsSheet = takeSessionSnapshot().resultsSheet
+
+    /**
+     * Write the current results to a file in the app's exports directory
+     */
+    suspend fun exportResults(format: ResultsExportFormat): Map<String, Any> {
+        val snapshot = takeSessionSnapshot()
+        return withContext(Dispatchers.IO) { exportResults(snapshot, format) }
+    }
+
+    private fun exportResults(snapshot: SessionSnapshot, format: ResultsExportFormat): Map<String, Any> {
+        return try {
+            val sheet = snapshot.resultsSheet
+            if (sheet.entries.isEmpty()) {
+                return mapOf("success" to false, "error" to "No athletes to export")
+            }
+
+            val (fileName, content) = when (format) {
+                ResultsExportFormat.HYTEK -> HyTekResultsExporter.fileName(sheet) to HyTekResultsExporter.export(sheet)
+                ResultsExportFormat.TAF3 -> Taf3ResultsExporter.fileName(sheet) to Taf3ResultsExporter.export(sheet)
+            }
+
+            val file = writeExportFile(fileName, content)
+
+            Log.d(TAG, "Exported ${sheet.entries.size} results as $format to ${file.absolutePath}")
+            mapOf(
+                "success" to true,
+                "path" to file.absolutePath,
+                "mimeType" to format.mimeType,
+                "athletes" to sheet.entries.size
+            )
+        } catch (e: Exception) {
+            Log.e(TAG, "Results export failed: ${e.message}")
+            mapOf("success" to false, "error" to "Export failed: ${e.message}")
+        }
+    }
+
+    /**
+     * End the qualification round: places not taken by automatic qualifiers go to the best
+     * remaining marks. Returns the qualifiers in order.
+     */
+    fun completeQualificationRound(): Map<String, Any> {
+        val settings = competitionManager.getSettings()
+        if (!settings.isQualificationRound) {
+            return mapOf("success" to false, "error" to "Not a qualification round")
+        }
+        competitionManager.setQualificationComplete(true)
+        val qualifiers = buildResultsSheet().entries.filter { it.qualification != null }
+        Log.d(TAG, "Qualification complete: ${qualifiers.count { it.qualification == QualificationMark.AUTOMATIC }} Q, " +
+            "${qualifiers.count { it.qualification == QualificationMark.ON_PERFORMANCE }} q")
+        return mapOf(
+            "success" to true,
+            "qualifiers" to qualifiers.map {
+                mapOf("bib" to it.athlete.bib, "name" to it.athlete.name, "mark" to it.qualification?.symbol, "bestMark" to it.bestMark)
+            }
+        )
+    }
+
+    private fun buildLeaderboard(): Leaderboard = takeSessionSnapshot().leaderboard
+
+    /**
+     * Current placings, best marks, attempts remaining and what each athlete needs to
+     * advance or lead, for an event identified by its event id. A blank id means the
+     * current stand-alone competition.
+     */
+    fun getLeaderboard(sessionId: String): Map<String, Any> {
+        val snapshot = takeSessionSnapshot()
+        if (sessionId.isNotBlank() && sessionId != snapshot.competitionState.selectedEvent?.id) {
+            return mapOf("success" to false, "error" to "No results held for session $sessionId")
+        }
+        val leaderboard = snapshot.leaderboard
+        if (leaderboard.entries.isEmpty()) {
+            return mapOf("success" to false, "error" to "No athletes in the competition")
+        }
+        return mapOf("success" to true) + snapshot.outputPrecision.apply(leaderboard.toMap())
+    }
+
+    /**
+     * Generate a self-contained HTML results page for an event, identified by its event id.
+     * A blank id means the current stand-alone competition.
+     */
+    suspend fun generateResultsHTML(sessionId: String): Map<String, Any> {
+        val snapshot = takeSessionSnapshot()
+        return withContext(Dispatchers.IO) { generateResultsHTML(snapshot, sessionId) }
+    }
+
+    private fun generateResultsHTML(snapshot: SessionSnapshot, sessionId: String): Map<String, Any> {
+        return try {
+            if (sessionId.isNotBlank() && sessionId != snapshot.competitionState.selectedEvent?.id) {
+                return mapOf("success" to false, "error" to "No results held for session $sessionId")
+            }
+
+            val sheet = snapshot.resultsSheet
+            if (sheet.entries.isEmpty()) {
+                return mapOf("success" to false, "error" to "No athletes to publish")
+            }
+
+            val html = ResultsHtmlGenerator.generate(sheet, snapshot.resultsConditions)
+            val file = writeExportFile(ResultsHtmlGenerator.fileName(sheet), html)
+
+            Log.d(TAG, "Generated results page for ${sheet.eventName} at ${file.absolutePath}")
+            mapOf(
+                "success" to true,
+                "path" to file.absolutePath,
+                "mimeType" to "text/html",
+                "html" to html
+            )
+        } catch (e: Exception) {
+            Log.e(TAG, "Results page generation failed: ${e.message}")
+            mapOf("success" to false, "error" to "Results page failed: ${e.message}")
+        }
+    }
+
+    /**
+     * Official results card for the host app to render as PDF
+     */
+    fun buildResultsCardReport(): ReportDocument {
+        val snapshot = takeSessionSnapshot()
+        val sheet = snapshot.resultsSheet
+        return ReportBuilder.resultsCard(
+            sheet,
+            snapshot.resultsConditions,
+            conditionsLog.summary(),
+            implementInspections.getInspections().map { Pair(it, implementRegistry.get(it.implementId)) },
+            ResultsSignOff.current(snapshot.signOffs, ResultsVerification.sessionHash(sheet))
+        )
+    }
+
+    /**
+     * Log a condition reading entered by an official, e.g. temperature from the venue thermometer
+     */
+    fun recordCondition(kind: ConditionKind, value: Double) {
+        conditionsLog.record(kind, value)
+        when (kind) {
+            ConditionKind.TEMPERATURE -> edmModule.setAmbientConditions(temperatureC = value)
+            ConditionKind.PRESSURE -> edmModule.setAmbientConditions(pressureHpa = value)
+            ConditionKind.HUMIDITY -> edmModule.setAmbientConditions(humidityPct = value)
+            else -> {}
+        }
+        Log.d(TAG, "Recorded $kind: $value ${kind.unit}")
+    }
+
+    fun addConditionsNote(text: String) {
+        conditionsLog.addNote(text)
+    }
+
+    /**
+     * Note an official's remark about a round, e.g. "delay due to rain"; the current round by default
+     */
+    fun addRoundNote(text: String, round: Int = competitionManager.competitionState.value.currentRound) {
+        conditionsLog.addNote(text, round = round)
+    }
+
+    /**
+     * Note an official's remark about one attempt, e.g. "cage adjusted". False if no athlete
+     * has an attempt with that ID.
+     */
+    fun addAttemptNote(attemptId: String, text: String): Boolean {
+        athleteManager.athleteState.value.athletes.forEach { athlete ->
+            val attempt = athlete.attempts.find { it.id == attemptId } ?: return@forEach
+            conditionsLog.addNote(text, round = attempt.round, athleteBib = athlete.bib, attemptId = attemptId)
+            return true
+        }
+        return false
+    }
+
+    /**
+     * Officials' notes for the session, oldest first
+     */
+    fun getOfficialNotes(): List<ConditionEntry> = conditionsLog.summary().notes
+
+    fun getConditionsSummary(): ConditionsSummary = conditionsLog.summary()
+
+    /**
+     * Clear the previous event's conditions and sample the wind gauge for the rest of the session
+     */
+    private fun startConditionsLog() {
+        conditionsLog.reset()
+        conditionsSamplingJob?.cancel()
+        conditionsSamplingJob = viewModelScope.launch(Dispatchers.IO) {
+            var lastSampleAt = 0L
+            while (isActive) {
+                // The gauge listener's newest buffered sample; polling the gauge here would compete with it
+                edmModule.getWindSamples().lastOrNull()?.takeIf { it.timestamp > lastSampleAt }?.let { sample ->
+                    conditionsLog.record(ConditionKind.WIND, sample.windSpeed, source = "wind_gauge", timestamp = sample.timestamp)
+                    lastSampleAt = sample.timestamp
+                }
+                delay(CONDITIONS_SAMPLE_INTERVAL_MS)
+            }
+        }
+    }
+
+    /**
+     * Circle-certification record for the current calibration
+     */
+    fun buildCircleCertificationReport(): ReportDocument {
+        val calibration = getCalibrationState()
+        return ReportBuilder.circleCertification(
+            calibration = calibration,
+            tolerance = edmModule.getTolerance(calibration.circleType),
+            deviceName = edmModule.getSelectedEDMDevice().displayName
+        )
+    }
+
+    /**
+     * Correction breakdown for every measured throw this event, for record paperwork
+     */
+    fun buildCorrectionBreakdownReport(): ReportDocument {
+        return ReportBuilder.correctionBreakdown(takeSessionSnapshot().eventName, throwAudit.getEntries())
+    }
+
+    /**
+     * Export the raw readings and each reduction step as CSV. With sectorFrame the landing
+     * points are also given along the sector centreline, which needs the sector line measured.
+     */
+    suspend fun exportCorrectionBreakdown(sectorFrame: Boolean = false): Map<String, Any> {
+        val snapshot = takeSessionSnapshot()
+        return withContext(Dispatchers.IO) { exportCorrectionBreakdown(snapshot, sectorFrame) }
+    }
+
+    private fun exportCorrectionBreakdown(snapshot: SessionSnapshot, sectorFrame: Boolean): Map<String, Any> {
+        return try {
+            // Throws measured after the snapshot belong to the next export
+            val entries = throwAudit.getEntries(until = snapshot.takenAt)
+            if (entries.isEmpty()) {
+                return mapOf("success" to false, "error" to "No measured throws to export")
+            }
+            val bisectorRad = if (sectorFrame) {
+                val calibration = snapshot.calibration
+                calibration.sectorLineCoordinates
+                    ?.takeIf { calibration.sectorLineSet }
+                    ?.let { CoordinateTransforms.sectorBisector(it, calibration.circleType) }
+                    ?: return mapOf("success" to false, "error" to "Measure the sector line to export sector coordinates")
+            } else {
+                null
+            }
+            val file = writeExportFile(
+                "${snapshot.resultsSheet.fileBaseName}_corrections.csv",
+                CorrectionBreakdown.toCsv(entries, snapshot.outputPrecision, bisectorRad)
+            )
+            Log.d(TAG, "Exported correction breakdown for ${entries.size} throws to ${file.absolutePath}")
+            mapOf("success" to true, "path" to file.absolutePath, "mimeType" to "text/csv", "throws" to entries.size)
+        } catch (e: Exception) {
+            Log.e(TAG, "Correction breakdown export failed: ${e.message}")
+            mapOf("success" to false, "error" to "Export failed: ${e.message}")
+        }
+    }
+
+    /**
+     * Begin the full UKA/WA certification survey of the calibrated circle. This is separate
+     * from pre-event calibration and records nothing against athletes.
+     */
+    fun startCircleCertification(): Map<String, Any> {
+        val calibration = getCalibrationState()
+        if (!calibration.centreSet) {
+            return mapOf("success" to false, "error" to "Set the circle centre before certifying")
+        }
+        val procedure = CircleCertificationProcedure(calibration.circleType, calibration.targetRadius)
+        certification = procedure
+        Log.d(TAG, "Started ${calibration.circleType} circle certification")
+        return mapOf("success" to true, "step" to procedure.currentStep().name)
+    }
+
+    /**
+     * Measure the point the current certification step asks for
+     */
+    suspend fun measureCertificationPoint(deviceType: String = edmDeviceId): Map<String, Any> {
+        val procedure = certification
+            ?: return mapOf("success" to false, "error" to "No certification in progress")
+        if (procedure.currentStep() == CertificationStep.COMPLETE) {
+            return mapOf("success" to false, "error" to "Certification already complete")
+        }
+
+        val reading = edmInterface.measure(deviceType)
+        if (reading.isFailure) {
+            return EDMInterface.failureMap(reading.exceptionOrNull(), "Reading failed")
+        }
+        val throwCoords = reading.getOrThrow()["throwCoordinates"] as? Map<*, *>
+        val x = throwCoords?.get("x") as? Double
+        val y = throwCoords?.get("y") as? Double
+        if (x == null || y == null) {
+            return mapOf("success" to false, "error" to "No position in reading")
+        }
+
+        val measuredStep = procedure.currentStep()
+        val nextStep = procedure.addPoint(Pair(x, y))
+        Log.d(TAG, "Certification $measuredStep point at ($x, $y)")
+        return mapOf(
+            "success" to true,
+            "measured" to measuredStep.name,
+            "step" to nextStep.name,
+            "passed" to procedure.result().passed
+        )
+    }
+
+    fun getCircleCertificationResult(): CertificationResult? = certification?.result()
+
+    /**
+     * Certification report for the host app to render as PDF
+     */
+    fun buildUkaCertificationReport(): ReportDocument? {
+        val result = certification?.result() ?: return null
+        return ReportBuilder.ukaCertification(result, edmModule.getSelectedEDMDevice().displayName)
+    }
+
+    fun cancelCircleCertification() {
+        certification = null
+    }
+
+    /**
+     * Begin the cage gate survey for the calibrated discus or hammer circle; the result is
+     * saved in the venue profile of venueCircleId
+     */
+    fun startCageSurvey(venueCircleId: String): Map<String, Any> {
+        val calibration = getCalibrationState()
+        if (!CageSurveyProcedure.supports(calibration.circleType)) {
+            return mapOf("success" to false, "error" to "Cage survey is for discus and hammer circles")
+        }
+        val sectorLine = calibration.sectorLineCoordinates?.takeIf { calibration.centreSet && calibration.sectorLineSet }
+            ?: return mapOf("success" to false, "error" to "Set the centre and measure a sector line before surveying the cage")
+        val procedure = CageSurveyProcedure(calibration.circleType, sectorLine)
+        cageSurvey = venueCircleId to procedure
+        Log.d(TAG, "Started ${calibration.circleType} cage survey for $venueCircleId")
+        return mapOf("success" to true, "step" to procedure.currentStep().name)
+    }
+
+    /**
+     * Measure the gate point the cage survey asks for; the second point completes and saves it
+     */
+    suspend fun measureCageSurveyPoint(deviceType: String = edmDeviceId): Map<String, Any> {
+        val (venueCircleId, procedure) = cageSurvey
+            ?: return mapOf("success" to false, "error" to "No cage survey in progress")
+        if (procedure.currentStep() == CageSurveyStep.COMPLETE) {
+            return mapOf("success" to false, "error" to "Cage survey already complete")
+        }
+
+        val reading = edmInterface.measure(deviceType)
+        if (reading.isFailure) {
+            return EDMInterface.failureMap(reading.exceptionOrNull(), "Reading failed")
+        }
+        val throwCoords = reading.getOrThrow()["throwCoordinates"] as? Map<*, *>
+        val x = throwCoords?.get("x") as? Double
+        val y = throwCoords?.get("y") as? Double
+        if (x == null || y == null) {
+            return mapOf("success" to false, "error" to "No position in reading")
+        }
+
+        val measuredStep = procedure.currentStep()
+        val nextStep = procedure.addPoint(Pair(x, y))
+        Log.d(TAG, "Cage survey $measuredStep point at ($x, $y)")
+
+        val result = procedure.result()
+        result?.let { venueProfiles.saveCageSurvey(venueCircleId, it) }
+        return listOfNotNull(result?.let { "passed" to it.passed }).toMap() + mapOf(
+            "success" to true,
+            "measured" to measuredStep.name,
+            "step" to nextStep.name
+        )
+    }
+
+    fun getCageSurveyResult(): CageSurveyResult? = cageSurvey?.second?.result()
+
+    /**
+     * Venue profile holding the last saved cage survey for a circle
+     */
+    fun getVenueProfile(venueCircleId: String): VenueProfile? = venueProfiles.getProfile(venueCircleId)
+
+    fun cancelCageSurvey() {
+        cageSurvey = null
+    }
+
+    /**
+     * Signed payload for the host to render as a QR code next to an athlete's posted result
+     */
+    fun generateVerificationPayload(athleteBib: String): Map<String, Any> {
+        return try {
+            val payload = resultsVerification.createPayload(buildResultsSheet(), athleteBib)
+                ?: return mapOf("success" to false, "error" to "Athlete $athleteBib is not in the results")
+            mapOf(
+                "success" to true,
+                "payload" to payload,
+                "publicKey" to resultsVerification.getPublicKey()
+            )
+        } catch (e: Exception) {
+            Log.e(TAG, "Verification payload failed: ${e.message}")
+            mapOf("success" to false, "error" to "Verification payload failed: ${e.message}")
+        }
+    }
+
+    private fun writeExportFile(fileName: String, content: String): java.io.File {
+        val exportDir = java.io.File(context.getExternalFilesDir(null) ?: context.filesDir, "exports").apply { mkdirs() }
+        return java.io.File(exportDir, fileName).apply { writeText(content, Charsets.UTF_8) }
+    }
+
+    /**
+     * Bundle all app state into one archive so a replacement tablet can take over
+     */
+    suspend fun backupAll(path: String): Map<String, Any> = withContext(Dispatchers.IO) {
+        try {
+            val manifest = StateBackup(context).backupAll(java.io.File(path))
+            mapOf(
+                "success" to true,
+                "path" to path,
+                "preferences" to manifest.preferences.size,
+                "files" to manifest.files.size
+            )
+        } catch (e: Exception) {
+            Log.e(TAG, "Backup failed: ${e.message}")
+            mapOf("success" to false, "error" to "Backup failed: ${e.message}")
+        }
+    }
+
+    /**
+     * Replace this device's state with a backup. Refused while a competition is running here;
+     * the app must be restarted afterwards to pick up the restored state.
+     */
+    suspend fun restoreAll(path: String): Map<String, Any> = withContext(Dispatchers.IO) {
+        if (competitionManager.isCompetitionActive()) {
+            return@withContext mapOf("success" to false, "error" to "End the running competition before restoring")
+        }
+        try {
+            val manifest = StateBackup(context).restoreAll(java.io.File(path))
+            mapOf(
+                "success" to true,
+                "sourceDevice" to manifest.deviceModel,
+                "createdAt" to manifest.createdAt,
+                "restartRequired" to true
+            )
+        } catch (e: Exception) {
+            Log.e(TAG, "Restore failed: ${e.message}")
+            mapOf("success" to false, "error" to "Restore failed: ${e.message}")
+        }
+    }
+
     /**
      * Sync all current athlete results to the server
      * This is called periodically in the background to ensure server is up-to-date
@@ -1107,7 +2677,9 @@ class CompetitionMeasurementManager(
                                     attempt = coord.attemptNumber,
                                     valid = coord.isValid
                                 )
-                            }
+                            },
+                            implementId = measurement.implementId,
+                            measuredAt = measurement.measuredAt
                         )
                     }
 
@@ -1156,6 +2728,56 @@ class CompetitionMeasurementManager(
         }
     }
 
+    /**
+     * Start the spectator feed with the given sanitisation settings
+     */
+    fun startSpectatorFeed(config: SpectatorFeedConfig = SpectatorFeedConfig()): Boolean {
+        spectatorFeed.config = config
+        return spectatorFeed.start()
+    }
+
+    /**
+     * Stop the spectator feed
+     */
+    fun stopSpectatorFeed() {
+        spectatorFeed.stop()
+    }
+
+    fun isSpectatorFeedRunning(): Boolean = spectatorFeed.isRunning
+
+    /**
+     * Put the EDM into standby between flights; the next measurement wakes it
+     */
+    fun standbyInstrument() {
+        if (_measurementState.value.isDemoMode) return
+        viewModelScope.launch {
+            val result = edmModule.standbyEDM()
+            if (result["success"] != true) {
+                Log.w(TAG, "Standby not applied: ${result["error"]}")
+            }
+        }
+    }
+
+    /**
+     * Wake the EDM ahead of the next athlete so the first reading is not delayed
+     */
+    fun wakeInstrument() {
+        if (_measurementState.value.isDemoMode) return
+        viewModelScope.launch {
+            val result = edmModule.wakeEDM()
+            if (result["success"] != true && result["supported"] == true) {
+                _measurementState.value = _measurementState.value.copy(
+                    errorMessage = "Could not wake EDM: ${result["error"]}"
+                )
+            }
+        }
+    }
+
+    override fun onCleared() {
+        super.onCleared()
+        spectatorFeed.shutdown()
+    }
+
     /**
      * Display measurement result on connected scoreboard
      */
@@ -1163,9 +2785,7 @@ class CompetitionMeasurementManager(
         viewModelScope.launch {
             try {
                 // Check if scoreboard is connected
-                if (!edmModule.isDeviceConnected("scoreboard") &&
-                    !edmModule.isDeviceConnected("scoreboard_daktronics") &&
-                    !edmModule.isDeviceConnected("daktronics")) {
+                if (!edmModule.isScoreboardConnected()) {
                     Log.d(TAG, "Scoreboard not connected, skipping display")
                     return@launch
                 }
@@ -1188,10 +2808,16 @@ class CompetitionMeasurementManager(
                     athleteName = athleteName,
                     bib = result.athleteBib,
                     attempt = result.attemptNumber
-                )
+                ).let { display ->
+                    // Lets a later correction on the board be matched to this attempt
+                    display.copy(parameters = display.parameters + ("attemptId" to result.id))
+                }
 
-                // Send to scoreboard
-                val response = edmModule.sendScoreboardCommand(command)
+                // Send to this circle's scoreboard
+                val response = edmModule.sendScoreboardCommand(
+                    command,
+                    sessionId = competitionManager.competitionState.value.selectedEvent?.id
+                )
 
                 if (response.success) {
                     Log.d(TAG, "Result displayed on scoreboard: $distanceStr for ${result.athleteBib}")
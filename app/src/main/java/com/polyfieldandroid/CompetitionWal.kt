@@ -0,0 +1,291 @@
+package com.polyfieldandroid
+
+import android.content.Context
+import android.util.Log
+import com.google.gson.Gson
+import java.io.File
+import java.io.FileOutputStream
+
+/**
+ * Competition operations recorded in the write-ahead log
+ */
+enum class WalOperation {
+    ATTEMPT,     // Measured mark
+    FOUL,
+    PASS,
+    VOID,        // Attempt for a round cleared so it can be re-measured
+    CALIBRATION, // Current calibration replaced
+    SIGN_OFF,    // An official confirmed the results set
+    REVIEW,      // Protest or review of a recorded attempt raised or decided
+    FOUL_MARGIN  // Foul margin of a recorded foul set or cleared
+}
+
+enum class WalPhase {
+    BEGIN,  // Operation and its data, written before any state changes
+    COMMIT, // Operation fully applied
+    ABORT   // Operation abandoned; its BEGIN is ignored on replay
+}
+
+/**
+ * One line of the log. Only BEGIN entries carry operation data; COMMIT and ABORT
+ * refer back to it by txId.
+ */
+data class WalEntry(
+    val seq: Long,
+    val txId: String,
+    val phase: WalPhase,
+    val operation: WalOperation? = null,
+    val athleteBib: String? = null,
+    val round: Int? = null,
+    val attemptNumber: Int? = null,
+    val distance: Double? = null,
+    val windSpeed: Double? = null,
+    val coordinates: ThrowCoordinate? = null,
+    val calibration: CalibrationState? = null,
+    val signOff: OfficialSignOff? = null,
+    val foulMarginMm: Double? = null,
+    val measuredAt: Long? = null,
+    val attemptId: String? = null, // Attempt the operation records; entries from before IDs were logged have none
+    val throwNumber: Int? = null,
+    val implementId: String? = null,
+    val review: AttemptReview? = null,
+    val timestamp: Long = SessionSources.now()
+)
+
+/**
+ * Competition state rebuilt from the log
+ */
+data class WalRecovery(
+    val attempts: Map<String, List<AthleteAttempt>> = emptyMap(),
+    val heatmapData: Map<String, List<ThrowCoordinate>> = emptyMap(),
+    val calibration: CalibrationState? = null,
+    val signOffs: List<OfficialSignOff> = emptyList(),
+    val inFlight: List<WalEntry> = emptyList(), // BEGIN entries with no COMMIT or ABORT
+    val operationsReplayed: Int = 0
+) {
+    val isEmpty: Boolean
+        get() = attempts.isEmpty() && calibration == null && signOffs.isEmpty() && inFlight.isEmpty()
+}
+
+/**
+ * Competition Write-Ahead Log
+ * Appends every attempt, foul, pass, void and calibration change to an fsynced file
+ * before it is applied, so the exact competition state can be rebuilt after a crash
+ */
+class CompetitionWal(context: Context) {
+
+    companion object {
+        private const val TAG = "CompetitionWal"
+        private const val FILE_NAME = "competition.wal"
+
+        // All instances share one file and one sequence
+        private val lock = Any()
+        private var nextSeq: Long = -1
+    }
+
+    private val file = File(context.applicationContext.filesDir, FILE_NAME)
+    private val gson = Gson()
+
+    /**
+     * Log the start of an operation and return its transaction id
+     */
+    fun begin(
+        operation: WalOperation,
+        athleteBib: String? = null,
+        round: Int? = null,
+        attemptNumber: Int? = null,
+        distance: Double? = null,
+        windSpeed: Double? = null,
+        coordinates: ThrowCoordinate? = null,
+        calibration: CalibrationState? = null,
+        signOff: OfficialSignOff? = null,
+        foulMarginMm: Double? = null,
+        measuredAt: Long? = null,
+        attemptId: String? = null,
+        throwNumber: Int? = null,
+        implementId: String? = null,
+        review: AttemptReview? = null
+    ): String {
+        val txId = SessionSources.newId()
+        append { seq ->
+            WalEntry(
+                seq = seq,
+                txId = txId,
+                phase = WalPhase.BEGIN,
+                operation = operation,
+                athleteBib = athleteBib,
+                round = round,
+                attemptNumber = attemptNumber,
+                distance = distance,
+                windSpeed = windSpeed,
+                coordinates = coordinates,
+                calibration = calibration,
+                signOff = signOff,
+                foulMarginMm = foulMarginMm,
+                measuredAt = measuredAt,
+                attemptId = attemptId,
+                throwNumber = throwNumber,
+                implementId = implementId,
+                review = review
+            )
+        }
+        return txId
+    }
+
+    fun commit(txId: String) {
+        append { seq -> WalEntry(seq = seq, txId = txId, phase = WalPhase.COMMIT) }
+    }
+
+    fun abort(txId: String) {
+        append { seq -> WalEntry(seq = seq, txId = txId, phase = WalPhase.ABORT) }
+    }
+
+    /**
+     * Log and commit an operation that has no intermediate state
+     */
+    fun record(operation: WalOperation, calibration: CalibrationState? = null, signOff: OfficialSignOff? = null) {
+        commit(begin(operation, calibration = calibration, signOff = signOff))
+    }
+
+    /**
+     * All readable entries in log order. A torn final line from a crash mid-write is skipped.
+     */
+    fun readEntries(): List<WalEntry> = synchronized(lock) {
+        if (!file.exists()) return emptyList()
+
+        val entries = mutableListOf<WalEntry>()
+        file.forEachLine { line ->
+            if (line.isBlank()) return@forEachLine
+            try {
+                gson.fromJson(line, WalEntry::class.java)?.let { entries.add(it) }
+            } catch (e: Exception) {
+                Log.w(TAG, "Skipping unreadable log line: ${e.message}")
+            }
+        }
+        entries
+    }
+
+    /**
+     * Rebuild competition state by applying committed operations in commit order.
+     * The same log always produces the same state.
+     */
+    fun recover(): WalRecovery {
+        val entries = readEntries()
+        val begins = entries.filter { it.phase == WalPhase.BEGIN }.associateBy { it.txId }
+        val finished = entries.filter { it.phase != WalPhase.BEGIN }.map { it.txId }.toSet()
+
+        val attempts = mutableMapOf<String, MutableList<AthleteAttempt>>()
+        val heatmap = mutableMapOf<String, MutableList<ThrowCoordinate>>()
+        var calibration: CalibrationState? = null
+        val signOffs = mutableListOf<OfficialSignOff>()
+        var replayed = 0
+
+        entries.filter { it.phase == WalPhase.COMMIT }.forEach { commit ->
+            val begin = begins[commit.txId] ?: return@forEach
+            // A damaged entry may have no operation
+            val operation = begin.operation ?: return@forEach
+            replayed++
+
+            if (operation == WalOperation.CALIBRATION) {
+                calibration = begin.calibration
+                return@forEach
+            }
+            if (operation == WalOperation.SIGN_OFF) {
+                begin.signOff?.let { signOffs.add(it) }
+                return@forEach
+            }
+
+            val bib = begin.athleteBib ?: return@forEach
+            val round = begin.round ?: return@forEach
+            if (operation == WalOperation.REVIEW) {
+                attempts[bib]?.updateRound(round) { it.copy(review = begin.review) }
+                return@forEach
+            }
+            if (operation == WalOperation.FOUL_MARGIN) {
+                attempts[bib]?.updateRound(round) { if (it.isValid) it else it.copy(foulMarginMm = begin.foulMarginMm) }
+                return@forEach
+            }
+            val athleteAttempts = attempts.getOrPut(bib) { mutableListOf() }
+            val athleteHeatmap = heatmap.getOrPut(bib) { mutableListOf() }
+
+            athleteAttempts.removeAll { it.round == round }
+            athleteHeatmap.removeAll { it.round == round }
+            if (operation != WalOperation.VOID) {
+                athleteAttempts.add(attemptFrom(begin))
+                begin.coordinates?.let { athleteHeatmap.add(it) }
+            }
+        }
+
+        val inFlight = begins.values
+            .filter { it.txId !in finished }
+            .sortedBy { it.seq }
+
+        Log.d(TAG, "Recovered $replayed operations, ${inFlight.size} in flight")
+        return WalRecovery(
+            attempts = attempts.mapValues { (_, list) -> list.sortedBy { it.round } }.filterValues { it.isNotEmpty() },
+            heatmapData = heatmap.filterValues { it.isNotEmpty() },
+            calibration = calibration,
+            signOffs = signOffs,
+            inFlight = inFlight,
+            operationsReplayed = replayed
+        )
+    }
+
+    /**
+     * Discard the log, e.g. once a competition has ended and its results are saved
+     */
+    fun reset() {
+        synchronized(lock) {
+            if (file.exists() && !file.delete()) {
+                Log.e(TAG, "Could not delete ${file.name}")
+            }
+            nextSeq = 0
+        }
+        Log.d(TAG, "Log reset")
+    }
+
+    /**
+     * Attempt rebuilt from its BEGIN entry, keeping the attempt ID it was recorded with. Older
+     * entries have none; their txId stands in so replay is still stable.
+     */
+    fun attemptFrom(begin: WalEntry): AthleteAttempt = AthleteAttempt(
+        id = begin.attemptId ?: begin.txId,
+        round = begin.round ?: 0,
+        attemptNumber = begin.attemptNumber ?: 1,
+        throwNumber = begin.throwNumber,
+        distance = if (begin.operation == WalOperation.ATTEMPT) begin.distance else null,
+        windSpeed = begin.windSpeed,
+        isValid = begin.operation != WalOperation.FOUL,
+        isPass = begin.operation == WalOperation.PASS,
+        timestamp = begin.timestamp,
+        coordinates = begin.coordinates,
+        foulMarginMm = begin.foulMarginMm.takeIf { begin.operation == WalOperation.FOUL },
+        measuredAt = begin.measuredAt,
+        implementId = begin.implementId,
+        review = begin.review
+    )
+
+    /**
+     * Apply an edit logged on its own to the attempt already rebuilt for a round
+     */
+    private fun MutableList<AthleteAttempt>.updateRound(round: Int, transform: (AthleteAttempt) -> AthleteAttempt) {
+        indices.filter { this[it].round == round }.forEach { this[it] = transform(this[it]) }
+    }
+
+    private fun append(build: (Long) -> WalEntry) {
+        synchronized(lock) {
+            if (nextSeq < 0) {
+                nextSeq = (readEntries().maxOfOrNull { it.seq } ?: -1) + 1
+            }
+            val entry = build(nextSeq++)
+            try {
+                FileOutputStream(file, true).use { out ->
+                    out.write((gson.toJson(entry) + "\n").toByteArray(Charsets.UTF_8))
+                    out.fd.sync()
+                }
+            } catch (e: Exception) {
+                Log.e(TAG, "Failed to append ${entry.phase} ${entry.operation ?: ""} ${entry.txId}: ${e.message}")
+            }
+        }
+    }
+}
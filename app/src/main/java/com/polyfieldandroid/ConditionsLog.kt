@@ -0,0 +1,154 @@
+package com.polyfieldandroid
+
+import android.content.Context
+import android.util.Log
+import com.google.gson.Gson
+import java.io.File
+
+/**
+ * Measured quantities kept in the conditions log
+ */
+enum class ConditionKind(val unit: String) {
+    WIND("m/s"),
+    TEMPERATURE("°C"),
+    HUMIDITY("%"),
+    PRESSURE("hPa")
+}
+
+/**
+ * One entry in the log: either a reading or a free-text note from an official. A note may
+ * be about a round ("delay due to rain") or one athlete's attempt ("cage adjusted").
+ */
+data class ConditionEntry(
+    val timestamp: Long = SessionSources.now(),
+    val kind: ConditionKind? = null,
+    val value: Double? = null,
+    val source: String = "manual", // "manual", "wind_gauge" or "measurement"
+    val note: String? = null,
+    val round: Int? = null,
+    val athleteBib: String? = null,
+    val attemptId: String? = null
+) {
+    /**
+     * What a note refers to, e.g. "Round 3" or "Bib 112, round 3"; empty for the whole event
+     */
+    fun appliesTo(): String = when {
+        athleteBib != null && round != null -> "Bib $athleteBib, round $round"
+        athleteBib != null -> "Bib $athleteBib"
+        round != null -> "Round $round"
+        else -> ""
+    }
+}
+
+/**
+ * Min/max/mean of one quantity over the session, with when the extremes occurred
+ */
+data class ConditionStats(
+    val kind: ConditionKind,
+    val count: Int,
+    val min: Double,
+    val minAt: Long,
+    val max: Double,
+    val maxAt: Long,
+    val mean: Double
+)
+
+data class ConditionsSummary(
+    val from: Long?,
+    val to: Long?,
+    val stats: List<ConditionStats>,
+    val notes: List<ConditionEntry>
+) {
+    val isEmpty: Boolean
+        get() = stats.isEmpty() && notes.isEmpty()
+}
+
+/**
+ * Conditions Log
+ * Records wind, temperature and any conditions entered by officials throughout a session,
+ * appended to a file so the record survives restarts until the next competition starts
+ */
+class ConditionsLog(context: Context) {
+
+    companion object {
+        private const val TAG = "ConditionsLog"
+        private const val FILE_NAME = "conditions.log"
+        private val lock = Any()
+    }
+
+    private val file = File(context.applicationContext.filesDir, FILE_NAME)
+    private val gson = Gson()
+
+    fun record(kind: ConditionKind, value: Double, source: String = "manual", timestamp: Long = SessionSources.now()) {
+        if (value.isNaN() || value.isInfinite()) return
+        append(ConditionEntry(timestamp = timestamp, kind = kind, value = value, source = source))
+    }
+
+    fun addNote(text: String, round: Int? = null, athleteBib: String? = null, attemptId: String? = null) {
+        if (text.isBlank()) return
+        append(ConditionEntry(note = text.trim(), round = round, athleteBib = athleteBib, attemptId = attemptId))
+    }
+
+    fun getEntries(): List<ConditionEntry> {
+        val lines = synchronized(lock) {
+            if (file.exists()) file.readLines(Charsets.UTF_8) else emptyList()
+        }
+
+        val entries = mutableListOf<ConditionEntry>()
+        lines.forEach { line ->
+            if (line.isBlank()) return@forEach
+            try {
+                gson.fromJson(line, ConditionEntry::class.java)?.let { entries.add(it) }
+            } catch (e: Exception) {
+                Log.w(TAG, "Skipping unreadable entry: ${e.message}")
+            }
+        }
+        return entries
+    }
+
+    fun summary(): ConditionsSummary {
+        val entries = getEntries()
+        val stats = ConditionKind.values().mapNotNull { kind ->
+            val readings = entries.filter { it.kind == kind && it.value != null }
+            if (readings.isEmpty()) return@mapNotNull null
+            val min = readings.minByOrNull { it.value!! }!!
+            val max = readings.maxByOrNull { it.value!! }!!
+            ConditionStats(
+                kind = kind,
+                count = readings.size,
+                min = min.value!!,
+                minAt = min.timestamp,
+                max = max.value!!,
+                maxAt = max.timestamp,
+                mean = readings.mapNotNull { it.value }.average()
+            )
+        }
+        return ConditionsSummary(
+            from = entries.minOfOrNull { it.timestamp },
+            to = entries.maxOfOrNull { it.timestamp },
+            stats = stats,
+            notes = entries.filter { it.note != null }.sortedBy { it.timestamp }
+        )
+    }
+
+    /**
+     * Start a fresh log for a new competition
+     */
+    fun reset() {
+        synchronized(lock) {
+            if (file.exists() && !file.delete()) {
+                Log.e(TAG, "Could not delete ${file.name}")
+            }
+        }
+    }
+
+    private fun append(entry: ConditionEntry) {
+        synchronized(lock) {
+            try {
+                file.appendText(gson.toJson(entry) + "\n", Charsets.UTF_8)
+            } catch (e: Exception) {
+                Log.e(TAG, "Failed to record condition: ${e.message}")
+            }
+        }
+    }
+}
@@ -0,0 +1,361 @@
+package com.polyfieldandroid
+
+import android.content.Context
+import android.util.Log
+import com.google.gson.Gson
+import com.google.gson.reflect.TypeToken
+import com.hoho.android.usbserial.driver.UsbSerialPort
+import org.json.JSONException
+import org.json.JSONObject
+
+/**
+ * Serial line parameters for USB-to-serial connections
+ */
+data class SerialPortConfig(
+    val baudRate: Int = 9600,
+    val dataBits: Int = 8,
+    val stopBits: Int = 1,
+    val parity: String = "NONE",      // NONE, ODD, EVEN, MARK, SPACE
+    val flowControl: String = "NONE"  // NONE, RTS_CTS, DTR_DSR, XON_XOFF
+) {
+    fun usbStopBits(): Int = when (stopBits) {
+        2 -> UsbSerialPort.STOPBITS_2
+        else -> UsbSerialPort.STOPBITS_1
+    }
+
+    fun usbParity(): Int = when (parity.uppercase()) {
+        "ODD" -> UsbSerialPort.PARITY_ODD
+        "EVEN" -> UsbSerialPort.PARITY_EVEN
+        "MARK" -> UsbSerialPort.PARITY_MARK
+        "SPACE" -> UsbSerialPort.PARITY_SPACE
+        else -> UsbSerialPort.PARITY_NONE
+    }
+
+    fun usbFlowControl(): UsbSerialPort.FlowControl {
+        // Gson leaves the field null in configs saved before flow control was added
+        val mode: String? = flowControl
+        return when (mode?.uppercase()) {
+            "RTS_CTS" -> UsbSerialPort.FlowControl.RTS_CTS
+            "DTR_DSR" -> UsbSerialPort.FlowControl.DTR_DSR
+            "XON_XOFF" -> UsbSerialPort.FlowControl.XON_XOFF
+            else -> UsbSerialPort.FlowControl.NONE
+        }
+    }
+
+    /**
+     * First problem with these settings, or null if the adapter can be set to them
+     */
+    fun validate(): String? = when {
+        baudRate !in 50..3_000_000 -> "Baud rate $baudRate is out of range"
+        dataBits !in 5..8 -> "Data bits must be 5 to 8"
+        stopBits !in 1..2 -> "Stop bits must be 1 or 2"
+        parity.uppercase() !in PARITIES -> "Unknown parity: $parity"
+        flowControl.uppercase() !in FLOW_CONTROLS -> "Unknown flow control: $flowControl"
+        else -> null
+    }
+
+    companion object {
+        private val PARITIES = setOf("NONE", "ODD", "EVEN", "MARK", "SPACE")
+        private val FLOW_CONTROLS = setOf("NONE", "RTS_CTS", "DTR_DSR", "XON_XOFF")
+
+        fun fromDeviceSpec(spec: EDMDeviceSpec): SerialPortConfig = SerialPortConfig(
+            baudRate = spec.baudRate,
+            dataBits = spec.dataBits,
+            stopBits = spec.stopBits,
+            parity = spec.parity
+        )
+
+        /**
+         * Settings from JSON such as {"baudRate":1200,"dataBits":7,"parity":"EVEN"}; fields
+         * left out take the 9600 8N1 defaults
+         */
+        fun fromJson(json: String): SerialPortConfig {
+            val defaults = SerialPortConfig()
+            val obj = try {
+                JSONObject(json)
+            } catch (e: JSONException) {
+                throw IllegalArgumentException("Invalid serial config: ${e.message}")
+            }
+            val config = SerialPortConfig(
+                baudRate = obj.optInt("baudRate", defaults.baudRate),
+                dataBits = obj.optInt("dataBits", defaults.dataBits),
+                stopBits = obj.optInt("stopBits", defaults.stopBits),
+                parity = obj.optString("parity", defaults.parity).uppercase(),
+                flowControl = obj.optString("flowControl", defaults.flowControl).uppercase()
+            )
+            config.validate()?.let { throw IllegalArgumentException(it) }
+            return config
+        }
+    }
+}
+
+/**
+ * Saved connection profile, e.g. "Stadium Moxa — Discus circle"
+ */
+data class ConnectionProfile(
+    val name: String,
+    val deviceType: String,          // edm (or a named station, edm_<name>), wind, scoreboard, daktronics
+    val transport: String,           // usb, serial, network, bluetooth, ble, usb_passthrough, rfc2217
+    val address: String = "",        // USB device name, host or Bluetooth MAC address
+    val port: Int = 0,               // TCP port for network and rfc2217 transports
+    val serialConfig: SerialPortConfig? = null,
+    val driver: String? = null,      // EDM registry key or protocol type, e.g. MATO_MTS602R, GILL_WINDMASTER
+    val vendorId: Int? = null,       // Used to find the USB device again if its name changed
+    val productId: Int? = null,
+    val bleService: String? = null,  // GATT service and notifying characteristic UUIDs for ble transport
+    val bleCharacteristic: String? = null,
+    val lastUsed: Long = 0L
+)
+
+/**
+ * Binds a USB adapter to a device role wherever it is plugged in.
+ * A mapping with a serial number only matches that exact adapter; without one
+ * it matches any adapter with the same vendor/product ID.
+ */
+data class UsbRoleMapping(
+    val vendorId: Int,
+    val productId: Int,
+    val serialNumber: String? = null,
+    val deviceType: String,          // edm, wind, scoreboard, daktronics
+    val serialConfig: SerialPortConfig? = null,
+    val driver: String? = null       // EDM registry key for edm roles
+) {
+    fun matches(info: UsbDeviceInfo): Boolean {
+        if (vendorId != info.vendorId || productId != info.productId) return false
+        return serialNumber.isNullOrEmpty() || serialNumber == info.serialNumber
+    }
+
+    val isSerialSpecific: Boolean
+        get() = !serialNumber.isNullOrEmpty()
+}
+
+/**
+ * Persists named connection profiles in SharedPreferences
+ */
+class ConnectionProfileStore(context: Context) {
+
+    companion object {
+        private const val TAG = "ConnectionProfileStore"
+        private const val PREFS_NAME = "polyfield_connection_profiles"
+        private const val PREF_PROFILES = "profiles"
+        private const val PREF_LAST_DEVICES = "last_device_set"
+        private const val PREF_USB_ROLE_MAPPINGS = "usb_role_mappings"
+        private const val PREF_SERIAL_CONFIGS = "serial_configs"
+        private const val PREF_AUTO_RECONNECT_OFF = "auto_reconnect_off"
+        private const val PREF_EDM_STATION_ROLES = "edm_station_roles"
+    }
+
+    private val preferences = context.getSharedPreferences(PREFS_NAME, Context.MODE_PRIVATE)
+    private val gson = Gson()
+    private val lock = Any()
+
+    fun getProfiles(): List<ConnectionProfile> = synchronized(lock) { readProfiles() }
+
+    fun getProfile(name: String): ConnectionProfile? = getProfiles().find { it.name == name }
+
+    /**
+     * Add or replace a profile by name
+     */
+    fun saveProfile(profile: ConnectionProfile) {
+        synchronized(lock) {
+            val profiles = readProfiles().filterNot { it.name == profile.name } + profile
+            writeProfiles(profiles)
+        }
+        Log.d(TAG, "Saved profile '${profile.name}' (${profile.deviceType} via ${profile.transport})")
+    }
+
+    fun deleteProfile(name: String): Boolean {
+        synchronized(lock) {
+            val profiles = readProfiles()
+            val remaining = profiles.filterNot { it.name == name }
+            if (remaining.size == profiles.size) return false
+            writeProfiles(remaining)
+        }
+        Log.d(TAG, "Deleted profile '$name'")
+        return true
+    }
+
+    fun markUsed(name: String) {
+        synchronized(lock) {
+            val profiles = readProfiles().map {
+                if (it.name == name) it.copy(lastUsed = SessionSources.now()) else it
+            }
+            writeProfiles(profiles)
+        }
+    }
+
+    /**
+     * Last known device set, one connection per device type, restored on launch
+     */
+    fun getLastDeviceSet(): List<ConnectionProfile> = synchronized(lock) { readLastDevices() }
+
+    fun rememberLastDevice(profile: ConnectionProfile) {
+        synchronized(lock) {
+            val devices = readLastDevices().filterNot { it.deviceType == profile.deviceType } + profile
+            writeList(PREF_LAST_DEVICES, devices)
+        }
+    }
+
+    fun forgetLastDevice(deviceType: String) {
+        synchronized(lock) {
+            writeList(PREF_LAST_DEVICES, readLastDevices().filterNot { it.deviceType == deviceType })
+        }
+    }
+
+    /**
+     * Line settings last chosen for a device type, reused whenever it reconnects over serial
+     */
+    fun getSerialConfig(deviceType: String): SerialPortConfig? = synchronized(lock) { readSerialConfigs()[deviceType] }
+
+    fun saveSerialConfig(deviceType: String, config: SerialPortConfig) {
+        synchronized(lock) {
+            preferences.edit()
+                .putString(PREF_SERIAL_CONFIGS, gson.toJson(readSerialConfigs() + (deviceType to config)))
+                .apply()
+        }
+        Log.d(TAG, "Saved serial config for $deviceType: ${config.baudRate} ${config.dataBits}${config.parity.first()}${config.stopBits}")
+    }
+
+    /**
+     * Forget the line settings kept for a device type, e.g. once a different driver is chosen
+     */
+    fun clearSerialConfig(deviceType: String) {
+        synchronized(lock) {
+            preferences.edit()
+                .putString(PREF_SERIAL_CONFIGS, gson.toJson(readSerialConfigs() - deviceType))
+                .apply()
+        }
+    }
+
+    private fun readSerialConfigs(): Map<String, SerialPortConfig> {
+        return try {
+            val json = preferences.getString(PREF_SERIAL_CONFIGS, null) ?: return emptyMap()
+            val mapType = object : TypeToken<Map<String, SerialPortConfig>>() {}.type
+            gson.fromJson<Map<String, SerialPortConfig>>(json, mapType) ?: emptyMap()
+        } catch (e: Exception) {
+            Log.e(TAG, "Error reading serial configs: ${e.message}")
+            emptyMap()
+        }
+    }
+
+    /**
+     * Whether a dropped connection is retried automatically; on unless turned off
+     */
+    fun isAutoReconnect(deviceType: String): Boolean = synchronized(lock) {
+        deviceType !in preferences.getStringSet(PREF_AUTO_RECONNECT_OFF, emptySet()).orEmpty()
+    }
+
+    fun setAutoReconnect(deviceType: String, enabled: Boolean) {
+        synchronized(lock) {
+            val off = preferences.getStringSet(PREF_AUTO_RECONNECT_OFF, emptySet()).orEmpty()
+            preferences.edit()
+                .putStringSet(PREF_AUTO_RECONNECT_OFF, if (enabled) off - deviceType else off + deviceType)
+                .apply()
+        }
+    }
+
+    /**
+     * What each named EDM station measures, e.g. "edm_discus" -> "discus"
+     */
+    fun getEdmStationRoles(): Map<String, String> = synchronized(lock) { readEdmStationRoles() }
+
+    fun setEdmStationRole(deviceId: String, role: String?) {
+        synchronized(lock) {
+            val roles = readEdmStationRoles()
+            preferences.edit()
+                .putString(PREF_EDM_STATION_ROLES, gson.toJson(if (role == null) roles - deviceId else roles + (deviceId to role)))
+                .apply()
+        }
+    }
+
+    private fun readEdmStationRoles(): Map<String, String> {
+        return try {
+            val json = preferences.getString(PREF_EDM_STATION_ROLES, null) ?: return emptyMap()
+            val mapType = object : TypeToken<Map<String, String>>() {}.type
+            gson.fromJson<Map<String, String>>(json, mapType) ?: emptyMap()
+        } catch (e: Exception) {
+            Log.e(TAG, "Error reading EDM station roles: ${e.message}")
+            emptyMap()
+        }
+    }
+
+    fun getUsbRoleMappings(): List<UsbRoleMapping> = synchronized(lock) { readRoleMappings() }
+
+    /**
+     * Add or replace the mapping for an adapter (vendor/product ID plus optional serial number)
+     */
+    fun saveUsbRoleMapping(mapping: UsbRoleMapping) {
+        synchronized(lock) {
+            val mappings = readRoleMappings().filterNot { it.sameAdapter(mapping) } + mapping
+            preferences.edit()
+                .putString(PREF_USB_ROLE_MAPPINGS, gson.toJson(mappings))
+                .apply()
+        }
+        Log.d(TAG, "Mapped USB ${"%04X".format(mapping.vendorId)}:${"%04X".format(mapping.productId)} to ${mapping.deviceType}")
+    }
+
+    fun removeUsbRoleMapping(mapping: UsbRoleMapping): Boolean {
+        synchronized(lock) {
+            val mappings = readRoleMappings()
+            val remaining = mappings.filterNot { it.sameAdapter(mapping) }
+            if (remaining.size == mappings.size) return false
+            preferences.edit()
+                .putString(PREF_USB_ROLE_MAPPINGS, gson.toJson(remaining))
+                .apply()
+        }
+        return true
+    }
+
+    /**
+     * Find the role for an attached adapter, preferring serial-number mappings
+     */
+    fun findUsbRoleMapping(info: UsbDeviceInfo): UsbRoleMapping? {
+        val candidates = getUsbRoleMappings().filter { it.matches(info) }
+        return candidates.firstOrNull { it.isSerialSpecific } ?: candidates.firstOrNull()
+    }
+
+    private fun UsbRoleMapping.sameAdapter(other: UsbRoleMapping): Boolean {
+        return vendorId == other.vendorId &&
+            productId == other.productId &&
+            serialNumber.orEmpty() == other.serialNumber.orEmpty()
+    }
+
+    private fun readRoleMappings(): List<UsbRoleMapping> {
+        return try {
+            val json = preferences.getString(PREF_USB_ROLE_MAPPINGS, null) ?: return emptyList()
+            val listType = object : TypeToken<List<UsbRoleMapping>>() {}.type
+            gson.fromJson<List<UsbRoleMapping>>(json, listType) ?: emptyList()
+        } catch (e: Exception) {
+            Log.e(TAG, "Error reading USB role mappings: ${e.message}")
+            emptyList()
+        }
+    }
+
+    /**
+     * Block until pending writes reach disk; an empty commit waits for earlier apply() calls
+     */
+    fun flush(): Boolean = preferences.edit().commit()
+
+    private fun readLastDevices(): List<ConnectionProfile> = readList(PREF_LAST_DEVICES)
+
+    private fun readProfiles(): List<ConnectionProfile> = readList(PREF_PROFILES)
+
+    private fun writeProfiles(profiles: List<ConnectionProfile>) = writeList(PREF_PROFILES, profiles)
+
+    private fun readList(key: String): List<ConnectionProfile> {
+        return try {
+            val json = preferences.getString(key, null) ?: return emptyList()
+            val listType = object : TypeToken<List<ConnectionProfile>>() {}.type
+            gson.fromJson<List<ConnectionProfile>>(json, listType) ?: emptyList()
+        } catch (e: Exception) {
+            Log.e(TAG, "Error reading profiles: ${e.message}")
+            emptyList()
+        }
+    }
+
+    private fun writeList(key: String, profiles: List<ConnectionProfile>) {
+        preferences.edit()
+            .putString(key, gson.toJson(profiles))
+            .apply()
+    }
+}
@@ -0,0 +1,190 @@
+package com.polyfieldandroid
+
+import kotlin.math.atan2
+import kotlin.math.cos
+import kotlin.math.sin
+
+/**
+ * A point in the georeferenced frame, WGS84 decimal degrees
+ */
+data class GeoPoint(
+    val latitude: Double,
+    val longitude: Double
+)
+
+/**
+ * Anchors the circle frame to the earth: where the circle centre is, and which way
+ * the circle frame's +x axis points as a bearing clockwise from true north
+ */
+data class GeoReference(
+    val centreLatitude: Double,
+    val centreLongitude: Double,
+    val xAxisBearingDeg: Double
+)
+
+/**
+ * A standard distance line across the landing sector. The mark is measured from the inside
+ * of the circle or arc, so the line lies at markM + the circle radius from the centre.
+ * Points run from the right-hand sector line to the left.
+ */
+data class DistanceArc(
+    val markM: Double,
+    val radiusM: Double,
+    val points: List<Pair<Double, Double>>
+)
+
+/**
+ * Coordinate Transforms
+ * The one set of rotations/translations between the frames the app works in, so the
+ * measurement pipeline, UI, exporters and external tools agree to the millimetre.
+ *
+ * Frames (all metres, points as (x, y)):
+ *   station - origin at the EDM, axes along the instrument's horizontal zero
+ *   circle  - origin at the circle centre, axes parallel to the station frame
+ *   sector  - origin at the circle centre, +y along the sector bisector, +x to the right
+ *   centreline - the sector frame turned for coaching tools: +x along the bisector, +y to the left
+ *   geo     - latitude/longitude via a GeoReference
+ */
+object CoordinateTransforms {
+
+    // Half the landing sector: 34.92° for circle throws, 28.96° for javelin
+    const val HALF_SECTOR_THROWS_DEG = 17.46
+    const val HALF_SECTOR_JAVELIN_DEG = 14.48
+
+    // Mean earth radius; the plane approximation is well inside a millimetre across a field
+    private const val EARTH_RADIUS_M = 6371008.8
+
+    /**
+     * Horizontal distance from the instrument: hd = sd * cos(90° - va), va from vertically upwards
+     */
+    fun horizontalDistance(slopeDistanceM: Double, verticalAngleDeg: Double): Double =
+        slopeDistanceM * cos(Math.toRadians(90.0) - Math.toRadians(verticalAngleDeg))
+
+    /**
+     * Height of the prism above the instrument: vd = sd * sin(90° - va)
+     */
+    fun verticalDistance(slopeDistanceM: Double, verticalAngleDeg: Double): Double =
+        slopeDistanceM * sin(Math.toRadians(90.0) - Math.toRadians(verticalAngleDeg))
+
+    /**
+     * Height of the ground under the target above the ground under the instrument, with the
+     * trunnion axis instrumentHeightM and the prism targetHeightM above their ground points.
+     * The horizontal distance needs neither height: the vertical angle is read to the prism
+     * itself, so hd is the same whatever the pole length.
+     */
+    fun groundHeightDifference(
+        slopeDistanceM: Double,
+        verticalAngleDeg: Double,
+        instrumentHeightM: Double,
+        targetHeightM: Double
+    ): Double = verticalDistance(slopeDistanceM, verticalAngleDeg) + instrumentHeightM - targetHeightM
+
+    /**
+     * EDM polar reading to a point in the station frame
+     */
+    fun readingToStation(slopeDistanceM: Double, verticalAngleDeg: Double, horizontalAngleDeg: Double): Pair<Double, Double> {
+        val hd = horizontalDistance(slopeDistanceM, verticalAngleDeg)
+        val harRad = Math.toRadians(horizontalAngleDeg)
+        return Pair(hd * cos(harRad), hd * sin(harRad))
+    }
+
+    /**
+     * Station position in the circle frame from a reading taken on the circle centre
+     */
+    fun stationFromCentreReading(slopeDistanceM: Double, verticalAngleDeg: Double, horizontalAngleDeg: Double): Pair<Double, Double> {
+        val (x, y) = readingToStation(slopeDistanceM, verticalAngleDeg, horizontalAngleDeg)
+        return Pair(-x, -y)
+    }
+
+    fun stationToCircle(point: Pair<Double, Double>, station: Pair<Double, Double>): Pair<Double, Double> =
+        Pair(station.first + point.first, station.second + point.second)
+
+    fun circleToStation(point: Pair<Double, Double>, station: Pair<Double, Double>): Pair<Double, Double> =
+        Pair(point.first - station.first, point.second - station.second)
+
+    fun halfSectorDeg(circleType: String): Double =
+        if (circleType == EDMCalculations.CIRCLE_JAVELIN) HALF_SECTOR_JAVELIN_DEG else HALF_SECTOR_THROWS_DEG
+
+    /**
+     * Direction of the sector bisector in the circle frame (radians from +x), given the
+     * measured right-hand sector line point
+     */
+    fun sectorBisector(rightSectorLine: Pair<Double, Double>, circleType: String): Double =
+        atan2(rightSectorLine.second, rightSectorLine.first) + Math.toRadians(halfSectorDeg(circleType))
+
+    /**
+     * Left-hand sector line point, the right-hand point rotated through the full sector angle
+     */
+    fun leftSectorLine(rightSectorLine: Pair<Double, Double>, circleType: String): Pair<Double, Double> =
+        rotate(rightSectorLine, Math.toRadians(2 * halfSectorDeg(circleType)))
+
+    fun circleToSector(point: Pair<Double, Double>, bisectorRad: Double): Pair<Double, Double> =
+        rotate(point, Math.PI / 2 - bisectorRad)
+
+    fun sectorToCircle(point: Pair<Double, Double>, bisectorRad: Double): Pair<Double, Double> =
+        rotate(point, bisectorRad - Math.PI / 2)
+
+    fun circleToCentreline(point: Pair<Double, Double>, bisectorRad: Double): Pair<Double, Double> =
+        rotate(point, -bisectorRad)
+
+    /**
+     * Distance arcs every intervalM up to maxDistanceM, as polylines in the circle frame of
+     * the given bisector. The default bisector is straight up +y, which gives the arcs in
+     * the sector frame.
+     */
+    fun distanceArcs(
+        circleType: String,
+        circleRadiusM: Double,
+        intervalM: Double,
+        maxDistanceM: Double,
+        bisectorRad: Double = Math.PI / 2,
+        segmentsPerArc: Int = 32
+    ): List<DistanceArc> {
+        require(intervalM > 0) { "Arc interval must be positive" }
+        require(maxDistanceM > 0) { "Maximum distance must be positive" }
+        require(segmentsPerArc > 0) { "An arc needs at least one segment" }
+
+        val halfSectorRad = Math.toRadians(halfSectorDeg(circleType))
+        // Step by count rather than by adding the interval, so 0.1 m arcs don't drift
+        return (1..(maxDistanceM / intervalM + 1e-9).toInt()).map { index ->
+            val mark = index * intervalM
+            val radius = mark + circleRadiusM
+            val points = (0..segmentsPerArc).map { step ->
+                val angle = bisectorRad - halfSectorRad + 2 * halfSectorRad * step / segmentsPerArc
+                Pair(radius * cos(angle), radius * sin(angle))
+            }
+            DistanceArc(markM = mark, radiusM = radius, points = points)
+        }
+    }
+
+    fun circleToGeo(point: Pair<Double, Double>, reference: GeoReference): GeoPoint {
+        // Rotate into east/north: +x lies along the reference bearing
+        val bearingRad = Math.toRadians(reference.xAxisBearingDeg)
+        val east = point.first * sin(bearingRad) - point.second * cos(bearingRad)
+        val north = point.first * cos(bearingRad) + point.second * sin(bearingRad)
+
+        val latRad = Math.toRadians(reference.centreLatitude)
+        return GeoPoint(
+            latitude = reference.centreLatitude + Math.toDegrees(north / EARTH_RADIUS_M),
+            longitude = reference.centreLongitude + Math.toDegrees(east / (EARTH_RADIUS_M * cos(latRad)))
+        )
+    }
+
+    fun geoToCircle(point: GeoPoint, reference: GeoReference): Pair<Double, Double> {
+        val latRad = Math.toRadians(reference.centreLatitude)
+        val north = Math.toRadians(point.latitude - reference.centreLatitude) * EARTH_RADIUS_M
+        val east = Math.toRadians(point.longitude - reference.centreLongitude) * EARTH_RADIUS_M * cos(latRad)
+
+        val bearingRad = Math.toRadians(reference.xAxisBearingDeg)
+        return Pair(
+            east * sin(bearingRad) + north * cos(bearingRad),
+            -east * cos(bearingRad) + north * sin(bearingRad)
+        )
+    }
+
+    fun rotate(point: Pair<Double, Double>, angleRad: Double): Pair<Double, Double> {
+        val c = cos(angleRad)
+        val s = sin(angleRad)
+        return Pair(point.first * c - point.second * s, point.first * s + point.second * c)
+    }
+}
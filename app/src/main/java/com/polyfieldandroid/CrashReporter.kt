@@ -0,0 +1,153 @@
+package com.polyfieldandroid
+
+import android.content.Context
+import android.util.Log
+import kotlinx.coroutines.CancellationException
+import java.io.File
+import java.text.SimpleDateFormat
+import java.util.Date
+import java.util.Locale
+
+/**
+ * Crash report passed to the registered callback
+ */
+data class CrashReport(
+    val entryPoint: String,
+    val error: String,
+    val exceptionType: String,
+    val stackTrace: String,
+    val recentLogs: List<String>,
+    val fatal: Boolean,
+    val timestamp: Long = SessionSources.now()
+)
+
+/**
+ * Crash Reporter
+ * Turns unexpected failures at device entry points into structured errors instead of
+ * letting one bad parse take down the app, and reports them with stack and recent logs
+ */
+object CrashReporter {
+
+    private const val TAG = "CrashReporter"
+    private const val REPORT_DIR = "crash_reports"
+    private const val MAX_REPORTS = 20
+    private const val RECENT_LOG_LINES = 200
+
+    const val ERROR_CODE_INTERNAL = "INTERNAL_ERROR"
+
+    @Volatile private var callback: ((CrashReport) -> Unit)? = null
+    @Volatile private var reportDir: File? = null
+
+    /**
+     * Persist reports under the app's files directory and report uncaught
+     * exceptions on any thread before the default handler runs
+     */
+    @JvmStatic
+    fun install(context: Context) {
+        reportDir = File(context.filesDir, REPORT_DIR).apply { mkdirs() }
+
+        val previousHandler = Thread.getDefaultUncaughtExceptionHandler()
+        Thread.setDefaultUncaughtExceptionHandler { thread, throwable ->
+            report("thread:${thread.name}", throwable, fatal = true)
+            previousHandler?.uncaughtException(thread, throwable)
+        }
+        Log.d(TAG, "Crash reporter installed")
+    }
+
+    /**
+     * Register the host callback invoked for every report. Pass null to remove it.
+     */
+    fun setCallback(onCrash: ((CrashReport) -> Unit)?) {
+        callback = onCrash
+    }
+
+    /**
+     * Run an entry point, converting any failure into the fallback value.
+     * Coroutine cancellation is rethrown untouched.
+     */
+    inline fun <T> guard(entryPoint: String, fallback: (Throwable) -> T, block: () -> T): T {
+        return try {
+            block()
+        } catch (e: CancellationException) {
+            throw e
+        } catch (t: Throwable) {
+            report(entryPoint, t, fatal = false)
+            fallback(t)
+        }
+    }
+
+    /**
+     * Guard an entry point that returns the usual success/error result map
+     */
+    inline fun guardResult(entryPoint: String, block: () -> Map<String, Any>): Map<String, Any> {
+        return guard(entryPoint, { t -> errorResult(entryPoint, t) }, block)
+    }
+
+    fun errorResult(entryPoint: String, throwable: Throwable): Map<String, Any> = mapOf(
+        "success" to false,
+        "error" to "Internal error in $entryPoint: ${throwable.message ?: throwable.javaClass.simpleName}",
+        "errorCode" to ERROR_CODE_INTERNAL
+    )
+
+    fun report(entryPoint: String, throwable: Throwable, fatal: Boolean) {
+        try {
+            Log.e(TAG, "Failure in $entryPoint", throwable)
+            val report = CrashReport(
+                entryPoint = entryPoint,
+                error = throwable.message ?: "",
+                exceptionType = throwable.javaClass.name,
+                stackTrace = Log.getStackTraceString(throwable),
+                recentLogs = recentLogs(),
+                fatal = fatal
+            )
+            writeReport(report)
+            callback?.invoke(report)
+        } catch (e: Throwable) {
+            // Reporting must never cause a second failure
+            Log.e(TAG, "Crash reporting failed: ${e.message}")
+        }
+    }
+
+    /**
+     * Saved reports, newest first
+     */
+    fun getSavedReports(): List<File> {
+        return reportDir?.listFiles()?.sortedByDescending { it.lastModified() } ?: emptyList()
+    }
+
+    /**
+     * Recent log lines from this process
+     */
+    private fun recentLogs(): List<String> {
+        return try {
+            val process = Runtime.getRuntime().exec(
+                arrayOf("logcat", "-d", "-t", RECENT_LOG_LINES.toString(), "--pid=${android.os.Process.myPid()}")
+            )
+            process.inputStream.bufferedReader().use { it.readLines() }
+        } catch (e: Exception) {
+            emptyList()
+        }
+    }
+
+    private fun writeReport(report: CrashReport) {
+        val dir = reportDir ?: return
+        val stamp = SimpleDateFormat("yyyyMMdd-HHmmss-SSS", Locale.US).format(Date(report.timestamp))
+        File(dir, "crash-$stamp.txt").writeText(
+            buildString {
+                appendLine("Entry point: ${report.entryPoint}")
+                appendLine("Fatal: ${report.fatal}")
+                appendLine("Time: ${Date(report.timestamp)}")
+                appendLine("Error: ${report.exceptionType}: ${report.error}")
+                appendLine()
+                appendLine(report.stackTrace)
+                appendLine("--- Recent logs ---")
+                report.recentLogs.forEach { appendLine(it) }
+            }
+        )
+
+        dir.listFiles()
+            ?.sortedByDescending { it.lastModified() }
+            ?.drop(MAX_REPORTS)
+            ?.forEach { it.delete() }
+    }
+}
@@ -0,0 +1,92 @@
+package com.polyfieldandroid
+
+import android.content.Context
+import android.util.Log
+import com.google.gson.Gson
+
+/**
+ * Layout and measurement noise the demo and the USB simulator work to. With a fixed station
+ * every device produces the same picture, for screenshots, documentation and training;
+ * leaving the station unset keeps the random placement.
+ */
+data class DemoGeometry(
+    val stationX: Double? = null,          // Station relative to the circle centre (m); null for random
+    val stationY: Double? = null,
+    val instrumentHeightM: Double = 1.5,   // Instrument above the prism
+    val distanceNoiseMm: Double = 0.0,     // ± on each simulated slope distance
+    val angleNoiseArcSec: Double = 0.0,    // ± on each simulated angle
+    val edgeNoiseMm: Double = 5.0          // ± on each demo edge verification reading
+) {
+    val hasFixedStation: Boolean get() = stationX != null && stationY != null
+
+    /**
+     * Station for a demo centre: the configured one, else a random spot within ±10 m
+     */
+    fun station(): Pair<Double, Double> {
+        val x = stationX
+        val y = stationY
+        if (x != null && y != null) return Pair(x, y)
+        return Pair((SessionSources.random.nextDouble() - 0.5) * 20, (SessionSources.random.nextDouble() - 0.5) * 20)
+    }
+
+    fun distanceNoiseM(): Double = noise(distanceNoiseMm) / 1000.0
+
+    fun angleNoiseDeg(): Double = noise(angleNoiseArcSec) / 3600.0
+
+    fun edgeNoiseM(): Double = noise(edgeNoiseMm) / 1000.0
+
+    private fun noise(amplitude: Double): Double =
+        if (amplitude <= 0.0) 0.0 else (SessionSources.random.nextDouble() * 2 - 1) * amplitude
+
+    fun toMap(): Map<String, Any> = listOfNotNull(
+        stationX?.let { "stationX" to it },
+        stationY?.let { "stationY" to it }
+    ).toMap() + mapOf(
+        "instrumentHeightM" to instrumentHeightM,
+        "distanceNoiseMm" to distanceNoiseMm,
+        "angleNoiseArcSec" to angleNoiseArcSec,
+        "edgeNoiseMm" to edgeNoiseMm
+    )
+}
+
+/**
+ * Persists the demo geometry
+ */
+class DemoGeometryStore(context: Context) {
+
+    companion object {
+        private const val TAG = "DemoGeometry"
+        private const val PREFS_NAME = "polyfield_demo_prefs"
+        private const val PREF_GEOMETRY = "demo_geometry"
+    }
+
+    private val preferences = context.getSharedPreferences(PREFS_NAME, Context.MODE_PRIVATE)
+    private val gson = Gson()
+
+    fun load(): DemoGeometry {
+        return try {
+            val json = preferences.getString(PREF_GEOMETRY, null) ?: return DemoGeometry()
+            gson.fromJson(json, DemoGeometry::class.java) ?: DemoGeometry()
+        } catch (e: Exception) {
+            Log.e(TAG, "Error loading demo geometry: ${e.message}")
+            DemoGeometry()
+        }
+    }
+
+    fun save(geometry: DemoGeometry) {
+        preferences.edit().putString(PREF_GEOMETRY, gson.toJson(geometry)).apply()
+    }
+
+    /**
+     * Check a geometry is usable, returning why not or null
+     */
+    fun validate(geometry: DemoGeometry): String? = when {
+        (geometry.stationX == null) != (geometry.stationY == null) -> "Set both station coordinates or neither"
+        listOfNotNull(geometry.stationX, geometry.stationY).any { kotlin.math.abs(it) > 100.0 } -> "Station must be within 100 m of the centre"
+        geometry.instrumentHeightM !in -5.0..5.0 -> "Instrument height must be within ±5 m of the prism"
+        geometry.distanceNoiseMm !in 0.0..50.0 -> "Distance noise must be 0-50 mm"
+        geometry.angleNoiseArcSec !in 0.0..60.0 -> "Angle noise must be 0-60\""
+        geometry.edgeNoiseMm !in 0.0..50.0 -> "Edge noise must be 0-50 mm"
+        else -> null
+    }
+}
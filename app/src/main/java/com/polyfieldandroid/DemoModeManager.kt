@@ -11,7 +11,6 @@ import kotlinx.coroutines.flow.MutableStateFlow
 import kotlinx.coroutines.flow.StateFlow
 import kotlinx.coroutines.flow.asStateFlow
 import kotlinx.coroutines.launch
-import kotlin.random.Random
 
 /**
  * Demo competition templates for different scenarios
@@ -19,7 +18,7 @@ import kotlin.random.Random
 data class DemoCompetitionTemplate(
     val id: String,
     val name: String,
-    val eventType: String, // SHOT, DISCUS, HAMMER, JAVELIN_ARC
+    val eventType: String, // SHOT, DISCUS, HAMMER, JAVELIN_ARC, LONG_JUMP, TRIPLE_JUMP
     val description: String,
     val athletes: List<DemoAthlete>,
     val settings: CompetitionSettings
@@ -50,6 +49,55 @@ enum class DemoSkillLevel(val baseDistance: Double, val variation: Double) {
     ELITE(25.0, 2.0)         // 23-27m range
 }
 
+/**
+ * Simulated horizontal jumps: jump distances, take-off fouls and a wind gauge that drops
+ * out or lands on the +2.0 m/s limit often enough for officials to practise both
+ */
+object DemoJumps {
+    const val LONG_JUMP = "LONG_JUMP"
+    const val TRIPLE_JUMP = "TRIPLE_JUMP"
+
+    private const val DROPOUT_CHANCE = 0.1
+    private const val AT_LIMIT_CHANCE = 0.1
+    private const val JUST_OVER_CHANCE = 0.1
+
+    fun isJumpEvent(eventType: String): Boolean = eventType.contains("JUMP", ignoreCase = true)
+
+    /**
+     * Long jump distance for the skill level; triple jump is a little over twice as far
+     */
+    fun distance(skillLevel: DemoSkillLevel, eventType: String): Double {
+        val longJump = when (skillLevel) {
+            DemoSkillLevel.BEGINNER -> 4.80
+            DemoSkillLevel.INTERMEDIATE -> 5.90
+            DemoSkillLevel.ADVANCED -> 6.90
+            DemoSkillLevel.ELITE -> 7.80
+        } + (SessionSources.random.nextDouble() - 0.5) * 0.6
+        return if (eventType.contains("TRIPLE", ignoreCase = true)) longJump * 2.1 else longJump
+    }
+
+    /**
+     * How far over the take-off line a foul was, as read from the plasticine
+     */
+    fun foulMarginMm(): Double = 1.0 + SessionSources.random.nextDouble() * 60.0
+
+    /**
+     * Wind over the rule window: usually -1.5 to +3.5 m/s, sometimes exactly +2.0 (still
+     * legal), +2.1 (wind assisted) or no reading at all
+     */
+    fun windWindow(): EDMModule.WindReading {
+        val roll = SessionSources.random.nextDouble()
+        val speed = when {
+            roll < DROPOUT_CHANCE ->
+                return EDMModule.WindReading(success = false, error = "No wind readings received during the window (demo gauge dropout)")
+            roll < DROPOUT_CHANCE + AT_LIMIT_CHANCE -> 2.0
+            roll < DROPOUT_CHANCE + AT_LIMIT_CHANCE + JUST_OVER_CHANCE -> 2.1
+            else -> -1.5 + SessionSources.random.nextDouble() * 5.0
+        }
+        return EDMModule.WindReading(success = true, windSpeed = kotlin.math.round(speed * 10) / 10.0)
+    }
+}
+
 /**
  * Demo mode state
  */
@@ -122,6 +170,8 @@ class DemoModeManager(private val context: Context) : ViewModel() {
             createDiscusTemplate(),
             createHammerTemplate(),
             createJavelinTemplate(),
+            createLongJumpTemplate(),
+            createTripleJumpTemplate(),
             createMixedSkillTemplate()
         )
         
@@ -203,9 +253,9 @@ class DemoModeManager(private val context: Context) : ViewModel() {
                 DemoAthlete(
                     bib = "30$i",
                     name = "Athlete $i",
-                    club = "Club ${('A'..'E').random()}",
-                    skillLevel = DemoSkillLevel.values().random(),
-                    consistency = 0.6f + Random.nextFloat() * 0.3f
+                    club = "Club ${('A'..'E').random(SessionSources.random)}",
+                    skillLevel = DemoSkillLevel.values().random(SessionSources.random),
+                    consistency = 0.6f + SessionSources.random.nextFloat() * 0.3f
                 )
             },
             settings = CompetitionSettings(
@@ -232,12 +282,12 @@ class DemoModeManager(private val context: Context) : ViewModel() {
                 DemoAthlete(
                     bib = "${400 + i}",
                     name = "Thrower $i",
-                    club = "Team ${('X'..'Z').random()}",
+                    club = "Team ${('X'..'Z').random(SessionSources.random)}",
                     skillLevel = if (i <= 3) DemoSkillLevel.ELITE 
                                 else if (i <= 6) DemoSkillLevel.ADVANCED
                                 else if (i <= 9) DemoSkillLevel.INTERMEDIATE
                                 else DemoSkillLevel.BEGINNER,
-                    consistency = 0.65f + Random.nextFloat() * 0.25f
+                    consistency = 0.65f + SessionSources.random.nextFloat() * 0.25f
                 )
             },
             settings = CompetitionSettings(
@@ -251,6 +301,64 @@ class DemoModeManager(private val context: Context) : ViewModel() {
         )
     }
     
+    /**
+     * Create long jump demo template
+     */
+    private fun createLongJumpTemplate(): DemoCompetitionTemplate {
+        return DemoCompetitionTemplate(
+            id = "demo_long_jump",
+            name = "Long Jump Competition",
+            eventType = DemoJumps.LONG_JUMP,
+            description = "8 athletes, 6 rounds with cut after round 3, wind gauge dropouts",
+            athletes = listOf(
+                DemoAthlete("201", "Marcus Johnson", "Jump Squad", DemoSkillLevel.ELITE, 0.9f),
+                DemoAthlete("202", "Daniel Reed", "Athletics Club", DemoSkillLevel.ADVANCED, 0.85f),
+                DemoAthlete("203", "Leo Grant", "City Runners", DemoSkillLevel.ADVANCED, 0.8f),
+                DemoAthlete("204", "Owen Price", "University TC", DemoSkillLevel.INTERMEDIATE, 0.75f),
+                DemoAthlete("205", "Jack Hughes", "Local Club", DemoSkillLevel.INTERMEDIATE, 0.7f),
+                DemoAthlete("206", "Emily Davis", "Jump Stars", DemoSkillLevel.INTERMEDIATE, 0.75f),
+                DemoAthlete("207", "Kevin Brown", "Distance Jumpers", DemoSkillLevel.BEGINNER, 0.6f),
+                DemoAthlete("208", "Noah Ward", "School Team", DemoSkillLevel.BEGINNER, 0.65f)
+            ),
+            settings = CompetitionSettings(
+                numberOfRounds = 6,
+                athleteCutoff = 3,
+                cutoffEnabled = true,
+                reorderAfterRound3 = true,
+                eventType = DemoJumps.LONG_JUMP,
+                allowAllAthletes = false
+            )
+        )
+    }
+
+    /**
+     * Create triple jump demo template
+     */
+    private fun createTripleJumpTemplate(): DemoCompetitionTemplate {
+        return DemoCompetitionTemplate(
+            id = "demo_triple_jump",
+            name = "Triple Jump Competition",
+            eventType = DemoJumps.TRIPLE_JUMP,
+            description = "6 athletes, 3 rounds, wind legality edge cases",
+            athletes = listOf(
+                DemoAthlete("301", "Sarah Mitchell", "Jump Squad", DemoSkillLevel.ELITE, 0.9f),
+                DemoAthlete("302", "Grace Turner", "Athletics Club", DemoSkillLevel.ADVANCED, 0.8f),
+                DemoAthlete("303", "Chloe Bennett", "Track Team", DemoSkillLevel.INTERMEDIATE, 0.75f),
+                DemoAthlete("304", "Amy Foster", "Local Club", DemoSkillLevel.INTERMEDIATE, 0.7f),
+                DemoAthlete("305", "Ella Morgan", "Youth Team", DemoSkillLevel.BEGINNER, 0.6f),
+                DemoAthlete("306", "Ruby Hayes", "School Team", DemoSkillLevel.BEGINNER, 0.65f)
+            ),
+            settings = CompetitionSettings(
+                numberOfRounds = 3,
+                athleteCutoff = -1,
+                cutoffEnabled = false,
+                reorderAfterRound3 = false,
+                eventType = DemoJumps.TRIPLE_JUMP,
+                allowAllAthletes = true
+            )
+        )
+    }
+
     /**
      * Create mixed skill level template for training
      */
@@ -399,9 +507,14 @@ class DemoModeManager(private val context: Context) : ViewModel() {
     /**
      * Generate realistic demo measurement for athlete
      */
-    fun generateDemoMeasurement(athlete: DemoAthlete): Double? {
+    fun generateDemoMeasurement(athlete: DemoAthlete, eventType: String = _demoState.value.currentTemplate?.eventType ?: "SHOT"): Double? {
         val skillLevel = athlete.skillLevel
         val consistency = athlete.consistency
+
+        if (DemoJumps.isJumpEvent(eventType)) {
+            // Take-off fouls are more common than throwing fouls
+            return if (SessionSources.random.nextDouble() < 0.25) null else DemoJumps.distance(skillLevel, eventType)
+        }
         
         // Base performance varies by skill level
         val baseDistance = skillLevel.baseDistance
@@ -411,7 +524,7 @@ class DemoModeManager(private val context: Context) : ViewModel() {
         val actualVariation = variation * (1.0 - consistency)
         
         // Generate distance with some randomness
-        val distance = baseDistance + (Random.nextDouble() - 0.5) * 2 * actualVariation
+        val distance = baseDistance + (SessionSources.random.nextDouble() - 0.5) * 2 * actualVariation
         
         // 15% chance of foul throw (reduced for elite athletes)
         val foulChance = when (skillLevel) {
@@ -421,7 +534,7 @@ class DemoModeManager(private val context: Context) : ViewModel() {
             DemoSkillLevel.BEGINNER -> 0.20
         }
         
-        return if (Random.nextDouble() < foulChance) null else maxOf(0.0, distance)
+        return if (SessionSources.random.nextDouble() < foulChance) null else maxOf(0.0, distance)
     }
     
     /**
@@ -429,9 +542,14 @@ class DemoModeManager(private val context: Context) : ViewModel() {
      */
     fun generateDemoWindReading(): Double {
         // Wind between -2.0 and +2.0 m/s
-        return -2.0 + Random.nextDouble() * 4.0
+        return -2.0 + SessionSources.random.nextDouble() * 4.0
     }
     
+    /**
+     * Generate demo wind for a horizontal jump; null when the simulated gauge drops out
+     */
+    fun generateDemoJumpWind(): Double? = DemoJumps.windWindow().windSpeed
+
     /**
      * Get demo step description
      */
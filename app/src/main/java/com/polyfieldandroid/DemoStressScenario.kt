@@ -0,0 +1,184 @@
+package com.polyfieldandroid
+
+import android.util.Log
+import kotlinx.coroutines.cancelAndJoin
+import kotlinx.coroutines.coroutineScope
+import kotlinx.coroutines.currentCoroutineContext
+import kotlinx.coroutines.delay
+import kotlinx.coroutines.isActive
+import kotlinx.coroutines.launch
+import java.util.Locale
+
+/**
+ * Pace of a stress run. The defaults are a busy jumps pit: a gauge streaming at 10 Hz, the
+ * wind on the board every second and an attempt measured every few seconds, several times
+ * faster than any real event so a slowdown shows as a missed budget rather than a feeling.
+ */
+data class StressScenarioConfig(
+    val attempts: Int = 30,
+    val attemptIntervalMs: Long = 4000,   // Start of one measurement to the next
+    val windSampleIntervalMs: Long = 100,
+    val windBoardIntervalMs: Long = 1000,
+    val measurementBudgetMs: Long = 2500, // A double read on the simulator takes about 1 s
+    val windBudgetMs: Long = 20,
+    val scoreboardBudgetMs: Long = 250
+)
+
+/**
+ * Latencies of one kind of operation over a run, and how many failed
+ */
+class StressTimings(val name: String, private val budgetMs: Long) {
+
+    private val latencies = mutableListOf<Long>()
+    private var failures = 0
+    private var lastError: String? = null
+
+    fun record(latencyMs: Long, error: String? = null) = synchronized(this) {
+        latencies += latencyMs
+        if (error != null) {
+            failures++
+            lastError = error
+        }
+    }
+
+    /**
+     * Within budget when the 95th percentile is; a single slow outlier is not a regression
+     */
+    fun withinBudget(): Boolean = synchronized(this) { percentile(95) <= budgetMs }
+
+    fun toMap(): Map<String, Any> = synchronized(this) {
+        listOfNotNull(lastError?.let { "lastError" to it }).toMap() + mapOf(
+            "count" to latencies.size,
+            "failures" to failures,
+            "p50Ms" to percentile(50),
+            "p95Ms" to percentile(95),
+            "maxMs" to (latencies.maxOrNull() ?: 0L),
+            "budgetMs" to budgetMs,
+            "withinBudget" to (percentile(95) <= budgetMs)
+        )
+    }
+
+    private fun percentile(p: Int): Long {
+        if (latencies.isEmpty()) return 0L
+        val sorted = latencies.sorted()
+        return sorted[((sorted.size - 1) * p / 100.0).toInt()]
+    }
+}
+
+/**
+ * Demo Stress Scenario
+ * Wind streaming, scoreboard output and back-to-back measurements all at once, as in a
+ * jumps final with the board live. Measurements go through the USB simulator, so the
+ * driver, the EDM read lock and the reading pipeline are the real ones; wind readings are
+ * fed to a wind listener buffer and averaged over the rule window for each attempt, as a
+ * connected gauge would be. Results and wind go to the scoreboard when one is connected and
+ * are otherwise only encoded, so the marshalling still runs. Each operation is timed against
+ * a budget so a regression in locking or marshalling is caught on the bench.
+ */
+class DemoStressScenario(
+    private val edmModule: EDMModule,
+    private val windListener: WindGaugeListener,
+    private val deviceType: String = "edm"
+) {
+
+    companion object {
+        private const val TAG = "DemoStressScenario"
+        private const val RULE_WINDOW_SECONDS = 5
+    }
+
+    private val encoder = ScoreboardProtocol()
+
+    @Volatile var isRunning = false
+        private set
+    @Volatile private var stopRequested = false
+
+    /**
+     * Run the scenario to the end, or until stopped after the current attempt, and report
+     * the timings
+     */
+    suspend fun run(config: StressScenarioConfig): Map<String, Any> = coroutineScope {
+        isRunning = true
+        stopRequested = false
+        val measurement = StressTimings("measurement", config.measurementBudgetMs)
+        val wind = StressTimings("wind", config.windBudgetMs)
+        val scoreboard = StressTimings("scoreboard", config.scoreboardBudgetMs)
+        val startedAt = SessionSources.now()
+        var completed = 0
+
+        val windStream = launch { streamWind(config, wind) }
+        val windBoard = launch { showWind(config, scoreboard) }
+
+        try {
+            for (attempt in 0 until config.attempts) {
+                if (stopRequested) break
+                val attemptStart = SessionSources.now()
+
+                val reading = edmModule.getReliableEDMReading(deviceType)
+                measurement.record(SessionSources.now() - attemptStart, reading.error.takeUnless { reading.success })
+
+                val windStart = SessionSources.now()
+                val windSpeed = windListener.averageOver(RULE_WINDOW_SECONDS, SessionSources.now())
+                wind.record(SessionSources.now() - windStart, if (windSpeed == null) "No wind in the rule window" else null)
+
+                val distance = reading.distance?.let { String.format(Locale.US, "%.2f", it) } ?: "FOUL"
+                display(createResultDisplay(distance, bib = "${100 + attempt % 12}", attempt = attempt / 12 + 1), scoreboard)
+                completed++
+
+                delay((config.attemptIntervalMs - (SessionSources.now() - attemptStart)).coerceAtLeast(0))
+            }
+        } finally {
+            windStream.cancelAndJoin()
+            windBoard.cancelAndJoin()
+            isRunning = false
+        }
+
+        val timings = listOf(measurement, wind, scoreboard)
+        val passed = timings.all { it.withinBudget() }
+        Log.d(TAG, "Stress run: $completed attempts, ${if (passed) "within" else "over"} budget")
+        mapOf(
+            "success" to true,
+            "attempts" to completed,
+            "stopped" to stopRequested,
+            "durationMs" to SessionSources.now() - startedAt,
+            "withinBudget" to passed,
+            "scoreboardConnected" to edmModule.isScoreboardConnected()
+        ) + timings.associate { it.name to it.toMap() }
+    }
+
+    fun stop() {
+        stopRequested = true
+    }
+
+    /**
+     * A gauge streaming gusty readings around a light tailwind
+     */
+    private suspend fun streamWind(config: StressScenarioConfig, timings: StressTimings) {
+        var speed = 1.0
+        while (currentCoroutineContext().isActive) {
+            speed = (speed + (SessionSources.random.nextDouble() - 0.5) * 0.4).coerceIn(-2.0, 4.0)
+            val start = SessionSources.now()
+            windListener.submitReading(start, speed, 180)
+            timings.record(SessionSources.now() - start)
+            delay(config.windSampleIntervalMs)
+        }
+    }
+
+    private suspend fun showWind(config: StressScenarioConfig, timings: StressTimings) {
+        while (currentCoroutineContext().isActive) {
+            delay(config.windBoardIntervalMs)
+            val speed = windListener.averageOver(1, SessionSources.now()) ?: continue
+            display(createTextDisplay(String.format(Locale.US, "Wind %+.1f", speed), line = 2), timings)
+        }
+    }
+
+    private suspend fun display(command: DeviceCommand, timings: StressTimings) {
+        val start = SessionSources.now()
+        val error = if (edmModule.isScoreboardConnected()) {
+            edmModule.sendScoreboardCommand(command).let { if (it.success) null else it.error ?: "Scoreboard did not accept the message" }
+        } else {
+            encoder.encodeCommand(command)
+            null
+        }
+        timings.record(SessionSources.now() - start, error)
+    }
+}
@@ -0,0 +1,183 @@
+package com.polyfieldandroid
+
+import android.util.Log
+import kotlinx.coroutines.CoroutineScope
+import kotlinx.coroutines.Dispatchers
+import kotlinx.coroutines.Job
+import kotlinx.coroutines.SupervisorJob
+import kotlinx.coroutines.delay
+import kotlinx.coroutines.isActive
+import kotlinx.coroutines.launch
+import org.json.JSONObject
+import java.util.concurrent.ConcurrentHashMap
+
+enum class DeviceHealthStatus {
+    OK,
+    DEGRADED,      // Recent exchanges failing, but not persistently
+    FAILING,       // Several exchanges in a row have failed
+    DISCONNECTED
+}
+
+/**
+ * How a device's link has been behaving since it connected
+ */
+data class DeviceHealth(
+    val deviceType: String,
+    val status: DeviceHealthStatus,
+    val connectedAt: Long?,
+    val uptimeMs: Long,
+    val lastSuccessAt: Long?,
+    val lastLatencyMs: Long?,
+    val averageLatencyMs: Double?,
+    val successCount: Int,
+    val errorCount: Int,
+    val consecutiveErrors: Int,
+    val lastError: String?,
+    val lastErrorAt: Long?,
+    val lastCheckAt: Long?
+) {
+    fun toJson(): JSONObject = JSONObject().apply {
+        put("deviceType", deviceType)
+        put("status", status.name)
+        put("connectedAt", connectedAt ?: JSONObject.NULL)
+        put("uptimeMs", uptimeMs)
+        put("lastSuccessAt", lastSuccessAt ?: JSONObject.NULL)
+        put("lastLatencyMs", lastLatencyMs ?: JSONObject.NULL)
+        put("averageLatencyMs", averageLatencyMs ?: JSONObject.NULL)
+        put("successCount", successCount)
+        put("errorCount", errorCount)
+        put("consecutiveErrors", consecutiveErrors)
+        put("lastError", lastError ?: JSONObject.NULL)
+        put("lastErrorAt", lastErrorAt ?: JSONObject.NULL)
+        put("lastCheckAt", lastCheckAt ?: JSONObject.NULL)
+    }
+}
+
+/**
+ * Device Health Monitor
+ * Counts every exchange with each device - measurements, wind reads, scoreboard updates -
+ * and checks each connected device in the background, so a link that is starting to fail
+ * shows up before it is needed. Devices that are quiet between uses (EDM, scoreboard) get
+ * their link checked, and a no-op command where their protocol has one.
+ */
+class DeviceHealthMonitor(
+    private val connectedDevices: () -> List<String>,
+    private val check: suspend (String) -> Unit
+) {
+
+    companion object {
+        private const val TAG = "DeviceHealth"
+        private const val CHECK_INTERVAL_MS = 15000L
+        private const val FAILING_AFTER_ERRORS = 3
+
+        // Weight of the newest sample in the running latency average
+        private const val LATENCY_SMOOTHING = 0.2
+    }
+
+    private class Stats {
+        var connectedAt: Long? = null
+        var lastSuccessAt: Long? = null
+        var lastLatencyMs: Long? = null
+        var averageLatencyMs: Double? = null
+        var successCount = 0
+        var errorCount = 0
+        var consecutiveErrors = 0
+        var lastError: String? = null
+        var lastErrorAt: Long? = null
+        var lastCheckAt: Long? = null
+    }
+
+    private val stats = ConcurrentHashMap<String, Stats>()
+    private val scope = CoroutineScope(SupervisorJob() + Dispatchers.IO)
+    private var checkJob: Job? = null
+
+    fun start() {
+        if (checkJob?.isActive == true) return
+        checkJob = scope.launch {
+            while (isActive) {
+                delay(CHECK_INTERVAL_MS)
+                connectedDevices().forEach { deviceType ->
+                    try {
+                        check(deviceType)
+                    } catch (e: Exception) {
+                        recordError(deviceType, e.message ?: "Health check failed")
+                    }
+                    stats(deviceType).let { synchronized(it) { it.lastCheckAt = SessionSources.now() } }
+                }
+            }
+        }
+    }
+
+    /**
+     * Restart the uptime clock. Counts carry over a reconnect, so a link that keeps
+     * dropping still shows its history.
+     */
+    fun onConnected(deviceType: String) {
+        val entry = stats(deviceType)
+        synchronized(entry) {
+            entry.connectedAt = SessionSources.now()
+            entry.consecutiveErrors = 0
+        }
+    }
+
+    fun onDisconnected(deviceType: String) {
+        stats[deviceType]?.let { synchronized(it) { it.connectedAt = null } }
+    }
+
+    fun recordSuccess(deviceType: String, latencyMs: Long? = null) {
+        val entry = stats(deviceType)
+        synchronized(entry) {
+            entry.successCount++
+            entry.consecutiveErrors = 0
+            entry.lastSuccessAt = SessionSources.now()
+            if (latencyMs != null) {
+                entry.lastLatencyMs = latencyMs
+                entry.averageLatencyMs = entry.averageLatencyMs
+                    ?.let { it + LATENCY_SMOOTHING * (latencyMs - it) }
+                    ?: latencyMs.toDouble()
+            }
+        }
+    }
+
+    fun recordError(deviceType: String, error: String) {
+        val entry = stats(deviceType)
+        synchronized(entry) {
+            entry.errorCount++
+            entry.consecutiveErrors++
+            entry.lastError = error
+            entry.lastErrorAt = SessionSources.now()
+            if (entry.consecutiveErrors == FAILING_AFTER_ERRORS) {
+                Log.w(TAG, "$deviceType failing: $FAILING_AFTER_ERRORS errors in a row, last '$error'")
+            }
+        }
+    }
+
+    fun getHealth(deviceType: String): DeviceHealth {
+        val entry = stats(deviceType)
+        return synchronized(entry) {
+            val connectedAt = entry.connectedAt
+            DeviceHealth(
+                deviceType = deviceType,
+                status = when {
+                    connectedAt == null -> DeviceHealthStatus.DISCONNECTED
+                    entry.consecutiveErrors >= FAILING_AFTER_ERRORS -> DeviceHealthStatus.FAILING
+                    entry.consecutiveErrors > 0 -> DeviceHealthStatus.DEGRADED
+                    else -> DeviceHealthStatus.OK
+                },
+                connectedAt = connectedAt,
+                uptimeMs = connectedAt?.let { SessionSources.now() - it } ?: 0L,
+                lastSuccessAt = entry.lastSuccessAt,
+                lastLatencyMs = entry.lastLatencyMs,
+                averageLatencyMs = entry.averageLatencyMs,
+                successCount = entry.successCount,
+                errorCount = entry.errorCount,
+                consecutiveErrors = entry.consecutiveErrors,
+                lastError = entry.lastError,
+                lastErrorAt = entry.lastErrorAt,
+                lastCheckAt = entry.lastCheckAt
+            )
+        }
+    }
+
+    private fun stats(deviceType: String): Stats = stats.getOrPut(deviceType) { Stats() }
+}
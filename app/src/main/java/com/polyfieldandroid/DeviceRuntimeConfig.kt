@@ -0,0 +1,123 @@
+package com.polyfieldandroid
+
+import android.content.Context
+import android.util.Log
+import com.google.gson.Gson
+import com.google.gson.reflect.TypeToken
+
+/**
+ * EDM read policy
+ */
+enum class ReadPolicy {
+    CALLER, // Single or double read as requested by the caller
+    SINGLE, // Always a single read
+    DOUBLE  // Always a double read with tolerance check
+}
+
+/**
+ * Device settings that can be tuned mid-competition without reconnecting.
+ * Instances are immutable; each operation takes one snapshot when it starts,
+ * so a change applies atomically from the next operation onwards.
+ */
+data class DeviceRuntimeConfig(
+    val edmReadTimeoutMs: Long = 10000, // Until an instrument's response times are known, unless fixedReadTimeout
+    val fixedReadTimeout: Boolean = false, // Always wait edmReadTimeoutMs rather than adapting to each instrument
+    val writeTimeoutMs: Int = 5000,
+    val readPolicy: ReadPolicy = ReadPolicy.CALLER,
+    val doubleReadDelayMs: Long = 100,
+    val doubleReadToleranceMm: Double = 3.0,
+    val windWindowSeconds: Int = 5,
+    val networkIdleTimeoutSeconds: Int = 0, // Close idle network sockets after this long; 0 keeps them open
+    val venueAltitudeM: Double = 0.0, // Height above sea level; gives standard pressure when none is entered
+    val atmosphericCorrection: Boolean = false, // Apply ppm correction to slope distances (off if the EDM corrects internally)
+    val verticalAngleConventions: Map<String, VerticalAngleConvention> = emptyMap() // Per EDM model, overriding the driver's default
+) {
+    /**
+     * Resolve whether a read should be a double read under this policy
+     */
+    fun useDoubleRead(callerRequestedDouble: Boolean): Boolean = when (readPolicy) {
+        ReadPolicy.CALLER -> callerRequestedDouble
+        ReadPolicy.SINGLE -> false
+        ReadPolicy.DOUBLE -> true
+    }
+
+    /**
+     * Vertical angle convention for an instrument: the configured override, else its driver's
+     */
+    fun verticalAngleConvention(device: EDMDeviceSpec): VerticalAngleConvention =
+        verticalAngleConventions[device.model] ?: device.verticalAngleConvention
+}
+
+/**
+ * Persists the runtime device configuration between sessions
+ */
+class DeviceRuntimeConfigStore(context: Context) {
+
+    companion object {
+        private const val TAG = "DeviceRuntimeConfig"
+        private const val PREFS_NAME = "polyfield_device_runtime"
+        private const val PREF_CONFIG = "runtime_config"
+        private const val PREF_WIND_GAUGES = "wind_gauge_configs"
+    }
+
+    private val preferences = context.getSharedPreferences(PREFS_NAME, Context.MODE_PRIVATE)
+    private val gson = Gson()
+
+    fun load(): DeviceRuntimeConfig {
+        return try {
+            val json = preferences.getString(PREF_CONFIG, null) ?: return DeviceRuntimeConfig()
+            gson.fromJson(json, DeviceRuntimeConfig::class.java)?.let { validate(it) } ?: DeviceRuntimeConfig()
+        } catch (e: Exception) {
+            Log.e(TAG, "Error loading runtime config: ${e.message}")
+            DeviceRuntimeConfig()
+        }
+    }
+
+    fun save(config: DeviceRuntimeConfig) {
+        preferences.edit()
+            .putString(PREF_CONFIG, gson.toJson(config))
+            .apply()
+    }
+
+    /**
+     * Listening configuration per wind gauge protocol type (e.g. GILL_WINDMASTER)
+     */
+    fun loadWindGaugeConfigs(): Map<String, WindGaugeConfig> {
+        return try {
+            val json = preferences.getString(PREF_WIND_GAUGES, null) ?: return emptyMap()
+            val mapType = object : TypeToken<Map<String, WindGaugeConfig>>() {}.type
+            gson.fromJson<Map<String, WindGaugeConfig>>(json, mapType) ?: emptyMap()
+        } catch (e: Exception) {
+            Log.e(TAG, "Error loading wind gauge configs: ${e.message}")
+            emptyMap()
+        }
+    }
+
+    fun saveWindGaugeConfig(gaugeType: String, config: WindGaugeConfig) {
+        val configs = loadWindGaugeConfigs() + (gaugeType to config)
+        preferences.edit()
+            .putString(PREF_WIND_GAUGES, gson.toJson(configs))
+            .apply()
+    }
+
+    /**
+     * Clamp values into workable ranges; fields missing from older saved JSON are defaulted
+     */
+    fun validate(config: DeviceRuntimeConfig): DeviceRuntimeConfig {
+        val defaults = DeviceRuntimeConfig()
+        val verticalAngleConventions = GsonFields.orNull<Map<String, VerticalAngleConvention?>>(config.verticalAngleConventions)
+        return config.copy(
+            edmReadTimeoutMs = config.edmReadTimeoutMs.takeIf { it > 0 }?.coerceIn(1000, 60000) ?: defaults.edmReadTimeoutMs,
+            writeTimeoutMs = config.writeTimeoutMs.takeIf { it > 0 }?.coerceIn(500, 30000) ?: defaults.writeTimeoutMs,
+            readPolicy = GsonFields.orDefault(config.readPolicy, defaults.readPolicy),
+            doubleReadDelayMs = config.doubleReadDelayMs.coerceIn(0, 5000),
+            doubleReadToleranceMm = config.doubleReadToleranceMm.takeIf { it > 0 } ?: defaults.doubleReadToleranceMm,
+            windWindowSeconds = config.windWindowSeconds.takeIf { it > 0 }?.coerceAtMost(60) ?: defaults.windWindowSeconds,
+            networkIdleTimeoutSeconds = config.networkIdleTimeoutSeconds.coerceIn(0, 3600),
+            venueAltitudeM = config.venueAltitudeM.takeIf { !it.isNaN() }?.coerceIn(-500.0, 5000.0) ?: defaults.venueAltitudeM,
+            verticalAngleConventions = verticalAngleConventions.orEmpty()
+                .mapNotNull { (model, convention) -> convention?.let { model to it } }
+                .toMap()
+        )
+    }
+}
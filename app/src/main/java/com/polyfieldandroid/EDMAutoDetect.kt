@@ -0,0 +1,133 @@
+package com.polyfieldandroid
+
+import android.hardware.usb.UsbDevice
+import android.util.Log
+
+/**
+ * EDM found on a port: the driver that understood it and the line settings it answered at
+ */
+data class EDMDetection(
+    val driverKey: String,
+    val deviceSpec: EDMDeviceSpec,
+    val config: SerialPortConfig,
+    val response: String
+)
+
+data class EDMDetectionResult(
+    val detection: EDMDetection? = null,
+    val settingsTried: Int = 0,
+    val error: String? = null,
+    val errorCode: SerialErrorCode? = null
+)
+
+/**
+ * EDM Auto Detector
+ * Finds the protocol and line settings of an unknown station by sending each registered
+ * driver's measurement command and keeping the first that parses to a valid reading. Each
+ * driver's own settings are tried first, then the common baud rates at every frame format
+ * the drivers use. Every probe triggers a measurement, so the instrument must be aimed at
+ * the prism; a full sweep of a silent port takes a few minutes.
+ */
+class EDMAutoDetector(private val serial: SerialCommunicationModule) {
+
+    companion object {
+        private const val TAG = "EDMAutoDetect"
+        private const val PROBE_TIMEOUT_MS = 3000L
+
+        // Most likely first; older Geodimeter and Sokkia units run at 1200-2400
+        val COMMON_BAUD_RATES = listOf(9600, 19200, 4800, 2400, 1200, 38400, 115200)
+
+        // Faults that no other setting will get past
+        private val FATAL_OPEN_ERRORS = setOf(
+            SerialErrorCode.PERMISSION_DENIED,
+            SerialErrorCode.DEVICE_UNPLUGGED,
+            SerialErrorCode.PORT_BUSY,
+            SerialErrorCode.NO_DRIVER
+        )
+    }
+
+    suspend fun detect(usbDevice: UsbDevice, probeTimeoutMs: Long = PROBE_TIMEOUT_MS): EDMDetectionResult {
+        val drivers = EDMDeviceRegistry.getDriverKeys().mapNotNull { key ->
+            EDMDeviceRegistry.findDeviceByKey(key)?.let { key to it }
+        }
+        var tried = 0
+
+        for ((config, candidates) in probePlan(drivers.map { it.second })) {
+            tried++
+            Log.d(TAG, "Trying ${config.baudRate} ${config.dataBits}${config.parity.first()}${config.stopBits} on ${usbDevice.deviceName}")
+
+            val opened = serial.openSerialPort(
+                usbDevice,
+                config.baudRate,
+                config.dataBits,
+                config.usbStopBits(),
+                config.usbParity()
+            )
+            val port = opened.port
+            if (port == null) {
+                if (opened.errorCode in FATAL_OPEN_ERRORS) {
+                    return EDMDetectionResult(settingsTried = tried, error = opened.error, errorCode = opened.errorCode)
+                }
+                continue
+            }
+
+            try {
+                for (spec in candidates) {
+                    val key = EDMDeviceRegistry.keyFor(spec)
+                    val translator = EDMDeviceRegistry.createTranslator(spec) ?: continue
+                    val response = serial.sendEDMCommandBytes(
+                        port,
+                        translator.getMeasurementCommand(),
+                        timeoutMs = probeTimeoutMs,
+                        hasAnswer = translator::hasAnswerLine
+                    )
+                    val data = response.data
+
+                    if (response.success && data != null) {
+                        val parsed = translator.parseReply(data)
+                        if (parsed.isValid) {
+                            Log.d(TAG, "Detected $key at ${config.baudRate} baud")
+                            return EDMDetectionResult(EDMDetection(key, spec, config, data), settingsTried = tried)
+                        }
+                        Log.d(TAG, "$key did not understand '$data'")
+                    }
+                    when (response.errorCode) {
+                        // Wrong line settings; no driver will read this
+                        SerialErrorCode.FRAMING_ERROR -> break
+                        SerialErrorCode.DEVICE_UNPLUGGED, SerialErrorCode.PERMISSION_DENIED ->
+                            return EDMDetectionResult(settingsTried = tried, error = response.error, errorCode = response.errorCode)
+                        else -> {}
+                    }
+                }
+            } finally {
+                serial.closeSerialPort(port)
+            }
+        }
+
+        Log.w(TAG, "No EDM detected on ${usbDevice.deviceName} after $tried settings")
+        return EDMDetectionResult(
+            settingsTried = tried,
+            error = "No EDM recognised. Check the station is on, aimed at the prism and cabled to this port"
+        )
+    }
+
+    /**
+     * Line settings in the order to try them, each with the drivers to probe at it
+     */
+    private fun probePlan(specs: List<EDMDeviceSpec>): List<Pair<SerialPortConfig, List<EDMDeviceSpec>>> {
+        val plan = linkedMapOf<SerialPortConfig, MutableList<EDMDeviceSpec>>()
+        specs.forEach { spec ->
+            plan.getOrPut(SerialPortConfig.fromDeviceSpec(spec)) { mutableListOf() }.add(spec)
+        }
+
+        val frames = specs.map { Triple(it.dataBits, it.parity, it.stopBits) }.distinct()
+        COMMON_BAUD_RATES.forEach { baudRate ->
+            frames.forEach { (dataBits, parity, stopBits) ->
+                val config = SerialPortConfig(baudRate = baudRate, dataBits = dataBits, stopBits = stopBits, parity = parity)
+                val candidates = plan.getOrPut(config) { mutableListOf() }
+                specs.filterNot { it in candidates }.forEach { candidates.add(it) }
+            }
+        }
+        return plan.map { (config, candidates) -> config to candidates.toList() }
+    }
+}
@@ -75,7 +75,7 @@ class EDMCalculations {
      */
     data class EDMCalibrationData(
         val deviceId: String,
-        val timestamp: Date = Date(),
+        val timestamp: Date = Date(SessionSources.now()),
         val selectedCircleType: String,
         val targetRadius: Double,
         val stationCoordinates: EDMPoint,
@@ -227,19 +227,9 @@ class EDMCalculations {
      * hd = sd * cos(90° - va) where va is measured from vertically upwards
      */
     fun calculateStationCoordinates(reading: AveragedEDMReading): EDMPoint {
-        val sdMeters = reading.slopeDistanceMm / 1000.0
-        val vazRad = Math.toRadians(reading.vazDecimal)
-        val harRad = Math.toRadians(reading.harDecimal)
-        
-        // CORRECTED: va is measured from vertically upwards, so hd = sd * cos(90° - va)
-        // This simplifies to: hd = sd * sin(va) only if va is from horizontal
-        // But since va is from vertical: hd = sd * cos(90° - va) = sd * sin(va)
-        // Actually, let's be explicit about the 90° conversion
-        val horizontalDistance = sdMeters * cos(Math.toRadians(90.0) - vazRad)
-        
-        val stationX = -horizontalDistance * cos(harRad)
-        val stationY = -horizontalDistance * sin(harRad)
-        
+        val (stationX, stationY) = CoordinateTransforms.stationFromCentreReading(
+            reading.slopeDistanceMm / 1000.0, reading.vazDecimal, reading.harDecimal
+        )
         return EDMPoint(stationX, stationY)
     }
     
@@ -254,17 +244,10 @@ class EDMCalculations {
         circleType: String,
         targetRadius: Double
     ): EdgeVerificationResult {
-        val sdMeters = reading.slopeDistanceMm / 1000.0
-        val vazRad = Math.toRadians(reading.vazDecimal)
-        val harRad = Math.toRadians(reading.harDecimal)
-        
-        // CORRECTED: Using proper formula hd = sd * cos(90° - va)
-        val horizontalDistance = sdMeters * cos(Math.toRadians(90.0) - vazRad)
-        val edgeX = horizontalDistance * cos(harRad)
-        val edgeY = horizontalDistance * sin(harRad)
-        
-        val absoluteEdgeX = stationCoordinates.x + edgeX
-        val absoluteEdgeY = stationCoordinates.y + edgeY
+        val (absoluteEdgeX, absoluteEdgeY) = CoordinateTransforms.stationToCircle(
+            CoordinateTransforms.readingToStation(reading.slopeDistanceMm / 1000.0, reading.vazDecimal, reading.harDecimal),
+            Pair(stationCoordinates.x, stationCoordinates.y)
+        )
         
         val measuredRadius = sqrt(absoluteEdgeX.pow(2) + absoluteEdgeY.pow(2))
         val diffMm = (measuredRadius - targetRadius) * 1000.0
@@ -290,17 +273,10 @@ class EDMCalculations {
         stationCoordinates: EDMPoint,
         circleRadius: Double
     ): Double {
-        val sdMeters = reading.slopeDistanceMm / 1000.0
-        val vazRad = Math.toRadians(reading.vazDecimal)
-        val harRad = Math.toRadians(reading.harDecimal)
-        
-        // CORRECTED: Using proper formula hd = sd * cos(90° - va)
-        val horizontalDistance = sdMeters * cos(Math.toRadians(90.0) - vazRad)
-        val throwX = horizontalDistance * cos(harRad)
-        val throwY = horizontalDistance * sin(harRad)
-        
-        val absoluteThrowX = stationCoordinates.x + throwX
-        val absoluteThrowY = stationCoordinates.y + throwY
+        val (absoluteThrowX, absoluteThrowY) = CoordinateTransforms.stationToCircle(
+            CoordinateTransforms.readingToStation(reading.slopeDistanceMm / 1000.0, reading.vazDecimal, reading.harDecimal),
+            Pair(stationCoordinates.x, stationCoordinates.y)
+        )
         
         val distanceFromCentre = sqrt(absoluteThrowX.pow(2) + absoluteThrowY.pow(2))
         return distanceFromCentre - circleRadius
@@ -116,7 +116,7 @@ class EDMCalibrationManager(private val context: Context) {
             val updatedCalibration = calibrationData.copy(
                 stationCoordinates = stationCoordinates,
                 isCentreSet = true,
-                timestamp = Date(),
+                timestamp = Date(SessionSources.now()),
                 edgeVerificationResult = null // Reset edge verification
             )
             
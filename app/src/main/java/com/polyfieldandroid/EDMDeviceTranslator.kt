@@ -8,7 +8,8 @@ import android.hardware.usb.UsbManager
 import android.util.Log
 import kotlinx.coroutines.Dispatchers
 import kotlinx.coroutines.withContext
-import kotlinx.coroutines.withTimeout
+import kotlinx.coroutines.ensureActive
+import org.json.JSONObject
 import java.nio.charset.Charset
 
 /**
@@ -27,7 +28,78 @@ enum class EDMManufacturer {
     MATO, 
     LEICA, 
     TOPCON, 
-    TRIMBLE 
+    TRIMBLE,
+    SOKKIA,
+    NIKON
+}
+
+/**
+ * Where an instrument measures its vertical angle from
+ */
+enum class VerticalAngleConvention {
+    ZENITH,         // 0° straight up, 90° horizontal (the reduction's native convention)
+    HORIZON,        // 0° horizontal, elevation upwards, 0-360° so depression reads e.g. 359°
+    HORIZON_SIGNED; // 0° horizontal, ±90° elevation/depression
+
+    /**
+     * Convert an angle in this convention to zenith-referenced degrees
+     */
+    fun toZenith(angleDeg: Double): Double = when (this) {
+        ZENITH -> angleDeg
+        HORIZON -> 90.0 - (if (angleDeg > 180.0) angleDeg - 360.0 else angleDeg)
+        HORIZON_SIGNED -> 90.0 - angleDeg
+    }
+}
+
+/**
+ * How an instrument delimits its replies on the wire
+ */
+enum class FramingType {
+    LINE,            // ASCII terminated by CR and/or LF (or a recognised fixed record)
+    STX_ETX,         // Record between STX (0x02) and ETX (0x03)
+    LENGTH_PREFIXED  // Binary record preceded by its length
+}
+
+data class ResponseFraming(
+    val type: FramingType = FramingType.LINE,
+    val lengthBytes: Int = 2,                    // LENGTH_PREFIXED: size of the length field
+    val bigEndian: Boolean = true,               // LENGTH_PREFIXED: byte order of the length field
+    val lengthIncludesHeader: Boolean = false,   // LENGTH_PREFIXED: whether the length counts its own field
+    val trailerBytes: Int = 0                    // Checksum bytes following the record (after ETX for STX_ETX)
+) {
+    companion object {
+        private const val STX: Byte = 0x02
+        private const val ETX: Byte = 0x03
+    }
+
+    /**
+     * The first complete record in bytes received so far, without its delimiters or length
+     * field; any checksum bytes are left at the end for the driver to verify. Null while
+     * the record is incomplete, and always for LINE framing, which is matched as text.
+     */
+    fun extract(bytes: ByteArray): ByteArray? = when (type) {
+        FramingType.LINE -> null
+        FramingType.STX_ETX -> {
+            val start = bytes.indexOf(STX)
+            val end = if (start < 0) -1 else (start + 1 until bytes.size).firstOrNull { bytes[it] == ETX } ?: -1
+            if (end < 0 || bytes.size < end + 1 + trailerBytes) {
+                null
+            } else {
+                bytes.copyOfRange(start + 1, end) + bytes.copyOfRange(end + 1, end + 1 + trailerBytes)
+            }
+        }
+        FramingType.LENGTH_PREFIXED -> {
+            if (bytes.size < lengthBytes) {
+                null
+            } else {
+                val header = bytes.copyOfRange(0, lengthBytes).let { if (bigEndian) it else it.reversedArray() }
+                val length = header.fold(0L) { acc, byte -> (acc shl 8) or (byte.toLong() and 0xff) }
+                val bodyLength = (if (lengthIncludesHeader) length - lengthBytes else length).toInt()
+                val end = lengthBytes + bodyLength + trailerBytes
+                if (bodyLength < 0 || bytes.size < end) null else bytes.copyOfRange(lengthBytes, end)
+            }
+        }
+    }
 }
 
 data class EDMDeviceSpec(
@@ -39,12 +111,92 @@ data class EDMDeviceSpec(
     val stopBits: Int = 1,
     val parity: String = "NONE",
     val vendorIds: List<Int> = emptyList(),
-    val productIds: List<Int> = emptyList()
+    val productIds: List<Int> = emptyList(),
+    val verticalAngleConvention: VerticalAngleConvention = VerticalAngleConvention.ZENITH,
+    // Binary records reach parseResponse as ISO-8859-1 text, one char per byte
+    val framing: ResponseFraming = ResponseFraming()
 )
 
+/**
+ * What one line of a reply is, for instruments that send more than the measurement
+ */
+enum class ResponseLineKind {
+    MEASUREMENT, // Parses as a reading
+    STATUS,      // Acknowledgement or progress, e.g. "OK" or "MEASURING"; skipped
+    ERROR,       // Instrument error report
+    UNKNOWN      // Anything else; left for the parser to report
+}
+
+/**
+ * What the instrument measures distance to
+ */
+enum class EDMMeasurementMode {
+    PRISM,         // A reflector on the pole; the prism constant applies
+    REFLECTORLESS; // Direct reflection (DR) off the surface aimed at, e.g. a sector line or cage post
+
+    val key: String get() = name.lowercase()
+
+    companion object {
+        fun fromKey(key: String): EDMMeasurementMode? = values().find { it.key == key.trim().lowercase() }
+    }
+}
+
+/**
+ * Kind of failure an instrument reports, with what to tell the official and what to do
+ */
+enum class EDMErrorKind(val humanMessage: String, val suggestedAction: String) {
+    NO_PRISM("Prism not found", "Check aiming at the prism and remeasure"),
+    WEAK_SIGNAL("Signal too weak", "Turn the prism to face the instrument and clear the line of sight"),
+    TIMEOUT("Measurement timed out", "Remeasure; if it keeps happening check the cable and the instrument's settings"),
+    TILT("Instrument tilt out of range", "Re-level the instrument, then re-verify the edge"),
+    BATTERY_LOW("Battery low", "Change the instrument battery before the next athlete"),
+    ABORTED("Measurement aborted", "Remeasure"),
+    COMMAND_REJECTED("Instrument rejected the command", "Check the instrument is in remote mode with the right protocol selected"),
+    HARDWARE("Instrument fault", "Restart the instrument; if it persists change to the backup EDM"),
+    UNKNOWN("Instrument error", "Remeasure; if it persists restart the instrument");
+
+    companion object {
+        /**
+         * Best guess from free text such as "EDM timeout", for replies with no code table entry
+         */
+        fun fromText(text: String): EDMErrorKind {
+            val lower = text.lowercase()
+            return when {
+                "prism" in lower || "no target" in lower || "not found" in lower -> NO_PRISM
+                "signal" in lower || "weak" in lower -> WEAK_SIGNAL
+                "timeout" in lower || "timed out" in lower -> TIMEOUT
+                "tilt" in lower || "level" in lower -> TILT
+                "battery" in lower || "power" in lower -> BATTERY_LOW
+                "abort" in lower || "stop" in lower -> ABORTED
+                "invalid command" in lower || "syntax" in lower -> COMMAND_REJECTED
+                else -> UNKNOWN
+            }
+        }
+    }
+}
+
+/**
+ * A failure reported by the instrument itself: its own code, what it means and what to do
+ */
+data class EDMInstrumentError(
+    val code: String,        // As sent, e.g. "E112" or "W139"; the text itself when there is no code
+    val kind: EDMErrorKind,
+    val detail: String? = null // The driver's own description, when it has one
+) {
+    val humanMessage: String get() = kind.humanMessage
+    val suggestedAction: String get() = kind.suggestedAction
+
+    fun toMap(): Map<String, Any> = listOfNotNull(detail?.let { "detail" to it }).toMap() + mapOf(
+        "code" to code,
+        "kind" to kind.name,
+        "humanMessage" to humanMessage,
+        "suggestedAction" to suggestedAction
+    )
+}
+
 data class EDMRawReading(
     val rawResponse: String,
-    val timestamp: Long = System.currentTimeMillis(),
+    val timestamp: Long = SessionSources.now(),
     val deviceSpec: EDMDeviceSpec
 )
 
@@ -54,7 +206,10 @@ data class EDMParsedReading(
     val horizontalAngleDegrees: Double,
     val statusCode: String? = null,
     val isValid: Boolean = true,
-    val errorMessage: String? = null
+    val errorMessage: String? = null,
+    val verticalAngleRaw: String? = null,   // Angle exactly as sent by the instrument, e.g. DDDMMSS
+    val horizontalAngleRaw: String? = null,
+    val instrumentError: EDMInstrumentError? = null // Set when the instrument reported a failure
 )
 
 data class EDMTranslationResult(
@@ -72,6 +227,15 @@ abstract class EDMDeviceTranslator(val deviceSpec: EDMDeviceSpec) {
         private const val TAG = "EDMDeviceTranslator"
         private const val USB_TIMEOUT_MS = 10000
         private const val READ_BUFFER_SIZE = 1024
+        private const val USB_TRANSFER_TIMEOUT_MS = 200L
+        
+        private val LINE_BREAK = Regex("[\\r\\n]+")
+        private val STATUS_LINE = Regex(
+            """^(OK|ACK|READY|BUSY|WAIT\w*|MEAS\w*|STATUS\b.*|STAT\b.*|[*?>])$""",
+            RegexOption.IGNORE_CASE
+        )
+        // Error forms used across the supported drivers: ERR/ERROR, E<nn>, Leica's @<code>
+        private val ERROR_LINE = Regex("""^(\*?@|ERR(OR)?\b|E\d{2,3}\b|FAIL|TIMEOUT)""", RegexOption.IGNORE_CASE)
     }
     
     /**
@@ -99,6 +263,149 @@ abstract class EDMDeviceTranslator(val deviceSpec: EDMDeviceSpec) {
      */
     abstract fun interpretStatusCode(statusCode: String?): String?
     
+    /**
+     * Classify one line of a reply. Drivers whose instruments send their own status lines
+     * ahead of the measurement override this to recognise them.
+     */
+    open fun classifyLine(line: String): ResponseLineKind {
+        val trimmed = line.trim()
+        return when {
+            trimmed.isEmpty() || STATUS_LINE.matches(trimmed) -> ResponseLineKind.STATUS
+            ERROR_LINE.containsMatchIn(trimmed) -> ResponseLineKind.ERROR
+            parseResponse(trimmed).isValid -> ResponseLineKind.MEASUREMENT
+            else -> ResponseLineKind.UNKNOWN
+        }
+    }
+    
+    /**
+     * Whether a line-framed reply holds its answer yet. A reply of status lines alone
+     * is still waiting for the measurement.
+     */
+    fun hasAnswerLine(response: String): Boolean =
+        responseLines(response).any { classifyLine(it) != ResponseLineKind.STATUS }
+    
+    /**
+     * Parse a reply that may span several lines, e.g. a status line before the measurement.
+     * The last measurement line wins; failing that the first error line is parsed so its
+     * message reaches the official; failing that the remaining non-status lines are parsed
+     * together, as a single-line reply always was.
+     */
+    fun parseReply(rawResponse: String): EDMParsedReading {
+        val lines = responseLines(rawResponse)
+        if (lines.size <= 1) return parseResponse(rawResponse)
+        
+        val classified = lines.map { it to classifyLine(it) }
+        classified.lastOrNull { it.second == ResponseLineKind.MEASUREMENT }?.let { (line, _) ->
+            Log.d(TAG, "Measurement line picked from ${lines.size}-line reply: '$line'")
+            return parseResponse(line)
+        }
+        classified.firstOrNull { it.second == ResponseLineKind.ERROR }?.let { (line, _) ->
+            val parsed = parseResponse(line)
+            val error = parsed.instrumentError ?: EDMInstrumentError(line, EDMErrorKind.fromText(line))
+            return if (parsed.errorMessage != null) parsed.copy(instrumentError = error)
+                else invalidReading("Instrument error: '$line'").copy(instrumentError = error)
+        }
+        val remaining = classified.filter { it.second != ResponseLineKind.STATUS }.map { it.first }
+        if (remaining.isEmpty()) {
+            return invalidReading("No measurement in reply, only status: ${lines.joinToString(" | ")}")
+        }
+        return parseResponse(remaining.joinToString("\n"))
+    }
+    
+    private fun responseLines(response: String): List<String> =
+        response.split(LINE_BREAK).map { it.trim() }.filter { it.isNotEmpty() }
+    
+    /**
+     * Command to put the instrument into standby between flights, keeping its setup.
+     * Null if the device has no remote standby.
+     */
+    open fun getStandbyCommand(): ByteArray? = null
+    
+    /**
+     * Command to wake the instrument from standby. Null if the device has no remote wake.
+     */
+    open fun getWakeCommand(): ByteArray? = null
+    
+    /**
+     * Time the instrument needs after waking before it will measure
+     */
+    open val wakeSettleMs: Long = 0
+    
+    /**
+     * Command to switch the instrument between prism and reflectorless measurement.
+     * Null if the device cannot be switched remotely.
+     */
+    open fun getMeasurementModeCommand(mode: EDMMeasurementMode): ByteArray? = null
+    
+    /**
+     * Command to read the angles without firing the distance meter. Null if the device has
+     * none, in which case a full reading is taken and its distance ignored.
+     */
+    open fun getAngleCommand(): ByteArray? = null
+    
+    /**
+     * Horizontal angle in the reply to getAngleCommand, or null if there is none
+     */
+    open fun parseAngleReply(rawResponse: String): Double? =
+        parseReply(rawResponse).takeIf { it.isValid }?.horizontalAngleDegrees
+    
+    /**
+     * The driver's error codes and what kind of failure each is
+     */
+    protected open val errorCodes: Map<String, EDMErrorKind> = emptyMap()
+    
+    /**
+     * Reading for an error code the instrument sent, translated through errorCodes
+     */
+    protected fun instrumentErrorReading(code: String): EDMParsedReading {
+        val kind = errorCodes[code] ?: EDMErrorKind.UNKNOWN
+        val detail = interpretStatusCode(code)
+        val error = EDMInstrumentError(code, kind, detail)
+        return invalidReading("${detail ?: kind.humanMessage} ($code). ${kind.suggestedAction}")
+            .copy(instrumentError = error)
+    }
+    
+    /**
+     * Reading marked invalid, for parse failures
+     */
+    protected fun invalidReading(message: String): EDMParsedReading {
+        return EDMParsedReading(
+            slopeDistanceMm = 0.0,
+            verticalAngleDegrees = 0.0,
+            horizontalAngleDegrees = 0.0,
+            isValid = false,
+            errorMessage = message
+        )
+    }
+    
+    /**
+     * JSON in the shape of Go Mobile's AveragedEDMReading
+     */
+    protected fun standardGoMobileFormat(parsedReading: EDMParsedReading): String {
+        return JSONObject().apply {
+            put("slopeDistanceMm", parsedReading.slopeDistanceMm)
+            put("vAzDecimal", parsedReading.verticalAngleDegrees)
+            put("harDecimal", parsedReading.horizontalAngleDegrees)
+        }.toString()
+    }
+    
+    /**
+     * Parse a DDDMMSS angle (leading zeros optional) to decimal degrees
+     */
+    protected fun parseDDDMMSS(angleStr: String): Double {
+        val digits = angleStr.padStart(7, '0')
+        if (digits.length != 7 || !digits.all { it.isDigit() }) {
+            throw IllegalArgumentException("Invalid DDDMMSS angle '$angleStr'")
+        }
+        val degrees = digits.substring(0, 3).toInt()
+        val minutes = digits.substring(3, 5).toInt()
+        val seconds = digits.substring(5, 7).toInt()
+        if (minutes >= 60 || seconds >= 60 || degrees > 360) {
+            throw IllegalArgumentException("Angle out of range in '$angleStr'")
+        }
+        return degrees + (minutes / 60.0) + (seconds / 3600.0)
+    }
+    
     /**
      * Send measurement command to USB device and get response
      */
@@ -157,15 +464,13 @@ abstract class EDMDeviceTranslator(val deviceSpec: EDMDeviceSpec) {
                     )
                 }
                 
-                // Read response with timeout
-                val response = withTimeout(USB_TIMEOUT_MS.toLong()) {
-                    readUsbResponse(connection, endpointIn)
-                }
+                // Read response; the read stops itself at the timeout
+                val response = readUsbResponse(connection, endpointIn, USB_TIMEOUT_MS.toLong())
                 
                 Log.d(TAG, "Received response: $response")
                 
                 // Parse response
-                val parsedReading = parseResponse(response)
+                val parsedReading = parseReply(response)
                 
                 if (!parsedReading.isValid) {
                     return@withContext EDMTranslationResult(
@@ -209,32 +514,36 @@ abstract class EDMDeviceTranslator(val deviceSpec: EDMDeviceSpec) {
         return null
     }
     
+    /**
+     * Read until a complete response or the timeout. Each transfer is short and bounded by
+     * the time left, so a cancelled caller is not kept waiting on a blocked transfer.
+     */
     private suspend fun readUsbResponse(
         connection: UsbDeviceConnection,
-        endpoint: UsbEndpoint
+        endpoint: UsbEndpoint,
+        timeoutMs: Long
     ): String = withContext(Dispatchers.IO) {
         val buffer = ByteArray(READ_BUFFER_SIZE)
         val responseBuilder = StringBuilder()
+        val deadline = System.currentTimeMillis() + timeoutMs
         
-        var attempts = 0
-        val maxAttempts = 5
-        
-        while (attempts < maxAttempts) {
-            val bytesRead = connection.bulkTransfer(endpoint, buffer, buffer.size, 2000)
+        while (System.currentTimeMillis() < deadline) {
+            ensureActive()
+            val transferTimeout = (deadline - System.currentTimeMillis()).coerceIn(1L, USB_TRANSFER_TIMEOUT_MS).toInt()
+            val bytesRead = connection.bulkTransfer(endpoint, buffer, buffer.size, transferTimeout)
             
             if (bytesRead > 0) {
                 val chunk = String(buffer, 0, bytesRead, Charset.forName("ASCII"))
                 responseBuilder.append(chunk)
                 
                 val currentResponse = responseBuilder.toString()
-                if (isResponseComplete(currentResponse)) {
+                // The latest line alone is checked too, in case status lines came first
+                val latestLine = responseLines(currentResponse).lastOrNull().orEmpty()
+                if (isResponseComplete(currentResponse) || isResponseComplete(latestLine)) {
                     break
                 }
-            } else if (bytesRead < 0) {
-                Log.w(TAG, "USB read error: $bytesRead")
             }
-            
-            attempts++
+            // A negative count is the transfer timing out with nothing to read
         }
         
         responseBuilder.toString().trim()
@@ -256,17 +565,44 @@ object EDMDeviceRegistry {
             baudRate = 9600,
             vendorIds = listOf(1027, 6790), // FTDI VID (0x0403) + CH340 VID (0x1A86)
             productIds = listOf(24577, 29987) // FTDI FT232R PID (0x6001) + CH340 PID (0x7523)
+        ),
+        // Stations below connect through generic USB-to-serial adapters, so they have no
+        // USB IDs of their own and are chosen by driver key when connecting
+        "LEICA_GSI" to EDMDeviceSpec(
+            manufacturer = EDMManufacturer.LEICA,
+            model = "GSI",
+            displayName = "Leica (GSI Online)",
+            baudRate = 19200
+        ),
+        "TOPCON_GTS" to EDMDeviceSpec(
+            manufacturer = EDMManufacturer.TOPCON,
+            model = "GTS",
+            displayName = "Topcon GTS",
+            baudRate = 1200,
+            dataBits = 7,
+            parity = "EVEN"
+        ),
+        "SOKKIA_SET" to EDMDeviceSpec(
+            manufacturer = EDMManufacturer.SOKKIA,
+            model = "SET",
+            displayName = "Sokkia SET",
+            baudRate = 9600
+        ),
+        "NIKON_DTM" to EDMDeviceSpec(
+            manufacturer = EDMManufacturer.NIKON,
+            model = "DTM",
+            displayName = "Nikon DTM / NPL",
+            baudRate = 9600
         )
-        // Future devices will be added here:
-        // "LEICA_TCR405" to EDMDeviceSpec(...),
-        // "TOPCON_GTS102N" to EDMDeviceSpec(...)
     )
     
     // Registry of device translators
     private val translators = mapOf<String, () -> EDMDeviceTranslator>(
-        "MATO_MTS602R" to { MatoMTS602RTranslator(deviceSpecs["MATO_MTS602R"]!!) }
-        // Future translators will be added here:
-        // "LEICA_TCR405" to { LeicaTCR405Translator(deviceSpecs["LEICA_TCR405"]!!) }
+        "MATO_MTS602R" to { MatoMTS602RTranslator(deviceSpecs["MATO_MTS602R"]!!) },
+        "LEICA_GSI" to { LeicaGSITranslator(deviceSpecs["LEICA_GSI"]!!) },
+        "TOPCON_GTS" to { TopconGTSTranslator(deviceSpecs["TOPCON_GTS"]!!) },
+        "SOKKIA_SET" to { SokkiaSETTranslator(deviceSpecs["SOKKIA_SET"]!!) },
+        "NIKON_DTM" to { NikonTranslator(deviceSpecs["NIKON_DTM"]!!) }
     )
     
     /**
@@ -292,11 +628,32 @@ object EDMDeviceRegistry {
         }
     }
     
+    /**
+     * Registry key for a device spec (e.g. "MATO_MTS602R")
+     */
+    fun keyFor(deviceSpec: EDMDeviceSpec): String {
+        return "${deviceSpec.manufacturer.name}_${deviceSpec.model.replace("+", "").replace("-", "")}"
+    }
+    
+    /**
+     * Registry keys of every device with a translator, for driver selection
+     */
+    fun getDriverKeys(): List<String> {
+        return translators.keys.toList()
+    }
+    
+    /**
+     * Find device spec by registry key (e.g. "MATO_MTS602R")
+     */
+    fun findDeviceByKey(key: String): EDMDeviceSpec? {
+        return deviceSpecs[key]
+    }
+    
     /**
      * Create translator for specified device
      */
     fun createTranslator(deviceSpec: EDMDeviceSpec): EDMDeviceTranslator? {
-        val key = "${deviceSpec.manufacturer.name}_${deviceSpec.model.replace("+", "").replace("-", "")}"
+        val key = keyFor(deviceSpec)
         Log.d(TAG, "Looking for translator with key: $key")
         return translators[key]?.invoke()
     }
@@ -356,7 +713,7 @@ class EDMCommunicationBridge {
             )
         
         try {
-            val parsed = translator.parseResponse(rawResponse)
+            val parsed = translator.parseReply(rawResponse)
             if (!parsed.isValid) {
                 return EDMTranslationResult(
                     success = false,
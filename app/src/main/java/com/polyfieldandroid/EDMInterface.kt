@@ -12,7 +12,10 @@ import kotlin.math.*
  * Single communication conduit to EDM devices with standardized response format
  * Provides 4 core measurement functions: setCentre, measure, verifyEdge, sectorCheck
  */
-class EDMInterface(private val context: Context) {
+class EDMInterface(
+    private val context: Context,
+    sharedEdmModule: EDMModule? = null
+) {
     
     companion object {
         private const val TAG = "EDMInterface"
@@ -26,15 +29,42 @@ class EDMInterface(private val context: Context) {
         // Tolerance specifications (World Athletics/UKA)
         const val TOLERANCE_THROWS_MM = 5.0   // Standard throws circles
         const val TOLERANCE_JAVELIN_MM = 10.0 // Javelin arc
+
+        /**
+         * Result map for a failed call, carrying the instrument's own error when it refused
+         */
+        fun failureMap(error: Throwable?, fallback: String): Map<String, Any> =
+            mapOf("success" to false, "error" to (error?.message ?: fallback)) +
+                ((error as? InstrumentException)?.let { mapOf("instrumentError" to it.instrumentError.toMap()) } ?: emptyMap())
     }
+
+    /**
+     * A reading the instrument itself refused, e.g. "Prism not found – check aiming at the prism and remeasure"
+     */
+    class InstrumentException(val instrumentError: EDMInstrumentError) : Exception(
+        "${instrumentError.humanMessage} – ${instrumentError.suggestedAction.replaceFirstChar { it.lowercase() }}"
+    )
     
-    // Communication layer - using existing modules
-    private val edmModule = EDMModule(context)
+    // Communication layer - share the app's module so its connections and runtime config apply
+    private val edmModule = sharedEdmModule ?: EDMModule(context)
     
-    // Current calibration state
-    private var centreCoordinates: Pair<Double, Double>? = null // EDM position relative to circle center (0,0)
-    private var currentCircleType: String? = null
-    private var currentCircleRadius: Double? = null
+    /**
+     * Calibration of one station; each named EDM measures against its own circle
+     */
+    private class StationCalibration {
+        var centreCoordinates: Pair<Double, Double>? = null // EDM position relative to circle center (0,0)
+        var centreGroundHeight: Double? = null // Circle centre ground above the instrument's ground point
+        var circleType: String? = null
+        var circleRadius: Double? = null
+        var centreTimestamp: String? = null
+        var edgeResult: EdgeResult? = null
+    }
+
+    // Current calibration state per deviceId
+    private val stations = java.util.concurrent.ConcurrentHashMap<String, StationCalibration>()
+
+    private fun station(deviceType: String): StationCalibration =
+        stations.getOrPut(deviceType) { StationCalibration() }
     
     /**
      * Data class for standardized EDM reading format
@@ -44,23 +74,84 @@ class EDMInterface(private val context: Context) {
         val slopeDistanceM: Double,      // Slope distance in meters
         val verticalAngleDeg: Double,    // Vertical angle in decimal degrees (from vertical upwards)
         val horizontalAngleDeg: Double,  // Horizontal angle in decimal degrees
-        val timestamp: String = java.time.Instant.now().toString()
+        val timestamp: String = java.time.Instant.ofEpochMilli(SessionSources.now()).toString(),
+        val rawSlopeDistanceM: Double? = null, // As read, before any correction; null if none was applied
+        val atmosphericPpm: Double? = null,
+        val prismConstantMm: Double? = null,
+        val measurementMode: String? = null,           // "prism" or "reflectorless"
+        val instrumentHeightM: Double = 0.0,           // Trunnion axis above the ground
+        val targetHeightM: Double = 0.0,               // Prism above the ground point
+        val atmosphere: AtmosphericConditions? = null, // Conditions the atmospheric correction used
+        val verticalAngleRaw: String? = null,   // Instrument's own angle strings, full seconds resolution
+        val horizontalAngleRaw: String? = null,
+        val triggeredAt: Long? = null,          // Epoch ms the instrument was told to measure
+        val deviceLatencyMs: Long? = null       // Trigger to response
     )
+
+    /**
+     * Intermediate values behind a measured distance, for technical officials to audit
+     * how the mark was reduced from the EDM reading
+     */
+    data class MeasurementComponents(
+        val rawSlopeDistanceM: Double,       // As read from the instrument
+        val correctedSlopeDistanceM: Double, // After prism constant and atmospheric correction
+        val atmosphericPpm: Double,          // 0 when no correction was applied
+        val verticalAngleDeg: Double,
+        val horizontalAngleDeg: Double,
+        val horizontalDistanceM: Double,     // Station to prism, reduced to the horizontal
+        val stationOffsetX: Double,          // EDM station relative to circle centre
+        val stationOffsetY: Double,
+        val throwX: Double,                  // Landing point relative to circle centre
+        val throwY: Double,
+        val distanceFromCentreM: Double,
+        val circleRadiusM: Double,
+        val throwDistanceM: Double,
+        val verticalAngleRaw: String? = null,
+        val horizontalAngleRaw: String? = null,
+        val prismConstantMm: Double = 0.0,
+        val atmosphere: AtmosphericConditions? = null,
+        val instrumentHeightM: Double = 0.0, // Setup heights; they affect only heightAboveCentreM
+        val targetHeightM: Double = 0.0,
+        val heightAboveCentreM: Double? = null
+    ) {
+        fun toMap(): Map<String, Any> = listOfNotNull(
+            verticalAngleRaw?.let { "verticalAngleRaw" to it },
+            horizontalAngleRaw?.let { "horizontalAngleRaw" to it },
+            atmosphere?.let { "atmosphere" to it.toMap() },
+            heightAboveCentreM?.let { "heightAboveCentreM" to it }
+        ).toMap() + mapOf(
+            "instrumentHeightM" to instrumentHeightM,
+            "targetHeightM" to targetHeightM,
+            "rawSlopeDistanceM" to rawSlopeDistanceM,
+            "correctedSlopeDistanceM" to correctedSlopeDistanceM,
+            "atmosphericPpm" to atmosphericPpm,
+            "prismConstantMm" to prismConstantMm,
+            "verticalAngleDeg" to verticalAngleDeg,
+            "horizontalAngleDeg" to horizontalAngleDeg,
+            "horizontalDistanceM" to horizontalDistanceM,
+            "stationOffset" to mapOf("x" to stationOffsetX, "y" to stationOffsetY),
+            "throwCoordinates" to mapOf("x" to throwX, "y" to throwY),
+            "distanceFromCentreM" to distanceFromCentreM,
+            "circleRadiusM" to circleRadiusM,
+            "throwDistanceM" to throwDistanceM
+        )
+    }
     
     /**
      * SINGLE EDM COMMUNICATION FUNCTION
      * All EDM device communication goes through this function
      * Returns standardized JSON format for any supported EDM device
      */
-    suspend fun getEDMReading(deviceType: String): Result<EDMReading> = withContext(Dispatchers.IO) {
+    suspend fun getEDMReading(deviceType: String, quickRead: Boolean = false): Result<EDMReading> = withContext(Dispatchers.IO) {
         return@withContext try {
             Log.d(TAG, "Getting EDM reading from device: $deviceType")
             
             // Use existing EDM module to get reading
-            val edmResult = edmModule.getReliableEDMReading(deviceType, true) // singleMode = true
+            val edmResult = edmModule.getReliableEDMReading(deviceType, true, quickRead) // singleMode = true
             if (!edmResult.success) {
                 return@withContext Result.failure(
-                    Exception("EDM communication failed: ${edmResult.error}")
+                    edmResult.instrumentError?.let { InstrumentException(it) }
+                        ?: Exception("EDM communication failed: ${edmResult.error}")
                 )
             }
             
@@ -84,7 +175,18 @@ class EDMInterface(private val context: Context) {
             val reading = EDMReading(
                 slopeDistanceM = slopeDistanceMm / 1000.0, // Convert mm to meters
                 verticalAngleDeg = verticalAngleDeg,
-                horizontalAngleDeg = horizontalAngleDeg
+                horizontalAngleDeg = horizontalAngleDeg,
+                rawSlopeDistanceM = if (jsonData.has("rawSlopeDistanceMm")) jsonData.getDouble("rawSlopeDistanceMm") / 1000.0 else null,
+                atmosphericPpm = if (jsonData.has("atmosphericPpm")) jsonData.getDouble("atmosphericPpm") else null,
+                prismConstantMm = if (jsonData.has("prismConstantMm")) jsonData.getDouble("prismConstantMm") else null,
+                measurementMode = if (jsonData.has("measurementMode")) jsonData.getString("measurementMode") else null,
+                instrumentHeightM = jsonData.optDouble("instrumentHeightM", 0.0),
+                targetHeightM = jsonData.optDouble("targetHeightM", 0.0),
+                atmosphere = jsonData.optJSONObject("atmosphere")?.let { parseAtmosphere(it) },
+                verticalAngleRaw = if (jsonData.has("vAzRaw")) jsonData.getString("vAzRaw") else null,
+                horizontalAngleRaw = if (jsonData.has("harRaw")) jsonData.getString("harRaw") else null,
+                triggeredAt = edmResult.triggeredAt,
+                deviceLatencyMs = edmResult.latencyMs
             )
             
             Log.d(TAG, "Standardized EDM reading: slope=${reading.slopeDistanceM}m, vertical=${reading.verticalAngleDeg}°, horizontal=${reading.horizontalAngleDeg}°")
@@ -107,8 +209,9 @@ class EDMInterface(private val context: Context) {
             Log.d(TAG, "Setting centre for circle type: $circleType")
             
             // Set circle configuration
-            currentCircleType = circleType
-            currentCircleRadius = getCircleRadius(circleType)
+            val station = station(deviceType)
+            station.circleType = circleType
+            station.circleRadius = getCircleRadius(circleType)
             
             // Get EDM reading
             val readingResult = getEDMReading(deviceType)
@@ -120,18 +223,28 @@ class EDMInterface(private val context: Context) {
             
             // Calculate EDM position relative to circle center (0,0)
             val coordinates = calculateCoordinatesFromReading(reading)
-            centreCoordinates = coordinates
+            val centreHeight = groundHeight(reading)
+            station.centreCoordinates = coordinates
+            station.centreGroundHeight = centreHeight
+            station.centreTimestamp = java.text.SimpleDateFormat("yyyy-MM-dd HH:mm:ss", java.util.Locale.getDefault())
+                .format(java.util.Date(SessionSources.now()))
+            station.edgeResult = null
             
-            Log.d(TAG, "Centre set: EDM at (${coordinates.first}, ${coordinates.second}) relative to circle center (0,0)")
+            Log.d(TAG, "Centre set for $deviceType: EDM at (${coordinates.first}, ${coordinates.second}) relative to circle center (0,0)")
             
             Result.success(mapOf(
                 "success" to true,
                 "circleType" to circleType,
-                "circleRadius" to currentCircleRadius!!,
+                "circleRadius" to getCircleRadius(circleType),
                 "edmPosition" to mapOf(
                     "x" to coordinates.first,
                     "y" to coordinates.second
                 ),
+                "prismConstantMm" to (reading.prismConstantMm ?: 0.0),
+                "instrumentHeightM" to reading.instrumentHeightM,
+                "targetHeightM" to reading.targetHeightM,
+                "centreHeightM" to centreHeight,
+                "measurementMode" to (reading.measurementMode ?: EDMMeasurementMode.PRISM.key),
                 "message" to "Centre set successfully"
             ))
             
@@ -146,17 +259,22 @@ class EDMInterface(private val context: Context) {
      * Calculates the distance from the centre of the circle to the measured response
      * Subtracts the radius of the selected event circle
      * Returns throw distance beyond circle edge
+     * With verbose set, the result also carries the intermediate "components";
+     * quickRead takes a single read regardless of the read policy
      */
-    suspend fun measure(deviceType: String): Result<Map<String, Any>> {
+    suspend fun measure(deviceType: String, verbose: Boolean = false, quickRead: Boolean = false): Result<Map<String, Any>> {
         return try {
-            if (centreCoordinates == null || currentCircleRadius == null) {
+            val station = station(deviceType)
+            val centre = station.centreCoordinates
+            val circleRadius = station.circleRadius
+            if (centre == null || circleRadius == null) {
                 return Result.failure(Exception("Centre must be set before measuring"))
             }
             
             Log.d(TAG, "Measuring throw distance")
             
             // Get EDM reading
-            val readingResult = getEDMReading(deviceType)
+            val readingResult = getEDMReading(deviceType, quickRead)
             if (readingResult.isFailure) {
                 return Result.failure(readingResult.exceptionOrNull()!!)
             }
@@ -164,17 +282,26 @@ class EDMInterface(private val context: Context) {
             val reading = readingResult.getOrThrow()
             
             // Calculate throw coordinates relative to circle center
-            val throwCoords = calculateThrowCoordinates(reading)
+            val throwCoords = calculateThrowCoordinates(reading, centre)
             
             // Distance from circle center to throw point
             val distanceFromCenter = sqrt(throwCoords.first.pow(2) + throwCoords.second.pow(2))
             
-            // Throw distance = distance from center - circle radius
-            val throwDistance = distanceFromCenter - currentCircleRadius!!
+            // Throw distance = distance from center - circle radius, unless a federation's
+            // own computation has been selected
+            val (throwDistance, markComputation) = MarkComputationRegistry.compute(
+                MarkInput(
+                    circleType = station.circleType ?: EDMCalculations.CIRCLE_SHOT,
+                    circleRadiusM = circleRadius,
+                    landingX = throwCoords.first,
+                    landingY = throwCoords.second,
+                    distanceFromCentreM = distanceFromCenter
+                )
+            )
             
             Log.d(TAG, "Throw measured: ${String.format("%.2f", throwDistance)}m beyond circle edge")
             
-            Result.success(mapOf(
+            val result = mutableMapOf<String, Any>(
                 "success" to true,
                 "throwDistance" to throwDistance,
                 "distanceFromCenter" to distanceFromCenter,
@@ -182,9 +309,42 @@ class EDMInterface(private val context: Context) {
                     "x" to throwCoords.first,
                     "y" to throwCoords.second
                 ),
-                "circleRadius" to currentCircleRadius!!,
-                "measurement" to "${String.format("%.2f", throwDistance)} m"
-            ))
+                "circleRadius" to circleRadius,
+                "markComputation" to markComputation,
+                "measurement" to "${String.format("%.2f", throwDistance)} m",
+                // When the throw was measured, as opposed to when this result was built
+                "measuredAt" to (reading.triggeredAt ?: SessionSources.now()),
+                "builtAt" to SessionSources.now()
+            )
+            reading.deviceLatencyMs?.let { result["deviceLatencyMs"] = it }
+            reading.measurementMode?.let { result["measurementMode"] = it }
+            heightAboveCentre(reading, station)?.let { result["heightAboveCentreM"] = it }
+            if (verbose) {
+                result["components"] = MeasurementComponents(
+                    rawSlopeDistanceM = reading.rawSlopeDistanceM ?: reading.slopeDistanceM,
+                    correctedSlopeDistanceM = reading.slopeDistanceM,
+                    atmosphericPpm = reading.atmosphericPpm ?: 0.0,
+                    verticalAngleDeg = reading.verticalAngleDeg,
+                    horizontalAngleDeg = reading.horizontalAngleDeg,
+                    horizontalDistanceM = horizontalDistance(reading),
+                    stationOffsetX = centre.first,
+                    stationOffsetY = centre.second,
+                    throwX = throwCoords.first,
+                    throwY = throwCoords.second,
+                    distanceFromCentreM = distanceFromCenter,
+                    circleRadiusM = circleRadius,
+                    throwDistanceM = throwDistance,
+                    verticalAngleRaw = reading.verticalAngleRaw,
+                    horizontalAngleRaw = reading.horizontalAngleRaw,
+                    prismConstantMm = reading.prismConstantMm ?: 0.0,
+                    atmosphere = reading.atmosphere,
+                    instrumentHeightM = reading.instrumentHeightM,
+                    targetHeightM = reading.targetHeightM,
+                    heightAboveCentreM = heightAboveCentre(reading, station)
+                )
+            }
+
+            Result.success(result)
             
         } catch (e: Exception) {
             Log.e(TAG, "Measure failed", e)
@@ -192,6 +352,59 @@ class EDMInterface(private val context: Context) {
         }
     }
     
+    /**
+     * Install a calibration taken earlier on a station, e.g. when a shared EDM switches circles
+     */
+    fun applyCalibration(calibration: CalibrationState, deviceType: String = "edm") {
+        val station = station(deviceType)
+        station.circleType = calibration.circleType
+        station.circleRadius = calibration.targetRadius
+        station.centreCoordinates = calibration.stationCoordinates?.takeIf { calibration.centreSet }
+        station.centreGroundHeight = null
+        station.centreTimestamp = calibration.centreTimestamp
+        station.edgeResult = calibration.edgeResult
+        Log.d(TAG, "Applied ${calibration.circleType} calibration to $deviceType, centre ${if (station.centreCoordinates != null) "set" else "not set"}")
+    }
+
+    fun isCentreSet(deviceType: String): Boolean = stations[deviceType]?.centreCoordinates != null
+
+    /**
+     * Forget a station's circle and centre, e.g. once it has been moved elsewhere
+     */
+    fun resetCalibration(deviceType: String) {
+        stations.remove(deviceType)
+        Log.d(TAG, "Calibration reset for $deviceType")
+    }
+
+    /**
+     * A station's calibration in the app's form, e.g. for the readiness checks
+     */
+    fun calibrationState(deviceType: String): CalibrationState {
+        val station = stations[deviceType] ?: return CalibrationState()
+        val circleType = station.circleType ?: return CalibrationState()
+        return CalibrationState(
+            circleType = circleType,
+            targetRadius = station.circleRadius ?: getCircleRadius(circleType),
+            centreSet = station.centreCoordinates != null,
+            centreTimestamp = station.centreTimestamp,
+            stationCoordinates = station.centreCoordinates,
+            edgeVerified = station.edgeResult != null,
+            edgeResult = station.edgeResult
+        )
+    }
+
+    /**
+     * Circle and centre a station measures against, for station lists and diagnostics
+     */
+    fun getStationCalibration(deviceType: String): Map<String, Any> {
+        val station = stations[deviceType]
+        return listOfNotNull(
+            station?.circleType?.let { "circleType" to it },
+            station?.circleRadius?.let { "circleRadius" to it },
+            station?.centreCoordinates?.let { "edmPosition" to mapOf("x" to it.first, "y" to it.second) }
+        ).toMap() + mapOf("centreSet" to (station?.centreCoordinates != null))
+    }
+
     /**
      * 3. VERIFY EDGE
      * Measures distance and compares against known circle radius
@@ -199,7 +412,11 @@ class EDMInterface(private val context: Context) {
      */
     suspend fun verifyEdge(deviceType: String): Result<Map<String, Any>> {
         return try {
-            if (centreCoordinates == null || currentCircleRadius == null || currentCircleType == null) {
+            val station = station(deviceType)
+            val centre = station.centreCoordinates
+            val circleRadius = station.circleRadius
+            val circleType = station.circleType
+            if (centre == null || circleRadius == null || circleType == null) {
                 return Result.failure(Exception("Centre must be set before verifying edge"))
             }
             
@@ -214,27 +431,33 @@ class EDMInterface(private val context: Context) {
             val reading = readingResult.getOrThrow()
             
             // Calculate edge coordinates relative to circle center
-            val edgeCoords = calculateThrowCoordinates(reading)
+            val edgeCoords = calculateThrowCoordinates(reading, centre)
             
             // Measured radius from center
             val measuredRadius = sqrt(edgeCoords.first.pow(2) + edgeCoords.second.pow(2))
             
             // Difference from target radius
-            val differenceMm = (measuredRadius - currentCircleRadius!!) * 1000.0
+            val differenceMm = (measuredRadius - circleRadius) * 1000.0
             
             // Check tolerance
-            val toleranceMm = if (currentCircleType == "JAVELIN_ARC") TOLERANCE_JAVELIN_MM else TOLERANCE_THROWS_MM
+            val toleranceMm = if (circleType == "JAVELIN_ARC") TOLERANCE_JAVELIN_MM else TOLERANCE_THROWS_MM
             val isInTolerance = abs(differenceMm) <= toleranceMm
+            station.edgeResult = EdgeResult(
+                toleranceCheck = isInTolerance,
+                averageRadius = measuredRadius,
+                deviation = differenceMm / 1000.0
+            )
             
-            Log.d(TAG, "Edge verification: measured=${String.format("%.3f", measuredRadius)}m, target=${String.format("%.3f", currentCircleRadius!!)}m, diff=${String.format("%.1f", differenceMm)}mm, tolerance=${toleranceMm}mm, result=${if (isInTolerance) "PASS" else "FAIL"}")
+            Log.d(TAG, "Edge verification: measured=${String.format("%.3f", measuredRadius)}m, target=${String.format("%.3f", circleRadius)}m, diff=${String.format("%.1f", differenceMm)}mm, tolerance=${toleranceMm}mm, result=${if (isInTolerance) "PASS" else "FAIL"}")
             
             Result.success(mapOf(
                 "success" to true,
                 "measuredRadius" to measuredRadius,
-                "targetRadius" to currentCircleRadius!!,
+                "targetRadius" to circleRadius,
                 "differenceMm" to differenceMm,
                 "toleranceMm" to toleranceMm,
                 "isInTolerance" to isInTolerance,
+                "heightAboveCentreM" to (heightAboveCentre(reading, station) ?: 0.0),
                 "result" to if (isInTolerance) "PASS" else "FAIL",
                 "message" to "Edge verification ${if (isInTolerance) "PASSED" else "FAILED"} - ${String.format("%.1f", abs(differenceMm))}mm ${if (differenceMm > 0) "over" else "under"}"
             ))
@@ -252,7 +475,10 @@ class EDMInterface(private val context: Context) {
      */
     suspend fun sectorCheck(deviceType: String): Result<Map<String, Any>> {
         return try {
-            if (centreCoordinates == null) {
+            val station = station(deviceType)
+            val centre = station.centreCoordinates
+            val circleRadius = station.circleRadius
+            if (centre == null || circleRadius == null) {
                 return Result.failure(Exception("Centre must be set before sector check"))
             }
             
@@ -267,13 +493,13 @@ class EDMInterface(private val context: Context) {
             val reading = readingResult.getOrThrow()
             
             // Calculate sector point coordinates relative to circle center
-            val sectorCoords = calculateThrowCoordinates(reading)
+            val sectorCoords = calculateThrowCoordinates(reading, centre)
 
             // Distance from circle center
             val distanceFromCenter = sqrt(sectorCoords.first.pow(2) + sectorCoords.second.pow(2))
 
             // Distance beyond circle edge (same as throw distance calculation)
-            val distanceBeyondEdge = distanceFromCenter - currentCircleRadius!!
+            val distanceBeyondEdge = distanceFromCenter - circleRadius
 
             // Calculate angle from positive X-axis for sector line
             val angleFromXAxis = atan2(sectorCoords.second, sectorCoords.first)
@@ -302,6 +528,20 @@ class EDMInterface(private val context: Context) {
     
     // ========== PRIVATE HELPER FUNCTIONS ==========
     
+    /**
+     * Atmospheric conditions from the measurement reply's "atmosphere" object
+     */
+    private fun parseAtmosphere(json: JSONObject): AtmosphericConditions =
+        AtmosphericConditions(
+            altitudeM = json.getDouble("altitudeM"),
+            pressureHpa = json.getDouble("pressureHpa"),
+            pressureMeasured = json.getBoolean("pressureMeasured"),
+            temperatureC = json.getDouble("temperatureC"),
+            temperatureMeasured = json.getBoolean("temperatureMeasured"),
+            ppm = json.getDouble("ppm"),
+            humidityPct = if (json.has("humidityPct")) json.getDouble("humidityPct") else null
+        )
+
     /**
      * Get circle radius for circle type
      */
@@ -316,43 +556,38 @@ class EDMInterface(private val context: Context) {
     }
     
     /**
-     * Calculate coordinates from EDM reading using PDF formula
-     * hd = sd * cos(90° - va) where va is measured from vertically upwards
+     * EDM position relative to circle center (0,0), from a reading on the centre
      */
-    private fun calculateCoordinatesFromReading(reading: EDMReading): Pair<Double, Double> {
-        val sdMeters = reading.slopeDistanceM
-        val vazRad = Math.toRadians(reading.verticalAngleDeg)
-        val harRad = Math.toRadians(reading.horizontalAngleDeg)
-        
-        // Apply PDF formula: hd = sd * cos(90° - va)
-        val horizontalDistance = sdMeters * cos(Math.toRadians(90.0) - vazRad)
-        
-        // Calculate coordinates (negative to position EDM relative to circle center)
-        val x = -horizontalDistance * cos(harRad)
-        val y = -horizontalDistance * sin(harRad)
-        
-        return Pair(x, y)
-    }
-    
+    private fun calculateCoordinatesFromReading(reading: EDMReading): Pair<Double, Double> =
+        CoordinateTransforms.stationFromCentreReading(reading.slopeDistanceM, reading.verticalAngleDeg, reading.horizontalAngleDeg)
+
+    /**
+     * Horizontal distance from station to prism: hd = sd * cos(90° - va)
+     */
+    private fun horizontalDistance(reading: EDMReading): Double =
+        CoordinateTransforms.horizontalDistance(reading.slopeDistanceM, reading.verticalAngleDeg)
+
+    /**
+     * Ground under the prism above the instrument's ground point, using the setup heights
+     */
+    private fun groundHeight(reading: EDMReading): Double =
+        CoordinateTransforms.groundHeightDifference(
+            reading.slopeDistanceM, reading.verticalAngleDeg, reading.instrumentHeightM, reading.targetHeightM
+        )
+
+    /**
+     * Ground under the prism above the circle centre; null when the centre was not set
+     * in this session
+     */
+    private fun heightAboveCentre(reading: EDMReading, station: StationCalibration): Double? =
+        station.centreGroundHeight?.let { groundHeight(reading) - it }
+
     /**
      * Calculate throw coordinates relative to circle center (0,0)
      */
-    private fun calculateThrowCoordinates(reading: EDMReading): Pair<Double, Double> {
-        val sdMeters = reading.slopeDistanceM
-        val vazRad = Math.toRadians(reading.verticalAngleDeg)
-        val harRad = Math.toRadians(reading.horizontalAngleDeg)
-        
-        // Apply PDF formula: hd = sd * cos(90° - va)
-        val horizontalDistance = sdMeters * cos(Math.toRadians(90.0) - vazRad)
-        
-        // Calculate throw point relative to EDM
-        val throwX = horizontalDistance * cos(harRad)
-        val throwY = horizontalDistance * sin(harRad)
-        
-        // Convert to coordinates relative to circle center (0,0)
-        val absoluteX = centreCoordinates!!.first + throwX
-        val absoluteY = centreCoordinates!!.second + throwY
-        
-        return Pair(absoluteX, absoluteY)
-    }
-}
\ No newline at end of file
+    private fun calculateThrowCoordinates(reading: EDMReading, centre: Pair<Double, Double>): Pair<Double, Double> =
+        CoordinateTransforms.stationToCircle(
+            CoordinateTransforms.readingToStation(reading.slopeDistanceM, reading.verticalAngleDeg, reading.horizontalAngleDeg),
+            centre
+        )
+}
@@ -5,13 +5,23 @@ import android.hardware.usb.UsbManager
 import android.hardware.usb.UsbDevice
 import android.util.Log
 import com.hoho.android.usbserial.driver.UsbSerialPort
+import kotlinx.coroutines.CoroutineScope
 import kotlinx.coroutines.Dispatchers
-import kotlinx.coroutines.GlobalScope
+import kotlinx.coroutines.Job
+import kotlinx.coroutines.SupervisorJob
 import kotlinx.coroutines.delay
+import kotlinx.coroutines.flow.MutableSharedFlow
+import kotlinx.coroutines.flow.SharedFlow
+import kotlinx.coroutines.flow.asSharedFlow
+import kotlinx.coroutines.isActive
+import kotlinx.coroutines.job
 import kotlinx.coroutines.launch
 import kotlinx.coroutines.withContext
 import kotlinx.coroutines.withTimeout
+import org.json.JSONArray
 import org.json.JSONObject
+import java.io.IOException
+import java.util.concurrent.ConcurrentHashMap
 
 /**
  * EDM (Electronic Distance Measurement) Module for device communication
@@ -21,24 +31,191 @@ class EDMModule(private val context: Context) {
     
     companion object {
         private const val TAG = "EDMModule"
+
+        // Stream links (Bluetooth SPP, BLE, USB passthrough and RFC 2217 serial servers) present as
+        // sockets to the network module
+        private val STREAM_LINK_TRANSPORTS = setOf("bluetooth", "ble", "usb_passthrough", "rfc2217")
+        private val NETWORK_MODULE_TRANSPORTS = STREAM_LINK_TRANSPORTS + "network"
+        private val EDM_COMMAND_TRANSPORTS = STREAM_LINK_TRANSPORTS + "serial"
+
+        private const val MAX_RECENT_CONNECTION_EVENTS = 100
+
+        private const val DIAGNOSTICS_PREFS = "polyfield_diagnostics"
+        private const val PREF_LAST_RUN_AT = "last_run_at"
+
+        // A second read answering within this long, or this many times faster than the first,
+        // with the same bytes is taken to be a buffered copy
+        private const val DUPLICATE_READ_MIN_LATENCY_MS = 30L
+        private const val DUPLICATE_READ_LATENCY_RATIO = 4
+        private const val MAX_DUPLICATE_RETRIGGERS = 2
+
+        // Largest prism constant accepted; real reflectors are within a few centimetres of zero
+        private const val MAX_PRISM_CONSTANT_MM = 100.0
+
+        // Tripods and prism poles stay within this
+        private const val MAX_SETUP_HEIGHT_M = 5.0
+
+        // Tracking: quick reads back to back with a short pause, giving up after a run of failures
+        private const val TRACKING_INTERVAL_MS = 200L
+        private const val MAX_TRACKING_FAILURES = 20
+
+        // Named EDM stations, e.g. "edm_discus" alongside "edm_hammer", each with its own
+        // driver and calibration
+        const val EDM_STATION_PREFIX = "edm_"
+
+        fun isEdmDeviceType(deviceType: String): Boolean =
+            deviceType == "edm" || deviceType.startsWith(EDM_STATION_PREFIX)
+
+        // Named scoreboards, e.g. "scoreboard_shot" and "scoreboard_discus" for two circles'
+        // infield boards
+        const val SCOREBOARD_PREFIX = "scoreboard_"
     }
     
     // Device connection states
-    private val connectedDevices = mutableMapOf<String, DeviceConnection>()
+    private val connectedDevices = ConcurrentHashMap<String, DeviceConnection>()
 
     // EDM device management
     private val edmCommunicationBridge = EDMCommunicationBridge()
     private val serialCommunicationModule = SerialCommunicationModule(context)
+    private val edmAutoDetector = EDMAutoDetector(serialCommunicationModule)
     private var selectedEDMDevice: EDMDeviceSpec = EDMDeviceRegistry.getDefaultDevice()
+    // Drivers chosen for named stations; "edm" itself uses selectedEDMDevice
+    private val edmStationDrivers = ConcurrentHashMap<String, EDMDeviceSpec>()
 
     // Track active serial connections
     private val activeSerialPorts = mutableMapOf<String, UsbSerialPort>()
 
+    // Bluetooth SPP: EDM links are kept here; wind gauges and scoreboards run through
+    // networkDeviceModule over the same link
+    private val bluetoothSerialModule = BluetoothSerialModule(context)
+    private val bleGattModule = BleGattModule(context)
+    private val activeBluetoothLinks = mutableMapOf<String, StreamLink>()
+    // Links fed by the host for USB ports it drives itself; also in activeBluetoothLinks for an EDM
+    private val passthroughLinks = ConcurrentHashMap<String, PassthroughLink>()
+    private val usbSimulator by lazy { UsbSimulator(this) }
+    @Volatile private var demoStress: DemoStressScenario? = null
+    private val demoGeometryStore = DemoGeometryStore(context)
+
     // Network device module for TCP/IP devices (wind gauges, scoreboards)
     private val networkDeviceModule = NetworkDeviceModule()
+    private val networkDiscovery by lazy { NetworkDiscovery(context) }
+
+    // Persisted queue of scoreboard commands that could not be delivered
+    private val scoreboardOutbox = ScoreboardOutbox(context)
+
+    // Runtime-tunable device settings; replaced wholesale, read once per operation
+    private val runtimeConfigStore = DeviceRuntimeConfigStore(context)
+    @Volatile private var runtimeConfig: DeviceRuntimeConfig = runtimeConfigStore.load().also {
+        networkDeviceModule.setIdleTimeout(it.networkIdleTimeoutSeconds * 1000L)
+    }
+
+    // Index and collimation errors per instrument model, and two-face observations being collected
+    private val instrumentErrorStore = InstrumentErrorStore(context)
+    private val faceOneObservations = mutableListOf<Pair<Double, Double>>()
+    private val twoFaceSets = mutableListOf<TwoFaceSet>()
+
+    // EDM read timeouts following each instrument's response times and the temperature
+    private val readTimeouts = ReadTimeoutPolicy()
+
+    // Background measurements with their progress, and the reporter for each device's running one
+    private val measurementRequests = MeasurementRequests()
+    private val measurementProgress = ConcurrentHashMap<String, (MeasurementPhase) -> Unit>()
+    private val lastReportedPhase = ConcurrentHashMap<String, MeasurementPhase>()
+
+    // Background work the module owns: tracking, outbox replays and network disconnects
+    private val moduleScope = CoroutineScope(SupervisorJob() + Dispatchers.IO)
+
+    // Continuous tracking per EDM, and the recent positions it has read
+    private val trackingJobs = ConcurrentHashMap<String, Job>()
+    private val trackingBuffers = ConcurrentHashMap<String, TrackingBuffer>()
+
+    // Reflector prism constants, applied to every slope distance
+    private val prismConstantStore = PrismConstantStore(context)
+
+    // Fixed target each EDM re-sights to check its horizontal zero has not moved
+    private val orientationReferences = OrientationReferenceStore(context)
+
+    // Instrument and prism heights above the ground, per EDM
+    private val setupHeightStore = SetupHeightStore(context)
+
+    // Prism or reflectorless, per EDM, as last set; readings are tagged with it
+    private val measurementModes = ConcurrentHashMap<String, EDMMeasurementMode>()
+
+    // Latest ambient readings for atmospheric correction; null falls back to standard atmosphere
+    @Volatile private var ambientTemperatureC: Double? = null
+    @Volatile private var ambientPressureHpa: Double? = null
+    @Volatile private var ambientHumidityPct: Double? = null
+
+    // Conditions measured at a particular instrument, e.g. a sensor beside a remote station;
+    // these override the ambient readings and turn correction on for that device
+    private val deviceAtmospheres = ConcurrentHashMap<String, AmbientReading>()
+
+    private data class AmbientReading(val temperatureC: Double, val pressureHpa: Double, val humidityPct: Double)
+
+    // Wind averaging window last sent to each gauge
+    private val appliedWindWindows = mutableMapOf<String, Int>()
+
+    // Background readers buffering wind gauge output, keyed by network device ID
+    private val windListeners = mutableMapOf<String, WindGaugeListener>()
+    // Clock offsets of gauges that stamp their own output, keyed the same way
+    private val clockOffsets = DeviceClockOffsets()
+
+    // Raw traffic recorded on request for diagnosing faults reported from the field
+    private val trafficCapture = TrafficCapture()
+
+    // EDM device types currently put into standby by the app
+    private val standbyDevices = java.util.Collections.synchronizedSet(mutableSetOf<String>())
+
+    // Saved venue connection setups
+    private val connectionProfileStore = ConnectionProfileStore(context)
+
+    // Circles sharing this EDM, and the scoreboard the active one routes results to
+    val sharedEdm = SharedEdmScheduler(context)
+    @Volatile private var scoreboardRoute: String? = null
+
+    // Attach/detach and role binding events
+    private val _connectionEvents = MutableSharedFlow<DeviceConnectionEvent>(extraBufferCapacity = 32)
+    val connectionEvents: SharedFlow<DeviceConnectionEvent> = _connectionEvents.asSharedFlow()
+    // Kept for screens that poll rather than collect
+    private val recentConnectionEvents = ArrayDeque<DeviceConnectionEvent>()
+
+    // Brings dropped network, Bluetooth and serial devices back; USB replugs go through notifyUsbAttached
+    private val reconnectSupervisor = ReconnectSupervisor(
+        watchedDevices = { connectedDevices.keys.toList() },
+        isAlive = ::isConnectionAlive,
+        // A device disconnected on purpose leaves connectedDevices and the last device set
+        isWanted = { deviceType ->
+            connectedDevices.containsKey(deviceType) &&
+                connectionProfileStore.isAutoReconnect(deviceType) &&
+                connectionProfileStore.getLastDeviceSet().any { it.deviceType == deviceType }
+        },
+        reconnect = ::reconnectDevice,
+        onEvent = { type, deviceType, message ->
+            emitConnectionEvent(type, deviceType, connectedDevices[deviceType]?.address.orEmpty(), message)
+        }
+    ).apply { start() }
+
+    // Exchange counts, latency and uptime per device, with a background link check
+    private val deviceHealth = DeviceHealthMonitor(
+        connectedDevices = { connectedDevices.keys.toList() },
+        check = ::checkDeviceHealth
+    ).apply { start() }
+
+    init {
+        networkDeviceModule.activityListener = { deviceId, success, latencyMs, error ->
+            val deviceType = deviceId.removeSuffix("_network")
+            if (success) deviceHealth.recordSuccess(deviceType, latencyMs) else deviceHealth.recordError(deviceType, error ?: "Exchange failed")
+        }
+        networkDeviceModule.trafficListener = { deviceId, direction, bytes ->
+            trafficCapture.record(deviceId.removeSuffix("_network"), direction, bytes)
+        }
+    }
     
     // Native Kotlin calibration management (replaces Go Mobile)
     private val calibrationManager = EDMCalibrationManager(context)
+
+    // Calibration the app measures with, one per station; shared by every screen and manager
+    val edmInterface: EDMInterface by lazy { EDMInterface(context, this) }
     private val edmCalculations = EDMCalculations()
     
     // Legacy: Bridge for Go Mobile integration (will be removed)
@@ -63,7 +240,11 @@ class EDMModule(private val context: Context) {
         val distance: Double? = null,
         val error: String? = null,
         val goMobileData: String? = null,
-        val rawResponse: String? = null
+        val rawResponse: String? = null,
+        val errorCode: SerialErrorCode? = null,
+        val triggeredAt: Long? = null,  // Wall clock when the instrument was told to measure
+        val latencyMs: Long? = null,    // Trigger to response from the instrument
+        val instrumentError: EDMInstrumentError? = null  // What the instrument itself reported, if it refused
     )
     
     data class WindReading(
@@ -76,7 +257,23 @@ class EDMModule(private val context: Context) {
      * Connect to USB device (handles both direct USB and USB-to-serial adapters)
      * CRITICAL: Never simulates connections in live mode - only real device connections allowed
      */
-    suspend fun connectUsbDevice(deviceType: String, address: String): Map<String, Any> {
+    suspend fun connectUsbDevice(
+        deviceType: String,
+        address: String,
+        serialConfig: SerialPortConfig? = null,
+        driver: String? = null
+    ): Map<String, Any> = CrashReporter.guardResult("connectUsbDevice") {
+        connectUsbDeviceGuarded(deviceType, address, serialConfig, driver)
+    }
+
+    private suspend fun connectUsbDeviceGuarded(
+        deviceType: String,
+        address: String,
+        serialConfig: SerialPortConfig?,
+        driver: String?
+    ): Map<String, Any> {
+        selectEDMDriver(deviceType, driver)?.let { return it }
+
         return withContext(Dispatchers.IO) {
             Log.d(TAG, "Connecting to USB device: $deviceType at $address")
             
@@ -96,8 +293,13 @@ class EDMModule(private val context: Context) {
                     )
                 }
                 
-                // Check if this is a known EDM device or USB-to-serial adapter
-                val edmDevice = EDMDeviceRegistry.matchUsbDevice(targetDevice.vendorId, targetDevice.productId)
+                // Check if this is a known EDM device or USB-to-serial adapter; a chosen driver
+                // means the station sits behind an adapter whatever its USB IDs
+                val edmDevice = if (isEdmDeviceType(deviceType) && driver != null) {
+                    edmSpecFor(deviceType)
+                } else {
+                    EDMDeviceRegistry.matchUsbDevice(targetDevice.vendorId, targetDevice.productId)
+                }
                 val isSerialAdapter = edmDevice != null || isKnownSerialAdapter(targetDevice.vendorId, targetDevice.productId)
                 
                 if (isSerialAdapter) {
@@ -110,21 +312,35 @@ class EDMModule(private val context: Context) {
                         return@withContext mapOf(
                             "success" to false,
                             "error" to "No permission to access USB device: $deviceName",
+                            "errorCode" to SerialErrorCode.PERMISSION_DENIED.name,
                             "deviceType" to deviceType
                         )
                     }
                     
-                    // Try to establish serial connection (use default 9600 baud if device not recognized)
-                    val serialPort = serialCommunicationModule.openSerialConnection(
-                        targetDevice, 
-                        edmDevice?.baudRate ?: 9600
+                    // Try to establish serial connection: settings given, then the chosen driver's,
+                    // then those last used for this device type, then the device's own (default
+                    // 9600 8N1 if not recognized)
+                    val lineConfig = serialConfig
+                        ?: edmDevice?.takeIf { driver != null }?.let { SerialPortConfig.fromDeviceSpec(it) }
+                        ?: connectionProfileStore.getSerialConfig(deviceType)
+                        ?: edmDevice?.let { SerialPortConfig.fromDeviceSpec(it) }
+                        ?: SerialPortConfig()
+                    val openResult = serialCommunicationModule.openSerialPort(
+                        targetDevice,
+                        lineConfig.baudRate,
+                        lineConfig.dataBits,
+                        lineConfig.usbStopBits(),
+                        lineConfig.usbParity(),
+                        lineConfig.usbFlowControl()
                     )
+                    val serialPort = openResult.port
                     
                     if (serialPort == null) {
-                        Log.e(TAG, "Failed to open serial connection to $deviceName")
+                        Log.e(TAG, "Failed to open serial connection to $deviceName: ${openResult.errorCode}")
                         return@withContext mapOf(
                             "success" to false,
-                            "error" to "Failed to open serial connection to $deviceName",
+                            "error" to "Failed to open serial connection to $deviceName: ${openResult.error}",
+                            "errorCode" to (openResult.errorCode ?: SerialErrorCode.IO_ERROR).name,
                             "deviceType" to deviceType
                         )
                     }
@@ -179,6 +395,20 @@ class EDMModule(private val context: Context) {
                 )
                 
                 connectedDevices[deviceType] = connection
+
+                connectionProfileStore.rememberLastDevice(
+                    ConnectionProfile(
+                        name = "Last $deviceType",
+                        deviceType = deviceType,
+                        transport = connectionType,
+                        address = address,
+                        serialConfig = serialConfig,
+                        driver = if (isEdmDeviceType(deviceType)) EDMDeviceRegistry.keyFor(edmSpecFor(deviceType)) else null,
+                        vendorId = targetDevice.vendorId,
+                        productId = targetDevice.productId,
+                        lastUsed = SessionSources.now()
+                    )
+                )
                 
                 val message = if (isSerialAdapter) {
                     val deviceName = edmDevice?.displayName ?: "USB-to-Serial Adapter"
@@ -188,6 +418,7 @@ class EDMModule(private val context: Context) {
                 }
                 
                 Log.d(TAG, "Real device connection established: $message")
+                emitConnectionEvent(ConnectionEventType.CONNECTED, deviceType, address, message)
                 
                 mapOf(
                     "success" to true,
@@ -208,11 +439,44 @@ class EDMModule(private val context: Context) {
         }
     }
     
+    /**
+     * Connect a USB-to-serial device with explicit line settings, e.g. 1200 baud 7E1 for
+     * older Geodimeter and Sokkia units. configJson takes baudRate, dataBits, parity,
+     * stopBits and flowControl; once connected the settings are kept for the device type
+     * so reconnects reuse them.
+     */
+    suspend fun connectSerialDeviceWithConfig(
+        deviceType: String,
+        portName: String,
+        configJson: String,
+        driver: String? = null
+    ): Map<String, Any> {
+        val config = try {
+            SerialPortConfig.fromJson(configJson)
+        } catch (e: IllegalArgumentException) {
+            return mapOf(
+                "success" to false,
+                "error" to e.message.orEmpty(),
+                "deviceType" to deviceType
+            )
+        }
+
+        val result = connectUsbDevice(deviceType, portName, config, driver)
+        if (result["success"] == true) {
+            connectionProfileStore.saveSerialConfig(deviceType, config)
+        }
+        return result
+    }
+
+    fun getSerialConfig(deviceType: String): SerialPortConfig? = connectionProfileStore.getSerialConfig(deviceType)
+
     /**
      * Connect to serial device
      * CRITICAL: Never simulates connections in live mode - only real device connections allowed
      */
-    suspend fun connectSerialDevice(deviceType: String, address: String): Map<String, Any> {
+    suspend fun connectSerialDevice(deviceType: String, address: String, driver: String? = null): Map<String, Any> {
+        selectEDMDriver(deviceType, driver)?.let { return it }
+
         return withContext(Dispatchers.IO) {
             Log.d(TAG, "Connecting to serial device: $deviceType at $address")
             
@@ -259,28 +523,187 @@ class EDMModule(private val context: Context) {
         }
     }
     
+    /**
+     * Connect an EDM as a named station, so two can run from one tablet (e.g. "edm_discus"
+     * and "edm_hammer"). Each station keeps its own driver and calibration; measure,
+     * calibrate and standby calls take the station's device ID in place of "edm".
+     */
+    suspend fun connectSerialDeviceNamed(
+        deviceId: String,
+        role: String,
+        address: String,
+        driver: String? = null,
+        serialConfig: SerialPortConfig? = null
+    ): Map<String, Any> {
+        if (!isEdmDeviceType(deviceId)) {
+            return mapOf(
+                "success" to false,
+                "error" to "EDM station IDs must be \"edm\" or start with \"$EDM_STATION_PREFIX\"",
+                "deviceType" to deviceId
+            )
+        }
+        if (isClaimedByOtherDevice(address, deviceId)) {
+            return mapOf(
+                "success" to false,
+                "error" to "$address is already in use by another device",
+                "deviceType" to deviceId
+            )
+        }
+
+        val result = connectUsbDevice(deviceId, address, serialConfig, driver)
+        if (result["success"] == true) {
+            connectionProfileStore.setEdmStationRole(deviceId, role)
+            Log.d(TAG, "EDM station $deviceId ($role) on $address")
+        }
+        return result + ("role" to role)
+    }
+
+    /**
+     * EDM stations with their role, driver, connection and calibration
+     */
+    suspend fun getEdmStations(): List<Map<String, Any>> {
+        val roles = connectionProfileStore.getEdmStationRoles()
+        val deviceIds = (connectedDevices.keys.filter { isEdmDeviceType(it) } + roles.keys).distinct().sorted()
+        return deviceIds.map { deviceId ->
+            val calibration = edmInterface.getStationCalibration(deviceId)
+            mapOf(
+                "deviceId" to deviceId,
+                "role" to (roles[deviceId] ?: ""),
+                "driver" to EDMDeviceRegistry.keyFor(edmSpecFor(deviceId)),
+                "connected" to isDeviceConnected(deviceId),
+                "address" to connectedDevices[deviceId]?.address.orEmpty(),
+                "circleType" to (calibration["circleType"] ?: ""),
+                "centreSet" to (calibration["centreSet"] == true)
+            ) + listOfNotNull(calibration["circleRadius"]?.let { "circleRadius" to it }).toMap()
+        }
+    }
+
+    /**
+     * Disconnect a named station and forget its role and driver
+     */
+    fun removeEdmStation(deviceId: String): Boolean {
+        val disconnected = disconnectDevice(deviceId)
+        connectionProfileStore.setEdmStationRole(deviceId, null)
+        edmStationDrivers.remove(deviceId)
+        return disconnected
+    }
+
+    /**
+     * Move a connected device to another role of the same kind without reconnecting, e.g. a
+     * station carried from the discus circle to the javelin arc. The port, socket or link,
+     * driver, line settings, prism constant and auto-reconnect setting go with it. Both roles'
+     * calibration, setup heights and orientation reference are reset, as they describe where a
+     * station stood rather than the instrument now in the role.
+     */
+    suspend fun reassignDevice(fromDeviceType: String, toDeviceType: String): Map<String, Any> {
+        val connection = connectedDevices[fromDeviceType]
+        val sameKind = (isEdmDeviceType(fromDeviceType) && isEdmDeviceType(toDeviceType)) ||
+            (isScoreboardType(fromDeviceType) && isScoreboardType(toDeviceType))
+        val refusal = when {
+            fromDeviceType == toDeviceType -> "$fromDeviceType is already in that role"
+            connection == null || !connection.isConnected -> "$fromDeviceType is not connected"
+            !sameKind -> "$fromDeviceType and $toDeviceType are different kinds of device"
+            connectedDevices[toDeviceType]?.isConnected == true -> "$toDeviceType already has a device; disconnect it first"
+            measurementRequests.active(fromDeviceType) != null -> "A measurement is in progress on $fromDeviceType"
+            isTracking(fromDeviceType) -> "Stop tracking on $fromDeviceType before reassigning it"
+            else -> null
+        }
+        if (refusal != null || connection == null) {
+            return mapOf("success" to false, "error" to (refusal ?: "$fromDeviceType is not connected"), "deviceType" to fromDeviceType)
+        }
+
+        if (connection.connectionType in NETWORK_MODULE_TRANSPORTS &&
+            !networkDeviceModule.reassign("${fromDeviceType}_network", "${toDeviceType}_network")
+        ) {
+            return mapOf("success" to false, "error" to "$fromDeviceType has no open link to move", "deviceType" to fromDeviceType)
+        }
+        activeSerialPorts.remove(fromDeviceType)?.let { activeSerialPorts[toDeviceType] = it }
+        activeBluetoothLinks.remove(fromDeviceType)?.let { activeBluetoothLinks[toDeviceType] = it }
+        passthroughLinks.remove(fromDeviceType)?.let { passthroughLinks[toDeviceType] = it }
+        connectedDevices.remove(fromDeviceType)
+        connectedDevices[toDeviceType] = connection.copy(deviceType = toDeviceType)
+
+        // The instrument and its settings move with the device
+        if (isEdmDeviceType(fromDeviceType)) {
+            val spec = edmSpecFor(fromDeviceType)
+            edmStationDrivers.remove(fromDeviceType)
+            if (toDeviceType == "edm") setSelectedEDMDevice(spec) else edmStationDrivers[toDeviceType] = spec
+        }
+        measurementModes.remove(fromDeviceType)?.let { measurementModes[toDeviceType] = it }
+        deviceAtmospheres.remove(fromDeviceType)?.let { deviceAtmospheres[toDeviceType] = it }
+        if (standbyDevices.remove(fromDeviceType)) standbyDevices.add(toDeviceType)
+        // Zero included, so a constant left on the target role from another instrument does not survive
+        prismConstantStore.save(toDeviceType, prismConstantStore.load(fromDeviceType))
+        connectionProfileStore.getSerialConfig(fromDeviceType)?.let { connectionProfileStore.saveSerialConfig(toDeviceType, it) }
+        connectionProfileStore.setAutoReconnect(toDeviceType, connectionProfileStore.isAutoReconnect(fromDeviceType))
+        connectionProfileStore.setAutoReconnect(fromDeviceType, true)
+        connectionProfileStore.getLastDeviceSet().find { it.deviceType == fromDeviceType }?.let { profile ->
+            connectionProfileStore.forgetLastDevice(fromDeviceType)
+            connectionProfileStore.rememberLastDevice(profile.copy(name = "Last $toDeviceType", deviceType = toDeviceType))
+        }
+        readTimeouts.reset(fromDeviceType)
+        trackingBuffers.remove(fromDeviceType)
+
+        // What was measured from the old position no longer holds, and whatever the target
+        // role held was set up for a different instrument
+        if (isEdmDeviceType(fromDeviceType)) {
+            listOf(fromDeviceType, toDeviceType).forEach { role ->
+                edmInterface.resetCalibration(role)
+                setupHeightStore.save(role, SetupHeights())
+                orientationReferences.clear(role)
+            }
+            if (fromDeviceType != "edm") {
+                val roles = connectionProfileStore.getEdmStationRoles()
+                connectionProfileStore.setEdmStationRole(fromDeviceType, null)
+                if (toDeviceType != "edm" && toDeviceType !in roles) {
+                    connectionProfileStore.setEdmStationRole(toDeviceType, toDeviceType.removePrefix(EDM_STATION_PREFIX))
+                }
+            }
+        }
+
+        Log.d(TAG, "Reassigned ${connection.address} from $fromDeviceType to $toDeviceType")
+        emitConnectionEvent(ConnectionEventType.DISCONNECTED, fromDeviceType, connection.address, "Reassigned to $toDeviceType")
+        emitConnectionEvent(ConnectionEventType.CONNECTED, toDeviceType, connection.address, "Reassigned from $fromDeviceType")
+        return mapOf(
+            "success" to true,
+            "fromDeviceType" to fromDeviceType,
+            "deviceType" to toDeviceType,
+            "connectionType" to connection.connectionType,
+            "address" to connection.address
+        )
+    }
+
     /**
      * Connect to network device (wind gauge or scoreboard)
      * Uses NetworkDeviceModule for TCP/IP communication
      */
-    suspend fun connectNetworkDevice(deviceType: String, address: String, port: Int): Map<String, Any> {
+    suspend fun connectNetworkDevice(
+        deviceType: String,
+        address: String,
+        port: Int,
+        protocolType: String? = null
+    ): Map<String, Any> = CrashReporter.guardResult("connectNetworkDevice") {
+        connectNetworkDeviceGuarded(deviceType, address, port, protocolType)
+    }
+
+    private suspend fun connectNetworkDeviceGuarded(
+        deviceType: String,
+        address: String,
+        port: Int,
+        protocolType: String?
+    ): Map<String, Any> {
         return withContext(Dispatchers.IO) {
             Log.d(TAG, "Connecting to network device: $deviceType at $address:$port")
 
             try {
-                // Select appropriate protocol based on device type
-                val protocol: DeviceProtocol = when (deviceType.lowercase()) {
-                    "wind" -> WindGaugeProtocol(WindGaugeProtocol.WindGaugeType.GENERIC)
-                    "scoreboard" -> ScoreboardProtocol(ScoreboardProtocol.ScoreboardType.GENERIC)
-                    "scoreboard_daktronics", "daktronics" -> DaktronicsScoreboardProtocol()
-                    else -> {
-                        Log.e(TAG, "Unknown device type: $deviceType")
-                        return@withContext mapOf(
-                            "success" to false,
-                            "error" to "Unknown device type: $deviceType",
-                            "deviceType" to deviceType
-                        )
-                    }
+                val protocol = deviceProtocolFor(deviceType, protocolType)
+                if (protocol == null) {
+                    Log.e(TAG, "Unknown device type: $deviceType")
+                    return@withContext mapOf(
+                        "success" to false,
+                        "error" to "Unknown device type: $deviceType",
+                        "deviceType" to deviceType
+                    )
                 }
 
                 // Connect using NetworkDeviceModule
@@ -306,7 +729,31 @@ class EDMModule(private val context: Context) {
                 )
                 connectedDevices[deviceType] = connection
 
+                connectionProfileStore.rememberLastDevice(
+                    ConnectionProfile(
+                        name = "Last $deviceType",
+                        deviceType = deviceType,
+                        transport = "network",
+                        address = address,
+                        port = port,
+                        driver = protocolType,
+                        lastUsed = SessionSources.now()
+                    )
+                )
+
                 Log.d(TAG, "Network device connected: ${result.connectionInfo}")
+                emitConnectionEvent(ConnectionEventType.CONNECTED, deviceType, "$address:$port", result.connectionInfo.orEmpty())
+
+                if (deviceType.lowercase() == "wind") {
+                    startWindListener(deviceId, protocolType ?: WindGaugeProtocol.WindGaugeType.GENERIC.name)
+                }
+
+                // Deliver scoreboard messages queued while the board was unreachable
+                if (isScoreboardType(deviceType)) {
+                    moduleScope.launch {
+                        replayScoreboardOutbox()
+                    }
+                }
 
                 mapOf(
                     "success" to true,
@@ -328,66 +775,1156 @@ class EDMModule(private val context: Context) {
     }
     
     /**
-     * Set the selected EDM device type
+     * Select the protocol for a wind gauge or scoreboard (and optional protocol variant)
      */
-    fun setSelectedEDMDevice(deviceSpec: EDMDeviceSpec) {
-        selectedEDMDevice = deviceSpec
-        Log.d(TAG, "Selected EDM device: ${deviceSpec.displayName}")
+    private fun deviceProtocolFor(deviceType: String, protocolType: String?): DeviceProtocol? {
+        return when (deviceType.lowercase()) {
+            "wind" -> WindGaugeProtocol(
+                WindGaugeProtocol.WindGaugeType.values().find { it.name == protocolType }
+                    ?: WindGaugeProtocol.WindGaugeType.GENERIC
+            )
+            "scoreboard" -> ScoreboardProtocol(
+                ScoreboardProtocol.ScoreboardType.values().find { it.name == protocolType }
+                    ?: ScoreboardProtocol.ScoreboardType.GENERIC
+            )
+            "scoreboard_daktronics", "daktronics" -> DaktronicsScoreboardProtocol()
+            else -> when {
+                !deviceType.startsWith(SCOREBOARD_PREFIX) -> null
+                protocolType == "DAKTRONICS" -> DaktronicsScoreboardProtocol()
+                else -> ScoreboardProtocol(
+                    ScoreboardProtocol.ScoreboardType.values().find { it.name == protocolType }
+                        ?: ScoreboardProtocol.ScoreboardType.GENERIC
+                )
+            }
+        }
     }
-    
+
     /**
-     * Get the currently selected EDM device
+     * Connect to a device behind a Bluetooth serial (SPP) adapter. The device must already be
+     * paired. driver is the EDM registry key for an EDM, or the protocol variant for a wind
+     * gauge or scoreboard, as for a network device.
      */
-    fun getSelectedEDMDevice(): EDMDeviceSpec {
-        return selectedEDMDevice
+    suspend fun connectBluetoothDevice(
+        deviceType: String,
+        macAddress: String,
+        driver: String? = null
+    ): Map<String, Any> = CrashReporter.guardResult("connectBluetoothDevice") {
+        connectBluetoothDeviceGuarded(deviceType, macAddress, driver)
     }
-    
+
+    private suspend fun connectBluetoothDeviceGuarded(
+        deviceType: String,
+        macAddress: String,
+        driver: String?
+    ): Map<String, Any> = connectLinkDevice(deviceType, macAddress, driver, "bluetooth", "Bluetooth") {
+        bluetoothSerialModule.connectLink(macAddress)
+    }
+
     /**
-     * Get single EDM reading for distance measurement (no tolerance checking)
-     * Uses device translator to communicate with actual EDM device, then calls Go Mobile for calculations
+     * Connect to a Bluetooth Low Energy device by subscribing to a notifying characteristic.
+     * UUIDs may be full or 16-bit short forms. Notifications feed the same wind buffer and
+     * EDM read pipeline as a serial channel; commands, where the device takes any, are
+     * written to the same characteristic. Wind gauges are listened to passively, since most
+     * BLE gauges push readings rather than answer polls.
      */
-    suspend fun getSingleEDMReading(deviceType: String): EDMReading {
+    suspend fun connectBleDevice(
+        deviceType: String,
+        deviceAddress: String,
+        serviceUuid: String,
+        characteristicUuid: String,
+        driver: String? = null
+    ): Map<String, Any> = CrashReporter.guardResult("connectBleDevice") {
+        connectLinkDevice(
+            deviceType, deviceAddress, driver, "ble", "Bluetooth LE",
+            bleService = serviceUuid,
+            bleCharacteristic = characteristicUuid
+        ) {
+            bleGattModule.connectLink(deviceAddress, serviceUuid, characteristicUuid)
+        }
+    }
+
+    /**
+     * Register a USB device whose port is driven by the host rather than opened here. The
+     * host writes out whatever getPendingUSBWrite returns and hands every byte it reads to
+     * submitUSBData; reads, averaging and tolerance checks then run as for any other port.
+     */
+    suspend fun connectUSBPassthrough(
+        deviceType: String,
+        deviceName: String,
+        driver: String? = null
+    ): Map<String, Any> = CrashReporter.guardResult("connectUSBPassthrough") {
+        connectLinkDevice(deviceType, deviceName, driver, "usb_passthrough", "USB passthrough") {
+            // Reopened after an idle close, the new link takes over from the old
+            PassthroughLink(deviceName).also { passthroughLinks[deviceType] = it }
+        }
+    }
+
+    /**
+     * Connect to a device on an Ethernet serial server that speaks Telnet with RFC 2217
+     * rather than raw TCP. The server's port is set to serialConfig, if given, so it need
+     * not be configured to match the instrument; otherwise its own settings stand. driver
+     * is as for a network or Bluetooth device.
+     */
+    suspend fun connectRFC2217Device(
+        deviceType: String,
+        address: String,
+        port: Int,
+        driver: String? = null,
+        serialConfig: SerialPortConfig? = null
+    ): Map<String, Any> = CrashReporter.guardResult("connectRFC2217Device") {
+        connectLinkDevice(
+            deviceType, address, driver, "rfc2217", "RFC 2217",
+            port = port,
+            serialConfig = serialConfig
+        ) {
+            Rfc2217Link.open(address, port, serialConfig)
+        }
+    }
+
+    /**
+     * Bytes the host read from a passthrough device. False if the device is not connected
+     * as one.
+     */
+    fun submitUSBData(deviceType: String, data: ByteArray): Boolean {
+        val link = passthroughLinks[deviceType] ?: return false
+        link.submit(data)
+        return true
+    }
+
+    /**
+     * Bytes waiting to go to a passthrough device, emptied by the call
+     */
+    fun getPendingUSBWrite(deviceType: String): ByteArray =
+        passthroughLinks[deviceType]?.takePendingWrite() ?: ByteArray(0)
+
+    /**
+     * Stand in a simulated USB EDM behind the passthrough link, so the whole USB read path
+     * can be exercised on an emulator. Development only: the device is not a real instrument.
+     */
+    suspend fun startUsbSimulation(): Map<String, Any> = usbSimulator.start()
+
+    fun stopUsbSimulation() = usbSimulator.stop()
+
+    fun isUsbSimulationRunning(): Boolean = usbSimulator.isRunning()
+
+    /**
+     * Run wind streaming, scoreboard output and back-to-back measurements together on the
+     * USB simulator and report each one's latencies against its budget. Development only,
+     * like the simulator it runs on; refused while a real EDM is connected.
+     */
+    suspend fun runDemoStressScenario(config: StressScenarioConfig = StressScenarioConfig()): Map<String, Any> {
+        if (demoStress?.isRunning == true) {
+            return mapOf("success" to false, "error" to "A stress run is already in progress")
+        }
+        if (isTracking("edm")) {
+            return mapOf("success" to false, "error" to "Stop tracking before a stress run")
+        }
+        if (!usbSimulator.isRunning() && connectedDevices["edm"]?.isConnected == true) {
+            return mapOf("success" to false, "error" to "A real EDM is connected; disconnect it to run on the simulator")
+        }
+
+        val startedSimulator = !usbSimulator.isRunning()
+        if (startedSimulator) {
+            val started = startUsbSimulation()
+            if (started["success"] != true) return started
+        }
+        val windListener = WindGaugeListener(networkDeviceModule, "wind_demo", "demo")
+        val scenario = DemoStressScenario(this, windListener)
+        demoStress = scenario
+        return try {
+            scenario.run(config)
+        } finally {
+            windListener.shutdown()
+            if (startedSimulator) stopUsbSimulation()
+        }
+    }
+
+    fun stopDemoStressScenario() {
+        demoStress?.stop()
+    }
+
+    /**
+     * Station position, instrument height and noise for the demo and the USB simulator
+     */
+    fun setDemoGeometry(geometry: DemoGeometry): Map<String, Any> {
+        demoGeometryStore.validate(geometry)?.let { return mapOf("success" to false, "error" to it) }
+        demoGeometryStore.save(geometry)
+        Log.d(TAG, "Demo geometry set: $geometry")
+        return mapOf("success" to true) + geometry.toMap()
+    }
+
+    fun getDemoGeometry(): DemoGeometry = demoGeometryStore.load()
+
+    /**
+     * Shared connect for stream links (Bluetooth SPP, BLE, USB passthrough and RFC 2217). An EDM keeps its link for
+     * request/response reads; wind gauges and scoreboards run their protocols over it
+     * through the network module.
+     */
+    private suspend fun connectLinkDevice(
+        deviceType: String,
+        address: String,
+        driver: String?,
+        transport: String,
+        transportLabel: String,
+        bleService: String? = null,
+        bleCharacteristic: String? = null,
+        port: Int = 0,
+        serialConfig: SerialPortConfig? = null,
+        openLink: () -> StreamLink
+    ): Map<String, Any> {
+        selectEDMDriver(deviceType, driver)?.let { return it }
+
         return withContext(Dispatchers.IO) {
-            Log.d(TAG, "Getting single EDM reading with ${selectedEDMDevice.displayName}: $deviceType")
-            
-            val connection = connectedDevices[deviceType]
-            Log.d(TAG, "Connection state: $connection")
-            
-            try {
-                // Check if this is a USB device that needs Android-side communication
-                if (connection?.connectionType == "usb") {
-                    return@withContext performUSBEDMReading(deviceType, single = true)
-                }
-                
-                // CRITICAL: Verify device is still physically connected
-                val usbManager = context.getSystemService(Context.USB_SERVICE) as UsbManager
-                val deviceList = usbManager.deviceList
-                val deviceStillConnected = deviceList.values.any { device ->
-                    EDMDeviceRegistry.matchUsbDevice(device.vendorId, device.productId) != null
+            Log.d(TAG, "Connecting to $transportLabel device: $deviceType at $address")
+
+            val deviceId = "${deviceType}_network"
+            val connectionInfo = if (isEdmDeviceType(deviceType)) {
+                val link = try {
+                    openLink()
+                } catch (e: IOException) {
+                    return@withContext mapOf(
+                        "success" to false,
+                        "error" to (e.message ?: "Could not connect to $address"),
+                        "deviceType" to deviceType
+                    )
                 }
-                
-                if (!deviceStillConnected) {
-                    Log.e(TAG, "EDM device no longer physically connected")
-                    return@withContext EDMReading(
-                        success = false,
-                        error = "EDM device disconnected - no device found"
+                activeBluetoothLinks.put(deviceType, link)?.close()
+                "Connected to ${edmSpecFor(deviceType).displayName} via $transportLabel at $address"
+            } else {
+                val protocol = deviceProtocolFor(deviceType, driver)
+                    ?: return@withContext mapOf(
+                        "success" to false,
+                        "error" to "Unknown device type: $deviceType",
+                        "deviceType" to deviceType
+                    )
+                val result = networkDeviceModule.connect(deviceId, address, 0, protocol, openLink)
+                if (!result.success) {
+                    Log.e(TAG, "$transportLabel connection failed: ${result.error}")
+                    return@withContext mapOf(
+                        "success" to false,
+                        "error" to result.error.orEmpty(),
+                        "deviceType" to deviceType
                     )
                 }
-                
-                // For serial/network connections, use our native serial communication
-                Log.d(TAG, "Using native serial communication for single EDM reading")
-                return@withContext performSerialEDMReading(deviceType, singleMode = true)
-                
-            } catch (e: Exception) {
-                Log.e(TAG, "Single EDM reading failed", e)
-                EDMReading(
-                    success = false,
-                    error = e.message ?: "Could not find prism. Check your aim and remeasure. If EDM displays \"STOP\" then press F1 to reset"
+                result.connectionInfo.orEmpty()
+            }
+
+            connectedDevices[deviceType] = DeviceConnection(
+                deviceType = deviceType,
+                connectionType = transport,
+                address = address,
+                port = port,
+                isConnected = true
+            )
+
+            connectionProfileStore.rememberLastDevice(
+                ConnectionProfile(
+                    name = "Last $deviceType",
+                    deviceType = deviceType,
+                    transport = transport,
+                    address = address,
+                    port = port,
+                    serialConfig = serialConfig,
+                    driver = if (isEdmDeviceType(deviceType)) EDMDeviceRegistry.keyFor(edmSpecFor(deviceType)) else driver,
+                    bleService = bleService,
+                    bleCharacteristic = bleCharacteristic,
+                    lastUsed = SessionSources.now()
                 )
+            )
+
+            Log.d(TAG, "$transportLabel device connected: $connectionInfo")
+            emitConnectionEvent(ConnectionEventType.CONNECTED, deviceType, address, connectionInfo)
+
+            if (deviceType.lowercase() == "wind") {
+                startWindListener(deviceId, driver ?: WindGaugeProtocol.WindGaugeType.GENERIC.name)
             }
+
+            if (isScoreboardType(deviceType)) {
+                moduleScope.launch {
+                    replayScoreboardOutbox()
+                }
+            }
+
+            mapOf(
+                "success" to true,
+                "message" to "Connected to $deviceType via $transportLabel at $address",
+                "deviceType" to deviceType,
+                "connectionType" to transport,
+                "deviceId" to deviceId
+            )
         }
     }
-    
+
+    /**
+     * Devices paired with this tablet, as a JSON array of {name, address, majorDeviceClass}
+     */
+    fun listBluetoothDevices(): String {
+        val devices = JSONArray()
+        bluetoothSerialModule.getPairedDevices().forEach { device ->
+            devices.put(JSONObject().apply {
+                put("name", device.name)
+                put("address", device.address)
+                device.majorDeviceClass?.let { put("majorDeviceClass", it) }
+            })
+        }
+        return devices.toString()
+    }
+
+    /**
+     * Browse the local network for EDM bridges and PolyField devices advertising over mDNS.
+     * Each candidate has its host, port and, where advertised, device type and driver,
+     * ready for connectNetworkDevice.
+     */
+    suspend fun discoverNetworkDevices(timeoutSeconds: Int = 5): Map<String, Any> =
+        CrashReporter.guardResult("discoverNetworkDevices") {
+            val devices = networkDiscovery.discover(timeoutSeconds)
+            mapOf(
+                "success" to true,
+                "devices" to devices.map { it.toMap() },
+                "count" to devices.size
+            )
+        }
+
+    /**
+     * Connect using a saved profile
+     * Returns the same result map as the underlying connect call, plus the profile name
+     */
+    suspend fun connectProfile(name: String): Map<String, Any> {
+        val profile = connectionProfileStore.getProfile(name)
+            ?: return mapOf(
+                "success" to false,
+                "error" to "Unknown connection profile: $name",
+                "profile" to name
+            )
+
+        Log.d(TAG, "Connecting profile '$name': ${profile.deviceType} via ${profile.transport}")
+
+        val result = connectFromProfile(profile)
+        if (result["success"] == true) {
+            connectionProfileStore.markUsed(name)
+        }
+        return result + ("profile" to name)
+    }
+
+    /**
+     * Restore the last known device set (EDM, wind gauge, scoreboard) after a restart
+     * @return Overall success plus one outcome entry per device
+     */
+    suspend fun reconnectAll(): Map<String, Any> {
+        val lastDevices = connectionProfileStore.getLastDeviceSet()
+        if (lastDevices.isEmpty()) {
+            return mapOf(
+                "success" to true,
+                "message" to "No previous devices to restore",
+                "results" to emptyList<Map<String, Any>>()
+            )
+        }
+
+        Log.d(TAG, "Reconnecting ${lastDevices.size} device(s): ${lastDevices.map { it.deviceType }}")
+
+        val results = lastDevices.map { profile ->
+            val outcome = if (isDeviceConnected(profile.deviceType)) {
+                mapOf("success" to true, "message" to "Already connected")
+            } else {
+                try {
+                    connectFromProfile(profile)
+                } catch (e: Exception) {
+                    mapOf("success" to false, "error" to (e.message ?: "Reconnect failed"))
+                }
+            }
+
+            val success = outcome["success"] == true
+            Log.d(TAG, "Reconnect ${profile.deviceType}: ${if (success) "ok" else outcome["error"]}")
+            mapOf(
+                "deviceType" to profile.deviceType,
+                "transport" to profile.transport,
+                "address" to profile.address,
+                "success" to success,
+                "message" to (outcome["message"] ?: outcome["error"] ?: ""),
+                "connectionType" to (outcome["connectionType"] ?: profile.transport),
+                "edmDevice" to (outcome["edmDevice"] ?: "")
+            )
+        }
+
+        return mapOf(
+            "success" to results.all { it["success"] == true },
+            "results" to results
+        )
+    }
+
+    fun getLastDeviceSet(): List<ConnectionProfile> = connectionProfileStore.getLastDeviceSet()
+
+    private suspend fun connectFromProfile(profile: ConnectionProfile): Map<String, Any> {
+        return when (profile.transport) {
+            "usb", "serial" -> {
+                val address = resolveUsbAddress(profile)
+                if (address == null) {
+                    mapOf(
+                        "success" to false,
+                        "error" to "Device for profile '${profile.name}' is not plugged in",
+                        "deviceType" to profile.deviceType
+                    )
+                } else {
+                    connectUsbDevice(profile.deviceType, address, profile.serialConfig, profile.driver)
+                }
+            }
+            "bluetooth" -> connectBluetoothDevice(profile.deviceType, profile.address, profile.driver)
+            "ble" -> {
+                val service = profile.bleService
+                val characteristic = profile.bleCharacteristic
+                if (service == null || characteristic == null) {
+                    mapOf(
+                        "success" to false,
+                        "error" to "Profile '${profile.name}' has no BLE service or characteristic",
+                        "deviceType" to profile.deviceType
+                    )
+                } else {
+                    connectBleDevice(profile.deviceType, profile.address, service, characteristic, profile.driver)
+                }
+            }
+            "usb_passthrough" -> connectUSBPassthrough(profile.deviceType, profile.address, profile.driver)
+            "rfc2217" -> connectRFC2217Device(profile.deviceType, profile.address, profile.port, profile.driver, profile.serialConfig)
+            "network" -> {
+                selectEDMDriver(profile.deviceType, profile.driver)
+                    ?: connectNetworkDevice(profile.deviceType, profile.address, profile.port, profile.driver)
+            }
+            else -> mapOf(
+                "success" to false,
+                "error" to "Unsupported transport: ${profile.transport}",
+                "deviceType" to profile.deviceType
+            )
+        }
+    }
+
+    /**
+     * Select the EDM protocol driver (registry key, e.g. "LEICA_GSI") before its port is
+     * opened. Returns an error result for an unknown driver, null to carry on connecting.
+     */
+    private fun selectEDMDriver(deviceType: String, driver: String?): Map<String, Any>? {
+        if (!isEdmDeviceType(deviceType) || driver == null) return null
+        val spec = EDMDeviceRegistry.findDeviceByKey(driver)
+            ?: return mapOf(
+                "success" to false,
+                "error" to "Unknown EDM driver: $driver",
+                "deviceType" to deviceType
+            )
+        if (deviceType == "edm") {
+            setSelectedEDMDevice(spec)
+        } else {
+            edmStationDrivers[deviceType] = spec
+            Log.d(TAG, "$deviceType driver: ${spec.displayName}")
+        }
+        // Line settings kept from an earlier driver would override the chosen one's on reconnect
+        connectionProfileStore.clearSerialConfig(deviceType)
+        return null
+    }
+
+    fun saveConnectionProfile(profile: ConnectionProfile) {
+        connectionProfileStore.saveProfile(profile)
+    }
+
+    fun deleteConnectionProfile(name: String): Boolean {
+        return connectionProfileStore.deleteProfile(name)
+    }
+
+    fun getConnectionProfiles(): List<ConnectionProfile> {
+        return connectionProfileStore.getProfiles()
+    }
+
+    /**
+     * Host notification that a USB device was plugged in.
+     * If it matches a role from the last device set that is not currently connected,
+     * the role is bound to it again so the session resumes without operator action.
+     */
+    suspend fun notifyUsbAttached(info: UsbDeviceInfo): Map<String, Any> =
+        CrashReporter.guardResult("notifyUsbAttached") { notifyUsbAttachedGuarded(info) }
+
+    private suspend fun notifyUsbAttachedGuarded(info: UsbDeviceInfo): Map<String, Any> {
+        Log.d(TAG, "USB attached: ${info.deviceName} (VID:${"%04X".format(info.vendorId)} PID:${"%04X".format(info.productId)})")
+        emitConnectionEvent(ConnectionEventType.ATTACHED, null, info.deviceName, info.productName ?: "USB device attached")
+
+        // An explicit adapter-to-role mapping wins over whatever the adapter was last used for
+        val profile = connectionProfileStore.findUsbRoleMapping(info)
+            ?.takeIf { !isDeviceConnected(it.deviceType) }
+            ?.let { mapping ->
+                ConnectionProfile(
+                    name = "USB ${mapping.deviceType}",
+                    deviceType = mapping.deviceType,
+                    transport = "usb",
+                    address = info.deviceName,
+                    serialConfig = mapping.serialConfig,
+                    driver = mapping.driver,
+                    vendorId = mapping.vendorId,
+                    productId = mapping.productId
+                )
+            }
+            ?: connectionProfileStore.getLastDeviceSet().find { candidate ->
+                candidate.transport != "network" &&
+                    candidate.vendorId == info.vendorId &&
+                    candidate.productId == info.productId &&
+                    !isDeviceConnected(candidate.deviceType)
+            }
+            ?: return mapOf("success" to true, "bound" to false, "message" to "No role known for this device")
+
+        val usbManager = context.getSystemService(Context.USB_SERVICE) as UsbManager
+        val usbDevice = usbManager.deviceList.values.find { it.deviceName == info.deviceName }
+        if (usbDevice != null && !usbManager.hasPermission(usbDevice)) {
+            emitConnectionEvent(ConnectionEventType.PERMISSION_REQUIRED, profile.deviceType, info.deviceName, "USB permission required")
+            return mapOf(
+                "success" to false,
+                "bound" to false,
+                "deviceType" to profile.deviceType,
+                "needsPermission" to true,
+                "error" to SerialErrorCode.PERMISSION_DENIED.userMessage,
+                "errorCode" to SerialErrorCode.PERMISSION_DENIED.name
+            )
+        }
+
+        val result = connectFromProfile(profile.copy(address = info.deviceName))
+        return if (result["success"] == true) {
+            emitConnectionEvent(ConnectionEventType.RESUMED, profile.deviceType, info.deviceName, "${profile.deviceType} reconnected")
+            result + mapOf("bound" to true, "deviceType" to profile.deviceType)
+        } else {
+            emitConnectionEvent(
+                ConnectionEventType.CONNECT_FAILED,
+                profile.deviceType,
+                info.deviceName,
+                result["error"] as? String ?: "Connection failed"
+            )
+            result + mapOf("bound" to false, "deviceType" to profile.deviceType)
+        }
+    }
+
+    /**
+     * Host notification that a USB device was unplugged.
+     * Drops any connection bound to it so callers see a disconnect rather than a dead port.
+     * The roles stay in the last device set so a replug can resume them.
+     */
+    fun notifyUsbDetached(info: UsbDeviceInfo): Map<String, Any> {
+        Log.d(TAG, "USB detached: ${info.deviceName}")
+        emitConnectionEvent(ConnectionEventType.DETACHED, null, info.deviceName, info.productName ?: "USB device detached")
+
+        val lostRoles = connectedDevices.values
+            .filter { it.address == info.deviceName && it.connectionType != "network" }
+            .map { it.deviceType }
+
+        for (deviceType in lostRoles) {
+            activeSerialPorts.remove(deviceType)?.let { serialCommunicationModule.closeSerialPort(it) }
+            connectedDevices.remove(deviceType)
+            Log.w(TAG, "$deviceType lost its USB device ${info.deviceName}")
+            emitConnectionEvent(
+                ConnectionEventType.DISCONNECTED,
+                deviceType,
+                info.deviceName,
+                SerialErrorCode.DEVICE_UNPLUGGED.userMessage
+            )
+        }
+
+        return mapOf("success" to true, "disconnected" to lostRoles)
+    }
+
+    fun getUsbRoleMappings(): List<UsbRoleMapping> = connectionProfileStore.getUsbRoleMappings()
+
+    fun saveUsbRoleMapping(mapping: UsbRoleMapping) = connectionProfileStore.saveUsbRoleMapping(mapping)
+
+    fun removeUsbRoleMapping(mapping: UsbRoleMapping): Boolean = connectionProfileStore.removeUsbRoleMapping(mapping)
+
+    /**
+     * Bind any already-attached adapters that have a role mapping, e.g. after the mapping is first saved
+     */
+    suspend fun applyUsbRoleMappings(): List<Map<String, Any>> {
+        val usbManager = context.getSystemService(Context.USB_SERVICE) as UsbManager
+        return usbManager.deviceList.values
+            .map { UsbDeviceInfo.from(it) }
+            .filter { info -> connectionProfileStore.findUsbRoleMapping(info) != null }
+            .map { info -> notifyUsbAttached(info) }
+    }
+
+    private fun emitConnectionEvent(type: ConnectionEventType, deviceType: String?, address: String, message: String) {
+        if (deviceType != null) {
+            when (type) {
+                ConnectionEventType.CONNECTED -> deviceHealth.onConnected(deviceType)
+                ConnectionEventType.DISCONNECTED -> deviceHealth.onDisconnected(deviceType)
+                else -> {}
+            }
+        }
+        val event = DeviceConnectionEvent(type, deviceType, address, message)
+        synchronized(recentConnectionEvents) {
+            recentConnectionEvents.addLast(event)
+            while (recentConnectionEvents.size > MAX_RECENT_CONNECTION_EVENTS) recentConnectionEvents.removeFirst()
+        }
+        _connectionEvents.tryEmit(event)
+    }
+
+    /**
+     * Connection state changes after a time (epoch ms), oldest first; all recent ones
+     * when since is null. Only the last 100 are kept.
+     */
+    fun getConnectionEvents(since: Long? = null): List<DeviceConnectionEvent> =
+        synchronized(recentConnectionEvents) {
+            recentConnectionEvents.filter { since == null || it.timestamp > since }
+        }
+
+    /**
+     * Turn automatic reconnection of a dropped device on or off. Turning it off stops any
+     * retries in progress.
+     */
+    fun setAutoReconnect(deviceType: String, enabled: Boolean) {
+        connectionProfileStore.setAutoReconnect(deviceType, enabled)
+        if (!enabled) reconnectSupervisor.cancel(deviceType)
+        Log.d(TAG, "Auto-reconnect for $deviceType ${if (enabled) "on" else "off"}")
+    }
+
+    fun isAutoReconnect(deviceType: String): Boolean = connectionProfileStore.isAutoReconnect(deviceType)
+
+    fun isReconnecting(deviceType: String): Boolean = reconnectSupervisor.isReconnecting(deviceType)
+
+    /**
+     * Whether a connected device's channel is still up. Channels that cannot tell, such
+     * as a host-driven USB passthrough, count as up.
+     */
+    private fun isConnectionAlive(deviceType: String): Boolean {
+        val connection = connectedDevices[deviceType] ?: return true
+        return when {
+            connection.connectionType == "usb_passthrough" -> true
+            connection.connectionType == "serial" -> activeSerialPorts[deviceType]?.isOpen ?: true
+            isEdmDeviceType(deviceType) && connection.connectionType in STREAM_LINK_TRANSPORTS ->
+                activeBluetoothLinks[deviceType]?.isConnected == true
+            connection.connectionType in NETWORK_MODULE_TRANSPORTS ->
+                networkDeviceModule.isConnected("${deviceType}_network")
+            else -> true
+        }
+    }
+
+    /**
+     * Link health of a device as JSON: status, latency, last good exchange, error counts
+     * and uptime
+     */
+    fun getDeviceHealth(deviceType: String): String = deviceHealth.getHealth(deviceType).toJson().toString()
+
+    /**
+     * Start logging every byte sent to and received from a device, with timestamps.
+     * Replaces any capture already running for it.
+     */
+    fun startDeviceCapture(deviceType: String): Map<String, Any> {
+        val driver = if (isEdmDeviceType(deviceType)) {
+            EDMDeviceRegistry.keyFor(edmSpecFor(deviceType))
+        } else {
+            connectionProfileStore.getLastDeviceSet().find { it.deviceType == deviceType }?.driver
+        }
+        trafficCapture.start(deviceType, driver)
+        Log.d(TAG, "Capturing $deviceType traffic (driver ${driver ?: "default"})")
+        return mapOf("success" to true, "deviceType" to deviceType, "connected" to connectedDevices.containsKey(deviceType))
+    }
+
+    /**
+     * Stop a capture and return it as JSON under "capture", or write it to filePath and
+     * return the path instead
+     */
+    fun stopDeviceCapture(deviceType: String, filePath: String? = null): Map<String, Any> {
+        val capture = trafficCapture.stop(deviceType)
+            ?: return mapOf("success" to false, "error" to "No capture running for $deviceType")
+        val json = TrafficCapture.toJson(capture)
+        val summary = mapOf(
+            "success" to true,
+            "deviceType" to deviceType,
+            "chunks" to capture.chunks.size,
+            "truncated" to capture.truncated
+        )
+        if (filePath == null) return summary + ("capture" to json)
+
+        return try {
+            java.io.File(filePath).writeText(json, Charsets.UTF_8)
+            summary + ("path" to filePath)
+        } catch (e: Exception) {
+            Log.e(TAG, "Could not write capture to $filePath: ${e.message}")
+            mapOf("success" to false, "error" to "Could not write $filePath: ${e.message}", "capture" to json)
+        }
+    }
+
+    /**
+     * Run a capture's received traffic back through the parser as if it had just arrived,
+     * with the driver in use when it was captured. EDM captures report each reading and
+     * the difference between successive ones against the double-read tolerance; wind
+     * captures report each decoded message.
+     */
+    fun replayCapture(deviceType: String, captureJson: String): Map<String, Any> {
+        val capture = try {
+            TrafficCapture.fromJson(captureJson)
+        } catch (e: Exception) {
+            return mapOf("success" to false, "error" to "Invalid capture: ${e.message}")
+        }
+
+        return when {
+            isEdmDeviceType(deviceType) -> replayEdmCapture(deviceType, capture)
+            deviceType == "wind" -> replayWindCapture(capture)
+            else -> mapOf("success" to false, "error" to "Replay is not supported for $deviceType")
+        }
+    }
+
+    private fun replayEdmCapture(deviceType: String, capture: DeviceCapture): Map<String, Any> {
+        val spec = capture.driver?.let { EDMDeviceRegistry.findDeviceByKey(it) } ?: edmSpecFor(deviceType)
+        val translator = EDMDeviceRegistry.createTranslator(spec)
+            ?: return mapOf("success" to false, "error" to "No translator available for ${spec.displayName}")
+        val tolerance = runtimeConfig.doubleReadToleranceMm
+
+        var previousMm: Double? = null
+        val readings = TrafficCapture.exchanges(capture).filter { it.command != null }.map { exchange ->
+            val response = if (spec.framing.type == FramingType.LINE) {
+                String(exchange.response, Charsets.UTF_8).trim()
+            } else {
+                spec.framing.extract(exchange.response)?.toString(Charsets.ISO_8859_1).orEmpty()
+            }
+            val parsed = if (response.isEmpty()) null else translator.parseReply(response)
+            val entry = mutableMapOf<String, Any?>(
+                "sentAt" to exchange.sentAt,
+                "response" to response,
+                "valid" to (parsed?.isValid == true),
+                "error" to when {
+                    parsed == null -> "No response"
+                    !parsed.isValid -> parsed.errorMessage ?: "Invalid response"
+                    else -> null
+                }
+            )
+            if (parsed != null && parsed.isValid) {
+                entry["slopeDistanceMm"] = parsed.slopeDistanceMm
+                entry["verticalAngleDegrees"] = parsed.verticalAngleDegrees
+                entry["horizontalAngleDegrees"] = parsed.horizontalAngleDegrees
+                previousMm?.let { previous ->
+                    val difference = kotlin.math.abs(parsed.slopeDistanceMm - previous)
+                    entry["differenceFromPreviousMm"] = difference
+                    entry["withinTolerance"] = difference <= tolerance
+                }
+                previousMm = parsed.slopeDistanceMm
+            }
+            entry
+        }
+
+        return mapOf(
+            "success" to true,
+            "deviceType" to deviceType,
+            "driver" to spec.displayName,
+            "toleranceMm" to tolerance,
+            "readings" to readings
+        )
+    }
+
+    private fun replayWindCapture(capture: DeviceCapture): Map<String, Any> {
+        val protocol = deviceProtocolFor("wind", capture.driver)
+            ?: return mapOf("success" to false, "error" to "Unknown wind protocol: ${capture.driver}")
+        val chunks: List<CapturedChunk>? = capture.chunks
+        val messages = chunks.orEmpty()
+            .filter { it.direction == TrafficDirection.RECEIVED && !it.drained }
+            .map { chunk ->
+                val raw = String(chunk.bytes(), Charsets.UTF_8)
+                val response = protocol.decodeResponse(raw, DeviceCommand(type = "READ_WIND"))
+                mapOf(
+                    "timestamp" to chunk.timestamp,
+                    "raw" to raw,
+                    "success" to response.success,
+                    "data" to response.data,
+                    "error" to response.error
+                )
+            }
+        return mapOf("success" to true, "deviceType" to "wind", "driver" to (capture.driver ?: "default"), "messages" to messages)
+    }
+
+    private fun captureTap(deviceType: String): TrafficTap? {
+        if (!trafficCapture.isCapturing(deviceType)) return null
+        return { direction, bytes, drained -> trafficCapture.record(deviceType, direction, bytes, drained) }
+    }
+
+    /**
+     * Tap for an EDM exchange: the capture, if running, and the moment the command has gone
+     * out for a measurement request's progress
+     */
+    private fun exchangeTap(deviceType: String): TrafficTap? {
+        val capture = captureTap(deviceType)
+        if (!measurementProgress.containsKey(deviceType)) return capture
+        return { direction, bytes, drained ->
+            capture?.invoke(direction, bytes, drained)
+            if (direction == TrafficDirection.SENT) reportPhase(deviceType, MeasurementPhase.WAITING_FOR_PRISM, after = MeasurementPhase.TRIGGERING)
+        }
+    }
+
+    /**
+     * Start a set centre, edge verification or throw measurement in the background, on the
+     * same per-station calibration the app records throws against.
+     * Returns its requestId to poll with getMeasurementStatus or stop with cancelMeasurement.
+     */
+    fun startMeasurement(
+        deviceType: String,
+        kind: MeasurementKind = MeasurementKind.MEASURE_THROW,
+        circleType: String? = null
+    ): Map<String, Any> {
+        if (kind == MeasurementKind.SET_CENTRE && circleType == null) {
+            return mapOf("success" to false, "error" to "Setting the centre needs a circle type")
+        }
+        measurementRequests.active(deviceType)?.let { active ->
+            return mapOf(
+                "success" to false,
+                "error" to "A measurement is already in progress on $deviceType",
+                "requestId" to active.requestId
+            )
+        }
+        if (isTracking(deviceType)) {
+            return mapOf("success" to false, "error" to "Stop tracking on $deviceType before measuring")
+        }
+        val requestId = measurementRequests.start(deviceType, kind) { report ->
+            lastReportedPhase.remove(deviceType)
+            measurementProgress[deviceType] = report
+            try {
+                val result = when (kind) {
+                    MeasurementKind.SET_CENTRE -> edmInterface.setCentre(deviceType, circleType!!)
+                    MeasurementKind.VERIFY_EDGE -> edmInterface.verifyEdge(deviceType)
+                    MeasurementKind.MEASURE_THROW -> edmInterface.measure(deviceType)
+                }
+                interfaceResultMap(result)
+            } finally {
+                measurementProgress.remove(deviceType, report)
+                lastReportedPhase.remove(deviceType)
+            }
+        }
+        Log.d(TAG, "Started $kind request $requestId on $deviceType")
+        return mapOf("success" to true, "requestId" to requestId, "phase" to MeasurementPhase.QUEUED.name)
+    }
+
+    /**
+     * An EDM interface result as this module's result map
+     */
+    private fun interfaceResultMap(result: Result<Map<String, Any>>): Map<String, Any> =
+        result.getOrElse { error -> EDMInterface.failureMap(error, "Measurement failed") }
+
+    fun getMeasurementStatus(requestId: String): Map<String, Any> =
+        measurementRequests.status(requestId)?.toMap()
+            ?: mapOf("success" to false, "error" to "Unknown measurement request $requestId")
+
+    fun cancelMeasurement(requestId: String): Map<String, Any> {
+        val cancelled = measurementRequests.cancel(requestId)
+        return if (cancelled) {
+            mapOf("success" to true, "requestId" to requestId, "phase" to MeasurementPhase.CANCELLED.name)
+        } else {
+            mapOf("success" to false, "error" to "No unfinished measurement request $requestId")
+        }
+    }
+
+    /**
+     * Keep reading the prism so a grounds crew walking it along a sector line or arc sees
+     * where it is. Each quick read is located against the device's centre and kept in a
+     * ring buffer; tracking stops on stopTracking, a disconnect or a run of failed reads.
+     */
+    suspend fun startTracking(deviceType: String): Map<String, Any> {
+        if (!isDeviceConnected(deviceType)) {
+            return mapOf("success" to false, "error" to "$deviceType is not connected")
+        }
+        if (isTracking(deviceType)) {
+            return mapOf("success" to true, "message" to "Already tracking $deviceType")
+        }
+        measurementRequests.active(deviceType)?.let { active ->
+            return mapOf(
+                "success" to false,
+                "error" to "A measurement is in progress on $deviceType",
+                "requestId" to active.requestId
+            )
+        }
+        if (!edmInterface.isCentreSet(deviceType)) {
+            return mapOf("success" to false, "error" to "Set the centre before tracking")
+        }
+
+        val buffer = TrackingBuffer()
+        trackingBuffers[deviceType] = buffer
+        trackingJobs[deviceType] = moduleScope.launch {
+            while (isActive) {
+                val error = trackOnce(deviceType, buffer)
+                if (error != null && buffer.fail(error) >= MAX_TRACKING_FAILURES) {
+                    Log.w(TAG, "Tracking on $deviceType stopped after $MAX_TRACKING_FAILURES failed reads: $error")
+                    break
+                }
+                delay(TRACKING_INTERVAL_MS)
+            }
+            trackingJobs.remove(deviceType, coroutineContext.job)
+        }
+        Log.d(TAG, "Tracking started on $deviceType")
+        return mapOf("success" to true, "deviceType" to deviceType)
+    }
+
+    /**
+     * One tracking read into the buffer; returns why it failed, or null
+     */
+    private suspend fun trackOnce(deviceType: String, buffer: TrackingBuffer): String? {
+        val reading = edmInterface.measure(deviceType, quickRead = true)
+        if (reading.isFailure) {
+            return reading.exceptionOrNull()?.message ?: "Prism reading failed"
+        }
+        val data = reading.getOrThrow()
+        val throwCoords = data["throwCoordinates"] as? Map<*, *>
+        val x = throwCoords?.get("x") as? Double
+        val y = throwCoords?.get("y") as? Double
+        val circleRadius = data["circleRadius"] as? Double
+        if (x == null || y == null || circleRadius == null) {
+            return "No prism position in reading"
+        }
+        buffer.add(x, y, kotlin.math.hypot(x, y) - circleRadius, data["measuredAt"] as? Long ?: SessionSources.now())
+        return null
+    }
+
+    fun stopTracking(deviceType: String): Map<String, Any> {
+        val job = trackingJobs.remove(deviceType)
+            ?: return mapOf("success" to false, "error" to "Not tracking $deviceType")
+        job.cancel()
+        Log.d(TAG, "Tracking stopped on $deviceType")
+        return mapOf("success" to true, "deviceType" to deviceType)
+    }
+
+    fun isTracking(deviceType: String): Boolean = trackingJobs[deviceType]?.isActive == true
+
+    /**
+     * Most recent tracked position, with whether tracking is still running and the last
+     * read failure, if any
+     */
+    fun getLatestTrackedPosition(deviceType: String): Map<String, Any> {
+        val buffer = trackingBuffers[deviceType]
+            ?: return mapOf("success" to false, "error" to "$deviceType has not been tracked")
+        val latest = buffer.latest()
+        val state = listOfNotNull(buffer.lastError()?.let { "lastError" to it }).toMap() +
+            mapOf("tracking" to isTracking(deviceType), "deviceType" to deviceType)
+        return if (latest == null) {
+            mapOf("success" to false, "error" to "No tracked position yet") + state
+        } else {
+            mapOf("success" to true) + latest.toMap() + state
+        }
+    }
+
+    /**
+     * Tracked positions newer than since, oldest first, e.g. to draw the prism's path
+     */
+    fun getTrackedPositions(deviceType: String, since: Long = 0L): List<TrackedPosition> =
+        trackingBuffers[deviceType]?.since(since) ?: emptyList()
+
+    /**
+     * Tell a measurement request on this device it has reached a phase; with after, only
+     * if it is currently in that phase
+     */
+    private fun reportPhase(deviceType: String, phase: MeasurementPhase, after: MeasurementPhase? = null) {
+        val report = measurementProgress[deviceType] ?: return
+        if (after != null && lastReportedPhase[deviceType] != after) return
+        lastReportedPhase[deviceType] = phase
+        report(phase)
+    }
+
+    /**
+     * Readiness before the first athlete: storage, clocks, every connected device, the
+     * calibration of each EDM and the scoreboard outbox, as one report. calibrations holds
+     * the app's calibration for the stations it has; the rest come from the EDM interface.
+     */
+    suspend fun runSelfDiagnostics(calibrations: Map<String, CalibrationState> = emptyMap()): Map<String, Any> = withContext(Dispatchers.IO) {
+        val now = SessionSources.now()
+        val diagnosticsPrefs = context.getSharedPreferences(DIAGNOSTICS_PREFS, Context.MODE_PRIVATE)
+        val lastRunAt = diagnosticsPrefs.getLong(PREF_LAST_RUN_AT, 0L).takeIf { it > 0L }
+
+        val checks = mutableListOf<DiagnosticCheck>()
+        checks += SelfDiagnostics.storage(context.filesDir)
+        checks += SelfDiagnostics.clock(now, lastRunAt, clockOffsets.getOffsets())
+
+        val devices = connectedDevices.keys.sorted()
+        if (devices.isEmpty()) {
+            checks += DiagnosticCheck("device", "Devices", DiagnosticStatus.WARN, "No devices connected")
+        }
+        devices.forEach { deviceType ->
+            checks += SelfDiagnostics.device(deviceType, isConnectionAlive(deviceType), deviceHealth.getHealth(deviceType))
+        }
+
+        val edms = devices.filter(::isEdmDeviceType).ifEmpty { listOf("edm") }
+        edms.forEach { deviceType ->
+            val calibration = calibrations[deviceType] ?: edmInterface.calibrationState(deviceType)
+            checks += SelfDiagnostics.calibration(deviceType, calibration, now)
+        }
+        checks += SelfDiagnostics.outbox(scoreboardOutbox.getStatus())
+
+        diagnosticsPrefs.edit().putLong(PREF_LAST_RUN_AT, maxOf(now, lastRunAt ?: 0L)).apply()
+        val report = DiagnosticsReport(checks, now)
+        Log.d(TAG, "Self diagnostics: ${report.status}, ${checks.count { it.status != DiagnosticStatus.PASS }} of ${checks.size} checks need attention")
+        report.toMap()
+    }
+
+    /**
+     * Background check: the link must be up, and a device that is not already being read
+     * all the time (as wind gauges are by their listener) is sent its protocol's no-op.
+     * EDMs are never sent anything, since any command could collide with a measurement.
+     */
+    private suspend fun checkDeviceHealth(deviceType: String) {
+        val connection = connectedDevices[deviceType] ?: return
+        if (!isConnectionAlive(deviceType)) {
+            deviceHealth.recordError(deviceType, "Link down")
+            return
+        }
+        val deviceId = "${deviceType}_network"
+        if (connection.connectionType in NETWORK_MODULE_TRANSPORTS && deviceId !in windListeners) {
+            networkDeviceModule.ping(deviceId)
+        }
+    }
+
+    /**
+     * Connect again from the last device set, first closing what is left of the dead channel
+     */
+    private suspend fun reconnectDevice(deviceType: String): Boolean {
+        val profile = connectionProfileStore.getLastDeviceSet().find { it.deviceType == deviceType } ?: return false
+        activeSerialPorts.remove(deviceType)?.let { serialCommunicationModule.closeSerialPort(it) }
+        return connectFromProfile(profile)["success"] == true
+    }
+
+    /**
+     * Find the profile's USB device by name, falling back to VID/PID since
+     * Android renumbers device names whenever an adapter is replugged
+     */
+    private fun resolveUsbAddress(profile: ConnectionProfile): String? {
+        val usbManager = context.getSystemService(Context.USB_SERVICE) as UsbManager
+        val devices = usbManager.deviceList.values
+        devices.find { it.deviceName == profile.address }?.let { return it.deviceName }
+
+        if (profile.vendorId != null && profile.productId != null) {
+            return devices.find {
+                it.vendorId == profile.vendorId && it.productId == profile.productId
+            }?.deviceName
+        }
+        return null
+    }
+
+    /**
+     * Replace the runtime device configuration without touching open connections.
+     * Operations already in progress keep the snapshot they started with.
+     */
+    fun updateRuntimeConfig(config: DeviceRuntimeConfig): DeviceRuntimeConfig {
+        val validated = runtimeConfigStore.validate(config)
+        runtimeConfig = validated
+        runtimeConfigStore.save(validated)
+        networkDeviceModule.setIdleTimeout(validated.networkIdleTimeoutSeconds * 1000L)
+        Log.d(TAG, "Runtime config updated: $validated")
+        return validated
+    }
+
+    fun getRuntimeConfig(): DeviceRuntimeConfig = runtimeConfig
+
+    /**
+     * Update the ambient temperature, pressure and/or humidity used for atmospheric correction
+     */
+    fun setAmbientConditions(
+        temperatureC: Double? = ambientTemperatureC,
+        pressureHpa: Double? = ambientPressureHpa,
+        humidityPct: Double? = ambientHumidityPct
+    ) {
+        ambientTemperatureC = temperatureC
+        ambientPressureHpa = pressureHpa
+        ambientHumidityPct = humidityPct
+    }
+
+    /**
+     * Set the conditions measured at one EDM. Its slope distances are then corrected for
+     * them whether or not atmospheric correction is enabled for the venue.
+     */
+    fun setAtmosphericConditions(deviceType: String, temperatureC: Double, pressureHpa: Double, humidityPct: Double): Map<String, Any> {
+        val error = when {
+            !isEdmDeviceType(deviceType) -> "$deviceType is not an EDM"
+            temperatureC.isNaN() || temperatureC !in -40.0..60.0 -> "Temperature must be between -40 and 60 °C"
+            pressureHpa.isNaN() || pressureHpa !in 500.0..1100.0 -> "Pressure must be between 500 and 1100 hPa"
+            humidityPct.isNaN() || humidityPct !in 0.0..100.0 -> "Humidity must be between 0 and 100 %"
+            else -> null
+        }
+        if (error != null) return mapOf("success" to false, "error" to error)
+
+        deviceAtmospheres[deviceType] = AmbientReading(temperatureC, pressureHpa, humidityPct)
+        val conditions = getAtmosphericConditions(deviceType)
+        Log.d(TAG, "$deviceType atmosphere ${temperatureC}°C ${pressureHpa} hPa ${humidityPct}%: ${String.format(java.util.Locale.US, "%+.1f", conditions.ppm)} ppm")
+        return mapOf("success" to true, "deviceType" to deviceType) + conditions.toMap()
+    }
+
+    /**
+     * Go back to the ambient conditions for a device
+     */
+    fun clearAtmosphericConditions(deviceType: String) {
+        deviceAtmospheres.remove(deviceType)
+    }
+
+    /**
+     * Atmosphere the next reading will be corrected for: the device's own conditions when
+     * set, else the venue altitude and any entered temperature/pressure/humidity
+     */
+    fun getAtmosphericConditions(deviceType: String? = null): AtmosphericConditions {
+        val device = deviceType?.let { deviceAtmospheres[it] }
+            ?: return AtmosphericCorrection.conditions(runtimeConfig.venueAltitudeM, ambientPressureHpa, ambientTemperatureC, ambientHumidityPct)
+        return AtmosphericCorrection.conditions(runtimeConfig.venueAltitudeM, device.pressureHpa, device.temperatureC, device.humidityPct)
+    }
+
+    fun isAtmosphericCorrectionOn(deviceType: String): Boolean =
+        runtimeConfig.atmosphericCorrection || deviceAtmospheres.containsKey(deviceType)
+
+    /**
+     * Set the selected EDM device type
+     */
+    fun setSelectedEDMDevice(deviceSpec: EDMDeviceSpec) {
+        selectedEDMDevice = deviceSpec
+        Log.d(TAG, "Selected EDM device: ${deviceSpec.displayName}")
+    }
+    
+    /**
+     * Get the currently selected EDM device
+     */
+    fun getSelectedEDMDevice(): EDMDeviceSpec {
+        return selectedEDMDevice
+    }
+
+    /**
+     * Instrument model an EDM device type talks to: its own driver for a named station,
+     * otherwise the selected EDM device
+     */
+    fun edmSpecFor(deviceType: String): EDMDeviceSpec = edmStationDrivers[deviceType] ?: selectedEDMDevice
+    
+    /**
+     * Get single EDM reading for distance measurement (no tolerance checking)
+     * Uses device translator to communicate with actual EDM device, then calls Go Mobile for calculations
+     */
+    suspend fun getSingleEDMReading(deviceType: String): EDMReading {
+        return withContext(Dispatchers.IO) {
+            Log.d(TAG, "Getting single EDM reading with ${edmSpecFor(deviceType).displayName}: $deviceType")
+            
+            val connection = connectedDevices[deviceType]
+            Log.d(TAG, "Connection state: $connection")
+            
+            try {
+                // Check if this is a USB device that needs Android-side communication
+                if (connection?.connectionType == "usb") {
+                    return@withContext performUSBEDMReading(deviceType, single = true)
+                }
+                
+                // CRITICAL: Verify device is still physically connected
+                val usbManager = context.getSystemService(Context.USB_SERVICE) as UsbManager
+                val deviceList = usbManager.deviceList
+                val deviceStillConnected = deviceList.values.any { device ->
+                    EDMDeviceRegistry.matchUsbDevice(device.vendorId, device.productId) != null
+                }
+                
+                if (!deviceStillConnected) {
+                    Log.e(TAG, "EDM device no longer physically connected")
+                    return@withContext EDMReading(
+                        success = false,
+                        error = "EDM device disconnected - no device found"
+                    )
+                }
+                
+                // For serial/network connections, use our native serial communication
+                Log.d(TAG, "Using native serial communication for single EDM reading")
+                return@withContext performSerialEDMReading(deviceType, singleMode = true)
+                
+            } catch (e: Exception) {
+                Log.e(TAG, "Single EDM reading failed", e)
+                EDMReading(
+                    success = false,
+                    error = e.message ?: "Could not find prism. Check your aim and remeasure. If EDM displays \"STOP\" then press F1 to reset"
+                )
+            }
+        }
+    }
+    
     /**
      * Perform USB/Serial EDM reading using device translator
      */
@@ -412,14 +1949,26 @@ class EDMModule(private val context: Context) {
             
             // Try to detect and connect to serial devices (CH340, FTDI, etc.)
             Log.d(TAG, "Checking for USB-to-serial devices (CH340, FTDI, etc.)")
+            // Adapters already bound to another device (e.g. a second EDM station) are left alone
             val serialDevices = serialCommunicationModule.getAvailableSerialDevices()
+                .filterNot { isClaimedByOtherDevice(it.deviceName, deviceType) }
             
             if (serialDevices.isNotEmpty()) {
                 Log.d(TAG, "Found ${serialDevices.size} USB-to-serial device(s), attempting connection")
                 val serialDevice = serialDevices.first() // Use first available serial device
                 
-                // Attempt to connect to serial device
-                val serialPort = serialCommunicationModule.openSerialConnection(serialDevice)
+                // Attempt to connect to serial device, with the line settings last used for this type
+                // or else those of its driver
+                val lineConfig = connectionProfileStore.getSerialConfig(deviceType)
+                    ?: SerialPortConfig.fromDeviceSpec(edmSpecFor(deviceType))
+                val serialPort = serialCommunicationModule.openSerialPort(
+                    serialDevice,
+                    lineConfig.baudRate,
+                    lineConfig.dataBits,
+                    lineConfig.usbStopBits(),
+                    lineConfig.usbParity(),
+                    lineConfig.usbFlowControl()
+                ).port
                 if (serialPort != null) {
                     Log.d(TAG, "Successfully connected to USB-to-serial device")
                     
@@ -441,7 +1990,7 @@ class EDMModule(private val context: Context) {
             
             // Original USB direct connection logic as fallback
             val usbManager = context.getSystemService(Context.USB_SERVICE) as UsbManager
-            val usbDevices = getConnectedEDMDevices()
+            val usbDevices = getConnectedEDMDevices().filterNot { isClaimedByOtherDevice(it.deviceName, deviceType) }
             
             if (usbDevices.isEmpty()) {
                 return EDMReading(
@@ -454,7 +2003,7 @@ class EDMModule(private val context: Context) {
             
             // Use device translator to communicate with EDM device
             val translationResult = edmCommunicationBridge.performMeasurement(
-                selectedEDMDevice,
+                edmSpecFor(deviceType),
                 usbManager,
                 usbDevice
             )
@@ -473,7 +2022,7 @@ class EDMModule(private val context: Context) {
             val slopeDistanceMm = jsonResult.getDouble("slopeDistanceMm")
             val distanceMeters = slopeDistanceMm / 1000.0
             
-            Log.d(TAG, "USB EDM reading successful: ${distanceMeters}m via ${selectedEDMDevice.displayName}")
+            Log.d(TAG, "USB EDM reading successful: ${distanceMeters}m via ${edmSpecFor(deviceType).displayName}")
             
             return EDMReading(
                 success = true,
@@ -526,41 +2075,50 @@ class EDMModule(private val context: Context) {
             }
             
             // Use the device translator to get the appropriate command for this EDM device
-            val edmTranslator = EDMDeviceRegistry.createTranslator(selectedEDMDevice)
+            val edmTranslator = EDMDeviceRegistry.createTranslator(edmSpecFor(deviceType))
             if (edmTranslator == null) {
-                Log.e(TAG, "No translator available for ${selectedEDMDevice.displayName}")
+                Log.e(TAG, "No translator available for ${edmSpecFor(deviceType).displayName}")
                 return EDMReading(
                     success = false,
-                    error = "No translator available for ${selectedEDMDevice.displayName}"
+                    error = "No translator available for ${edmSpecFor(deviceType).displayName}"
                 )
             }
             val measureCommandBytes = edmTranslator.getMeasurementCommand()
             
-            Log.d(TAG, "Sending measurement command to ${selectedEDMDevice.displayName}: ${measureCommandBytes.contentToString()}")
+            Log.d(TAG, "Sending measurement command to ${edmSpecFor(deviceType).displayName}: ${measureCommandBytes.contentToString()}")
             
             // Send command bytes directly (don't convert to string to avoid corruption)
+            val config = runtimeConfig
+            reportPhase(deviceType, MeasurementPhase.TRIGGERING)
             val response = serialCommunicationModule.sendEDMCommandBytes(
                 serialPort,
                 measureCommandBytes,
-                timeoutMs = 10000 // 10 second timeout for measurement
+                timeoutMs = edmReadTimeout(deviceType, config),
+                writeTimeoutMs = config.writeTimeoutMs,
+                tap = exchangeTap(deviceType),
+                framing = edmSpecFor(deviceType).framing,
+                hasAnswer = edmTranslator::hasAnswerLine
             )
+            recordEdmResponse(deviceType, response)
             
             if (!response.success) {
                 Log.e(TAG, "Failed to get response from EDM device: ${response.error}")
                 return EDMReading(
                     success = false,
-                    error = response.error ?: "No response from EDM device"
+                    error = response.error ?: "No response from EDM device",
+                    errorCode = response.errorCode
                 )
             }
             
             // Parse the response using device translator
-            val parsedResult = edmTranslator.parseResponse(response.data!!)
+            val parsedResult = edmTranslator.parseReply(response.data!!)
             
             if (!parsedResult.isValid) {
                 Log.e(TAG, "Failed to parse EDM response: ${parsedResult.errorMessage}")
                 return EDMReading(
                     success = false,
-                    error = parsedResult.errorMessage ?: "Invalid response from EDM device"
+                    error = parsedResult.errorMessage ?: "Invalid response from EDM device",
+                    instrumentError = parsedResult.instrumentError
                 )
             }
             
@@ -588,42 +2146,410 @@ class EDMModule(private val context: Context) {
             )
             
         } catch (e: Exception) {
-            Log.e(TAG, "Serial EDM reading with Go Mobile failed", e)
-            return EDMReading(
-                success = false,
-                error = "Serial measurement failed: ${e.message}"
-            )
+            Log.e(TAG, "Serial EDM reading with Go Mobile failed", e)
+            return EDMReading(
+                success = false,
+                error = "Serial measurement failed: ${e.message}"
+            )
+        }
+    }
+    
+    /**
+     * Get connected USB devices that match supported EDM devices
+     */
+    private fun isClaimedByOtherDevice(address: String, deviceType: String): Boolean =
+        connectedDevices.values.any { it.address == address && it.deviceType != deviceType && it.isConnected }
+
+    private fun getConnectedEDMDevices(): List<UsbDevice> {
+        val usbManager = context.getSystemService(Context.USB_SERVICE) as UsbManager
+        val deviceList = usbManager.deviceList
+        
+        return deviceList.values.filter { device ->
+            EDMDeviceRegistry.matchUsbDevice(device.vendorId, device.productId) != null
+        }
+    }
+
+    /**
+     * Get reliable EDM reading for distance measurement
+     * When called by Go Mobile functions, returns our stored EDM data
+     * When called directly, performs measurement through device translator
+     * A quick read is always a single read, whatever the read policy, for previews
+     */
+    suspend fun getReliableEDMReading(deviceType: String, singleMode: Boolean = false, quickRead: Boolean = false): EDMReading =
+        CrashReporter.guard(
+            "getReliableEDMReading",
+            { t -> EDMReading(success = false, error = "Internal error reading EDM: ${t.message ?: t.javaClass.simpleName}") }
+        ) {
+            val requestedAt = SessionSources.now()
+            val requestedNanos = System.nanoTime()
+            val reading = applyAtmosphericCorrection(
+                deviceType,
+                applyPrismConstant(
+                    deviceType,
+                    tagSetup(
+                        deviceType,
+                        applyInstrumentErrors(deviceType, applyVerticalAngleConvention(deviceType, getReliableEDMReadingGuarded(deviceType, singleMode, quickRead)))
+                    )
+                )
+            )
+            // Paths that cannot time the instrument command fall back to the whole request
+            if (reading.success && reading.triggeredAt == null) {
+                reading.copy(triggeredAt = requestedAt, latencyMs = (System.nanoTime() - requestedNanos) / 1_000_000)
+            } else {
+                reading
+            }
+        }
+
+    /**
+     * Remove the stored index and collimation errors and reduce face II readings to face I.
+     * The horizontal angle as read is kept alongside as rawHarDecimal.
+     */
+    private fun applyInstrumentErrors(deviceType: String, reading: EDMReading): EDMReading {
+        val errors = instrumentErrorStore.load(edmSpecFor(deviceType).model)
+        val data = reading.goMobileData
+        if (errors == null || !reading.success || data.isNullOrEmpty()) return reading
+
+        return try {
+            val json = JSONObject(data)
+            if (!json.has("vAzDecimal") || !json.has("harDecimal")) return reading
+            val vertical = json.getDouble("vAzDecimal")
+            val horizontal = json.getDouble("harDecimal")
+            val (correctedVertical, correctedHorizontal) = TwoFaceReduction.correct(vertical, horizontal, errors)
+            if (!json.has("rawVAzDecimal")) json.put("rawVAzDecimal", vertical)
+            json.put("rawHarDecimal", horizontal)
+            json.put("vAzDecimal", correctedVertical)
+            json.put("harDecimal", correctedHorizontal)
+            reading.copy(goMobileData = json.toString())
+        } catch (e: Exception) {
+            Log.e(TAG, "Instrument error correction skipped: ${e.message}")
+            reading
+        }
+    }
+
+    /**
+     * Observe the collimation target in one face (1 or 2) for instrument error calibration.
+     * Point at the same well-defined target in face I then face II; each pair makes a set.
+     */
+    suspend fun observeTwoFace(deviceType: String, face: Int): Map<String, Any> {
+        val reading = CrashReporter.guard(
+            "observeTwoFace",
+            { t -> EDMReading(success = false, error = "Internal error reading EDM: ${t.message ?: t.javaClass.simpleName}") }
+        ) {
+            applyVerticalAngleConvention(deviceType, getReliableEDMReadingGuarded(deviceType, singleMode = false, quickRead = false))
+        }
+        val data = reading.goMobileData
+        if (!reading.success || data.isNullOrEmpty()) {
+            return mapOf("success" to false, "error" to (reading.error ?: "Failed to observe target"))
+        }
+
+        val json = JSONObject(data)
+        val observation = Pair(json.getDouble("vAzDecimal"), json.getDouble("harDecimal"))
+        synchronized(twoFaceSets) {
+            when (face) {
+                1 -> faceOneObservations.add(observation)
+                2 -> {
+                    val faceOne = faceOneObservations.removeLastOrNull()
+                        ?: return mapOf("success" to false, "error" to "Observe the target in face I first")
+                    twoFaceSets.add(TwoFaceSet(faceOne.first, faceOne.second, observation.first, observation.second))
+                }
+                else -> return mapOf("success" to false, "error" to "Face must be 1 or 2")
+            }
+            Log.d(TAG, "Face $face observation: V=${observation.first}°, Hz=${observation.second}°")
+            return mapOf("success" to true, "face" to face, "sets" to twoFaceSets.size)
+        }
+    }
+
+    /**
+     * Average the collected two-face sets and store the errors for the selected instrument
+     */
+    fun saveInstrumentErrors(deviceType: String = "edm"): Map<String, Any> {
+        val errors = synchronized(twoFaceSets) {
+            TwoFaceReduction.compute(edmSpecFor(deviceType).model, twoFaceSets.toList()).also {
+                twoFaceSets.clear()
+                faceOneObservations.clear()
+            }
+        } ?: return mapOf("success" to false, "error" to "No two-face sets observed")
+
+        instrumentErrorStore.save(errors)
+        Log.d(TAG, "Instrument errors for ${errors.model}: index ${errors.indexErrorDeg * 3600}\", collimation ${errors.collimationErrorDeg * 3600}\"")
+        return mapOf(
+            "success" to true,
+            "indexErrorSeconds" to errors.indexErrorDeg * 3600,
+            "collimationErrorSeconds" to errors.collimationErrorDeg * 3600,
+            "sets" to errors.sets
+        )
+    }
+
+    fun getInstrumentErrors(deviceType: String = "edm"): InstrumentErrors? = instrumentErrorStore.load(edmSpecFor(deviceType).model)
+
+    fun clearInstrumentErrors(deviceType: String = "edm") {
+        instrumentErrorStore.clear(edmSpecFor(deviceType).model)
+        synchronized(twoFaceSets) {
+            twoFaceSets.clear()
+            faceOneObservations.clear()
+        }
+    }
+
+    /**
+     * Convert the vertical angle to zenith-referenced, which every reduction assumes.
+     * Instruments reading from the horizon would otherwise give sd·sin(v) instead of sd·cos(v).
+     * The angle as read is kept alongside as rawVAzDecimal.
+     */
+    private fun applyVerticalAngleConvention(deviceType: String, reading: EDMReading): EDMReading {
+        val convention = runtimeConfig.verticalAngleConvention(edmSpecFor(deviceType))
+        val data = reading.goMobileData
+        if (convention == VerticalAngleConvention.ZENITH || !reading.success || data.isNullOrEmpty()) return reading
+
+        return try {
+            val json = JSONObject(data)
+            if (!json.has("vAzDecimal")) return reading
+            val rawVertical = json.getDouble("vAzDecimal")
+            json.put("vAzDecimal", convention.toZenith(rawVertical))
+            json.put("rawVAzDecimal", rawVertical)
+            json.put("verticalAngleConvention", convention.name)
+            reading.copy(goMobileData = json.toString())
+        } catch (e: Exception) {
+            Log.e(TAG, "Vertical angle conversion skipped: ${e.message}")
+            reading
+        }
+    }
+
+    /**
+     * Set the prism constant of the reflector used with an EDM, e.g. -30 for a standard
+     * circular prism or +17.5 for a 360° prism. It is added to every slope distance before
+     * any other correction, so the instrument's own prism constant must be set to 0.
+     */
+    fun setPrismConstant(deviceType: String, constantMm: Double): Map<String, Any> {
+        if (!isEdmDeviceType(deviceType)) {
+            return mapOf("success" to false, "error" to "$deviceType is not an EDM")
+        }
+        if (constantMm.isNaN() || kotlin.math.abs(constantMm) > MAX_PRISM_CONSTANT_MM) {
+            return mapOf("success" to false, "error" to "Prism constant must be within ±$MAX_PRISM_CONSTANT_MM mm")
+        }
+        prismConstantStore.save(deviceType, constantMm)
+        Log.d(TAG, "$deviceType prism constant ${String.format(java.util.Locale.US, "%+.1f", constantMm)} mm")
+        return mapOf("success" to true, "deviceType" to deviceType, "prismConstantMm" to constantMm)
+    }
+
+    fun getPrismConstant(deviceType: String = "edm"): Double = prismConstantStore.load(deviceType)
+
+    /**
+     * Switch an EDM between "prism" and "reflectorless" measurement. Reflectorless is for
+     * marking sector lines or checking cage positions without a prism carrier; readings
+     * taken after the switch carry the mode and get no prism constant.
+     */
+    suspend fun setMeasurementMode(deviceType: String, mode: String): Map<String, Any> {
+        val measurementMode = EDMMeasurementMode.fromKey(mode)
+            ?: return mapOf("success" to false, "error" to "Unknown measurement mode $mode; use prism or reflectorless")
+        val spec = edmSpecFor(deviceType)
+        val command = EDMDeviceRegistry.createTranslator(spec)?.getMeasurementModeCommand(measurementMode)
+            ?: return mapOf(
+                "success" to false,
+                "supported" to false,
+                "error" to "${spec.displayName} cannot be switched to ${measurementMode.key} remotely"
+            )
+
+        // Whichever channel the EDM is on: serial, Bluetooth, BLE, USB passthrough or RFC 2217
+        val send = edmCommandSender(deviceType)
+            ?: return mapOf("success" to false, "supported" to true, "error" to "EDM not connected")
+
+        val response = send(command)
+        if (response.success) {
+            measurementModes[deviceType] = measurementMode
+            Log.d(TAG, "$deviceType measuring ${measurementMode.key}")
+        }
+        return mapOf(
+            "success" to response.success,
+            "supported" to true,
+            "mode" to getMeasurementMode(deviceType).key,
+            "error" to (response.error ?: "")
+        )
+    }
+
+    fun getMeasurementMode(deviceType: String = "edm"): EDMMeasurementMode =
+        measurementModes[deviceType] ?: EDMMeasurementMode.PRISM
+
+    /**
+     * Sight a fixed target (a flag, post or distant corner) and keep its horizontal angle
+     * as this setup's orientation reference
+     */
+    suspend fun setOrientationReference(deviceType: String, label: String): Map<String, Any> {
+        val angle = readHorizontalAngle(deviceType)
+        if (angle.isFailure) {
+            return mapOf("success" to false, "error" to (angle.exceptionOrNull()?.message ?: "Could not read the angle"))
+        }
+        val reference = OrientationReference(angle.getOrThrow().first, label, SessionSources.now())
+        orientationReferences.save(deviceType, reference)
+        Log.d(TAG, "Orientation reference for $deviceType: $label at ${reference.horizontalAngleDeg}°")
+        return mapOf("success" to true, "label" to label, "horizontalAngleDeg" to reference.horizontalAngleDeg)
+    }
+
+    /**
+     * Re-sight the orientation reference and report how far the horizontal angle has
+     * drifted, in seconds of arc. Much quicker than a full edge verification mid-event;
+     * drift beyond tolerance means the instrument has been knocked and needs re-verifying.
+     */
+    suspend fun checkOrientation(deviceType: String): Map<String, Any> {
+        val reference = orientationReferences.load(deviceType)
+            ?: return mapOf("success" to false, "error" to "No orientation reference set for $deviceType")
+        val angle = readHorizontalAngle(deviceType)
+        if (angle.isFailure) {
+            return mapOf("success" to false, "error" to (angle.exceptionOrNull()?.message ?: "Could not read the angle"))
+        }
+        val (horizontalAngleDeg, angleOnly) = angle.getOrThrow()
+        val result = OrientationCheckResult.compare(reference, horizontalAngleDeg, angleOnly)
+        Log.d(TAG, "Orientation check on $deviceType: drift ${String.format(java.util.Locale.US, "%.1f", result.driftArcSec)}\"")
+        return result.toMap()
+    }
+
+    fun clearOrientationReference(deviceType: String) = orientationReferences.clear(deviceType)
+
+    /**
+     * Horizontal angle now, and whether the instrument read it without measuring distance
+     */
+    private suspend fun readHorizontalAngle(deviceType: String): Result<Pair<Double, Boolean>> {
+        val translator = EDMDeviceRegistry.createTranslator(edmSpecFor(deviceType))
+        val command = translator?.getAngleCommand()
+        val send = edmCommandSender(deviceType)
+        if (translator != null && command != null && send != null) {
+            val response = send(command)
+            val angle = response.data?.takeIf { response.success }?.let { translator.parseAngleReply(it) }
+                ?: return Result.failure(Exception(response.error ?: "No angle in reply from ${translator.deviceSpec.displayName}"))
+            return Result.success(Pair(angle, true))
+        }
+
+        val reading = getReliableEDMReading(deviceType, singleMode = true, quickRead = true)
+        val data = reading.goMobileData
+        if (!reading.success || data.isNullOrEmpty()) {
+            return Result.failure(Exception(reading.error ?: "No EDM measurement data available"))
+        }
+        return try {
+            Result.success(Pair(JSONObject(data).getDouble("harDecimal"), false))
+        } catch (e: Exception) {
+            Result.failure(Exception("No horizontal angle in reading"))
+        }
+    }
+
+    /**
+     * Height of the instrument's trunnion axis above the ground it stands on
+     */
+    fun setInstrumentHeight(deviceType: String, meters: Double): Map<String, Any> =
+        setSetupHeights(deviceType, getSetupHeights(deviceType).copy(instrumentHeightM = meters))
+
+    /**
+     * Height of the prism above the ground point being measured, i.e. the pole length
+     */
+    fun setTargetHeight(deviceType: String, meters: Double): Map<String, Any> =
+        setSetupHeights(deviceType, getSetupHeights(deviceType).copy(targetHeightM = meters))
+
+    fun getSetupHeights(deviceType: String = "edm"): SetupHeights = setupHeightStore.load(deviceType)
+
+    private fun setSetupHeights(deviceType: String, heights: SetupHeights): Map<String, Any> {
+        if (listOf(heights.instrumentHeightM, heights.targetHeightM).any { it.isNaN() || it !in 0.0..MAX_SETUP_HEIGHT_M }) {
+            return mapOf("success" to false, "error" to "Heights must be between 0 and $MAX_SETUP_HEIGHT_M m")
+        }
+        setupHeightStore.save(deviceType, heights)
+        Log.d(TAG, "Setup heights for $deviceType: instrument ${heights.instrumentHeightM} m, target ${heights.targetHeightM} m")
+        return mapOf(
+            "success" to true,
+            "instrumentHeightM" to heights.instrumentHeightM,
+            "targetHeightM" to heights.targetHeightM
+        )
+    }
+
+    /**
+     * Record the measurement mode and setup heights a reading was taken with
+     */
+    private fun tagSetup(deviceType: String, reading: EDMReading): EDMReading {
+        val data = reading.goMobileData
+        if (!reading.success || data.isNullOrEmpty()) return reading
+        return try {
+            val heights = getSetupHeights(deviceType)
+            val json = JSONObject(data)
+                .put("measurementMode", getMeasurementMode(deviceType).key)
+                .put("instrumentHeightM", heights.instrumentHeightM)
+                .put("targetHeightM", heights.targetHeightM)
+            reading.copy(goMobileData = json.toString())
+        } catch (e: Exception) {
+            Log.e(TAG, "Reading setup not tagged: ${e.message}")
+            reading
         }
     }
-    
+
     /**
-     * Get connected USB devices that match supported EDM devices
+     * Add the reflector's prism constant to the slope distance. The distance as read is kept
+     * alongside as rawSlopeDistanceMm, and the constant as prismConstantMm even when zero,
+     * so every reading records which constant it was reduced with.
      */
-    private fun getConnectedEDMDevices(): List<UsbDevice> {
-        val usbManager = context.getSystemService(Context.USB_SERVICE) as UsbManager
-        val deviceList = usbManager.deviceList
-        
-        return deviceList.values.filter { device ->
-            EDMDeviceRegistry.matchUsbDevice(device.vendorId, device.productId) != null
+    private fun applyPrismConstant(deviceType: String, reading: EDMReading): EDMReading {
+        val data = reading.goMobileData
+        if (!reading.success || data.isNullOrEmpty()) return reading
+
+        return try {
+            val json = JSONObject(data)
+            if (!json.has("slopeDistanceMm")) return reading
+            // A reflectorless distance is to the surface itself, with no prism to correct for
+            val constantMm = if (getMeasurementMode(deviceType) == EDMMeasurementMode.REFLECTORLESS) 0.0 else prismConstantStore.load(deviceType)
+            val rawSlopeMm = json.getDouble("slopeDistanceMm")
+            json.put("prismConstantMm", constantMm)
+            if (constantMm == 0.0) return reading.copy(goMobileData = json.toString())
+
+            json.put("slopeDistanceMm", rawSlopeMm + constantMm)
+            json.put("rawSlopeDistanceMm", rawSlopeMm)
+            reading.copy(
+                distance = reading.distance?.let { it + constantMm / 1000.0 },
+                goMobileData = json.toString()
+            )
+        } catch (e: Exception) {
+            Log.e(TAG, "Prism constant skipped: ${e.message}")
+            reading
         }
     }
 
     /**
-     * Get reliable EDM reading for distance measurement
-     * When called by Go Mobile functions, returns our stored EDM data
-     * When called directly, performs measurement through device translator
+     * Scale the slope distance by the atmospheric ppm when enabled. Done once here so every
+     * consumer of the reading (calibration, throws, heatmap) sees the same corrected distance;
+     * the uncorrected value is kept alongside as rawSlopeDistanceMm, and the conditions used
+     * as atmosphere.
      */
-    suspend fun getReliableEDMReading(deviceType: String, singleMode: Boolean = false): EDMReading {
+    private fun applyAtmosphericCorrection(deviceType: String, reading: EDMReading): EDMReading {
+        val data = reading.goMobileData
+        if (!isAtmosphericCorrectionOn(deviceType) || !reading.success || data.isNullOrEmpty()) return reading
+
+        return try {
+            val json = JSONObject(data)
+            if (!json.has("slopeDistanceMm")) return reading
+            val slopeMm = json.getDouble("slopeDistanceMm")
+            val conditions = getAtmosphericConditions(deviceType)
+            val ppm = conditions.ppm
+            val correctedMm = AtmosphericCorrection.apply(slopeMm, ppm)
+            json.put("slopeDistanceMm", correctedMm)
+            // A prism constant has already recorded the distance as read
+            if (!json.has("rawSlopeDistanceMm")) json.put("rawSlopeDistanceMm", slopeMm)
+            json.put("atmosphericPpm", ppm)
+            json.put("atmosphere", JSONObject(conditions.toMap()))
+            Log.d(TAG, "Atmospheric correction ${String.format(java.util.Locale.US, "%+.1f", ppm)} ppm: $slopeMm -> $correctedMm mm")
+            reading.copy(
+                distance = reading.distance?.let { AtmosphericCorrection.apply(it, ppm) },
+                goMobileData = json.toString()
+            )
+        } catch (e: Exception) {
+            Log.e(TAG, "Atmospheric correction skipped: ${e.message}")
+            reading
+        }
+    }
+
+    private suspend fun getReliableEDMReadingGuarded(deviceType: String, singleMode: Boolean, quickRead: Boolean): EDMReading {
         return withContext(Dispatchers.IO) {
-            Log.d(TAG, "Getting reliable EDM reading with ${selectedEDMDevice.displayName}: $deviceType")
+            Log.d(TAG, "Getting reliable EDM reading with ${edmSpecFor(deviceType).displayName}: $deviceType")
             
             // For serial connections, we need to handle EDM communication for Go Mobile
             val connection = connectedDevices[deviceType]
-            if (connection?.connectionType == "serial") {
-                Log.d(TAG, "Go Mobile delegation: Performing EDM reading via serial connection")
+            if (connection?.connectionType in EDM_COMMAND_TRANSPORTS) {
+                Log.d(TAG, "Go Mobile delegation: Performing EDM reading via ${connection.connectionType} connection")
                 
                 // Get the actual EDM reading from our serial communication
-                val rawReading = getRawEDMReading(deviceType, !singleMode) // Convert singleMode to doubleReadMode parameter
+                val rawReading = getRawEDMReading(deviceType, !singleMode, quickRead) // Convert singleMode to doubleReadMode parameter
                 if (rawReading.success && rawReading.parsedReading != null) {
                     val reading = rawReading.parsedReading!!
                     Log.d(TAG, "Go Mobile EDM data: slope=${reading.slopeDistanceMm}mm, vAz=${reading.verticalAngleDegrees}°, hAr=${reading.horizontalAngleDegrees}°")
@@ -635,6 +2561,8 @@ class EDMModule(private val context: Context) {
                         put("slopeDistanceMm", reading.slopeDistanceMm)
                         put("vAzDecimal", reading.verticalAngleDegrees) 
                         put("harDecimal", reading.horizontalAngleDegrees)
+                        reading.verticalAngleRaw?.let { put("vAzRaw", it) }
+                        reading.horizontalAngleRaw?.let { put("harRaw", it) }
                     }
                     
                     Log.d(TAG, "Returning EDM data to Go Mobile: ${jsonResult}")
@@ -642,13 +2570,17 @@ class EDMModule(private val context: Context) {
                     return@withContext EDMReading(
                         success = true,
                         distance = reading.slopeDistanceMm / 1000.0,
-                        goMobileData = jsonResult.toString()
+                        goMobileData = jsonResult.toString(),
+                        triggeredAt = rawReading.triggeredAt,
+                        latencyMs = rawReading.latencyMs
                     )
                 } else {
                     Log.e(TAG, "Failed to get EDM data for Go Mobile: ${rawReading.error}")
                     return@withContext EDMReading(
                         success = false,
-                        error = rawReading.error ?: "Failed to get serial EDM reading for Go Mobile"
+                        error = rawReading.error ?: "Failed to get serial EDM reading for Go Mobile",
+                        errorCode = rawReading.errorCode,
+                        instrumentError = rawReading.instrumentError
                     )
                 }
             }
@@ -703,6 +2635,8 @@ class EDMModule(private val context: Context) {
                 return performSerialEDMReading(deviceType, singleMode = false)
             }
             
+            val config = runtimeConfig
+
             // For direct USB connections, perform double reading on Android side
             // First reading
             val reading1 = performUSBEDMReading(deviceType, single = true)
@@ -710,8 +2644,8 @@ class EDMModule(private val context: Context) {
                 return reading1
             }
             
-            // Wait 100ms between readings (matches Go Mobile delayBetweenReadsInPair)
-            delay(100)
+            // Wait between readings (default 100ms matches Go Mobile delayBetweenReadsInPair)
+            delay(config.doubleReadDelayMs)
             
             // Second reading
             val reading2 = performUSBEDMReading(deviceType, single = true)
@@ -724,7 +2658,7 @@ class EDMModule(private val context: Context) {
             val distance2Mm = reading2.distance!! * 1000.0
             val difference = kotlin.math.abs(distance1Mm - distance2Mm)
             
-            if (difference <= 3.0) { // 3mm tolerance
+            if (difference <= config.doubleReadToleranceMm) {
                 // Average the readings
                 val averageDistance = (reading1.distance!! + reading2.distance!!) / 2.0
                 
@@ -754,7 +2688,43 @@ class EDMModule(private val context: Context) {
     /**
      * Measure wind speed
      */
-    suspend fun measureWind(): WindReading {
+    suspend fun measureWind(): WindReading =
+        CrashReporter.guard(
+            "measureWind",
+            { t -> WindReading(success = false, error = "Internal error reading wind: ${t.message ?: t.javaClass.simpleName}") }
+        ) {
+            measureWindGuarded()
+        }
+
+    /**
+     * Capture the wind over a window starting now, e.g. the 5 s rule window for horizontal
+     * jumps, returning once it has closed. A polled gauge is read at the close and reports
+     * over its own averaging window.
+     */
+    suspend fun measureWindWindow(windowSeconds: Int): WindReading =
+        CrashReporter.guard(
+            "measureWindWindow",
+            { t -> WindReading(success = false, error = "Internal error reading wind: ${t.message ?: t.javaClass.simpleName}") }
+        ) {
+            val connection = connectedDevices["wind"]
+            if (connection == null || !connection.isConnected) {
+                return@guard WindReading(success = false, error = "Wind gauge not connected")
+            }
+            val windowEnd = System.currentTimeMillis() + windowSeconds * 1000L
+            delay(windowEnd - System.currentTimeMillis())
+
+            val listener = windListeners["${connection.deviceType}_network"]
+            if (listener != null && listener.isRunning) {
+                val average = listener.averageOver(windowSeconds, now = windowEnd)
+                Log.d(TAG, "Wind over ${windowSeconds}s window: ${average}m/s")
+                if (average != null) WindReading(success = true, windSpeed = average)
+                else WindReading(success = false, error = "No wind readings received during the ${windowSeconds}s window")
+            } else {
+                measureWindGuarded()
+            }
+        }
+
+    private suspend fun measureWindGuarded(): WindReading {
         return withContext(Dispatchers.IO) {
             Log.d(TAG, "Measuring wind speed")
             
@@ -766,6 +2736,20 @@ class EDMModule(private val context: Context) {
                 )
             }
             
+            // A running listener already holds the readings around this moment
+            val listener = windListeners["${connection.deviceType}_network"]
+            if (listener != null && listener.isRunning) {
+                val buffered = listener.averageOver(runtimeConfig.windWindowSeconds)
+                if (buffered != null) {
+                    Log.d(TAG, "Wind from buffer: ${buffered}m/s over ${runtimeConfig.windWindowSeconds}s")
+                    return@withContext WindReading(success = true, windSpeed = buffered)
+                }
+                return@withContext WindReading(
+                    success = false,
+                    error = "No wind readings received in the last ${runtimeConfig.windWindowSeconds}s"
+                )
+            }
+            
             try {
                 // Real wind gauge communication
                 val windSpeed = sendWindCommand(connection)
@@ -786,24 +2770,213 @@ class EDMModule(private val context: Context) {
         }
     }
     
+    /**
+     * Put the EDM into standby between flights to save battery.
+     * The instrument keeps its setup, so calibration stays valid.
+     */
+    suspend fun standbyEDM(deviceType: String = "edm"): Map<String, Any> {
+        val spec = edmSpecFor(deviceType)
+        val translator = EDMDeviceRegistry.createTranslator(spec)
+        val command = translator?.getStandbyCommand()
+            ?: return mapOf(
+                "success" to false,
+                "supported" to false,
+                "error" to "${spec.displayName} does not support remote standby"
+            )
+
+        val send = edmCommandSender(deviceType)
+            ?: return mapOf("success" to false, "supported" to true, "error" to "EDM not connected")
+
+        val response = send(command)
+        if (response.success) {
+            standbyDevices.add(deviceType)
+            Log.d(TAG, "$deviceType in standby")
+        }
+        return mapOf(
+            "success" to response.success,
+            "supported" to true,
+            "error" to (response.error ?: "")
+        )
+    }
+
+    /**
+     * Wake the EDM from standby and wait until it is ready to measure
+     */
+    suspend fun wakeEDM(deviceType: String = "edm"): Map<String, Any> {
+        val spec = edmSpecFor(deviceType)
+        val translator = EDMDeviceRegistry.createTranslator(spec)
+        val command = translator?.getWakeCommand()
+        if (translator == null || command == null) {
+            standbyDevices.remove(deviceType)
+            return mapOf(
+                "success" to false,
+                "supported" to false,
+                "error" to "${spec.displayName} does not support remote wake"
+            )
+        }
+
+        val send = edmCommandSender(deviceType)
+            ?: return mapOf("success" to false, "supported" to true, "error" to "EDM not connected")
+
+        val response = send(command)
+        if (response.success) {
+            delay(translator.wakeSettleMs)
+            standbyDevices.remove(deviceType)
+            Log.d(TAG, "$deviceType awake")
+        }
+        return mapOf(
+            "success" to response.success,
+            "supported" to true,
+            "error" to (response.error ?: "")
+        )
+    }
+
+    fun isEDMInStandby(deviceType: String = "edm"): Boolean = standbyDevices.contains(deviceType)
+
+    /**
+     * End the session cleanly: stop listeners, deliver or persist queued scoreboard
+     * messages, and close every device. The last device set is kept so the next
+     * launch can reconnect.
+     * @return Summary of what was stopped, closed and left pending
+     */
+    suspend fun shutdownAll(): Map<String, Any> = withContext(Dispatchers.IO) {
+        Log.d(TAG, "Shutting down all devices")
+
+        // Nothing closed here should be brought back by the supervisor
+        connectedDevices.keys.forEach { reconnectSupervisor.cancel(it) }
+
+        val listenersStopped = windListeners.size
+        windListeners.values.forEach { it.shutdown() }
+        windListeners.clear()
+
+        val scoreboardReplayed = try {
+            replayScoreboardOutbox()
+        } catch (e: Exception) {
+            Log.w(TAG, "Scoreboard outbox replay during shutdown failed: ${e.message}")
+            0
+        }
+
+        val serialClosed = activeSerialPorts.keys.toList()
+        activeSerialPorts.values.forEach { serialCommunicationModule.closeSerialPort(it) }
+        activeSerialPorts.clear()
+
+        networkDeviceModule.shutdown()
+
+        val devicesClosed = connectedDevices.keys.toList()
+        connectedDevices.clear()
+        devicesClosed.forEach { reconnectSupervisor.cancel(it) } // Any retry started while closing
+        appliedWindWindows.clear()
+        standbyDevices.clear()
+        devicesClosed.forEach { deviceType ->
+            emitConnectionEvent(ConnectionEventType.DISCONNECTED, deviceType, "", "Session ended")
+        }
+
+        val profilesFlushed = connectionProfileStore.flush()
+
+        Log.d(TAG, "Shutdown complete: closed $devicesClosed, stopped $listenersStopped listener(s)")
+        mapOf(
+            "success" to profilesFlushed,
+            "devicesClosed" to devicesClosed,
+            "serialPortsClosed" to serialClosed.size,
+            "listenersStopped" to listenersStopped,
+            "scoreboardReplayed" to scoreboardReplayed,
+            "scoreboardPending" to scoreboardOutbox.getStatus().depth
+        )
+    }
+
+    /**
+     * Listening configuration for a wind gauge type, with defaults for anything unset
+     */
+    fun getWindGaugeConfig(gaugeType: String): WindGaugeConfig {
+        val saved = runtimeConfigStore.loadWindGaugeConfigs()[gaugeType] ?: return WindGaugeConfig()
+        return saved.copy(
+            mode = GsonFields.orDefault(saved.mode, WindListenMode.POLLED),
+            pollCommand = saved.pollCommand?.takeIf { it.isNotBlank() },
+            pollIntervalMs = saved.pollIntervalMs.coerceIn(100, 60000),
+            sampleIntervalMs = saved.sampleIntervalMs.takeIf { it > 0 }?.coerceIn(100, 10000) ?: 1000,
+            retentionSeconds = saved.retentionSeconds.takeIf { it > 0 }?.coerceAtMost(3600) ?: 120
+        )
+    }
+
+    /**
+     * Save a gauge's listening configuration, restarting its listener if connected
+     */
+    fun setWindGaugeConfig(gaugeType: String, config: WindGaugeConfig) {
+        runtimeConfigStore.saveWindGaugeConfig(gaugeType, config)
+        windListeners.values
+            .filter { it.gaugeType == gaugeType }
+            .forEach { it.start(listenerConfig(it.deviceId, gaugeType)) }
+    }
+
+    fun getWindSamples(deviceType: String = "wind"): List<WindSample> =
+        windListeners["${deviceType}_network"]?.getSamples() ?: emptyList()
+
+    /**
+     * Clock offsets of devices that stamp their own output, for aligning their timelines
+     */
+    fun getClockOffsets(): List<ClockOffset> = clockOffsets.getOffsets()
+
+    private fun startWindListener(deviceId: String, gaugeType: String) {
+        windListeners.remove(deviceId)?.shutdown()
+        val listener = WindGaugeListener(networkDeviceModule, deviceId, gaugeType, clockOffsets)
+        windListeners[deviceId] = listener
+        listener.start(listenerConfig(deviceId, gaugeType))
+    }
+
+    /**
+     * Saved configuration for the gauge type, listened to passively over BLE where
+     * notifications arrive unprompted and the characteristic may not take writes
+     */
+    private fun listenerConfig(deviceId: String, gaugeType: String): WindGaugeConfig {
+        val config = getWindGaugeConfig(gaugeType)
+        val deviceType = deviceId.removeSuffix("_network")
+        return if (connectedDevices[deviceType]?.connectionType == "ble") {
+            config.copy(mode = WindListenMode.PASSIVE)
+        } else {
+            config
+        }
+    }
+    
     /**
      * Disconnect device (USB or network)
      */
     fun disconnectDevice(deviceType: String): Boolean {
         Log.d(TAG, "Disconnecting device: $deviceType")
 
+        // An explicit disconnect means the device should not come back on next launch
+        connectionProfileStore.forgetLastDevice(deviceType)
+        reconnectSupervisor.cancel(deviceType)
+        trackingJobs.remove(deviceType)?.cancel()
+
         return if (connectedDevices.containsKey(deviceType)) {
             val connection = connectedDevices[deviceType]
 
-            // Handle network device disconnect (launch in background)
-            if (connection?.connectionType == "network") {
-                GlobalScope.launch(Dispatchers.IO) {
+            // Handle network device disconnect (launch in background); Bluetooth wind
+            // gauges and scoreboards run through the network module too
+            if (connection?.connectionType in NETWORK_MODULE_TRANSPORTS) {
+                moduleScope.launch {
                     val deviceId = "${deviceType}_network"
                     networkDeviceModule.disconnect(deviceId)
                     Log.d(TAG, "Disconnected network device: $deviceId")
                 }
             }
 
+            appliedWindWindows.remove("${deviceType}_network")
+            windListeners.remove("${deviceType}_network")?.shutdown()
+            clockOffsets.reset("${deviceType}_network")
+            standbyDevices.remove(deviceType)
+            measurementModes.remove(deviceType)
+            readTimeouts.reset(deviceType)
+
+            passthroughLinks.remove(deviceType)
+            activeBluetoothLinks.remove(deviceType)?.let { link ->
+                try {
+                    link.close()
+                } catch (e: Exception) {
+                    Log.w(TAG, "Error closing Bluetooth link for $deviceType: ${e.message}")
+                }
+            }
+
             // Clean up serial connection if exists
             val serialPort = activeSerialPorts.remove(deviceType)
             if (serialPort != null) {
@@ -812,6 +2985,7 @@ class EDMModule(private val context: Context) {
             }
 
             connectedDevices.remove(deviceType)
+            emitConnectionEvent(ConnectionEventType.DISCONNECTED, deviceType, connection?.address.orEmpty(), "Disconnected")
             true
         } else {
             false
@@ -833,39 +3007,112 @@ class EDMModule(private val context: Context) {
      * Send command to scoreboard
      * Displays result, athlete info, or text on connected scoreboard
      */
-    suspend fun sendScoreboardCommand(command: DeviceCommand): DeviceResponse {
+    suspend fun sendScoreboardCommand(
+        command: DeviceCommand,
+        sessionId: String? = null
+    ): DeviceResponse {
         return withContext(Dispatchers.IO) {
-            val connection = connectedDevices["scoreboard"]
-                ?: connectedDevices["scoreboard_daktronics"]
-                ?: connectedDevices["daktronics"]
+            // A result for a circle with its own board waits for that board rather than
+            // falling back to another circle's
+            val target = sharedEdm.scoreboardFor(sessionId)
+            val connection = if (target != null) connectedDevices[target] else scoreboardConnection()
 
             if (connection == null || !connection.isConnected) {
+                if (target != null) scoreboardOutbox.enqueue(command, target)
                 return@withContext DeviceResponse(
                     success = false,
-                    error = "Scoreboard not connected"
+                    error = if (target != null) "Scoreboard $target not connected" else "Scoreboard not connected"
                 )
             }
 
-            if (connection.connectionType != "network") {
+            if (connection.connectionType !in NETWORK_MODULE_TRANSPORTS) {
                 return@withContext DeviceResponse(
                     success = false,
-                    error = "Only network scoreboards are supported"
+                    error = "Only network and Bluetooth scoreboards are supported"
                 )
             }
 
-            try {
-                val deviceId = "${connection.deviceType}_network"
-                networkDeviceModule.sendCommand(deviceId, command)
-            } catch (e: Exception) {
-                Log.e(TAG, "Scoreboard command failed: ${e.message}")
-                DeviceResponse(
-                    success = false,
-                    error = e.message ?: "Scoreboard command failed"
-                )
+            val response = deliverScoreboardCommand(connection, command)
+
+            // Keep the message so it is delivered once the board comes back
+            if (!response.success) {
+                scoreboardOutbox.enqueue(command, target)
+            }
+            response
+        }
+    }
+
+    private suspend fun deliverScoreboardCommand(connection: DeviceConnection, command: DeviceCommand): DeviceResponse {
+        return try {
+            val deviceId = "${connection.deviceType}_network"
+            networkDeviceModule.sendCommand(deviceId, command)
+        } catch (e: Exception) {
+            Log.e(TAG, "Scoreboard command failed: ${e.message}")
+            DeviceResponse(
+                success = false,
+                error = e.message ?: "Scoreboard command failed"
+            )
+        }
+    }
+
+    /**
+     * Depth and age of scoreboard messages waiting for delivery
+     */
+    fun getScoreboardOutboxStatus(): OutboxStatus = scoreboardOutbox.getStatus()
+
+    /**
+     * Retry delivery of queued scoreboard messages
+     * @return Number of messages delivered
+     */
+    suspend fun replayScoreboardOutbox(): Int {
+        return withContext(Dispatchers.IO) {
+            scoreboardOutbox.replay { target, command ->
+                val connection = if (target != null) connectedDevices[target] else scoreboardConnection()
+                if (connection == null || !connection.isConnected ||
+                    connection.connectionType !in NETWORK_MODULE_TRANSPORTS) {
+                    DeviceResponse(success = false, error = "Scoreboard ${target ?: "default"} not connected")
+                } else {
+                    deliverScoreboardCommand(connection, command)
+                }
             }
         }
     }
 
+    fun clearScoreboardOutbox() {
+        scoreboardOutbox.clear()
+    }
+
+    /**
+     * Board results go to: the active shared-EDM circle's board if set, else the first connected
+     */
+    private fun scoreboardConnection(): DeviceConnection? =
+        scoreboardRoute?.let { connectedDevices[it] }
+            ?: connectedDevices["scoreboard"]
+            ?: connectedDevices["scoreboard_daktronics"]
+            ?: connectedDevices["daktronics"]
+
+    /**
+     * Swap this EDM to another circle: applyCalibration installs the incoming calibration and
+     * results are routed to that circle's scoreboard
+     */
+    fun switchActiveCircle(
+        name: String,
+        outgoingCalibration: CalibrationState?,
+        applyCalibration: (CalibrationState) -> Unit
+    ): Result<ActiveCircle> = sharedEdm.switchTo(name, outgoingCalibration) { profile ->
+        profile.scoreboardDeviceType?.let { deviceType ->
+            require(isScoreboardType(deviceType)) { "$deviceType is not a scoreboard" }
+        }
+        applyCalibration(profile.calibration)
+        scoreboardRoute = profile.scoreboardDeviceType
+    }
+
+    private fun isScoreboardType(deviceType: String): Boolean {
+        return deviceType == "scoreboard" || deviceType == "daktronics" || deviceType.startsWith(SCOREBOARD_PREFIX)
+    }
+
+    fun isScoreboardConnected(): Boolean = connectedDevices.keys.any(::isScoreboardType)
+
     /**
      * Test scoreboard with countdown sequence: 3 → 2 → 1 → 0
      * Useful for verifying scoreboard connection and display functionality
@@ -937,11 +3184,26 @@ class EDMModule(private val context: Context) {
     private suspend fun sendWindCommand(connection: DeviceConnection): Double {
         return withContext(Dispatchers.IO) {
             when (connection.connectionType) {
-                "network" -> {
-                    Log.d(TAG, "Reading wind from network device")
+                "network", "bluetooth", "ble" -> {
+                    Log.d(TAG, "Reading wind from ${connection.connectionType} device")
 
                     // Send command via NetworkDeviceModule
                     val deviceId = "${connection.deviceType}_network"
+
+                    // Apply a changed averaging window before this read
+                    val windWindow = runtimeConfig.windWindowSeconds
+                    if (appliedWindWindows[deviceId] != windWindow) {
+                        val averaging = DeviceCommand(
+                            type = "SET_AVERAGING",
+                            parameters = mapOf("seconds" to windWindow),
+                            expectResponse = false
+                        )
+                        if (networkDeviceModule.sendCommand(deviceId, averaging).success) {
+                            appliedWindWindows[deviceId] = windWindow
+                            Log.d(TAG, "Wind averaging window set to ${windWindow}s")
+                        }
+                    }
+
                     val command = DeviceCommand(type = "READ_WIND", expectResponse = true)
 
                     val response = networkDeviceModule.sendCommand(deviceId, command)
@@ -971,16 +3233,29 @@ class EDMModule(private val context: Context) {
     data class RawEDMResult(
         val success: Boolean,
         val parsedReading: EDMParsedReading? = null,
-        val error: String? = null
+        val error: String? = null,
+        val errorCode: SerialErrorCode? = null,
+        val triggeredAt: Long? = null,
+        val latencyMs: Long? = null,
+        val instrumentError: EDMInstrumentError? = null
     )
     
     /**
      * Get raw EDM reading without Go Mobile processing
      * This is used internally to feed data to Go Mobile functions
-     * Supports both single and double read modes
+     * Supports both single and double read modes, over serial and every stream link
+     * (Bluetooth, BLE, USB passthrough and RFC 2217 serial servers)
      */
-    private suspend fun getRawEDMReading(deviceType: String, doubleReadMode: Boolean = false): RawEDMResult {
-        Log.d(TAG, "🔵 getRawEDMReading called with doubleReadMode: $doubleReadMode")
+    private suspend fun getRawEDMReading(deviceType: String, requestedDoubleRead: Boolean = false, quickRead: Boolean = false): RawEDMResult {
+        // An instrument left in standby is woken transparently before the next athlete
+        if (standbyDevices.contains(deviceType)) {
+            wakeEDM(deviceType)
+        }
+
+        // Snapshot the runtime config so a mid-read change cannot mix settings
+        val config = runtimeConfig
+        val doubleReadMode = !quickRead && config.useDoubleRead(requestedDoubleRead)
+        Log.d(TAG, "🔵 getRawEDMReading called with doubleReadMode: $doubleReadMode (policy ${config.readPolicy})")
         return withContext(Dispatchers.IO) {
             Log.d(TAG, "Getting raw EDM reading for Go Mobile processing")
             
@@ -990,10 +3265,10 @@ class EDMModule(private val context: Context) {
             
             try {
                 when (connection?.connectionType ?: "serial") {
-                    "serial" -> {
-                        // Get serial port and perform measurement
-                        val serialPort = activeSerialPorts[deviceType]
-                        if (serialPort == null) {
+                    in EDM_COMMAND_TRANSPORTS -> {
+                        // Get serial port (or stream link) and perform measurement
+                        val sendCommand = edmCommandSender(deviceType)
+                        if (sendCommand == null) {
                             return@withContext RawEDMResult(
                                 success = false,
                                 error = "Serial port not available"
@@ -1001,11 +3276,11 @@ class EDMModule(private val context: Context) {
                         }
                         
                         // Get translator and send command
-                        val edmTranslator = EDMDeviceRegistry.createTranslator(selectedEDMDevice)
+                        val edmTranslator = EDMDeviceRegistry.createTranslator(edmSpecFor(deviceType))
                         if (edmTranslator == null) {
                             return@withContext RawEDMResult(
                                 success = false,
-                                error = "No translator available for ${selectedEDMDevice.displayName}"
+                                error = "No translator available for ${edmSpecFor(deviceType).displayName}"
                             )
                         }
                         
@@ -1015,58 +3290,72 @@ class EDMModule(private val context: Context) {
                             Log.d(TAG, "🔵 Performing double EDM reading with tolerance checking")
                             
                             // First reading
-                            val response1 = serialCommunicationModule.sendEDMCommandBytes(
-                                serialPort,
-                                measureCommandBytes,
-                                timeoutMs = 10000
-                            )
+                            reportPhase(deviceType, MeasurementPhase.TRIGGERING)
+                            val response1 = sendCommand(measureCommandBytes)
                             
                             if (!response1.success) {
                                 return@withContext RawEDMResult(
                                     success = false,
-                                    error = response1.error ?: "No response from EDM device on first reading"
+                                    error = response1.error ?: "No response from EDM device on first reading",
+                                    errorCode = response1.errorCode
                                 )
                             }
                             
-                            val parsedResult1 = edmTranslator.parseResponse(response1.data!!)
+                            val parsedResult1 = edmTranslator.parseReply(response1.data!!)
                             if (!parsedResult1.isValid) {
                                 return@withContext RawEDMResult(
                                     success = false,
-                                    error = parsedResult1.errorMessage ?: "Invalid response from EDM device on first reading"
+                                    error = parsedResult1.errorMessage ?: "Invalid response from EDM device on first reading",
+                                    instrumentError = parsedResult1.instrumentError
                                 )
                             }
                             
-                            // Wait 100ms between readings (matches Go Mobile delayBetweenReadsInPair)
-                            delay(100)
+                            // Wait between readings (default 100ms matches Go Mobile delayBetweenReadsInPair)
+                            delay(config.doubleReadDelayMs)
                             
-                            // Second reading
-                            val response2 = serialCommunicationModule.sendEDMCommandBytes(
-                                serialPort,
-                                measureCommandBytes,
-                                timeoutMs = 10000
-                            )
+                            // Second reading. A stale copy of the first reply left in a buffer comes back
+                            // almost at once and passes the tolerance check trivially, so re-trigger
+                            reportPhase(deviceType, MeasurementPhase.SECOND_READ)
+                            var response2 = sendCommand(measureCommandBytes)
+                            var retriggers = 0
+                            while (response2.success && isBufferedDuplicate(response1, response2)) {
+                                if (retriggers == MAX_DUPLICATE_RETRIGGERS) {
+                                    deviceHealth.recordError(deviceType, "Buffered duplicate reading")
+                                    return@withContext RawEDMResult(
+                                        success = false,
+                                        error = "EDM repeated its first reading instead of measuring again. Check the instrument and retry"
+                                    )
+                                }
+                                retriggers++
+                                Log.w(TAG, "Second reading is a buffered duplicate (${response2.latencyMs}ms vs ${response1.latencyMs}ms), re-triggering ($retriggers)")
+                                delay(config.doubleReadDelayMs)
+                                response2 = sendCommand(measureCommandBytes)
+                            }
                             
                             if (!response2.success) {
                                 return@withContext RawEDMResult(
                                     success = false,
-                                    error = response2.error ?: "No response from EDM device on second reading"
+                                    error = response2.error ?: "No response from EDM device on second reading",
+                                    errorCode = response2.errorCode
                                 )
                             }
                             
-                            val parsedResult2 = edmTranslator.parseResponse(response2.data!!)
+                            val parsedResult2 = edmTranslator.parseReply(response2.data!!)
                             if (!parsedResult2.isValid) {
                                 return@withContext RawEDMResult(
                                     success = false,
-                                    error = parsedResult2.errorMessage ?: "Invalid response from EDM device on second reading"
+                                    error = parsedResult2.errorMessage ?: "Invalid response from EDM device on second reading",
+                                    instrumentError = parsedResult2.instrumentError
                                 )
                             }
                             
                             // Compare readings for tolerance (3mm for slope distance - matches Go Mobile sdToleranceMm)
+                            reportPhase(deviceType, MeasurementPhase.AVERAGING)
                             val distance1Mm = parsedResult1.slopeDistanceMm
                             val distance2Mm = parsedResult2.slopeDistanceMm
                             val difference = kotlin.math.abs(distance1Mm - distance2Mm)
                             
-                            if (difference <= 3.0) { // 3mm tolerance
+                            if (difference <= config.doubleReadToleranceMm) {
                                 // Average the readings
                                 val avgSlopeDistance = (parsedResult1.slopeDistanceMm + parsedResult2.slopeDistanceMm) / 2.0
                                 val avgVerticalAngle = (parsedResult1.verticalAngleDegrees + parsedResult2.verticalAngleDegrees) / 2.0
@@ -1079,12 +3368,20 @@ class EDMModule(private val context: Context) {
                                     verticalAngleDegrees = avgVerticalAngle,
                                     horizontalAngleDegrees = avgHorizontalAngle,
                                     statusCode = parsedResult1.statusCode,
-                                    isValid = true
+                                    isValid = true,
+                                    // Both readings' original strings, so the average can be audited
+                                    verticalAngleRaw = listOfNotNull(parsedResult1.verticalAngleRaw, parsedResult2.verticalAngleRaw)
+                                        .joinToString("/").ifEmpty { null },
+                                    horizontalAngleRaw = listOfNotNull(parsedResult1.horizontalAngleRaw, parsedResult2.horizontalAngleRaw)
+                                        .joinToString("/").ifEmpty { null }
                                 )
                                 
+                                // Timed from the first read; the pair is averaged to that instant
                                 return@withContext RawEDMResult(
                                     success = true,
-                                    parsedReading = averagedResult
+                                    parsedReading = averagedResult,
+                                    triggeredAt = response1.sentAt,
+                                    latencyMs = response1.latencyMs
                                 )
                             } else {
                                 Log.w(TAG, "Readings inconsistent - R1: ${distance1Mm}mm, R2: ${distance2Mm}mm, Diff: ${difference}mm")
@@ -1098,35 +3395,36 @@ class EDMModule(private val context: Context) {
                             Log.d(TAG, "🔵 Performing single EDM reading (doubleReadMode=false)")
                             
                             // Single reading
-                            val response = serialCommunicationModule.sendEDMCommandBytes(
-                                serialPort,
-                                measureCommandBytes,
-                                timeoutMs = 10000
-                            )
+                            reportPhase(deviceType, MeasurementPhase.TRIGGERING)
+                            val response = sendCommand(measureCommandBytes)
                             
                             if (!response.success) {
                                 return@withContext RawEDMResult(
                                     success = false,
-                                    error = response.error ?: "No response from EDM device"
+                                    error = response.error ?: "No response from EDM device",
+                                    errorCode = response.errorCode
                                 )
                             }
                             
-                            val parsedResult = edmTranslator.parseResponse(response.data!!)
+                            val parsedResult = edmTranslator.parseReply(response.data!!)
                             if (!parsedResult.isValid) {
                                 return@withContext RawEDMResult(
                                     success = false,
-                                    error = parsedResult.errorMessage ?: "Invalid response from EDM device"
+                                    error = parsedResult.errorMessage ?: "Invalid response from EDM device",
+                                    instrumentError = parsedResult.instrumentError
                                 )
                             }
                             
                             return@withContext RawEDMResult(
                                 success = true,
-                                parsedReading = parsedResult
+                                parsedReading = parsedResult,
+                                triggeredAt = response.sentAt,
+                                latencyMs = response.latencyMs
                             )
                         }
                     }
                     else -> {
-                        // For USB/network connections, delegate to Go Mobile
+                        // Plain network sockets carry no EDM command protocol
                         return@withContext RawEDMResult(
                             success = false,
                             error = "Raw reading not supported for ${connection?.connectionType ?: "unknown"} connections"
@@ -1143,6 +3441,80 @@ class EDMModule(private val context: Context) {
         }
     }
     
+    /**
+     * Whether a second reply is the first one again from a buffer rather than a new
+     * measurement: identical bytes, answered far faster than the instrument can measure
+     */
+    private fun isBufferedDuplicate(
+        first: SerialCommunicationModule.SerialResponse,
+        second: SerialCommunicationModule.SerialResponse
+    ): Boolean {
+        val firstLatency = first.latencyMs ?: return false
+        val secondLatency = second.latencyMs ?: return false
+        if (first.data != second.data) return false
+        return secondLatency < DUPLICATE_READ_MIN_LATENCY_MS || secondLatency * DUPLICATE_READ_LATENCY_RATIO < firstLatency
+    }
+
+    /**
+     * Request/response exchange with the EDM over whichever serial channel it is on
+     */
+    private fun edmCommandSender(deviceType: String): (suspend (ByteArray) -> SerialCommunicationModule.SerialResponse)? {
+        val send = edmChannelSender(deviceType) ?: return null
+        return { bytes -> send(bytes).also { recordEdmResponse(deviceType, it) } }
+    }
+
+    private fun recordEdmResponse(deviceType: String, response: SerialCommunicationModule.SerialResponse) {
+        if (response.success) {
+            deviceHealth.recordSuccess(deviceType, response.latencyMs)
+            response.latencyMs?.let { readTimeouts.recordResponse(deviceType, it) }
+        } else {
+            deviceHealth.recordError(deviceType, response.error ?: "No response")
+            if (response.errorCode == SerialErrorCode.TIMEOUT) readTimeouts.recordTimeout(deviceType)
+        }
+    }
+
+    /**
+     * How long to wait for an EDM to answer: the configured timeout when fixed, otherwise
+     * adapted to the instrument's recent response times and the ambient temperature
+     */
+    fun edmReadTimeout(deviceType: String, config: DeviceRuntimeConfig = runtimeConfig): Long =
+        if (config.fixedReadTimeout) {
+            config.edmReadTimeoutMs
+        } else {
+            readTimeouts.timeoutFor(deviceType, config.edmReadTimeoutMs, ambientTemperatureC)
+        }
+
+    private fun edmChannelSender(deviceType: String): (suspend (ByteArray) -> SerialCommunicationModule.SerialResponse)? {
+        val config = runtimeConfig
+        val hasAnswer = EDMDeviceRegistry.createTranslator(edmSpecFor(deviceType))?.let { it::hasAnswerLine }
+        if (connectedDevices[deviceType]?.connectionType in STREAM_LINK_TRANSPORTS) {
+            val link = activeBluetoothLinks[deviceType] ?: return null
+            return { bytes ->
+                serialCommunicationModule.sendEDMCommandBytes(
+                    link,
+                    bytes,
+                    timeoutMs = edmReadTimeout(deviceType, config),
+                    writeTimeoutMs = config.writeTimeoutMs,
+                    tap = exchangeTap(deviceType),
+                    framing = edmSpecFor(deviceType).framing,
+                    hasAnswer = hasAnswer
+                )
+            }
+        }
+        val port = activeSerialPorts[deviceType] ?: return null
+        return { bytes ->
+            serialCommunicationModule.sendEDMCommandBytes(
+                port,
+                bytes,
+                timeoutMs = edmReadTimeout(deviceType, config),
+                writeTimeoutMs = config.writeTimeoutMs,
+                tap = exchangeTap(deviceType),
+                framing = edmSpecFor(deviceType).framing,
+                hasAnswer = hasAnswer
+            )
+        }
+    }
+
     /**
      * Get latest EDM reading for Go Mobile consumption
      * This is called by Go Mobile's getReliableEDMReading when it needs our data
@@ -1189,6 +3561,154 @@ class EDMModule(private val context: Context) {
         return knownAdapters.any { (vid, pid) -> vid == vendorId && pid == productId }
     }
     
+    /**
+     * List USB devices, optionally probing each serial port to suggest its role.
+     * Each port gains "likelyDevice" (edm, wind, unknown) and, where known, "likelyModel".
+     * Ports already in use are reported with their current role instead of being opened.
+     */
+    suspend fun listUsbDevices(probe: Boolean): Map<String, Any> =
+        CrashReporter.guardResult("listUsbDevices") { listUsbDevicesGuarded(probe) }
+
+    private suspend fun listUsbDevicesGuarded(probe: Boolean): Map<String, Any> {
+        val result = listUsbDevices()
+        if (!probe) return result
+
+        @Suppress("UNCHECKED_CAST")
+        val ports = result["ports"] as? List<Map<String, Any>> ?: return result
+        val usbManager = context.getSystemService(Context.USB_SERVICE) as UsbManager
+        val deviceList = usbManager.deviceList
+
+        val probedPorts = ports.map { portInfo ->
+            val deviceName = portInfo["deviceName"] as? String ?: ""
+            val annotated = portInfo.toMutableMap()
+
+            val connectedRole = connectedDevices.values.find { it.address == deviceName && it.isConnected }
+            val usbDevice = deviceList.values.find { it.deviceName == deviceName }
+
+            when {
+                connectedRole != null -> {
+                    annotated["likelyDevice"] = connectedRole.deviceType
+                    annotated["probeStatus"] = "in_use"
+                }
+                usbDevice == null || portInfo["isSerial"] != true -> {
+                    annotated["likelyDevice"] = "unknown"
+                    annotated["probeStatus"] = "not_serial"
+                }
+                !usbManager.hasPermission(usbDevice) -> {
+                    annotated["likelyDevice"] = "unknown"
+                    annotated["probeStatus"] = "no_permission"
+                }
+                else -> {
+                    val matchedEDMDevice = EDMDeviceRegistry.matchUsbDevice(usbDevice.vendorId, usbDevice.productId)
+                    val probeConfig = matchedEDMDevice?.let { SerialPortConfig.fromDeviceSpec(it) } ?: SerialPortConfig()
+                    val captured = serialCommunicationModule.probePort(usbDevice, probeConfig)
+
+                    if (captured == null) {
+                        annotated["likelyDevice"] = "unknown"
+                        annotated["probeStatus"] = "open_failed"
+                    } else {
+                        val identification = identifyProbeResponse(captured, matchedEDMDevice)
+                        annotated.putAll(identification)
+                        annotated["probeStatus"] = if (captured.isBlank()) "silent" else "responded"
+                    }
+                }
+            }
+
+            Log.d(TAG, "Probe $deviceName: ${annotated["likelyDevice"]} (${annotated["probeStatus"]})")
+            annotated.toMap()
+        }
+
+        return result + mapOf("ports" to probedPorts, "probed" to true)
+    }
+
+    /**
+     * Work out which EDM protocol and line settings the station on a USB-to-serial port
+     * uses, for officials who don't know them. Returns JSON with the driver key and a
+     * "config" object that connectSerialDeviceWithConfig accepts as it is. The station must
+     * be aimed at the prism: each probe takes a measurement.
+     */
+    suspend fun autoDetectEDM(portName: String): String = withContext(Dispatchers.IO) {
+        val result = JSONObject().put("portName", portName)
+        val usbManager = context.getSystemService(Context.USB_SERVICE) as UsbManager
+        val usbDevice = usbManager.deviceList.values.find { it.deviceName == portName }
+
+        val error = when {
+            usbDevice == null -> "Device not found at address: $portName"
+            connectedDevices.values.any { it.address == portName && it.isConnected } ->
+                "Port is in use by a connected device; disconnect it first"
+            !usbManager.hasPermission(usbDevice) -> SerialErrorCode.PERMISSION_DENIED.userMessage
+            else -> null
+        }
+        if (error != null || usbDevice == null) {
+            return@withContext result.put("success", false).put("error", error).toString()
+        }
+
+        val detected = try {
+            edmAutoDetector.detect(usbDevice)
+        } catch (e: Exception) {
+            Log.e(TAG, "EDM auto-detect failed", e)
+            EDMDetectionResult(error = "Detection failed: ${e.message}")
+        }
+        result.put("settingsTried", detected.settingsTried)
+
+        val detection = detected.detection
+            ?: return@withContext result
+                .put("success", false)
+                .put("error", detected.error)
+                .apply { detected.errorCode?.let { put("errorCode", it.name) } }
+                .toString()
+
+        val config = detection.config
+        result
+            .put("success", true)
+            .put("driver", detection.driverKey)
+            .put("model", detection.deviceSpec.displayName)
+            .put("baudRate", config.baudRate)
+            .put("dataBits", config.dataBits)
+            .put("parity", config.parity)
+            .put("stopBits", config.stopBits)
+            .put("config", JSONObject()
+                .put("baudRate", config.baudRate)
+                .put("dataBits", config.dataBits)
+                .put("parity", config.parity)
+                .put("stopBits", config.stopBits)
+                .put("flowControl", config.flowControl))
+            .put("response", detection.response)
+            .toString()
+    }
+
+    /**
+     * Guess a device role from the text captured while probing a port
+     */
+    private fun identifyProbeResponse(captured: String, matchedEDMDevice: EDMDeviceSpec?): Map<String, Any> {
+        val lines = captured.split('\r', '\n').map { it.trim() }.filter { it.isNotEmpty() }
+
+        val windModel = lines.firstNotNullOfOrNull { line ->
+            when {
+                line.startsWith("\$WIMWV") || line.startsWith("\$IIMWV") -> "NMEA_STYLE"
+                line.matches(Regex("^[A-Z],\\d{0,3},[+-]?\\d+(\\.\\d+)?,M.*")) -> "GILL_WINDMASTER"
+                line.startsWith("WS:") -> "LYNX_3002"
+                line.matches(Regex("^[+-]\\d{1,2}\\.\\d{1,2}$")) -> "GENERIC"
+                else -> null
+            }
+        }
+        if (windModel != null) {
+            return mapOf("likelyDevice" to "wind", "likelyModel" to windModel)
+        }
+
+        val looksLikeEDM = lines.any { it.matches(Regex("^\\d{7}\\s+\\d{7}\\s+\\d{7}\\s+[0-9a-fA-F]{2}$")) }
+        if (looksLikeEDM || matchedEDMDevice != null) {
+            val spec = matchedEDMDevice ?: EDMDeviceRegistry.getDefaultDevice()
+            return mapOf(
+                "likelyDevice" to "edm",
+                "likelyModel" to EDMDeviceRegistry.keyFor(spec),
+                "likelyModelName" to spec.displayName
+            )
+        }
+
+        return mapOf("likelyDevice" to "unknown")
+    }
+
     /**
      * List all connected USB devices (matches v16 implementation)
      */
@@ -1356,8 +3876,9 @@ class EDMModule(private val context: Context) {
             if (!edmReading.success) {
                 return mapOf(
                     "success" to false,
-                    "error" to (edmReading.error ?: "Failed to get EDM reading")
-                )
+                    "error" to (edmReading.instrumentError?.humanMessage ?: edmReading.error ?: "Failed to get EDM reading"),
+                    "errorCode" to (edmReading.errorCode?.name ?: "")
+                ) + (edmReading.instrumentError?.let { mapOf("instrumentError" to it.toMap()) } ?: emptyMap())
             }
             
             val goMobileData = edmReading.goMobileData
@@ -1411,8 +3932,9 @@ class EDMModule(private val context: Context) {
             if (!edmReading.success) {
                 return mapOf(
                     "success" to false,
-                    "error" to (edmReading.error ?: "Failed to get EDM reading")
-                )
+                    "error" to (edmReading.instrumentError?.humanMessage ?: edmReading.error ?: "Failed to get EDM reading"),
+                    "errorCode" to (edmReading.errorCode?.name ?: "")
+                ) + (edmReading.instrumentError?.let { mapOf("instrumentError" to it.toMap()) } ?: emptyMap())
             }
             
             val goMobileData = edmReading.goMobileData
@@ -1465,8 +3987,9 @@ class EDMModule(private val context: Context) {
             if (!edmReading.success) {
                 return mapOf(
                     "success" to false,
-                    "error" to (edmReading.error ?: "Failed to get EDM reading")
-                )
+                    "error" to (edmReading.instrumentError?.humanMessage ?: edmReading.error ?: "Failed to get EDM reading"),
+                    "errorCode" to (edmReading.errorCode?.name ?: "")
+                ) + (edmReading.instrumentError?.let { mapOf("instrumentError" to it.toMap()) } ?: emptyMap())
             }
             
             val goMobileData = edmReading.goMobileData
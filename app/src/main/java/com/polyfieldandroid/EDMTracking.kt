@@ -0,0 +1,66 @@
+package com.polyfieldandroid
+
+/**
+ * One tracked prism position in the circle frame
+ */
+data class TrackedPosition(
+    val x: Double,
+    val y: Double,
+    val distanceM: Double,         // Beyond the circle edge, as read
+    val smoothedDistanceM: Double, // For the live display
+    val timestamp: Long
+) {
+    fun toMap(): Map<String, Any> = mapOf(
+        "x" to x,
+        "y" to y,
+        "distance" to distanceM,
+        "smoothedDistance" to smoothedDistanceM,
+        "timestamp" to timestamp
+    )
+}
+
+/**
+ * Tracking Buffer
+ * The most recent positions from a tracking EDM, oldest dropped first, with the smoother
+ * that steadies the displayed distance and the last failure if reads are going wrong.
+ * Tracked positions are for placing and checking marks, never for recording a result.
+ */
+class TrackingBuffer(private val capacity: Int = 200) {
+
+    private val positions = ArrayDeque<TrackedPosition>()
+    private val smoother = TrackingSmoother()
+    private var lastError: String? = null
+    private var consecutiveFailures = 0
+
+    @Synchronized
+    fun add(x: Double, y: Double, distanceM: Double, timestamp: Long = SessionSources.now()): TrackedPosition {
+        val smoothed = smoother.add(distanceM, timestamp)
+        val position = TrackedPosition(x, y, distanceM, smoothed.smoothed, timestamp)
+        positions.addLast(position)
+        while (positions.size > capacity) positions.removeFirst()
+        lastError = null
+        consecutiveFailures = 0
+        return position
+    }
+
+    /**
+     * Note a failed read; returns how many have failed in a row
+     */
+    @Synchronized
+    fun fail(error: String): Int {
+        lastError = error
+        return ++consecutiveFailures
+    }
+
+    @Synchronized
+    fun latest(): TrackedPosition? = positions.lastOrNull()
+
+    @Synchronized
+    fun since(timestamp: Long): List<TrackedPosition> = positions.filter { it.timestamp > timestamp }
+
+    @Synchronized
+    fun lastError(): String? = lastError
+
+    @Synchronized
+    fun size(): Int = positions.size
+}
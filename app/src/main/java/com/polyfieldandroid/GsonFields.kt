@@ -0,0 +1,25 @@
+package com.polyfieldandroid
+
+/**
+ * Gson Fields
+ * Gson builds objects by reflection and bypasses Kotlin null-safety, so a non-null property
+ * missing from older saves or damaged JSON still reads back as null. Loaders pass such
+ * properties through here before trusting them, rather than re-typing each one by hand.
+ */
+object GsonFields {
+
+    /**
+     * The loaded value, typed as the null it may really be
+     */
+    fun <T : Any> orNull(value: T?): T? = value
+
+    /**
+     * The loaded value, or the default when it was missing from the JSON
+     */
+    fun <T : Any> orDefault(value: T?, default: T): T = value ?: default
+
+    /**
+     * Whether every given property was present in the JSON
+     */
+    fun allSet(vararg values: Any?): Boolean = values.all { it != null }
+}
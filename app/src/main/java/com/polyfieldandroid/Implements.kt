@@ -0,0 +1,156 @@
+package com.polyfieldandroid
+
+import android.content.Context
+import android.util.Log
+import com.google.gson.Gson
+import com.google.gson.reflect.TypeToken
+import java.io.File
+
+/**
+ * A competition implement (shot, discus, hammer, javelin) known to the venue
+ */
+data class Implement(
+    val id: String,                  // Painted or engraved ID on the implement
+    val eventType: String,           // Circle type it is thrown from, e.g. EDMCalculations.CIRCLE_SHOT
+    val weightKg: Double,
+    val certificationNumber: String? = null,
+    val manufacturer: String? = null,
+    val owner: String? = null        // Venue, or the athlete's name for a personal implement
+) {
+    fun getDisplayName(): String = "$id - ${String.format(java.util.Locale.US, "%.3f", weightKg)} kg"
+}
+
+/**
+ * Attempts thrown with one implement, for implement-check reconciliation
+ */
+data class ImplementUsage(
+    val implement: Implement?,  // Null when the ID is no longer in the registry
+    val implementId: String,
+    val attempts: List<Pair<String, Int>> // Athlete bib and round
+)
+
+/**
+ * Implement Registry
+ * Keeps the venue's implements between sessions so they only need entering once
+ */
+class ImplementRegistry(context: Context) {
+
+    companion object {
+        private const val TAG = "ImplementRegistry"
+        private const val PREFS_NAME = "polyfield_implements"
+        private const val PREF_IMPLEMENTS = "implements"
+    }
+
+    private val preferences = context.getSharedPreferences(PREFS_NAME, Context.MODE_PRIVATE)
+    private val gson = Gson()
+    private val implements = load().toMutableList()
+
+    fun getImplements(): List<Implement> = synchronized(implements) { implements.toList() }
+
+    fun getImplements(eventType: String): List<Implement> = getImplements().filter { it.eventType == eventType }
+
+    fun get(id: String): Implement? = synchronized(implements) { implements.find { it.id == id } }
+
+    /**
+     * Add an implement, replacing any with the same ID
+     */
+    fun register(implement: Implement): Boolean {
+        if (implement.id.isBlank() || implement.weightKg <= 0.0) {
+            Log.w(TAG, "Rejected implement '${implement.id}' with weight ${implement.weightKg} kg")
+            return false
+        }
+        synchronized(implements) {
+            implements.removeAll { it.id == implement.id }
+            implements.add(implement.copy(id = implement.id.trim()))
+            save()
+        }
+        Log.d(TAG, "Registered implement ${implement.getDisplayName()}")
+        return true
+    }
+
+    fun remove(id: String) {
+        synchronized(implements) {
+            if (implements.removeAll { it.id == id }) save()
+        }
+    }
+
+    private fun load(): List<Implement> {
+        return try {
+            val json = preferences.getString(PREF_IMPLEMENTS, null) ?: return emptyList()
+            val type = object : TypeToken<List<Implement>>() {}.type
+            gson.fromJson<List<Implement>>(json, type) ?: emptyList()
+        } catch (e: Exception) {
+            Log.e(TAG, "Error loading implements: ${e.message}")
+            emptyList()
+        }
+    }
+
+    private fun save() {
+        preferences.edit()
+            .putString(PREF_IMPLEMENTS, gson.toJson(implements))
+            .apply()
+    }
+}
+
+/**
+ * Pre-event inspection of one implement at check-in
+ */
+data class ImplementInspection(
+    val implementId: String,
+    val weightKg: Double,                  // As weighed at check-in
+    val dimensions: Map<String, Double>,   // Millimetres, e.g. "diameter", "length"
+    val passed: Boolean,
+    val inspector: String,
+    val notes: String? = null,
+    val timestamp: Long = SessionSources.now()
+)
+
+/**
+ * Implement Inspection Log
+ * Check-in results for the session, appended to a file so they survive restarts until the
+ * next competition starts. An implement re-inspected later is judged by its latest result.
+ */
+class ImplementInspectionLog(context: Context) {
+
+    companion object {
+        private const val TAG = "ImplementInspections"
+        private const val FILE_NAME = "implement_inspections.jsonl"
+        private val lock = Any()
+    }
+
+    private val file = File(context.applicationContext.filesDir, FILE_NAME)
+    private val gson = Gson()
+
+    fun record(inspection: ImplementInspection) {
+        synchronized(lock) {
+            try {
+                file.appendText(gson.toJson(inspection) + "\n", Charsets.UTF_8)
+                Log.d(TAG, "Implement ${inspection.implementId} ${if (inspection.passed) "approved" else "rejected"} by ${inspection.inspector}")
+            } catch (e: Exception) {
+                Log.e(TAG, "Failed to record inspection: ${e.message}")
+            }
+        }
+    }
+
+    /**
+     * Latest inspection of each implement, in the order first inspected
+     */
+    fun getInspections(): List<ImplementInspection> {
+        val lines = synchronized(lock) {
+            if (file.exists()) file.readLines(Charsets.UTF_8) else emptyList()
+        }
+
+        val latest = linkedMapOf<String, ImplementInspection>()
+        lines.forEach { line ->
+            if (line.isBlank()) return@forEach
+            try {
+                gson.fromJson(line, ImplementInspection::class.java)?.let { latest[it.implementId] = it }
+            } catch (e: Exception) {
+                Log.w(TAG, "Skipping unreadable entry: ${e.message}")
+            }
+        }
+        return latest.values.toList()
+    }
+
+    fun latest(implementId: String): ImplementInspection? = getInspections().find { it.implementId == implementId }
+}
@@ -0,0 +1,168 @@
+package com.polyfieldandroid
+
+import android.content.Context
+import android.util.Log
+import com.google.gson.Gson
+import kotlin.math.abs
+import kotlin.math.sin
+
+/**
+ * Vertical index and horizontal collimation errors of one instrument
+ */
+data class InstrumentErrors(
+    val model: String,
+    val indexErrorDeg: Double,       // Added to every zenith angle by the instrument
+    val collimationErrorDeg: Double, // Line of sight off perpendicular to the trunnion axis
+    val sets: Int,                   // Two-face sets the values were averaged from
+    val determinedAt: Long = SessionSources.now()
+)
+
+/**
+ * A target observed in both faces; angles zenith-referenced, degrees
+ */
+data class TwoFaceSet(
+    val faceOneVertical: Double,
+    val faceOneHorizontal: Double,
+    val faceTwoVertical: Double,
+    val faceTwoHorizontal: Double
+)
+
+/**
+ * Two-face reduction
+ * Face I reads V1 = z + i and Hz1 = Hz + c/sin z; face II reads V2 = 360 - z + i and
+ * Hz2 = Hz + 180 - c/sin z. Averaging the faces gives the index error i and collimation c,
+ * which can then be removed from single-face readings.
+ */
+object TwoFaceReduction {
+
+    fun indexError(set: TwoFaceSet): Double = (set.faceOneVertical + set.faceTwoVertical - 360.0) / 2
+
+    fun collimationError(set: TwoFaceSet): Double {
+        val zenith = set.faceOneVertical - indexError(set)
+        val difference = normalise180(set.faceOneHorizontal - set.faceTwoHorizontal + 180.0)
+        return difference / 2 * sin(Math.toRadians(zenith))
+    }
+
+    fun compute(model: String, sets: List<TwoFaceSet>): InstrumentErrors? {
+        if (sets.isEmpty()) return null
+        return InstrumentErrors(
+            model = model,
+            indexErrorDeg = sets.map { indexError(it) }.average(),
+            collimationErrorDeg = sets.map { collimationError(it) }.average(),
+            sets = sets.size
+        )
+    }
+
+    /**
+     * Remove the errors from a single-face reading and reduce it to face I.
+     * Returns (zenith, horizontal) in degrees.
+     */
+    fun correct(verticalDeg: Double, horizontalDeg: Double, errors: InstrumentErrors): Pair<Double, Double> {
+        val vertical = verticalDeg - errors.indexErrorDeg
+        val faceTwo = vertical > 180.0
+        val zenith = if (faceTwo) 360.0 - vertical else vertical
+        val sinZenith = sin(Math.toRadians(zenith)).takeIf { abs(it) > 1e-6 } ?: return Pair(zenith, horizontalDeg)
+
+        val collimation = errors.collimationErrorDeg / sinZenith
+        val horizontal = if (faceTwo) horizontalDeg + collimation - 180.0 else horizontalDeg - collimation
+        return Pair(zenith, normalise360(horizontal))
+    }
+
+    private fun normalise180(angle: Double): Double {
+        var a = angle % 360.0
+        if (a > 180.0) a -= 360.0
+        if (a < -180.0) a += 360.0
+        return a
+    }
+
+    private fun normalise360(angle: Double): Double {
+        val a = angle % 360.0
+        return if (a < 0) a + 360.0 else a
+    }
+}
+
+/**
+ * Persists instrument errors per EDM model
+ */
+class InstrumentErrorStore(context: Context) {
+
+    companion object {
+        private const val TAG = "InstrumentErrors"
+        private const val PREFS_NAME = "polyfield_instrument_errors"
+    }
+
+    private val preferences = context.getSharedPreferences(PREFS_NAME, Context.MODE_PRIVATE)
+    private val gson = Gson()
+
+    fun load(model: String): InstrumentErrors? {
+        return try {
+            val json = preferences.getString(model, null) ?: return null
+            gson.fromJson(json, InstrumentErrors::class.java)
+        } catch (e: Exception) {
+            Log.e(TAG, "Error loading instrument errors for $model: ${e.message}")
+            null
+        }
+    }
+
+    fun save(errors: InstrumentErrors) {
+        preferences.edit()
+            .putString(errors.model, gson.toJson(errors))
+            .apply()
+    }
+
+    fun clear(model: String) {
+        preferences.edit().remove(model).apply()
+    }
+}
+
+/**
+ * Persists the prism constant of the reflector used with each EDM, by device type
+ */
+class PrismConstantStore(context: Context) {
+
+    companion object {
+        private const val PREFS_NAME = "polyfield_prism_constants"
+    }
+
+    private val preferences = context.getSharedPreferences(PREFS_NAME, Context.MODE_PRIVATE)
+
+    fun load(deviceType: String): Double =
+        preferences.getString(deviceType, null)?.toDoubleOrNull() ?: 0.0
+
+    fun save(deviceType: String, constantMm: Double) {
+        preferences.edit().apply {
+            if (constantMm == 0.0) remove(deviceType) else putString(deviceType, constantMm.toString())
+        }.apply()
+    }
+}
+
+/**
+ * Height of the trunnion axis and of the prism above the ground, for one EDM setup
+ */
+data class SetupHeights(
+    val instrumentHeightM: Double = 0.0,
+    val targetHeightM: Double = 0.0
+)
+
+/**
+ * Persists the setup heights of each EDM, by device type, so they survive a restart
+ * mid-competition
+ */
+class SetupHeightStore(context: Context) {
+
+    companion object {
+        private const val PREFS_NAME = "polyfield_setup_heights"
+    }
+
+    private val preferences = context.getSharedPreferences(PREFS_NAME, Context.MODE_PRIVATE)
+    private val gson = Gson()
+
+    fun load(deviceType: String): SetupHeights =
+        preferences.getString(deviceType, null)
+            ?.let { runCatching { gson.fromJson(it, SetupHeights::class.java) }.getOrNull() }
+            ?: SetupHeights()
+
+    fun save(deviceType: String, heights: SetupHeights) {
+        preferences.edit().putString(deviceType, gson.toJson(heights)).apply()
+    }
+}
@@ -174,6 +174,9 @@ enum class TestStatus {
     RUNNING, PASSED, FAILED
 }
 
+private const val INTEGRATION_TEST_SEED = 20250101L
+private const val INTEGRATION_TEST_START_MILLIS = 1735732800000L // 2025-01-01 12:00 UTC
+
 /**
  * Run integration tests for all components
  */
@@ -186,6 +189,8 @@ fun runIntegrationTests(
     onComplete: () -> Unit
 ) {
     modeManager.viewModelScope.launch {
+        // Fixed clock and seed so demo data, IDs and timestamps are the same every run
+        SessionSources.install(ManualClock(INTEGRATION_TEST_START_MILLIS, stepMs = 1), seed = INTEGRATION_TEST_SEED)
         try {
             // Test 1: Mode Manager
             onResult(TestResult("Mode Manager Initialization", TestStatus.RUNNING))
@@ -317,7 +322,8 @@ fun runIntegrationTests(
             ))
             Log.e("IntegrationTest", "Integration tests failed", e)
         } finally {
+            SessionSources.reset()
             onComplete()
         }
     }
-}
\ No newline at end of file
+}
@@ -0,0 +1,163 @@
+package com.polyfieldandroid
+
+import java.util.Locale
+
+/**
+ * One athlete's line on the live leaderboard
+ */
+data class LeaderboardEntry(
+    val place: Int?,
+    val bib: String,
+    val name: String,
+    val club: String,
+    val bestMark: Double?,
+    val bestMarkWind: Double?,
+    val lastMark: String?,          // Latest attempt as displayed, e.g. "15.21 m" or "X"
+    val attemptsTaken: Int,
+    val attemptsRemaining: Int,
+    val advancing: Boolean?,        // Null while there is no cut to make
+    val needsToAdvance: Double?,    // Mark that moves the athlete inside the cut
+    val needsToLead: Double?,       // Mark that takes the lead outright
+    val qualification: String? = null // Q or q in a qualification round
+) {
+    val hint: String?
+        get() = when {
+            attemptsRemaining == 0 -> null
+            needsToAdvance != null -> "Needs ${formatMark(needsToAdvance)} m to advance"
+            needsToLead != null -> "Needs ${formatMark(needsToLead)} m to lead"
+            else -> null
+        }
+
+    fun toMap(): Map<String, Any?> = mapOf(
+        "place" to place,
+        "bib" to bib,
+        "name" to name,
+        "club" to club,
+        "bestMark" to bestMark,
+        "bestMarkWind" to bestMarkWind,
+        "lastMark" to lastMark,
+        "attemptsTaken" to attemptsTaken,
+        "attemptsRemaining" to attemptsRemaining,
+        "advancing" to advancing,
+        "needsToAdvance" to needsToAdvance,
+        "needsToLead" to needsToLead,
+        "hint" to hint,
+        "qualification" to qualification
+    )
+
+    private fun formatMark(metres: Double): String = String.format(Locale.US, "%.2f", metres)
+}
+
+data class Leaderboard(
+    val eventId: String,
+    val eventName: String,
+    val round: Int,
+    val totalRounds: Int,
+    val cutSize: Int?,              // Athletes through to the final rounds, if there is a cut
+    val entries: List<LeaderboardEntry>,
+    val updatedAt: Long = SessionSources.now()
+) {
+    fun toMap(): Map<String, Any> = mapOf(
+        "eventId" to eventId,
+        "eventName" to eventName,
+        "round" to round,
+        "totalRounds" to totalRounds,
+        "cutSize" to (cutSize ?: 0),
+        "entries" to entries.map { it.toMap() },
+        "updatedAt" to updatedAt
+    )
+
+    companion object {
+        // Marks are recorded to the centimetre, so one more centimetre beats a mark outright
+        private const val MARK_STEP_M = 0.01
+
+        // Attempts before the cut to the final rounds
+        const val ROUNDS_BEFORE_CUT = 3
+
+        /**
+         * Leaderboard from the results sheet. Before the cut, athletes outside it are told the
+         * mark that takes them past the last qualifier; afterwards those through are told the
+         * mark that takes the lead. In a qualification round the hint is the lower of the
+         * standard and the mark that moves the athlete inside the qualifying places.
+         */
+        fun build(
+            sheet: ResultsSheet,
+            currentRound: Int,
+            settings: CompetitionSettings,
+            progressingAthletes: List<String>
+        ): Leaderboard {
+            val cutMade = progressingAthletes.isNotEmpty()
+            val cutSize = when {
+                cutMade -> progressingAthletes.size
+                settings.cutoffEnabled && !settings.allowAllAthletes && settings.athleteCutoff > 0 &&
+                    settings.numberOfRounds > ROUNDS_BEFORE_CUT -> settings.athleteCutoff
+                else -> null
+            }
+
+            val ranked = sheet.entries.filter { it.bestMark != null }
+            val leaderMark = ranked.firstOrNull()?.bestMark
+            val cutMark = cutSize?.let { ranked.getOrNull(it - 1)?.bestMark }
+
+            // Qualification: the standard, or beating the last athlete currently inside the target
+            val qualifyingMark = if (settings.isQualificationRound) {
+                val onPerformance = ranked.getOrNull(settings.qualifierTarget - 1)?.bestMark?.let { it + MARK_STEP_M }
+                    ?: if (ranked.size < settings.qualifierTarget) MARK_STEP_M else null
+                listOfNotNull(settings.qualifyingStandardM, onPerformance).minOrNull()
+            } else {
+                null
+            }
+
+            val entries = sheet.entries.map { entry ->
+                val athlete = entry.athlete
+                val taken = athlete.attempts.map { it.round }.distinct().size
+                val eliminated = cutMade && athlete.bib !in progressingAthletes
+                // Automatic qualifiers take no further attempts
+                val allowed = when {
+                    entry.qualification == QualificationMark.AUTOMATIC -> taken
+                    eliminated -> ROUNDS_BEFORE_CUT
+                    else -> sheet.rounds
+                }
+                val qualifyingPosition = settings.isQualificationRound && entry.place != null && entry.place <= settings.qualifierTarget
+                val insideCut = cutSize?.let { size -> entry.place != null && entry.place <= size }
+
+                LeaderboardEntry(
+                    place = entry.place,
+                    bib = athlete.bib,
+                    name = athlete.name,
+                    club = athlete.club,
+                    bestMark = entry.bestMark,
+                    bestMarkWind = entry.bestMarkWind,
+                    lastMark = athlete.attempts.maxByOrNull { it.timestamp }?.getDisplayMark(),
+                    attemptsTaken = taken,
+                    attemptsRemaining = (allowed - taken).coerceAtLeast(0),
+                    advancing = when {
+                        cutSize == null -> null
+                        cutMade -> !eliminated
+                        else -> insideCut
+                    },
+                    needsToAdvance = when {
+                        settings.isQualificationRound -> qualifyingMark.takeIf { entry.qualification == null && !qualifyingPosition }
+                        // Fewer marks than places in the cut: any valid mark goes through
+                        !cutMade && insideCut == false && currentRound <= ROUNDS_BEFORE_CUT -> (cutMark ?: 0.0) + MARK_STEP_M
+                        else -> null
+                    },
+                    qualification = entry.qualification?.symbol,
+                    needsToLead = if (leaderMark != null && entry.place != 1 && !eliminated && !settings.isQualificationRound && (cutMade || cutSize == null)) {
+                        leaderMark + MARK_STEP_M
+                    } else {
+                        null
+                    }
+                )
+            }
+
+            return Leaderboard(
+                eventId = sheet.eventId,
+                eventName = sheet.eventName,
+                round = currentRound,
+                totalRounds = sheet.rounds,
+                cutSize = cutSize,
+                entries = entries
+            )
+        }
+    }
+}
@@ -0,0 +1,158 @@
+package com.polyfieldandroid
+
+import android.util.Log
+
+/**
+ * Leica GSI Online Translator
+ * 
+ * Handles Leica total stations speaking GSI Online (TPS300/400/700/1100/1200 series).
+ * 
+ * Request: "GET/M/WI21/WI22/WI31" - measure, then return Hz, V and slope distance
+ * Angles only: "GET/I/WI21/WI22" - the current Hz and V, without firing the distance meter
+ * EDM mode: "SET/161/0" for a prism (IR standard), "SET/161/10" reflectorless (RL standard)
+ * Response (GSI8): "21.324+12345670 22.324+09000000 31..00+00012345"
+ * Response (GSI16): the same words, prefixed with '*' and carrying 16 data digits
+ * - Word index (2 chars), info block (4 chars, the last is the units), sign, data digits
+ * - 21: Horizontal angle
+ * - 22: Vertical angle (zenith)
+ * - 31: Slope distance
+ * Errors come back as "@W<code>" or "@E<code>".
+ */
+class LeicaGSITranslator(deviceSpec: EDMDeviceSpec) : EDMDeviceTranslator(deviceSpec) {
+    
+    companion object {
+        private const val TAG = "LeicaGSITranslator"
+        
+        private val MEASUREMENT_COMMAND = "GET/M/WI21/WI22/WI31\r\n".toByteArray(Charsets.US_ASCII)
+        private val ANGLE_COMMAND = "GET/I/WI21/WI22\r\n".toByteArray(Charsets.US_ASCII)
+        private val PRISM_MODE_COMMAND = "SET/161/0\r\n".toByteArray(Charsets.US_ASCII)
+        private val REFLECTORLESS_MODE_COMMAND = "SET/161/10\r\n".toByteArray(Charsets.US_ASCII)
+        
+        private const val WI_HORIZONTAL = "21"
+        private const val WI_VERTICAL = "22"
+        private const val WI_SLOPE_DISTANCE = "31"
+        
+        private const val FEET_TO_MM = 304.8
+        
+        private val STATUS_CODES = mapOf(
+            "W127" to "Invalid command",
+            "W139" to "EDM error - no prism found",
+            "W158" to "Measurement aborted",
+            "E112" to "Battery low",
+            "E139" to "EDM error",
+            "E158" to "Sensor error"
+        )
+        
+        private val ERROR_KINDS = mapOf(
+            "W127" to EDMErrorKind.COMMAND_REJECTED,
+            "W139" to EDMErrorKind.NO_PRISM,
+            "W158" to EDMErrorKind.ABORTED,
+            "E112" to EDMErrorKind.BATTERY_LOW,
+            "E139" to EDMErrorKind.HARDWARE,
+            "E158" to EDMErrorKind.HARDWARE
+        )
+    }
+    
+    override val errorCodes: Map<String, EDMErrorKind> = ERROR_KINDS
+    
+    override fun getMeasurementCommand(): ByteArray {
+        return MEASUREMENT_COMMAND
+    }
+    
+    override fun parseResponse(rawResponse: String): EDMParsedReading {
+        Log.d(TAG, "Parsing Leica GSI response: '$rawResponse'")
+        
+        val trimmed = rawResponse.trim().removePrefix("*")
+        if (trimmed.startsWith("@")) {
+            val code = trimmed.removePrefix("@")
+            return instrumentErrorReading(code)
+        }
+        
+        return try {
+            val words = gsiWords(trimmed)
+            
+            val hz = words[WI_HORIZONTAL] ?: return invalidReading("No horizontal angle (WI21) in '$trimmed'")
+            val v = words[WI_VERTICAL] ?: return invalidReading("No vertical angle (WI22) in '$trimmed'")
+            val sd = words[WI_SLOPE_DISTANCE] ?: return invalidReading("No slope distance (WI31) in '$trimmed'")
+            
+            EDMParsedReading(
+                slopeDistanceMm = parseDistanceMm(sd),
+                verticalAngleDegrees = parseAngle(v),
+                horizontalAngleDegrees = parseAngle(hz),
+                verticalAngleRaw = v.substring(6),
+                horizontalAngleRaw = hz.substring(6)
+            )
+        } catch (e: Exception) {
+            Log.e(TAG, "Error parsing Leica response: '$rawResponse'", e)
+            invalidReading("Parse error: ${e.message}")
+        }
+    }
+    
+    override fun getAngleCommand(): ByteArray = ANGLE_COMMAND
+    
+    override fun getMeasurementModeCommand(mode: EDMMeasurementMode): ByteArray = when (mode) {
+        EDMMeasurementMode.PRISM -> PRISM_MODE_COMMAND
+        EDMMeasurementMode.REFLECTORLESS -> REFLECTORLESS_MODE_COMMAND
+    }
+    
+    override fun parseAngleReply(rawResponse: String): Double? {
+        val trimmed = rawResponse.trim().removePrefix("*")
+        if (trimmed.startsWith("@")) return null
+        return gsiWords(trimmed)[WI_HORIZONTAL]?.let { runCatching { parseAngle(it) }.getOrNull() }
+    }
+    
+    override fun toGoMobileFormat(parsedReading: EDMParsedReading): String {
+        return standardGoMobileFormat(parsedReading)
+    }
+    
+    override fun isResponseComplete(response: String): Boolean {
+        val trimmed = response.trim().removePrefix("*")
+        if (trimmed.startsWith("@")) return trimmed.length >= 5
+        val indices = trimmed.split("\\s+".toRegex()).map { it.removePrefix("*").take(2) }
+        return indices.containsAll(listOf(WI_HORIZONTAL, WI_VERTICAL, WI_SLOPE_DISTANCE))
+    }
+    
+    override fun interpretStatusCode(statusCode: String?): String? {
+        return statusCode?.let { STATUS_CODES[it] }
+    }
+    
+    /**
+     * GSI words of a reply by word index
+     */
+    private fun gsiWords(reply: String): Map<String, String> =
+        reply.split("\\s+".toRegex())
+            .map { it.removePrefix("*") }
+            .filter { it.length >= 8 }
+            .associateBy { it.substring(0, 2) }
+    
+    /**
+     * Signed data digits of a GSI word, before unit scaling
+     */
+    private fun wordValue(word: String): Long {
+        val sign = if (word[6] == '-') -1 else 1
+        return sign * word.substring(7).toLong()
+    }
+    
+    private fun parseAngle(word: String): Double {
+        val value = wordValue(word)
+        return when (val units = word[5]) {
+            '2' -> value / 100000.0 * 0.9        // 400 gon
+            '3' -> value / 100000.0              // 360° decimal
+            '4' -> parseDDDMMSS((value / 10).toString()) + (value % 10) / 36000.0 // 360° sexagesimal, tenths of a second
+            '5' -> value / 10000.0 * 360.0 / 6400.0 // 6400 mil
+            else -> throw IllegalArgumentException("Unsupported GSI angle units '$units'")
+        }
+    }
+    
+    private fun parseDistanceMm(word: String): Double {
+        val value = wordValue(word)
+        return when (val units = word[5]) {
+            '0' -> value.toDouble()              // metres, mm
+            '1' -> value / 1000.0 * FEET_TO_MM   // feet, 1/1000
+            '6' -> value / 10.0                  // metres, 1/10 mm
+            '7' -> value / 10000.0 * FEET_TO_MM  // feet, 1/10000
+            '8' -> value / 100.0                 // metres, 1/100 mm
+            else -> throw IllegalArgumentException("Unsupported GSI distance units '$units'")
+        }
+    }
+}
@@ -63,6 +63,9 @@ import kotlinx.coroutines.flow.MutableStateFlow
 import org.json.JSONObject
 import kotlinx.coroutines.flow.StateFlow
 import kotlinx.coroutines.flow.asStateFlow
+import kotlinx.coroutines.flow.distinctUntilChanged
+import kotlinx.coroutines.flow.drop
+import kotlinx.coroutines.flow.map
 import kotlinx.coroutines.launch
 import androidx.lifecycle.viewModelScope
 import androidx.lifecycle.ViewModelProvider
@@ -96,18 +99,19 @@ data class DeviceConfig(
 
 // Complete Calibration Record
 data class CalibrationRecord(
-    val id: String = java.util.UUID.randomUUID().toString(),
+    val id: String = SessionSources.newId(),
     val circleType: String,
     val targetRadius: Double,
-    val timestamp: Long = System.currentTimeMillis(),
-    val dateString: String = java.text.SimpleDateFormat("yyyy-MM-dd HH:mm:ss", java.util.Locale.getDefault()).format(java.util.Date()),
+    val timestamp: Long = SessionSources.now(),
+    val dateString: String = java.text.SimpleDateFormat("yyyy-MM-dd HH:mm:ss", java.util.Locale.getDefault()).format(java.util.Date(SessionSources.now())),
     val stationCoordinates: Pair<Double, Double>? = null,
     val edgeResult: EdgeResult? = null,
     val sectorLineDistance: Double? = null,
-    val sectorLineCoordinates: Pair<Double, Double>? = null
+    val sectorLineCoordinates: Pair<Double, Double>? = null,
+    val prismConstantMm: Double = 0.0         // Reflector constant the centre was measured with
 ) {
     fun isFromToday(): Boolean {
-        val today = java.text.SimpleDateFormat("yyyy-MM-dd", java.util.Locale.getDefault()).format(java.util.Date())
+        val today = java.text.SimpleDateFormat("yyyy-MM-dd", java.util.Locale.getDefault()).format(java.util.Date(SessionSources.now()))
         val calibrationDate = java.text.SimpleDateFormat("yyyy-MM-dd", java.util.Locale.getDefault()).format(java.util.Date(timestamp))
         return today == calibrationDate
     }
@@ -134,6 +138,8 @@ data class CalibrationState(
     val sectorLineSet: Boolean = false,
     val sectorLineDistance: Double? = null,
     val sectorLineCoordinates: Pair<Double, Double>? = null,
+    val stopboardVerification: StopboardVerification? = null,  // Shot put only
+    val prismConstantMm: Double = 0.0,
     val selectedHistoricalCalibration: CalibrationRecord? = null
 )
 
@@ -153,7 +159,7 @@ data class AppSettings(
 
 // DEBUG: Serial Communication Log Entry (REMOVE WHEN DEBUG COMPLETE)
 data class SerialCommLogEntry(
-    val timestamp: Long = System.currentTimeMillis(),
+    val timestamp: Long = SessionSources.now(),
     val direction: String, // "OUT" or "IN"
     val data: String,
     val dataHex: String = "",
@@ -706,7 +712,8 @@ data class DetectedDevice(
     val vendorId: Int,
     val productId: Int,
     val deviceName: String,
-    val serialPath: String
+    val serialPath: String,
+    val likelyDevice: String = "unknown" // edm, wind, unknown - set when ports are probed
 )
 
 // Complete App State matching original
@@ -751,8 +758,29 @@ class AppViewModel(context: android.content.Context, private val fastInit: Boole
     // SharedPreferences for persistent storage - use Application context to avoid memory leaks
     private val sharedPrefs = context.applicationContext.getSharedPreferences("PolyFieldCalibrations", android.content.Context.MODE_PRIVATE)
     private val settingsPrefs = context.applicationContext.getSharedPreferences("PolyFieldSettings", android.content.Context.MODE_PRIVATE)
+
+    // Calibration changes are logged so the calibration in use survives a crash
+    private val competitionWal = CompetitionWal(context.applicationContext)
+
+    // Completed calibrations per venue circle, kept across sessions for comparison
+    private val calibrationArchive = CalibrationArchive(context.applicationContext)
+
+    // Stopboard position check in progress, if any
+    private var stopboardProcedure: StopboardVerificationProcedure? = null
+
+    // Station being calibrated; each keeps its own centre in the EDM interface
+    @Volatile private var calibrationStation = "edm"
     
     init {
+        // Recovered device failures surface as a dialog instead of a crash
+        CrashReporter.setCallback { report ->
+            if (!report.fatal) {
+                viewModelScope.launch(kotlinx.coroutines.Dispatchers.Main) {
+                    showErrorDialog("Internal Error", "${report.entryPoint} failed: ${report.exceptionType.substringAfterLast('.')}: ${report.error}")
+                }
+            }
+        }
+
         if (fastInit) {
             // Fast initialization - ZERO blocking operations
             android.util.Log.d("PolyField", "AppViewModel fast initialization - all I/O deferred to background")
@@ -763,24 +791,31 @@ class AppViewModel(context: android.content.Context, private val fastInit: Boole
                 loadCriticalSettingsFromDisk()
                 loadRemainingSettingsFromDisk()
                 loadCalibrationHistoryFromDisk()
+                restoreCalibrationFromWal()
 
-                // Heavy modules initialized only when accessed
+                // Restore the last device set only once settings (demo mode among them) are
+                // loaded; this is the first access of the EDM module, so it is built here in
+                // the background rather than on the startup path
+                reconnectAll()
             }
         } else {
             // Traditional full initialization for backward compatibility
             viewModelScope.launch {
                 // Initialize heavy modules first
-                edmInterface = EDMInterface(appContext)
                 edmModule = EDMModule(appContext)
+                edmInterface = edmModule?.edmInterface
 
                 // Load settings
                 loadSettingsFromDisk()
 
                 // Then load calibration history
                 loadCalibrationHistoryFromDisk()
+                restoreCalibrationFromWal()
 
                 // Setup debug logger after startup
                 setupDebugLogger()
+
+                reconnectAll()
             }
 
             android.util.Log.d("PolyField", "AppViewModel initialized - Settings will load asynchronously")
@@ -791,7 +826,7 @@ class AppViewModel(context: android.content.Context, private val fastInit: Boole
     private fun getEDMInterface(): EDMInterface {
         return edmInterface ?: run {
             android.util.Log.d("PolyField", "Lazy initializing EDMInterface...")
-            EDMInterface(appContext).also { 
+            getEDMModule().edmInterface.also { 
                 edmInterface = it
                 // Setup debug logger after EDM initialization
                 if (fastInit) {
@@ -803,7 +838,10 @@ class AppViewModel(context: android.content.Context, private val fastInit: Boole
         }
     }
     
-    private fun getEDMModule(): EDMModule {
+    /**
+     * Shared EDM module - the single owner of device connections for the whole app
+     */
+    fun getEDMModule(): EDMModule {
         return edmModule ?: run {
             android.util.Log.d("PolyField", "Lazy initializing EDMModule...")
             EDMModule(appContext).also { 
@@ -832,6 +870,25 @@ class AppViewModel(context: android.content.Context, private val fastInit: Boole
         }
     }
     
+    /**
+     * Restore the calibration in use before the app last stopped, then log every later change
+     */
+    private fun restoreCalibrationFromWal() {
+        competitionWal.recover().calibration?.let { calibration ->
+            _uiState.value = _uiState.value.copy(calibration = calibration)
+            android.util.Log.d("PolyField", "Restored ${calibration.circleType} calibration from write-ahead log")
+        }
+
+        viewModelScope.launch(kotlinx.coroutines.Dispatchers.IO) {
+            uiState.map { it.calibration }
+                .distinctUntilChanged()
+                .drop(1)
+                .collect { calibration ->
+                    competitionWal.record(WalOperation.CALIBRATION, calibration = calibration)
+                }
+        }
+    }
+
     /**
      * Load calibration history from persistent storage
      */
@@ -882,7 +939,8 @@ class AppViewModel(context: android.content.Context, private val fastInit: Boole
                     stationCoordinates = stationCoordinates,
                     edgeResult = edgeResult,
                     sectorLineDistance = if (jsonObj.has("sectorLineDistance")) jsonObj.getDouble("sectorLineDistance") else null,
-                    sectorLineCoordinates = sectorLineCoordinates
+                    sectorLineCoordinates = sectorLineCoordinates,
+                    prismConstantMm = jsonObj.optDouble("prismConstantMm", 0.0)
                 )
                 
                 calibrations.add(calibration)
@@ -948,6 +1006,15 @@ class AppViewModel(context: android.content.Context, private val fastInit: Boole
         }
     }
     
+    /**
+     * Apply new timeouts, read policy or wind window without reconnecting devices
+     */
+    fun updateDeviceRuntimeConfig(config: DeviceRuntimeConfig): DeviceRuntimeConfig {
+        return getEDMModule().updateRuntimeConfig(config)
+    }
+
+    fun getDeviceRuntimeConfig(): DeviceRuntimeConfig = getEDMModule().getRuntimeConfig()
+    
     fun updateSettings(settings: AppSettings) {
         _uiState.value = _uiState.value.copy(settings = settings)
         
@@ -1028,6 +1095,42 @@ class AppViewModel(context: android.content.Context, private val fastInit: Boole
         }
     }
 
+    /**
+     * Probe each USB serial port and annotate detected devices with their likely role
+     */
+    fun probeUsbDevices() {
+        if (_uiState.value.isDemoMode) {
+            refreshUsbDevices()
+            return
+        }
+
+        viewModelScope.launch {
+            _uiState.value = _uiState.value.copy(isLoading = true)
+            try {
+                val result = getEDMModule().listUsbDevices(probe = true)
+                @Suppress("UNCHECKED_CAST")
+                val usbDevices = (result["ports"] as? List<Map<String, Any>>) ?: emptyList()
+
+                val detectedDevices = usbDevices.mapIndexed { index, deviceInfo ->
+                    DetectedDevice(
+                        vendorId = deviceInfo["vendorId"] as? Int ?: 0,
+                        productId = deviceInfo["productId"] as? Int ?: 0,
+                        deviceName = deviceInfo["description"] as? String ?: "USB Device ${index + 1}",
+                        serialPath = deviceInfo["port"] as? String ?: "/dev/ttyUSB$index",
+                        likelyDevice = deviceInfo["likelyDevice"] as? String ?: "unknown"
+                    )
+                }
+
+                android.util.Log.d("PolyField", "Probed ${detectedDevices.size} USB devices: ${detectedDevices.map { it.likelyDevice }}")
+                updateDetectedDevices(detectedDevices)
+            } catch (e: Exception) {
+                android.util.Log.e("PolyField", "USB probe failed", e)
+            } finally {
+                _uiState.value = _uiState.value.copy(isLoading = false)
+            }
+        }
+    }
+
     /**
      * Test scoreboard with countdown sequence (3-2-1-0)
      */
@@ -1192,6 +1295,541 @@ class AppViewModel(context: android.content.Context, private val fastInit: Boole
         }
     }
     
+    /**
+     * Connect a device from a saved connection profile
+     */
+    fun connectProfile(name: String) {
+        viewModelScope.launch {
+            _uiState.value = _uiState.value.copy(isLoading = true)
+            try {
+                val profile = getEDMModule().getConnectionProfiles().find { it.name == name }
+                val result = getEDMModule().connectProfile(name)
+                if (result["success"] == true && profile != null) {
+                    applyProfileConnection(profile, result)
+                } else {
+                    showErrorDialog("Connection Failed", result["error"] as? String ?: "Could not connect profile $name")
+                }
+            } catch (e: Exception) {
+                android.util.Log.e("PolyField", "Profile connection error", e)
+                showErrorDialog("Connection Failed", "Profile connection error: ${e.message}")
+            } finally {
+                _uiState.value = _uiState.value.copy(isLoading = false)
+            }
+        }
+    }
+
+    /**
+     * Connect a device through a paired Bluetooth serial adapter
+     */
+    fun connectBluetoothDevice(deviceType: String, macAddress: String, driver: String? = null) {
+        viewModelScope.launch {
+            _uiState.value = _uiState.value.copy(isLoading = true)
+            try {
+                val result = getEDMModule().connectBluetoothDevice(deviceType, macAddress, driver)
+                if (result["success"] == true) {
+                    val profile = ConnectionProfile(
+                        name = "Bluetooth $deviceType",
+                        deviceType = deviceType,
+                        transport = "bluetooth",
+                        address = macAddress,
+                        driver = driver
+                    )
+                    applyProfileConnection(profile, result)
+                } else {
+                    showErrorDialog("Connection Failed", result["error"] as? String ?: "Could not connect to $macAddress")
+                }
+            } catch (e: Exception) {
+                android.util.Log.e("PolyField", "Bluetooth connection error", e)
+                showErrorDialog("Connection Failed", "Bluetooth connection error: ${e.message}")
+            } finally {
+                _uiState.value = _uiState.value.copy(isLoading = false)
+            }
+        }
+    }
+
+    /**
+     * Connect a Bluetooth LE device through its notifying characteristic
+     */
+    fun connectBleDevice(
+        deviceType: String,
+        deviceAddress: String,
+        serviceUuid: String,
+        characteristicUuid: String,
+        driver: String? = null
+    ) {
+        viewModelScope.launch {
+            _uiState.value = _uiState.value.copy(isLoading = true)
+            try {
+                val result = getEDMModule().connectBleDevice(deviceType, deviceAddress, serviceUuid, characteristicUuid, driver)
+                if (result["success"] == true) {
+                    val profile = ConnectionProfile(
+                        name = "Bluetooth LE $deviceType",
+                        deviceType = deviceType,
+                        transport = "ble",
+                        address = deviceAddress,
+                        driver = driver,
+                        bleService = serviceUuid,
+                        bleCharacteristic = characteristicUuid
+                    )
+                    applyProfileConnection(profile, result)
+                } else {
+                    showErrorDialog("Connection Failed", result["error"] as? String ?: "Could not connect to $deviceAddress")
+                }
+            } catch (e: Exception) {
+                android.util.Log.e("PolyField", "Bluetooth LE connection error", e)
+                showErrorDialog("Connection Failed", "Bluetooth LE connection error: ${e.message}")
+            } finally {
+                _uiState.value = _uiState.value.copy(isLoading = false)
+            }
+        }
+    }
+
+    /**
+     * Connect a device on an Ethernet serial server that speaks Telnet/RFC 2217
+     */
+    fun connectRFC2217Device(
+        deviceType: String,
+        address: String,
+        port: Int,
+        driver: String? = null,
+        serialConfig: SerialPortConfig? = null
+    ) {
+        viewModelScope.launch {
+            _uiState.value = _uiState.value.copy(isLoading = true)
+            try {
+                val result = getEDMModule().connectRFC2217Device(deviceType, address, port, driver, serialConfig)
+                if (result["success"] == true) {
+                    val profile = ConnectionProfile(
+                        name = "RFC 2217 $deviceType",
+                        deviceType = deviceType,
+                        transport = "rfc2217",
+                        address = address,
+                        port = port,
+                        serialConfig = serialConfig,
+                        driver = driver
+                    )
+                    applyProfileConnection(profile, result)
+                } else {
+                    showErrorDialog("Connection Failed", result["error"] as? String ?: "Could not connect to $address:$port")
+                }
+            } catch (e: Exception) {
+                android.util.Log.e("PolyField", "RFC 2217 connection error", e)
+                showErrorDialog("Connection Failed", "RFC 2217 connection error: ${e.message}")
+            } finally {
+                _uiState.value = _uiState.value.copy(isLoading = false)
+            }
+        }
+    }
+
+    /**
+     * Connect a USB device whose port the host drives, passing raw bytes through the
+     * EDM module's submitUSBData and getPendingUSBWrite
+     */
+    fun connectUSBPassthrough(deviceType: String, deviceName: String, driver: String? = null) {
+        viewModelScope.launch {
+            _uiState.value = _uiState.value.copy(isLoading = true)
+            try {
+                val result = getEDMModule().connectUSBPassthrough(deviceType, deviceName, driver)
+                if (result["success"] == true) {
+                    val profile = ConnectionProfile(
+                        name = "USB $deviceType",
+                        deviceType = deviceType,
+                        transport = "usb_passthrough",
+                        address = deviceName,
+                        driver = driver
+                    )
+                    applyProfileConnection(profile, result)
+                } else {
+                    showErrorDialog("Connection Failed", result["error"] as? String ?: "Could not register $deviceName")
+                }
+            } catch (e: Exception) {
+                android.util.Log.e("PolyField", "USB passthrough connection error", e)
+                showErrorDialog("Connection Failed", "USB passthrough connection error: ${e.message}")
+            } finally {
+                _uiState.value = _uiState.value.copy(isLoading = false)
+            }
+        }
+    }
+
+    /**
+     * Connect a second (or further) EDM as a named station, e.g. "edm_hammer" alongside
+     * "edm_discus"
+     */
+    fun connectSerialDeviceNamed(deviceId: String, role: String, portName: String, driver: String? = null) {
+        viewModelScope.launch {
+            _uiState.value = _uiState.value.copy(isLoading = true)
+            try {
+                val result = getEDMModule().connectSerialDeviceNamed(deviceId, role, portName, driver)
+                if (result["success"] == true) {
+                    val profile = ConnectionProfile(
+                        name = "EDM $role",
+                        deviceType = deviceId,
+                        transport = "serial",
+                        address = portName,
+                        driver = driver
+                    )
+                    applyProfileConnection(profile, result)
+                } else {
+                    showErrorDialog("Connection Failed", result["error"] as? String ?: "Could not connect $deviceId")
+                }
+            } catch (e: Exception) {
+                android.util.Log.e("PolyField", "EDM station connection error", e)
+                showErrorDialog("Connection Failed", "EDM station connection error: ${e.message}")
+            } finally {
+                _uiState.value = _uiState.value.copy(isLoading = false)
+            }
+        }
+    }
+
+    suspend fun getEdmStations(): List<Map<String, Any>> = getEDMModule().getEdmStations()
+
+    /**
+     * Station the calibration screens set the centre, verify and measure with
+     */
+    fun setCalibrationStation(deviceId: String): Boolean {
+        if (!EDMModule.isEdmDeviceType(deviceId)) return false
+        calibrationStation = deviceId
+        return true
+    }
+
+    fun getCalibrationStation(): String = calibrationStation
+
+    /**
+     * Connect a USB-to-serial device with explicit line settings, kept for later reconnects
+     */
+    fun connectSerialDeviceWithConfig(deviceType: String, portName: String, configJson: String, driver: String? = null) {
+        viewModelScope.launch {
+            _uiState.value = _uiState.value.copy(isLoading = true)
+            try {
+                val result = getEDMModule().connectSerialDeviceWithConfig(deviceType, portName, configJson, driver)
+                if (result["success"] == true) {
+                    val profile = ConnectionProfile(
+                        name = "Serial $deviceType",
+                        deviceType = deviceType,
+                        transport = result["connectionType"] as? String ?: "serial",
+                        address = portName,
+                        serialConfig = getEDMModule().getSerialConfig(deviceType),
+                        driver = driver
+                    )
+                    applyProfileConnection(profile, result)
+                } else {
+                    showErrorDialog("Connection Failed", result["error"] as? String ?: "Could not connect to $portName")
+                }
+            } catch (e: Exception) {
+                android.util.Log.e("PolyField", "Serial connection error", e)
+                showErrorDialog("Connection Failed", "Serial connection error: ${e.message}")
+            } finally {
+                _uiState.value = _uiState.value.copy(isLoading = false)
+            }
+        }
+    }
+
+    /**
+     * Find the protocol and line settings of the EDM on a port, then connect with them
+     */
+    fun autoDetectAndConnectEDM(portName: String) {
+        viewModelScope.launch {
+            _uiState.value = _uiState.value.copy(isLoading = true)
+            val detected = try {
+                JSONObject(getEDMModule().autoDetectEDM(portName))
+            } catch (e: Exception) {
+                android.util.Log.e("PolyField", "EDM auto-detect error", e)
+                null
+            } finally {
+                _uiState.value = _uiState.value.copy(isLoading = false)
+            }
+
+            if (detected?.optBoolean("success") == true) {
+                android.util.Log.d("PolyField", "Detected ${detected.getString("model")} at ${detected.getInt("baudRate")} baud")
+                connectSerialDeviceWithConfig(
+                    "edm",
+                    portName,
+                    detected.getJSONObject("config").toString(),
+                    detected.getString("driver")
+                )
+            } else {
+                showErrorDialog("EDM Not Detected", detected?.optString("error")?.takeIf { it.isNotEmpty() } ?: "Detection failed")
+            }
+        }
+    }
+
+    fun listBluetoothDevices(): String = getEDMModule().listBluetoothDevices()
+
+    /**
+     * EDM bridges and wind gauges advertising on the local network, to connect without typing an IP
+     */
+    suspend fun discoverNetworkDevices(timeoutSeconds: Int = 5): Map<String, Any> =
+        getEDMModule().discoverNetworkDevices(timeoutSeconds)
+
+    fun saveConnectionProfile(profile: ConnectionProfile) {
+        getEDMModule().saveConnectionProfile(profile)
+    }
+
+    fun getConnectionProfiles(): List<ConnectionProfile> = getEDMModule().getConnectionProfiles()
+
+    /**
+     * End the session: flush the results outbox and close every device
+     * @return Combined shutdown summary
+     */
+    suspend fun shutdownAll(modeManager: ModeManagerViewModel? = null): Map<String, Any> {
+        val resultsStatus = try {
+            modeManager?.flushResultsOutbox()
+        } catch (e: Exception) {
+            android.util.Log.e("PolyField", "Results outbox flush failed", e)
+            null
+        }
+
+        // Nothing to close if the EDM module was never created
+        val deviceSummary = edmModule?.shutdownAll() ?: mapOf("success" to true)
+
+        return deviceSummary + mapOf(
+            "resultsPending" to (resultsStatus?.depth ?: 0)
+        )
+    }
+
+    /**
+     * Map a USB adapter to a role and bind it straight away if it is already plugged in
+     */
+    fun saveUsbRoleMapping(mapping: UsbRoleMapping) {
+        getEDMModule().saveUsbRoleMapping(mapping)
+        if (_uiState.value.isDemoMode) return
+
+        viewModelScope.launch {
+            try {
+                getEDMModule().applyUsbRoleMappings()
+                    .filter { it["bound"] == true }
+                    .forEach { result ->
+                        val profile = getEDMModule().getLastDeviceSet().find { it.deviceType == result["deviceType"] }
+                        profile?.let { applyProfileConnection(it, result) }
+                    }
+            } catch (e: Exception) {
+                android.util.Log.e("PolyField", "Applying USB role mapping failed", e)
+            }
+        }
+    }
+
+    fun removeUsbRoleMapping(mapping: UsbRoleMapping): Boolean = getEDMModule().removeUsbRoleMapping(mapping)
+
+    fun getUsbRoleMappings(): List<UsbRoleMapping> = getEDMModule().getUsbRoleMappings()
+
+    /**
+     * USB device plugged in - rebind it to its mapped or previous role if it has one
+     * @param onUnbound Called when the device matched no known role
+     */
+    fun onUsbAttached(device: UsbDevice, onUnbound: () -> Unit = {}) {
+        if (_uiState.value.isDemoMode) return
+
+        viewModelScope.launch {
+            try {
+                val result = getEDMModule().notifyUsbAttached(UsbDeviceInfo.from(device))
+                val deviceType = result["deviceType"] as? String
+                val profile = getEDMModule().getLastDeviceSet().find { it.deviceType == deviceType }
+
+                when {
+                    result["bound"] == true && profile != null -> {
+                        applyProfileConnection(profile.copy(address = device.deviceName), result)
+                        android.util.Log.d("PolyField", "USB $deviceType resumed on ${device.deviceName}")
+                    }
+                    deviceType == null -> onUnbound()
+                    result["needsPermission"] != true -> {
+                        showErrorDialog("Reconnect", "Could not reconnect $deviceType: ${result["error"]}")
+                    }
+                }
+            } catch (e: Exception) {
+                android.util.Log.e("PolyField", "USB attach handling failed", e)
+            }
+        }
+    }
+
+    /**
+     * USB device unplugged - mark any roles it served as disconnected
+     */
+    fun onUsbDetached(device: UsbDevice) {
+        val result = getEDMModule().notifyUsbDetached(UsbDeviceInfo.from(device))
+        @Suppress("UNCHECKED_CAST")
+        val lostRoles = result["disconnected"] as? List<String> ?: emptyList()
+        lostRoles.forEach { deviceType ->
+            val uiDeviceType = if (deviceType == "daktronics" || deviceType.startsWith(EDMModule.SCOREBOARD_PREFIX)) "scoreboard" else deviceType
+            updateDeviceConnectionState(uiDeviceType, false)
+        }
+    }
+
+    /**
+     * Restore the devices that were connected when the app last closed
+     */
+    fun reconnectAll() {
+        if (_uiState.value.isDemoMode) return
+
+        viewModelScope.launch {
+            try {
+                val lastDevices = getEDMModule().getLastDeviceSet()
+                if (lastDevices.isEmpty()) return@launch
+
+                val result = getEDMModule().reconnectAll()
+                @Suppress("UNCHECKED_CAST")
+                val outcomes = result["results"] as? List<Map<String, Any>> ?: emptyList()
+
+                outcomes.forEach { outcome ->
+                    val profile = lastDevices.find { it.deviceType == outcome["deviceType"] }
+                    if (outcome["success"] == true && profile != null) {
+                        applyProfileConnection(profile, outcome)
+                    }
+                }
+
+                val failures = outcomes.filter { it["success"] != true }
+                if (failures.isNotEmpty()) {
+                    val summary = failures.joinToString("\n") { "${it["deviceType"]}: ${it["message"]}" }
+                    showErrorDialog("Reconnect", "Some devices could not be restored:\n$summary")
+                }
+            } catch (e: Exception) {
+                android.util.Log.e("PolyField", "Reconnect on startup failed", e)
+            }
+        }
+    }
+
+    /**
+     * Turn automatic reconnection of a dropped device on or off
+     */
+    fun setAutoReconnect(deviceType: String, enabled: Boolean) {
+        getEDMModule().setAutoReconnect(deviceType, enabled)
+    }
+
+    /**
+     * Connection state changes since a time (epoch ms), for the connection screen to poll
+     */
+    fun getConnectionEvents(since: Long? = null): List<DeviceConnectionEvent> =
+        getEDMModule().getConnectionEvents(since)
+
+    /**
+     * Link health for a device as JSON, for the device status panel
+     */
+    fun getDeviceHealth(deviceType: String): String = getEDMModule().getDeviceHealth(deviceType)
+
+    /**
+     * Raw traffic capture for diagnosing a device, and replay of a capture through the parser
+     */
+    fun startDeviceCapture(deviceType: String): Map<String, Any> = getEDMModule().startDeviceCapture(deviceType)
+
+    fun stopDeviceCapture(deviceType: String, filePath: String? = null): Map<String, Any> =
+        getEDMModule().stopDeviceCapture(deviceType, filePath)
+
+    fun replayCapture(deviceType: String, captureJson: String): Map<String, Any> =
+        getEDMModule().replayCapture(deviceType, captureJson)
+
+    /**
+     * Prism constant of the reflector in use; recorded with the next centre set
+     */
+    fun setPrismConstant(deviceType: String, constantMm: Double): Map<String, Any> =
+        getEDMModule().setPrismConstant(deviceType, constantMm)
+
+    fun getPrismConstant(deviceType: String = "edm"): Double = getEDMModule().getPrismConstant(deviceType)
+
+    /**
+     * Temperature, pressure and humidity measured at an EDM, for its atmospheric correction
+     */
+    fun setAtmosphericConditions(deviceType: String, temperatureC: Double, pressureHpa: Double, humidityPct: Double): Map<String, Any> =
+        getEDMModule().setAtmosphericConditions(deviceType, temperatureC, pressureHpa, humidityPct)
+
+    /**
+     * Background measurement with progress, for screens that show the phase and a cancel button
+     */
+    fun startMeasurement(deviceType: String, kind: MeasurementKind, circleType: String? = null): Map<String, Any> =
+        getEDMModule().startMeasurement(deviceType, kind, circleType)
+
+    fun getMeasurementStatus(requestId: String): Map<String, Any> = getEDMModule().getMeasurementStatus(requestId)
+
+    fun cancelMeasurement(requestId: String): Map<String, Any> = getEDMModule().cancelMeasurement(requestId)
+
+    /**
+     * Move a connected station or scoreboard to another role without reconnecting
+     */
+    suspend fun reassignDevice(fromDeviceType: String, toDeviceType: String): Map<String, Any> {
+        val result = getEDMModule().reassignDevice(fromDeviceType, toDeviceType)
+        val stationAffected = fromDeviceType == calibrationStation || toDeviceType == calibrationStation
+        if (result["success"] == true && stationAffected) {
+            // The station being calibrated moved, or another instrument took its role; either
+            // way its centre and edge no longer hold
+            if (fromDeviceType == calibrationStation) calibrationStation = toDeviceType
+            _uiState.value = _uiState.value.copy(
+                calibration = _uiState.value.calibration.let { CalibrationState(circleType = it.circleType, targetRadius = it.targetRadius) }
+            )
+        }
+        return result
+    }
+
+    /**
+     * Bench check for locking and marshalling regressions: wind, scoreboard and measurements at once
+     */
+    suspend fun runDemoStressScenario(config: StressScenarioConfig = StressScenarioConfig()): Map<String, Any> =
+        getEDMModule().runDemoStressScenario(config)
+
+    fun stopDemoStressScenario() = getEDMModule().stopDemoStressScenario()
+
+    /**
+     * Quick mid-event check that the EDM's horizontal zero has not moved
+     */
+    suspend fun setOrientationReference(deviceType: String, label: String): Map<String, Any> =
+        getEDMModule().setOrientationReference(deviceType, label)
+
+    suspend fun checkOrientation(deviceType: String): Map<String, Any> = getEDMModule().checkOrientation(deviceType)
+
+    /**
+     * Storage, clocks, devices, calibration and outbox checked in one go before the first athlete
+     */
+    suspend fun runSelfDiagnostics(): Map<String, Any> =
+        getEDMModule().runSelfDiagnostics(mapOf(calibrationStation to _uiState.value.calibration))
+
+    /**
+     * Instrument and prism heights above the ground, for ground level differences
+     */
+    fun setInstrumentHeight(deviceType: String, meters: Double): Map<String, Any> =
+        getEDMModule().setInstrumentHeight(deviceType, meters)
+
+    fun setTargetHeight(deviceType: String, meters: Double): Map<String, Any> =
+        getEDMModule().setTargetHeight(deviceType, meters)
+
+    /**
+     * "prism" or "reflectorless", e.g. to mark sector lines without a prism carrier
+     */
+    suspend fun setMeasurementMode(deviceType: String, mode: String): Map<String, Any> =
+        getEDMModule().setMeasurementMode(deviceType, mode)
+
+    /**
+     * Live prism position while the grounds crew walks a sector line or arc
+     */
+    suspend fun startTracking(deviceType: String = "edm"): Map<String, Any> = getEDMModule().startTracking(deviceType)
+
+    fun stopTracking(deviceType: String = "edm"): Map<String, Any> = getEDMModule().stopTracking(deviceType)
+
+    fun getLatestTrackedPosition(deviceType: String = "edm"): Map<String, Any> = getEDMModule().getLatestTrackedPosition(deviceType)
+
+    /**
+     * Fixed demo layout, so demo screenshots and training runs match across devices
+     */
+    fun setDemoGeometry(geometry: DemoGeometry): Map<String, Any> = getEDMModule().setDemoGeometry(geometry)
+
+    fun getDemoGeometry(): DemoGeometry = getEDMModule().getDemoGeometry()
+
+    private fun applyProfileConnection(profile: ConnectionProfile, result: Map<String, Any>) {
+        // RFC 2217 serial servers are addressed like network devices
+        val networked = profile.transport == "network" || profile.transport == "rfc2217"
+        val deviceState = DeviceState(
+            connected = true,
+            connectionType = result["connectionType"] as? String ?: profile.transport,
+            serialPort = if (networked) DeviceState().serialPort else profile.address,
+            ipAddress = if (networked) profile.address else DeviceState().ipAddress,
+            port = if (networked) profile.port else DeviceState().port,
+            deviceName = (result["edmDevice"] as? String)?.takeIf { it.isNotEmpty() } ?: profile.name
+        )
+        // Daktronics and named boards share the scoreboard slot in the UI
+        val uiDeviceType = if (profile.deviceType == "daktronics" || profile.deviceType.startsWith(EDMModule.SCOREBOARD_PREFIX)) {
+            "scoreboard"
+        } else {
+            profile.deviceType
+        }
+        updateDeviceConfig(uiDeviceType, deviceState)
+    }
+    
     private fun updateDeviceConnectionState(deviceType: String, connected: Boolean) {
         val devices = _uiState.value.devices
         val updatedDevices = when (deviceType) {
@@ -1219,9 +1857,8 @@ class AppViewModel(context: android.content.Context, private val fastInit: Boole
         
         if (_uiState.value.isDemoMode) {
             // Demo mode - simulate centre setting
-            val timestamp = java.text.SimpleDateFormat("yyyy-MM-dd HH:mm:ss", java.util.Locale.getDefault()).format(java.util.Date())
-            val stationX = (kotlin.random.Random.nextDouble() - 0.5) * 20 // ±10m
-            val stationY = (kotlin.random.Random.nextDouble() - 0.5) * 20 // ±10m
+            val timestamp = java.text.SimpleDateFormat("yyyy-MM-dd HH:mm:ss", java.util.Locale.getDefault()).format(java.util.Date(SessionSources.now()))
+            val (stationX, stationY) = getEDMModule().getDemoGeometry().station()
             
             _uiState.value = _uiState.value.copy(
                 calibration = _uiState.value.calibration.copy(
@@ -1299,8 +1936,9 @@ class AppViewModel(context: android.content.Context, private val fastInit: Boole
         if (_uiState.value.isDemoMode) {
             // Demo mode - simulate edge verification
             val targetRadius = _uiState.value.calibration.targetRadius
+            val geometry = getEDMModule().getDemoGeometry()
             val measurements = List(5) { 
-                targetRadius + (kotlin.random.Random.nextDouble() - 0.5) * 0.01 // ±5mm variation
+                targetRadius + geometry.edgeNoiseM()
             }
             val averageRadius = measurements.average()
             val deviation = kotlin.math.abs(averageRadius - targetRadius)
@@ -1382,7 +2020,7 @@ class AppViewModel(context: android.content.Context, private val fastInit: Boole
             // Demo mode - simulate sector line measurement
             // Sector angles: Shot/Discus/Hammer = 17.46° each side, Javelin = 14.48° each side
             val sectorAngleDegrees = if (_uiState.value.calibration.circleType == "JAVELIN_ARC") 14.48 else 17.46
-            val distanceFromCentre = 15.0 + kotlin.random.Random.nextDouble() * 10.0 // 15-25m from centre
+            val distanceFromCentre = 15.0 + SessionSources.random.nextDouble() * 10.0 // 15-25m from centre
             
             val sectorLineCoordinates = Pair(
                 distanceFromCentre * kotlin.math.sin(Math.toRadians(sectorAngleDegrees)),
@@ -1488,7 +2126,7 @@ class AppViewModel(context: android.content.Context, private val fastInit: Boole
         
         if (_uiState.value.isDemoMode) {
             // Demo mode - use simulated values
-            val windSpeed = (kotlin.random.Random.nextDouble() - 0.5) * 4.0 // ±2 m/s
+            val windSpeed = (SessionSources.random.nextDouble() - 0.5) * 4.0 // ±2 m/s
             _uiState.value = _uiState.value.copy(
                 windMeasurement = String.format(java.util.Locale.UK, "%s%.1f m/s", if (windSpeed > 0) "+" else "", windSpeed),
                 isLoading = false
@@ -1568,7 +2206,7 @@ class AppViewModel(context: android.content.Context, private val fastInit: Boole
                 val circleType = _uiState.value.calibration.circleType
                 android.util.Log.d("PolyField", "Setting centre with clean EDM interface for circle: $circleType")
                 
-                val result = getEDMInterface().setCentre("edm", circleType)
+                val result = getEDMInterface().setCentre(calibrationStation, circleType)
                 if (result.isSuccess) {
                     val data = result.getOrThrow()
                     android.util.Log.d("PolyField", "Clean set centre successful: $data")
@@ -1580,7 +2218,9 @@ class AppViewModel(context: android.content.Context, private val fastInit: Boole
                     _uiState.value = _uiState.value.copy(
                         calibration = _uiState.value.calibration.copy(
                             centreSet = true,
-                            stationCoordinates = Pair(stationX, stationY)
+                            centreTimestamp = java.text.SimpleDateFormat("yyyy-MM-dd HH:mm:ss", java.util.Locale.getDefault()).format(java.util.Date(SessionSources.now())),
+                            stationCoordinates = Pair(stationX, stationY),
+                            prismConstantMm = data["prismConstantMm"] as? Double ?: 0.0
                         ),
                         isLoading = false
                     )
@@ -1607,7 +2247,7 @@ class AppViewModel(context: android.content.Context, private val fastInit: Boole
             try {
                 android.util.Log.d("PolyField", "Verifying edge with clean EDM interface")
                 
-                val result = getEDMInterface().verifyEdge("edm")
+                val result = getEDMInterface().verifyEdge(calibrationStation)
                 if (result.isSuccess) {
                     val data = result.getOrThrow()
                     android.util.Log.d("PolyField", "Clean verify edge successful: $data")
@@ -1653,7 +2293,7 @@ class AppViewModel(context: android.content.Context, private val fastInit: Boole
             try {
                 android.util.Log.d("PolyField", "Measuring distance with clean EDM interface")
                 
-                val result = getEDMInterface().measure("edm")
+                val result = getEDMInterface().measure(calibrationStation)
                 if (result.isSuccess) {
                     val data = result.getOrThrow()
                     android.util.Log.d("PolyField", "Clean measure successful: $data")
@@ -1700,7 +2340,7 @@ class AppViewModel(context: android.content.Context, private val fastInit: Boole
             try {
                 android.util.Log.d("PolyField", "Performing sector check with clean EDM interface")
                 
-                val result = getEDMInterface().sectorCheck("edm")
+                val result = getEDMInterface().sectorCheck(calibrationStation)
                 if (result.isSuccess) {
                     val data = result.getOrThrow()
                     android.util.Log.d("PolyField", "Clean sector check successful: $data")
@@ -1731,6 +2371,108 @@ class AppViewModel(context: android.content.Context, private val fastInit: Boole
         }
     }
     
+    /**
+     * Begin the stopboard position check for the calibrated shot put circle
+     */
+    fun startStopboardVerification(): Boolean {
+        val calibration = _uiState.value.calibration
+        if (calibration.circleType != EDMCalculations.CIRCLE_SHOT || !calibration.centreSet) {
+            showErrorDialog("Stopboard Check", "Set the centre of a shot put circle before checking the stopboard")
+            return false
+        }
+        stopboardProcedure = StopboardVerificationProcedure(
+            calibration.targetRadius,
+            calibration.sectorLineCoordinates?.takeIf { calibration.sectorLineSet }
+        )
+        android.util.Log.d("PolyField", "Started stopboard verification")
+        return true
+    }
+
+    fun getStopboardStep(): StopboardStep? = stopboardProcedure?.currentStep()
+
+    fun getStopboardPointCount(): Int = stopboardProcedure?.pointCount() ?: 0
+
+    /**
+     * Measure the point the stopboard check asks for. Once enough edge points are in, the
+     * result is kept with the calibration and updated with each further point.
+     */
+    fun measureStopboardPoint() {
+        val procedure = stopboardProcedure ?: return
+        if (!_uiState.value.devices.edm.connected) {
+            showErrorDialog("Device Error", "EDM device is not connected. Please connect a device first.")
+            return
+        }
+        _uiState.value = _uiState.value.copy(isLoading = true)
+
+        viewModelScope.launch {
+            try {
+                val result = getEDMInterface().measure(calibrationStation, verbose = true)
+                val components = result.getOrNull()?.get("components") as? EDMInterface.MeasurementComponents
+                if (components == null) {
+                    showErrorDialog("Stopboard Check Error", result.exceptionOrNull()?.message ?: "No position in reading")
+                    _uiState.value = _uiState.value.copy(isLoading = false)
+                    return@launch
+                }
+
+                val point = StopboardPoint(
+                    x = components.throwX,
+                    y = components.throwY,
+                    heightM = CoordinateTransforms.verticalDistance(components.correctedSlopeDistanceM, components.verticalAngleDeg)
+                )
+                val measuredStep = procedure.currentStep()
+                procedure.addPoint(point)
+                android.util.Log.d("PolyField", "Stopboard $measuredStep point at (${point.x}, ${point.y}), height ${point.heightM}")
+
+                val verification = procedure.result()
+                _uiState.value = _uiState.value.copy(
+                    calibration = if (verification != null) {
+                        _uiState.value.calibration.copy(stopboardVerification = verification)
+                    } else {
+                        _uiState.value.calibration
+                    },
+                    isLoading = false
+                )
+                verification?.let { calibrationArchive.attachStopboard(getVenueCircleId(), it) }
+            } catch (e: Exception) {
+                android.util.Log.e("PolyField", "Stopboard check error", e)
+                showErrorDialog("Device Error", "Failed to measure stopboard: ${e.message}")
+                _uiState.value = _uiState.value.copy(isLoading = false)
+            }
+        }
+    }
+
+    fun cancelStopboardVerification() {
+        stopboardProcedure = null
+    }
+
+    /**
+     * Save the current calibration as a circle served by the shared EDM
+     */
+    fun saveCircleProfile(name: String, sessionId: String?, scoreboardDeviceType: String?) {
+        getEDMModule().sharedEdm.saveProfile(
+            CircleProfile(name.trim(), _uiState.value.calibration, sessionId, scoreboardDeviceType)
+        )
+    }
+
+    fun getCircleProfiles(): List<CircleProfile> = getEDMModule().sharedEdm.getProfiles()
+
+    fun getActiveCircle(): ActiveCircle? = getEDMModule().sharedEdm.active.value
+
+    /**
+     * Point the shared EDM at another circle, swapping calibration, session and scoreboard
+     */
+    fun switchActiveCircle(name: String): Boolean {
+        val result = getEDMModule().switchActiveCircle(name, _uiState.value.calibration) { calibration ->
+            getEDMInterface().applyCalibration(calibration, calibrationStation)
+            _uiState.value = _uiState.value.copy(calibration = calibration)
+        }
+        result.exceptionOrNull()?.let { error ->
+            showErrorDialog("Circle Switch", error.message ?: "Could not switch to $name")
+            return false
+        }
+        return true
+    }
+
     // Calibration History Management
     fun saveCurrentCalibrationToHistory() {
         val currentCalibration = _uiState.value.calibration
@@ -1743,7 +2485,8 @@ class AppViewModel(context: android.content.Context, private val fastInit: Boole
                 stationCoordinates = currentCalibration.stationCoordinates,
                 edgeResult = currentCalibration.edgeResult,
                 sectorLineDistance = currentCalibration.sectorLineDistance,
-                sectorLineCoordinates = currentCalibration.sectorLineCoordinates
+                sectorLineCoordinates = currentCalibration.sectorLineCoordinates,
+                prismConstantMm = currentCalibration.prismConstantMm
             )
             
             // Add to history and keep only last 2 records
@@ -1757,10 +2500,33 @@ class AppViewModel(context: android.content.Context, private val fastInit: Boole
             
             // Save to persistent storage
             saveCalibrationHistoryToDisk()
+            CalibrationArchive.entryFor(getVenueCircleId(), currentCalibration, calibrationRecord.timestamp)
+                ?.let { calibrationArchive.archive(it) }
             
             android.util.Log.d("PolyField", "Saved calibration to history: ${calibrationRecord.getDisplayName()}")
         }
     }
+
+    /**
+     * Name of the physical circle being calibrated (e.g. "Kingston Shot 1"), so its
+     * calibrations can be compared across sessions. Defaults to the circle type.
+     */
+    fun getVenueCircleId(): String =
+        sharedPrefs.getString("venue_circle_id", null)?.takeIf { it.isNotBlank() }
+            ?: _uiState.value.calibration.circleType
+
+    fun setVenueCircleId(venueCircleId: String) {
+        sharedPrefs.edit { putString("venue_circle_id", venueCircleId.trim()) }
+    }
+
+    /**
+     * Compare the current calibration with earlier sessions at this circle, flagging shifts
+     * of the edge, centre plate or sector lines
+     */
+    fun compareCalibrationWithPreviousSessions(): CalibrationComparison? {
+        val current = CalibrationArchive.entryFor(getVenueCircleId(), _uiState.value.calibration) ?: return null
+        return calibrationArchive.compare(current)
+    }
     
     /**
      * Save calibration history to persistent storage
@@ -1777,6 +2543,7 @@ class AppViewModel(context: android.content.Context, private val fastInit: Boole
                     put("targetRadius", calibration.targetRadius)
                     put("timestamp", calibration.timestamp)
                     put("dateString", calibration.dateString)
+                    put("prismConstantMm", calibration.prismConstantMm)
                     
                     // Station coordinates
                     calibration.stationCoordinates?.let { coords ->
@@ -1844,6 +2611,7 @@ class AppViewModel(context: android.content.Context, private val fastInit: Boole
                 sectorLineSet = calibrationRecord.sectorLineDistance != null,
                 sectorLineDistance = calibrationRecord.sectorLineDistance,
                 sectorLineCoordinates = calibrationRecord.sectorLineCoordinates,
+                prismConstantMm = calibrationRecord.prismConstantMm,
                 selectedHistoricalCalibration = calibrationRecord
             )
         )
@@ -1988,11 +2756,11 @@ class AppViewModel(context: android.content.Context, private val fastInit: Boole
     
     private fun generateDemoThrow(): Double {
         val baseDistance = when (_uiState.value.calibration.circleType) {
-            "SHOT" -> 15.0 + kotlin.random.Random.nextDouble() * 8.0 // 15-23m
-            "DISCUS" -> 45.0 + kotlin.random.Random.nextDouble() * 25.0 // 45-70m  
-            "HAMMER" -> 55.0 + kotlin.random.Random.nextDouble() * 25.0 // 55-80m
-            "JAVELIN_ARC" -> 60.0 + kotlin.random.Random.nextDouble() * 30.0 // 60-90m
-            else -> 20.0 + kotlin.random.Random.nextDouble() * 10.0
+            "SHOT" -> 15.0 + SessionSources.random.nextDouble() * 8.0 // 15-23m
+            "DISCUS" -> 45.0 + SessionSources.random.nextDouble() * 25.0 // 45-70m  
+            "HAMMER" -> 55.0 + SessionSources.random.nextDouble() * 25.0 // 55-80m
+            "JAVELIN_ARC" -> 60.0 + SessionSources.random.nextDouble() * 30.0 // 60-90m
+            else -> 20.0 + SessionSources.random.nextDouble() * 10.0
         }
         return baseDistance
     }
@@ -2004,10 +2772,10 @@ class AppViewModel(context: android.content.Context, private val fastInit: Boole
         val maxSectorAngle = Math.toRadians(halfSectorAngleDegrees) // Half sector angle in radians
         
         // Random angle within the sector (can be negative for left side)
-        val throwAngle = kotlin.random.Random.nextDouble() * 2 * maxSectorAngle - maxSectorAngle
+        val throwAngle = SessionSources.random.nextDouble() * 2 * maxSectorAngle - maxSectorAngle
         
         // Add some variation to the distance (±10% for realism)
-        val distanceVariation = 0.9 + kotlin.random.Random.nextDouble() * 0.2 // 0.9 to 1.1
+        val distanceVariation = 0.9 + SessionSources.random.nextDouble() * 0.2 // 0.9 to 1.1
         val actualDistance = distance * distanceVariation
         
         // Calculate X (lateral) and Y (forward) coordinates
@@ -2344,9 +3112,17 @@ class MainActivityCompose : ComponentActivity() {
     private var usbReceiver: BroadcastReceiver? = null
     
     private lateinit var viewModel: AppViewModel
+    private var modeManager: ModeManagerViewModel? = null
     
     companion object {
         private const val ACTION_USB_PERMISSION = "com.polyfieldandroid.USB_PERMISSION"
+        private const val SHUTDOWN_TIMEOUT_MS = 3000L
+
+        // Outlives the activity and its view model so a session shutdown can finish; each
+        // shutdown is bounded by SHUTDOWN_TIMEOUT_MS
+        private val shutdownScope = kotlinx.coroutines.CoroutineScope(
+            kotlinx.coroutines.SupervisorJob() + kotlinx.coroutines.Dispatchers.IO
+        )
     }
     
     // USB status logging (console only like original)
@@ -2384,12 +3160,50 @@ class MainActivityCompose : ComponentActivity() {
     }
     
     override fun onDestroy() {
+        if (isFinishing) {
+            shutdownSession()
+        }
         super.onDestroy()
         usbReceiver?.let { unregisterReceiver(it) }
     }
     
+    /**
+     * Hand the activity its view model once progressive startup has created it,
+     * and start listening for USB attach/detach
+     */
+    fun attachViewModel(appViewModel: AppViewModel) {
+        if (::viewModel.isInitialized) return
+        viewModel = appViewModel
+        registerUsbReceiver()
+    }
+    
+    fun attachModeManager(manager: ModeManagerViewModel) {
+        modeManager = manager
+    }
+    
+    /**
+     * Close devices and flush outboxes when the user leaves the app for good. Runs on the
+     * app's shutdown scope so the main thread is not held up while the activity is destroyed.
+     */
+    private fun shutdownSession() {
+        if (!::viewModel.isInitialized) return
+        val sessionViewModel = viewModel
+        val sessionModeManager = modeManager
+        shutdownScope.launch {
+            val summary = kotlinx.coroutines.withTimeoutOrNull(SHUTDOWN_TIMEOUT_MS) {
+                sessionViewModel.shutdownAll(sessionModeManager)
+            }
+            android.util.Log.d("PolyField", "Session shutdown: ${summary ?: "timed out"}")
+        }
+    }
+    
     // USB Management Implementation
     private fun initializeUSB() {
+        registerUsbReceiver()
+        requestRuntimePermissions()
+    }
+    
+    private fun registerUsbReceiver() {
         usbManager = getSystemService(Context.USB_SERVICE) as UsbManager
         permissionIntent = PendingIntent.getBroadcast(
             this, 0, Intent(ACTION_USB_PERMISSION),
@@ -2433,15 +3247,18 @@ class MainActivityCompose : ComponentActivity() {
             filter, 
             androidx.core.content.ContextCompat.RECEIVER_NOT_EXPORTED
         )
-        
-        requestRuntimePermissions()
     }
     
     private fun requestRuntimePermissions() {
-        val permissions = arrayOf(
+        val permissions = listOf(
             Manifest.permission.ACCESS_FINE_LOCATION,
             Manifest.permission.ACCESS_COARSE_LOCATION
-        )
+        ) + if (android.os.Build.VERSION.SDK_INT >= android.os.Build.VERSION_CODES.S) {
+            // Bluetooth EDMs and gauges are paired, not scanned for, so only connect is needed
+            listOf(Manifest.permission.BLUETOOTH_CONNECT)
+        } else {
+            emptyList()
+        }
         
         val permissionsToRequest = permissions.filter {
             ContextCompat.checkSelfPermission(this, it) != PackageManager.PERMISSION_GRANTED
@@ -2530,6 +3347,11 @@ class MainActivityCompose : ComponentActivity() {
             return true
         }
         
+        // WCH CH340/CH341 (0x1a86 = 6790 decimal)
+        if (vendorId == 6790 && (productId == 29987 || productId == 21795)) {
+            return true
+        }
+        
         return false
     }
     
@@ -2547,6 +3369,8 @@ class MainActivityCompose : ComponentActivity() {
     }
     
     private fun onUSBDeviceDetached(device: UsbDevice) {
+        viewModel.onUsbDetached(device)
+        
         val currentDevice = viewModel.uiState.value.connectedDevice
         if (device == currentDevice) {
             viewModel.updateDevice(null, true)
@@ -2555,6 +3379,11 @@ class MainActivityCompose : ComponentActivity() {
     }
     
     private fun onUSBDevicePermissionGranted(device: UsbDevice) {
+        // Known devices go back to their previous role; anything else uses single-device auto-connect
+        viewModel.onUsbAttached(device) { autoConnectSingleDevice(device) }
+    }
+    
+    private fun autoConnectSingleDevice(device: UsbDevice) {
         val deviceName = device.productName ?: "Serial Device"
         viewModel.updateDevice(device, false)
         
@@ -2630,6 +3459,9 @@ fun ZeroDelayApp(onReady: () -> Unit = {}) {
         launch(kotlinx.coroutines.Dispatchers.Default) {
             // Phase 1: Critical ViewModels first (lightweight initialization)
             appViewModel = AppViewModel(context, fastInit = true)
+            appViewModel?.let { appVM ->
+                kotlinx.coroutines.withContext(kotlinx.coroutines.Dispatchers.Main) { context.attachViewModel(appVM) }
+            }
 
             // Phase 2: Secondary ViewModels
             launch {
@@ -2645,6 +3477,9 @@ fun ZeroDelayApp(onReady: () -> Unit = {}) {
             appViewModel?.let { appVM ->
                 modeManager = ViewModelProvider(context, ModeManagerViewModelFactory(context, appVM))
                     .get(ModeManagerViewModel::class.java)
+                modeManager?.let { manager ->
+                    kotlinx.coroutines.withContext(kotlinx.coroutines.Dispatchers.Main) { context.attachModeManager(manager) }
+                }
             }
 
             // Phase 4: Signal ready when critical components loaded
@@ -2704,7 +3539,7 @@ fun ProgressivePolyFieldApp(
             }
             
             remember {
-                val edm = edmModule ?: EDMModule(context).also { edmModule = it }
+                val edm = edmModule ?: appViewModel.getEDMModule().also { edmModule = it }
                 ViewModelProvider(
                     context,
                     CompetitionMeasurementManagerFactory(
@@ -8,5 +8,6 @@ public class MainApplication extends Application {
     public void onCreate() {
         super.onCreate();
         // Simple native Android app initialization
+        CrashReporter.install(this);
     }
 }
\ No newline at end of file
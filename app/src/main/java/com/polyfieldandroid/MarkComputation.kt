@@ -0,0 +1,115 @@
+package com.polyfieldandroid
+
+import android.util.Log
+
+/**
+ * What a mark computation has to work with: the measured landing point, in the circle
+ * frame, and the circle it was thrown from
+ */
+data class MarkInput(
+    val circleType: String,
+    val circleRadiusM: Double,
+    val landingX: Double,
+    val landingY: Double,
+    val distanceFromCentreM: Double
+)
+
+/**
+ * Rule for turning a measured landing point into a mark. Federations register their own
+ * to change how marks are computed (para rules, measuring to a different part of the
+ * landing mark) without changing the measurement pipeline.
+ */
+interface MarkComputation {
+    val name: String
+
+    /**
+     * Mark in metres, or null to fall back to the standard computation
+     */
+    fun computeMark(input: MarkInput): Double?
+}
+
+/**
+ * Centre of the circle to the landing point, less the circle radius
+ */
+object StandardMarkComputation : MarkComputation {
+    override val name = "standard"
+
+    override fun computeMark(input: MarkInput): Double = input.distanceFromCentreM - input.circleRadiusM
+}
+
+/**
+ * Prism placed in the middle of the landing mark: offsetM is taken off to reach the
+ * mark's nearer edge, e.g. the radius of the prism pole foot
+ */
+class NearerEdgeMarkComputation(
+    private val offsetM: Double,
+    override val name: String = "nearer_edge"
+) : MarkComputation {
+    override fun computeMark(input: MarkInput): Double =
+        StandardMarkComputation.computeMark(input) - offsetM
+}
+
+/**
+ * Mark Computation Registry
+ * Holds the registered computations and the one in use. Anything that fails or returns
+ * an unusable mark falls back to the standard computation, so a faulty hook cannot stop
+ * measuring.
+ */
+object MarkComputationRegistry {
+
+    private const val TAG = "MarkComputationRegistry"
+
+    private val computations = mutableMapOf<String, MarkComputation>(
+        StandardMarkComputation.name to StandardMarkComputation
+    )
+    @Volatile private var selected: MarkComputation = StandardMarkComputation
+
+    @Synchronized
+    fun register(computation: MarkComputation) {
+        computations[computation.name] = computation
+        Log.d(TAG, "Registered mark computation ${computation.name}")
+    }
+
+    /**
+     * Remove a computation; if it was in use, marks go back to the standard computation
+     */
+    @Synchronized
+    fun unregister(name: String) {
+        if (name == StandardMarkComputation.name) return
+        computations.remove(name)
+        if (selected.name == name) selected = StandardMarkComputation
+    }
+
+    @Synchronized
+    fun getNames(): List<String> = computations.keys.sorted()
+
+    /**
+     * Use a registered computation for all following marks; null selects the standard one
+     */
+    @Synchronized
+    fun select(name: String?): Boolean {
+        val computation = computations[name ?: StandardMarkComputation.name] ?: return false
+        selected = computation
+        Log.d(TAG, "Marks now computed by ${computation.name}")
+        return true
+    }
+
+    fun selectedName(): String = selected.name
+
+    /**
+     * Mark for a landing point and the name of the computation that produced it
+     */
+    fun compute(input: MarkInput): Pair<Double, String> {
+        val computation = selected
+        if (computation !== StandardMarkComputation) {
+            try {
+                val mark = computation.computeMark(input)
+                if (mark != null && mark.isFinite()) return Pair(mark, computation.name)
+                Log.w(TAG, "${computation.name} gave no mark; using standard")
+            } catch (e: Exception) {
+                Log.e(TAG, "${computation.name} failed: ${e.message}; using standard")
+            }
+        }
+        return Pair(StandardMarkComputation.computeMark(input), StandardMarkComputation.name)
+    }
+}
@@ -147,7 +147,9 @@ class MatoMTS602RTranslator(deviceSpec: EDMDeviceSpec) : EDMDeviceTranslator(dev
                 horizontalAngleDegrees = horizontalAngleDegrees,
                 statusCode = statusCode,
                 isValid = isValidMeasurement,
-                errorMessage = if (isValidMeasurement) null else statusMessage
+                errorMessage = if (isValidMeasurement) null else statusMessage,
+                verticalAngleRaw = parts[1],
+                horizontalAngleRaw = parts[2]
             )
             
         } catch (e: Exception) {
@@ -0,0 +1,147 @@
+package com.polyfieldandroid
+
+import android.util.Log
+import kotlinx.coroutines.CancellationException
+import kotlinx.coroutines.CoroutineScope
+import kotlinx.coroutines.Dispatchers
+import kotlinx.coroutines.Job
+import kotlinx.coroutines.SupervisorJob
+import kotlinx.coroutines.launch
+import java.util.concurrent.ConcurrentHashMap
+
+enum class MeasurementKind { SET_CENTRE, VERIFY_EDGE, MEASURE_THROW }
+
+/**
+ * Where a measurement request has got to
+ */
+enum class MeasurementPhase {
+    QUEUED,            // Waiting for the EDM to be free
+    TRIGGERING,        // Sending the measure command
+    WAITING_FOR_PRISM, // Command sent; the instrument is measuring
+    SECOND_READ,       // Double read: taking the confirming reading
+    AVERAGING,         // Both readings in; checking tolerance and averaging
+    COMPLETE,
+    FAILED,
+    CANCELLED;
+
+    val isFinished: Boolean get() = this == COMPLETE || this == FAILED || this == CANCELLED
+}
+
+data class MeasurementRequestStatus(
+    val requestId: String,
+    val deviceType: String,
+    val kind: MeasurementKind,
+    val phase: MeasurementPhase,
+    val startedAt: Long,
+    val updatedAt: Long,
+    val result: Map<String, Any>? = null, // The synchronous call's result, once finished
+    val error: String? = null
+) {
+    fun toMap(): Map<String, Any> = listOfNotNull(
+        result?.let { "result" to it },
+        error?.let { "error" to it }
+    ).toMap() + mapOf(
+        "success" to true,
+        "requestId" to requestId,
+        "deviceType" to deviceType,
+        "kind" to kind.name,
+        "phase" to phase.name,
+        "finished" to phase.isFinished,
+        "startedAt" to startedAt,
+        "updatedAt" to updatedAt
+    )
+}
+
+/**
+ * Measurement Requests
+ * Runs set centre, edge verification and throw measurement in the background so a caller
+ * need not block for up to two read timeouts. Each request gets an ID to poll for its phase
+ * and result, or to cancel. Finished requests are kept until MAX_FINISHED newer ones exist.
+ */
+class MeasurementRequests {
+
+    companion object {
+        private const val TAG = "MeasurementRequests"
+        private const val MAX_FINISHED = 50
+    }
+
+    private class Request(@Volatile var status: MeasurementRequestStatus) {
+        var job: Job? = null
+    }
+
+    private val requests = ConcurrentHashMap<String, Request>()
+    private val scope = CoroutineScope(SupervisorJob() + Dispatchers.IO)
+
+    /**
+     * Start a request. run reports phases through its argument and returns the result map;
+     * a result without success=true finishes the request as FAILED.
+     */
+    fun start(
+        deviceType: String,
+        kind: MeasurementKind,
+        run: suspend (report: (MeasurementPhase) -> Unit) -> Map<String, Any>
+    ): String {
+        val now = SessionSources.now()
+        val requestId = SessionSources.newId()
+        val request = Request(MeasurementRequestStatus(requestId, deviceType, kind, MeasurementPhase.QUEUED, now, now))
+        requests[requestId] = request
+        prune()
+
+        request.job = scope.launch {
+            val result = try {
+                run { phase -> update(request, phase) }
+            } catch (e: CancellationException) {
+                return@launch
+            } catch (e: Exception) {
+                CrashReporter.errorResult("startMeasurement", e)
+            }
+            synchronized(request) {
+                if (request.status.phase == MeasurementPhase.CANCELLED) return@launch
+                val succeeded = result["success"] == true
+                request.status = request.status.copy(
+                    phase = if (succeeded) MeasurementPhase.COMPLETE else MeasurementPhase.FAILED,
+                    updatedAt = SessionSources.now(),
+                    result = result,
+                    error = if (succeeded) null else result["error"] as? String ?: "Measurement failed"
+                )
+            }
+            Log.d(TAG, "$kind request $requestId on $deviceType finished: ${request.status.phase}")
+        }
+        return requestId
+    }
+
+    fun status(requestId: String): MeasurementRequestStatus? = requests[requestId]?.status
+
+    /**
+     * The unfinished request on a device, if any
+     */
+    fun active(deviceType: String): MeasurementRequestStatus? =
+        requests.values.map { it.status }.firstOrNull { it.deviceType == deviceType && !it.phase.isFinished }
+
+    /**
+     * Cancel a request that has not finished. Returns false if it is unknown or already done.
+     */
+    fun cancel(requestId: String): Boolean {
+        val request = requests[requestId] ?: return false
+        synchronized(request) {
+            if (request.status.phase.isFinished) return false
+            request.status = request.status.copy(phase = MeasurementPhase.CANCELLED, updatedAt = SessionSources.now())
+        }
+        request.job?.cancel()
+        Log.d(TAG, "Cancelled request $requestId")
+        return true
+    }
+
+    private fun update(request: Request, phase: MeasurementPhase) {
+        synchronized(request) {
+            if (request.status.phase.isFinished || request.status.phase == phase) return
+            request.status = request.status.copy(phase = phase, updatedAt = SessionSources.now())
+        }
+    }
+
+    private fun prune() {
+        val finished = requests.values.map { it.status }.filter { it.phase.isFinished }
+        if (finished.size <= MAX_FINISHED) return
+        finished.sortedBy { it.updatedAt }.take(finished.size - MAX_FINISHED).forEach { requests.remove(it.requestId) }
+    }
+}
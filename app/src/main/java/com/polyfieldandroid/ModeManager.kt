@@ -94,6 +94,12 @@ class ModeManagerViewModel(
         )
         
         Log.d(TAG, "Loaded settings: mode=$mode, server=$ipAddress:$port")
+
+        // Replay any results left in the outbox by a previous session
+        if (mode == AppMode.CONNECTED && cacheManager.hasCachedResults()) {
+            Log.d(TAG, "Found ${cacheManager.getCachedResultsCount()} results queued from previous session, scheduling sync")
+            ResultSyncWorker.scheduleImmediateSync(context, ipAddress, port)
+        }
     }
     
     /**
@@ -243,7 +249,7 @@ class ModeManagerViewModel(
                         serverConfig = _modeState.value.serverConfig.copy(
                             ipAddress = finalIpAddress,
                             port = finalPort,
-                            lastConnectionAttempt = System.currentTimeMillis()
+                            lastConnectionAttempt = SessionSources.now()
                         )
                     )
                     
@@ -413,6 +419,7 @@ class ModeManagerViewModel(
     fun getCachedResultsCount(): Int = cacheManager.getCachedResultsCount()
     fun hasCachedResults(): Boolean = cacheManager.hasCachedResults()
     fun getCacheMetadata(): ResultsCacheManager.CacheMetadata = cacheManager.getMetadata()
+    fun getResultsOutboxStatus(): OutboxStatus = cacheManager.getOutboxStatus()
     
     /**
      * Manually trigger sync of cached results
@@ -425,6 +432,18 @@ class ModeManagerViewModel(
         }
     }
     
+    /**
+     * Hand any pending results to WorkManager so they upload even after the app exits
+     * @return Outbox status at the time of the flush
+     */
+    fun flushResultsOutbox(): OutboxStatus {
+        val status = cacheManager.getOutboxStatus()
+        if (status.depth > 0) {
+            triggerManualSync()
+        }
+        return status
+    }
+    
     /**
      * Clear all cached results (use with caution)
      */
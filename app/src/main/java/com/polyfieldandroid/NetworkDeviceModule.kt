@@ -1,7 +1,13 @@
 package com.polyfieldandroid
 
 import android.util.Log
+import kotlinx.coroutines.CoroutineScope
 import kotlinx.coroutines.Dispatchers
+import kotlinx.coroutines.Job
+import kotlinx.coroutines.SupervisorJob
+import kotlinx.coroutines.delay
+import kotlinx.coroutines.isActive
+import kotlinx.coroutines.launch
 import kotlinx.coroutines.withContext
 import kotlinx.coroutines.withTimeout
 import java.io.BufferedReader
@@ -21,11 +27,24 @@ class NetworkDeviceModule {
         private const val TAG = "NetworkDeviceModule"
         private const val DEFAULT_TIMEOUT_MS = 5000
         private const val DEFAULT_SOCKET_TIMEOUT_MS = 3000
+        private const val IDLE_CHECK_INTERVAL_MS = 5000L
     }
 
     // Active device connections
     private val connections = ConcurrentHashMap<String, NetworkDeviceConnection>()
 
+    // Idle policy: sockets unused for this long are closed and reopened on next use (0 = never)
+    @Volatile private var idleTimeoutMs = 0L
+    private val idleScope = CoroutineScope(Dispatchers.IO + SupervisorJob())
+    private var idleMonitorJob: Job? = null
+
+    // Told of every exchange: device ID, success, round-trip time (null for unsolicited reads), error
+    @Volatile var activityListener: ((String, Boolean, Long?, String?) -> Unit)? = null
+
+    // Told of text sent and messages received, by device ID, for traffic capture. Binary
+    // protocols that drive the socket themselves (Daktronics) are not seen.
+    @Volatile var trafficListener: ((String, TrafficDirection, ByteArray) -> Unit)? = null
+
     /**
      * Network device connection wrapper
      */
@@ -34,9 +53,12 @@ class NetworkDeviceModule {
         val host: String,
         val port: Int,
         val protocol: DeviceProtocol,
+        val openSocket: () -> Socket,
         var socket: Socket? = null,
         var isConnected: Boolean = false,
-        var lastError: String? = null
+        var lastError: String? = null,
+        @Volatile var lastActivity: Long = System.currentTimeMillis(),
+        @Volatile var idleClosed: Boolean = false // Socket closed by the idle policy; reopened on next use
     )
 
     /**
@@ -45,26 +67,27 @@ class NetworkDeviceModule {
      * @param host IP address or hostname
      * @param port TCP port number
      * @param protocol Device-specific protocol adapter
+     * @param openSocket Opens the channel; TCP by default, or e.g. a Bluetooth serial link
      * @return Success status and connection info
      */
     suspend fun connect(
         deviceId: String,
         host: String,
         port: Int,
-        protocol: DeviceProtocol
+        protocol: DeviceProtocol,
+        openSocket: () -> Socket = { openTcpSocket(host, port) }
     ): NetworkConnectionResult = withContext(Dispatchers.IO) {
+        val endpoint = if (port > 0) "$host:$port" else host
         try {
-            Log.d(TAG, "Connecting to device $deviceId at $host:$port")
+            Log.d(TAG, "Connecting to device $deviceId at $endpoint")
 
             // Close existing connection if present
             disconnect(deviceId)
 
             // Create socket with timeout
             val socket = withTimeout(DEFAULT_TIMEOUT_MS.toLong()) {
-                Socket(host, port).apply {
+                openSocket().apply {
                     soTimeout = DEFAULT_SOCKET_TIMEOUT_MS
-                    keepAlive = true
-                    tcpNoDelay = true // Disable Nagle's algorithm for low-latency
                 }
             }
 
@@ -74,6 +97,7 @@ class NetworkDeviceModule {
                 host = host,
                 port = port,
                 protocol = protocol,
+                openSocket = openSocket,
                 socket = socket,
                 isConnected = true
             )
@@ -90,15 +114,15 @@ class NetworkDeviceModule {
 
             connections[deviceId] = connection
 
-            Log.d(TAG, "Successfully connected to $deviceId at $host:$port")
+            Log.d(TAG, "Successfully connected to $deviceId at $endpoint")
             NetworkConnectionResult(
                 success = true,
                 deviceId = deviceId,
-                connectionInfo = "Connected to $host:$port (${protocol.name})"
+                connectionInfo = "Connected to $endpoint (${protocol.name})"
             )
 
         } catch (e: SocketTimeoutException) {
-            Log.e(TAG, "Connection timeout for $deviceId at $host:$port")
+            Log.e(TAG, "Connection timeout for $deviceId at $endpoint")
             NetworkConnectionResult(
                 success = false,
                 error = "Connection timeout: ${e.message}"
@@ -112,13 +136,24 @@ class NetworkDeviceModule {
         }
     }
 
+    private fun openTcpSocket(host: String, port: Int): Socket {
+        return Socket(host, port).apply {
+            keepAlive = true
+            tcpNoDelay = true // Disable Nagle's algorithm for low-latency
+        }
+    }
+
     /**
      * Disconnect from a network device
      */
     suspend fun disconnect(deviceId: String): Boolean = withContext(Dispatchers.IO) {
         try {
             val connection = connections.remove(deviceId)
-            if (connection?.socket != null) {
+            if (connection != null && connection.idleClosed) {
+                connection.isConnected = false
+                Log.d(TAG, "Disconnected from $deviceId (socket already closed while idle)")
+                true
+            } else if (connection?.socket != null) {
                 connection.protocol.cleanup(connection.socket!!)
                 connection.socket?.close()
                 connection.isConnected = false
@@ -133,6 +168,17 @@ class NetworkDeviceModule {
         }
     }
 
+    /**
+     * Move an open connection to another device ID, keeping its socket and protocol
+     */
+    fun reassign(fromDeviceId: String, toDeviceId: String): Boolean {
+        if (connections.containsKey(toDeviceId)) return false
+        val connection = connections.remove(fromDeviceId) ?: return false
+        connections[toDeviceId] = connection.copy(deviceId = toDeviceId)
+        Log.d(TAG, "Moved connection $fromDeviceId to $toDeviceId")
+        return true
+    }
+
     /**
      * Send command to device and receive response
      * @param deviceId Device identifier
@@ -145,6 +191,13 @@ class NetworkDeviceModule {
     ): DeviceResponse = withContext(Dispatchers.IO) {
         val connection = connections[deviceId]
 
+        if (connection != null && connection.idleClosed && !reopenIdleConnection(connection)) {
+            return@withContext DeviceResponse(
+                success = false,
+                error = "Device $deviceId could not be reconnected: ${connection.lastError}"
+            )
+        }
+
         if (connection == null || !connection.isConnected || connection.socket == null) {
             return@withContext DeviceResponse(
                 success = false,
@@ -152,12 +205,20 @@ class NetworkDeviceModule {
             )
         }
 
-        try {
+        val startedAt = System.currentTimeMillis()
+        val response = exchange(connection, command)
+        activityListener?.invoke(deviceId, response.success, System.currentTimeMillis() - startedAt, response.error)
+        response
+    }
+
+    private suspend fun exchange(connection: NetworkDeviceConnection, command: DeviceCommand): DeviceResponse {
+        return try {
             val socket = connection.socket!!
+            connection.lastActivity = System.currentTimeMillis()
 
             // Check if protocol supports binary messages (e.g., Daktronics)
             if (connection.protocol is DaktronicsScoreboardProtocol) {
-                return@withContext (connection.protocol as DaktronicsScoreboardProtocol)
+                return (connection.protocol as DaktronicsScoreboardProtocol)
                     .sendBinaryMessage(socket, command)
             }
 
@@ -169,32 +230,34 @@ class NetworkDeviceModule {
             val writer = OutputStreamWriter(socket.getOutputStream(), Charsets.UTF_8)
             writer.write(encodedCommand)
             writer.flush()
+            trafficListener?.invoke(connection.deviceId, TrafficDirection.SENT, encodedCommand.toByteArray(Charsets.UTF_8))
 
-            Log.d(TAG, "Sent command to $deviceId: ${command.type}")
+            Log.d(TAG, "Sent command to ${connection.deviceId}: ${command.type}")
 
             // Read response if expected
             if (command.expectResponse) {
                 val reader = BufferedReader(InputStreamReader(socket.getInputStream(), Charsets.UTF_8))
                 val rawResponse = connection.protocol.readResponse(reader)
+                trafficListener?.invoke(connection.deviceId, TrafficDirection.RECEIVED, rawResponse.toByteArray(Charsets.UTF_8))
 
                 // Decode response using protocol
                 val response = connection.protocol.decodeResponse(rawResponse, command)
 
-                Log.d(TAG, "Received response from $deviceId: ${response.data}")
+                Log.d(TAG, "Received response from ${connection.deviceId}: ${response.data}")
                 response
             } else {
                 DeviceResponse(success = true, data = mapOf("status" to "sent"))
             }
 
         } catch (e: SocketTimeoutException) {
-            Log.e(TAG, "Command timeout for $deviceId")
+            Log.e(TAG, "Command timeout for ${connection.deviceId}")
             connection.lastError = "Timeout: ${e.message}"
             DeviceResponse(
                 success = false,
                 error = "Command timeout: ${e.message}"
             )
         } catch (e: Exception) {
-            Log.e(TAG, "Error sending command to $deviceId: ${e.message}", e)
+            Log.e(TAG, "Error sending command to ${connection.deviceId}: ${e.message}", e)
             connection.lastError = e.message
             connection.isConnected = false
             DeviceResponse(
@@ -204,6 +267,126 @@ class NetworkDeviceModule {
         }
     }
 
+    /**
+     * Send the protocol's no-op command, if it has one. Null when there is nothing to send.
+     */
+    suspend fun ping(deviceId: String): DeviceResponse? {
+        val command = connections[deviceId]?.protocol?.pingCommand() ?: return null
+        return sendCommand(deviceId, command)
+    }
+
+    /**
+     * Read one unsolicited message from a device that streams data, without sending anything
+     * @param command Command the message is decoded as (e.g. READ_WIND for a streaming gauge)
+     */
+    suspend fun readMessage(
+        deviceId: String,
+        command: DeviceCommand
+    ): DeviceResponse = withContext(Dispatchers.IO) {
+        val connection = connections[deviceId]
+        if (connection != null && connection.idleClosed && !reopenIdleConnection(connection)) {
+            return@withContext DeviceResponse(success = false, error = "Device $deviceId could not be reconnected")
+        }
+        val socket = connection?.socket
+
+        if (connection == null || !connection.isConnected || socket == null) {
+            return@withContext DeviceResponse(
+                success = false,
+                error = "Device $deviceId not connected"
+            )
+        }
+
+        try {
+            val reader = BufferedReader(InputStreamReader(socket.getInputStream(), Charsets.UTF_8))
+            val rawResponse = connection.protocol.readResponse(reader)
+            connection.lastActivity = System.currentTimeMillis()
+            trafficListener?.invoke(deviceId, TrafficDirection.RECEIVED, rawResponse.toByteArray(Charsets.UTF_8))
+            connection.protocol.decodeResponse(rawResponse, command).also {
+                activityListener?.invoke(deviceId, it.success, null, it.error)
+            }
+        } catch (e: SocketTimeoutException) {
+            // Nothing sent within the socket timeout - not an error for a quiet stream
+            DeviceResponse(success = false, error = "No data")
+        } catch (e: Exception) {
+            Log.e(TAG, "Error reading from $deviceId: ${e.message}")
+            connection.lastError = e.message
+            connection.isConnected = false
+            activityListener?.invoke(deviceId, false, null, e.message)
+            DeviceResponse(
+                success = false,
+                error = "Read failed: ${e.message}"
+            )
+        }
+    }
+
+    /**
+     * Set the idle policy. Sockets idle for longer than this are closed, and the next
+     * command reopens them, so access points that drop idle connections do no harm.
+     * @param timeoutMs Idle time before closing; 0 disables the policy
+     */
+    fun setIdleTimeout(timeoutMs: Long) {
+        idleTimeoutMs = timeoutMs
+        if (timeoutMs > 0 && idleMonitorJob?.isActive != true) {
+            idleMonitorJob = idleScope.launch {
+                while (isActive) {
+                    delay(IDLE_CHECK_INTERVAL_MS)
+                    closeIdleConnections()
+                }
+            }
+        } else if (timeoutMs <= 0) {
+            idleMonitorJob?.cancel()
+            idleMonitorJob = null
+        }
+    }
+
+    private suspend fun closeIdleConnections() {
+        val timeout = idleTimeoutMs
+        if (timeout <= 0) return
+        val now = System.currentTimeMillis()
+
+        connections.values
+            .filter { it.isConnected && !it.idleClosed && now - it.lastActivity > timeout }
+            .forEach { connection ->
+                try {
+                    connection.socket?.let { connection.protocol.cleanup(it) }
+                    connection.socket?.close()
+                } catch (e: Exception) {
+                    Log.w(TAG, "Error closing idle socket for ${connection.deviceId}: ${e.message}")
+                }
+                connection.socket = null
+                connection.idleClosed = true
+                Log.d(TAG, "Closed idle socket for ${connection.deviceId} after ${timeout}ms")
+            }
+    }
+
+    /**
+     * Reopen a socket closed by the idle policy, repeating the protocol handshake
+     */
+    private suspend fun reopenIdleConnection(connection: NetworkDeviceConnection): Boolean {
+        return try {
+            val socket = withTimeout(DEFAULT_TIMEOUT_MS.toLong()) {
+                connection.openSocket().apply {
+                    soTimeout = DEFAULT_SOCKET_TIMEOUT_MS
+                }
+            }
+            val initResult = connection.protocol.initialize(socket)
+            if (!initResult.success) {
+                socket.close()
+                connection.lastError = initResult.error
+                return false
+            }
+            connection.socket = socket
+            connection.idleClosed = false
+            connection.lastActivity = System.currentTimeMillis()
+            Log.d(TAG, "Reopened idle connection to ${connection.deviceId}")
+            true
+        } catch (e: Exception) {
+            Log.e(TAG, "Failed to reopen ${connection.deviceId}: ${e.message}")
+            connection.lastError = e.message
+            false
+        }
+    }
+
     /**
      * Check if device is connected
      */
@@ -234,6 +417,15 @@ class NetworkDeviceModule {
         deviceIds.forEach { disconnect(it) }
     }
 
+    /**
+     * Disconnect everything and stop the idle monitor
+     */
+    suspend fun shutdown() {
+        idleMonitorJob?.cancel()
+        idleMonitorJob = null
+        disconnectAll()
+    }
+
     /**
      * Get list of all connected devices
      */
@@ -272,6 +464,11 @@ interface DeviceProtocol {
      * Cleanup before disconnect
      */
     suspend fun cleanup(socket: Socket)
+
+    /**
+     * A command with no effect on the device, for health checks; null if there is none
+     */
+    fun pingCommand(): DeviceCommand? = null
 }
 
 /**
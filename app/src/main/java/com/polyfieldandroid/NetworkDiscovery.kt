@@ -0,0 +1,130 @@
+package com.polyfieldandroid
+
+import android.content.Context
+import android.net.nsd.NsdManager
+import android.net.nsd.NsdServiceInfo
+import android.util.Log
+import kotlinx.coroutines.CompletableDeferred
+import kotlinx.coroutines.Dispatchers
+import kotlinx.coroutines.channels.Channel
+import kotlinx.coroutines.coroutineScope
+import kotlinx.coroutines.delay
+import kotlinx.coroutines.launch
+import kotlinx.coroutines.withTimeoutOrNull
+import java.util.concurrent.ConcurrentHashMap
+
+/**
+ * A device found advertising itself on the local network
+ */
+data class DiscoveredNetworkDevice(
+    val name: String,
+    val serviceType: String,     // e.g. "_rfc2217._tcp"
+    val host: String,
+    val port: Int,
+    val deviceType: String?,     // Advertised role (edm, wind, scoreboard) from the "type" TXT record
+    val driver: String?,         // Advertised EDM driver or wind protocol from the "driver" TXT record
+    val attributes: Map<String, String> = emptyMap()
+) {
+    fun toMap(): Map<String, Any?> = mapOf(
+        "name" to name,
+        "serviceType" to serviceType,
+        "host" to host,
+        "port" to port,
+        "deviceType" to deviceType,
+        "driver" to driver,
+        "attributes" to attributes
+    )
+}
+
+/**
+ * Network Device Discovery
+ * Browses mDNS for serial-over-TCP bridges and PolyField devices, so an official can pick
+ * the EDM bridge or wind gauge from a list instead of typing its IP address. Bridges that
+ * follow RFC 2217 advertise "_rfc2217._tcp"; PolyField-aware devices advertise
+ * "_polyfield._tcp" with "type" and "driver" TXT records.
+ */
+class NetworkDiscovery(context: Context) {
+
+    companion object {
+        private const val TAG = "NetworkDiscovery"
+        val SERVICE_TYPES = listOf("_polyfield._tcp", "_rfc2217._tcp")
+        private const val RESOLVE_TIMEOUT_MS = 3000L
+    }
+
+    private val nsdManager = context.getSystemService(Context.NSD_SERVICE) as NsdManager
+
+    /**
+     * Browse for timeoutSeconds and return every service that could be resolved to an
+     * address, one entry per host and port
+     */
+    suspend fun discover(timeoutSeconds: Int, serviceTypes: List<String> = SERVICE_TYPES): List<DiscoveredNetworkDevice> = coroutineScope {
+        val found = Channel<NsdServiceInfo>(Channel.UNLIMITED)
+        val devices = ConcurrentHashMap<String, DiscoveredNetworkDevice>()
+
+        val listeners = serviceTypes.map { serviceType ->
+            object : NsdManager.DiscoveryListener {
+                override fun onServiceFound(serviceInfo: NsdServiceInfo) {
+                    found.trySend(serviceInfo)
+                }
+                override fun onServiceLost(serviceInfo: NsdServiceInfo) {}
+                override fun onDiscoveryStarted(type: String) {
+                    Log.d(TAG, "Browsing $type")
+                }
+                override fun onDiscoveryStopped(type: String) {}
+                override fun onStartDiscoveryFailed(type: String, errorCode: Int) {
+                    Log.w(TAG, "Could not browse $type: error $errorCode")
+                }
+                override fun onStopDiscoveryFailed(type: String, errorCode: Int) {}
+            }.also { nsdManager.discoverServices(serviceType, NsdManager.PROTOCOL_DNS_SD, it) }
+        }
+
+        // Resolve one at a time; older NsdManager versions refuse concurrent resolves
+        val resolver = launch(Dispatchers.IO) {
+            for (service in found) {
+                resolve(service)?.let { device -> devices["${device.host}:${device.port}"] = device }
+            }
+        }
+
+        delay(timeoutSeconds.coerceIn(1, 60) * 1000L)
+        listeners.forEach { listener ->
+            try {
+                nsdManager.stopServiceDiscovery(listener)
+            } catch (e: IllegalArgumentException) {
+                // Discovery never started for this type
+            }
+        }
+        found.close()
+        resolver.join()
+
+        Log.d(TAG, "Found ${devices.size} network device(s)")
+        devices.values.sortedWith(compareBy({ it.deviceType == null }, { it.name }))
+    }
+
+    private suspend fun resolve(service: NsdServiceInfo): DiscoveredNetworkDevice? {
+        val resolved = CompletableDeferred<NsdServiceInfo?>()
+        @Suppress("DEPRECATION")
+        nsdManager.resolveService(service, object : NsdManager.ResolveListener {
+            override fun onServiceResolved(serviceInfo: NsdServiceInfo) {
+                resolved.complete(serviceInfo)
+            }
+            override fun onResolveFailed(serviceInfo: NsdServiceInfo, errorCode: Int) {
+                Log.w(TAG, "Could not resolve ${serviceInfo.serviceName}: error $errorCode")
+                resolved.complete(null)
+            }
+        })
+        val info = withTimeoutOrNull(RESOLVE_TIMEOUT_MS) { resolved.await() } ?: return null
+
+        @Suppress("DEPRECATION")
+        val host = info.host?.hostAddress ?: return null
+        val attributes = info.attributes.mapValues { (_, value) -> value?.toString(Charsets.UTF_8).orEmpty() }
+        return DiscoveredNetworkDevice(
+            name = info.serviceName,
+            serviceType = service.serviceType.trim('.').removeSuffix(".local"),
+            host = host,
+            port = info.port,
+            deviceType = attributes["type"]?.lowercase()?.takeIf { it.isNotEmpty() },
+            driver = attributes["driver"]?.takeIf { it.isNotEmpty() },
+            attributes = attributes
+        )
+    }
+}
@@ -0,0 +1,111 @@
+package com.polyfieldandroid
+
+import android.util.Log
+
+/**
+ * Nikon Translator
+ * 
+ * Handles Nikon DTM / NPL stations in Nikon raw output format, where each value is tagged.
+ * 
+ * Response format: "SD 12.3450 VA 90.2030 HA 123.4050"
+ * - SD: Slope distance in metres
+ * - VA: Zenith angle in DDD.MMSS (90° 20' 30")
+ * - HA: Horizontal angle in DDD.MMSS
+ * Tags and values may also be separated by commas. Errors come back as "ERR" and a code.
+ * Target mode: "TP" for a prism, "TN" reflectorless (NPL stations).
+ */
+class NikonTranslator(deviceSpec: EDMDeviceSpec) : EDMDeviceTranslator(deviceSpec) {
+    
+    companion object {
+        private const val TAG = "NikonTranslator"
+        
+        private val MEASUREMENT_COMMAND = "MD\r\n".toByteArray(Charsets.US_ASCII)
+        private val PRISM_MODE_COMMAND = "TP\r\n".toByteArray(Charsets.US_ASCII)
+        private val REFLECTORLESS_MODE_COMMAND = "TN\r\n".toByteArray(Charsets.US_ASCII)
+        
+        private val REQUIRED_TAGS = listOf("SD", "VA", "HA")
+        
+        private val STATUS_CODES = mapOf(
+            "1" to "Measurement error - no prism found",
+            "2" to "Signal too weak",
+            "3" to "Tilt out of range"
+        )
+        
+        private val ERROR_KINDS = mapOf(
+            "1" to EDMErrorKind.NO_PRISM,
+            "2" to EDMErrorKind.WEAK_SIGNAL,
+            "3" to EDMErrorKind.TILT
+        )
+    }
+    
+    override val errorCodes: Map<String, EDMErrorKind> = ERROR_KINDS
+    
+    override fun getMeasurementCommand(): ByteArray {
+        return MEASUREMENT_COMMAND
+    }
+    
+    override fun getMeasurementModeCommand(mode: EDMMeasurementMode): ByteArray = when (mode) {
+        EDMMeasurementMode.PRISM -> PRISM_MODE_COMMAND
+        EDMMeasurementMode.REFLECTORLESS -> REFLECTORLESS_MODE_COMMAND
+    }
+    
+    override fun parseResponse(rawResponse: String): EDMParsedReading {
+        Log.d(TAG, "Parsing Nikon response: '$rawResponse'")
+        
+        val tokens = tokenize(rawResponse)
+        if (tokens.firstOrNull() == "ERR") {
+            val code = tokens.getOrNull(1)
+            return instrumentErrorReading(code ?: "ERR")
+        }
+        
+        val values = tagged(tokens)
+        val missing = REQUIRED_TAGS.filter { it !in values }
+        if (missing.isNotEmpty()) {
+            return invalidReading("Missing ${missing.joinToString()} in '${rawResponse.trim()}'")
+        }
+        
+        return try {
+            val vertical = values.getValue("VA")
+            val horizontal = values.getValue("HA")
+            EDMParsedReading(
+                slopeDistanceMm = values.getValue("SD").toDouble() * 1000.0,
+                verticalAngleDegrees = parseDotMMSS(vertical),
+                horizontalAngleDegrees = parseDotMMSS(horizontal),
+                verticalAngleRaw = vertical,
+                horizontalAngleRaw = horizontal
+            )
+        } catch (e: Exception) {
+            Log.e(TAG, "Error parsing Nikon response: '$rawResponse'", e)
+            invalidReading("Parse error: ${e.message}")
+        }
+    }
+    
+    override fun toGoMobileFormat(parsedReading: EDMParsedReading): String {
+        return standardGoMobileFormat(parsedReading)
+    }
+    
+    override fun isResponseComplete(response: String): Boolean {
+        val tokens = tokenize(response)
+        if (tokens.firstOrNull() == "ERR") return tokens.size >= 2
+        return tagged(tokens).keys.containsAll(REQUIRED_TAGS)
+    }
+    
+    override fun interpretStatusCode(statusCode: String?): String? {
+        return statusCode?.let { STATUS_CODES[it] }
+    }
+    
+    private fun tokenize(response: String): List<String> =
+        response.trim().split("[\\s,]+".toRegex()).filter { it.isNotEmpty() }
+    
+    private fun tagged(tokens: List<String>): Map<String, String> =
+        tokens.zipWithNext().filter { (tag, _) -> tag in REQUIRED_TAGS }.toMap()
+    
+    /**
+     * DDD.MMSS to decimal degrees, e.g. "90.2030" -> 90° 20' 30"
+     */
+    private fun parseDotMMSS(angleStr: String): Double {
+        val degrees = angleStr.substringBefore('.')
+        val minutesSeconds = angleStr.substringAfter('.', "").padEnd(4, '0').take(4)
+        return parseDDDMMSS(degrees.padStart(3, '0') + minutesSeconds)
+    }
+}
@@ -0,0 +1,86 @@
+package com.polyfieldandroid
+
+import android.content.Context
+import android.util.Log
+import com.google.gson.Gson
+
+/**
+ * Horizontal angle to a fixed reference target, taken when the station was set up
+ */
+data class OrientationReference(
+    val horizontalAngleDeg: Double,
+    val label: String,          // What the reference is, e.g. "Flag on north fence"
+    val recordedAt: Long
+)
+
+/**
+ * Result of re-sighting the reference: how far the instrument's horizontal zero has moved
+ */
+data class OrientationCheckResult(
+    val reference: OrientationReference,
+    val horizontalAngleDeg: Double,
+    val driftArcSec: Double,    // Now minus reference, wrapped to ±180°
+    val toleranceArcSec: Double,
+    val angleOnly: Boolean      // False when the instrument had to take a full reading
+) {
+    val withinTolerance: Boolean
+        get() = kotlin.math.abs(driftArcSec) <= toleranceArcSec
+
+    fun toMap(): Map<String, Any> = mapOf(
+        "success" to true,
+        "label" to reference.label,
+        "referenceAngleDeg" to reference.horizontalAngleDeg,
+        "referenceRecordedAt" to reference.recordedAt,
+        "horizontalAngleDeg" to horizontalAngleDeg,
+        "driftArcSec" to driftArcSec,
+        "toleranceArcSec" to toleranceArcSec,
+        "withinTolerance" to withinTolerance,
+        "angleOnly" to angleOnly
+    )
+
+    companion object {
+        // About 3 mm across the sector at 60 m; beyond this, re-verify the edge
+        const val DEFAULT_TOLERANCE_ARCSEC = 10.0
+
+        fun compare(
+            reference: OrientationReference,
+            horizontalAngleDeg: Double,
+            angleOnly: Boolean,
+            toleranceArcSec: Double = DEFAULT_TOLERANCE_ARCSEC
+        ): OrientationCheckResult {
+            val driftDeg = ((horizontalAngleDeg - reference.horizontalAngleDeg) % 360.0 + 540.0) % 360.0 - 180.0
+            return OrientationCheckResult(reference, horizontalAngleDeg, driftDeg * 3600.0, toleranceArcSec, angleOnly)
+        }
+    }
+}
+
+/**
+ * Persists each EDM's orientation reference, by device type
+ */
+class OrientationReferenceStore(context: Context) {
+
+    companion object {
+        private const val TAG = "OrientationReference"
+        private const val PREFS_NAME = "polyfield_orientation_references"
+    }
+
+    private val preferences = context.getSharedPreferences(PREFS_NAME, Context.MODE_PRIVATE)
+    private val gson = Gson()
+
+    fun load(deviceType: String): OrientationReference? {
+        return try {
+            preferences.getString(deviceType, null)?.let { gson.fromJson(it, OrientationReference::class.java) }
+        } catch (e: Exception) {
+            Log.e(TAG, "Error loading orientation reference for $deviceType: ${e.message}")
+            null
+        }
+    }
+
+    fun save(deviceType: String, reference: OrientationReference) {
+        preferences.edit().putString(deviceType, gson.toJson(reference)).apply()
+    }
+
+    fun clear(deviceType: String) {
+        preferences.edit().remove(deviceType).apply()
+    }
+}
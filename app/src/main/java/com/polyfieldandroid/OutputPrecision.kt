@@ -0,0 +1,118 @@
+package com.polyfieldandroid
+
+import android.content.Context
+import android.util.Log
+import com.google.gson.Gson
+import java.math.BigDecimal
+import java.math.RoundingMode
+
+/**
+ * Decimal places and rounding for numbers leaving the app in responses and exports.
+ * Official marks keep their rule-based rounding regardless of these settings.
+ */
+data class OutputPrecision(
+    val distanceDecimals: Int = 3,    // Metres
+    val coordinateDecimals: Int = 3,  // Metres, x/y
+    val angleDecimals: Int = 5,       // Degrees
+    val windDecimals: Int = 1,        // m/s
+    val ppmDecimals: Int = 1,
+    val roundingMode: RoundingMode = RoundingMode.HALF_UP
+) {
+    enum class Field { DISTANCE, COORDINATE, ANGLE, WIND, PPM }
+
+    fun decimals(field: Field): Int = when (field) {
+        Field.DISTANCE -> distanceDecimals
+        Field.COORDINATE -> coordinateDecimals
+        Field.ANGLE -> angleDecimals
+        Field.WIND -> windDecimals
+        Field.PPM -> ppmDecimals
+    }
+
+    fun round(value: Double, field: Field): Double {
+        if (value.isNaN() || value.isInfinite()) return value
+        return BigDecimal.valueOf(value).setScale(decimals(field), roundingMode).toDouble()
+    }
+
+    fun format(value: Double, field: Field): String =
+        if (value.isNaN() || value.isInfinite()) value.toString()
+        else BigDecimal.valueOf(value).setScale(decimals(field), roundingMode).toPlainString()
+
+    /**
+     * Round every Double in a response map, recursing into nested maps and lists.
+     * The field type is taken from the key, e.g. "x"/"y" are coordinates, "*Angle*" angles.
+     */
+    fun apply(map: Map<String, Any>): Map<String, Any> = map.mapValues { (key, value) -> applyValue(key, value) }
+
+    private fun applyValue(key: String, value: Any): Any = when (value) {
+        is Double -> round(value, fieldFor(key))
+        is Map<*, *> -> value.entries.associate { (k, v) ->
+            val childKey = k.toString()
+            childKey to (v?.let { applyValue(childKey, it) })
+        }
+        is List<*> -> value.map { item -> item?.let { applyValue(key, it) } }
+        else -> value
+    }
+
+    private fun fieldFor(key: String): Field {
+        val name = key.lowercase()
+        return when {
+            name in COORDINATE_KEYS -> Field.COORDINATE
+            "angle" in name || name.endsWith("deg") || name.endsWith("decimal") -> Field.ANGLE
+            "wind" in name -> Field.WIND
+            "ppm" in name -> Field.PPM
+            else -> Field.DISTANCE
+        }
+    }
+
+    private companion object {
+        val COORDINATE_KEYS = setOf("x", "y", "stationx", "stationy", "throwx", "throwy", "stationoffsetx", "stationoffsety")
+    }
+}
+
+/**
+ * Persists the output precision settings
+ */
+class OutputPrecisionStore(context: Context) {
+
+    companion object {
+        private const val TAG = "OutputPrecision"
+        private const val PREFS_NAME = "polyfield_output_precision"
+        private const val PREF_PRECISION = "precision"
+    }
+
+    private val preferences = context.getSharedPreferences(PREFS_NAME, Context.MODE_PRIVATE)
+    private val gson = Gson()
+
+    fun load(): OutputPrecision {
+        return try {
+            val json = preferences.getString(PREF_PRECISION, null) ?: return OutputPrecision()
+            gson.fromJson(json, OutputPrecision::class.java)?.let { validate(it) } ?: OutputPrecision()
+        } catch (e: Exception) {
+            Log.e(TAG, "Error loading output precision: ${e.message}")
+            OutputPrecision()
+        }
+    }
+
+    fun save(precision: OutputPrecision): OutputPrecision {
+        val validated = validate(precision)
+        preferences.edit()
+            .putString(PREF_PRECISION, gson.toJson(validated))
+            .apply()
+        return validated
+    }
+
+    /**
+     * Keep decimal places within 0-10; rounding mode missing from older saved JSON is defaulted
+     */
+    fun validate(precision: OutputPrecision): OutputPrecision {
+        val roundingMode = GsonFields.orNull(precision.roundingMode)
+        return precision.copy(
+            distanceDecimals = precision.distanceDecimals.coerceIn(0, 10),
+            coordinateDecimals = precision.coordinateDecimals.coerceIn(0, 10),
+            angleDecimals = precision.angleDecimals.coerceIn(0, 10),
+            windDecimals = precision.windDecimals.coerceIn(0, 10),
+            ppmDecimals = precision.ppmDecimals.coerceIn(0, 10),
+            roundingMode = roundingMode?.takeIf { it != RoundingMode.UNNECESSARY } ?: RoundingMode.HALF_UP
+        )
+    }
+}
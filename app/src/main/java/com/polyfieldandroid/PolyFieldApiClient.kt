@@ -21,13 +21,11 @@ class PolyFieldApiClient(private val context: Context) {
         private const val TAG = "PolyFieldApiClient"
         private const val CONNECTION_TIMEOUT = 10000 // 10 seconds
         private const val READ_TIMEOUT = 15000 // 15 seconds
-        private const val CACHE_FILE_NAME = "polyfield_results_cache.json"
         private const val RETRY_INTERVAL_MS = 120000L // 2 minutes
     }
     
     private val gson = Gson()
     private var baseUrl: String = ""
-    private val resultsCacheManager = ResultsCacheManager(context)
     
     // Data classes matching API specification
     data class Event(
@@ -66,7 +64,9 @@ class PolyFieldApiClient(private val context: Context) {
         val unit: String,
         val wind: String? = null,
         val valid: Boolean,
-        val coordinates: HeatmapCoordinate? = null
+        val coordinates: HeatmapCoordinate? = null,
+        val implementId: String? = null,
+        val measuredAt: Long? = null  // Epoch ms the EDM was triggered
     )
 
     data class HeatmapCoordinate(
@@ -88,7 +88,9 @@ class PolyFieldApiClient(private val context: Context) {
         val edmPosition: Coordinate,         // EDM station position (x, y) relative to circle center
         val sectorLines: SectorLines? = null, // Sector line positions (null for javelin)
         val timestamp: String,               // ISO 8601 timestamp of calibration
-        val calibrationId: String? = null    // Unique ID for calibration session
+        val calibrationId: String? = null,   // Unique ID for calibration session
+        val venueAltitudeM: Double? = null,  // Venue height above sea level in meters
+        val prismConstantMm: Double = 0.0    // Reflector prism constant applied to EDM distances
     )
 
     /**
@@ -151,24 +153,16 @@ class PolyFieldApiClient(private val context: Context) {
     }
     
     /**
-     * Submit results to server with automatic caching and retry
+     * Submit results to server
+     * Failures are thrown so the caller can queue the result in the persistent outbox
      */
     suspend fun postResult(ip: String, port: Int, payload: ResultPayload) = withContext(Dispatchers.IO) {
-        try {
-            setServerAddress(ip, port)
-            val url = URL("$baseUrl/api/v1/results")
-            val jsonPayload = gson.toJson(payload)
-            
-            performHttpRequest(url, "POST", jsonPayload)
-            Log.d(TAG, "Result submitted successfully for athlete ${payload.athleteBib}")
-            
-        } catch (e: Exception) {
-            Log.w(TAG, "Failed to submit result, caching for retry: ${e.message}")
-            resultsCacheManager.cacheResult(payload)
-            
-            // Start background retry process 
-            // Note: In production, this should use WorkManager for proper background processing
-        }
+        setServerAddress(ip, port)
+        val url = URL("$baseUrl/api/v1/results")
+        val jsonPayload = gson.toJson(payload)
+        
+        performHttpRequest(url, "POST", jsonPayload)
+        Log.d(TAG, "Result submitted successfully for athlete ${payload.athleteBib}")
     }
     
     /**
@@ -206,81 +200,4 @@ class PolyFieldApiClient(private val context: Context) {
             connection.disconnect()
         }
     }
-    
-    /**
-     * Start background retry process for cached results
-     * Note: Simplified implementation - production should use WorkManager
-     */
-    private fun startRetryProcess(ip: String, port: Int) {
-        // In production, this would use WorkManager for proper background processing
-        // For now, this is just a placeholder
-        Log.d(TAG, "Retry process would start in background (WorkManager implementation needed)")
-    }
-    
-    /**
-     * Results caching manager for offline resilience
-     */
-    private class ResultsCacheManager(private val context: Context) {
-        private val gson = Gson()
-        
-        fun cacheResult(result: ResultPayload) {
-            try {
-                val cachedResults = getCachedResults().toMutableList()
-                cachedResults.add(result)
-                
-                val cacheFile = File(context.cacheDir, CACHE_FILE_NAME)
-                cacheFile.writeText(gson.toJson(cachedResults))
-                
-                Log.d(TAG, "Result cached successfully")
-            } catch (e: Exception) {
-                Log.e(TAG, "Error caching result: ${e.message}")
-            }
-        }
-        
-        fun getCachedResults(): List<ResultPayload> {
-            return try {
-                val cacheFile = File(context.cacheDir, CACHE_FILE_NAME)
-                if (cacheFile.exists()) {
-                    val jsonString = cacheFile.readText()
-                    val listType = object : TypeToken<List<ResultPayload>>() {}.type
-                    gson.fromJson<List<ResultPayload>>(jsonString, listType) ?: emptyList()
-                } else {
-                    emptyList()
-                }
-            } catch (e: Exception) {
-                Log.e(TAG, "Error reading cached results: ${e.message}")
-                emptyList()
-            }
-        }
-        
-        fun removeFromCache(result: ResultPayload) {
-            try {
-                val cachedResults = getCachedResults().toMutableList()
-                cachedResults.removeAll { 
-                    it.eventId == result.eventId && it.athleteBib == result.athleteBib 
-                }
-                
-                val cacheFile = File(context.cacheDir, CACHE_FILE_NAME)
-                cacheFile.writeText(gson.toJson(cachedResults))
-                
-            } catch (e: Exception) {
-                Log.e(TAG, "Error removing result from cache: ${e.message}")
-            }
-        }
-        
-        fun hasCachedResults(): Boolean {
-            return getCachedResults().isNotEmpty()
-        }
-        
-        fun clearCache() {
-            try {
-                val cacheFile = File(context.cacheDir, CACHE_FILE_NAME)
-                if (cacheFile.exists()) {
-                    cacheFile.delete()
-                }
-            } catch (e: Exception) {
-                Log.e(TAG, "Error clearing cache: ${e.message}")
-            }
-        }
-    }
-}
\ No newline at end of file
+}
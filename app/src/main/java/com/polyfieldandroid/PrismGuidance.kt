@@ -0,0 +1,65 @@
+package com.polyfieldandroid
+
+import kotlin.math.atan2
+import kotlin.math.hypot
+
+/**
+ * Where the prism is relative to the circle and to the mark it is being placed on
+ */
+data class PrismGuidance(
+    val x: Double,                          // Prism position relative to circle centre
+    val y: Double,
+    val distanceFromEdgeM: Double,          // As read
+    val smoothedDistanceFromEdgeM: Double,  // For the live display
+    val targetDistanceM: Double? = null,    // Mark being placed, normally the last throw
+    val alongOffsetM: Double? = null,       // + means walk further out, - means come back in
+    val lateralOffsetM: Double? = null,     // + means move anticlockwise around the circle, - clockwise
+    val timestamp: Long = SessionSources.now()
+) {
+    fun toMap(): Map<String, Any> {
+        val map = mutableMapOf<String, Any>(
+            "x" to x,
+            "y" to y,
+            "distanceFromEdge" to distanceFromEdgeM,
+            "smoothedDistanceFromEdge" to smoothedDistanceFromEdgeM,
+            "timestamp" to timestamp
+        )
+        targetDistanceM?.let { map["targetDistance"] = it }
+        alongOffsetM?.let { map["alongOffset"] = it }
+        lateralOffsetM?.let { map["lateralOffset"] = it }
+        return map
+    }
+
+    companion object {
+        /**
+         * Guidance for a prism at (x, y) in the circle frame. Offsets are split along the
+         * radius through the target and across it, which is how the carrier walks.
+         */
+        fun compute(
+            point: Pair<Double, Double>,
+            circleRadius: Double,
+            smoothedDistanceFromEdge: Double,
+            target: ThrowCoordinate?
+        ): PrismGuidance {
+            val (x, y) = point
+            val distanceFromEdge = hypot(x, y) - circleRadius
+            if (target == null) {
+                return PrismGuidance(x, y, distanceFromEdge, smoothedDistanceFromEdge)
+            }
+
+            // Rotate so the target's radius lies along +x: x' is along, y' is across
+            val (along, across) = CoordinateTransforms.rotate(point, -atan2(target.y, target.x))
+            val targetFromCentre = hypot(target.x, target.y)
+
+            return PrismGuidance(
+                x = x,
+                y = y,
+                distanceFromEdgeM = distanceFromEdge,
+                smoothedDistanceFromEdgeM = smoothedDistanceFromEdge,
+                targetDistanceM = target.distance,
+                alongOffsetM = targetFromCentre - along,
+                lateralOffsetM = -across
+            )
+        }
+    }
+}
@@ -0,0 +1,46 @@
+package com.polyfieldandroid
+
+/**
+ * How an athlete qualified from a qualification round
+ */
+enum class QualificationMark(val symbol: String) {
+    AUTOMATIC("Q"),      // Reached the qualifying standard
+    ON_PERFORMANCE("q")  // Filled a remaining place on best mark once the round ended
+}
+
+/**
+ * Qualification
+ * Q is awarded the moment an athlete reaches the qualifying standard. When the round is
+ * complete the places left up to the target number go to the best remaining marks as q;
+ * athletes tied for the last place all go through.
+ */
+object Qualification {
+
+    /**
+     * Qualification marks for a ranked results sheet, by bib
+     */
+    fun marks(
+        entries: List<ResultsEntry>,
+        settings: CompetitionSettings,
+        roundComplete: Boolean
+    ): Map<String, QualificationMark> {
+        if (!settings.isQualificationRound) return emptyMap()
+        val standard = settings.qualifyingStandardM
+
+        val marks = linkedMapOf<String, QualificationMark>()
+        if (standard != null) {
+            entries.filter { (it.bestMark ?: 0.0) >= standard }
+                .forEach { marks[it.athlete.bib] = QualificationMark.AUTOMATIC }
+        }
+        if (!roundComplete) return marks
+
+        val remainingPlaces = settings.qualifierTarget - marks.size
+        if (remainingPlaces <= 0) return marks
+
+        val candidates = entries.filter { it.place != null && it.athlete.bib !in marks }
+        val lastPlace = candidates.getOrNull(remainingPlaces - 1)?.place
+        candidates.filter { lastPlace == null || (it.place ?: Int.MAX_VALUE) <= lastPlace }
+            .forEach { marks[it.athlete.bib] = QualificationMark.ON_PERFORMANCE }
+        return marks
+    }
+}
@@ -0,0 +1,88 @@
+package com.polyfieldandroid
+
+import android.util.Log
+import java.util.concurrent.ConcurrentHashMap
+
+/**
+ * Read Timeout Policy
+ * A single fixed timeout has to suit the slowest instrument on the coldest day, which leaves
+ * an official waiting on a dead EDM far longer than a healthy one ever takes. This keeps
+ * each instrument's recent response times and waits a margin over the slowest of them,
+ * stretched below freezing (EDMs measure noticeably slower in the cold) and after each
+ * timeout until the instrument answers again. Until enough responses have been seen the
+ * configured timeout applies.
+ */
+class ReadTimeoutPolicy {
+
+    companion object {
+        private const val TAG = "ReadTimeoutPolicy"
+        private const val SAMPLE_COUNT = 20
+        private const val MIN_SAMPLES = 5
+        private const val LATENCY_MARGIN = 2.5
+        private const val MIN_TIMEOUT_MS = 2000L
+        private const val MAX_TIMEOUT_MS = 60000L
+        private const val TIMEOUT_BACKOFF = 1.5
+        private const val MAX_BACKOFF = 4.0
+
+        // Extra time allowed per degree below 0 °C, up to COLD_FACTOR_MAX
+        private const val COLD_FACTOR_PER_DEGREE = 0.03
+        private const val COLD_FACTOR_MAX = 2.0
+    }
+
+    private class History {
+        val latencies = ArrayDeque<Long>()
+        var backoff = 1.0
+    }
+
+    private val histories = ConcurrentHashMap<String, History>()
+
+    /**
+     * Timeout for the next read from a device
+     */
+    fun timeoutFor(deviceType: String, configuredMs: Long, temperatureC: Double?): Long {
+        val history = history(deviceType)
+        val (base, backoff) = synchronized(history) {
+            val base = if (history.latencies.size < MIN_SAMPLES) {
+                configuredMs
+            } else {
+                (history.latencies.max() * LATENCY_MARGIN).toLong()
+            }
+            base to history.backoff
+        }
+        return (base * coldFactor(temperatureC) * backoff).toLong().coerceIn(MIN_TIMEOUT_MS, MAX_TIMEOUT_MS)
+    }
+
+    fun recordResponse(deviceType: String, latencyMs: Long) {
+        val history = history(deviceType)
+        synchronized(history) {
+            history.latencies.addLast(latencyMs)
+            while (history.latencies.size > SAMPLE_COUNT) history.latencies.removeFirst()
+            history.backoff = 1.0
+        }
+    }
+
+    /**
+     * Wait longer next time, in case the instrument is only slower than it has been
+     */
+    fun recordTimeout(deviceType: String) {
+        val history = history(deviceType)
+        synchronized(history) {
+            history.backoff = (history.backoff * TIMEOUT_BACKOFF).coerceAtMost(MAX_BACKOFF)
+            Log.d(TAG, "$deviceType timed out; next timeout x${history.backoff}")
+        }
+    }
+
+    /**
+     * Forget a device's history, e.g. when a different instrument is connected in its place
+     */
+    fun reset(deviceType: String) {
+        histories.remove(deviceType)
+    }
+
+    private fun coldFactor(temperatureC: Double?): Double {
+        if (temperatureC == null || temperatureC >= 0.0) return 1.0
+        return (1.0 - temperatureC * COLD_FACTOR_PER_DEGREE).coerceAtMost(COLD_FACTOR_MAX)
+    }
+
+    private fun history(deviceType: String): History = histories.getOrPut(deviceType) { History() }
+}
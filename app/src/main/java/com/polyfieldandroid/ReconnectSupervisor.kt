@@ -0,0 +1,90 @@
+package com.polyfieldandroid
+
+import android.util.Log
+import kotlinx.coroutines.CoroutineScope
+import kotlinx.coroutines.Dispatchers
+import kotlinx.coroutines.Job
+import kotlinx.coroutines.SupervisorJob
+import kotlinx.coroutines.delay
+import kotlinx.coroutines.isActive
+import kotlinx.coroutines.launch
+import java.util.concurrent.ConcurrentHashMap
+
+/**
+ * Reconnect Supervisor
+ * Watches connected devices and brings back any whose channel has died - a pulled
+ * network cable, a Wi-Fi blip, a Bluetooth gauge walked out of range - retrying with
+ * exponential backoff until it is back or no longer wanted. A device is wanted while
+ * auto-reconnect is on for it and it has not been disconnected on purpose.
+ */
+class ReconnectSupervisor(
+    private val watchedDevices: () -> List<String>,
+    private val isAlive: (String) -> Boolean,
+    private val isWanted: (String) -> Boolean,
+    private val reconnect: suspend (String) -> Boolean,
+    private val onEvent: (ConnectionEventType, String, String) -> Unit
+) {
+
+    companion object {
+        private const val TAG = "ReconnectSupervisor"
+        private const val CHECK_INTERVAL_MS = 2000L
+        private const val INITIAL_BACKOFF_MS = 1000L
+        private const val MAX_BACKOFF_MS = 60000L
+    }
+
+    private val scope = CoroutineScope(SupervisorJob() + Dispatchers.IO)
+    private val reconnecting = ConcurrentHashMap<String, Job>()
+    private var monitorJob: Job? = null
+
+    fun start() {
+        if (monitorJob?.isActive == true) return
+        monitorJob = scope.launch {
+            while (isActive) {
+                delay(CHECK_INTERVAL_MS)
+                try {
+                    watchedDevices()
+                        .filter { !isReconnecting(it) && isWanted(it) && !isAlive(it) }
+                        .forEach { beginReconnect(it) }
+                } catch (e: Exception) {
+                    Log.w(TAG, "Connection check failed: ${e.message}")
+                }
+            }
+        }
+    }
+
+    fun isReconnecting(deviceType: String): Boolean = reconnecting[deviceType]?.isActive == true
+
+    /**
+     * Stop retrying a device, e.g. when auto-reconnect is turned off for it
+     */
+    fun cancel(deviceType: String) {
+        reconnecting.remove(deviceType)?.cancel()
+    }
+
+    private fun beginReconnect(deviceType: String) {
+        Log.w(TAG, "$deviceType connection lost")
+        onEvent(ConnectionEventType.DISCONNECTED, deviceType, "Connection lost")
+
+        reconnecting[deviceType] = scope.launch {
+            var backoffMs = INITIAL_BACKOFF_MS
+            var attempt = 0
+            while (isActive && isWanted(deviceType)) {
+                attempt++
+                onEvent(ConnectionEventType.RECONNECTING, deviceType, "Reconnect attempt $attempt")
+                val restored = try {
+                    reconnect(deviceType)
+                } catch (e: Exception) {
+                    Log.w(TAG, "Reconnect attempt $attempt for $deviceType failed: ${e.message}")
+                    false
+                }
+                if (restored) {
+                    Log.d(TAG, "$deviceType reconnected after $attempt attempt(s)")
+                    onEvent(ConnectionEventType.RESUMED, deviceType, "$deviceType reconnected")
+                    return@launch
+                }
+                delay(backoffMs)
+                backoffMs = (backoffMs * 2).coerceAtMost(MAX_BACKOFF_MS)
+            }
+        }
+    }
+}
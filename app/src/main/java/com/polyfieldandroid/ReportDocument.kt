@@ -0,0 +1,492 @@
+package com.polyfieldandroid
+
+import com.google.gson.Gson
+import java.text.SimpleDateFormat
+import java.util.Date
+import java.util.Locale
+
+enum class ReportBlockType {
+    PARAGRAPH,
+    KEY_VALUES, // Label/value pairs, e.g. event details
+    TABLE,
+    SIGNATURES  // Lines for officials to sign
+}
+
+/**
+ * One renderable block. Only the fields for its type are set, which keeps the
+ * document a flat structure that serialises cleanly for the renderer.
+ */
+data class ReportBlock(
+    val type: ReportBlockType,
+    val text: String? = null,
+    val items: List<Pair<String, String>>? = null,
+    val columns: List<String>? = null,
+    val rows: List<List<String>>? = null,
+    val signatories: List<String>? = null
+)
+
+data class ReportSection(
+    val title: String,
+    val blocks: List<ReportBlock>
+)
+
+/**
+ * Print-ready report for the host app to lay out as PDF
+ */
+data class ReportDocument(
+    val title: String,
+    val subtitle: String? = null,
+    val sections: List<ReportSection>,
+    val generatedAt: Long = SessionSources.now()
+) {
+    fun toJson(): String = Gson().toJson(this)
+}
+
+/**
+ * Builds the official results card and circle-certification record from held data
+ */
+object ReportBuilder {
+
+    private val signatureOfficials = listOf("Chief Judge", "Referee")
+
+    /**
+     * Official results card: event details, full attempt matrix with placings, conditions
+     * and signatures
+     */
+    fun resultsCard(
+        sheet: ResultsSheet,
+        conditions: ResultsConditions?,
+        conditionsLog: ConditionsSummary? = null,
+        implementInspections: List<Pair<ImplementInspection, Implement?>> = emptyList(),
+        signOffs: List<OfficialSignOff> = emptyList()
+    ): ReportDocument {
+        val columns = mutableListOf("Pl", "Bib", "Athlete", "Club")
+        for (round in 1..sheet.rounds) columns.add("R$round")
+        columns.add("Best")
+
+        val rows = sheet.entries.map { entry ->
+            val row = mutableListOf(
+                entry.place?.toString() ?: "",
+                entry.athlete.bib,
+                entry.athlete.name,
+                entry.athlete.club
+            )
+            for (round in 1..sheet.rounds) row.add(formatAttempt(entry.attemptFor(round)))
+            row.add((entry.bestMark?.let { formatMark(it) } ?: "NM") + (entry.qualification?.let { " ${it.symbol}" } ?: ""))
+            row
+        }
+
+        val sections = mutableListOf(
+            ReportSection(
+                title = "Event",
+                blocks = listOf(
+                    keyValues(
+                        "Event" to sheet.eventName,
+                        "Discipline" to sheet.eventType,
+                        "Rounds" to sheet.rounds.toString(),
+                        "Competitors" to sheet.entries.size.toString(),
+                        "Generated" to formatTime(sheet.generatedAt)
+                    )
+                )
+            ),
+            ReportSection(
+                title = "Results",
+                blocks = listOf(
+                    ReportBlock(type = ReportBlockType.TABLE, columns = columns, rows = rows),
+                    ReportBlock(type = ReportBlockType.PARAGRAPH, text = "X = foul, - = pass, NM = no valid mark, R = under review, D = disallowed on review, " +
+                            "Q = qualified by standard, q = qualified on performance")
+                )
+            )
+        )
+
+        conditions?.let { sections.add(ReportSection("Conditions", listOf(conditionValues(it)))) }
+        conditionsLog?.takeIf { !it.isEmpty }?.let { sections.add(conditionsLogSection(it)) }
+        if (implementInspections.isNotEmpty()) sections.add(implementsSection(implementInspections))
+        if (signOffs.isNotEmpty()) sections.add(confirmationsSection(signOffs))
+        sections.add(signatures())
+
+        return ReportDocument(
+            title = "Official Results",
+            subtitle = sheet.eventName,
+            sections = sections,
+            generatedAt = sheet.generatedAt
+        )
+    }
+
+    /**
+     * Circle-certification record: circle, EDM station, edge verification, sector line and stopboard
+     */
+    fun circleCertification(calibration: CalibrationState, tolerance: Double, deviceName: String?): ReportDocument {
+        val sections = mutableListOf<ReportSection>()
+
+        sections.add(
+            ReportSection(
+                title = "Circle",
+                blocks = listOf(
+                    keyValues(
+                        "Circle type" to calibration.circleType.replace("_", " "),
+                        "Nominal radius" to "${String.format(Locale.US, "%.4f", calibration.targetRadius)} m",
+                        "Tolerance" to "±${String.format(Locale.US, "%.1f", tolerance * 1000)} mm",
+                        "Instrument" to (deviceName ?: "Not recorded"),
+                        "Centre set" to (calibration.centreTimestamp ?: "Not set")
+                    )
+                )
+            )
+        )
+
+        calibration.stationCoordinates?.let { (x, y) ->
+            sections.add(
+                ReportSection(
+                    title = "EDM Station",
+                    blocks = listOf(
+                        keyValues(
+                            "X" to "${String.format(Locale.US, "%.3f", x)} m",
+                            "Y" to "${String.format(Locale.US, "%.3f", y)} m"
+                        )
+                    )
+                )
+            )
+        }
+
+        val edge = calibration.edgeResult
+        sections.add(
+            ReportSection(
+                title = "Edge Verification",
+                blocks = if (edge == null) {
+                    listOf(ReportBlock(type = ReportBlockType.PARAGRAPH, text = "Edge not verified"))
+                } else {
+                    listOf(
+                        keyValues(
+                            "Average radius" to "${String.format(Locale.US, "%.4f", edge.averageRadius)} m",
+                            "Deviation" to "${String.format(Locale.US, "%+.1f", edge.deviation * 1000)} mm",
+                            "Result" to if (edge.toleranceCheck) "Within tolerance" else "Outside tolerance"
+                        ),
+                        ReportBlock(
+                            type = ReportBlockType.TABLE,
+                            columns = listOf("Reading", "Radius (m)"),
+                            rows = edge.measurements.mapIndexed { index, radius ->
+                                listOf((index + 1).toString(), String.format(Locale.US, "%.4f", radius))
+                            }
+                        )
+                    )
+                }
+            )
+        )
+
+        if (calibration.sectorLineSet) {
+            sections.add(
+                ReportSection(
+                    title = "Sector Line",
+                    blocks = listOf(
+                        keyValues(
+                            "Check distance" to (calibration.sectorLineDistance?.let { "${String.format(Locale.US, "%.2f", it)} m" } ?: "-")
+                        )
+                    )
+                )
+            )
+        }
+
+        calibration.stopboardVerification?.let { stopboard ->
+            sections.add(
+                ReportSection(
+                    title = "Stopboard",
+                    blocks = listOf(
+                        keyValues(
+                            "Off inner edge" to "${String.format(Locale.US, "%+.1f", stopboard.edgeDeviationMm)} mm (${passFail(stopboard.edgePassed)})",
+                            "Off centre" to (stopboard.centreOffsetMm?.let { offset ->
+                                "${String.format(Locale.US, "%+.1f", offset)} mm (${passFail(stopboard.centrePassed == true)})"
+                            } ?: "No sector line"),
+                            "Height" to "${String.format(Locale.US, "%.1f", stopboard.heightMm)} mm, worst ${String.format(Locale.US, "%+.1f", stopboard.heightDeviationMm)} mm (${passFail(stopboard.heightPassed)})",
+                            "Length measured" to "${String.format(Locale.US, "%.3f", stopboard.spanM)} m"
+                        ),
+                        ReportBlock(
+                            type = ReportBlockType.TABLE,
+                            columns = listOf("Point", "X (m)", "Y (m)", "Height (mm)"),
+                            rows = stopboard.points.mapIndexed { index, point ->
+                                listOf(
+                                    (index + 1).toString(),
+                                    String.format(Locale.US, "%.3f", point.x),
+                                    String.format(Locale.US, "%.3f", point.y),
+                                    String.format(Locale.US, "%.1f", (point.heightM - stopboard.circleLevelM) * 1000)
+                                )
+                            }
+                        )
+                    )
+                )
+            )
+        }
+
+        sections.add(signatures())
+
+        return ReportDocument(
+            title = "Circle Certification",
+            subtitle = calibration.circleType.replace("_", " "),
+            sections = sections
+        )
+    }
+
+    /**
+     * Full UKA/WA certification survey: diameters, sector angle and stopboard with pass/fail
+     */
+    fun ukaCertification(result: CertificationResult, deviceName: String?): ReportDocument {
+        val sections = mutableListOf(
+            ReportSection(
+                title = "Circle",
+                blocks = listOf(
+                    keyValues(
+                        "Circle type" to result.circleType.replace("_", " "),
+                        "Nominal diameter" to "${String.format(Locale.US, "%.3f", result.nominalRadius * 2)} m",
+                        "Instrument" to (deviceName ?: "Not recorded"),
+                        "Surveyed" to formatTime(result.completedAt),
+                        "Overall" to passFail(result.passed)
+                    )
+                )
+            ),
+            ReportSection(
+                title = "Inner Diameter",
+                blocks = listOf(
+                    ReportBlock(
+                        type = ReportBlockType.TABLE,
+                        columns = listOf("Chord", "Diameter (m)", "Deviation (mm)", "Result"),
+                        rows = result.diameters.map { chord ->
+                            listOf(
+                                chord.index.toString(),
+                                String.format(Locale.US, "%.4f", chord.diameterM),
+                                String.format(Locale.US, "%+.1f", chord.deviationMm),
+                                passFail(chord.passed)
+                            )
+                        }
+                    )
+                )
+            )
+        )
+
+        result.sector?.let { sector ->
+            sections.add(
+                ReportSection(
+                    title = "Sector",
+                    blocks = listOf(
+                        keyValues(
+                            "Measured angle" to "${String.format(Locale.US, "%.3f", sector.angleDeg)}°",
+                            "Nominal angle" to "${String.format(Locale.US, "%.2f", sector.nominalDeg)}°",
+                            "Deviation" to "${String.format(Locale.US, "%+.3f", sector.deviationDeg)}°",
+                            "Result" to passFail(sector.passed)
+                        )
+                    )
+                )
+            )
+        }
+
+        result.stopboard?.let { stopboard ->
+            sections.add(
+                ReportSection(
+                    title = "Stopboard",
+                    blocks = listOf(
+                        keyValues(
+                            "Inside width" to "${String.format(Locale.US, "%.3f", stopboard.widthM)} m (${passFail(stopboard.widthPassed)})",
+                            "Off centre" to "${String.format(Locale.US, "%+.1f", stopboard.centreOffsetMm)} mm (${passFail(stopboard.centrePassed)})",
+                            "Off inner edge" to "${String.format(Locale.US, "%+.1f", stopboard.edgeDeviationMm)} mm (${passFail(stopboard.edgePassed)})"
+                        )
+                    )
+                )
+            )
+        }
+
+        sections.add(signatures())
+
+        return ReportDocument(
+            title = "Circle Certification (UKA/WA)",
+            subtitle = "${result.circleType.replace("_", " ")} - ${passFail(result.passed)}",
+            sections = sections,
+            generatedAt = result.completedAt
+        )
+    }
+
+    /**
+     * Per-throw correction breakdown for record documentation: one section per throw
+     * listing each correction with the distance before and after it
+     */
+    fun correctionBreakdown(eventName: String, entries: List<ThrowAuditEntry>): ReportDocument {
+        val sections = entries.map { entry ->
+            val c = entry.components
+            ReportSection(
+                title = "Bib ${entry.athleteBib}, round ${entry.round}",
+                blocks = listOf(
+                    keyValues(
+                        "Measured" to formatClock(entry.timestamp),
+                        "Raw reading" to "SD ${String.format(Locale.US, "%.4f", c.rawSlopeDistanceM)} m, " +
+                            "VA ${String.format(Locale.US, "%.5f", c.verticalAngleDeg)}°, " +
+                            "HA ${String.format(Locale.US, "%.5f", c.horizontalAngleDeg)}°",
+                        "Angles as read" to if (c.verticalAngleRaw != null || c.horizontalAngleRaw != null) {
+                            "VA ${c.verticalAngleRaw ?: "-"}, HA ${c.horizontalAngleRaw ?: "-"}"
+                        } else "Not provided by instrument",
+                        "Official mark" to formatMark(CorrectionBreakdown.officialMark(c.throwDistanceM))
+                    ),
+                    ReportBlock(
+                        type = ReportBlockType.TABLE,
+                        columns = listOf("Correction", "Before (m)", "After (m)", "Change (mm)", "Applied", "Note"),
+                        rows = CorrectionBreakdown.steps(c).map { step ->
+                            listOf(
+                                step.name,
+                                String.format(Locale.US, "%.4f", step.before),
+                                String.format(Locale.US, "%.4f", step.after),
+                                String.format(Locale.US, "%+.1f", step.changeMm),
+                                if (step.applied) "Yes" else "No",
+                                step.note
+                            )
+                        }
+                    )
+                )
+            )
+        }
+
+        return ReportDocument(
+            title = "Measurement Corrections",
+            subtitle = eventName,
+            sections = sections + signatures()
+        )
+    }
+
+    private fun conditionValues(conditions: ResultsConditions): ReportBlock {
+        val items = mutableListOf(
+            "Circle" to "${conditions.circleType.replace("_", " ")}, radius ${String.format(Locale.US, "%.4f", conditions.circleRadius)} m"
+        )
+        conditions.calibratedAt?.let { items.add("Calibrated" to it) }
+        conditions.edgeDeviationMm?.let { deviation ->
+            val tolerance = when (conditions.edgeInTolerance) {
+                true -> " (within tolerance)"
+                false -> " (outside tolerance)"
+                null -> ""
+            }
+            items.add("Edge check" to "${String.format(Locale.US, "%+.1f", deviation)} mm$tolerance")
+        }
+        conditions.averageWind?.let { items.add("Average wind" to "${String.format(Locale.US, "%+.1f", it)} m/s") }
+        conditions.venueAltitudeM?.let { altitude ->
+            val annotation = if (AtmosphericCorrection.isAltitudeAnnotated(altitude)) " (marks set at altitude)" else ""
+            items.add("Venue altitude" to "${String.format(Locale.US, "%.0f", altitude)} m$annotation")
+        }
+        conditions.atmosphericPpm?.let { items.add("Atmospheric correction" to "${String.format(Locale.US, "%+.1f", it)} ppm") }
+        conditions.windGaugePlacement?.let { placement ->
+            val problems = placement.problems()
+            val compliance = if (problems.isEmpty()) "" else " (outside rules: ${problems.joinToString("; ")})"
+            items.add("Wind gauge" to placement.describe() + compliance)
+        }
+        return ReportBlock(type = ReportBlockType.KEY_VALUES, items = items)
+    }
+
+    /**
+     * Min/max/mean of each logged quantity with the times the extremes were recorded,
+     * followed by officials' notes in the order they were made
+     */
+    private fun conditionsLogSection(summary: ConditionsSummary): ReportSection {
+        val blocks = mutableListOf<ReportBlock>()
+        if (summary.from != null && summary.to != null) {
+            blocks.add(keyValues("Period" to "${formatClock(summary.from)} - ${formatClock(summary.to)}"))
+        }
+        if (summary.stats.isNotEmpty()) {
+            blocks.add(
+                ReportBlock(
+                    type = ReportBlockType.TABLE,
+                    columns = listOf("Condition", "Min", "At", "Max", "At", "Mean", "Readings"),
+                    rows = summary.stats.map { stats ->
+                        val unit = stats.kind.unit
+                        listOf(
+                            stats.kind.name.lowercase().replaceFirstChar { it.uppercase() },
+                            "${String.format(Locale.US, "%.1f", stats.min)} $unit",
+                            formatClock(stats.minAt),
+                            "${String.format(Locale.US, "%.1f", stats.max)} $unit",
+                            formatClock(stats.maxAt),
+                            "${String.format(Locale.US, "%.1f", stats.mean)} $unit",
+                            stats.count.toString()
+                        )
+                    }
+                )
+            )
+        }
+        if (summary.notes.isNotEmpty()) {
+            blocks.add(
+                ReportBlock(
+                    type = ReportBlockType.TABLE,
+                    columns = listOf("Time", "Applies to", "Note"),
+                    rows = summary.notes.map { listOf(formatClock(it.timestamp), it.appliesTo(), it.note.orEmpty()) }
+                )
+            )
+        }
+        return ReportSection(title = "Conditions Log", blocks = blocks)
+    }
+
+    /**
+     * Implements inspected at check-in; approved ones first
+     */
+    private fun implementsSection(inspections: List<Pair<ImplementInspection, Implement?>>): ReportSection {
+        val rows = inspections.sortedBy { !it.first.passed }.map { (inspection, implement) ->
+            listOf(
+                inspection.implementId,
+                implement?.certificationNumber.orEmpty(),
+                "${String.format(Locale.US, "%.3f", inspection.weightKg)} kg",
+                inspection.dimensions.entries.joinToString(", ") { "${it.key} ${String.format(Locale.US, "%.1f", it.value)} mm" },
+                if (inspection.passed) "Approved" else "Rejected",
+                inspection.inspector,
+                formatClock(inspection.timestamp),
+                inspection.notes.orEmpty()
+            )
+        }
+        return ReportSection(
+            title = "Implements",
+            blocks = listOf(
+                ReportBlock(
+                    type = ReportBlockType.TABLE,
+                    columns = listOf("Implement", "Cert. no.", "Weight", "Dimensions", "Result", "Inspector", "Time", "Notes"),
+                    rows = rows
+                )
+            )
+        )
+    }
+
+    /**
+     * Electronic confirmations given on the device, with the results hash they cover
+     */
+    private fun confirmationsSection(signOffs: List<OfficialSignOff>) = ReportSection(
+        title = "Confirmed",
+        blocks = listOf(
+            ReportBlock(
+                type = ReportBlockType.TABLE,
+                columns = listOf("Role", "Official", "Time", "Results hash"),
+                rows = signOffs.map { listOf(it.role.title, it.name, formatTime(it.timestamp), it.resultsHash) }
+            )
+        )
+    )
+
+    private fun signatures() = ReportSection(
+        title = "Signatures",
+        blocks = listOf(ReportBlock(type = ReportBlockType.SIGNATURES, signatories = signatureOfficials))
+    )
+
+    private fun passFail(passed: Boolean): String = if (passed) "PASS" else "FAIL"
+
+    private fun keyValues(vararg items: Pair<String, String>) =
+        ReportBlock(type = ReportBlockType.KEY_VALUES, items = items.toList())
+
+    private fun formatAttempt(attempt: AthleteAttempt?): String {
+        if (attempt == null) return ""
+        val mark = when {
+            !attempt.isValid -> "X"
+            attempt.isPass -> "-"
+            attempt.distance != null -> formatMark(attempt.distance)
+            else -> ""
+        }
+        return when {
+            attempt.isUnderReview -> "$mark R"
+            attempt.review?.resolution == ReviewResolution.DISALLOWED -> "$mark D"
+            else -> mark
+        }
+    }
+
+    private fun formatMark(metres: Double): String = String.format(Locale.US, "%.2f", metres)
+
+    private fun formatClock(timestamp: Long): String =
+        SimpleDateFormat("HH:mm:ss", Locale.getDefault()).format(Date(timestamp))
+
+    private fun formatTime(timestamp: Long): String =
+        SimpleDateFormat("yyyy-MM-dd HH:mm", Locale.getDefault()).format(Date(timestamp))
+}
@@ -163,6 +163,8 @@ class ResultSyncWorker(
 
 /**
  * Enhanced Results Cache Manager with better organization
+ * Results are kept in filesDir (not cacheDir) so the upload outbox survives restarts
+ * and is never purged by the system under storage pressure
  */
 class ResultsCacheManager(private val context: Context) {
     companion object {
@@ -177,8 +179,36 @@ class ResultsCacheManager(private val context: Context) {
         val lastSyncAttempt: Long = 0L,
         val totalCachedResults: Int = 0,
         val lastSuccessfulSync: Long = 0L,
-        val consecutiveFailures: Int = 0
+        val consecutiveFailures: Int = 0,
+        val queuedAt: Map<String, Long>? = null // Enqueue time per event/athlete key
     )
+
+    init {
+        migrateFromCacheDir()
+    }
+
+    private fun cacheFile() = java.io.File(context.filesDir, CACHE_FILE_NAME)
+    private fun metadataFile() = java.io.File(context.filesDir, METADATA_FILE_NAME)
+    private fun queueKey(result: PolyFieldApiClient.ResultPayload) = "${result.eventId}:${result.athleteBib}"
+
+    /**
+     * Move any outbox left in cacheDir by earlier versions into filesDir
+     */
+    private fun migrateFromCacheDir() {
+        try {
+            for (name in listOf(CACHE_FILE_NAME, METADATA_FILE_NAME)) {
+                val legacy = java.io.File(context.cacheDir, name)
+                val current = java.io.File(context.filesDir, name)
+                if (legacy.exists() && !current.exists()) {
+                    legacy.copyTo(current)
+                    legacy.delete()
+                    Log.d(TAG, "Migrated $name to persistent storage")
+                }
+            }
+        } catch (e: Exception) {
+            Log.e(TAG, "Error migrating results cache: ${e.message}", e)
+        }
+    }
     
     fun cacheResult(result: PolyFieldApiClient.ResultPayload) {
         try {
@@ -191,14 +221,17 @@ class ResultsCacheManager(private val context: Context) {
             
             cachedResults.add(result)
             
-            val cacheFile = java.io.File(context.cacheDir, CACHE_FILE_NAME)
+            val cacheFile = cacheFile()
             cacheFile.writeText(gson.toJson(cachedResults))
             
             // Update metadata
             updateMetadata { metadata ->
+                val queuedAt = (metadata.queuedAt ?: emptyMap()).toMutableMap()
+                queuedAt.getOrPut(queueKey(result)) { SessionSources.now() }
                 metadata.copy(
                     totalCachedResults = cachedResults.size,
-                    lastSyncAttempt = System.currentTimeMillis()
+                    lastSyncAttempt = SessionSources.now(),
+                    queuedAt = queuedAt
                 )
             }
             
@@ -210,7 +243,7 @@ class ResultsCacheManager(private val context: Context) {
     
     fun getCachedResults(): List<PolyFieldApiClient.ResultPayload> {
         return try {
-            val cacheFile = java.io.File(context.cacheDir, CACHE_FILE_NAME)
+            val cacheFile = cacheFile()
             if (cacheFile.exists()) {
                 val jsonString = cacheFile.readText()
                 val listType = object : com.google.gson.reflect.TypeToken<List<PolyFieldApiClient.ResultPayload>>() {}.type
@@ -232,14 +265,15 @@ class ResultsCacheManager(private val context: Context) {
             }
             
             if (removed) {
-                val cacheFile = java.io.File(context.cacheDir, CACHE_FILE_NAME)
+                val cacheFile = cacheFile()
                 cacheFile.writeText(gson.toJson(cachedResults))
                 
                 updateMetadata { metadata ->
                     metadata.copy(
                         totalCachedResults = cachedResults.size,
-                        lastSuccessfulSync = System.currentTimeMillis(),
-                        consecutiveFailures = 0
+                        lastSuccessfulSync = SessionSources.now(),
+                        consecutiveFailures = 0,
+                        queuedAt = metadata.queuedAt?.minus(queueKey(result))
                     )
                 }
                 
@@ -258,9 +292,21 @@ class ResultsCacheManager(private val context: Context) {
         return getCachedResults().size
     }
     
+    /**
+     * Outbox depth and age of the oldest result still waiting for upload
+     */
+    fun getOutboxStatus(): OutboxStatus {
+        val results = getCachedResults()
+        val queuedAt = getMetadata().queuedAt ?: emptyMap()
+        return OutboxStatus(
+            depth = results.size,
+            oldestQueuedAt = results.mapNotNull { queuedAt[queueKey(it)] }.minOrNull()
+        )
+    }
+    
     fun clearCache() {
         try {
-            val cacheFile = java.io.File(context.cacheDir, CACHE_FILE_NAME)
+            val cacheFile = cacheFile()
             if (cacheFile.exists()) {
                 cacheFile.delete()
             }
@@ -268,7 +314,8 @@ class ResultsCacheManager(private val context: Context) {
             updateMetadata { metadata ->
                 metadata.copy(
                     totalCachedResults = 0,
-                    lastSuccessfulSync = System.currentTimeMillis()
+                    lastSuccessfulSync = SessionSources.now(),
+                    queuedAt = null
                 )
             }
             
@@ -280,7 +327,7 @@ class ResultsCacheManager(private val context: Context) {
     
     fun getMetadata(): CacheMetadata {
         return try {
-            val metadataFile = java.io.File(context.cacheDir, METADATA_FILE_NAME)
+            val metadataFile = metadataFile()
             if (metadataFile.exists()) {
                 val jsonString = metadataFile.readText()
                 gson.fromJson(jsonString, CacheMetadata::class.java) ?: CacheMetadata()
@@ -298,7 +345,7 @@ class ResultsCacheManager(private val context: Context) {
             val currentMetadata = getMetadata()
             val updatedMetadata = update(currentMetadata)
             
-            val metadataFile = java.io.File(context.cacheDir, METADATA_FILE_NAME)
+            val metadataFile = metadataFile()
             metadataFile.writeText(gson.toJson(updatedMetadata))
         } catch (e: Exception) {
             Log.e(TAG, "Error updating cache metadata: ${e.message}", e)
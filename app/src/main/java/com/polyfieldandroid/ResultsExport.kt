@@ -0,0 +1,248 @@
+package com.polyfieldandroid
+
+import java.util.Locale
+import kotlin.math.floor
+
+/**
+ * One competitor's line in the results, with their series and placing
+ */
+data class ResultsEntry(
+    val place: Int?, // Null when the athlete has no valid mark
+    val athlete: CompetitionAthlete,
+    val bestMark: Double?,
+    val bestMarkWind: Double?,
+    val qualification: QualificationMark? = null
+) {
+    /**
+     * Attempt for a round, or null if the athlete did not take one
+     */
+    fun attemptFor(round: Int): AthleteAttempt? = athlete.attempts.find { it.round == round }
+}
+
+/**
+ * Event results assembled from the athlete state, shared by every export format
+ */
+data class ResultsSheet(
+    val eventId: String,
+    val eventName: String,
+    val eventType: String,
+    val rounds: Int,
+    val entries: List<ResultsEntry>,
+    val generatedAt: Long = SessionSources.now()
+) {
+    /**
+     * Event name made safe for use in a file name
+     */
+    val fileBaseName: String
+        get() = eventName.replace(Regex("[^A-Za-z0-9]+"), "_").trim('_').ifBlank { "results" }
+
+    companion object {
+
+        /**
+         * Rank athletes by best mark, breaking ties on the next best mark and so on.
+         * Athletes still level after every mark share the place.
+         */
+        fun build(
+            event: PolyFieldApiClient.Event?,
+            settings: CompetitionSettings,
+            athletes: List<CompetitionAthlete>,
+            qualificationComplete: Boolean = false
+        ): ResultsSheet {
+            val series = athletes.associate { athlete ->
+                athlete.bib to athlete.getValidMeasurements().mapNotNull { it.distance }.sortedDescending()
+            }
+            val countback = Comparator<CompetitionAthlete> { a, b ->
+                val marksA = series[a.bib].orEmpty()
+                val marksB = series[b.bib].orEmpty()
+                for (i in 0 until maxOf(marksA.size, marksB.size)) {
+                    val compare = (marksB.getOrNull(i) ?: -1.0).compareTo(marksA.getOrNull(i) ?: -1.0)
+                    if (compare != 0) return@Comparator compare
+                }
+                0
+            }
+
+            val ranked = athletes.sortedWith(countback.thenBy { it.order })
+            val entries = mutableListOf<ResultsEntry>()
+            ranked.forEachIndexed { index, athlete ->
+                val best = athlete.getBestMark()
+                val previous = entries.lastOrNull()
+                val place = when {
+                    best == null -> null
+                    previous != null && countback.compare(previous.athlete, athlete) == 0 -> previous.place
+                    else -> index + 1
+                }
+                entries.add(
+                    ResultsEntry(
+                        place = place,
+                        athlete = athlete,
+                        bestMark = best,
+                        bestMarkWind = athlete.getValidMeasurements().maxByOrNull { it.distance ?: 0.0 }?.windSpeed
+                    )
+                )
+            }
+
+            val qualification = Qualification.marks(entries, settings, qualificationComplete)
+            val qualifiedEntries = if (qualification.isEmpty()) entries else {
+                entries.map { it.copy(qualification = qualification[it.athlete.bib]) }
+            }
+
+            val roundsTaken = athletes.flatMap { it.attempts }.maxOfOrNull { it.round } ?: 0
+            return ResultsSheet(
+                eventId = event?.id ?: "",
+                eventName = event?.name ?: settings.eventType,
+                eventType = event?.type ?: settings.eventType,
+                rounds = maxOf(settings.numberOfRounds, roundsTaken),
+                entries = qualifiedEntries
+            )
+        }
+    }
+}
+
+/**
+ * Export formats for results files
+ */
+enum class ResultsExportFormat(val extension: String, val mimeType: String) {
+    HYTEK("txt", "text/plain"),
+    TAF3("csv", "text/csv")
+}
+
+/**
+ * Hy-Tek Meet Manager results export
+ * Semicolon-delimited text with one record per competitor, in the layout Meet Manager
+ * imports field event results from:
+ *
+ *   place;competitor number;last name;first name;team;metric mark;imperial mark;wind;attempt 1;...;attempt n
+ *
+ * Marks are metres to two decimals; attempts use FOUL and PASS, and athletes with no
+ * valid mark are reported as NM with an empty place.
+ */
+object HyTekResultsExporter {
+
+    private const val DELIMITER = ";"
+
+    fun export(sheet: ResultsSheet): String = buildString {
+        sheet.entries.forEach { entry ->
+            val (firstName, lastName) = splitName(entry.athlete.name)
+            val fields = mutableListOf(
+                entry.place?.toString() ?: "",
+                entry.athlete.bib,
+                lastName,
+                firstName,
+                entry.athlete.club,
+                entry.bestMark?.let { formatMetric(it) } ?: "NM",
+                entry.bestMark?.let { formatImperial(it) } ?: "",
+                entry.bestMarkWind?.let { formatWind(it) } ?: ""
+            )
+            for (round in 1..sheet.rounds) {
+                fields.add(formatAttempt(entry.attemptFor(round)))
+            }
+            append(fields.joinToString(DELIMITER) { clean(it) })
+            append("\r\n")
+        }
+    }
+
+    fun fileName(sheet: ResultsSheet): String = "${sheet.fileBaseName}_hytek.${ResultsExportFormat.HYTEK.extension}"
+
+    private fun formatAttempt(attempt: AthleteAttempt?): String = when {
+        attempt == null -> ""
+        !attempt.isValid -> "FOUL"
+        attempt.isPass -> "PASS"
+        attempt.distance != null -> formatMetric(attempt.distance)
+        else -> ""
+    }
+
+    private fun formatMetric(metres: Double): String = String.format(Locale.US, "%.2f", metres)
+
+    private fun formatWind(windSpeed: Double): String = String.format(Locale.US, "%+.1f", windSpeed)
+
+    /**
+     * Feet-inches to the quarter inch below, e.g. 45-03.25
+     */
+    private fun formatImperial(metres: Double): String {
+        val quarterInches = floor(metres / 0.0254 * 4).toInt()
+        val feet = quarterInches / 48
+        val inches = (quarterInches % 48) / 4.0
+        return String.format(Locale.US, "%d-%05.2f", feet, inches)
+    }
+
+    /**
+     * Names are held as "First Last"; Meet Manager wants them separately
+     */
+    private fun splitName(name: String): Pair<String, String> {
+        val trimmed = name.trim()
+        val split = trimmed.lastIndexOf(' ')
+        return if (split < 0) "" to trimmed else trimmed.substring(0, split) to trimmed.substring(split + 1)
+    }
+
+    private fun clean(value: String): String = value.replace(DELIMITER, ",").replace("\r", " ").replace("\n", " ")
+}
+
+/**
+ * Seltec TAF3 results export
+ * Semicolon-separated CSV with a header row, one row per competitor, carrying the full
+ * series with the wind for every attempt:
+ *
+ *   Rank;Bib;Name;Club;Result;Wind;R1;W1;...;Rn;Wn
+ *
+ * Attempts use the European notation TAF3 displays: x for a foul, - for a pass.
+ * Athletes without a valid mark have an empty rank and NM as the result.
+ */
+object Taf3ResultsExporter {
+
+    private const val DELIMITER = ";"
+
+    fun export(sheet: ResultsSheet): String = buildString {
+        val header = mutableListOf("Rank", "Bib", "Name", "Club", "Result", "Wind")
+        for (round in 1..sheet.rounds) {
+            header.add("R$round")
+            header.add("W$round")
+        }
+        appendRow(header)
+
+        sheet.entries.forEach { entry ->
+            val fields = mutableListOf(
+                entry.place?.toString() ?: "",
+                entry.athlete.bib,
+                entry.athlete.name,
+                entry.athlete.club,
+                entry.bestMark?.let { formatMark(it) } ?: "NM",
+                entry.bestMarkWind?.let { formatWind(it) } ?: ""
+            )
+            for (round in 1..sheet.rounds) {
+                val attempt = entry.attemptFor(round)
+                fields.add(formatAttempt(attempt))
+                fields.add(attempt?.takeIf { it.isValid && !it.isPass }?.windSpeed?.let { formatWind(it) } ?: "")
+            }
+            appendRow(fields)
+        }
+    }
+
+    fun fileName(sheet: ResultsSheet): String = "${sheet.fileBaseName}_taf3.${ResultsExportFormat.TAF3.extension}"
+
+    private fun StringBuilder.appendRow(fields: List<String>) {
+        append(fields.joinToString(DELIMITER) { quote(it) })
+        append("\r\n")
+    }
+
+    private fun formatAttempt(attempt: AthleteAttempt?): String = when {
+        attempt == null -> ""
+        !attempt.isValid -> "x"
+        attempt.isPass -> "-"
+        attempt.distance != null -> formatMark(attempt.distance)
+        else -> ""
+    }
+
+    private fun formatMark(metres: Double): String = String.format(Locale.US, "%.2f", metres)
+
+    private fun formatWind(windSpeed: Double): String = String.format(Locale.US, "%+.1f", windSpeed)
+
+    /**
+     * Quote fields containing the delimiter, quotes or line breaks
+     */
+    private fun quote(value: String): String =
+        if (value.any { it == ';' || it == '"' || it == '\n' || it == '\r' }) {
+            "\"" + value.replace("\"", "\"\"") + "\""
+        } else {
+            value
+        }
+}
@@ -0,0 +1,170 @@
+package com.polyfieldandroid
+
+import java.text.SimpleDateFormat
+import java.util.Date
+import java.util.Locale
+
+/**
+ * Conditions printed under the results: circle calibration, wind and where the wind
+ * gauge stood
+ */
+data class ResultsConditions(
+    val circleType: String,
+    val circleRadius: Double,
+    val calibratedAt: String? = null,
+    val edgeDeviationMm: Double? = null,
+    val edgeInTolerance: Boolean? = null,
+    val averageWind: Double? = null,
+    val venueAltitudeM: Double? = null,
+    val atmosphericPpm: Double? = null, // Set only when distances were atmospherically corrected
+    val windGaugePlacement: WindGaugePlacement? = null
+) {
+    companion object {
+        fun from(
+            calibration: CalibrationState,
+            sheet: ResultsSheet,
+            venueAltitudeM: Double? = null,
+            atmosphericPpm: Double? = null,
+            windGaugePlacement: WindGaugePlacement? = null
+        ): ResultsConditions {
+            val winds = sheet.entries.flatMap { it.athlete.attempts }.mapNotNull { it.windSpeed }
+            return ResultsConditions(
+                circleType = calibration.circleType,
+                circleRadius = calibration.targetRadius,
+                calibratedAt = calibration.centreTimestamp,
+                edgeDeviationMm = calibration.edgeResult?.deviation?.let { it * 1000 },
+                edgeInTolerance = calibration.edgeResult?.toleranceCheck,
+                averageWind = if (winds.isEmpty()) null else winds.average(),
+                venueAltitudeM = venueAltitudeM,
+                atmosphericPpm = atmosphericPpm,
+                windGaugePlacement = windGaugePlacement
+            )
+        }
+    }
+}
+
+/**
+ * Static results page
+ * A single self-contained HTML file (inline styles, no scripts or external assets)
+ * with the attempt matrix, placings and conditions, ready to publish or share as-is
+ */
+object ResultsHtmlGenerator {
+
+    fun generate(sheet: ResultsSheet, conditions: ResultsConditions?): String {
+        val generated = SimpleDateFormat("d MMM yyyy HH:mm", Locale.UK).format(Date(sheet.generatedAt))
+        val hasWind = sheet.entries.any { entry -> entry.athlete.attempts.any { it.windSpeed != null } }
+
+        return buildString {
+            appendLine("<!DOCTYPE html>")
+            appendLine("<html lang=\"en\">")
+            appendLine("<head>")
+            appendLine("<meta charset=\"utf-8\">")
+            appendLine("<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">")
+            appendLine("<title>${escape(sheet.eventName)} - Results</title>")
+            appendLine("<style>")
+            appendLine(STYLES)
+            appendLine("</style>")
+            appendLine("</head>")
+            appendLine("<body>")
+            appendLine("<h1>${escape(sheet.eventName)}</h1>")
+            appendLine("<p class=\"meta\">${escape(sheet.eventType)} &middot; Results generated $generated</p>")
+
+            appendLine("<table>")
+            append("<thead><tr><th>Pl</th><th>Bib</th><th>Athlete</th><th>Club</th>")
+            for (round in 1..sheet.rounds) append("<th>R$round</th>")
+            append("<th>Best</th>")
+            if (hasWind) append("<th>Wind</th>")
+            appendLine("</tr></thead>")
+
+            appendLine("<tbody>")
+            sheet.entries.forEach { entry ->
+                append("<tr>")
+                append("<td>${entry.place ?: ""}</td>")
+                append("<td>${escape(entry.athlete.bib)}</td>")
+                append("<td class=\"name\">${escape(entry.athlete.name)}</td>")
+                append("<td>${escape(entry.athlete.club)}</td>")
+                for (round in 1..sheet.rounds) {
+                    append(attemptCell(entry, entry.attemptFor(round), hasWind))
+                }
+                append("<td class=\"best\">${entry.bestMark?.let { formatMark(it) } ?: "NM"}${entry.qualification?.let { " ${it.symbol}" } ?: ""}</td>")
+                if (hasWind) append("<td>${entry.bestMarkWind?.let { formatWind(it) } ?: ""}</td>")
+                appendLine("</tr>")
+            }
+            appendLine("</tbody>")
+            appendLine("</table>")
+
+            conditions?.let { appendConditions(it) }
+
+            appendLine("<p class=\"footer\">Measured with PolyField</p>")
+            appendLine("</body>")
+            appendLine("</html>")
+        }
+    }
+
+    fun fileName(sheet: ResultsSheet): String = "${sheet.fileBaseName}_results.html"
+
+    private fun attemptCell(entry: ResultsEntry, attempt: AthleteAttempt?, hasWind: Boolean): String {
+        val mark = when {
+            attempt == null -> ""
+            !attempt.isValid -> "X"
+            attempt.isPass -> "&ndash;"
+            attempt.distance != null -> formatMark(attempt.distance)
+            else -> ""
+        }
+        val isBest = attempt?.isValid == true && attempt.distance != null && attempt.distance == entry.bestMark
+        val wind = attempt?.takeIf { hasWind && it.isValid && !it.isPass }?.windSpeed
+            ?.let { "<br><span class=\"wind\">${formatWind(it)}</span>" } ?: ""
+        return if (isBest) "<td class=\"best\">$mark$wind</td>" else "<td>$mark$wind</td>"
+    }
+
+    private fun StringBuilder.appendConditions(conditions: ResultsConditions) {
+        appendLine("<h2>Conditions</h2>")
+        appendLine("<ul class=\"conditions\">")
+        appendLine("<li>Circle: ${escape(conditions.circleType)}, radius ${String.format(Locale.US, "%.4f", conditions.circleRadius)} m</li>")
+        conditions.calibratedAt?.let { appendLine("<li>Calibrated: ${escape(it)}</li>") }
+        conditions.edgeDeviationMm?.let { deviation ->
+            val tolerance = when (conditions.edgeInTolerance) {
+                true -> " (within tolerance)"
+                false -> " (outside tolerance)"
+                null -> ""
+            }
+            appendLine("<li>Edge check: ${String.format(Locale.US, "%+.1f", deviation)} mm$tolerance</li>")
+        }
+        conditions.averageWind?.let { appendLine("<li>Average wind: ${formatWind(it)} m/s</li>") }
+        conditions.venueAltitudeM?.let { altitude ->
+            val annotation = if (AtmosphericCorrection.isAltitudeAnnotated(altitude)) " (marks set at altitude)" else ""
+            appendLine("<li>Venue altitude: ${String.format(Locale.US, "%.0f", altitude)} m$annotation</li>")
+        }
+        conditions.atmosphericPpm?.let { appendLine("<li>Atmospheric correction: ${String.format(Locale.US, "%+.1f", it)} ppm</li>") }
+        conditions.windGaugePlacement?.let { placement ->
+            val problems = placement.problems()
+            val compliance = if (problems.isEmpty()) "" else " (outside rules: ${problems.joinToString("; ")})"
+            appendLine("<li>Wind gauge: ${escape(placement.describe() + compliance)}</li>")
+        }
+        appendLine("</ul>")
+    }
+
+    private fun formatMark(metres: Double): String = String.format(Locale.US, "%.2f", metres)
+
+    private fun formatWind(windSpeed: Double): String = String.format(Locale.US, "%+.1f", windSpeed)
+
+    private fun escape(text: String): String = text
+        .replace("&", "&amp;")
+        .replace("<", "&lt;")
+        .replace(">", "&gt;")
+        .replace("\"", "&quot;")
+
+    private const val STYLES = """
+body { font-family: -apple-system, "Segoe UI", Roboto, Arial, sans-serif; margin: 16px; color: #222; }
+h1 { margin-bottom: 4px; }
+.meta, .footer { color: #666; font-size: 0.9em; }
+table { border-collapse: collapse; width: 100%; margin-top: 12px; }
+th, td { border: 1px solid #ccc; padding: 6px 8px; text-align: center; }
+th { background: #1565c0; color: #fff; }
+td.name { text-align: left; }
+td.best { font-weight: bold; }
+tbody tr:nth-child(even) { background: #f5f5f5; }
+.wind { color: #666; font-size: 0.8em; }
+.conditions { padding-left: 20px; }
+"""
+}
@@ -0,0 +1,178 @@
+package com.polyfieldandroid
+
+import android.content.Context
+import android.util.Base64
+import android.util.Log
+import java.security.KeyFactory
+import java.security.KeyPair
+import java.security.KeyPairGenerator
+import java.security.MessageDigest
+import java.security.PublicKey
+import java.security.Signature
+import java.security.spec.ECGenParameterSpec
+import java.security.spec.PKCS8EncodedKeySpec
+import java.security.spec.X509EncodedKeySpec
+import java.util.Locale
+
+/**
+ * Fields carried by a results verification payload
+ */
+data class VerificationPayload(
+    val eventId: String,
+    val athleteBib: String,
+    val bestMark: String,    // Metres to two decimals, or NM
+    val sessionHash: String, // Identifies the exact results sheet the mark came from
+    val issuedAt: Long       // Unix seconds
+)
+
+/**
+ * Results Verification
+ * Signs a compact payload for an athlete's best mark so results posted on paper can be
+ * checked by scanning a QR code. The signing key is an ECDSA P-256 key pair held by this
+ * device; verifiers only need the published public key.
+ *
+ * Payload format (pipe-delimited, ASCII):
+ *   PF1|eventId|bib|bestMark|sessionHash|issuedAt|signature
+ * The signature covers everything before the final pipe and is URL-safe base64 without padding.
+ */
+class ResultsVerification(context: Context) {
+
+    companion object {
+        private const val TAG = "ResultsVerification"
+        private const val PREFS_NAME = "polyfield_verification_prefs"
+        private const val PREF_PRIVATE_KEY = "signing_private_key"
+        private const val PREF_PUBLIC_KEY = "signing_public_key"
+        private const val VERSION = "PF1"
+        private const val SEPARATOR = "|"
+        private const val SIGNATURE_ALGORITHM = "SHA256withECDSA"
+        private const val BASE64_FLAGS = Base64.URL_SAFE or Base64.NO_PADDING or Base64.NO_WRAP
+        private const val SESSION_HASH_CHARS = 16
+
+        /**
+         * Short hash of the full results sheet: event, placings and every attempt
+         */
+        fun sessionHash(sheet: ResultsSheet): String {
+            val canonical = buildString {
+                append(sheet.eventId).append('\n')
+                append(sheet.eventName).append('\n')
+                sheet.entries.forEach { entry ->
+                    append(entry.athlete.bib).append(',').append(entry.place ?: "")
+                    entry.athlete.attempts.sortedBy { it.round }.forEach { attempt ->
+                        append(',').append(attempt.round).append(':')
+                        append(
+                            when {
+                                !attempt.isValid -> "X"
+                                attempt.isPass -> "P"
+                                else -> attempt.distance?.let { String.format(Locale.US, "%.2f", it) } ?: ""
+                            }
+                        )
+                    }
+                    append('\n')
+                }
+            }
+            val digest = MessageDigest.getInstance("SHA-256").digest(canonical.toByteArray(Charsets.UTF_8))
+            return digest.joinToString("") { "%02x".format(it) }.take(SESSION_HASH_CHARS)
+        }
+
+        /**
+         * Check a scanned payload against a device's public key. Returns the decoded fields,
+         * or null if the payload is malformed or the signature does not match.
+         */
+        fun verify(payload: String, publicKeyBase64: String): VerificationPayload? {
+            return try {
+                val signatureStart = payload.lastIndexOf(SEPARATOR)
+                if (signatureStart < 0) return null
+                val signed = payload.substring(0, signatureStart)
+                val fields = signed.split(SEPARATOR)
+                if (fields.size != 6 || fields[0] != VERSION) return null
+
+                val publicKey = KeyFactory.getInstance("EC")
+                    .generatePublic(X509EncodedKeySpec(Base64.decode(publicKeyBase64, BASE64_FLAGS)))
+                val valid = Signature.getInstance(SIGNATURE_ALGORITHM).run {
+                    initVerify(publicKey)
+                    update(signed.toByteArray(Charsets.US_ASCII))
+                    verify(Base64.decode(payload.substring(signatureStart + 1), BASE64_FLAGS))
+                }
+                if (!valid) return null
+
+                VerificationPayload(
+                    eventId = fields[1],
+                    athleteBib = fields[2],
+                    bestMark = fields[3],
+                    sessionHash = fields[4],
+                    issuedAt = fields[5].toLong()
+                )
+            } catch (e: Exception) {
+                Log.w(TAG, "Payload verification failed: ${e.message}")
+                null
+            }
+        }
+    }
+
+    private val preferences = context.getSharedPreferences(PREFS_NAME, Context.MODE_PRIVATE)
+
+    private val keyPair: KeyPair by lazy { loadOrCreateKeyPair() }
+
+    /**
+     * Public key verifiers need, as URL-safe base64 X.509
+     */
+    fun getPublicKey(): String = Base64.encodeToString(keyPair.public.encoded, BASE64_FLAGS)
+
+    /**
+     * Signed payload for one athlete's best mark in the sheet, or null if they are not in it
+     */
+    fun createPayload(sheet: ResultsSheet, athleteBib: String): String? {
+        val entry = sheet.entries.find { it.athlete.bib == athleteBib } ?: return null
+        val fields = VerificationPayload(
+            eventId = sheet.eventId.ifBlank { sheet.eventName },
+            athleteBib = athleteBib,
+            bestMark = entry.bestMark?.let { String.format(Locale.US, "%.2f", it) } ?: "NM",
+            sessionHash = sessionHash(sheet),
+            issuedAt = SessionSources.now() / 1000
+        )
+
+        val signed = listOf(
+            VERSION,
+            clean(fields.eventId),
+            clean(fields.athleteBib),
+            fields.bestMark,
+            fields.sessionHash,
+            fields.issuedAt.toString()
+        ).joinToString(SEPARATOR)
+
+        val signature = Signature.getInstance(SIGNATURE_ALGORITHM).run {
+            initSign(keyPair.private)
+            update(signed.toByteArray(Charsets.US_ASCII))
+            sign()
+        }
+        return signed + SEPARATOR + Base64.encodeToString(signature, BASE64_FLAGS)
+    }
+
+    private fun loadOrCreateKeyPair(): KeyPair {
+        val storedPrivate = preferences.getString(PREF_PRIVATE_KEY, null)
+        val storedPublic = preferences.getString(PREF_PUBLIC_KEY, null)
+        if (storedPrivate != null && storedPublic != null) {
+            try {
+                val keyFactory = KeyFactory.getInstance("EC")
+                val publicKey: PublicKey = keyFactory.generatePublic(X509EncodedKeySpec(Base64.decode(storedPublic, BASE64_FLAGS)))
+                val privateKey = keyFactory.generatePrivate(PKCS8EncodedKeySpec(Base64.decode(storedPrivate, BASE64_FLAGS)))
+                return KeyPair(publicKey, privateKey)
+            } catch (e: Exception) {
+                Log.e(TAG, "Stored signing key unreadable, generating a new one: ${e.message}")
+            }
+        }
+
+        val generator = KeyPairGenerator.getInstance("EC")
+        generator.initialize(ECGenParameterSpec("secp256r1"))
+        val keyPair = generator.generateKeyPair()
+        preferences.edit()
+            .putString(PREF_PRIVATE_KEY, Base64.encodeToString(keyPair.private.encoded, BASE64_FLAGS))
+            .putString(PREF_PUBLIC_KEY, Base64.encodeToString(keyPair.public.encoded, BASE64_FLAGS))
+            .apply()
+        Log.d(TAG, "Generated results signing key")
+        return keyPair
+    }
+
+    // Bibs and event ids must not break the pipe-delimited layout or the ASCII signing input
+    private fun clean(value: String): String = value.replace(SEPARATOR, "/").filter { it.code in 32..126 }
+}
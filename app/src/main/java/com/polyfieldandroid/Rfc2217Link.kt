@@ -0,0 +1,337 @@
+package com.polyfieldandroid
+
+import android.util.Log
+import java.io.ByteArrayOutputStream
+import java.io.IOException
+import java.io.InputStream
+import java.io.OutputStream
+import java.net.InetSocketAddress
+import java.net.Socket
+import java.nio.ByteBuffer
+import java.util.concurrent.ConcurrentHashMap
+import java.util.concurrent.CountDownLatch
+import java.util.concurrent.TimeUnit
+
+/**
+ * RFC 2217 Link
+ * A serial port on an Ethernet serial server, reached over Telnet with the COM-PORT-OPTION
+ * extension. The port's baud rate, framing and flow control are set from here, so the
+ * server need not be preconfigured to match the instrument. Telnet commands are answered
+ * and stripped on the way in, and 0xFF data bytes are escaped on the way out, so the EDM
+ * parser and network protocols only ever see the instrument's own bytes.
+ */
+class Rfc2217Link private constructor(
+    private val socket: Socket,
+    override val address: String
+) : StreamLink() {
+
+    companion object {
+        private const val TAG = "Rfc2217Link"
+        private const val CONNECT_TIMEOUT_MS = 5000
+        private const val NEGOTIATION_TIMEOUT_MS = 3000L
+        private const val MAX_BUFFERED_BYTES = 64 * 1024
+
+        // Telnet (RFC 854)
+        private const val IAC = 255
+        private const val DONT = 254
+        private const val DO = 253
+        private const val WONT = 252
+        private const val WILL = 251
+        private const val SB = 250
+        private const val SE = 240
+        private const val OPT_BINARY = 0
+        private const val OPT_ECHO = 1
+        private const val OPT_SGA = 3
+        private const val OPT_COM_PORT = 44
+
+        // COM-PORT-OPTION client commands; the server answers each with the command + 100
+        private const val SET_BAUDRATE = 1
+        private const val SET_DATASIZE = 2
+        private const val SET_PARITY = 3
+        private const val SET_STOPSIZE = 4
+        private const val SET_CONTROL = 5
+        private const val SERVER_OFFSET = 100
+
+        // Options this end will perform, and asks the server to perform
+        private val LOCAL_OPTIONS = setOf(OPT_BINARY, OPT_SGA, OPT_COM_PORT)
+        private val REMOTE_OPTIONS = setOf(OPT_BINARY, OPT_SGA)
+
+        /**
+         * Connect to host:port and, given a serial config, set the remote port to it.
+         * Throws IOException if the server cannot be reached or refuses COM-PORT-OPTION.
+         */
+        fun open(host: String, port: Int, serialConfig: SerialPortConfig?): Rfc2217Link {
+            val socket = Socket()
+            try {
+                socket.connect(InetSocketAddress(host, port), CONNECT_TIMEOUT_MS)
+                socket.keepAlive = true
+                socket.tcpNoDelay = true
+            } catch (e: IOException) {
+                socket.close()
+                throw IOException("Could not connect to serial server $host:$port: ${e.message}", e)
+            }
+
+            val link = Rfc2217Link(socket, "$host:$port")
+            try {
+                link.negotiate(serialConfig)
+            } catch (e: IOException) {
+                link.close()
+                throw e
+            }
+            return link
+        }
+    }
+
+    private val received = ReceiveBuffer(MAX_BUFFERED_BYTES) { !closed && !endOfStream }
+    override val source: InputStream = received
+    override val sink: OutputStream = EscapingOutputStream()
+
+    private val rawOut = socket.getOutputStream()
+    private val writeLock = Any()
+
+    @Volatile private var endOfStream = false
+    @Volatile private var remoteBinary = false
+
+    // Options already offered or requested, so replies to our own requests are not answered again
+    private val sentWill = ConcurrentHashMap.newKeySet<Int>()
+    private val sentDo = ConcurrentHashMap.newKeySet<Int>()
+
+    private val comPortAnswer = CountDownLatch(1)
+    @Volatile private var comPortAccepted = false
+    private val acknowledged = ConcurrentHashMap<Int, ByteArray>()
+
+    init {
+        Thread({ readLoop() }, "rfc2217-$address").apply {
+            isDaemon = true
+            start()
+        }
+    }
+
+    override fun closeChannel() {
+        socket.close()
+    }
+
+    override fun isChannelConnected(): Boolean = socket.isConnected && !socket.isClosed && !endOfStream
+
+    private fun negotiate(serialConfig: SerialPortConfig?) {
+        offer(WILL, OPT_BINARY)
+        offer(DO, OPT_BINARY)
+        offer(WILL, OPT_SGA)
+        offer(DO, OPT_SGA)
+        offer(WILL, OPT_COM_PORT)
+
+        if (!comPortAnswer.await(NEGOTIATION_TIMEOUT_MS, TimeUnit.MILLISECONDS)) {
+            throw IOException("$address did not answer the RFC 2217 negotiation; is it set to raw TCP?")
+        }
+        if (!comPortAccepted) {
+            throw IOException("$address refused the RFC 2217 COM port option")
+        }
+
+        val config = serialConfig ?: return
+        sendComPort(SET_BAUDRATE, ByteBuffer.allocate(4).putInt(config.baudRate).array())
+        sendComPort(SET_DATASIZE, byteArrayOf(config.dataBits.toByte()))
+        sendComPort(SET_PARITY, byteArrayOf(parityCode(config.parity).toByte()))
+        sendComPort(SET_STOPSIZE, byteArrayOf((if (config.stopBits == 2) 2 else 1).toByte()))
+        sendComPort(SET_CONTROL, byteArrayOf(flowControlCode(config.flowControl).toByte()))
+
+        // Servers answer with the value actually applied; a port that cannot take the
+        // requested rate reports what it kept
+        val deadline = System.currentTimeMillis() + NEGOTIATION_TIMEOUT_MS
+        while (acknowledged[SET_BAUDRATE + SERVER_OFFSET] == null && System.currentTimeMillis() < deadline) {
+            Thread.sleep(20)
+        }
+        val baudAnswer = acknowledged[SET_BAUDRATE + SERVER_OFFSET]
+        when {
+            baudAnswer == null -> Log.w(TAG, "$address did not confirm the port settings")
+            baudAnswer.size >= 4 && ByteBuffer.wrap(baudAnswer).int != config.baudRate ->
+                throw IOException("$address kept ${ByteBuffer.wrap(baudAnswer).int} baud instead of ${config.baudRate}")
+            else -> Log.d(TAG, "$address set to ${config.baudRate} ${config.dataBits}${config.parity.first()}${config.stopBits}")
+        }
+    }
+
+    private fun parityCode(parity: String): Int = when (parity.uppercase()) {
+        "ODD" -> 2
+        "EVEN" -> 3
+        "MARK" -> 4
+        "SPACE" -> 5
+        else -> 1
+    }
+
+    private fun flowControlCode(flowControl: String): Int = when (flowControl.uppercase()) {
+        "XON_XOFF" -> 2
+        "RTS_CTS" -> 3
+        "DTR_DSR" -> 19
+        else -> 1
+    }
+
+    private fun offer(verb: Int, option: Int) {
+        if (verb == WILL) sentWill.add(option) else if (verb == DO) sentDo.add(option)
+        sendRaw(byteArrayOf(IAC.toByte(), verb.toByte(), option.toByte()))
+    }
+
+    private fun sendComPort(command: Int, value: ByteArray) {
+        val frame = ByteArrayOutputStream()
+        frame.write(byteArrayOf(IAC.toByte(), SB.toByte(), OPT_COM_PORT.toByte(), command.toByte()))
+        value.forEach { byte ->
+            frame.write(byte.toInt())
+            if (byte.toInt() and 0xff == IAC) frame.write(IAC)
+        }
+        frame.write(byteArrayOf(IAC.toByte(), SE.toByte()))
+        sendRaw(frame.toByteArray())
+    }
+
+    private fun sendRaw(bytes: ByteArray) {
+        synchronized(writeLock) {
+            rawOut.write(bytes)
+            rawOut.flush()
+        }
+    }
+
+    /**
+     * Strips Telnet commands from the incoming stream, answering option negotiation, and
+     * passes the remaining data on for reading
+     */
+    private fun readLoop() {
+        val input = socket.getInputStream()
+        val data = ByteArrayOutputStream()
+        val subnegotiation = ByteArrayOutputStream()
+        var state = ReadState.DATA
+        var verb = 0
+        var lastWasCr = false
+        val buffer = ByteArray(1024)
+
+        try {
+            while (!closed) {
+                val count = input.read(buffer)
+                if (count < 0) break
+
+                for (i in 0 until count) {
+                    val b = buffer[i].toInt() and 0xff
+                    when (state) {
+                        ReadState.DATA -> when {
+                            b == IAC -> state = ReadState.COMMAND
+                            // Outside binary mode a bare CR is sent as CR NUL
+                            b == 0 && lastWasCr && !remoteBinary -> lastWasCr = false
+                            else -> {
+                                data.write(b)
+                                lastWasCr = b == '\r'.code
+                            }
+                        }
+                        ReadState.COMMAND -> when (b) {
+                            IAC -> {
+                                data.write(IAC)
+                                lastWasCr = false
+                                state = ReadState.DATA
+                            }
+                            WILL, WONT, DO, DONT -> {
+                                verb = b
+                                state = ReadState.OPTION
+                            }
+                            SB -> {
+                                subnegotiation.reset()
+                                state = ReadState.SUBNEGOTIATION
+                            }
+                            else -> state = ReadState.DATA // NOP, GA and the like
+                        }
+                        ReadState.OPTION -> {
+                            answer(verb, b)
+                            state = ReadState.DATA
+                        }
+                        ReadState.SUBNEGOTIATION -> if (b == IAC) {
+                            state = ReadState.SUBNEGOTIATION_IAC
+                        } else {
+                            subnegotiation.write(b)
+                        }
+                        ReadState.SUBNEGOTIATION_IAC -> when (b) {
+                            SE -> {
+                                handleSubnegotiation(subnegotiation.toByteArray())
+                                state = ReadState.DATA
+                            }
+                            IAC -> {
+                                subnegotiation.write(IAC)
+                                state = ReadState.SUBNEGOTIATION
+                            }
+                            else -> state = ReadState.SUBNEGOTIATION
+                        }
+                    }
+                }
+
+                if (data.size() > 0) {
+                    received.append(data.toByteArray())
+                    data.reset()
+                }
+            }
+        } catch (e: IOException) {
+            if (!closed) Log.w(TAG, "Read from $address failed: ${e.message}")
+        } finally {
+            endOfStream = true
+            comPortAnswer.countDown()
+        }
+    }
+
+    private fun answer(verb: Int, option: Int) {
+        when (verb) {
+            DO -> {
+                if (option == OPT_COM_PORT) {
+                    comPortAccepted = true
+                    comPortAnswer.countDown()
+                }
+                if (option in LOCAL_OPTIONS) {
+                    if (sentWill.add(option)) sendRaw(byteArrayOf(IAC.toByte(), WILL.toByte(), option.toByte()))
+                } else {
+                    sendRaw(byteArrayOf(IAC.toByte(), WONT.toByte(), option.toByte()))
+                }
+            }
+            DONT -> {
+                if (option == OPT_COM_PORT) comPortAnswer.countDown()
+                if (sentWill.remove(option)) sendRaw(byteArrayOf(IAC.toByte(), WONT.toByte(), option.toByte()))
+            }
+            WILL -> {
+                if (option == OPT_BINARY) remoteBinary = true
+                if (option in REMOTE_OPTIONS) {
+                    if (sentDo.add(option)) sendRaw(byteArrayOf(IAC.toByte(), DO.toByte(), option.toByte()))
+                } else {
+                    // Notably ECHO: the instrument's commands must not come back as data
+                    sendRaw(byteArrayOf(IAC.toByte(), DONT.toByte(), option.toByte()))
+                    if (option == OPT_ECHO) Log.d(TAG, "Declined echo from $address")
+                }
+            }
+            WONT -> {
+                if (option == OPT_BINARY) remoteBinary = false
+                if (sentDo.remove(option)) sendRaw(byteArrayOf(IAC.toByte(), DONT.toByte(), option.toByte()))
+            }
+        }
+    }
+
+    private fun handleSubnegotiation(bytes: ByteArray) {
+        if (bytes.size < 2 || bytes[0].toInt() and 0xff != OPT_COM_PORT) return
+        val command = bytes[1].toInt() and 0xff
+        // Line and modem state notifications are not needed for the instrument exchange
+        if (command in SET_BAUDRATE + SERVER_OFFSET..SET_CONTROL + SERVER_OFFSET) {
+            acknowledged[command] = bytes.copyOfRange(2, bytes.size)
+        }
+    }
+
+    private enum class ReadState { DATA, COMMAND, OPTION, SUBNEGOTIATION, SUBNEGOTIATION_IAC }
+
+    /**
+     * Doubles 0xFF data bytes so the server does not take them for Telnet commands
+     */
+    private inner class EscapingOutputStream : OutputStream() {
+        override fun write(b: Int) = write(byteArrayOf(b.toByte()), 0, 1)
+
+        override fun write(b: ByteArray, off: Int, len: Int) {
+            val escaped = ByteArrayOutputStream(len + 8)
+            for (i in off until off + len) {
+                val value = b[i].toInt() and 0xff
+                escaped.write(value)
+                if (value == IAC) escaped.write(IAC)
+            }
+            sendRaw(escaped.toByteArray())
+        }
+
+        override fun flush() {
+            synchronized(writeLock) { rawOut.flush() }
+        }
+    }
+}
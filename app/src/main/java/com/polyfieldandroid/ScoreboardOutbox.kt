@@ -0,0 +1,161 @@
+package com.polyfieldandroid
+
+import android.content.Context
+import android.util.Log
+import com.google.gson.Gson
+import com.google.gson.reflect.TypeToken
+import java.io.File
+
+/**
+ * Depth and age of a persisted outbox, for operator status display
+ */
+data class OutboxStatus(
+    val depth: Int,
+    val oldestQueuedAt: Long? = null
+) {
+    val oldestAgeMs: Long?
+        get() = oldestQueuedAt?.let { SessionSources.now() - it }
+}
+
+/**
+ * Scoreboard Outbox
+ * Persists scoreboard commands that could not be delivered so they survive
+ * app restarts and are replayed once the scoreboard is reachable again
+ */
+class ScoreboardOutbox(private val context: Context) {
+
+    companion object {
+        private const val TAG = "ScoreboardOutbox"
+        private const val OUTBOX_FILE_NAME = "polyfield_scoreboard_outbox.json"
+        private const val MAX_ENTRIES = 100
+    }
+
+    data class OutboxEntry(
+        val id: String = SessionSources.newId(),
+        val type: String,
+        val parameters: Map<String, Any> = emptyMap(),
+        val expectResponse: Boolean = false,
+        val queuedAt: Long = SessionSources.now(),
+        val target: String? = null // Routed scoreboard; null for the default board
+    ) {
+        fun toCommand(): DeviceCommand = DeviceCommand(
+            type = type,
+            // Gson reads every number back as Double; restore whole numbers to Int
+            parameters = parameters.mapValues { (_, value) ->
+                if (value is Double && value % 1.0 == 0.0) value.toInt() else value
+            },
+            expectResponse = expectResponse
+        )
+    }
+
+    private val gson = Gson()
+    private val lock = Any()
+
+    private val outboxFile: File
+        get() = File(context.filesDir, OUTBOX_FILE_NAME)
+
+    /**
+     * Queue a command for later delivery to target (null for the default board), dropping
+     * the oldest entries beyond the cap
+     */
+    fun enqueue(command: DeviceCommand, target: String? = null) {
+        synchronized(lock) {
+            val entries = readEntries().toMutableList()
+            entries.add(
+                OutboxEntry(
+                    type = command.type,
+                    parameters = command.parameters,
+                    expectResponse = command.expectResponse,
+                    target = target
+                )
+            )
+            while (entries.size > MAX_ENTRIES) {
+                entries.removeAt(0)
+            }
+            writeEntries(entries)
+            Log.d(TAG, "Queued ${command.type} for ${target ?: "default board"}. Outbox depth: ${entries.size}")
+        }
+    }
+
+    fun getEntries(): List<OutboxEntry> = synchronized(lock) { readEntries() }
+
+    fun getStatus(): OutboxStatus {
+        val entries = getEntries()
+        return OutboxStatus(
+            depth = entries.size,
+            oldestQueuedAt = entries.minOfOrNull { it.queuedAt }
+        )
+    }
+
+    fun remove(id: String) {
+        synchronized(lock) {
+            val entries = readEntries().filterNot { it.id == id }
+            writeEntries(entries)
+        }
+    }
+
+    fun clear() {
+        synchronized(lock) {
+            if (outboxFile.exists()) {
+                outboxFile.delete()
+            }
+        }
+        Log.d(TAG, "Outbox cleared")
+    }
+
+    /**
+     * Replay queued commands in order. A failure stops that board's replay so its ordering
+     * is preserved; other boards carry on.
+     * @return Number of commands delivered
+     */
+    suspend fun replay(send: suspend (target: String?, command: DeviceCommand) -> DeviceResponse): Int {
+        val entries = getEntries()
+        if (entries.isEmpty()) return 0
+
+        Log.d(TAG, "Replaying ${entries.size} queued scoreboard commands")
+        var delivered = 0
+        val stalled = mutableSetOf<String?>()
+        for (entry in entries) {
+            if (entry.target in stalled) continue
+            val response = try {
+                send(entry.target, entry.toCommand())
+            } catch (e: Exception) {
+                DeviceResponse(success = false, error = e.message)
+            }
+
+            if (!response.success) {
+                Log.w(TAG, "Replay for ${entry.target ?: "default board"} stopped at ${entry.type}: ${response.error}")
+                stalled.add(entry.target)
+                continue
+            }
+
+            remove(entry.id)
+            delivered++
+        }
+
+        Log.d(TAG, "Replayed $delivered of ${entries.size} queued commands")
+        return delivered
+    }
+
+    private fun readEntries(): List<OutboxEntry> {
+        return try {
+            if (outboxFile.exists()) {
+                val listType = object : TypeToken<List<OutboxEntry>>() {}.type
+                gson.fromJson<List<OutboxEntry>>(outboxFile.readText(), listType) ?: emptyList()
+            } else {
+                emptyList()
+            }
+        } catch (e: Exception) {
+            Log.e(TAG, "Error reading outbox: ${e.message}", e)
+            emptyList()
+        }
+    }
+
+    private fun writeEntries(entries: List<OutboxEntry>) {
+        try {
+            outboxFile.writeText(gson.toJson(entries))
+        } catch (e: Exception) {
+            Log.e(TAG, "Error writing outbox: ${e.message}", e)
+        }
+    }
+}
@@ -0,0 +1,174 @@
+package com.polyfieldandroid
+
+import java.io.File
+import java.text.SimpleDateFormat
+import java.util.Date
+import java.util.Locale
+
+enum class DiagnosticStatus { PASS, WARN, FAIL }
+
+/**
+ * Outcome of one readiness check, with what the operator should know about it
+ */
+data class DiagnosticCheck(
+    val category: String,   // "storage", "clock", "device", "calibration" or "outbox"
+    val name: String,
+    val status: DiagnosticStatus,
+    val detail: String
+) {
+    fun toMap(): Map<String, Any> = mapOf(
+        "category" to category,
+        "name" to name,
+        "status" to status.name,
+        "detail" to detail
+    )
+}
+
+/**
+ * Every check from one run. Ready unless a check failed; warnings are for the operator to
+ * look at but need not hold up the first athlete.
+ */
+data class DiagnosticsReport(
+    val checks: List<DiagnosticCheck>,
+    val ranAt: Long = SessionSources.now()
+) {
+    val status: DiagnosticStatus
+        get() = checks.maxOfOrNull { it.status } ?: DiagnosticStatus.PASS
+
+    val isReady: Boolean
+        get() = status != DiagnosticStatus.FAIL
+
+    fun toMap(): Map<String, Any> = mapOf(
+        "success" to true,
+        "ready" to isReady,
+        "status" to status.name,
+        "ranAt" to ranAt,
+        "failures" to checks.count { it.status == DiagnosticStatus.FAIL },
+        "warnings" to checks.count { it.status == DiagnosticStatus.WARN },
+        "checks" to checks.map { it.toMap() }
+    )
+}
+
+/**
+ * Self Diagnostics
+ * The individual checks behind EDMModule.runSelfDiagnostics, kept free of device state so
+ * each can be reasoned about on its own.
+ */
+object SelfDiagnostics {
+
+    // Exports, the WAL and backups all need room; below this a long meet may run out
+    private const val MIN_FREE_BYTES = 50L * 1024 * 1024
+
+    // No clock reading earlier than this can be right: the app did not exist
+    private const val EARLIEST_PLAUSIBLE_TIME = 1704067200000L // 2024-01-01 UTC
+
+    // Clock drift tolerated between the tablet and clock-stamping devices, and backwards
+    // movement tolerated since the last run (manual adjustments, network time corrections)
+    private const val MAX_DEVICE_OFFSET_MS = 2000L
+    private const val MAX_BACKWARDS_MS = 60_000L
+
+    // A queued scoreboard message older than this has probably missed its moment
+    private const val STALE_OUTBOX_MS = 10 * 60 * 1000L
+
+    fun storage(dir: File): List<DiagnosticCheck> {
+        val writable = try {
+            val probe = File(dir, ".diagnostics_probe")
+            probe.writeText(SessionSources.now().toString())
+            val readBack = probe.readText().isNotEmpty()
+            probe.delete() && readBack
+        } catch (e: Exception) {
+            false
+        }
+        val freeBytes = dir.usableSpace
+        return listOf(
+            DiagnosticCheck(
+                "storage", "Writable",
+                if (writable) DiagnosticStatus.PASS else DiagnosticStatus.FAIL,
+                if (writable) "App storage accepts writes" else "Cannot write to ${dir.absolutePath}; results would not be saved"
+            ),
+            DiagnosticCheck(
+                "storage", "Free space",
+                if (freeBytes >= MIN_FREE_BYTES) DiagnosticStatus.PASS else DiagnosticStatus.WARN,
+                "${freeBytes / (1024 * 1024)} MB free"
+            )
+        )
+    }
+
+    /**
+     * now against a plausible floor and against the last run, and each device clock offset
+     */
+    fun clock(now: Long, lastRunAt: Long?, offsets: List<ClockOffset>): List<DiagnosticCheck> {
+        val checks = mutableListOf<DiagnosticCheck>()
+        checks += when {
+            now < EARLIEST_PLAUSIBLE_TIME -> DiagnosticCheck(
+                "clock", "Tablet clock", DiagnosticStatus.FAIL,
+                "Tablet clock reads ${formatTime(now)}; set the date and time before recording results"
+            )
+            lastRunAt != null && now < lastRunAt - MAX_BACKWARDS_MS -> DiagnosticCheck(
+                "clock", "Tablet clock", DiagnosticStatus.WARN,
+                "Tablet clock is behind the last check (${formatTime(lastRunAt)}); it may have been reset"
+            )
+            else -> DiagnosticCheck("clock", "Tablet clock", DiagnosticStatus.PASS, formatTime(now))
+        }
+        offsets.forEach { offset ->
+            val drifted = kotlin.math.abs(offset.offsetMs) > MAX_DEVICE_OFFSET_MS
+            checks += DiagnosticCheck(
+                "clock", "${offset.deviceId} clock",
+                if (drifted) DiagnosticStatus.WARN else DiagnosticStatus.PASS,
+                "${offset.offsetMs} ms from the tablet over ${offset.samples} samples"
+            )
+        }
+        return checks
+    }
+
+    /**
+     * A device that is connected should answer; an EDM that is failing is not ready
+     */
+    fun device(deviceType: String, alive: Boolean, health: DeviceHealth): DiagnosticCheck {
+        val (status, detail) = when {
+            !alive -> DiagnosticStatus.FAIL to "Link is down"
+            health.status == DeviceHealthStatus.FAILING -> DiagnosticStatus.FAIL to
+                "${health.consecutiveErrors} exchanges failed in a row: ${health.lastError ?: "no detail"}"
+            health.status == DeviceHealthStatus.DEGRADED -> DiagnosticStatus.WARN to
+                "Recent exchange failed: ${health.lastError ?: "no detail"}"
+            else -> DiagnosticStatus.PASS to (health.lastLatencyMs?.let { "Answering in $it ms" } ?: "Connected")
+        }
+        return DiagnosticCheck("device", deviceType, status, detail)
+    }
+
+    /**
+     * Centre set and edge verified in tolerance, and set today; a calibration from an
+     * earlier day is for a setup that may have been moved
+     */
+    fun calibration(deviceType: String, state: CalibrationState, now: Long): DiagnosticCheck {
+        val today = SimpleDateFormat("yyyy-MM-dd", Locale.US).format(Date(now))
+        val (status, detail) = when {
+            !state.centreSet -> DiagnosticStatus.FAIL to "Centre not set"
+            state.edgeResult == null -> DiagnosticStatus.FAIL to "Edge not verified"
+            !state.edgeResult.toleranceCheck -> DiagnosticStatus.FAIL to
+                "Edge out of tolerance by ${String.format(Locale.US, "%.1f", state.edgeResult.deviation * 1000)} mm"
+            state.centreTimestamp?.startsWith(today) != true -> DiagnosticStatus.WARN to
+                "Calibrated ${state.centreTimestamp ?: "at an unknown time"}, not today"
+            else -> DiagnosticStatus.PASS to "${state.circleType} calibrated ${state.centreTimestamp}"
+        }
+        return DiagnosticCheck("calibration", deviceType, status, detail)
+    }
+
+    fun outbox(status: OutboxStatus): DiagnosticCheck {
+        val age = status.oldestAgeMs
+        return when {
+            status.depth == 0 -> DiagnosticCheck("outbox", "Scoreboard outbox", DiagnosticStatus.PASS, "Empty")
+            age != null && age > STALE_OUTBOX_MS -> DiagnosticCheck(
+                "outbox", "Scoreboard outbox", DiagnosticStatus.WARN,
+                "${status.depth} messages waiting, oldest ${age / 60000} min; check the board or clear the outbox"
+            )
+            else -> DiagnosticCheck(
+                "outbox", "Scoreboard outbox", DiagnosticStatus.WARN,
+                "${status.depth} messages waiting for the board"
+            )
+        }
+    }
+
+    private fun formatTime(time: Long): String =
+        SimpleDateFormat("yyyy-MM-dd HH:mm:ss", Locale.US).format(Date(time))
+}
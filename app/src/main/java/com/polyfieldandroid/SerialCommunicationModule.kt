@@ -4,15 +4,36 @@ import android.content.Context
 import android.hardware.usb.UsbDevice
 import android.hardware.usb.UsbManager
 import android.util.Log
+import com.hoho.android.usbserial.driver.FtdiSerialDriver
 import com.hoho.android.usbserial.driver.UsbSerialDriver
 import com.hoho.android.usbserial.driver.UsbSerialProber
 import com.hoho.android.usbserial.driver.UsbSerialPort
+import kotlinx.coroutines.CancellationException
 import kotlinx.coroutines.Dispatchers
 import kotlinx.coroutines.delay
+import kotlinx.coroutines.ensureActive
+import kotlinx.coroutines.isActive
 import kotlinx.coroutines.withContext
-import kotlinx.coroutines.withTimeout
+import kotlinx.coroutines.sync.Mutex
+import kotlinx.coroutines.sync.withLock
+import java.io.ByteArrayOutputStream
 import java.io.IOException
 
+/**
+ * Actionable categories for USB-serial open/read failures
+ */
+enum class SerialErrorCode(val userMessage: String) {
+    PERMISSION_DENIED("USB permission denied. Reconnect the cable and allow access when prompted"),
+    NO_DRIVER("USB device is not a supported serial adapter"),
+    PORT_BUSY("Serial port is in use by another app or connection"),
+    DEVICE_UNPLUGGED("USB device was unplugged. Check the cable and reconnect"),
+    FRAMING_ERROR("Garbled data received. Check baud rate, parity and stop bits"),
+    TIMEOUT("No response from device"),
+    WRITE_FAILED("Failed to send command to device"),
+    FLOW_CONTROL_UNSUPPORTED("Serial adapter does not support the selected flow control"),
+    IO_ERROR("Serial communication error")
+}
+
 /**
  * Serial Communication Module for EDM devices via USB-to-serial adapters
  * Handles CH340, FTDI, and other USB-to-serial chips
@@ -24,6 +45,18 @@ class SerialCommunicationModule(private val context: Context) {
         private const val READ_TIMEOUT_MS = 10000 // 10 seconds per read
         private const val WRITE_TIMEOUT_MS = 5000  // 5 seconds for write
         private const val CONNECTION_TIMEOUT_MS = 5000 // 5 seconds for connection
+        
+        // Input is flushed before a trigger until quiet this long, giving up after FLUSH_MAX_MS
+        private const val FLUSH_QUIET_MS = 30L
+        private const val FLUSH_MAX_MS = 500L
+    }
+    
+    // One exchange at a time per port or link, so a new read never starts while an aborted
+    // one is still returning from the channel
+    private val exchangeLocks = java.util.WeakHashMap<Any, Mutex>()
+    
+    private fun exchangeLock(channel: Any): Mutex = synchronized(exchangeLocks) {
+        exchangeLocks.getOrPut(channel) { Mutex() }
     }
     
     // DEBUG: Serial Communication Logging (REMOVE WHEN DEBUG COMPLETE)
@@ -32,9 +65,21 @@ class SerialCommunicationModule(private val context: Context) {
     data class SerialResponse(
         val success: Boolean,
         val data: String? = null,
-        val error: String? = null
+        val error: String? = null,
+        val errorCode: SerialErrorCode? = null,
+        val sentAt: Long? = null,     // Wall clock when the command went out
+        val latencyMs: Long? = null   // Command sent to complete response
     )
     
+    data class SerialOpenResult(
+        val port: UsbSerialPort? = null,
+        val errorCode: SerialErrorCode? = null,
+        val error: String? = null
+    ) {
+        val success: Boolean
+            get() = port != null
+    }
+    
     /**
      * Open serial connection to USB device
      */
@@ -45,6 +90,20 @@ class SerialCommunicationModule(private val context: Context) {
         stopBits: Int = UsbSerialPort.STOPBITS_1,
         parity: Int = UsbSerialPort.PARITY_NONE
     ): UsbSerialPort? {
+        return openSerialPort(usbDevice, baudRate, dataBits, stopBits, parity).port
+    }
+    
+    /**
+     * Open serial connection to USB device, classifying any failure
+     */
+    suspend fun openSerialPort(
+        usbDevice: UsbDevice,
+        baudRate: Int = 9600,
+        dataBits: Int = 8,
+        stopBits: Int = UsbSerialPort.STOPBITS_1,
+        parity: Int = UsbSerialPort.PARITY_NONE,
+        flowControl: UsbSerialPort.FlowControl = UsbSerialPort.FlowControl.NONE
+    ): SerialOpenResult {
         return withContext(Dispatchers.IO) {
             try {
                 Log.d(TAG, "Opening serial connection to device: ${usbDevice.deviceName}")
@@ -52,57 +111,156 @@ class SerialCommunicationModule(private val context: Context) {
                 
                 val usbManager = context.getSystemService(Context.USB_SERVICE) as UsbManager
                 
+                if (!isAttached(usbDevice)) {
+                    Log.e(TAG, "USB device no longer attached")
+                    return@withContext openFailure(SerialErrorCode.DEVICE_UNPLUGGED)
+                }
+                
                 // Check if we have permission
                 if (!usbManager.hasPermission(usbDevice)) {
                     Log.e(TAG, "No permission to access USB device")
-                    return@withContext null
+                    return@withContext openFailure(SerialErrorCode.PERMISSION_DENIED)
                 }
                 
                 // Find compatible driver
                 val driver = UsbSerialProber.getDefaultProber().probeDevice(usbDevice)
                 if (driver == null) {
                     Log.e(TAG, "No compatible USB serial driver found for device")
-                    return@withContext null
+                    return@withContext openFailure(SerialErrorCode.NO_DRIVER)
                 }
                 
                 Log.d(TAG, "Found compatible driver: ${driver.javaClass.simpleName}")
                 
                 if (driver.ports.isEmpty()) {
                     Log.e(TAG, "Driver has no ports available")
-                    return@withContext null
+                    return@withContext openFailure(SerialErrorCode.NO_DRIVER)
                 }
                 
                 val port = driver.ports[0] // Use first port
                 Log.d(TAG, "Using port: ${port.portNumber}")
                 
-                // Open connection
+                // Open connection - null here means another connection holds the device
                 val connection = usbManager.openDevice(usbDevice)
                 if (connection == null) {
                     Log.e(TAG, "Failed to open USB device connection")
-                    return@withContext null
+                    return@withContext openFailure(
+                        if (isAttached(usbDevice)) SerialErrorCode.PORT_BUSY else SerialErrorCode.DEVICE_UNPLUGGED
+                    )
                 }
                 
-                port.open(connection)
+                try {
+                    port.open(connection)
+                } catch (e: IOException) {
+                    connection.close()
+                    throw e
+                }
                 
                 // Configure serial parameters
                 port.setParameters(baudRate, dataBits, stopBits, parity)
                 
-                // Set DTR/RTS for proper communication
-                port.dtr = true
-                port.rts = true
+                if (flowControl != UsbSerialPort.FlowControl.NONE) {
+                    if (flowControl !in port.supportedFlowControl) {
+                        Log.e(TAG, "${driver.javaClass.simpleName} does not support $flowControl flow control")
+                        closeSerialPort(port)
+                        return@withContext openFailure(SerialErrorCode.FLOW_CONTROL_UNSUPPORTED)
+                    }
+                    port.flowControl = flowControl
+                }
+                
+                applyChipsetQuirks(port, driver, flowControl)
                 
                 Log.d(TAG, "Serial connection established successfully")
-                Log.d(TAG, "Parameters - Baud: $baudRate, Data: $dataBits, Stop: $stopBits, Parity: $parity")
+                Log.d(TAG, "Parameters - Baud: $baudRate, Data: $dataBits, Stop: $stopBits, Parity: $parity, Flow: $flowControl")
                 
-                return@withContext port
+                return@withContext SerialOpenResult(port = port)
                 
             } catch (e: Exception) {
                 Log.e(TAG, "Failed to open serial connection", e)
-                return@withContext null
+                val code = classifyException(e, usbDevice)
+                return@withContext SerialOpenResult(errorCode = code, error = "${code.userMessage} (${e.message})")
             }
         }
     }
     
+    /**
+     * Apply latency timer, modem line and settle handling for the bridge chipset
+     */
+    private suspend fun applyChipsetQuirks(
+        port: UsbSerialPort,
+        driver: UsbSerialDriver,
+        flowControl: UsbSerialPort.FlowControl = UsbSerialPort.FlowControl.NONE
+    ) {
+        val chipset = UsbSerialQuirks.detect(driver)
+        val quirks = UsbSerialQuirks.quirksFor(chipset)
+        Log.d(TAG, "Chipset $chipset quirks: $quirks")
+        
+        quirks.latencyTimerMs?.let { latency ->
+            try {
+                (port as? FtdiSerialDriver.FtdiSerialPort)?.setLatencyTimer(latency)
+            } catch (e: IOException) {
+                Log.w(TAG, "Could not set FTDI latency timer: ${e.message}")
+            }
+        }
+        
+        if (quirks.dtrWakePulseMs > 0) {
+            port.dtr = false
+            delay(quirks.dtrWakePulseMs)
+        }
+        
+        // Set DTR/RTS for proper communication, leaving any line hardware flow control drives
+        if (flowControl != UsbSerialPort.FlowControl.DTR_DSR) port.dtr = quirks.assertDtr
+        if (flowControl != UsbSerialPort.FlowControl.RTS_CTS) port.rts = quirks.assertRts
+        
+        if (quirks.settleDelayMs > 0) {
+            delay(quirks.settleDelayMs)
+        }
+    }
+    
+    /**
+     * Line terminator for text commands on this port's chipset
+     */
+    fun lineEndingFor(port: UsbSerialPort): String {
+        return UsbSerialQuirks.quirksFor(port.driver).lineEnding
+    }
+    
+    private fun openFailure(code: SerialErrorCode): SerialOpenResult {
+        return SerialOpenResult(errorCode = code, error = code.userMessage)
+    }
+    
+    private fun isAttached(usbDevice: UsbDevice): Boolean {
+        val usbManager = context.getSystemService(Context.USB_SERVICE) as UsbManager
+        return usbManager.deviceList.values.any { it.deviceName == usbDevice.deviceName }
+    }
+    
+    /**
+     * Map a USB-serial exception to an error category.
+     * usb-serial-for-android reports most failures as IOException, so the
+     * message text and attachment state are the only signals available.
+     */
+    fun classifyException(e: Exception, usbDevice: UsbDevice? = null): SerialErrorCode {
+        val message = e.message?.lowercase() ?: ""
+        return when {
+            e is SecurityException || "permission" in message -> SerialErrorCode.PERMISSION_DENIED
+            usbDevice != null && !isAttached(usbDevice) -> SerialErrorCode.DEVICE_UNPLUGGED
+            "claim" in message || "busy" in message || "already open" in message -> SerialErrorCode.PORT_BUSY
+            "get_status" in message || "connection closed" in message || "not open" in message ||
+                "no such device" in message -> SerialErrorCode.DEVICE_UNPLUGGED
+            "framing" in message || "parity" in message || "overrun" in message -> SerialErrorCode.FRAMING_ERROR
+            e is kotlinx.coroutines.TimeoutCancellationException || e is java.net.SocketTimeoutException ||
+                "timeout" in message || "timed out" in message -> SerialErrorCode.TIMEOUT
+            else -> SerialErrorCode.IO_ERROR
+        }
+    }
+    
+    /**
+     * Partial data full of non-printable bytes almost always means mismatched line settings
+     */
+    private fun looksGarbled(data: String): Boolean {
+        if (data.isEmpty()) return false
+        val unprintable = data.count { it.code < 0x20 && it != '\r' && it != '\n' && it != '\t' || it.code > 0x7E }
+        return unprintable * 4 >= data.length
+    }
+    
     /**
      * Send command to EDM device and wait for response
      */
@@ -119,37 +277,97 @@ class SerialCommunicationModule(private val context: Context) {
         port: UsbSerialPort,
         commandBytes: ByteArray,
         expectedResponseLength: Int = 0,
-        timeoutMs: Long = READ_TIMEOUT_MS.toLong()
+        timeoutMs: Long = READ_TIMEOUT_MS.toLong(),
+        writeTimeoutMs: Int = WRITE_TIMEOUT_MS,
+        tap: TrafficTap? = null,
+        framing: ResponseFraming = ResponseFraming(),
+        hasAnswer: ((String) -> Boolean)? = null
+    ): SerialResponse = exchangeLock(port).withLock {
+        exchangeEDMCommand(
+            commandBytes,
+            timeoutMs,
+            writeTimeoutMs,
+            tap,
+            framing,
+            hasAnswer,
+            purgeInput = { port.purgeHwBuffers(false, true) },
+            read = { buffer, readTimeoutMs -> port.read(buffer, readTimeoutMs) },
+            write = { bytes, timeout -> port.write(bytes, timeout) }
+        )
+    }
+    
+    /**
+     * Same exchange over a Bluetooth link (SPP or BLE)
+     */
+    suspend fun sendEDMCommandBytes(
+        link: StreamLink,
+        commandBytes: ByteArray,
+        timeoutMs: Long = READ_TIMEOUT_MS.toLong(),
+        writeTimeoutMs: Int = WRITE_TIMEOUT_MS,
+        tap: TrafficTap? = null,
+        framing: ResponseFraming = ResponseFraming(),
+        hasAnswer: ((String) -> Boolean)? = null
+    ): SerialResponse = exchangeLock(link).withLock {
+        exchangeEDMCommand(
+            commandBytes,
+            timeoutMs,
+            writeTimeoutMs,
+            tap,
+            framing,
+            hasAnswer,
+            read = { buffer, readTimeoutMs -> link.read(buffer, readTimeoutMs) },
+            write = { bytes, timeout -> link.write(bytes, timeout) }
+        )
+    }
+    
+    private suspend fun exchangeEDMCommand(
+        commandBytes: ByteArray,
+        timeoutMs: Long,
+        writeTimeoutMs: Int,
+        tap: TrafficTap?,
+        framing: ResponseFraming,
+        hasAnswer: ((String) -> Boolean)?,
+        purgeInput: (() -> Unit)? = null,
+        read: (ByteArray, Int) -> Int,
+        write: (ByteArray, Int) -> Unit
     ): SerialResponse {
         return withContext(Dispatchers.IO) {
+            var answered = false
             try {
                 Log.d(TAG, "Sending EDM command bytes: ${commandBytes.contentToString()}")
                 
-                // Clear any existing data in buffer
-                val buffer = ByteArray(1024)
+                // Clear anything left by an aborted or timed-out read, so it cannot be taken
+                // for this measurement: the adapter's receive buffer where it can be purged,
+                // then whatever is still arriving until the line goes quiet
                 try {
-                    while (port.read(buffer, 10) > 0) {
-                        // Drain buffer
-                    }
-                } catch (e: IOException) {
-                    // Ignore timeout on buffer clearing
+                    purgeInput?.invoke()
+                } catch (e: Exception) {
+                    // Not every adapter supports purging; the flush below still clears it
                 }
-                
-                // Send command
-                val bytesWritten = withTimeout(WRITE_TIMEOUT_MS.toLong()) {
-                    port.write(commandBytes, WRITE_TIMEOUT_MS)
-                    commandBytes.size // Return the expected number of bytes
+                val flushed = flushInput(read, tap)
+                if (flushed > 0) {
+                    Log.w(TAG, "Discarded $flushed stale bytes before sending the command")
                 }
                 
-                if (bytesWritten != commandBytes.size) {
-                    Log.e(TAG, "Failed to write complete command. Expected: ${commandBytes.size}, Written: $bytesWritten")
+                // Send command
+                val sentAt = SessionSources.now()
+                val sentNanos = System.nanoTime()
+                // The channel enforces the write deadline itself; a coroutine timeout
+                // could not interrupt a blocked write
+                try {
+                    write(commandBytes, writeTimeoutMs)
+                } catch (e: IOException) {
+                    Log.e(TAG, "Failed to write command: ${e.message}")
+                    val code = classifyException(e)
                     return@withContext SerialResponse(
                         success = false,
-                        error = "Failed to send complete command to EDM device"
+                        error = if (code == SerialErrorCode.IO_ERROR) "Failed to send complete command to EDM device" else code.userMessage,
+                        errorCode = if (code == SerialErrorCode.IO_ERROR) SerialErrorCode.WRITE_FAILED else code
                     )
                 }
                 
-                Log.d(TAG, "Command sent successfully ($bytesWritten bytes)")
+                Log.d(TAG, "Command sent successfully (${commandBytes.size} bytes)")
+                tap?.invoke(TrafficDirection.SENT, commandBytes, false)
                 
                 // DEBUG: Log outgoing command (REMOVE WHEN DEBUG COMPLETE)
                 debugLogger?.invoke(
@@ -162,15 +380,23 @@ class SerialCommunicationModule(private val context: Context) {
                 
                 // Wait for response
                 val response = StringBuilder()
+                val received = ByteArrayOutputStream()
                 val readBuffer = ByteArray(1024)
                 val startTime = System.currentTimeMillis()
                 
                 while (System.currentTimeMillis() - startTime < timeoutMs) {
+                    // A cancelled measurement request stops waiting rather than running out the timeout
+                    ensureActive()
                     try {
-                        val bytesRead = port.read(readBuffer, 100) // 100ms read timeout
+                        // Short reads, never past the deadline, so a timeout or cancellation
+                        // is noticed within one read
+                        val remainingMs = timeoutMs - (System.currentTimeMillis() - startTime)
+                        val bytesRead = read(readBuffer, remainingMs.coerceIn(1L, 100L).toInt())
                         if (bytesRead > 0) {
+                            tap?.invoke(TrafficDirection.RECEIVED, readBuffer.copyOf(bytesRead), false)
                             val chunk = String(readBuffer, 0, bytesRead)
                             response.append(chunk)
+                            received.write(readBuffer, 0, bytesRead)
                             Log.d(TAG, "📥 RAW CHUNK: '$chunk' (${bytesRead} bytes) [HEX: ${readBuffer.take(bytesRead).joinToString(" ") { "%02x".format(it) }}]")
                             
                             // DEBUG: Log incoming chunk (REMOVE WHEN DEBUG COMPLETE)
@@ -186,18 +412,36 @@ class SerialCommunicationModule(private val context: Context) {
                             val responseStr = response.toString()
                             Log.d(TAG, "📥 FULL RESPONSE SO FAR: '$responseStr' (${responseStr.length} chars)")
                             
-                            if (isCompleteEDMResponse(responseStr)) {
+                            val complete = if (framing.type == FramingType.LINE) {
+                                // A line of its own may be only a status line, with the measurement to follow
+                                responseStr.takeIf { isCompleteEDMResponse(it) && hasAnswer?.invoke(it) != false }?.trim()
+                            } else {
+                                framing.extract(received.toByteArray())?.toString(Charsets.ISO_8859_1)
+                            }
+                            if (complete != null) {
                                 Log.d(TAG, "✅ Complete EDM response received: '$responseStr'")
+                                answered = true
                                 return@withContext SerialResponse(
                                     success = true,
-                                    data = responseStr.trim()
+                                    data = complete,
+                                    sentAt = sentAt,
+                                    latencyMs = (System.nanoTime() - sentNanos) / 1_000_000
                                 )
                             } else {
                                 Log.d(TAG, "⏳ Response not yet complete, continuing to read...")
                             }
                         }
                     } catch (e: IOException) {
-                        // Timeout on individual read - continue trying
+                        // Timeout on individual read - continue trying, unless the device has gone
+                        val code = classifyException(e)
+                        if (code == SerialErrorCode.DEVICE_UNPLUGGED || code == SerialErrorCode.PERMISSION_DENIED) {
+                            Log.e(TAG, "Serial read aborted: ${e.message}")
+                            return@withContext SerialResponse(
+                                success = false,
+                                error = code.userMessage,
+                                errorCode = code
+                            )
+                        }
                         delay(10)
                     }
                 }
@@ -206,9 +450,25 @@ class SerialCommunicationModule(private val context: Context) {
                 val responseStr = response.toString()
                 if (responseStr.isNotEmpty()) {
                     Log.w(TAG, "Partial response received before timeout: '$responseStr'")
+                    if (framing.type != FramingType.LINE) {
+                        val hex = received.toByteArray().joinToString(" ") { "%02x".format(it) }
+                        return@withContext SerialResponse(
+                            success = false,
+                            error = "Incomplete ${framing.type} record from EDM device: $hex",
+                            errorCode = SerialErrorCode.TIMEOUT
+                        )
+                    }
+                    if (looksGarbled(responseStr)) {
+                        return@withContext SerialResponse(
+                            success = false,
+                            error = SerialErrorCode.FRAMING_ERROR.userMessage,
+                            errorCode = SerialErrorCode.FRAMING_ERROR
+                        )
+                    }
                     return@withContext SerialResponse(
                         success = false,
-                        error = "Incomplete response from EDM device: '$responseStr'"
+                        error = "Incomplete response from EDM device: '$responseStr'",
+                        errorCode = SerialErrorCode.TIMEOUT
                     )
                 } else {
                     Log.e(TAG, "No response received from EDM device within timeout")
@@ -224,16 +484,85 @@ class SerialCommunicationModule(private val context: Context) {
                     
                     return@withContext SerialResponse(
                         success = false,
-                        error = "No response from EDM. Press F1 on EDM to reset if \"STOP\" is displayed"
+                        error = "No response from EDM. Press F1 on EDM to reset if \"STOP\" is displayed",
+                        errorCode = SerialErrorCode.TIMEOUT
                     )
                 }
                 
             } catch (e: Exception) {
+                // Cancellation propagates; a write timeout is reported like any other timeout
+                if (e is CancellationException && !isActive) throw e
                 Log.e(TAG, "EDM command failed", e)
+                val code = classifyException(e)
                 return@withContext SerialResponse(
                     success = false,
-                    error = "Communication error: ${e.message}"
+                    error = if (code == SerialErrorCode.IO_ERROR) "Communication error: ${e.message}" else code.userMessage,
+                    errorCode = code
                 )
+            } finally {
+                // After a timeout or cancellation drop whatever the adapter still holds, so a
+                // late reply waits for no one; the flush before the next command clears the rest
+                if (!answered) {
+                    try {
+                        purgeInput?.invoke()
+                    } catch (e: Exception) {
+                        // Purge unsupported or port gone
+                    }
+                }
+            }
+        }
+    }
+    
+    /**
+     * Read and discard input until none has arrived for FLUSH_QUIET_MS. A reply to an
+     * earlier command may still be on its way, so an empty buffer alone is not enough.
+     * Returns the number of bytes discarded.
+     */
+    private fun flushInput(read: (ByteArray, Int) -> Int, tap: TrafficTap?): Int {
+        val buffer = ByteArray(1024)
+        var discarded = 0
+        val startedAt = System.currentTimeMillis()
+        var lastByteAt = startedAt
+        while (true) {
+            val now = System.currentTimeMillis()
+            if (now - lastByteAt >= FLUSH_QUIET_MS) break
+            if (now - startedAt >= FLUSH_MAX_MS) {
+                Log.w(TAG, "Input still active after ${FLUSH_MAX_MS}ms of flushing; sending anyway")
+                break
+            }
+            val count = try {
+                read(buffer, 10)
+            } catch (e: IOException) {
+                0 // Read timeout
+            }
+            if (count > 0) {
+                tap?.invoke(TrafficDirection.RECEIVED, buffer.copyOf(count), true)
+                discarded += count
+                lastByteAt = System.currentTimeMillis()
+            }
+        }
+        return discarded
+    }
+    
+    /**
+     * Write a command that has no reply, such as a standby or wake command
+     */
+    suspend fun writeCommandBytes(
+        port: UsbSerialPort,
+        commandBytes: ByteArray,
+        writeTimeoutMs: Int = WRITE_TIMEOUT_MS,
+        tap: TrafficTap? = null
+    ): SerialResponse {
+        return withContext(Dispatchers.IO) {
+            try {
+                port.write(commandBytes, writeTimeoutMs)
+                tap?.invoke(TrafficDirection.SENT, commandBytes, false)
+                Log.d(TAG, "Wrote ${commandBytes.size} bytes without awaiting a reply")
+                SerialResponse(success = true)
+            } catch (e: Exception) {
+                Log.e(TAG, "Command write failed", e)
+                val code = classifyException(e)
+                SerialResponse(success = false, error = code.userMessage, errorCode = code)
             }
         }
     }
@@ -310,6 +639,61 @@ class SerialCommunicationModule(private val context: Context) {
         }
     }
     
+    /**
+     * Briefly open a port and capture whatever it sends, for device identification.
+     * Listens passively first (wind gauges stream continuously), then nudges with a
+     * bare CR/LF. No measurement command is sent so an EDM is never triggered.
+     * @return Captured text, or null if the port could not be opened
+     */
+    suspend fun probePort(
+        usbDevice: UsbDevice,
+        config: SerialPortConfig = SerialPortConfig(),
+        listenMs: Long = 1500
+    ): String? {
+        return withContext(Dispatchers.IO) {
+            val port = openSerialConnection(
+                usbDevice,
+                config.baudRate,
+                config.dataBits,
+                config.usbStopBits(),
+                config.usbParity()
+            ) ?: return@withContext null
+
+            try {
+                val captured = StringBuilder()
+                val buffer = ByteArray(1024)
+
+                fun listen(durationMs: Long) {
+                    val startTime = System.currentTimeMillis()
+                    while (System.currentTimeMillis() - startTime < durationMs) {
+                        try {
+                            val bytesRead = port.read(buffer, 100)
+                            if (bytesRead > 0) {
+                                captured.append(String(buffer, 0, bytesRead, Charsets.US_ASCII))
+                            }
+                        } catch (e: IOException) {
+                            // Individual read timeout - keep listening
+                        }
+                    }
+                }
+
+                listen(listenMs)
+                if (captured.isEmpty()) {
+                    port.write(lineEndingFor(port).toByteArray(Charsets.US_ASCII), WRITE_TIMEOUT_MS)
+                    listen(listenMs / 2)
+                }
+
+                Log.d(TAG, "Probe of ${usbDevice.deviceName} captured ${captured.length} chars")
+                captured.toString()
+            } catch (e: Exception) {
+                Log.w(TAG, "Probe of ${usbDevice.deviceName} failed: ${e.message}")
+                ""
+            } finally {
+                closeSerialPort(port)
+            }
+        }
+    }
+    
     /**
      * Close serial port
      */
@@ -329,7 +713,7 @@ class SerialCommunicationModule(private val context: Context) {
         return withContext(Dispatchers.IO) {
             try {
                 // Send a simple test command (varies by device)
-                val response = sendEDMCommand(port, "\r\n", timeoutMs = 2000)
+                val response = sendEDMCommand(port, lineEndingFor(port), timeoutMs = 2000)
                 response.success
             } catch (e: Exception) {
                 Log.e(TAG, "Serial connection test failed", e)
@@ -0,0 +1,134 @@
+package com.polyfieldandroid
+
+/**
+ * Session Snapshot
+ * The athlete, competition and sign-off state captured together at one instant, for
+ * statistics and exports. Taking one only copies references out of the state flows, so it
+ * never waits on a measurement in progress; everything derived from it is worked out
+ * against the captured state, so a long export sees one consistent set of results
+ * however many marks are recorded meanwhile.
+ */
+data class SessionSnapshot(
+    val takenAt: Long,
+    val athleteState: AthleteManagementState,
+    val competitionState: CompetitionState,
+    val signOffs: List<OfficialSignOff>,
+    val calibration: CalibrationState,
+    val outputPrecision: OutputPrecision,
+    val venueAltitudeM: Double,
+    val atmosphericPpm: Double?,  // Null when distances were not corrected
+    val windGaugePlacement: WindGaugePlacement? = null
+) {
+    // An earlier snapshot of the same revision whose derived results can be reused
+    @Volatile internal var derivedFrom: SessionSnapshot? = null
+
+    val eventName: String
+        get() = competitionState.selectedEvent?.name ?: "Competition"
+
+    /**
+     * Ranked results from checked-in athletes (or all selected athletes when nobody has
+     * been checked in)
+     */
+    val resultsSheet: ResultsSheet by lazy {
+        derivedFrom?.let { return@lazy it.resultsSheet }
+        val checkedIn = athleteState.athletes.filter { it.bib in athleteState.checkedInAthletes }
+        ResultsSheet.build(
+            event = competitionState.selectedEvent,
+            settings = competitionState.settings,
+            athletes = checkedIn.ifEmpty { athleteState.selectedAthletes },
+            qualificationComplete = competitionState.qualificationComplete || competitionState.competitionComplete
+        )
+    }
+
+    val leaderboard: Leaderboard by lazy {
+        derivedFrom?.let { return@lazy it.leaderboard }
+        Leaderboard.build(
+            resultsSheet,
+            competitionState.currentRound,
+            competitionState.settings,
+            competitionState.progressingAthletes
+        )
+    }
+
+    val resultsConditions: ResultsConditions by lazy {
+        derivedFrom?.let { return@lazy it.resultsConditions }
+        ResultsConditions.from(calibration, resultsSheet, venueAltitudeM, atmosphericPpm, windGaugePlacement)
+    }
+
+    /**
+     * Checked-in athletes with a valid mark, best first
+     */
+    val athleteRankings: List<AthleteRanking> by lazy {
+        derivedFrom?.let { return@lazy it.athleteRankings }
+        athleteState.selectedAthletes
+            .filter { it.bib in athleteState.checkedInAthletes && it.getBestMark() != null }
+            .sortedByDescending { it.getBestMark() ?: 0.0 }
+            .mapIndexed { index, athlete ->
+                AthleteRanking(
+                    position = index + 1,
+                    athlete = athlete,
+                    bestMark = athlete.getBestMark(),
+                    isAdvancing = true, // Will be set properly during cut calculation
+                    recentChange = 0
+                )
+            }
+    }
+
+    fun isResultsLocked(): Boolean =
+        signOffs.isNotEmpty() && ResultsSignOff.isLocked(signOffs, ResultsVerification.sessionHash(resultsSheet))
+}
+
+/**
+ * Identifies one revision of the session. The state objects are immutable and replaced on
+ * every attempt, void or correction, so comparing them by identity tells revisions apart
+ * without walking the attempt lists.
+ */
+internal class SessionRevision(snapshot: SessionSnapshot) {
+    private val inputs: List<Any?> = listOf(
+        snapshot.athleteState,
+        snapshot.competitionState,
+        snapshot.signOffs,
+        snapshot.calibration,
+        snapshot.outputPrecision,
+        snapshot.windGaugePlacement
+    )
+    private val values: List<Any?> = listOf(snapshot.venueAltitudeM, snapshot.atmosphericPpm)
+
+    override fun equals(other: Any?): Boolean =
+        other is SessionRevision && values == other.values && inputs.indices.all { inputs[it] === other.inputs[it] }
+
+    override fun hashCode(): Int =
+        inputs.fold(values.hashCode()) { hash, input -> 31 * hash + System.identityHashCode(input) }
+}
+
+/**
+ * Session Snapshot Cache
+ * The most recent snapshots by revision, so repeated polls for the leaderboard or
+ * statistics reuse results already worked out instead of rebuilding them over the whole
+ * attempt list. A new attempt, void or correction is a new revision and is built afresh.
+ */
+class SessionSnapshotCache(private val capacity: Int = 8) {
+
+    private val entries = object : LinkedHashMap<SessionRevision, SessionSnapshot>(capacity, 0.75f, true) {
+        override fun removeEldestEntry(eldest: MutableMap.MutableEntry<SessionRevision, SessionSnapshot>): Boolean =
+            size > capacity
+    }
+
+    /**
+     * The snapshot, sharing derived results with an earlier one of the same revision
+     */
+    @Synchronized
+    fun share(snapshot: SessionSnapshot): SessionSnapshot {
+        val revision = SessionRevision(snapshot)
+        val cached = entries[revision]
+        if (cached == null) {
+            entries[revision] = snapshot
+        } else {
+            snapshot.derivedFrom = cached
+        }
+        return snapshot
+    }
+
+    @Synchronized
+    fun clear() = entries.clear()
+}
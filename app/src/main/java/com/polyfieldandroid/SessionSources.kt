@@ -0,0 +1,86 @@
+package com.polyfieldandroid
+
+import android.util.Log
+import java.util.UUID
+import java.util.concurrent.atomic.AtomicLong
+import kotlin.random.Random
+
+/**
+ * Wall-clock time as recorded against results, logs and sync state
+ */
+interface Clock {
+    fun now(): Long
+}
+
+object SystemWallClock : Clock {
+    override fun now(): Long = System.currentTimeMillis()
+}
+
+/**
+ * Clock that only moves when advanced, or by stepMs on every read so successive
+ * timestamps stay distinct and ordered
+ */
+class ManualClock(startMillis: Long, private val stepMs: Long = 0) : Clock {
+    private val time = AtomicLong(startMillis)
+
+    override fun now(): Long = time.getAndAdd(stepMs)
+
+    fun advance(ms: Long) {
+        time.addAndGet(ms)
+    }
+
+    fun set(millis: Long) {
+        time.set(millis)
+    }
+}
+
+/**
+ * Session Sources
+ * The clock and random generator behind recorded timestamps, demo generators and sync
+ * retry bookkeeping. Live sessions use the system clock and an unseeded generator; tests
+ * and replays install a manual clock and a seed so a run reproduces exactly. Pacing of
+ * real device I/O (serial timeouts, latency, socket idle checks) stays on the system
+ * clock, since a stopped clock there would hang the read.
+ */
+object SessionSources {
+
+    private const val TAG = "SessionSources"
+
+    @Volatile var clock: Clock = SystemWallClock
+        private set
+
+    @Volatile var random: Random = Random.Default
+        private set
+
+    // Seed of the installed generator; null for an unseeded live session
+    @Volatile var seed: Long? = null
+        private set
+
+    fun now(): Long = clock.now()
+
+    /**
+     * Random (version 4) UUID drawn from the session generator, for record IDs
+     */
+    fun newId(): String {
+        val source = random
+        val mostSignificant = (source.nextLong() and -0xf001L) or 0x4000L
+        val leastSignificant = (source.nextLong() and 0x3fffffffffffffffL) or Long.MIN_VALUE
+        return UUID(mostSignificant, leastSignificant).toString()
+    }
+
+    /**
+     * Install the sources for a session. Call before the session records anything.
+     */
+    @Synchronized
+    fun install(clock: Clock = SystemWallClock, seed: Long? = null) {
+        this.clock = clock
+        this.random = seed?.let { Random(it) } ?: Random.Default
+        this.seed = seed
+        Log.d(TAG, "Session sources: ${clock.javaClass.simpleName}, seed ${seed ?: "none"}")
+    }
+
+    /**
+     * Back to the system clock and an unseeded generator
+     */
+    fun reset() = install()
+}
@@ -0,0 +1,175 @@
+package com.polyfieldandroid
+
+import android.content.Context
+import android.util.Log
+import com.google.gson.Gson
+import com.google.gson.reflect.TypeToken
+import kotlinx.coroutines.flow.MutableStateFlow
+import kotlinx.coroutines.flow.StateFlow
+import kotlinx.coroutines.flow.asStateFlow
+
+/**
+ * One circle served by a shared EDM: its calibration, the session its measurements are
+ * recorded into and the scoreboard that shows them
+ */
+data class CircleProfile(
+    val name: String,                          // As shown to officials, e.g. "Shot 1"
+    val calibration: CalibrationState,
+    val sessionId: String? = null,             // Event ID; null accepts any session
+    val scoreboardDeviceType: String? = null   // e.g. "daktronics"; null for the first connected board
+)
+
+data class ActiveCircle(
+    val profile: CircleProfile,
+    val generation: Long,  // Bumped on every switch
+    val switchedAt: Long = SessionSources.now()
+)
+
+/**
+ * Shared EDM Scheduler
+ * At small meets one EDM often alternates between two nearby circles. Each circle keeps
+ * its own profile and switching swaps calibration, session and scoreboard routing as one
+ * step. A switch is refused while a measurement is being taken, and a measurement is
+ * refused when the operator's circle or the session it would be recorded into does not
+ * match the active circle. With no profiles saved the scheduler stays out of the way.
+ */
+class SharedEdmScheduler(context: Context) {
+
+    companion object {
+        private const val TAG = "SharedEdmScheduler"
+        private const val PREFS_NAME = "polyfield_shared_edm"
+        private const val PREF_PROFILES = "profiles"
+    }
+
+    private val preferences = context.getSharedPreferences(PREFS_NAME, Context.MODE_PRIVATE)
+    private val gson = Gson()
+    private val profiles = load().toMutableList()
+
+    private val _active = MutableStateFlow<ActiveCircle?>(null)
+    val active: StateFlow<ActiveCircle?> = _active.asStateFlow()
+
+    private var generation = 0L
+    private var measuring = false
+
+    @Synchronized
+    fun getProfiles(): List<CircleProfile> = profiles.toList()
+
+    @Synchronized
+    fun isInUse(): Boolean = profiles.isNotEmpty()
+
+    /**
+     * Scoreboard of the circle recording into this session, so circles running at once
+     * each feed their own board; null when no circle claims one
+     */
+    @Synchronized
+    fun scoreboardFor(sessionId: String?): String? {
+        if (sessionId == null) return null
+        return profiles.firstOrNull { it.sessionId == sessionId && it.scoreboardDeviceType != null }?.scoreboardDeviceType
+    }
+
+    /**
+     * Add or replace a circle profile; the active circle's routing follows the new profile
+     * from the next switch
+     */
+    @Synchronized
+    fun saveProfile(profile: CircleProfile) {
+        profiles.removeAll { it.name == profile.name }
+        profiles.add(profile)
+        save()
+        Log.d(TAG, "Saved circle profile ${profile.name}")
+    }
+
+    @Synchronized
+    fun removeProfile(name: String): Boolean {
+        if (_active.value?.profile?.name == name) {
+            Log.w(TAG, "Refused to remove active circle $name")
+            return false
+        }
+        val removed = profiles.removeAll { it.name == name }
+        if (removed) save()
+        return removed
+    }
+
+    /**
+     * Make another circle active. The outgoing circle's calibration is kept in its profile,
+     * then apply is given the incoming profile to swap into place; nothing changes if it
+     * throws.
+     */
+    @Synchronized
+    fun switchTo(name: String, outgoingCalibration: CalibrationState?, apply: (CircleProfile) -> Unit): Result<ActiveCircle> {
+        if (measuring) {
+            return Result.failure(IllegalStateException("Measurement in progress on ${_active.value?.profile?.name}"))
+        }
+        val incoming = profiles.find { it.name == name }
+            ?: return Result.failure(IllegalArgumentException("No circle profile named $name"))
+
+        val outgoing = _active.value?.profile
+        return try {
+            apply(incoming)
+
+            if (outgoing != null && outgoingCalibration != null && outgoing.name != name) {
+                profiles.replaceAll { if (it.name == outgoing.name) it.copy(calibration = outgoingCalibration) else it }
+                save()
+            }
+            generation++
+            val switched = ActiveCircle(incoming, generation)
+            _active.value = switched
+            Log.d(TAG, "Switched shared EDM from ${outgoing?.name ?: "none"} to $name")
+            Result.success(switched)
+        } catch (e: Exception) {
+            Log.e(TAG, "Switch to $name failed: ${e.message}")
+            Result.failure(e)
+        }
+    }
+
+    /**
+     * Claim the EDM for one measurement. expectedCircle is the circle the operator is
+     * looking at; sessionId the session the result will be recorded into.
+     */
+    @Synchronized
+    fun beginMeasurement(expectedCircle: String?, sessionId: String?): Result<Long> {
+        if (profiles.isEmpty()) return Result.success(generation)
+
+        val active = _active.value
+            ?: return Result.failure(IllegalStateException("No active circle selected for the shared EDM"))
+        if (measuring) {
+            return Result.failure(IllegalStateException("Measurement already in progress on ${active.profile.name}"))
+        }
+        if (expectedCircle != null && expectedCircle != active.profile.name) {
+            return Result.failure(IllegalStateException("EDM is set to ${active.profile.name}, not $expectedCircle"))
+        }
+        val activeSession = active.profile.sessionId
+        if (activeSession != null && sessionId != activeSession) {
+            return Result.failure(IllegalStateException("${active.profile.name} records into session $activeSession, not ${sessionId ?: "none"}"))
+        }
+        measuring = true
+        return Result.success(active.generation)
+    }
+
+    /**
+     * Release the EDM. Returns false if the active circle changed since the measurement
+     * began, in which case the measurement must not be recorded.
+     */
+    @Synchronized
+    fun endMeasurement(claimedGeneration: Long): Boolean {
+        measuring = false
+        return claimedGeneration == generation
+    }
+
+    private fun load(): List<CircleProfile> {
+        return try {
+            val json = preferences.getString(PREF_PROFILES, null) ?: return emptyList()
+            val type = object : TypeToken<List<CircleProfile>>() {}.type
+            gson.fromJson<List<CircleProfile>>(json, type) ?: emptyList()
+        } catch (e: Exception) {
+            Log.e(TAG, "Error loading circle profiles: ${e.message}")
+            emptyList()
+        }
+    }
+
+    private fun save() {
+        preferences.edit()
+            .putString(PREF_PROFILES, gson.toJson(profiles))
+            .apply()
+    }
+}
@@ -0,0 +1,46 @@
+package com.polyfieldandroid
+
+/**
+ * Officials who confirm a results set
+ */
+enum class OfficialRole(val title: String) {
+    REFEREE("Referee"),
+    CHIEF_JUDGE("Chief Judge")
+}
+
+/**
+ * One official's electronic confirmation of the results as they stood
+ */
+data class OfficialSignOff(
+    val name: String,
+    val role: OfficialRole,
+    val resultsHash: String, // ResultsVerification.sessionHash of the sheet confirmed
+    val timestamp: Long = SessionSources.now()
+)
+
+/**
+ * Results Sign-off
+ * The results set is locked once every role has confirmed the same results. A confirmation
+ * given before the results last changed no longer counts and must be given again.
+ */
+object ResultsSignOff {
+
+    val requiredRoles: List<OfficialRole> = OfficialRole.values().toList()
+
+    /**
+     * Confirmations that still apply to the results, latest per role
+     */
+    fun current(signOffs: List<OfficialSignOff>, resultsHash: String): List<OfficialSignOff> =
+        signOffs.filter { it.resultsHash == resultsHash }
+            .groupBy { it.role }
+            .mapNotNull { (_, bySameRole) -> bySameRole.maxByOrNull { it.timestamp } }
+            .sortedBy { it.role.ordinal }
+
+    fun outstandingRoles(signOffs: List<OfficialSignOff>, resultsHash: String): List<OfficialRole> {
+        val signed = current(signOffs, resultsHash).map { it.role }.toSet()
+        return requiredRoles.filter { it !in signed }
+    }
+
+    fun isLocked(signOffs: List<OfficialSignOff>, resultsHash: String): Boolean =
+        signOffs.isNotEmpty() && outstandingRoles(signOffs, resultsHash).isEmpty()
+}
@@ -0,0 +1,97 @@
+package com.polyfieldandroid
+
+import android.util.Log
+
+/**
+ * Sokkia SET Translator
+ * 
+ * Handles Sokkia SET-series stations. The Mato MTS-602R+ speaks a variant of this format;
+ * Sokkia stations may sign the distance, omit the trailing status and report errors.
+ * 
+ * Response format: "0008390 1001021 3080834 83"
+ * - 0008390: Slope distance in mm, optionally signed
+ * - 1001021: Zenith angle in DDDMMSS
+ * - 3080834: Horizontal angle in DDDMMSS
+ * - 83: Optional status/checksum
+ * Errors come back as "E" followed by the station's error number.
+ * Target mode: "/B" for a prism, "/N" non-prism, on R-series stations with a DR distance meter.
+ */
+class SokkiaSETTranslator(deviceSpec: EDMDeviceSpec) : EDMDeviceTranslator(deviceSpec) {
+    
+    companion object {
+        private const val TAG = "SokkiaSETTranslator"
+        
+        private val MEASUREMENT_COMMAND = byteArrayOf(0x11, 0x0d, 0x0a)
+        private val PRISM_MODE_COMMAND = "/B\r\n".toByteArray(Charsets.US_ASCII)
+        private val REFLECTORLESS_MODE_COMMAND = "/N\r\n".toByteArray(Charsets.US_ASCII)
+        
+        private const val MIN_PARTS = 3
+        
+        private val STATUS_CODES = mapOf(
+            "E31" to "Measurement error - no prism found",
+            "E32" to "Signal too weak",
+            "E35" to "Measurement timeout",
+            "E60" to "Tilt out of range"
+        )
+        
+        private val ERROR_KINDS = mapOf(
+            "E31" to EDMErrorKind.NO_PRISM,
+            "E32" to EDMErrorKind.WEAK_SIGNAL,
+            "E35" to EDMErrorKind.TIMEOUT,
+            "E60" to EDMErrorKind.TILT
+        )
+    }
+    
+    override val errorCodes: Map<String, EDMErrorKind> = ERROR_KINDS
+    
+    override fun getMeasurementCommand(): ByteArray {
+        return MEASUREMENT_COMMAND
+    }
+    
+    override fun getMeasurementModeCommand(mode: EDMMeasurementMode): ByteArray = when (mode) {
+        EDMMeasurementMode.PRISM -> PRISM_MODE_COMMAND
+        EDMMeasurementMode.REFLECTORLESS -> REFLECTORLESS_MODE_COMMAND
+    }
+    
+    override fun parseResponse(rawResponse: String): EDMParsedReading {
+        Log.d(TAG, "Parsing Sokkia SET response: '$rawResponse'")
+        
+        val trimmed = rawResponse.trim()
+        if (trimmed.startsWith("E")) {
+            return instrumentErrorReading(trimmed.take(3))
+        }
+        
+        val parts = trimmed.split("\\s+".toRegex())
+        if (parts.size < MIN_PARTS) {
+            return invalidReading("Invalid response format. Expected at least $MIN_PARTS parts, got ${parts.size}: $parts")
+        }
+        
+        return try {
+            EDMParsedReading(
+                slopeDistanceMm = parts[0].toDouble(),
+                verticalAngleDegrees = parseDDDMMSS(parts[1]),
+                horizontalAngleDegrees = parseDDDMMSS(parts[2]),
+                statusCode = parts.getOrNull(3),
+                verticalAngleRaw = parts[1],
+                horizontalAngleRaw = parts[2]
+            )
+        } catch (e: Exception) {
+            Log.e(TAG, "Error parsing Sokkia response: '$rawResponse'", e)
+            invalidReading("Parse error: ${e.message}")
+        }
+    }
+    
+    override fun toGoMobileFormat(parsedReading: EDMParsedReading): String {
+        return standardGoMobileFormat(parsedReading)
+    }
+    
+    override fun isResponseComplete(response: String): Boolean {
+        val trimmed = response.trim()
+        if (trimmed.startsWith("E")) return trimmed.length >= 3
+        return trimmed.split("\\s+".toRegex()).size >= MIN_PARTS
+    }
+    
+    override fun interpretStatusCode(statusCode: String?): String? {
+        return statusCode?.let { STATUS_CODES[it] }
+    }
+}
@@ -0,0 +1,262 @@
+package com.polyfieldandroid
+
+import android.util.Log
+import com.google.gson.Gson
+import kotlinx.coroutines.CoroutineScope
+import kotlinx.coroutines.Dispatchers
+import kotlinx.coroutines.Job
+import kotlinx.coroutines.SupervisorJob
+import kotlinx.coroutines.cancel
+import kotlinx.coroutines.isActive
+import kotlinx.coroutines.launch
+import java.io.BufferedReader
+import java.io.InputStreamReader
+import java.net.ServerSocket
+import java.net.Socket
+
+/**
+ * Spectator feed configuration
+ * Only bib and name are published by default; everything else is opt-in
+ */
+data class SpectatorFeedConfig(
+    val port: Int = 8090,
+    val includeClub: Boolean = false,
+    val includeAttempts: Boolean = true,
+    val includeWind: Boolean = false,
+    val minPublishIntervalMs: Long = 2000
+)
+
+data class SpectatorFeedEntry(
+    val position: Int,
+    val bib: String,
+    val name: String,
+    val club: String? = null,
+    val bestMark: String? = null,
+    val marks: List<String>? = null,
+    val winds: List<String?>? = null
+)
+
+data class SpectatorFeedSnapshot(
+    val eventName: String?,
+    val round: Int,
+    val updatedAt: Long,
+    val entries: List<SpectatorFeedEntry>
+)
+
+/**
+ * Spectator Feed Server
+ * Embedded read-only HTTP server exposing a throttled, sanitised standings feed
+ * for venue big screens (GET /feed.json) and a simple spectator page (GET /)
+ */
+class SpectatorFeedServer(
+    @Volatile var config: SpectatorFeedConfig = SpectatorFeedConfig()
+) {
+
+    companion object {
+        private const val TAG = "SpectatorFeedServer"
+        private const val CLIENT_SOCKET_TIMEOUT_MS = 3000
+    }
+
+    private val gson = Gson()
+    private val scope = CoroutineScope(Dispatchers.IO + SupervisorJob())
+
+    private var serverSocket: ServerSocket? = null
+    private var acceptJob: Job? = null
+
+    // Latest published snapshot; pending is held back until the throttle interval passes
+    @Volatile private var publishedJson: String = gson.toJson(SpectatorFeedSnapshot(null, 0, 0L, emptyList()))
+    @Volatile private var pendingSnapshot: SpectatorFeedSnapshot? = null
+    @Volatile private var lastPublishTime = 0L
+
+    val isRunning: Boolean
+        get() = serverSocket?.isClosed == false
+
+    /**
+     * Start serving the feed on the configured port
+     */
+    fun start(): Boolean {
+        if (isRunning) return true
+
+        return try {
+            val socket = ServerSocket(config.port)
+            serverSocket = socket
+            acceptJob = scope.launch {
+                while (isActive && !socket.isClosed) {
+                    try {
+                        val client = socket.accept()
+                        launch { handleClient(client) }
+                    } catch (e: Exception) {
+                        if (!socket.isClosed) {
+                            Log.w(TAG, "Accept failed: ${e.message}")
+                        }
+                    }
+                }
+            }
+            Log.d(TAG, "Spectator feed serving on port ${config.port}")
+            true
+        } catch (e: Exception) {
+            Log.e(TAG, "Failed to start spectator feed: ${e.message}")
+            false
+        }
+    }
+
+    /**
+     * Stop serving the feed
+     */
+    fun stop() {
+        try {
+            serverSocket?.close()
+        } catch (e: Exception) {
+            Log.w(TAG, "Error closing spectator feed socket: ${e.message}")
+        }
+        acceptJob?.cancel()
+        serverSocket = null
+        acceptJob = null
+        Log.d(TAG, "Spectator feed stopped")
+    }
+
+    /**
+     * Release the server and its coroutine scope
+     */
+    fun shutdown() {
+        stop()
+        scope.cancel()
+    }
+
+    /**
+     * Publish current standings. Personal data is stripped here so nothing
+     * beyond the configured fields is ever held by the server.
+     */
+    fun publish(eventName: String?, round: Int, athletes: List<CompetitionAthlete>) {
+        val feedConfig = config
+        val ranked = athletes.sortedByDescending { it.getBestMark() ?: -1.0 }
+        // Equal marks share a place and the places they fill are skipped (1, 2, 2, 4)
+        val positions = IntArray(ranked.size)
+        ranked.forEachIndexed { index, athlete ->
+            val tied = index > 0 && ranked[index - 1].getBestMark() == athlete.getBestMark()
+            positions[index] = if (tied) positions[index - 1] else index + 1
+        }
+        val entries = ranked
+            .mapIndexed { index, athlete ->
+                SpectatorFeedEntry(
+                    position = positions[index],
+                    bib = athlete.bib,
+                    name = athlete.name,
+                    club = if (feedConfig.includeClub) athlete.club else null,
+                    bestMark = athlete.getBestMark()?.let { "%.2f".format(it) },
+                    marks = if (feedConfig.includeAttempts) {
+                        athlete.attempts.sortedBy { it.round }.map { it.getDisplayMark() }
+                    } else null,
+                    winds = if (feedConfig.includeAttempts && feedConfig.includeWind) {
+                        athlete.attempts.sortedBy { it.round }.map { attempt ->
+                            attempt.windSpeed?.let { "%+.1f".format(it) }
+                        }
+                    } else null
+                )
+            }
+
+        pendingSnapshot = SpectatorFeedSnapshot(
+            eventName = eventName,
+            round = round,
+            updatedAt = SessionSources.now(),
+            entries = entries
+        )
+    }
+
+    /**
+     * Return the feed JSON, promoting the pending snapshot at most once per interval
+     */
+    fun getFeedJson(): String {
+        val now = System.currentTimeMillis()
+        val pending = pendingSnapshot
+        if (pending != null && now - lastPublishTime >= config.minPublishIntervalMs) {
+            publishedJson = gson.toJson(pending)
+            pendingSnapshot = null
+            lastPublishTime = now
+        }
+        return publishedJson
+    }
+
+    private fun handleClient(client: Socket) {
+        client.use { socket ->
+            try {
+                socket.soTimeout = CLIENT_SOCKET_TIMEOUT_MS
+                val reader = BufferedReader(InputStreamReader(socket.getInputStream()))
+                val requestLine = reader.readLine() ?: return
+                val parts = requestLine.split(" ")
+                val method = parts.getOrNull(0) ?: ""
+                val path = parts.getOrNull(1)?.substringBefore("?") ?: "/"
+
+                // Drain headers; the feed ignores them
+                while (true) {
+                    val line = reader.readLine() ?: break
+                    if (line.isEmpty()) break
+                }
+
+                when {
+                    method != "GET" -> writeResponse(socket, 405, "text/plain", "Method Not Allowed")
+                    path == "/feed.json" -> writeResponse(socket, 200, "application/json", getFeedJson())
+                    path == "/" || path == "/index.html" -> writeResponse(socket, 200, "text/html", spectatorPage())
+                    else -> writeResponse(socket, 404, "text/plain", "Not Found")
+                }
+            } catch (e: Exception) {
+                Log.w(TAG, "Error serving spectator client: ${e.message}")
+            }
+        }
+    }
+
+    private fun writeResponse(socket: Socket, status: Int, contentType: String, body: String) {
+        val statusText = when (status) {
+            200 -> "OK"
+            404 -> "Not Found"
+            405 -> "Method Not Allowed"
+            else -> "Error"
+        }
+        val bytes = body.toByteArray(Charsets.UTF_8)
+        val header = "HTTP/1.1 $status $statusText\r\n" +
+            "Content-Type: $contentType; charset=utf-8\r\n" +
+            "Content-Length: ${bytes.size}\r\n" +
+            "Cache-Control: no-cache\r\n" +
+            "Access-Control-Allow-Origin: *\r\n" +
+            "Connection: close\r\n\r\n"
+        val output = socket.getOutputStream()
+        output.write(header.toByteArray(Charsets.US_ASCII))
+        output.write(bytes)
+        output.flush()
+    }
+
+    private fun spectatorPage(): String {
+        val refreshMs = config.minPublishIntervalMs.coerceAtLeast(1000)
+        return """
+            <!DOCTYPE html>
+            <html><head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1">
+            <title>PolyField Live</title>
+            <style>
+              body { font-family: sans-serif; background: #111; color: #fff; margin: 0; padding: 16px; }
+              h1 { font-size: 1.6em; margin: 0 0 8px 0; }
+              table { width: 100%; border-collapse: collapse; font-size: 1.3em; }
+              th, td { padding: 6px 8px; border-bottom: 1px solid #333; text-align: left; }
+              td.mark { font-weight: bold; color: #ffd54f; }
+            </style></head>
+            <body><h1 id="title">PolyField Live</h1><table id="results"></table>
+            <script>
+              function esc(s) { return String(s == null ? '' : s).replace(/[&<>"]/g, c => ({'&':'&amp;','<':'&lt;','>':'&gt;','"':'&quot;'}[c])); }
+              async function refresh() {
+                try {
+                  const feed = await (await fetch('/feed.json')).json();
+                  document.getElementById('title').textContent = (feed.eventName || 'PolyField Live') + (feed.round ? ' - Round ' + feed.round : '');
+                  let rows = '<tr><th>Pos</th><th>Bib</th><th>Name</th><th>Best</th><th>Marks</th></tr>';
+                  for (const e of feed.entries) {
+                    rows += '<tr><td>' + e.position + '</td><td>' + esc(e.bib) + '</td><td>' + esc(e.name) +
+                      (e.club ? ' <small>(' + esc(e.club) + ')</small>' : '') + '</td><td class="mark">' + esc(e.bestMark || '-') +
+                      '</td><td>' + esc((e.marks || []).join('  ')) + '</td></tr>';
+                  }
+                  document.getElementById('results').innerHTML = rows;
+                } catch (err) {}
+              }
+              refresh();
+              setInterval(refresh, $refreshMs);
+            </script></body></html>
+        """.trimIndent()
+    }
+}
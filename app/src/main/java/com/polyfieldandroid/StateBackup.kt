@@ -0,0 +1,222 @@
+package com.polyfieldandroid
+
+import android.content.Context
+import android.os.Build
+import android.util.Log
+import com.google.gson.Gson
+import com.google.gson.JsonObject
+import com.google.gson.JsonParser
+import java.io.File
+import java.util.zip.ZipEntry
+import java.util.zip.ZipInputStream
+import java.util.zip.ZipOutputStream
+
+/**
+ * What a backup archive holds
+ */
+data class BackupManifest(
+    val version: Int,
+    val createdAt: Long,
+    val deviceModel: String,
+    val preferences: List<String>,
+    val files: List<String>
+)
+
+/**
+ * State Backup
+ * Bundles configuration, venue and connection profiles, calibrations, the competition's
+ * results and write-ahead log, registries and logs into one zip so a replacement tablet
+ * can take over. Every preference set the app has written is included, so new stores need
+ * no registering here. The results-signing key, cloud storage credentials and upload
+ * targets (which carry tokens) are left out and must be set up again on the new device.
+ *
+ * Archive layout: manifest.json, prefs/<name>.json (typed key/values), files/<name>.
+ */
+class StateBackup(private val context: Context) {
+
+    companion object {
+        private const val TAG = "StateBackup"
+        private const val VERSION = 1
+        private const val MANIFEST = "manifest.json"
+        private const val PREFS_DIR = "prefs/"
+        private const val FILES_DIR = "files/"
+
+        private const val PREFS_EXTENSION = ".xml"
+
+        // Written by libraries rather than the app
+        private const val LIBRARY_PREFS_PREFIX = "androidx."
+
+        // Keys that stay on the device they were created on
+        private val EXCLUDED_KEYS = mapOf(
+            "polyfield_verification_prefs" to setOf("signing_private_key", "signing_public_key"),
+            "polyfield_cloud_sync_prefs" to setOf("sync_target_config"),
+            "polyfield_upload_target_prefs" to setOf("upload_targets") // Bearer tokens and webhook secrets
+        )
+
+        val FILES = listOf(
+            "competition.wal",
+            "conditions.log",
+            "calibration_archive.jsonl",
+            "throw_audit.jsonl",
+            "implement_inspections.jsonl",
+            "wind_marks.jsonl",
+            "attempt_timeline.jsonl",
+            "polyfield_results_cache.json",
+            "polyfield_cache_metadata.json",
+            "polyfield_scoreboard_outbox.json",
+            "polyfield_upload_pending.json"
+        )
+    }
+
+    private val gson = Gson()
+    private val filesDir = context.applicationContext.filesDir
+
+    /**
+     * Write every known preference set and state file to a zip at the given path
+     */
+    fun backupAll(archive: File): BackupManifest {
+        archive.parentFile?.mkdirs()
+        val preferences = preferenceNames()
+        val files = FILES.filter { File(filesDir, it).exists() }
+        val manifest = BackupManifest(VERSION, SessionSources.now(), "${Build.MANUFACTURER} ${Build.MODEL}", preferences, files)
+
+        val temp = File(archive.path + ".tmp")
+        ZipOutputStream(temp.outputStream().buffered()).use { zip ->
+            zip.putNextEntry(ZipEntry(MANIFEST))
+            zip.write(gson.toJson(manifest).toByteArray(Charsets.UTF_8))
+            zip.closeEntry()
+
+            preferences.forEach { name ->
+                zip.putNextEntry(ZipEntry("$PREFS_DIR$name.json"))
+                zip.write(exportPreferences(name).toString().toByteArray(Charsets.UTF_8))
+                zip.closeEntry()
+            }
+            files.forEach { name ->
+                zip.putNextEntry(ZipEntry("$FILES_DIR$name"))
+                File(filesDir, name).inputStream().use { it.copyTo(zip) }
+                zip.closeEntry()
+            }
+        }
+        if (!temp.renameTo(archive)) {
+            temp.copyTo(archive, overwrite = true)
+            temp.delete()
+        }
+        Log.d(TAG, "Backed up ${preferences.size} preference sets and ${files.size} files to ${archive.absolutePath}")
+        return manifest
+    }
+
+    /**
+     * Replace this device's state with an archive's. Only entries named in the manifest are
+     * restored, and of the files only those known to this version. The app must be restarted
+     * afterwards so every component reloads what it holds in memory.
+     */
+    fun restoreAll(archive: File): BackupManifest {
+        require(archive.exists()) { "Backup not found: ${archive.absolutePath}" }
+
+        val entries = mutableMapOf<String, ByteArray>()
+        ZipInputStream(archive.inputStream().buffered()).use { zip ->
+            var entry = zip.nextEntry
+            while (entry != null) {
+                if (!entry.isDirectory) entries[entry.name] = zip.readBytes()
+                entry = zip.nextEntry
+            }
+        }
+
+        val manifest = entries[MANIFEST]?.let { gson.fromJson(String(it, Charsets.UTF_8), BackupManifest::class.java) }
+            ?: throw IllegalArgumentException("Not a PolyField backup: no manifest")
+        require(manifest.version <= VERSION) { "Backup version ${manifest.version} is newer than this app supports" }
+
+        val preferences = GsonFields.orNull(manifest.preferences)
+        val files = GsonFields.orNull(manifest.files)
+
+        preferences.orEmpty().filter { isAppPreferences(it) }.forEach { name ->
+            val json = entries["$PREFS_DIR$name.json"] ?: return@forEach
+            importPreferences(name, JsonParser.parseString(String(json, Charsets.UTF_8)).asJsonObject)
+        }
+        FILES.forEach { name ->
+            val target = File(filesDir, name)
+            val content = entries["$FILES_DIR$name"]
+            when {
+                name in files.orEmpty() && content != null -> {
+                    val temp = File(filesDir, "$name.restore")
+                    temp.writeBytes(content)
+                    if (!temp.renameTo(target)) {
+                        temp.copyTo(target, overwrite = true)
+                        temp.delete()
+                    }
+                }
+                // State the other tablet did not have must not survive from this one
+                else -> if (target.exists() && !target.delete()) Log.w(TAG, "Could not clear $name")
+            }
+        }
+        Log.d(TAG, "Restored backup from ${manifest.deviceModel} taken at ${manifest.createdAt}")
+        return manifest
+    }
+
+    /**
+     * Names of the preference sets the app has written, read from the shared_prefs directory
+     */
+    private fun preferenceNames(): List<String> {
+        val prefsDir = File(context.applicationInfo.dataDir, "shared_prefs")
+        return prefsDir.listFiles().orEmpty()
+            .map { it.name }
+            .filter { it.endsWith(PREFS_EXTENSION) }
+            .map { it.removeSuffix(PREFS_EXTENSION) }
+            .filter { isAppPreferences(it) }
+            .sorted()
+    }
+
+    // An archive's names become file names, so nothing that could leave shared_prefs
+    private fun isAppPreferences(name: String): Boolean =
+        name.isNotBlank() && !name.startsWith(LIBRARY_PREFS_PREFIX) && '/' !in name && '\\' !in name
+
+    /**
+     * Preference values with their types, so they restore exactly
+     */
+    private fun exportPreferences(name: String): JsonObject {
+        val result = JsonObject()
+        val excluded = EXCLUDED_KEYS[name].orEmpty()
+        context.getSharedPreferences(name, Context.MODE_PRIVATE).all.forEach { (key, value) ->
+            if (key in excluded) return@forEach
+            val typed = JsonObject()
+            when (value) {
+                is String -> { typed.addProperty("type", "string"); typed.addProperty("value", value) }
+                is Int -> { typed.addProperty("type", "int"); typed.addProperty("value", value) }
+                is Long -> { typed.addProperty("type", "long"); typed.addProperty("value", value) }
+                is Float -> { typed.addProperty("type", "float"); typed.addProperty("value", value) }
+                is Boolean -> { typed.addProperty("type", "boolean"); typed.addProperty("value", value) }
+                is Set<*> -> { typed.addProperty("type", "stringSet"); typed.add("value", gson.toJsonTree(value.map { it.toString() })) }
+                else -> return@forEach
+            }
+            result.add(key, typed)
+        }
+        return result
+    }
+
+    private fun importPreferences(name: String, values: JsonObject) {
+        // Keys kept out of backups are left as they are on this device
+        val excluded = EXCLUDED_KEYS[name].orEmpty()
+        val preferences = context.getSharedPreferences(name, Context.MODE_PRIVATE)
+        val editor = preferences.edit()
+        preferences.all.keys.filterNot { it in excluded }.forEach { editor.remove(it) }
+        values.entrySet().forEach { (key, element) ->
+            if (key in excluded) return@forEach
+            try {
+                val typed = element.asJsonObject
+                val value = typed.get("value")
+                when (typed.get("type")?.asString) {
+                    "string" -> editor.putString(key, value.asString)
+                    "int" -> editor.putInt(key, value.asInt)
+                    "long" -> editor.putLong(key, value.asLong)
+                    "float" -> editor.putFloat(key, value.asFloat)
+                    "boolean" -> editor.putBoolean(key, value.asBoolean)
+                    "stringSet" -> editor.putStringSet(key, value.asJsonArray.map { it.asString }.toSet())
+                }
+            } catch (e: Exception) {
+                Log.w(TAG, "Skipping $name/$key: ${e.message}")
+            }
+        }
+        // Written synchronously so a restart straight after restoring sees the values
+        editor.commit()
+    }
+}
@@ -0,0 +1,144 @@
+package com.polyfieldandroid
+
+import android.content.Context
+import android.util.Log
+import com.google.gson.Gson
+import com.google.gson.reflect.TypeToken
+import kotlin.math.hypot
+
+/**
+ * One EDM station position used during the event. Each setup has its own circle frame:
+ * the origin is always the circle centre, but the axes follow the instrument's horizontal
+ * zero, so they turn whenever the station is moved and re-calibrated.
+ */
+data class StationSetup(
+    val id: Int,
+    val stationX: Double,
+    val stationY: Double,
+    val centreTimestamp: String?,
+    val circleType: String,
+    val sectorLineX: Double? = null, // Shared reference used to align this setup with the others
+    val sectorLineY: Double? = null,
+    val createdAt: Long = SessionSources.now()
+) {
+    /**
+     * Sector bisector in this setup's frame, or null until the sector line is measured
+     */
+    fun bisectorRad(): Double? {
+        val x = sectorLineX ?: return null
+        val y = sectorLineY ?: return null
+        return CoordinateTransforms.sectorBisector(Pair(x, y), circleType)
+    }
+}
+
+/**
+ * Station Setups
+ * Keeps every station setup used in the session so throws measured from an earlier
+ * position can be rotated into the current frame. The sector line, measured in each
+ * setup, is the common reference: the bisector is the same direction on the field, so
+ * the difference between setups' bisectors is the rotation between their frames.
+ */
+class StationSetupLog(context: Context) {
+
+    companion object {
+        private const val TAG = "StationSetups"
+        private const val PREFS_NAME = "polyfield_station_setups"
+        private const val PREF_SETUPS = "setups"
+
+        // Station shifts smaller than this are re-centring noise, not a move
+        private const val STATION_MOVE_THRESHOLD_M = 0.005
+    }
+
+    private val preferences = context.getSharedPreferences(PREFS_NAME, Context.MODE_PRIVATE)
+    private val gson = Gson()
+    private val setups = load().toMutableList()
+
+    fun getSetups(): List<StationSetup> = synchronized(setups) { setups.toList() }
+
+    fun current(): StationSetup? = synchronized(setups) { setups.lastOrNull() }
+
+    /**
+     * Note the calibration about to be measured with. Starts a new setup when the station
+     * has moved or the centre was set again, and fills in the sector line once measured.
+     * Returns the current setup, or null if the centre is not set.
+     */
+    fun track(calibration: CalibrationState): StationSetup? = synchronized(setups) {
+        val station = calibration.stationCoordinates
+        if (!calibration.centreSet || station == null) return null
+
+        val sectorLine = calibration.sectorLineCoordinates?.takeIf { calibration.sectorLineSet }
+        val last = setups.lastOrNull()
+        val moved = last == null ||
+            last.circleType != calibration.circleType ||
+            last.centreTimestamp != calibration.centreTimestamp ||
+            hypot(station.first - last.stationX, station.second - last.stationY) > STATION_MOVE_THRESHOLD_M
+
+        if (moved) {
+            val setup = StationSetup(
+                id = (last?.id ?: 0) + 1,
+                stationX = station.first,
+                stationY = station.second,
+                centreTimestamp = calibration.centreTimestamp,
+                circleType = calibration.circleType,
+                sectorLineX = sectorLine?.first,
+                sectorLineY = sectorLine?.second
+            )
+            setups.add(setup)
+            save()
+            Log.d(TAG, "Station setup ${setup.id} at (${setup.stationX}, ${setup.stationY})")
+            return setup
+        }
+
+        if (sectorLine != null && (last!!.sectorLineX != sectorLine.first || last.sectorLineY != sectorLine.second)) {
+            val updated = last.copy(sectorLineX = sectorLine.first, sectorLineY = sectorLine.second)
+            setups[setups.lastIndex] = updated
+            save()
+            return updated
+        }
+        last
+    }
+
+    /**
+     * Throw re-expressed in the current setup's frame. Throws already in it, or from a
+     * setup that cannot be aligned yet (no sector line on either side), come back unchanged.
+     */
+    fun toCurrentFrame(coordinate: ThrowCoordinate): ThrowCoordinate {
+        val fromId = coordinate.setupId ?: return coordinate
+        val (from, to) = synchronized(setups) {
+            Pair(setups.find { it.id == fromId }, setups.lastOrNull())
+        }
+        if (from == null || to == null || from.id == to.id) return coordinate
+
+        val fromBisector = from.bisectorRad() ?: return coordinate
+        val toBisector = to.bisectorRad() ?: return coordinate
+        val (x, y) = CoordinateTransforms.rotate(Pair(coordinate.x, coordinate.y), toBisector - fromBisector)
+        return coordinate.copy(x = x, y = y, setupId = to.id)
+    }
+
+    /**
+     * Start afresh for a new competition
+     */
+    fun reset() {
+        synchronized(setups) {
+            setups.clear()
+            preferences.edit().remove(PREF_SETUPS).apply()
+        }
+    }
+
+    private fun load(): List<StationSetup> {
+        return try {
+            val json = preferences.getString(PREF_SETUPS, null) ?: return emptyList()
+            val listType = object : TypeToken<List<StationSetup>>() {}.type
+            gson.fromJson<List<StationSetup>>(json, listType) ?: emptyList()
+        } catch (e: Exception) {
+            Log.e(TAG, "Error loading station setups: ${e.message}")
+            emptyList()
+        }
+    }
+
+    private fun save() {
+        preferences.edit()
+            .putString(PREF_SETUPS, gson.toJson(setups))
+            .apply()
+    }
+}
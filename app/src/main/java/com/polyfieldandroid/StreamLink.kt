@@ -0,0 +1,191 @@
+package com.polyfieldandroid
+
+import java.io.IOException
+import java.io.InputStream
+import java.io.OutputStream
+import java.net.Socket
+import java.net.SocketTimeoutException
+import java.util.concurrent.Executors
+import java.util.concurrent.TimeUnit
+import java.util.concurrent.atomic.AtomicBoolean
+
+/**
+ * Stream Link
+ * A non-TCP device channel (Bluetooth SPP, BLE GATT, USB passthrough) presented as a Socket, so network
+ * protocols and the wind listener run over it unchanged, with serial-port style reads
+ * for the EDM exchange. Reads honour soTimeout by throwing SocketTimeoutException, which
+ * the underlying streams do not do on their own.
+ */
+abstract class StreamLink : Socket() {
+
+    companion object {
+        private const val POLL_INTERVAL_MS = 10L
+
+        // Closes links whose writes overrun their deadline
+        private val writeWatchdog = Executors.newSingleThreadScheduledExecutor { runnable ->
+            Thread(runnable, "StreamLinkWriteWatchdog").apply { isDaemon = true }
+        }
+    }
+
+    abstract val address: String
+
+    @Volatile private var readTimeoutMs = 0
+    @Volatile protected var closed = false
+        private set
+
+    /**
+     * Bytes as they arrive from the device
+     */
+    protected abstract val source: InputStream
+
+    protected abstract val sink: OutputStream
+
+    /**
+     * Release the underlying channel
+     */
+    protected abstract fun closeChannel()
+
+    protected abstract fun isChannelConnected(): Boolean
+
+    private val input by lazy { TimedInputStream(source) }
+
+    override fun getInputStream(): InputStream = input
+
+    override fun getOutputStream(): OutputStream = sink
+
+    override fun setSoTimeout(timeout: Int) {
+        readTimeoutMs = timeout
+    }
+
+    override fun getSoTimeout(): Int = readTimeoutMs
+
+    override fun isConnected(): Boolean = !closed && isChannelConnected()
+
+    override fun isClosed(): Boolean = closed
+
+    override fun close() {
+        closed = true
+        try {
+            closeChannel()
+        } finally {
+            super.close()
+        }
+    }
+
+    /**
+     * Read whatever arrives within timeoutMs: 0 when nothing came
+     */
+    fun read(buffer: ByteArray, timeoutMs: Int): Int {
+        readTimeoutMs = timeoutMs
+        return try {
+            input.read(buffer, 0, buffer.size).coerceAtLeast(0)
+        } catch (e: SocketTimeoutException) {
+            0
+        }
+    }
+
+    fun write(bytes: ByteArray) {
+        sink.apply {
+            write(bytes)
+            flush()
+        }
+    }
+
+    /**
+     * Write with a deadline. A stalled channel cannot be interrupted mid-write, so once the
+     * deadline passes the link is closed, which fails the write and frees the caller; the
+     * device then reconnects as after any dropped link.
+     */
+    fun write(bytes: ByteArray, timeoutMs: Int) {
+        if (timeoutMs <= 0) return write(bytes)
+        val timedOut = AtomicBoolean(false)
+        val watchdog = writeWatchdog.schedule({
+            if (!closed) {
+                timedOut.set(true)
+                close()
+            }
+        }, timeoutMs.toLong(), TimeUnit.MILLISECONDS)
+        try {
+            write(bytes)
+        } catch (e: IOException) {
+            if (timedOut.get()) throw SocketTimeoutException("Write to $address timed out after ${timeoutMs}ms")
+            throw e
+        } finally {
+            watchdog.cancel(false)
+        }
+    }
+
+    private inner class TimedInputStream(private val upstream: InputStream) : InputStream() {
+
+        override fun read(): Int {
+            awaitData()
+            return upstream.read()
+        }
+
+        override fun read(b: ByteArray, off: Int, len: Int): Int {
+            if (len == 0) return 0
+            awaitData()
+            return upstream.read(b, off, minOf(len, upstream.available().coerceAtLeast(1)))
+        }
+
+        override fun available(): Int = upstream.available()
+
+        override fun close() = upstream.close()
+
+        private fun awaitData() {
+            val timeout = readTimeoutMs
+            if (timeout <= 0) return
+            val deadline = System.currentTimeMillis() + timeout
+            while (upstream.available() == 0) {
+                if (closed) throw IOException("Link to $address closed")
+                if (System.currentTimeMillis() >= deadline) throw SocketTimeoutException("Read timed out")
+                Thread.sleep(POLL_INTERVAL_MS)
+            }
+        }
+    }
+}
+
+/**
+ * Bytes pushed in by a callback, waiting to be read; for channels that deliver data as
+ * events rather than a stream. Reads block until data arrives or isOpen turns false. The
+ * oldest bytes are dropped beyond maxBytes.
+ */
+class ReceiveBuffer(
+    private val maxBytes: Int,
+    private val isOpen: () -> Boolean
+) : InputStream() {
+    private val bytes = ArrayDeque<Byte>()
+    private val lock = Object()
+
+    fun append(value: ByteArray?) {
+        if (value == null || value.isEmpty()) return
+        synchronized(lock) {
+            value.forEach { bytes.addLast(it) }
+            while (bytes.size > maxBytes) bytes.removeFirst()
+            lock.notifyAll()
+        }
+    }
+
+    override fun available(): Int = synchronized(lock) { bytes.size }
+
+    override fun read(): Int = synchronized(lock) {
+        if (!awaitBytes()) return -1
+        bytes.removeFirst().toInt() and 0xff
+    }
+
+    override fun read(b: ByteArray, off: Int, len: Int): Int = synchronized(lock) {
+        if (len == 0) return 0
+        if (!awaitBytes()) return -1
+        val count = minOf(len, bytes.size)
+        for (i in 0 until count) b[off + i] = bytes.removeFirst()
+        count
+    }
+
+    private fun awaitBytes(): Boolean {
+        while (bytes.isEmpty()) {
+            if (!isOpen()) return false
+            lock.wait(100)
+        }
+        return true
+    }
+}
@@ -0,0 +1,200 @@
+package com.polyfieldandroid
+
+import android.content.Context
+import android.util.Log
+import com.google.gson.Gson
+import java.io.File
+import java.util.Locale
+import kotlin.math.floor
+
+/**
+ * Raw reading and reduction values kept for one measured throw
+ */
+data class ThrowAuditEntry(
+    val athleteBib: String,
+    val round: Int,
+    val attemptNumber: Int,
+    val components: EDMInterface.MeasurementComponents,
+    val timestamp: Long = SessionSources.now()
+)
+
+/**
+ * One stage of the reduction from slope distance to official mark
+ */
+data class CorrectionStep(
+    val name: String,
+    val before: Double,   // Metres
+    val after: Double,    // Metres
+    val applied: Boolean,
+    val note: String
+) {
+    val changeMm: Double
+        get() = (after - before) * 1000
+}
+
+/**
+ * Correction Breakdown
+ * Lists every correction between the instrument's slope distance and the official mark,
+ * with before/after values, for record documentation
+ */
+object CorrectionBreakdown {
+
+    private const val EARTH_RADIUS_M = 6371000.0
+
+    /**
+     * Marks are recorded to the centimetre below when not a whole centimetre
+     */
+    fun officialMark(distanceM: Double): Double = floor(distanceM * 100 + 1e-9) / 100
+
+    fun steps(components: EDMInterface.MeasurementComponents): List<CorrectionStep> {
+        val c = components
+        // Chord vs arc over the horizontal distance: hd³ / 24R², far below a millimetre on a field
+        val curvatureM = c.horizontalDistanceM * c.horizontalDistanceM * c.horizontalDistanceM / (24 * EARTH_RADIUS_M * EARTH_RADIUS_M)
+        val prismCorrectedM = c.rawSlopeDistanceM + c.prismConstantMm / 1000.0
+        return listOf(
+            CorrectionStep(
+                "Prism constant", c.rawSlopeDistanceM, prismCorrectedM, c.prismConstantMm != 0.0,
+                "${String.format(Locale.US, "%+.1f", c.prismConstantMm)} mm"
+            ),
+            CorrectionStep(
+                "Atmospheric", prismCorrectedM, c.correctedSlopeDistanceM, c.atmosphericPpm != 0.0,
+                "${String.format(Locale.US, "%+.1f", c.atmosphericPpm)} ppm" + (c.atmosphere?.let { a ->
+                    " at ${String.format(Locale.US, "%.1f", a.temperatureC)} °C, ${String.format(Locale.US, "%.1f", a.pressureHpa)} hPa" +
+                        (a.humidityPct?.let { ", ${String.format(Locale.US, "%.0f", it)} % RH" } ?: "")
+                } ?: "")
+            ),
+            CorrectionStep(
+                "Horizontal reduction", c.correctedSlopeDistanceM, c.horizontalDistanceM, true,
+                "Vertical angle ${String.format(Locale.US, "%.5f", c.verticalAngleDeg)}°"
+            ),
+            CorrectionStep(
+                "Earth curvature", c.horizontalDistanceM, c.horizontalDistanceM, false,
+                "${String.format(Locale.US, "%.6f", curvatureM * 1000)} mm, negligible"
+            ),
+            CorrectionStep(
+                "Station offset", c.horizontalDistanceM, c.distanceFromCentreM, true,
+                "Station at (${String.format(Locale.US, "%.3f", c.stationOffsetX)}, ${String.format(Locale.US, "%.3f", c.stationOffsetY)}) m"
+            ),
+            CorrectionStep(
+                "Circle radius", c.distanceFromCentreM, c.throwDistanceM, true,
+                "${String.format(Locale.US, "%.4f", c.circleRadiusM)} m to inner edge"
+            ),
+            CorrectionStep(
+                "Rounding", c.throwDistanceM, officialMark(c.throwDistanceM), true,
+                "Down to the centimetre"
+            )
+        )
+    }
+
+    /**
+     * One row per throw. With the sector bisector, the landing point is also given along the
+     * centreline (x) and to its left (y), as well as in the station-aligned circle frame.
+     */
+    fun toCsv(
+        entries: List<ThrowAuditEntry>,
+        precision: OutputPrecision = OutputPrecision(),
+        sectorBisectorRad: Double? = null
+    ): String = buildString {
+        val distance = OutputPrecision.Field.DISTANCE
+        val coordinate = OutputPrecision.Field.COORDINATE
+        val angle = OutputPrecision.Field.ANGLE
+        appendLine(
+            "Bib,Round,Time,Raw slope (m),Prism (mm),ppm,Corrected slope (m),VA raw,HA raw,VA (deg),HA (deg),Horizontal (m)," +
+                "Station X,Station Y,Throw X,Throw Y," +
+                (if (sectorBisectorRad != null) "Centreline X,Centreline Y," else "") +
+                "From centre (m),Radius (m),Distance (m),Official mark (m)"
+        )
+        entries.forEach { entry ->
+            val c = entry.components
+            val centreline = sectorBisectorRad?.let {
+                CoordinateTransforms.circleToCentreline(Pair(c.throwX, c.throwY), it)
+            }
+            val fields = listOf(
+                entry.athleteBib.replace(",", " "),
+                entry.round.toString(),
+                java.time.Instant.ofEpochMilli(entry.timestamp).toString(),
+                precision.format(c.rawSlopeDistanceM, distance),
+                String.format(Locale.US, "%.1f", c.prismConstantMm),
+                precision.format(c.atmosphericPpm, OutputPrecision.Field.PPM),
+                precision.format(c.correctedSlopeDistanceM, distance),
+                c.verticalAngleRaw.orEmpty(),
+                c.horizontalAngleRaw.orEmpty(),
+                precision.format(c.verticalAngleDeg, angle),
+                precision.format(c.horizontalAngleDeg, angle),
+                precision.format(c.horizontalDistanceM, distance),
+                precision.format(c.stationOffsetX, coordinate),
+                precision.format(c.stationOffsetY, coordinate),
+                precision.format(c.throwX, coordinate),
+                precision.format(c.throwY, coordinate)
+            ) + listOfNotNull(
+                centreline?.let { precision.format(it.first, coordinate) },
+                centreline?.let { precision.format(it.second, coordinate) }
+            ) + listOf(
+                precision.format(c.distanceFromCentreM, distance),
+                precision.format(c.circleRadiusM, distance),
+                precision.format(c.throwDistanceM, distance),
+                String.format(Locale.US, "%.2f", officialMark(c.throwDistanceM))
+            )
+            appendLine(fields.joinToString(","))
+        }
+    }
+}
+
+/**
+ * Throw Audit Log
+ * Keeps the reduction of every measured throw for the event so the correction breakdown can
+ * be produced after the fact; cleared when the next competition starts
+ */
+class ThrowAuditLog(context: Context) {
+
+    companion object {
+        private const val TAG = "ThrowAuditLog"
+        private const val FILE_NAME = "throw_audit.jsonl"
+        private val lock = Any()
+    }
+
+    private val file = File(context.applicationContext.filesDir, FILE_NAME)
+    private val gson = Gson()
+
+    fun append(entry: ThrowAuditEntry) {
+        synchronized(lock) {
+            try {
+                file.appendText(gson.toJson(entry) + "\n", Charsets.UTF_8)
+            } catch (e: Exception) {
+                Log.e(TAG, "Failed to record throw audit: ${e.message}")
+            }
+        }
+    }
+
+    /**
+     * Latest measurement per athlete and round; re-measured throws replace earlier ones.
+     * until leaves out anything recorded after that time.
+     */
+    fun getEntries(until: Long? = null): List<ThrowAuditEntry> {
+        // Only the raw read holds the lock, so parsing a long log never holds up a write
+        val lines = synchronized(lock) {
+            if (file.exists()) file.readLines(Charsets.UTF_8) else emptyList()
+        }
+
+        val latest = linkedMapOf<Pair<String, Int>, ThrowAuditEntry>()
+        lines.forEach { line ->
+            if (line.isBlank()) return@forEach
+            try {
+                gson.fromJson(line, ThrowAuditEntry::class.java)
+                    ?.takeIf { until == null || it.timestamp <= until }
+                    ?.let { latest[it.athleteBib to it.round] = it }
+            } catch (e: Exception) {
+                Log.w(TAG, "Skipping unreadable entry: ${e.message}")
+            }
+        }
+        return latest.values.sortedWith(compareBy({ it.round }, { it.timestamp }))
+    }
+
+    fun reset() {
+        synchronized(lock) {
+            if (file.exists() && !file.delete()) {
+                Log.e(TAG, "Could not delete ${file.name}")
+            }
+        }
+    }
+}
@@ -0,0 +1,102 @@
+package com.polyfieldandroid
+
+import android.util.Log
+
+/**
+ * Topcon GTS Translator
+ * 
+ * Handles Topcon GTS-series stations in slope distance output mode (7 data bits, even parity).
+ * 
+ * Response format: "+00012345m0902030+1234050d+00"
+ * - +00012345m: Slope distance in mm, 'm' for metres ('f' when the station is set to feet)
+ * - 0902030: Zenith angle in DDDMMSS (90° 20' 30")
+ * - +1234050d: Horizontal angle in DDDMMSS, 'd' for degrees
+ * - +00: Trailing status, ignored
+ * Errors come back as "E" followed by the station's error number.
+ * Target mode: "Z34081" for a prism, "Z34082" non-prism, on stations with a DR distance meter.
+ */
+class TopconGTSTranslator(deviceSpec: EDMDeviceSpec) : EDMDeviceTranslator(deviceSpec) {
+    
+    companion object {
+        private const val TAG = "TopconGTSTranslator"
+        
+        // Slope distance measurement request
+        private val MEASUREMENT_COMMAND = "Z64088\u0003".toByteArray(Charsets.US_ASCII)
+        
+        // Target mode switches, same ETX framing
+        private val PRISM_MODE_COMMAND = "Z34081\u0003".toByteArray(Charsets.US_ASCII)
+        private val REFLECTORLESS_MODE_COMMAND = "Z34082\u0003".toByteArray(Charsets.US_ASCII)
+        
+        private val READING_PATTERN = Regex("""([+-]?\d{6,9})([mf])(\d{6,7})([+-]?)(\d{6,7})d""")
+        private val ERROR_PATTERN = Regex("""^E(\d{2,3})""")
+        
+        private const val FEET_TO_MM = 304.8
+        
+        private val STATUS_CODES = mapOf(
+            "31" to "Measurement error - no prism found",
+            "32" to "Signal too weak",
+            "35" to "Measurement timeout",
+            "60" to "Tilt out of range"
+        )
+        
+        private val ERROR_KINDS = mapOf(
+            "31" to EDMErrorKind.NO_PRISM,
+            "32" to EDMErrorKind.WEAK_SIGNAL,
+            "35" to EDMErrorKind.TIMEOUT,
+            "60" to EDMErrorKind.TILT
+        )
+    }
+    
+    override val errorCodes: Map<String, EDMErrorKind> = ERROR_KINDS
+    
+    override fun getMeasurementCommand(): ByteArray {
+        return MEASUREMENT_COMMAND
+    }
+    
+    override fun getMeasurementModeCommand(mode: EDMMeasurementMode): ByteArray = when (mode) {
+        EDMMeasurementMode.PRISM -> PRISM_MODE_COMMAND
+        EDMMeasurementMode.REFLECTORLESS -> REFLECTORLESS_MODE_COMMAND
+    }
+    
+    override fun parseResponse(rawResponse: String): EDMParsedReading {
+        Log.d(TAG, "Parsing Topcon GTS response: '$rawResponse'")
+        
+        val trimmed = rawResponse.trim().removePrefix("?")
+        ERROR_PATTERN.find(trimmed)?.let { error ->
+            val code = error.groupValues[1]
+            return instrumentErrorReading(code)
+        }
+        
+        val match = READING_PATTERN.find(trimmed)
+            ?: return invalidReading("Unrecognised Topcon response: '$trimmed'")
+        
+        return try {
+            val (distance, unit, vertical, _, horizontal) = match.destructured
+            val slopeDistanceMm = if (unit == "f") distance.toLong() / 1000.0 * FEET_TO_MM else distance.toDouble()
+            
+            EDMParsedReading(
+                slopeDistanceMm = slopeDistanceMm,
+                verticalAngleDegrees = parseDDDMMSS(vertical),
+                horizontalAngleDegrees = parseDDDMMSS(horizontal),
+                verticalAngleRaw = vertical,
+                horizontalAngleRaw = horizontal
+            )
+        } catch (e: Exception) {
+            Log.e(TAG, "Error parsing Topcon response: '$rawResponse'", e)
+            invalidReading("Parse error: ${e.message}")
+        }
+    }
+    
+    override fun toGoMobileFormat(parsedReading: EDMParsedReading): String {
+        return standardGoMobileFormat(parsedReading)
+    }
+    
+    override fun isResponseComplete(response: String): Boolean {
+        val trimmed = response.trim().removePrefix("?")
+        return READING_PATTERN.containsMatchIn(trimmed) || ERROR_PATTERN.containsMatchIn(trimmed)
+    }
+    
+    override fun interpretStatusCode(statusCode: String?): String? {
+        return statusCode?.let { STATUS_CODES[it] }
+    }
+}
@@ -0,0 +1,65 @@
+package com.polyfieldandroid
+
+/**
+ * One tracked position with both the instrument's value and the smoothed display value
+ */
+data class SmoothedSample(
+    val raw: Double,
+    val smoothed: Double,
+    val timestamp: Long
+)
+
+/**
+ * Tracking Smoother
+ * Light filtering for a continuous stream of prism distances so a live display doesn't
+ * jitter. A short moving median rejects single-reading spikes, then a simple 1-D Kalman
+ * filter settles the remaining noise while still following a prism that is being walked.
+ *
+ * Smoothing is for display only: raw values are kept alongside so the official read is
+ * always taken from the instrument, never from the filter.
+ */
+class TrackingSmoother(
+    private val medianWindow: Int = 5,
+    private val processNoise: Double = 0.0004,    // m² per sample; how far the prism may move between readings
+    private val measurementNoise: Double = 0.0004 // m²; instrument noise in tracking mode (~2 cm)
+) {
+
+    private val window = ArrayDeque<Double>()
+    private var estimate: Double? = null
+    private var errorCovariance = 1.0
+    private var latest: SmoothedSample? = null
+
+    @Synchronized
+    fun add(raw: Double, timestamp: Long = SessionSources.now()): SmoothedSample {
+        window.addLast(raw)
+        while (window.size > medianWindow.coerceAtLeast(1)) window.removeFirst()
+        val median = window.sorted().let { sorted ->
+            if (sorted.size % 2 == 1) sorted[sorted.size / 2]
+            else (sorted[sorted.size / 2 - 1] + sorted[sorted.size / 2]) / 2
+        }
+
+        val previous = estimate
+        val smoothed = if (previous == null) {
+            median
+        } else {
+            val predictedCovariance = errorCovariance + processNoise
+            val gain = predictedCovariance / (predictedCovariance + measurementNoise)
+            errorCovariance = (1 - gain) * predictedCovariance
+            previous + gain * (median - previous)
+        }
+        estimate = smoothed
+
+        return SmoothedSample(raw = raw, smoothed = smoothed, timestamp = timestamp).also { latest = it }
+    }
+
+    @Synchronized
+    fun latest(): SmoothedSample? = latest
+
+    @Synchronized
+    fun reset() {
+        window.clear()
+        estimate = null
+        errorCovariance = 1.0
+        latest = null
+    }
+}
@@ -0,0 +1,135 @@
+package com.polyfieldandroid
+
+import com.google.gson.Gson
+import java.util.concurrent.ConcurrentHashMap
+
+enum class TrafficDirection { SENT, RECEIVED }
+
+/**
+ * Told of bytes as they cross a channel: direction, the bytes, and whether they were
+ * drained unread before a command
+ */
+typealias TrafficTap = (TrafficDirection, ByteArray, Boolean) -> Unit
+
+/**
+ * Bytes in one direction as they crossed the channel. drained marks stale bytes cleared
+ * from the port before a command was sent, which the live read discarded.
+ */
+data class CapturedChunk(
+    val timestamp: Long,
+    val direction: TrafficDirection,
+    val hex: String,
+    val text: String,           // Printable rendering for reading the capture by eye
+    val drained: Boolean = false
+) {
+    fun bytes(): ByteArray =
+        hex.split(" ").filter { it.isNotEmpty() }.map { it.toInt(16).toByte() }.toByteArray()
+}
+
+data class DeviceCapture(
+    val deviceType: String,
+    val driver: String?,        // EDM registry key or protocol type in use when capture started
+    val startedAt: Long,
+    val stoppedAt: Long? = null,
+    val chunks: List<CapturedChunk> = emptyList(),
+    val truncated: Boolean = false
+)
+
+/**
+ * A command and everything received after it, up to the next command
+ */
+data class CapturedExchange(
+    val sentAt: Long?,
+    val command: ByteArray?,
+    val response: ByteArray
+)
+
+/**
+ * Traffic Capture
+ * Records every byte exchanged with a device, timestamped, while a capture is running, so
+ * a fault seen in the field ("readings inconsistent") can be examined and replayed through
+ * the parser afterwards. Captures are held in memory; long ones stop growing at MAX_CHUNKS.
+ */
+class TrafficCapture {
+
+    companion object {
+        private const val MAX_CHUNKS = 20000
+        private val gson = Gson()
+
+        fun toJson(capture: DeviceCapture): String = gson.toJson(capture)
+
+        fun fromJson(json: String): DeviceCapture = gson.fromJson(json, DeviceCapture::class.java)
+
+        /**
+         * Group a capture into command/response exchanges, leaving out drained bytes as the
+         * live read did. Traffic before the first command, such as a streaming gauge's
+         * output, forms an exchange with no command.
+         */
+        fun exchanges(capture: DeviceCapture): List<CapturedExchange> {
+            val exchanges = mutableListOf<CapturedExchange>()
+            var sentAt: Long? = null
+            var command: ByteArray? = null
+            var response = ByteArray(0)
+            // Gson leaves a missing list null despite the declared type
+            val chunks: List<CapturedChunk>? = capture.chunks
+            chunks.orEmpty().filter { !it.drained }.forEach { chunk ->
+                when (chunk.direction) {
+                    TrafficDirection.SENT -> {
+                        if (command != null || response.isNotEmpty()) exchanges.add(CapturedExchange(sentAt, command, response))
+                        sentAt = chunk.timestamp
+                        command = chunk.bytes()
+                        response = ByteArray(0)
+                    }
+                    TrafficDirection.RECEIVED -> response += chunk.bytes()
+                }
+            }
+            if (command != null || response.isNotEmpty()) exchanges.add(CapturedExchange(sentAt, command, response))
+            return exchanges
+        }
+    }
+
+    private class Recording(val capture: DeviceCapture) {
+        val chunks = ArrayList<CapturedChunk>()
+        var truncated = false
+    }
+
+    private val recordings = ConcurrentHashMap<String, Recording>()
+
+    /**
+     * Start capturing a device's traffic, discarding any capture already running for it
+     */
+    fun start(deviceType: String, driver: String?) {
+        recordings[deviceType] = Recording(DeviceCapture(deviceType, driver, SessionSources.now()))
+    }
+
+    fun isCapturing(deviceType: String): Boolean = recordings.containsKey(deviceType)
+
+    fun record(deviceType: String, direction: TrafficDirection, bytes: ByteArray, drained: Boolean = false) {
+        val recording = recordings[deviceType] ?: return
+        if (bytes.isEmpty()) return
+        val chunk = CapturedChunk(
+            timestamp = SessionSources.now(),
+            direction = direction,
+            hex = bytes.joinToString(" ") { "%02x".format(it) },
+            text = bytes.map { byte -> byte.toInt().toChar().takeIf { it in ' '..'~' } ?: '.' }.joinToString(""),
+            drained = drained
+        )
+        synchronized(recording) {
+            if (recording.chunks.size < MAX_CHUNKS) recording.chunks.add(chunk) else recording.truncated = true
+        }
+    }
+
+    /**
+     * Stop capturing and return what was recorded; null if no capture was running
+     */
+    fun stop(deviceType: String): DeviceCapture? {
+        val recording = recordings.remove(deviceType) ?: return null
+        return synchronized(recording) {
+            recording.capture.copy(
+                stoppedAt = SessionSources.now(),
+                chunks = recording.chunks.toList(),
+                truncated = recording.truncated
+            )
+        }
+    }
+}
@@ -0,0 +1,366 @@
+package com.polyfieldandroid
+
+import android.content.Context
+import android.util.Log
+import com.google.gson.Gson
+import com.google.gson.reflect.TypeToken
+import kotlinx.coroutines.Dispatchers
+import kotlinx.coroutines.async
+import kotlinx.coroutines.awaitAll
+import kotlinx.coroutines.coroutineScope
+import kotlinx.coroutines.sync.Mutex
+import kotlinx.coroutines.sync.withLock
+import kotlinx.coroutines.withContext
+import java.io.File
+import java.io.IOException
+import java.net.HttpURLConnection
+import java.net.InetSocketAddress
+import java.net.Socket
+import java.net.URI
+import java.net.URL
+import java.util.concurrent.ConcurrentHashMap
+import javax.crypto.Mac
+import javax.crypto.spec.SecretKeySpec
+
+/**
+ * Somewhere an athlete's series is sent each time it changes, alongside the PolyField
+ * server: a scoreboard, the meet software, a live web feed
+ */
+interface UploadTarget {
+    val id: String
+    val name: String
+
+    suspend fun upload(payload: PolyFieldApiClient.ResultPayload): SyncResult
+}
+
+enum class UploadTargetType {
+    REST_JSON,  // POST of the result payload as the PolyField server receives it
+    OPENTRACK,  // POST of the series as OpenTrack-style trials
+    FIELDLYNX,  // Comma-separated result lines over TCP, as FieldLynx-style meet software imports
+    WEBHOOK     // POST of a result event, signed when a secret is set
+}
+
+data class UploadTargetConfig(
+    val id: String = SessionSources.newId(),
+    val type: UploadTargetType = UploadTargetType.REST_JSON,
+    val name: String = "",
+    val url: String = "",               // http(s) URL, or tcp://host:port for FieldLynx
+    val token: String? = null,          // Bearer token; the signing secret for webhooks
+    val competitionId: String? = null,  // OpenTrack only
+    val enabled: Boolean = true
+)
+
+/**
+ * Delivery state of one target, for the upload settings screen
+ */
+data class UploadTargetStatus(
+    val targetId: String,
+    val name: String,
+    val enabled: Boolean,
+    val lastAttemptAt: Long? = null,
+    val lastSuccessAt: Long? = null,
+    val lastError: String? = null,
+    val delivered: Int = 0,
+    val failed: Int = 0,
+    val pending: Int = 0      // Series waiting to be resent after a failure
+) {
+    fun toMap(): Map<String, Any?> = mapOf(
+        "targetId" to targetId,
+        "name" to name,
+        "enabled" to enabled,
+        "lastAttemptAt" to lastAttemptAt,
+        "lastSuccessAt" to lastSuccessAt,
+        "lastError" to lastError,
+        "delivered" to delivered,
+        "failed" to failed,
+        "pending" to pending
+    )
+}
+
+/**
+ * Reference targets for the built-in types. Everything is sent over HTTP except FieldLynx,
+ * which takes plain lines on a TCP port.
+ */
+class ConfiguredUploadTarget(private val config: UploadTargetConfig) : UploadTarget {
+
+    companion object {
+        private const val TAG = "UploadTarget"
+        private const val CONNECTION_TIMEOUT = 5000
+        private const val READ_TIMEOUT = 10000
+    }
+
+    private val gson = Gson()
+
+    override val id: String
+        get() = config.id
+
+    override val name: String
+        get() = config.name.ifBlank { "${config.type} ${config.url}" }
+
+    override suspend fun upload(payload: PolyFieldApiClient.ResultPayload): SyncResult = withContext(Dispatchers.IO) {
+        try {
+            when (config.type) {
+                UploadTargetType.REST_JSON -> post(gson.toJson(payload))
+                UploadTargetType.OPENTRACK -> post(gson.toJson(openTrackBody(payload)))
+                UploadTargetType.WEBHOOK -> post(gson.toJson(webhookBody(payload)), sign = true)
+                UploadTargetType.FIELDLYNX -> sendLines(fieldLynxLines(payload))
+            }
+            SyncResult(true, "Sent ${payload.athleteBib} to $name")
+        } catch (e: Exception) {
+            Log.w(TAG, "Upload of ${payload.athleteBib} to $name failed: ${e.message}")
+            SyncResult(false, e.message ?: "Upload failed")
+        }
+    }
+
+    private fun openTrackBody(payload: PolyFieldApiClient.ResultPayload): Map<String, Any?> = mapOf(
+        "competitionId" to config.competitionId,
+        "eventId" to payload.eventId,
+        "bib" to payload.athleteBib,
+        "trials" to payload.series.map { performance ->
+            mapOf(
+                "attempt" to performance.attempt,
+                "result" to trialMark(performance),
+                "wind" to performance.wind
+            )
+        }
+    )
+
+    private fun webhookBody(payload: PolyFieldApiClient.ResultPayload): Map<String, Any?> = mapOf(
+        "type" to "result",
+        "sentAt" to SessionSources.now(),
+        "result" to payload
+    )
+
+    // eventId,bib,attempt,mark,wind - fouls as x and passes as -, as on a field card
+    private fun fieldLynxLines(payload: PolyFieldApiClient.ResultPayload): List<String> =
+        payload.series.map { performance ->
+            listOf(
+                payload.eventId,
+                payload.athleteBib,
+                performance.attempt.toString(),
+                trialMark(performance),
+                performance.wind.orEmpty()
+            ).joinToString(",") { it.replace(",", " ") }
+        }
+
+    private fun trialMark(performance: PolyFieldApiClient.Performance): String = when (performance.mark) {
+        "FOUL" -> "x"
+        "PASS" -> "-"
+        else -> performance.mark
+    }
+
+    private fun post(json: String, sign: Boolean = false) {
+        val body = json.toByteArray(Charsets.UTF_8)
+        val connection = URL(config.url).openConnection() as HttpURLConnection
+        try {
+            connection.requestMethod = "POST"
+            connection.connectTimeout = CONNECTION_TIMEOUT
+            connection.readTimeout = READ_TIMEOUT
+            connection.setRequestProperty("Content-Type", "application/json")
+            config.token?.takeIf { it.isNotBlank() }?.let { token ->
+                if (sign) {
+                    connection.setRequestProperty("X-PolyField-Signature", "sha256=" + hmacHex(token, body))
+                } else {
+                    connection.setRequestProperty("Authorization", "Bearer $token")
+                }
+            }
+            connection.doOutput = true
+            connection.setFixedLengthStreamingMode(body.size)
+            connection.outputStream.use { it.write(body) }
+
+            val responseCode = connection.responseCode
+            if (responseCode !in 200..299) {
+                val error = connection.errorStream?.bufferedReader()?.readText()
+                throw IOException("HTTP $responseCode: $error")
+            }
+        } finally {
+            connection.disconnect()
+        }
+    }
+
+    private fun sendLines(lines: List<String>) {
+        val uri = URI(config.url)
+        val host = uri.host ?: throw IOException("No host in ${config.url}")
+        if (uri.port == -1) throw IOException("No port in ${config.url}")
+        Socket().use { socket ->
+            socket.connect(InetSocketAddress(host, uri.port), CONNECTION_TIMEOUT)
+            socket.soTimeout = READ_TIMEOUT
+            socket.getOutputStream().apply {
+                write(lines.joinToString("\r\n", postfix = "\r\n").toByteArray(Charsets.US_ASCII))
+                flush()
+            }
+        }
+    }
+
+    private fun hmacHex(secret: String, body: ByteArray): String {
+        val mac = Mac.getInstance("HmacSHA256")
+        mac.init(SecretKeySpec(secret.toByteArray(Charsets.UTF_8), "HmacSHA256"))
+        return mac.doFinal(body).joinToString("") { "%02x".format(it) }
+    }
+}
+
+/**
+ * Upload Target Manager
+ * Sends every result to all enabled targets at once, each independently: a scoreboard
+ * that is down does not hold up the live feed. A target that fails keeps the athlete's
+ * latest series and resends it before its next upload; since each payload is the whole
+ * series, only the newest per athlete needs keeping. What each target still owes is kept
+ * on disk so it survives an app restart.
+ */
+class UploadTargetManager(context: Context) {
+
+    companion object {
+        private const val TAG = "UploadTargetManager"
+        private const val PREFS_NAME = "polyfield_upload_target_prefs"
+        private const val PREF_TARGETS = "upload_targets"
+        private const val PENDING_FILE_NAME = "polyfield_upload_pending.json"
+    }
+
+    private val preferences = context.getSharedPreferences(PREFS_NAME, Context.MODE_PRIVATE)
+    private val gson = Gson()
+    private val pendingFile = File(context.filesDir, PENDING_FILE_NAME)
+
+    @Volatile private var configs: List<UploadTargetConfig> = loadConfigs()
+    private val customTargets = ConcurrentHashMap<String, UploadTarget>()
+    private val statuses = ConcurrentHashMap<String, UploadTargetStatus>()
+    private val pending = ConcurrentHashMap(loadPending())
+    private val locks = ConcurrentHashMap<String, Mutex>()
+
+    val hasTargets: Boolean
+        get() = configs.any { it.enabled } || customTargets.isNotEmpty()
+
+    fun getConfigs(): List<UploadTargetConfig> = configs
+
+    /**
+     * Add a target, or replace the one with the same id
+     */
+    fun saveConfig(config: UploadTargetConfig) {
+        configs = configs.filter { it.id != config.id } + config
+        if (!config.enabled && pending.remove(config.id) != null) storePending()
+        storeConfigs()
+        Log.d(TAG, "Upload target ${config.id} saved (${config.type})")
+    }
+
+    fun removeConfig(targetId: String) {
+        configs = configs.filter { it.id != targetId }
+        forget(targetId)
+        storeConfigs()
+    }
+
+    /**
+     * Register a target implemented in code rather than configured, e.g. an in-venue display
+     */
+    fun addTarget(target: UploadTarget) {
+        customTargets[target.id] = target
+    }
+
+    fun removeTarget(targetId: String) {
+        customTargets.remove(targetId)
+        forget(targetId)
+    }
+
+    fun getStatuses(): List<UploadTargetStatus> {
+        val configured = configs.map { config ->
+            status(config.id, ConfiguredUploadTarget(config).name).copy(enabled = config.enabled)
+        }
+        return configured + customTargets.values.map { status(it.id, it.name) }
+    }
+
+    /**
+     * Send an athlete's series to every enabled target, resending anything each still owes
+     * first. Returns each target's result for this payload.
+     */
+    suspend fun fanOut(payload: PolyFieldApiClient.ResultPayload): Map<String, SyncResult> = coroutineScope {
+        val targets = configs.filter { it.enabled }.map { ConfiguredUploadTarget(it) } + customTargets.values
+        targets.map { target ->
+            async { target.id to deliver(target, payload) }
+        }.awaitAll().toMap()
+    }
+
+    private suspend fun deliver(target: UploadTarget, payload: PolyFieldApiClient.ResultPayload): SyncResult {
+        val lock = locks.getOrPut(target.id) { Mutex() }
+        return lock.withLock {
+            val queue = pending.getOrPut(target.id) { LinkedHashMap() }
+            val key = "${payload.eventId}/${payload.athleteBib}"
+            synchronized(queue) {
+                queue.remove(key)
+                queue[key] = payload
+            }
+            storePending()
+
+            var last = SyncResult(true)
+            val toSend = synchronized(queue) { queue.entries.map { it.key to it.value } }
+            for ((queuedKey, queued) in toSend) {
+                last = target.upload(queued)
+                record(target, last)
+                if (!last.success) break
+                synchronized(queue) { queue.remove(queuedKey) }
+            }
+            storePending()
+            last
+        }
+    }
+
+    private fun record(target: UploadTarget, result: SyncResult) {
+        val now = SessionSources.now()
+        val previous = status(target.id, target.name)
+        statuses[target.id] = if (result.success) {
+            previous.copy(name = target.name, lastAttemptAt = now, lastSuccessAt = now, lastError = null,
+                delivered = previous.delivered + 1)
+        } else {
+            previous.copy(name = target.name, lastAttemptAt = now, lastError = result.message,
+                failed = previous.failed + 1)
+        }
+    }
+
+    private fun status(targetId: String, name: String): UploadTargetStatus {
+        val queued = pending[targetId]?.let { synchronized(it) { it.size } } ?: 0
+        return (statuses[targetId] ?: UploadTargetStatus(targetId, name, enabled = true)).copy(pending = queued)
+    }
+
+    private fun forget(targetId: String) {
+        statuses.remove(targetId)
+        if (pending.remove(targetId) != null) storePending()
+        locks.remove(targetId)
+    }
+
+    private fun storePending() {
+        val snapshot = pending.mapValues { (_, queue) -> synchronized(queue) { LinkedHashMap(queue) } }
+        synchronized(pendingFile) {
+            try {
+                pendingFile.writeText(gson.toJson(snapshot))
+            } catch (e: Exception) {
+                Log.e(TAG, "Error writing pending uploads: ${e.message}")
+            }
+        }
+    }
+
+    private fun loadPending(): Map<String, LinkedHashMap<String, PolyFieldApiClient.ResultPayload>> {
+        if (!pendingFile.exists()) return emptyMap()
+        return try {
+            val type = object : TypeToken<Map<String, LinkedHashMap<String, PolyFieldApiClient.ResultPayload>>>() {}.type
+            val loaded: Map<String, LinkedHashMap<String, PolyFieldApiClient.ResultPayload>>? = gson.fromJson(pendingFile.readText(), type)
+            loaded.orEmpty()
+        } catch (e: Exception) {
+            Log.e(TAG, "Invalid pending uploads: ${e.message}")
+            emptyMap()
+        }
+    }
+
+    private fun storeConfigs() {
+        preferences.edit().putString(PREF_TARGETS, gson.toJson(configs)).apply()
+    }
+
+    private fun loadConfigs(): List<UploadTargetConfig> {
+        val json = preferences.getString(PREF_TARGETS, null) ?: return emptyList()
+        return try {
+            val type = object : TypeToken<List<UploadTargetConfig>>() {}.type
+            val loaded: List<UploadTargetConfig> = gson.fromJson(json, type) ?: emptyList()
+            // Entries missing fields from older saves are dropped
+            loaded.filter { GsonFields.allSet(it.id, it.type) }
+        } catch (e: Exception) {
+            Log.e(TAG, "Invalid upload targets: ${e.message}")
+            emptyList()
+        }
+    }
+}
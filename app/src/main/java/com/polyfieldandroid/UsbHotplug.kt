@@ -0,0 +1,54 @@
+package com.polyfieldandroid
+
+import android.hardware.usb.UsbDevice
+
+/**
+ * Identity of a USB device as reported by the host on attach/detach
+ */
+data class UsbDeviceInfo(
+    val deviceName: String,
+    val vendorId: Int,
+    val productId: Int,
+    val serialNumber: String? = null,
+    val productName: String? = null
+) {
+    companion object {
+        fun from(device: UsbDevice): UsbDeviceInfo {
+            // Reading the serial number needs USB permission on Android 10+
+            val serialNumber = try {
+                device.serialNumber
+            } catch (e: SecurityException) {
+                null
+            }
+            return UsbDeviceInfo(
+                deviceName = device.deviceName,
+                vendorId = device.vendorId,
+                productId = device.productId,
+                serialNumber = serialNumber,
+                productName = device.productName
+            )
+        }
+    }
+}
+
+enum class ConnectionEventType {
+    ATTACHED,            // USB device plugged in
+    DETACHED,            // USB device unplugged
+    PERMISSION_REQUIRED, // Known device attached but USB permission not yet granted
+    CONNECTED,           // Device bound to a role
+    RESUMED,             // Previously connected role bound again after a replug
+    DISCONNECTED,        // Role lost its device
+    RECONNECTING,        // Lost connection being retried
+    CONNECT_FAILED       // Known device attached but could not be connected
+}
+
+/**
+ * Device connection event for observers such as the connection screen
+ */
+data class DeviceConnectionEvent(
+    val type: ConnectionEventType,
+    val deviceType: String? = null,
+    val address: String = "",
+    val message: String = "",
+    val timestamp: Long = SessionSources.now()
+)
@@ -0,0 +1,48 @@
+package com.polyfieldandroid
+
+import java.io.ByteArrayOutputStream
+import java.io.OutputStream
+
+/**
+ * USB Passthrough Link
+ * A USB device whose port is owned by something else - a host integration with its own USB
+ * stack - that only shuttles raw bytes: what the device sends is handed in with submit(),
+ * and what the app writes is collected for the host with takePendingWrite(). Everything
+ * above the bytes (driver parsing, double-read averaging, tolerance checks) runs here as
+ * for a port the app opens itself.
+ */
+class PassthroughLink(override val address: String) : StreamLink() {
+
+    companion object {
+        private const val MAX_BUFFERED_BYTES = 64 * 1024
+    }
+
+    private val received = ReceiveBuffer(MAX_BUFFERED_BYTES) { !closed }
+    override val source = received
+
+    private val outgoing = ByteArrayOutputStream()
+    override val sink: OutputStream = object : OutputStream() {
+        override fun write(b: Int) = synchronized(outgoing) { outgoing.write(b) }
+
+        override fun write(b: ByteArray, off: Int, len: Int) = synchronized(outgoing) { outgoing.write(b, off, len) }
+    }
+
+    /**
+     * Bytes read from the device by the host
+     */
+    fun submit(data: ByteArray) = received.append(data)
+
+    /**
+     * Bytes waiting to be written to the device, emptied by the call; empty when there are none
+     */
+    fun takePendingWrite(): ByteArray = synchronized(outgoing) {
+        outgoing.toByteArray().also { outgoing.reset() }
+    }
+
+    override fun closeChannel() {
+        synchronized(outgoing) { outgoing.reset() }
+    }
+
+    // The host owns the port; the link stays up until the device is disconnected here
+    override fun isChannelConnected(): Boolean = true
+}
@@ -0,0 +1,58 @@
+package com.polyfieldandroid
+
+import com.hoho.android.usbserial.driver.Ch34xSerialDriver
+import com.hoho.android.usbserial.driver.Cp21xxSerialDriver
+import com.hoho.android.usbserial.driver.FtdiSerialDriver
+import com.hoho.android.usbserial.driver.ProlificSerialDriver
+import com.hoho.android.usbserial.driver.UsbSerialDriver
+
+/**
+ * USB-to-serial bridge chipsets with known behavioural differences
+ */
+enum class UsbSerialChipset {
+    FTDI,
+    CH340,
+    PROLIFIC,
+    CP210X,
+    OTHER
+}
+
+/**
+ * Per-chipset handling applied when a port is opened
+ */
+data class ChipsetQuirks(
+    val latencyTimerMs: Int? = null,  // FTDI buffers up to 16ms by default before sending a short reply upstream
+    val assertDtr: Boolean = true,
+    val assertRts: Boolean = true,
+    val dtrWakePulseMs: Long = 0,     // Drop DTR for this long before raising it, for EDMs that wake on a DTR edge
+    val settleDelayMs: Long = 0,      // Wait after open before the first write
+    val lineEnding: String = "\r\n"   // Terminator for text commands
+)
+
+/**
+ * Chipset detection and quirk table for the bridge layer
+ */
+object UsbSerialQuirks {
+
+    private val quirks = mapOf(
+        UsbSerialChipset.FTDI to ChipsetQuirks(latencyTimerMs = 1),
+        // CH340 modem lines power up low; some EDMs only answer after seeing DTR rise
+        UsbSerialChipset.CH340 to ChipsetQuirks(dtrWakePulseMs = 100, settleDelayMs = 50),
+        // PL2303 clones can drop the first bytes written straight after open
+        UsbSerialChipset.PROLIFIC to ChipsetQuirks(settleDelayMs = 100),
+        UsbSerialChipset.CP210X to ChipsetQuirks(settleDelayMs = 20),
+        UsbSerialChipset.OTHER to ChipsetQuirks()
+    )
+
+    fun detect(driver: UsbSerialDriver): UsbSerialChipset = when (driver) {
+        is FtdiSerialDriver -> UsbSerialChipset.FTDI
+        is Ch34xSerialDriver -> UsbSerialChipset.CH340
+        is ProlificSerialDriver -> UsbSerialChipset.PROLIFIC
+        is Cp21xxSerialDriver -> UsbSerialChipset.CP210X
+        else -> UsbSerialChipset.OTHER
+    }
+
+    fun quirksFor(chipset: UsbSerialChipset): ChipsetQuirks = quirks[chipset] ?: ChipsetQuirks()
+
+    fun quirksFor(driver: UsbSerialDriver): ChipsetQuirks = quirksFor(detect(driver))
+}
@@ -0,0 +1,130 @@
+package com.polyfieldandroid
+
+import android.util.Log
+import kotlinx.coroutines.CoroutineScope
+import kotlinx.coroutines.Dispatchers
+import kotlinx.coroutines.Job
+import kotlinx.coroutines.SupervisorJob
+import kotlinx.coroutines.delay
+import kotlinx.coroutines.isActive
+import kotlinx.coroutines.launch
+import kotlin.math.atan2
+import kotlin.math.cos
+import kotlin.math.hypot
+import kotlin.math.roundToLong
+import kotlin.math.sin
+
+/**
+ * USB Simulator
+ * A pretend USB host and Mato MTS-602R+ behind the passthrough link, for emulators and
+ * bench testing with no USB host. It answers measurement commands only through
+ * getPendingUSBWrite and submitUSBData, so the driver parsing, double-read averaging and
+ * tolerance checks run exactly as for a real instrument. Reads close together are of the
+ * same prism with the same noise, so a double read agrees as it would on a real instrument;
+ * after a pause the prism has moved and a fresh error is drawn. With a fixed demo station
+ * the prism lands in the sector in front of the circle, so the picture is the same on every
+ * device.
+ */
+class UsbSimulator(private val edmModule: EDMModule, private val deviceType: String = "edm") {
+
+    companion object {
+        private const val TAG = "UsbSimulator"
+        const val DEVICE_NAME = "/dev/bus/usb/sim/001"
+        private const val DRIVER = "MATO_MTS602R"
+
+        private const val POLL_INTERVAL_MS = 20L
+        private const val MEASURE_TIME_MS = 400L     // A real MTS-602R+ takes about this long
+        private const val SAME_PRISM_WINDOW_MS = 3000L
+        private const val SECTOR_HALF_ANGLE_DEG = 17.46
+    }
+
+    private val scope = CoroutineScope(SupervisorJob() + Dispatchers.IO)
+    private var job: Job? = null
+
+    private val measurementCommand = EDMDeviceRegistry.findDeviceByKey(DRIVER)
+        ?.let { EDMDeviceRegistry.createTranslator(it) }
+        ?.getMeasurementCommand()
+
+    // Prism position in the station frame (metres), its measurement error and when it was
+    // last measured
+    private var prism = Pair(0.0, 0.0)
+    private var noise = Noise(0.0, 0.0, 0.0)
+    private var lastReadAt = 0L
+
+    private data class Noise(val distanceM: Double, val verticalDeg: Double, val horizontalDeg: Double)
+
+    fun isRunning(): Boolean = job?.isActive == true
+
+    /**
+     * Register the simulated device and start answering it
+     */
+    suspend fun start(): Map<String, Any> {
+        if (isRunning()) return mapOf("success" to true, "message" to "Simulator already running", "deviceType" to deviceType)
+        val result = edmModule.connectUSBPassthrough(deviceType, DEVICE_NAME, DRIVER)
+        if (result["success"] != true) return result
+
+        job = scope.launch {
+            while (isActive) {
+                val command = edmModule.getPendingUSBWrite(deviceType)
+                if (command.isEmpty()) {
+                    delay(POLL_INTERVAL_MS)
+                    continue
+                }
+                if (measurementCommand != null && command.contentEquals(measurementCommand)) {
+                    delay(MEASURE_TIME_MS)
+                    edmModule.submitUSBData(deviceType, respond().toByteArray(Charsets.US_ASCII))
+                } else {
+                    Log.d(TAG, "Ignoring command ${command.joinToString(" ") { "%02x".format(it) }}")
+                }
+            }
+        }
+        Log.d(TAG, "Simulated $DRIVER on $DEVICE_NAME as $deviceType")
+        return result
+    }
+
+    fun stop() {
+        job?.cancel()
+        job = null
+        edmModule.disconnectDevice(deviceType)
+        Log.d(TAG, "Simulator stopped")
+    }
+
+    /**
+     * Response in the MTS-602R+ format: "SSSSSSS VVVVVVV HHHHHHH 83", slope distance in mm,
+     * vertical angle from the zenith and horizontal angle in DDDMMSS
+     */
+    private fun respond(): String {
+        val geometry = edmModule.getDemoGeometry()
+        val now = SessionSources.now()
+        if (now - lastReadAt > SAME_PRISM_WINDOW_MS) {
+            // Somewhere a throw might land, 5-60 m out
+            val distance = 5.0 + SessionSources.random.nextDouble() * 55.0
+            prism = if (geometry.hasFixedStation) {
+                // In the sector in front of the circle, seen from the configured station
+                val station = geometry.station()
+                val bearing = Math.toRadians((SessionSources.random.nextDouble() * 2 - 1) * SECTOR_HALF_ANGLE_DEG)
+                Pair(distance * sin(bearing) - station.first, distance * cos(bearing) - station.second)
+            } else {
+                val bearing = Math.toRadians(SessionSources.random.nextDouble() * 360.0)
+                Pair(distance * cos(bearing), distance * sin(bearing))
+            }
+            // Drawn once per measurement; drawing it per read could split a double read
+            // beyond tolerance
+            noise = Noise(geometry.distanceNoiseM(), geometry.angleNoiseDeg(), geometry.angleNoiseDeg())
+        }
+        lastReadAt = now
+
+        val height = geometry.instrumentHeightM
+        val horizontal = hypot(prism.first, prism.second)
+        val slopeMm = ((hypot(horizontal, height) + noise.distanceM) * 1000).roundToLong()
+        val verticalDeg = 90.0 + Math.toDegrees(atan2(height, horizontal)) + noise.verticalDeg
+        val horizontalDeg = (Math.toDegrees(atan2(prism.second, prism.first)) + noise.horizontalDeg + 360.0) % 360.0
+
+        return "%07d %s %s 83\r\n".format(slopeMm, dddmmss(verticalDeg), dddmmss(horizontalDeg))
+    }
+
+    private fun dddmmss(angleDeg: Double): String {
+        val totalSeconds = (angleDeg * 3600).roundToLong()
+        return "%03d%02d%02d".format(totalSeconds / 3600, totalSeconds / 60 % 60, totalSeconds % 60)
+    }
+}
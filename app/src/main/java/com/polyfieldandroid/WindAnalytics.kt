@@ -0,0 +1,156 @@
+package com.polyfieldandroid
+
+import android.content.Context
+import android.util.Log
+import com.google.gson.Gson
+import java.io.File
+import kotlin.math.sqrt
+
+/**
+ * A valid mark measured with a wind reading
+ */
+data class WindMarkSample(
+    val athleteBib: String,
+    val athleteName: String,
+    val distance: Double,   // Metres
+    val windSpeed: Double,  // m/s along the throwing direction, + tailwind
+    val eventName: String,
+    val timestamp: Long = SessionSources.now()
+)
+
+/**
+ * How one athlete's marks varied with the wind over a period
+ */
+data class WindCorrelation(
+    val athleteBib: String,
+    val athleteName: String,
+    val samples: Int,
+    val from: Long,
+    val to: Long,
+    val meanWind: Double,
+    val meanDistance: Double,
+    val correlation: Double?,       // Pearson r; null when the wind did not vary
+    val slopeMPerMs: Double?,       // Metres gained per m/s of tailwind
+    val bestMark: Double,
+    val bestMarkWind: Double,
+    val windAdjustedBest: Double?,  // Best mark with the fitted wind effect removed
+    val tailwindShare: Double       // Fraction of marks made with a tailwind
+) {
+    fun toMap(): Map<String, Any?> = mapOf(
+        "athleteBib" to athleteBib,
+        "athleteName" to athleteName,
+        "samples" to samples,
+        "from" to from,
+        "to" to to,
+        "meanWind" to meanWind,
+        "meanDistance" to meanDistance,
+        "correlation" to correlation,
+        "slopeMPerMs" to slopeMPerMs,
+        "bestMark" to bestMark,
+        "bestMarkWind" to bestMarkWind,
+        "windAdjustedBest" to windAdjustedBest,
+        "tailwindShare" to tailwindShare
+    )
+}
+
+/**
+ * Wind Performance Analysis
+ * Least-squares fit of distance against wind component for one athlete. With few marks
+ * or little spread in the wind the fit says more about day-to-day form than about wind,
+ * so no fit is made below MIN_SAMPLES marks or MIN_WIND_SPREAD of wind range.
+ */
+object WindPerformanceAnalysis {
+
+    const val MIN_SAMPLES = 5
+    const val MIN_WIND_SPREAD = 0.5 // m/s
+
+    fun analyse(samples: List<WindMarkSample>): WindCorrelation? {
+        if (samples.isEmpty()) return null
+        val winds = samples.map { it.windSpeed }
+        val distances = samples.map { it.distance }
+        val meanWind = winds.average()
+        val meanDistance = distances.average()
+
+        val sxx = winds.sumOf { (it - meanWind) * (it - meanWind) }
+        val syy = distances.sumOf { (it - meanDistance) * (it - meanDistance) }
+        val sxy = samples.sumOf { (it.windSpeed - meanWind) * (it.distance - meanDistance) }
+
+        val fits = samples.size >= MIN_SAMPLES && (winds.maxOrNull()!! - winds.minOrNull()!!) >= MIN_WIND_SPREAD && sxx > 0.0
+        val slope = if (fits) sxy / sxx else null
+        val correlation = if (fits && syy > 0.0) sxy / sqrt(sxx * syy) else null
+
+        val best = samples.maxByOrNull { it.distance }!!
+        val latest = samples.maxByOrNull { it.timestamp }!!
+        return WindCorrelation(
+            athleteBib = best.athleteBib,
+            athleteName = latest.athleteName,
+            samples = samples.size,
+            from = samples.minOf { it.timestamp },
+            to = latest.timestamp,
+            meanWind = meanWind,
+            meanDistance = meanDistance,
+            correlation = correlation,
+            slopeMPerMs = slope,
+            bestMark = best.distance,
+            bestMarkWind = best.windSpeed,
+            windAdjustedBest = slope?.let { best.distance - it * best.windSpeed },
+            tailwindShare = samples.count { it.windSpeed > 0.0 }.toDouble() / samples.size
+        )
+    }
+}
+
+/**
+ * Wind Performance Log
+ * Marks with wind readings kept across sessions so a training block can be analysed;
+ * unlike the conditions log it is not cleared when a competition starts
+ */
+class WindPerformanceLog(context: Context) {
+
+    companion object {
+        private const val TAG = "WindPerformanceLog"
+        private const val FILE_NAME = "wind_marks.jsonl"
+        private val lock = Any()
+    }
+
+    private val file = File(context.applicationContext.filesDir, FILE_NAME)
+    private val gson = Gson()
+
+    fun append(sample: WindMarkSample) {
+        if (sample.windSpeed.isNaN() || sample.distance <= 0.0) return
+        synchronized(lock) {
+            try {
+                file.appendText(gson.toJson(sample) + "\n", Charsets.UTF_8)
+            } catch (e: Exception) {
+                Log.e(TAG, "Failed to record wind mark: ${e.message}")
+            }
+        }
+    }
+
+    fun getSamples(from: Long? = null, to: Long? = null): List<WindMarkSample> {
+        // The log spans sessions; filter outside the lock so marks can still be appended
+        val lines = synchronized(lock) {
+            if (file.exists()) file.readLines(Charsets.UTF_8) else emptyList()
+        }
+
+        val samples = mutableListOf<WindMarkSample>()
+        lines.forEach { line ->
+            if (line.isBlank()) return@forEach
+            try {
+                gson.fromJson(line, WindMarkSample::class.java)
+                    ?.takeIf { (from == null || it.timestamp >= from) && (to == null || it.timestamp <= to) }
+                    ?.let { samples.add(it) }
+            } catch (e: Exception) {
+                Log.w(TAG, "Skipping unreadable entry: ${e.message}")
+            }
+        }
+        return samples
+    }
+
+    fun clear() {
+        synchronized(lock) {
+            if (file.exists() && !file.delete()) {
+                Log.e(TAG, "Could not delete ${file.name}")
+            }
+        }
+    }
+}
@@ -0,0 +1,256 @@
+package com.polyfieldandroid
+
+import android.util.Log
+import kotlinx.coroutines.CoroutineScope
+import kotlinx.coroutines.Dispatchers
+import kotlinx.coroutines.Job
+import kotlinx.coroutines.SupervisorJob
+import kotlinx.coroutines.cancel
+import kotlinx.coroutines.delay
+import kotlinx.coroutines.isActive
+import kotlinx.coroutines.launch
+
+/**
+ * How the listener gets readings from a gauge
+ */
+enum class WindListenMode {
+    PASSIVE, // Gauge streams readings continuously; the listener just reads lines
+    POLLED   // Gauge only answers a query; the listener sends one every poll interval
+}
+
+/**
+ * Per-gauge listening configuration, keyed by wind gauge protocol type.
+ * Polled is the default because every supported protocol has a read query.
+ */
+data class WindGaugeConfig(
+    val mode: WindListenMode = WindListenMode.POLLED,
+    val pollCommand: String? = null, // Raw query text; null uses the protocol's read command
+    val pollIntervalMs: Long = 1000,
+    val sampleIntervalMs: Long = 1000, // Buffer resolution; faster gauges are downsampled to this
+    val retentionSeconds: Int = 120    // How much history the buffer keeps
+) {
+    /**
+     * Number of buffered samples needed to cover the retention period
+     */
+    val windBufferSize: Int
+        get() = ((retentionSeconds * 1000L + sampleIntervalMs - 1) / sampleIntervalMs).toInt().coerceAtLeast(1)
+}
+
+/**
+ * One buffered sample: the time-weighted mean over the interval ending at timestamp
+ */
+data class WindSample(
+    val timestamp: Long,
+    val windSpeed: Double,
+    val windDirection: Int? = null,
+    val durationMs: Long = 0
+)
+
+/**
+ * Wind Gauge Listener
+ * Keeps a rolling buffer of recent readings from a network wind gauge so a
+ * measurement can use the readings around the moment of the attempt
+ */
+class WindGaugeListener(
+    private val networkDeviceModule: NetworkDeviceModule,
+    val deviceId: String,
+    val gaugeType: String,
+    private val clockOffsets: DeviceClockOffsets? = null
+) {
+
+    companion object {
+        private const val TAG = "WindGaugeListener"
+        private const val ERROR_BACKOFF_MS = 1000L
+        private const val MIN_HOLD_MS = 2000L
+    }
+
+    private val scope = CoroutineScope(Dispatchers.IO + SupervisorJob())
+    private var job: Job? = null
+    private val buffer = ArrayDeque<WindSample>()
+    private val lock = Any()
+
+    // Downsampling state: the last reading is held until the next one arrives, and its
+    // speed is integrated over that time into fixed buckets of sampleIntervalMs
+    private var heldSpeed: Double? = null
+    private var heldDirection: Int? = null
+    private var heldSince = 0L
+    private var bucketStart = 0L
+    private var bucketWeightedSum = 0.0
+    private var bucketCoveredMs = 0L
+
+    @Volatile var config: WindGaugeConfig = WindGaugeConfig()
+        private set
+
+    val isRunning: Boolean
+        get() = job?.isActive == true
+
+    fun start(gaugeConfig: WindGaugeConfig) {
+        stop()
+        synchronized(lock) {
+            config = gaugeConfig
+            resetDownsampling()
+        }
+        Log.d(TAG, "Listening to $deviceId in ${gaugeConfig.mode} mode")
+
+        job = scope.launch {
+            while (isActive && networkDeviceModule.isConnected(deviceId)) {
+                val tickStart = System.currentTimeMillis()
+                val response = when (gaugeConfig.mode) {
+                    WindListenMode.PASSIVE -> networkDeviceModule.readMessage(
+                        deviceId,
+                        DeviceCommand(type = "READ_WIND")
+                    )
+                    WindListenMode.POLLED -> networkDeviceModule.sendCommand(deviceId, pollCommand(gaugeConfig))
+                }
+
+                if (response.success) {
+                    // Stamped output is placed by the gauge's own clock, corrected to the tablet's
+                    val receivedAt = System.currentTimeMillis()
+                    val deviceTime = response.data["deviceTimestamp"] as? Long
+                    deviceTime?.let { clockOffsets?.record(deviceId, it, receivedAt) }
+                    (response.data["windSpeed"] as? Double)?.let { speed ->
+                        val readAt = deviceTime?.let { clockOffsets?.toLocalTime(deviceId, it)?.coerceAtMost(receivedAt) } ?: receivedAt
+                        onReading(readAt, speed, response.data["windDirection"] as? Int)
+                    }
+                }
+
+                when {
+                    gaugeConfig.mode == WindListenMode.POLLED -> {
+                        val elapsed = System.currentTimeMillis() - tickStart
+                        delay((gaugeConfig.pollIntervalMs - elapsed).coerceAtLeast(0))
+                    }
+                    !response.success -> delay(ERROR_BACKOFF_MS)
+                }
+            }
+            Log.d(TAG, "Listener for $deviceId stopped")
+        }
+    }
+
+    fun stop() {
+        job?.cancel()
+        job = null
+    }
+
+    /**
+     * Stop listening and release the coroutine scope
+     */
+    fun shutdown() {
+        stop()
+        scope.cancel()
+        synchronized(lock) {
+            buffer.clear()
+            resetDownsampling()
+        }
+    }
+
+    fun getSamples(): List<WindSample> = synchronized(lock) { buffer.toList() }
+
+    /**
+     * Time-weighted mean wind speed over the most recent window, including the
+     * bucket still being filled, or null if no readings fall inside it
+     */
+    fun averageOver(windowSeconds: Int, now: Long = System.currentTimeMillis()): Double? {
+        val cutoff = now - windowSeconds * 1000L
+        var weightedSum = 0.0
+        var coveredMs = 0L
+
+        synchronized(lock) {
+            for (sample in buffer) {
+                if (sample.timestamp <= cutoff) continue
+                val duration = sample.durationMs.coerceAtLeast(1)
+                weightedSum += sample.windSpeed * duration
+                coveredMs += duration
+            }
+            if (bucketCoveredMs > 0) {
+                weightedSum += bucketWeightedSum
+                coveredMs += bucketCoveredMs
+            }
+            // The held reading still counts up to now, within the hold limit
+            heldSpeed?.let { speed ->
+                val heldMs = (minOf(now, heldSince + holdLimitMs()) - maxOf(heldSince, cutoff)).coerceAtLeast(0)
+                weightedSum += speed * heldMs
+                coveredMs += heldMs
+            }
+        }
+
+        return if (coveredMs == 0L) null else weightedSum / coveredMs
+    }
+
+    /**
+     * Take a reading that did not come from this listener's own link, e.g. a simulated gauge
+     */
+    fun submitReading(timestamp: Long, speed: Double, direction: Int? = null) = onReading(timestamp, speed, direction)
+
+    private fun onReading(timestamp: Long, speed: Double, direction: Int?) {
+        synchronized(lock) {
+            heldSpeed?.let { previous ->
+                integrate(heldSince, minOf(timestamp, heldSince + holdLimitMs()), previous)
+            }
+            heldSpeed = speed
+            heldDirection = direction
+            heldSince = timestamp
+        }
+    }
+
+    /**
+     * Spread a held reading over the buckets it spans, flushing each completed bucket
+     */
+    private fun integrate(from: Long, to: Long, speed: Double) {
+        val interval = config.sampleIntervalMs.coerceAtLeast(100)
+        var cursor = from
+        while (cursor < to) {
+            if (bucketStart == 0L || cursor >= bucketStart + interval) {
+                flushBucket(interval)
+                bucketStart = cursor - cursor % interval
+            }
+            val segmentEnd = minOf(to, bucketStart + interval)
+            bucketWeightedSum += speed * (segmentEnd - cursor)
+            bucketCoveredMs += segmentEnd - cursor
+            cursor = segmentEnd
+        }
+    }
+
+    private fun flushBucket(interval: Long) {
+        if (bucketCoveredMs > 0) {
+            buffer.addLast(
+                WindSample(
+                    timestamp = bucketStart + interval,
+                    windSpeed = bucketWeightedSum / bucketCoveredMs,
+                    windDirection = heldDirection,
+                    durationMs = bucketCoveredMs
+                )
+            )
+            while (buffer.size > config.windBufferSize) {
+                buffer.removeFirst()
+            }
+        }
+        bucketWeightedSum = 0.0
+        bucketCoveredMs = 0L
+    }
+
+    /**
+     * Longest a single reading is trusted to represent the wind before it counts as a dropout
+     */
+    private fun holdLimitMs(): Long {
+        val current = config
+        return if (current.mode == WindListenMode.POLLED) {
+            maxOf(MIN_HOLD_MS, current.pollIntervalMs * 2)
+        } else {
+            MIN_HOLD_MS
+        }
+    }
+
+    private fun resetDownsampling() {
+        heldSpeed = null
+        heldDirection = null
+        heldSince = 0L
+        bucketStart = 0L
+        bucketWeightedSum = 0.0
+        bucketCoveredMs = 0L
+    }
+
+    private fun pollCommand(gaugeConfig: WindGaugeConfig): DeviceCommand {
+        val parameters = gaugeConfig.pollCommand?.let { mapOf("command" to it) } ?: emptyMap()
+        return DeviceCommand(type = "READ_WIND", parameters = parameters, expectResponse = true)
+    }
+}
@@ -0,0 +1,89 @@
+package com.polyfieldandroid
+
+import android.content.Context
+import android.util.Log
+import com.google.gson.Gson
+import java.util.Locale
+
+/**
+ * Where the wind gauge was set up for the event, as the rules require it to be recorded
+ * for horizontal jumps: 1.22 m ± 0.05 m high, no more than 2 m from the runway and 20 m
+ * from the take-off line
+ */
+data class WindGaugePlacement(
+    val heightM: Double,
+    val distanceFromRunwayM: Double,
+    val distanceFromTakeOffM: Double? = null,
+    val recordedAt: Long = SessionSources.now()
+) {
+    companion object {
+        const val HEIGHT_M = 1.22
+        const val HEIGHT_TOLERANCE_M = 0.05
+        const val MAX_DISTANCE_FROM_RUNWAY_M = 2.0
+        const val DISTANCE_FROM_TAKE_OFF_M = 20.0
+
+        // Allowance for taping the 20 m out; the rule gives the distance without a tolerance
+        const val TAKE_OFF_LAYOUT_TOLERANCE_M = 0.10
+    }
+
+    /**
+     * Ways the placement falls outside the rules; empty when it complies
+     */
+    fun problems(): List<String> {
+        val problems = mutableListOf<String>()
+        if (kotlin.math.abs(heightM - HEIGHT_M) > HEIGHT_TOLERANCE_M + 1e-9) {
+            problems.add("Gauge height ${format(heightM)} m is outside ${format(HEIGHT_M)} ± ${format(HEIGHT_TOLERANCE_M)} m")
+        }
+        if (distanceFromRunwayM < 0 || distanceFromRunwayM > MAX_DISTANCE_FROM_RUNWAY_M + 1e-9) {
+            problems.add("Gauge is ${format(distanceFromRunwayM)} m from the runway; it must be within ${format(MAX_DISTANCE_FROM_RUNWAY_M)} m")
+        }
+        distanceFromTakeOffM?.let { distance ->
+            if (kotlin.math.abs(distance - DISTANCE_FROM_TAKE_OFF_M) > TAKE_OFF_LAYOUT_TOLERANCE_M + 1e-9) {
+                problems.add("Gauge is ${format(distance)} m from the take-off line; it must be ${format(DISTANCE_FROM_TAKE_OFF_M)} m")
+            }
+        }
+        return problems
+    }
+
+    fun isCompliant(): Boolean = problems().isEmpty()
+
+    /**
+     * One line for reports, e.g. "1.22 m high, 1.50 m from runway, 20.00 m from take-off"
+     */
+    fun describe(): String = buildString {
+        append("${format(heightM)} m high, ${format(distanceFromRunwayM)} m from runway")
+        distanceFromTakeOffM?.let { append(", ${format(it)} m from take-off") }
+    }
+
+    private fun format(metres: Double): String = String.format(Locale.US, "%.2f", metres)
+}
+
+/**
+ * Keeps the event's wind gauge placement across app restarts
+ */
+class WindGaugePlacementStore(context: Context) {
+
+    companion object {
+        private const val TAG = "WindGaugePlacement"
+        private const val PREFS_NAME = "polyfield_wind_gauge_placement"
+        private const val PREF_PLACEMENT = "placement"
+    }
+
+    private val preferences = context.getSharedPreferences(PREFS_NAME, Context.MODE_PRIVATE)
+    private val gson = Gson()
+
+    fun load(): WindGaugePlacement? {
+        return try {
+            preferences.getString(PREF_PLACEMENT, null)?.let { gson.fromJson(it, WindGaugePlacement::class.java) }
+        } catch (e: Exception) {
+            Log.e(TAG, "Error loading wind gauge placement: ${e.message}")
+            null
+        }
+    }
+
+    fun save(placement: WindGaugePlacement?) {
+        preferences.edit().apply {
+            if (placement == null) remove(PREF_PLACEMENT) else putString(PREF_PLACEMENT, gson.toJson(placement))
+        }.apply()
+    }
+}
@@ -73,7 +73,8 @@ class WindGaugeProtocol(
      */
     override fun encodeCommand(command: DeviceCommand): String {
         return when (command.type) {
-            "READ_WIND" -> encodeReadCommand()
+            "READ_WIND" -> (command.parameters["command"] as? String)?.let { encodeRawCommand(it) }
+                ?: encodeReadCommand()
             "SET_AVERAGING" -> encodeAveragingCommand(command.parameters)
             "RESET" -> encodeResetCommand()
             else -> {
@@ -83,6 +84,11 @@ class WindGaugeProtocol(
         }
     }
 
+    /**
+     * A reading changes nothing on the gauge
+     */
+    override fun pingCommand(): DeviceCommand = DeviceCommand(type = "READ_WIND")
+
     /**
      * Read response from wind gauge
      */
@@ -169,6 +175,13 @@ class WindGaugeProtocol(
         }
     }
 
+    /**
+     * Encode a configured poll query, terminated with CR/LF if not already
+     */
+    private fun encodeRawCommand(text: String): String {
+        return if (text.endsWith("\n") || text.endsWith("\r")) text else "$text\r\n"
+    }
+
     /**
      * Encode averaging period command
      */
@@ -281,6 +294,7 @@ class WindGaugeProtocol(
      * Decode NMEA-style response
      * Format: "$WIMWV,123.4,R,2.3,M,A*checksum\r\n"
      * Fields: Sentence ID, Wind Angle, Reference, Wind Speed, Units, Status, Checksum
+     * Sensors with a clock also send ZDA time sentences, decoded as a device timestamp
      */
     private fun decodeNMEAResponse(response: String): DeviceResponse {
         val trimmed = response.trim()
@@ -289,6 +303,10 @@ class WindGaugeProtocol(
         val sentence = trimmed.removePrefix("$").split("*")[0]
         val parts = sentence.split(",")
 
+        if (parts[0].endsWith("ZDA")) {
+            return decodeNMEATime(parts, response)
+        }
+
         if (parts.size < 6) {
             return DeviceResponse(
                 success = false,
@@ -317,6 +335,42 @@ class WindGaugeProtocol(
             )
         )
     }
+
+    /**
+     * Decode an NMEA ZDA time sentence
+     * Format: "$WIZDA,hhmmss.ss,dd,mm,yyyy,zh,zm*checksum", UTC
+     */
+    private fun decodeNMEATime(parts: List<String>, response: String): DeviceResponse {
+        if (parts.size < 5 || parts[1].length < 6) {
+            return DeviceResponse(
+                success = false,
+                error = "Invalid NMEA time format"
+            )
+        }
+
+        val time = parts[1]
+        val calendar = java.util.Calendar.getInstance(java.util.TimeZone.getTimeZone("UTC")).apply {
+            clear()
+            set(
+                parts[4].toInt(),
+                parts[3].toInt() - 1,
+                parts[2].toInt(),
+                time.substring(0, 2).toInt(),
+                time.substring(2, 4).toInt(),
+                time.substring(4, 6).toInt()
+            )
+        }
+        val fractionMs = time.substringAfter('.', "").takeIf { it.isNotEmpty() }
+            ?.let { ("0.$it".toDouble() * 1000).toLong() } ?: 0L
+
+        return DeviceResponse(
+            success = true,
+            data = mapOf(
+                "deviceTimestamp" to calendar.timeInMillis + fractionMs,
+                "rawResponse" to response
+            )
+        )
+    }
 }
 
 /**
@@ -0,0 +1,123 @@
+package com.polyfieldandroid.mockedm
+
+import com.polyfieldandroid.EDMDeviceRegistry
+import com.polyfieldandroid.ScoreboardProtocol
+import com.polyfieldandroid.WindGaugeProtocol
+import org.json.JSONArray
+import org.json.JSONException
+import org.json.JSONObject
+
+/**
+ * What a scripted response does besides, or instead of, answering
+ */
+enum class MockFault {
+    NONE,       // Answer normally
+    SILENT,     // Swallow the request; the app sees a read timeout
+    GARBAGE,    // Answer with bytes no driver can parse
+    TRUNCATE,   // Send the first half of the answer with no line end
+    DISCONNECT  // Drop the connection without answering
+}
+
+/**
+ * One scripted exchange. A request line matching the pattern is answered with the next of
+ * responses, in turn, after delayMs. With a count the rule applies to that many matching
+ * requests and then falls through to the rules after it, so "the first two reads time out"
+ * is a SILENT rule with a count of 2.
+ */
+data class MockRule(
+    val match: Regex,
+    val responses: List<String>,
+    val delayMs: Long,
+    val fault: MockFault,
+    val count: Int?
+)
+
+/**
+ * Mock EDM Scenario
+ * The device the server pretends to be and its script, from JSON:
+ *
+ *   {
+ *     "device": "edm",              // "edm", "wind" or "scoreboard"
+ *     "driver": "MATO_MTS602R",     // EDM registry key, wind gauge type or scoreboard type;
+ *                                   // defaults to MATO_MTS602R for an EDM, else GENERIC
+ *     "telnet": false,              // Answer RFC 2217 negotiation, for connectRFC2217Device
+ *     "delayMs": 0,                 // Before every answer without a delay of its own
+ *     "rules": [
+ *       {"match": "^\\u0011$", "responses": ["0012345 0901000 0451230 83"], "delayMs": 400},
+ *       {"match": ".*", "fault": "SILENT", "count": 2}
+ *     ]
+ *   }
+ *
+ * Requests no rule matches get the device's own answer where the server knows it: a
+ * random reading for the Mato MTS-602R+, a wind reading in the gauge's format, or the
+ * scoreboard's acknowledgement. Other EDM drivers need their replies scripted.
+ */
+data class MockEDMScenario(
+    val device: String,
+    val driver: String,
+    val telnet: Boolean,
+    val delayMs: Long,
+    val rules: List<MockRule>
+) {
+    companion object {
+        private val DEVICES = setOf("edm", "wind", "scoreboard")
+
+        /**
+         * Throws IllegalArgumentException naming what is wrong with the scenario
+         */
+        fun parse(json: String): MockEDMScenario {
+            val root = try {
+                JSONObject(json.ifBlank { "{}" })
+            } catch (e: JSONException) {
+                throw IllegalArgumentException("Scenario is not valid JSON: ${e.message}", e)
+            }
+
+            val device = root.optString("device", "edm").lowercase()
+            require(device in DEVICES) { "Unknown device \"$device\"; expected one of $DEVICES" }
+
+            val driver = root.optString("driver").ifEmpty { if (device == "edm") "MATO_MTS602R" else "GENERIC" }
+            val known = when (device) {
+                "edm" -> EDMDeviceRegistry.findDeviceByKey(driver) != null
+                "wind" -> WindGaugeProtocol.WindGaugeType.values().any { it.name == driver }
+                else -> driver == "DAKTRONICS" || ScoreboardProtocol.ScoreboardType.values().any { it.name == driver }
+            }
+            require(known) { "Unknown $device driver \"$driver\"" }
+
+            val delayMs = root.optLong("delayMs", 0)
+            require(delayMs >= 0) { "delayMs cannot be negative" }
+
+            return MockEDMScenario(
+                device = device,
+                driver = driver,
+                telnet = root.optBoolean("telnet", false),
+                delayMs = delayMs,
+                rules = parseRules(root.optJSONArray("rules") ?: JSONArray(), delayMs)
+            )
+        }
+
+        private fun parseRules(array: JSONArray, defaultDelayMs: Long): List<MockRule> =
+            (0 until array.length()).map { index ->
+                val rule = array.optJSONObject(index)
+                    ?: throw IllegalArgumentException("Rule $index is not a JSON object")
+                val match = try {
+                    Regex(rule.optString("match", ".*"))
+                } catch (e: IllegalArgumentException) {
+                    throw IllegalArgumentException("Rule $index: bad match pattern: ${e.message}", e)
+                }
+                val fault = rule.optString("fault", MockFault.NONE.name).uppercase().let { name ->
+                    MockFault.values().find { it.name == name }
+                        ?: throw IllegalArgumentException("Rule $index: unknown fault \"$name\"")
+                }
+                val responses = rule.optJSONArray("responses")
+                    ?.let { list -> (0 until list.length()).map { list.optString(it) } }
+                    ?: listOfNotNull(rule.optString("response").takeIf { rule.has("response") })
+                require(responses.isNotEmpty() || fault != MockFault.NONE) {
+                    "Rule $index: needs a response or a fault"
+                }
+                val count = if (rule.has("count")) rule.optInt("count", 0) else null
+                require(count == null || count > 0) { "Rule $index: count must be positive" }
+
+                MockRule(match, responses, rule.optLong("delayMs", defaultDelayMs), fault, count)
+            }
+    }
+}
@@ -0,0 +1,347 @@
+package com.polyfieldandroid.mockedm
+
+import android.util.Log
+import com.polyfieldandroid.SessionSources
+import kotlinx.coroutines.CoroutineScope
+import kotlinx.coroutines.Dispatchers
+import kotlinx.coroutines.Job
+import kotlinx.coroutines.SupervisorJob
+import kotlinx.coroutines.cancel
+import kotlinx.coroutines.delay
+import kotlinx.coroutines.isActive
+import kotlinx.coroutines.launch
+import java.io.ByteArrayOutputStream
+import java.io.IOException
+import java.io.OutputStream
+import java.net.ServerSocket
+import java.net.Socket
+import java.util.Locale
+import kotlin.math.atan2
+import kotlin.math.hypot
+import kotlin.math.roundToLong
+
+/**
+ * Start a mock device on port (0 for any free port; see MockEDMServer.port) playing the
+ * scenario. Throws IllegalArgumentException for a bad scenario and IOException if the port
+ * cannot be bound.
+ */
+fun startMockEDMServer(port: Int, scenarioJson: String): MockEDMServer =
+    MockEDMServer(MockEDMScenario.parse(scenarioJson), port).apply { start() }
+
+/**
+ * Mock EDM Server
+ * A pretend EDM, wind gauge or scoreboard on a TCP port, for exercising connectNetworkDevice
+ * (and, with telnet on, connectRFC2217Device for EDMs) end to end on a bench or in CI with
+ * no hardware. Each request (a line, or for Topcon a command ended by ETX) is answered from
+ * the scenario's rules, with their delays and injected faults, else with the device's own
+ * answer. Every request is kept so a test can check what the app sent, e.g. the lines a
+ * scoreboard was given.
+ */
+class MockEDMServer(val scenario: MockEDMScenario, private val requestedPort: Int = 0) {
+
+    companion object {
+        private const val TAG = "MockEDMServer"
+        private const val MAX_LINE_BYTES = 4096 // Binary protocols with no line end are taken in chunks
+        private const val MAX_RECORDED = 1000
+
+        // Telnet (RFC 854) and COM-PORT-OPTION (RFC 2217), server side
+        private const val IAC = 255
+        private const val DONT = 254
+        private const val DO = 253
+        private const val WONT = 252
+        private const val WILL = 251
+        private const val SB = 250
+        private const val SE = 240
+        private const val OPT_COM_PORT = 44
+        private const val SERVER_OFFSET = 100
+
+        private const val MATO_MEASURE = "\u0011"
+        private const val ETX = 0x03 // Topcon GTS ends each command with ETX instead of a line end
+        private val WIND_SILENT = listOf("P", "AVG", "RESET")
+        private val SCOREBOARD_HANDSHAKES = listOf("INIT", "CONNECT", "POLYFIELD_V1")
+    }
+
+    private val scope = CoroutineScope(Dispatchers.IO + SupervisorJob())
+    private var serverSocket: ServerSocket? = null
+    private var acceptJob: Job? = null
+
+    private val ruleUses = IntArray(scenario.rules.size)
+    private val responseCursors = IntArray(scenario.rules.size)
+    private val received = ArrayDeque<String>()
+
+    val port: Int
+        get() = serverSocket?.localPort ?: requestedPort
+
+    val isRunning: Boolean
+        get() = serverSocket?.isClosed == false
+
+    fun start() {
+        if (isRunning) return
+        val socket = ServerSocket(requestedPort)
+        serverSocket = socket
+        acceptJob = scope.launch {
+            while (isActive && !socket.isClosed) {
+                try {
+                    val client = socket.accept()
+                    launch { serve(client) }
+                } catch (e: IOException) {
+                    if (!socket.isClosed) Log.w(TAG, "Accept failed: ${e.message}")
+                }
+            }
+        }
+        Log.d(TAG, "Mock ${scenario.device} (${scenario.driver}) on port $port")
+    }
+
+    fun stop() {
+        try {
+            serverSocket?.close()
+        } catch (e: IOException) {
+            Log.w(TAG, "Error closing mock server: ${e.message}")
+        }
+        scope.cancel()
+        serverSocket = null
+        acceptJob = null
+        Log.d(TAG, "Mock server stopped")
+    }
+
+    /**
+     * Requests received so far, oldest first, line ends and ETX removed
+     */
+    fun receivedLines(): List<String> = synchronized(received) { received.toList() }
+
+    private suspend fun serve(client: Socket) {
+        Log.d(TAG, "Client connected from ${client.remoteSocketAddress}")
+        val telnet = if (scenario.telnet) TelnetState(client.getOutputStream()) else null
+        val line = ByteArrayOutputStream()
+        val buffer = ByteArray(1024)
+        val etxFramed = scenario.device == "edm" && scenario.driver == "TOPCON_GTS"
+        var afterCr = false
+
+        try {
+            client.use { socket ->
+                val input = socket.getInputStream()
+                while (scope.isActive) {
+                    val count = input.read(buffer)
+                    if (count < 0) break
+                    for (i in 0 until count) {
+                        val b = buffer[i].toInt() and 0xff
+                        val data = telnet?.filter(b) ?: b
+                        if (data < 0) continue
+                        // The \n of a \r\n pair has already ended its request
+                        val skip = !etxFramed && afterCr && data == '\n'.code
+                        afterCr = data == '\r'.code
+                        if (skip) continue
+                        val end = if (etxFramed) data == ETX else data == '\r'.code || data == '\n'.code
+                        if (end || line.size() >= MAX_LINE_BYTES) {
+                            if (!end) line.write(data)
+                            val request = line.toString(Charsets.ISO_8859_1.name())
+                            line.reset()
+                            if (!answer(request, socket, telnet)) return
+                        } else {
+                            line.write(data)
+                        }
+                    }
+                }
+            }
+        } catch (e: IOException) {
+            Log.d(TAG, "Client connection ended: ${e.message}")
+        }
+    }
+
+    /**
+     * Answer one request; false once the connection has been dropped
+     */
+    private suspend fun answer(request: String, socket: Socket, telnet: TelnetState?): Boolean {
+        synchronized(received) {
+            received.addLast(request)
+            while (received.size > MAX_RECORDED) received.removeFirst()
+        }
+
+        val (rule, response) = nextScripted(request) ?: Pair(null, defaultAnswer(request))
+        delay(rule?.delayMs ?: scenario.delayMs)
+
+        val bytes = when (rule?.fault ?: MockFault.NONE) {
+            MockFault.NONE -> response?.let { withLineEnd(it) }
+            MockFault.SILENT -> null
+            MockFault.GARBAGE -> ByteArray(12) { (0x80 + SessionSources.random.nextInt(0x7f)).toByte() } + "\r\n".toByteArray()
+            MockFault.TRUNCATE -> response?.let { it.substring(0, it.length / 2).toByteArray(Charsets.ISO_8859_1) }
+            MockFault.DISCONNECT -> {
+                Log.d(TAG, "Dropping the connection on \"${printable(request)}\"")
+                socket.close()
+                return false
+            }
+        }
+        bytes?.let { write(socket.getOutputStream(), it, telnet) }
+        return true
+    }
+
+    /**
+     * The first rule still in play that matches, and its next response
+     */
+    private fun nextScripted(request: String): Pair<MockRule, String?>? {
+        synchronized(ruleUses) {
+            for ((index, rule) in scenario.rules.withIndex()) {
+                val count = rule.count
+                if (count != null && ruleUses[index] >= count) continue
+                if (!rule.match.containsMatchIn(request)) continue
+                ruleUses[index]++
+                val response = rule.responses.takeIf { it.isNotEmpty() }
+                    ?.let { it[responseCursors[index]++ % it.size] }
+                    ?: defaultAnswer(request)
+                return Pair(rule, response)
+            }
+        }
+        return null
+    }
+
+    /**
+     * What the real device would say, or null if it says nothing
+     */
+    private fun defaultAnswer(request: String): String? = when (scenario.device) {
+        "edm" -> if (scenario.driver == "MATO_MTS602R" && request == MATO_MEASURE) matoReading() else null
+        "wind" -> if (WIND_SILENT.any { request.trim().startsWith(it) }) null else windReading()
+        else -> when {
+            SCOREBOARD_HANDSHAKES.any { it in request } -> null
+            scenario.driver == "SEIKO_OMEGA" -> "\u0006"
+            scenario.driver == "FINISH_LYNX" -> "OK"
+            scenario.driver == "POLYFIELD" -> "{\"success\":true}"
+            else -> null
+        }
+    }
+
+    /**
+     * A prism 5-60 m out, 1.5 m below the instrument, in the MTS-602R+ format
+     */
+    private fun matoReading(): String {
+        val horizontal = 5.0 + SessionSources.random.nextDouble() * 55.0
+        val slopeMm = (hypot(horizontal, 1.5) * 1000).roundToLong()
+        val vertical = 90.0 + Math.toDegrees(atan2(1.5, horizontal))
+        val bearing = SessionSources.random.nextDouble() * 360.0
+        return "%07d %s %s 83".format(slopeMm, dddmmss(vertical), dddmmss(bearing))
+    }
+
+    private fun windReading(): String {
+        val speed = -1.5 + SessionSources.random.nextDouble() * 5.0
+        val direction = SessionSources.random.nextInt(360)
+        return when (scenario.driver) {
+            "GILL_WINDMASTER" -> String.format(Locale.US, "Q,%03d,%+07.2f,M,00,", direction, speed)
+            "LYNX_3002" -> String.format(Locale.US, "WS:%+.1f,WD:%03d", speed, direction)
+            "NMEA_STYLE" -> {
+                val sentence = String.format(Locale.US, "WIMWV,%.1f,R,%.1f,M,A", direction.toDouble(), kotlin.math.abs(speed))
+                "$" + sentence + "*%02X".format(sentence.fold(0) { sum, c -> sum xor c.code })
+            }
+            else -> String.format(Locale.US, "%+.1f", speed)
+        }
+    }
+
+    private fun dddmmss(angleDeg: Double): String {
+        val totalSeconds = (angleDeg * 3600).roundToLong()
+        return "%03d%02d%02d".format(totalSeconds / 3600, totalSeconds / 60 % 60, totalSeconds % 60)
+    }
+
+    private fun withLineEnd(text: String): ByteArray =
+        (if (text.endsWith("\n")) text else "$text\r\n").toByteArray(Charsets.ISO_8859_1)
+
+    private fun write(output: OutputStream, bytes: ByteArray, telnet: TelnetState?) {
+        synchronized(output) {
+            output.write(telnet?.escape(bytes) ?: bytes)
+            output.flush()
+        }
+    }
+
+    private fun printable(text: String): String =
+        text.map { if (it.code < 0x20) "\\x%02x".format(it.code) else it.toString() }.joinToString("")
+
+    /**
+     * Server half of the RFC 2217 negotiation: agrees to every option the client offers or
+     * asks for, echoes each COM port setting back as applied, and strips all of it from the
+     * data stream
+     */
+    private inner class TelnetState(private val output: OutputStream) {
+        private var state = ReadState.DATA
+        private var verb = 0
+        private val subnegotiation = ByteArrayOutputStream()
+        private val agreed = mutableSetOf<Pair<Int, Int>>()
+
+        /**
+         * The data byte, or -1 if b was part of a Telnet command
+         */
+        fun filter(b: Int): Int {
+            when (state) {
+                ReadState.DATA -> if (b == IAC) state = ReadState.COMMAND else return b
+                ReadState.COMMAND -> when (b) {
+                    IAC -> {
+                        state = ReadState.DATA
+                        return IAC
+                    }
+                    WILL, WONT, DO, DONT -> {
+                        verb = b
+                        state = ReadState.OPTION
+                    }
+                    SB -> {
+                        subnegotiation.reset()
+                        state = ReadState.SUBNEGOTIATION
+                    }
+                    else -> state = ReadState.DATA
+                }
+                ReadState.OPTION -> {
+                    reply(verb, b)
+                    state = ReadState.DATA
+                }
+                ReadState.SUBNEGOTIATION -> if (b == IAC) {
+                    state = ReadState.SUBNEGOTIATION_IAC
+                } else {
+                    subnegotiation.write(b)
+                }
+                ReadState.SUBNEGOTIATION_IAC -> when (b) {
+                    SE -> {
+                        acknowledge(subnegotiation.toByteArray())
+                        state = ReadState.DATA
+                    }
+                    IAC -> {
+                        subnegotiation.write(IAC)
+                        state = ReadState.SUBNEGOTIATION
+                    }
+                    else -> state = ReadState.SUBNEGOTIATION
+                }
+            }
+            return -1
+        }
+
+        fun escape(bytes: ByteArray): ByteArray {
+            val escaped = ByteArrayOutputStream(bytes.size + 8)
+            bytes.forEach { byte ->
+                escaped.write(byte.toInt())
+                if (byte.toInt() and 0xff == IAC) escaped.write(IAC)
+            }
+            return escaped.toByteArray()
+        }
+
+        private fun reply(verb: Int, option: Int) {
+            val answer = when (verb) {
+                WILL -> DO
+                DO -> WILL
+                else -> return
+            }
+            if (agreed.add(Pair(answer, option))) send(byteArrayOf(IAC.toByte(), answer.toByte(), option.toByte()))
+        }
+
+        private fun acknowledge(bytes: ByteArray) {
+            if (bytes.size < 2 || bytes[0].toInt() and 0xff != OPT_COM_PORT) return
+            val frame = ByteArrayOutputStream()
+            frame.write(byteArrayOf(IAC.toByte(), SB.toByte(), OPT_COM_PORT.toByte(), ((bytes[1].toInt() and 0xff) + SERVER_OFFSET).toByte()))
+            frame.write(escape(bytes.copyOfRange(2, bytes.size)))
+            frame.write(byteArrayOf(IAC.toByte(), SE.toByte()))
+            send(frame.toByteArray())
+        }
+
+        private enum class ReadState { DATA, COMMAND, OPTION, SUBNEGOTIATION, SUBNEGOTIATION_IAC }
+
+        private fun send(bytes: ByteArray) {
+            synchronized(output) {
+                output.write(bytes)
+                output.flush()
+            }
+        }
+    }
+}